@@ -5,12 +5,17 @@ import (
 	"time"
 
 	"github.com/travigo/travigo/pkg/api"
+	"github.com/travigo/travigo/pkg/ctdfinspect"
+	"github.com/travigo/travigo/pkg/dataexport"
 	"github.com/travigo/travigo/pkg/dataimporter"
 	"github.com/travigo/travigo/pkg/datalinker"
+	"github.com/travigo/travigo/pkg/dbsnapshot"
 	"github.com/travigo/travigo/pkg/dbwatch"
 	"github.com/travigo/travigo/pkg/events"
 	"github.com/travigo/travigo/pkg/indexer"
+	"github.com/travigo/travigo/pkg/loadtest"
 	"github.com/travigo/travigo/pkg/notify"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/realtime"
 	stats "github.com/travigo/travigo/pkg/stats/cli"
 
@@ -52,6 +57,13 @@ func main() {
 			dbwatch.RegisterCLI(),
 			indexer.RegisterCLI(),
 			datalinker.RegisterCLI(),
+			loadtest.RegisterCLI(),
+			dbsnapshot.RegisterCLI(),
+			dataexport.RegisterCLI(),
+			dataexport.RegisterDownloadsCLI(),
+			dataexport.RegisterTimetableCLI(),
+			queuemessage.RegisterCLI(),
+			ctdfinspect.RegisterCLI(),
 		},
 	}
 
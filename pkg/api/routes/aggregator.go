@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/dataaggregator"
+)
+
+func AggregatorSources(c *fiber.Ctx) error {
+	return c.JSON(dataaggregator.GlobalAggregator.GetSourceMetrics())
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+)
+
+// sendDataQualityScores responds with the last 4 weeks of data quality scores so clients can show a confidence indicator
+func sendDataQualityScores(c *fiber.Ctx, subjectType ctdf.DataQualitySubjectType, subjectRef string) error {
+	scores, err := dataaggregator.Lookup[[]*ctdf.DataQualityScore](query.DataQualityScores{
+		SubjectType: subjectType,
+		SubjectRef:  subjectRef,
+		Since:       time.Now().AddDate(0, 0, -28),
+	})
+	if err != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var latest *ctdf.DataQualityScore
+	if len(scores) > 0 {
+		latest = scores[len(scores)-1]
+	}
+
+	return c.JSON(fiber.Map{
+		"SubjectType": subjectType,
+		"SubjectRef":  subjectRef,
+		"Latest":      latest,
+		"History":     scores,
+	})
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator"
 	"github.com/travigo/travigo/pkg/dataaggregator/query"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
@@ -9,6 +10,7 @@ import (
 
 func DatasourcesRouter(router fiber.Router) {
 	router.Get("/dataset/:identifier", getDataset)
+	router.Get("/dataset/:identifier/data-quality", getDatasetDataQuality)
 	router.Get("/provider/:identifier", getProvider)
 }
 
@@ -30,6 +32,22 @@ func getDataset(c *fiber.Ctx) error {
 	}
 }
 
+func getDatasetDataQuality(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	dataset, err := dataaggregator.Lookup[*datasets.DataSet](query.DataSet{
+		DataSetID: identifier,
+	})
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendDataQualityScores(c, ctdf.DataQualitySubjectDataSet, dataset.Identifier)
+}
+
 func getProvider(c *fiber.Ctx) error {
 	identifier := c.Params("identifier")
 
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/liip/sheriff"
 	"github.com/travigo/travigo/pkg/ctdf"
@@ -16,10 +18,26 @@ func JourneysRouter(router fiber.Router) {
 func getJourney(c *fiber.Ctx) error {
 	identifier := c.Params("identifier")
 	realtimeOnly := c.QueryBool("realtime_only", false)
+	dateString := c.Query("date")
+
+	var date time.Time
+	if dateString != "" {
+		var err error
+		date, err = time.ParseInLocation(time.DateOnly, dateString, time.Local)
+
+		if err != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":    "Parameter date should be a YYYY-MM-DD date",
+				"detailed": err,
+			})
+		}
+	}
 
 	var journey *ctdf.Journey
-	journey, err := dataaggregator.Lookup[*ctdf.Journey](query.Journey{
+	journey, err := dataaggregator.Lookup[*ctdf.Journey](query.JourneyByIDAndDate{
 		PrimaryIdentifier: identifier,
+		Date:              date,
 	})
 
 	if err != nil {
@@ -28,10 +46,6 @@ func getJourney(c *fiber.Ctx) error {
 			"error": err.Error(),
 		})
 	} else {
-		journey.GetReferences()
-		journey.GetDeepReferences()
-		journey.GetRealtimeJourney(nil)
-
 		var journeyReduced interface{}
 
 		if realtimeOnly {
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/ctdf"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -35,3 +36,13 @@ func getBoundsQuery(c *fiber.Ctx) (bson.M, error) {
 		},
 	}, nil
 }
+
+// getLanguages returns the languages names & texts should be given in, in order of preference.
+// The lang parameter takes priority over the Accept-Language header.
+func getLanguages(c *fiber.Ctx) []string {
+	if lang := c.Query("lang"); lang != "" {
+		return ctdf.ParseLanguagePreference(lang)
+	}
+
+	return ctdf.ParseLanguagePreference(c.Get(fiber.HeaderAcceptLanguage))
+}
@@ -5,10 +5,12 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator"
 	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/transforms"
 )
 
 func OperatorGroupsRouter(router fiber.Router) {
 	router.Get("/:identifier", getOperatorGroup)
+	router.Get("/:identifier/services", getOperatorGroupServices)
 }
 
 func getOperatorGroup(c *fiber.Ctx) error {
@@ -29,3 +31,33 @@ func getOperatorGroup(c *fiber.Ctx) error {
 		return c.JSON(operatorGroup)
 	}
 }
+
+func getOperatorGroupServices(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	operatorGroup, err := dataaggregator.Lookup[*ctdf.OperatorGroup](query.OperatorGroup{
+		Identifier: identifier,
+	})
+
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	services, err := dataaggregator.Lookup[[]*ctdf.Service](query.ServicesByOperatorGroup{
+		OperatorGroup:  operatorGroup,
+		TransportTypes: ctdf.ParseTransportTypeList(c.Query("transport_type")),
+	})
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	transforms.Transform(services, 3)
+
+	return c.JSON(services)
+}
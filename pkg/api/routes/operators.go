@@ -7,7 +7,6 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/liip/sheriff"
-	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator"
 	"github.com/travigo/travigo/pkg/dataaggregator/query"
@@ -25,6 +24,7 @@ func OperatorsRouter(router fiber.Router) {
 	router.Get("/", listOperators)
 	router.Get("/:identifier", getOperator)
 	router.Get("/:identifier/services", getOperatorServices)
+	router.Get("/:identifier/data-quality", getOperatorDataQuality)
 }
 
 func listOperators(c *fiber.Ctx) error {
@@ -126,19 +126,15 @@ func getOperatorServices(c *fiber.Ctx) error {
 			"error": err.Error(),
 		})
 	} else {
-		var services []*ctdf.Service
-
-		servicesCollection := database.GetCollection("services")
-		cursor, _ := servicesCollection.Find(context.Background(), bson.M{"operatorref": bson.M{"$in": operator.OtherIdentifiers}})
-
-		for cursor.Next(context.Background()) {
-			var service ctdf.Service
-			err := cursor.Decode(&service)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to decode Service")
-			}
-
-			services = append(services, &service)
+		services, err := dataaggregator.Lookup[[]*ctdf.Service](query.ServicesByOperator{
+			Operator:       operator,
+			TransportTypes: ctdf.ParseTransportTypeList(c.Query("transport_type")),
+		})
+		if err != nil {
+			c.SendStatus(404)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
 		}
 
 		transforms.Transform(services, 3)
@@ -147,6 +143,20 @@ func getOperatorServices(c *fiber.Ctx) error {
 	}
 }
 
+func getOperatorDataQuality(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	operator, err := getOperatorById(identifier)
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendDataQualityScores(c, ctdf.DataQualitySubjectOperator, operator.PrimaryIdentifier)
+}
+
 func getOperatorById(identifier string) (*ctdf.Operator, error) {
 	var operator *ctdf.Operator
 	operator, err := dataaggregator.Lookup[*ctdf.Operator](query.Operator{
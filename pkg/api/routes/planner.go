@@ -33,7 +33,8 @@ func getPlanBetweenStops(c *fiber.Ctx) error {
 	// Get stops
 	var originStop *ctdf.Stop
 	originStop, err = dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-		Identifier: originIdentifier,
+		Identifier:      originIdentifier,
+		IncludeInactive: true,
 	})
 	if err != nil {
 		c.SendStatus(fiber.StatusNotFound)
@@ -44,7 +45,8 @@ func getPlanBetweenStops(c *fiber.Ctx) error {
 	}
 	var destinationStop *ctdf.Stop
 	destinationStop, err = dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-		Identifier: destinationIdentifier,
+		Identifier:      destinationIdentifier,
+		IncludeInactive: true,
 	})
 	if err != nil {
 		c.SendStatus(fiber.StatusNotFound)
@@ -78,6 +80,7 @@ func getPlanBetweenStops(c *fiber.Ctx) error {
 		DestinationStop: destinationStop,
 		Count:           count,
 		StartDateTime:   startDateTime,
+		TransportTypes:  ctdf.ParseTransportTypeList(c.Query("transport_type")),
 	})
 
 	// Sort departures by DepartureBoard time
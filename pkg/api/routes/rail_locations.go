@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/liip/sheriff"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+)
+
+func RailLocationsRouter(router fiber.Router) {
+	router.Get("/:code", getRailLocation)
+}
+
+func getRailLocation(c *fiber.Ctx) error {
+	code := c.Params("code")
+
+	var railLocation *ctdf.RailLocation
+	railLocation, err := dataaggregator.Lookup[*ctdf.RailLocation](query.RailLocationByCode{
+		Code: code,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reducedRailLocation, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic", "detailed"},
+	}, railLocation)
+
+	return c.JSON(reducedRailLocation)
+}
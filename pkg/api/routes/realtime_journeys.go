@@ -3,6 +3,8 @@ package routes
 import (
 	"context"
 	"encoding/json"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
@@ -15,7 +17,9 @@ import (
 
 func RealtimeJourneysRouter(router fiber.Router) {
 	router.Get("/", listRealtimeJourney)
+	router.Get("/identify", identifyRealtimeJourney)
 	router.Get("/:identifier", getRealtimeJourney)
+	router.Get("/:identifier/track", getRealtimeJourneyTrack)
 }
 
 type realtimeJourneyMinimised struct {
@@ -107,3 +111,85 @@ func getRealtimeJourney(c *fiber.Ctx) error {
 		return c.JSON(realtimeJourney)
 	}
 }
+
+// getRealtimeJourneyTrack returns where the vehicle has been on the journey so far, for drawing its path on a map
+func getRealtimeJourneyTrack(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	vehicleTrack, err := dataaggregator.Lookup[*ctdf.VehicleTrack](query.VehicleTrack{
+		RealtimeJourneyRef: identifier,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(vehicleTrack)
+}
+
+// identifyRealtimeJourney finds the vehicle the user is most likely on from the location & time their device reports
+func identifyRealtimeJourney(c *fiber.Ctx) error {
+	latitude, latErr := strconv.ParseFloat(c.Query("latitude"), 64)
+	longitude, lonErr := strconv.ParseFloat(c.Query("longitude"), 64)
+	if latErr != nil || lonErr != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "Parameters latitude & longitude should be numbers",
+		})
+	}
+
+	timestamp := time.Now()
+	if c.Query("datetime") != "" {
+		var err error
+		timestamp, err = time.Parse(time.RFC3339, c.Query("datetime"))
+		if err != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":    "Parameter datetime should be an RFS3339/ISO8601 datetime",
+				"detailed": err,
+			})
+		}
+	}
+
+	radius, err := strconv.ParseFloat(c.Query("radius", "150"), 64)
+	if err != nil || radius <= 0 || radius > 1000 {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "Parameter radius should be a number of metres up to 1000",
+		})
+	}
+
+	locationQuery := query.RealtimeJourneyByLocation{
+		Location: ctdf.Location{
+			Type:        "Point",
+			Coordinates: []float64{longitude, latitude},
+		},
+		Timestamp:  timestamp,
+		ServiceRef: c.Query("service"),
+		Radius:     radius,
+	}
+
+	if c.Query("bearing") != "" {
+		bearing, err := strconv.ParseFloat(c.Query("bearing"), 64)
+		if err != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": "Parameter bearing should be a number of degrees",
+			})
+		}
+		locationQuery.Bearing = &bearing
+	}
+
+	realtimeJourney, err := dataaggregator.Lookup[*ctdf.RealtimeJourney](locationQuery)
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(realtimeJourney)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/travigo/travigo/pkg/ctdf"
@@ -14,6 +15,7 @@ import (
 func ServiceAlertRouter(router fiber.Router) {
 	router.Get("/matching/:identifier", getMatchingIdentifierServiceAlerts)
 	router.Get("/stop/:identifier", getStopServiceAlerts)
+	router.Get("/service/:identifier", getServiceServiceAlerts)
 }
 
 func filterIdenticalServiceAlerts(serviceAlerts []*ctdf.ServiceAlert) []*ctdf.ServiceAlert {
@@ -47,6 +49,7 @@ func getMatchingIdentifierServiceAlerts(c *fiber.Ctx) error {
 	})
 
 	serviceAlertsFiltered := filterIdenticalServiceAlerts(serviceAlerts)
+	ctdf.Localise(serviceAlertsFiltered, getLanguages(c))
 
 	if err != nil {
 		c.SendStatus(404)
@@ -64,7 +67,8 @@ func getStopServiceAlerts(c *fiber.Ctx) error {
 	// First get the stop
 	var stop *ctdf.Stop
 	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-		Identifier: identifier,
+		Identifier:      identifier,
+		IncludeInactive: true,
 	})
 
 	if err != nil {
@@ -74,27 +78,58 @@ func getStopServiceAlerts(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get the services for that stop
-	var services []*ctdf.Service
-	services, err = dataaggregator.Lookup[[]*ctdf.Service](query.ServicesByStop{
+	var serviceAlerts []*ctdf.ServiceAlert
+	serviceAlerts, err = dataaggregator.Lookup[[]*ctdf.ServiceAlert](query.AlertsForStop{
 		Stop: stop,
+		Time: time.Now(),
 	})
 
-	// setup query for disruptions
-	matchingIdentifiers := []string{
-		identifier,
+	serviceAlertsFiltered := filterIdenticalServiceAlerts(serviceAlerts)
+	ctdf.Localise(serviceAlertsFiltered, getLanguages(c))
+
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if strings.ToLower(c.Query("isllm")) == "true" {
+		serviceAlertsReduced, err := ctdf.RenderDeparturesLLM(serviceAlertsFiltered, ctdf.DeparturesLLMOptions{})
+		if err != nil {
+			c.SendStatus(fiber.StatusInternalServerError)
+			return c.JSON(fiber.Map{
+				"error": "Could not reduce service alerts",
+			})
+		}
+
+		return c.JSON(serviceAlertsReduced)
+	} else {
+		return c.JSON(serviceAlertsFiltered)
 	}
+}
+
+func getServiceServiceAlerts(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	var service *ctdf.Service
+	service, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+		PrimaryIdentifier: identifier,
+	})
 
-	for _, service := range services {
-		matchingIdentifiers = append(matchingIdentifiers, service.PrimaryIdentifier)
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
 	var serviceAlerts []*ctdf.ServiceAlert
-	serviceAlerts, err = dataaggregator.Lookup[[]*ctdf.ServiceAlert](query.ServiceAlertsForMatchingIdentifiers{
-		MatchingIdentifiers: matchingIdentifiers,
+	serviceAlerts, err = dataaggregator.Lookup[[]*ctdf.ServiceAlert](query.AlertsForService{
+		Service: service,
+		Time:    time.Now(),
 	})
 
 	serviceAlertsFiltered := filterIdenticalServiceAlerts(serviceAlerts)
+	ctdf.Localise(serviceAlertsFiltered, getLanguages(c))
 
 	if err != nil {
 		c.SendStatus(404)
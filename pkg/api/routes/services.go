@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator"
@@ -9,7 +11,30 @@ import (
 )
 
 func ServicesRouter(router fiber.Router) {
+	router.Get("/search", searchServices)
 	router.Get("/:identifier", getService)
+	router.Get("/:identifier/occupancy", getServiceOccupancy)
+	router.Get("/:identifier/routes", getServiceRoutes)
+}
+
+func searchServices(c *fiber.Ctx) error {
+	services, err := dataaggregator.Lookup[[]*ctdf.Service](query.ServiceSearch{
+		ServiceName:    c.Query("name"),
+		OperatorRef:    c.Query("operator"),
+		TransportTypes: ctdf.ParseTransportTypeList(c.Query("transport_type")),
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	transforms.Transform(services, 2)
+	ctdf.Localise(services, getLanguages(c))
+
+	return c.JSON(services)
 }
 
 func getService(c *fiber.Ctx) error {
@@ -27,7 +52,83 @@ func getService(c *fiber.Ctx) error {
 		})
 	} else {
 		transforms.Transform(service, 2)
+		ctdf.Localise(service, getLanguages(c))
 
 		return c.JSON(service)
 	}
 }
+
+func getServiceOccupancy(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	service, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+		PrimaryIdentifier: identifier,
+	})
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	periods, err := dataaggregator.Lookup[[]*ctdf.ServiceOccupancyPeriod](query.OccupancyByService{
+		ServiceRef: service.PrimaryIdentifier,
+		Since:      time.Now().AddDate(0, 0, -28),
+		Timezone:   c.Query("timezone"),
+	})
+	if err != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"ServiceRef": service.PrimaryIdentifier,
+		"Periods":    periods,
+	})
+}
+
+func getServiceRoutes(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	service, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+		PrimaryIdentifier: identifier,
+	})
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	routeQuery := query.RouteForService{
+		Service: service,
+	}
+
+	if c.Query("stop") != "" {
+		routeQuery.Stop, err = dataaggregator.Lookup[*ctdf.Stop](query.Stop{
+			Identifier:      c.Query("stop"),
+			IncludeInactive: true,
+		})
+		if err != nil {
+			c.SendStatus(fiber.StatusNotFound)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	routes, err := dataaggregator.Lookup[[]*ctdf.Route](routeQuery)
+	if err != nil {
+		c.SendStatus(404)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"ServiceRef": service.PrimaryIdentifier,
+		"Routes":     routes,
+	})
+}
@@ -28,9 +28,14 @@ func StopsRouter(router fiber.Router) {
 	router.Get("/", listStops)
 
 	router.Get("/search", searchStops)
+	router.Get("/locate", locateStops)
 
 	router.Get("/:identifier", getStop)
 	router.Get("/:identifier/departures", getStopDepartures)
+	router.Get("/:identifier/service_summaries", getStopServiceSummaries)
+	router.Get("/:identifier/transfers", getStopTransfers)
+	router.Get("/:identifier/availability", getStopAvailability)
+	router.Get("/:identifier/carparks", getStopCarParks)
 }
 
 func listStops(c *fiber.Ctx) error {
@@ -60,6 +65,7 @@ func listStops(c *fiber.Ctx) error {
 		}
 	}
 
+	// Most important first so map clients can prioritise their labels in order
 	opts := options.Find().SetProjection(bson.D{
 		bson.E{Key: "_id", Value: 0},
 		bson.E{Key: "otheridentifiers", Value: 0},
@@ -67,7 +73,7 @@ func listStops(c *fiber.Ctx) error {
 		bson.E{Key: "creationdatetime", Value: 0},
 		bson.E{Key: "modificationdatetime", Value: 0},
 		bson.E{Key: "associations", Value: 0},
-	})
+	}).SetSort(bson.D{{Key: "importance.score", Value: -1}})
 
 	cursor, _ := stopsCollection.Find(context.Background(), bsonQuery, opts)
 
@@ -95,6 +101,8 @@ func listStops(c *fiber.Ctx) error {
 	}
 	wg.Wait()
 
+	ctdf.Localise(stops, getLanguages(c))
+
 	reducedStops, _ := sheriff.Marshal(&sheriff.Options{
 		Groups: []string{"basic"},
 	}, stops)
@@ -109,7 +117,8 @@ func getStop(c *fiber.Ctx) error {
 
 	var stop *ctdf.Stop
 	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-		Identifier: identifier,
+		Identifier:      identifier,
+		IncludeInactive: c.Query("inactive") == "true",
 	})
 
 	if err != nil {
@@ -119,11 +128,15 @@ func getStop(c *fiber.Ctx) error {
 		})
 	} else {
 		stop.Services, _ = dataaggregator.Lookup[[]*ctdf.Service](query.ServicesByStop{
-			Stop: stop,
+			Stop:           stop,
+			TransportTypes: ctdf.ParseTransportTypeList(c.Query("transport_type")),
 		})
+		stop.GetLocality()
 
 		transforms.Transform(stop, 3)
 
+		ctdf.Localise(stop, getLanguages(c))
+
 		reduceGroupsName := []string{"basic", "detailed"}
 		if isLLM == "true" {
 			reduceGroupsName = []string{"stop-llm"}
@@ -152,7 +165,8 @@ func getStopDepartures(c *fiber.Ctx) error {
 
 	var stop *ctdf.Stop
 	stop, err = dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-		Identifier: stopIdentifier,
+		Identifier:      stopIdentifier,
+		IncludeInactive: true,
 	})
 
 	if err != nil {
@@ -185,6 +199,8 @@ func getStopDepartures(c *fiber.Ctx) error {
 		Stop:          stop,
 		Count:         count,
 		StartDateTime: startDateTime,
+
+		TransportTypes: ctdf.ParseTransportTypeList(c.Query("transport_type")),
 	})
 
 	// Sort departures by DepartureBoard time
@@ -206,15 +222,19 @@ func getStopDepartures(c *fiber.Ctx) error {
 		transforms.Transform(item.Journey.Service, 1)
 	}
 
-	reduceGroupsName := []string{"basic"}
+	ctdf.Localise(departureBoard, getLanguages(c))
+
+	var departureBoardReduced interface{}
 	if isLLM == "true" {
-		reduceGroupsName = []string{"departures-llm"}
+		departureBoardReduced, err = ctdf.RenderDeparturesLLM(departureBoard, ctdf.DeparturesLLMOptions{
+			DetailedRailInformation: strings.ToLower(c.Query("detailedrail")) == "true",
+		})
+	} else {
+		departureBoardReduced, err = sheriff.Marshal(&sheriff.Options{
+			Groups: []string{"basic"},
+		}, departureBoard)
 	}
 
-	departureBoardReduced, err := sheriff.Marshal(&sheriff.Options{
-		Groups: reduceGroupsName,
-	}, departureBoard)
-
 	if err != nil {
 		c.SendStatus(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
@@ -227,6 +247,72 @@ func getStopDepartures(c *fiber.Ctx) error {
 	return c.JSON(departureBoardReduced)
 }
 
+func getStopServiceSummaries(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	var stop *ctdf.Stop
+	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
+		Identifier:      identifier,
+		IncludeInactive: true,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	summaries, err := dataaggregator.Lookup[[]*ctdf.ServiceStopSummary](query.ServiceStopSummariesByStop{
+		Stop: stop,
+	})
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reducedSummaries, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic"},
+	}, summaries)
+
+	return c.JSON(reducedSummaries)
+}
+
+func getStopTransfers(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	var stop *ctdf.Stop
+	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
+		Identifier:      identifier,
+		IncludeInactive: true,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	transfers, err := dataaggregator.Lookup[[]*ctdf.Transfer](query.TransfersByStop{
+		Stop: stop,
+	})
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reducedTransfers, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic"},
+	}, transfers)
+
+	return c.JSON(reducedTransfers)
+}
+
 func searchStops(c *fiber.Ctx) error {
 	searchTerm := c.Query("name")
 	transportType := c.Query("transporttype")
@@ -255,6 +341,11 @@ func searchStops(c *fiber.Ctx) error {
 							"PrimaryName.search_as_you_type": searchTerm,
 						},
 					},
+					map[string]interface{}{
+						"match_phrase_prefix": map[string]interface{}{
+							"TranslatedNames.search_as_you_type": searchTerm,
+						},
+					},
 					map[string]interface{}{
 						"match_phrase_prefix": map[string]interface{}{
 							"OtherIdentifiers.search_as_you_type": searchTerm,
@@ -289,6 +380,16 @@ func searchStops(c *fiber.Ctx) error {
 		"collapse": map[string]interface{}{
 			"field": "PrimaryIdentifier.keyword",
 		},
+		// Everything is a filter so matches all score the same, put the most important stops first instead
+		"sort": []interface{}{
+			map[string]interface{}{
+				"Importance.Score": map[string]interface{}{
+					"order":         "desc",
+					"missing":       "_last",
+					"unmapped_type": "float",
+				},
+			},
+		},
 	}
 
 	json.NewEncoder(&queryBytes).Encode(searchQuery)
@@ -329,6 +430,8 @@ func searchStops(c *fiber.Ctx) error {
 		stops = append(stops, hit.Source)
 	}
 
+	ctdf.Localise(stops, getLanguages(c))
+
 	reduceGroupName := "search"
 	if isLLM == "true" {
 		reduceGroupName = "search-llm"
@@ -342,3 +445,122 @@ func searchStops(c *fiber.Ctx) error {
 		"stops": stopsReduced,
 	})
 }
+
+func getStopAvailability(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+
+	var stop *ctdf.Stop
+	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
+		Identifier:      identifier,
+		IncludeInactive: true,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	availability := stop.GetDockAvailability()
+	if availability == nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "No availability for this stop",
+		})
+	}
+
+	reducedAvailability, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic"},
+	}, availability)
+
+	return c.JSON(reducedAvailability)
+}
+
+func getStopCarParks(c *fiber.Ctx) error {
+	identifier := c.Params("identifier")
+	radius, _ := strconv.ParseFloat(c.Query("radius"), 64)
+	parkAndRideOnly := c.Query("parkandride") == "true"
+
+	var stop *ctdf.Stop
+	stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
+		Identifier:      identifier,
+		IncludeInactive: true,
+	})
+
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	carParks, err := dataaggregator.Lookup[[]*ctdf.CarPark](query.CarParksNearStop{
+		Stop:            stop,
+		Radius:          radius,
+		ParkAndRideOnly: parkAndRideOnly,
+	})
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reducedCarParks, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic"},
+	}, carParks)
+
+	return c.JSON(reducedCarParks)
+}
+
+// locateStops finds the stops nearest a Plus Code or latitude & longitude someone has typed or read out.
+// Short Plus Codes need a reference latitude & longitude to be resolved against
+func locateStops(c *fiber.Ctx) error {
+	text := c.Query("q")
+	if text == "" {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "Missing `q` query parameter",
+		})
+	}
+
+	radius, _ := strconv.ParseFloat(c.Query("radius"), 64)
+	count, _ := strconv.Atoi(c.Query("count"))
+
+	locationQuery := query.StopsNearLocationText{
+		Text:   text,
+		Radius: radius,
+		Count:  count,
+	}
+
+	if c.Query("latitude") != "" || c.Query("longitude") != "" {
+		latitude, latErr := strconv.ParseFloat(c.Query("latitude"), 64)
+		longitude, lonErr := strconv.ParseFloat(c.Query("longitude"), 64)
+		if latErr != nil || lonErr != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": "Parameters latitude & longitude should be numbers",
+			})
+		}
+
+		locationQuery.Reference = &ctdf.Location{
+			Type:        "Point",
+			Coordinates: []float64{longitude, latitude},
+		}
+	}
+
+	stops, err := dataaggregator.Lookup[[]*ctdf.Stop](locationQuery)
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reducedStops, _ := sheriff.Marshal(&sheriff.Options{
+		Groups: []string{"basic"},
+	}, stops)
+
+	return c.JSON(reducedStops)
+}
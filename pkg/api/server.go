@@ -13,9 +13,11 @@ func SetupServer(listen string) error {
 	group := webApp.Group("/core")
 
 	group.Get("version", routes.APIVersion)
+	group.Get("aggregator/sources", routes.AggregatorSources)
 
 	routes.StopsRouter(group.Group("/stops"))
 	routes.StopGroupsRouter(group.Group("/stop_groups"))
+	routes.RailLocationsRouter(group.Group("/rail_locations"))
 
 	routes.OperatorsRouter(group.Group("/operators"))
 	routes.OperatorGroupsRouter(group.Group("/operator_groups"))
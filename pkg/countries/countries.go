@@ -0,0 +1,105 @@
+package countries
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Profile holds the defaults that differ between the countries datasets are imported from
+type Profile struct {
+	// ISO 3166-1 alpha-2 code in lower case, or eu for feeds covering several countries
+	Code string
+	Name string
+
+	// Prefix every identifier created for this country starts with, eg. gb-atco-...
+	IdentifierPrefix string
+	// Format for stop identifiers from the national stop database, empty when the country doesn't have one we use
+	NationalStopIDFormat string
+
+	// Timezone used when a dataset doesn't specify one itself
+	Timezone string
+
+	// Holidays returns the public holidays for a year, nil when the profile has no calendar
+	Holidays func(year int) []Holiday
+}
+
+type Holiday struct {
+	Name string
+	Date time.Time
+}
+
+// Default is used for datasets that don't belong to any known country, the importer started out GB only
+var Default = GB
+
+var profiles = map[string]*Profile{}
+
+func register(profile *Profile) *Profile {
+	profiles[profile.Code] = profile
+
+	return profile
+}
+
+// Get returns the profile for a country code
+func Get(code string) (*Profile, bool) {
+	profile, exists := profiles[strings.ToLower(code)]
+
+	return profile, exists
+}
+
+// GetAll returns every registered profile
+func GetAll() []*Profile {
+	var all []*Profile
+	for _, profile := range profiles {
+		all = append(all, profile)
+	}
+
+	return all
+}
+
+// HasIdentifierPrefix checks the identifier was created for this country
+func (profile *Profile) HasIdentifierPrefix(identifier string) bool {
+	return strings.HasPrefix(identifier, profile.IdentifierPrefix+"-")
+}
+
+// FormatNationalStopID returns the identifier of a stop in the national stop database, or false if there isn't one
+func (profile *Profile) FormatNationalStopID(stopCode string) (string, bool) {
+	if profile.NationalStopIDFormat == "" {
+		return "", false
+	}
+
+	return fmt.Sprintf(profile.NationalStopIDFormat, stopCode), true
+}
+
+// GetLocation loads the default timezone of the country, falling back to UTC if it doesn't have one
+func (profile *Profile) GetLocation() *time.Location {
+	if profile.Timezone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(profile.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+func (profile *Profile) GetHolidays(year int) []Holiday {
+	if profile.Holidays == nil {
+		return nil
+	}
+
+	return profile.Holidays(year)
+}
+
+// GetHoliday finds the public holiday that falls on a date
+func (profile *Profile) GetHoliday(date time.Time) (Holiday, bool) {
+	for _, holiday := range profile.GetHolidays(date.Year()) {
+		if holiday.Date.Year() == date.Year() && holiday.Date.Month() == date.Month() && holiday.Date.Day() == date.Day() {
+			return holiday, true
+		}
+	}
+
+	return Holiday{}, false
+}
@@ -0,0 +1,45 @@
+package countries
+
+import "time"
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// GetEasterSunday uses the anonymous Gregorian algorithm
+func GetEasterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := ((h + l - 7*m + 114) % 31) + 1
+
+	return date(year, time.Month(month), day)
+}
+
+// GetFirstWeekday returns the first matching weekday on or after the date
+func GetFirstWeekday(from time.Time, weekday time.Weekday) time.Time {
+	for from.Weekday() != weekday {
+		from = from.AddDate(0, 0, 1)
+	}
+
+	return from
+}
+
+// GetLastWeekday returns the last matching weekday on or before the date
+func GetLastWeekday(from time.Time, weekday time.Weekday) time.Time {
+	for from.Weekday() != weekday {
+		from = from.AddDate(0, 0, -1)
+	}
+
+	return from
+}
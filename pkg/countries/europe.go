@@ -0,0 +1,119 @@
+package countries
+
+import "time"
+
+var IE = register(&Profile{
+	Code:             "ie",
+	Name:             "Ireland",
+	IdentifierPrefix: "ie",
+	Timezone:         "Europe/Dublin",
+	Holidays: func(year int) []Holiday {
+		easter := GetEasterSunday(year)
+
+		// St Brigid's Day is the first Monday in February, unless the 1st is a Friday
+		stBrigidsDay := date(year, time.February, 1)
+		if stBrigidsDay.Weekday() != time.Friday {
+			stBrigidsDay = GetFirstWeekday(stBrigidsDay, time.Monday)
+		}
+
+		holidays := []Holiday{
+			{Name: "NewYearsDay", Date: date(year, time.January, 1)},
+			{Name: "StPatricksDay", Date: date(year, time.March, 17)},
+			{Name: "EasterMonday", Date: easter.AddDate(0, 0, 1)},
+			{Name: "MayBankHoliday", Date: GetFirstWeekday(date(year, time.May, 1), time.Monday)},
+			{Name: "JuneBankHoliday", Date: GetFirstWeekday(date(year, time.June, 1), time.Monday)},
+			{Name: "AugustBankHoliday", Date: GetFirstWeekday(date(year, time.August, 1), time.Monday)},
+			{Name: "OctoberBankHoliday", Date: GetLastWeekday(date(year, time.October, 31), time.Monday)},
+			{Name: "ChristmasDay", Date: date(year, time.December, 25)},
+			{Name: "StStephensDay", Date: date(year, time.December, 26)},
+		}
+		if year >= 2023 {
+			holidays = append(holidays, Holiday{Name: "StBrigidsDay", Date: stBrigidsDay})
+		}
+
+		return holidays
+	},
+})
+
+var DE = register(&Profile{
+	Code:             "de",
+	Name:             "Germany",
+	IdentifierPrefix: "de",
+	Timezone:         "Europe/Berlin",
+	// Only the nationwide holidays, each state adds its own on top
+	Holidays: func(year int) []Holiday {
+		easter := GetEasterSunday(year)
+
+		return []Holiday{
+			{Name: "Neujahr", Date: date(year, time.January, 1)},
+			{Name: "Karfreitag", Date: easter.AddDate(0, 0, -2)},
+			{Name: "Ostermontag", Date: easter.AddDate(0, 0, 1)},
+			{Name: "TagDerArbeit", Date: date(year, time.May, 1)},
+			{Name: "ChristiHimmelfahrt", Date: easter.AddDate(0, 0, 39)},
+			{Name: "Pfingstmontag", Date: easter.AddDate(0, 0, 50)},
+			{Name: "TagDerDeutschenEinheit", Date: date(year, time.October, 3)},
+			{Name: "ErsterWeihnachtstag", Date: date(year, time.December, 25)},
+			{Name: "ZweiterWeihnachtstag", Date: date(year, time.December, 26)},
+		}
+	},
+})
+
+var FR = register(&Profile{
+	Code:             "fr",
+	Name:             "France",
+	IdentifierPrefix: "fr",
+	Timezone:         "Europe/Paris",
+	Holidays: func(year int) []Holiday {
+		easter := GetEasterSunday(year)
+
+		return []Holiday{
+			{Name: "JourDeLAn", Date: date(year, time.January, 1)},
+			{Name: "LundiDePaques", Date: easter.AddDate(0, 0, 1)},
+			{Name: "FeteDuTravail", Date: date(year, time.May, 1)},
+			{Name: "Victoire1945", Date: date(year, time.May, 8)},
+			{Name: "Ascension", Date: easter.AddDate(0, 0, 39)},
+			{Name: "LundiDePentecote", Date: easter.AddDate(0, 0, 50)},
+			{Name: "FeteNationale", Date: date(year, time.July, 14)},
+			{Name: "Assomption", Date: date(year, time.August, 15)},
+			{Name: "Toussaint", Date: date(year, time.November, 1)},
+			{Name: "Armistice1918", Date: date(year, time.November, 11)},
+			{Name: "Noel", Date: date(year, time.December, 25)},
+		}
+	},
+})
+
+var SE = register(&Profile{
+	Code:             "se",
+	Name:             "Sweden",
+	IdentifierPrefix: "se",
+	Timezone:         "Europe/Stockholm",
+	// Includes the eves that aren't official holidays but that timetables treat as one
+	Holidays: func(year int) []Holiday {
+		easter := GetEasterSunday(year)
+		midsummerDay := GetFirstWeekday(date(year, time.June, 20), time.Saturday)
+
+		return []Holiday{
+			{Name: "Nyarsdagen", Date: date(year, time.January, 1)},
+			{Name: "Trettondedagjul", Date: date(year, time.January, 6)},
+			{Name: "Langfredagen", Date: easter.AddDate(0, 0, -2)},
+			{Name: "AnnandagPask", Date: easter.AddDate(0, 0, 1)},
+			{Name: "ForstaMaj", Date: date(year, time.May, 1)},
+			{Name: "KristiHimmelfardsdag", Date: easter.AddDate(0, 0, 39)},
+			{Name: "Nationaldagen", Date: date(year, time.June, 6)},
+			{Name: "Midsommarafton", Date: midsummerDay.AddDate(0, 0, -1)},
+			{Name: "Midsommardagen", Date: midsummerDay},
+			{Name: "AllaHelgonsDag", Date: GetFirstWeekday(date(year, time.October, 31), time.Saturday)},
+			{Name: "Julafton", Date: date(year, time.December, 24)},
+			{Name: "Juldagen", Date: date(year, time.December, 25)},
+			{Name: "AnnandagJul", Date: date(year, time.December, 26)},
+			{Name: "Nyarsafton", Date: date(year, time.December, 31)},
+		}
+	},
+})
+
+// EU is for feeds that cover several countries, eg. FlixBus, they have to give their own timezones & holidays
+var EU = register(&Profile{
+	Code:             "eu",
+	Name:             "Europe",
+	IdentifierPrefix: "eu",
+})
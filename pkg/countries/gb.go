@@ -0,0 +1,107 @@
+package countries
+
+import "time"
+
+var GB = register(&Profile{
+	Code:                 "gb",
+	Name:                 "Great Britain",
+	IdentifierPrefix:     "gb",
+	NationalStopIDFormat: "gb-atco-%s",
+	Timezone:             "Europe/London",
+	Holidays:             getGBHolidays,
+})
+
+// The bank holidays of England & Wales, Scotland has its own which datasets reference by name
+var gbCalendarHolidays = []string{
+	"NewYearsDay", "NewYearsDayHoliday", "GoodFriday", "EasterMonday", "MayDay", "SpringBank",
+	"LateSummerBankHolidayNotScotland", "ChristmasDay", "BoxingDay", "ChristmasDayHoliday", "BoxingDayHoliday",
+}
+
+func getGBHolidays(year int) []Holiday {
+	var holidays []Holiday
+
+	for _, name := range gbCalendarHolidays {
+		if holidayDate, exists := GetGBBankHoliday(name, year); exists {
+			holidays = append(holidays, Holiday{Name: name, Date: holidayDate})
+		}
+	}
+
+	return holidays
+}
+
+// GetGBBankHoliday returns the date of a bank holiday named as it is in TransXChange
+func GetGBBankHoliday(name string, year int) (time.Time, bool) {
+	switch name {
+	case "NewYearsDay":
+		return date(year, time.January, 1), true
+	case "Jan2ndScotland":
+		return date(year, time.January, 2), true
+	case "GoodFriday":
+		return GetEasterSunday(year).AddDate(0, 0, -2), true
+	case "EasterMonday":
+		return GetEasterSunday(year).AddDate(0, 0, 1), true
+	case "MayDay":
+		return GetFirstWeekday(date(year, time.May, 1), time.Monday), true
+	case "SpringBank":
+		return GetLastWeekday(date(year, time.May, 31), time.Monday), true
+	case "AugustBankHolidayScotland":
+		return GetFirstWeekday(date(year, time.August, 1), time.Monday), true
+	case "LateSummerBankHolidayNotScotland":
+		return GetLastWeekday(date(year, time.August, 31), time.Monday), true
+	case "StAndrewsDay":
+		return date(year, time.November, 30), true
+	case "ChristmasEve":
+		return date(year, time.December, 24), true
+	case "ChristmasDay":
+		return date(year, time.December, 25), true
+	case "BoxingDay":
+		return date(year, time.December, 26), true
+	case "NewYearsEve":
+		return date(year, time.December, 31), true
+	case "ChristmasDayHoliday":
+		christmasDayHoliday, _ := getDisplacementHolidays(date(year, time.December, 25))
+		return christmasDayHoliday, !christmasDayHoliday.IsZero()
+	case "BoxingDayHoliday":
+		_, boxingDayHoliday := getDisplacementHolidays(date(year, time.December, 25))
+		return boxingDayHoliday, !boxingDayHoliday.IsZero()
+	case "NewYearsDayHoliday":
+		// Outside of Scotland there's no 2nd January holiday to displace so it's always the following Monday
+		newYearsDay := date(year, time.January, 1)
+		if newYearsDay.Weekday() == time.Saturday || newYearsDay.Weekday() == time.Sunday {
+			return GetFirstWeekday(newYearsDay, time.Monday), true
+		}
+		return time.Time{}, false
+	case "Jan2ndScotlandHoliday":
+		_, jan2ndHoliday := getDisplacementHolidays(date(year, time.January, 1))
+		return jan2ndHoliday, !jan2ndHoliday.IsZero()
+	case "StAndrewsDayHoliday":
+		stAndrewsDay := date(year, time.November, 30)
+		if stAndrewsDay.Weekday() == time.Saturday || stAndrewsDay.Weekday() == time.Sunday {
+			return GetFirstWeekday(stAndrewsDay, time.Monday), true
+		}
+		return time.Time{}, false
+	default:
+		return time.Time{}, false
+	}
+}
+
+// getDisplacementHolidays works out the substitute days for a pair of consecutive holidays (Christmas & Boxing Day, or
+// New Years Day & 2nd January) that land on a weekend. A zero time means that day doesn't need a substitute
+func getDisplacementHolidays(first time.Time) (time.Time, time.Time) {
+	var firstHoliday, secondHoliday time.Time
+
+	switch first.Weekday() {
+	case time.Friday:
+		// Second day is on Saturday
+		secondHoliday = first.AddDate(0, 0, 3)
+	case time.Saturday:
+		// Both days are on the weekend
+		firstHoliday = first.AddDate(0, 0, 2)
+		secondHoliday = first.AddDate(0, 0, 3)
+	case time.Sunday:
+		// Second day is already the Monday
+		firstHoliday = first.AddDate(0, 0, 2)
+	}
+
+	return firstHoliday, secondHoliday
+}
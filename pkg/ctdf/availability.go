@@ -1,6 +1,7 @@
 package ctdf
 
 import (
+	"slices"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ type Availability struct {
 	MatchSecondary []AvailabilityRule `groups:"basic,departureboard-cache"` // Must match at least one if exists
 	Condition      []AvailabilityRule `groups:"basic,departureboard-cache"` // Must match all
 	Exclude        []AvailabilityRule `groups:"basic,departureboard-cache"` // Must not match one
+	Include        []AvailabilityRule `groups:"basic,departureboard-cache"` // Overrides Match & MatchSecondary if any match (eg. added dates & special days)
 }
 
 func (availability *Availability) MatchDate(dateTime time.Time) bool {
@@ -19,6 +21,7 @@ func (availability *Availability) MatchDate(dateTime time.Time) bool {
 	matchSecondaryHit := false
 	conditionHit := true
 	excludeHit := false
+	includeHit := false
 
 	// Parse all the Match - if any are true then mark the matchHit as true
 	for _, rule := range availability.Match {
@@ -45,12 +48,99 @@ func (availability *Availability) MatchDate(dateTime time.Time) bool {
 		}
 	}
 
+	// Parse all the Include - if any are true then mark the includeHit as true
+	for _, rule := range availability.Include {
+		if checkRule(&rule, dateTime) {
+			includeHit = true
+		}
+	}
+
 	// If theres nothing in MatchSecondary then just set it as true
 	if len(availability.MatchSecondary) == 0 {
 		matchSecondaryHit = true
 	}
 
-	return matchHit && matchSecondaryHit && conditionHit && !excludeHit
+	return (includeHit || (matchHit && matchSecondaryHit)) && conditionHit && !excludeHit
+}
+
+// PossibleDaysOfWeek returns the days of the week the availability could match on. This is a superset, exclusions
+// aren't taken into account so MatchDate still needs to be used for a specific date
+func (availability *Availability) PossibleDaysOfWeek() []string {
+	days := map[string]bool{}
+	for _, day := range getRulesDaysOfWeek(availability.Match) {
+		days[day] = true
+	}
+
+	if len(availability.MatchSecondary) > 0 {
+		secondaryDays := getRulesDaysOfWeek(availability.MatchSecondary)
+
+		for day := range days {
+			if !slices.Contains(secondaryDays, day) {
+				delete(days, day)
+			}
+		}
+	}
+
+	for _, day := range getRulesDaysOfWeek(availability.Include) {
+		days[day] = true
+	}
+
+	for _, rule := range availability.Condition {
+		conditionDays := getRulesDaysOfWeek([]AvailabilityRule{rule})
+
+		for day := range days {
+			if !slices.Contains(conditionDays, day) {
+				delete(days, day)
+			}
+		}
+	}
+
+	var possibleDays []string
+	for _, day := range daysOfWeek {
+		if days[day] {
+			possibleDays = append(possibleDays, day)
+		}
+	}
+
+	return possibleDays
+}
+
+// getRulesDaysOfWeek returns the days of the week that any of the rules could match on
+func getRulesDaysOfWeek(rules []AvailabilityRule) []string {
+	var days []string
+
+	for _, rule := range rules {
+		switch rule.Type {
+		case AvailabilityDayOfWeek:
+			days = append(days, rule.Value)
+		case AvailabilityDate:
+			ruleDateTime, err := time.Parse(YearMonthDayFormat, rule.Value)
+			if err != nil {
+				return daysOfWeek
+			}
+
+			days = append(days, daysOfWeek[ruleDateTime.Weekday()])
+		case AvailabilityDateRange:
+			splitDateRange := strings.Split(rule.Value, ":")
+			if len(splitDateRange) != 2 {
+				return daysOfWeek
+			}
+
+			startDate, startErr := time.Parse(YearMonthDayFormat, splitDateRange[0])
+			endDate, endErr := time.Parse(YearMonthDayFormat, splitDateRange[1])
+			if startErr != nil || endErr != nil || endDate.Sub(startDate) >= 6*24*time.Hour {
+				return daysOfWeek
+			}
+
+			for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+				days = append(days, daysOfWeek[date.Weekday()])
+			}
+		default:
+			return daysOfWeek
+		}
+	}
+
+	return days
 }
 
 type AvailabilityRule struct {
@@ -62,10 +152,12 @@ type AvailabilityRule struct {
 type AvailabilityRecordType string
 
 const (
-	AvailabilityDayOfWeek AvailabilityRecordType = "DayOfWeek"
-	AvailabilityDate                             = "Date"
-	AvailabilityDateRange                        = "DateRange"
-	AvailabilityMatchAll                         = "MatchAll"
+	AvailabilityDayOfWeek     AvailabilityRecordType = "DayOfWeek"
+	AvailabilityDate                                 = "Date"
+	AvailabilityDateRange                            = "DateRange"
+	AvailabilityMatchAll                             = "MatchAll"
+	AvailabilitySchoolTerm                           = "SchoolTerm"    // Value is a SchoolTermCalendar reference
+	AvailabilitySchoolHoliday                        = "SchoolHoliday" // Value is a SchoolTermCalendar reference
 )
 
 const YearMonthDayFormat = "2006-01-02"
@@ -105,6 +197,8 @@ func checkRule(rule *AvailabilityRule, dateTime time.Time) bool {
 		return (dateTime.After(startDate) && dateTime.Before(endDate)) || datesMatch(startDate, dateTime) || datesMatch(endDate, dateTime)
 	case AvailabilityMatchAll:
 		return true
+	case AvailabilitySchoolTerm, AvailabilitySchoolHoliday:
+		return checkSchoolTermRule(rule, dateTime)
 	default:
 		log.Error().Msgf("Cannot parse rule type %s", rule.Type)
 		return false
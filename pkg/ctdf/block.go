@@ -0,0 +1,92 @@
+package ctdf
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const BlockIDFormat = "%s-block-%s"
+
+// Block is a vehicle working - the sequence of journeys operated one after another by the same vehicle
+type Block struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	BlockNumber string `groups:"basic"`
+	OperatorRef string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	// Ordered by departure day offset & time
+	Journeys []*BlockJourney `groups:"basic"`
+}
+
+type BlockJourney struct {
+	JourneyRef    string    `groups:"basic"`
+	DepartureTime time.Time `groups:"basic"`
+	// Days after the service day the journey departs, for journeys departing after midnight
+	DepartureDayOffset int `groups:"basic" bson:",omitempty"`
+
+	Availability *Availability `groups:"internal"`
+}
+
+// GetDepartureDateTime is when the journey departs on the given service day
+func (b *BlockJourney) GetDepartureDateTime(serviceDay time.Time) time.Time {
+	return getServiceDayDateTime(serviceDay, b.DepartureTime, b.DepartureDayOffset)
+}
+
+// Before checks if the journey departs earlier in the service day than the other one
+func (b *BlockJourney) Before(other *BlockJourney) bool {
+	if b.DepartureDayOffset != other.DepartureDayOffset {
+		return b.DepartureDayOffset < other.DepartureDayOffset
+	}
+
+	return b.DepartureTime.Hour()*3600+b.DepartureTime.Minute()*60+b.DepartureTime.Second() <
+		other.DepartureTime.Hour()*3600+other.DepartureTime.Minute()*60+other.DepartureTime.Second()
+}
+
+// GetNextJourney finds the journey in the block that runs after journeyRef on the given date
+func (b *Block) GetNextJourney(journeyRef string, date time.Time) *BlockJourney {
+	found := false
+
+	for _, blockJourney := range b.Journeys {
+		if blockJourney.JourneyRef == journeyRef {
+			found = true
+			continue
+		}
+
+		if found && (blockJourney.Availability == nil || blockJourney.Availability.MatchDate(date)) {
+			return blockJourney
+		}
+	}
+
+	return nil
+}
+
+// GetJourneyBlock returns the block a journey is part of, or nil if it isn't in one
+func GetJourneyBlock(journeyRef string) *Block {
+	var journey struct {
+		BlockRef string
+	}
+	journeysCollection := database.GetCollection("journeys")
+	opts := options.FindOne().SetProjection(bson.D{{Key: "blockref", Value: 1}})
+	err := journeysCollection.FindOne(context.Background(), bson.M{"primaryidentifier": journeyRef}, opts).Decode(&journey)
+	if err != nil || journey.BlockRef == "" {
+		return nil
+	}
+
+	var block *Block
+	blocksCollection := database.GetCollection("blocks")
+	err = blocksCollection.FindOne(context.Background(), bson.M{"primaryidentifier": journey.BlockRef}).Decode(&block)
+	if err != nil {
+		return nil
+	}
+
+	return block
+}
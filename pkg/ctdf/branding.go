@@ -0,0 +1,41 @@
+package ctdf
+
+import (
+	"regexp"
+	"strings"
+)
+
+var hexColourRegex = regexp.MustCompile("^[0-9a-fA-F]{6}$")
+
+// Branding is the visual identity of a Service or Operator so clients can render them in their own colours
+type Branding struct {
+	Colour     string `groups:"basic,search" bson:",omitempty"`
+	TextColour string `groups:"basic,search" bson:",omitempty"`
+	LogoURL    string `groups:"basic,search" bson:",omitempty"`
+}
+
+// NewBranding builds a Branding from colours as feeds supply them, returning nil if there is nothing usable
+func NewBranding(colour string, textColour string, logoURL string) *Branding {
+	branding := &Branding{
+		Colour:     NormaliseColour(colour),
+		TextColour: NormaliseColour(textColour),
+		LogoURL:    strings.TrimSpace(logoURL),
+	}
+
+	if branding.Colour == "" && branding.TextColour == "" && branding.LogoURL == "" {
+		return nil
+	}
+
+	return branding
+}
+
+// NormaliseColour converts the various ways feeds write hex colours into a lowercase #rrggbb, or empty if it isn't one
+func NormaliseColour(colour string) string {
+	colour = strings.TrimPrefix(strings.TrimSpace(colour), "#")
+
+	if !hexColourRegex.MatchString(colour) {
+		return ""
+	}
+
+	return "#" + strings.ToLower(colour)
+}
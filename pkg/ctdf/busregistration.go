@@ -0,0 +1,83 @@
+package ctdf
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const BusRegistrationIDFormat = "gb-busregistration-%s"
+
+type BusRegistrationServiceLinkMethod string
+
+const (
+	BusRegistrationServiceLinkCode    BusRegistrationServiceLinkMethod = "code"
+	BusRegistrationServiceLinkLicence                                  = "licence"
+)
+
+// Registration numbers are the operators licence number & a route number, eg. PB0000582/123
+var busRegistrationNumberRegex = regexp.MustCompile(`^([A-Z]{2}\d{7})[/:](\d+)$`)
+
+// BusRegistration is a local bus service registered with the Office of the Traffic Commissioner.
+// Only the latest variation of each registration is kept.
+type BusRegistration struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	RegistrationNumber string `groups:"basic"`
+	VariationNumber    int    `groups:"basic"`
+	Status             string `groups:"basic"`
+
+	LicenceNumber string `groups:"basic"`
+	OperatorName  string `groups:"basic"`
+	TradingName   string `groups:"basic" bson:",omitempty"`
+	TrafficArea   string `groups:"detailed" bson:",omitempty"`
+
+	ServiceNumbers []string `groups:"basic"`
+	ServiceType    string   `groups:"detailed" bson:",omitempty"`
+	StartPoint     string   `groups:"basic" bson:",omitempty"`
+	FinishPoint    string   `groups:"basic" bson:",omitempty"`
+	Via            string   `groups:"detailed" bson:",omitempty"`
+
+	ReceivedDate  time.Time `groups:"detailed" bson:",omitempty"`
+	EffectiveDate time.Time `groups:"basic" bson:",omitempty"`
+	EndDate       time.Time `groups:"basic" bson:",omitempty"`
+
+	// Set by the bus registrations linker
+	OperatorRef       string                           `groups:"basic" bson:",omitempty"`
+	ServiceRefs       []string                         `groups:"basic" bson:",omitempty"`
+	ServiceLinkMethod BusRegistrationServiceLinkMethod `groups:"internal" bson:",omitempty"`
+}
+
+// IsCurrent is whether the registration allows the service to run on the date, cancelled & refused ones never do
+func (r *BusRegistration) IsCurrent(date time.Time) bool {
+	status := strings.ToLower(r.Status)
+	if status != "registered" && status != "variation" {
+		return false
+	}
+
+	if !r.EffectiveDate.IsZero() && r.EffectiveDate.After(date) {
+		return false
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(date) {
+		return false
+	}
+
+	return true
+}
+
+// GetBusRegistrationIdentifier turns a registration number into its identifier, or an empty string if it isn't one.
+// The TransXChange service codes of registered services are the registration number with a colon rather than a slash.
+func GetBusRegistrationIdentifier(registrationNumber string) string {
+	match := busRegistrationNumberRegex.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(registrationNumber)))
+	if match == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(BusRegistrationIDFormat, fmt.Sprintf("%s:%s", match[1], match[2]))
+}
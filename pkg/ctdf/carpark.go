@@ -0,0 +1,39 @@
+package ctdf
+
+import "time"
+
+const CarParkIDFormat = "%s-carpark-%s"
+
+// CarPark is somewhere to leave a car, park & ride sites also link the stops that serve them
+type CarPark struct {
+	PrimaryIdentifier string   `groups:"basic"`
+	OtherIdentifiers  []string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	Name     string    `groups:"basic"`
+	Location *Location `groups:"basic"`
+
+	ParkAndRide bool     `groups:"basic"`
+	StopRefs    []string `groups:"basic" bson:",omitempty"`
+
+	Capacity  *CarParkCapacity  `groups:"basic" bson:",omitempty"`
+	Occupancy *CarParkOccupancy `groups:"basic" bson:",omitempty"`
+}
+
+// CarParkCapacity is the number of spaces, 0 where the source doesn't break them down
+type CarParkCapacity struct {
+	Spaces         int `groups:"basic"`
+	DisabledSpaces int `groups:"basic"`
+}
+
+// CarParkOccupancy is the latest realtime count of spaces in use
+type CarParkOccupancy struct {
+	OccupiedSpaces int `groups:"basic"`
+	FreeSpaces     int `groups:"basic"`
+
+	RecordedAt time.Time `groups:"basic"`
+}
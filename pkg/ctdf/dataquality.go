@@ -0,0 +1,85 @@
+package ctdf
+
+import (
+	"fmt"
+	"time"
+)
+
+type DataQualitySubjectType string
+
+const (
+	DataQualitySubjectOperator DataQualitySubjectType = "Operator"
+	DataQualitySubjectDataSet                         = "DataSet"
+)
+
+// DataQualityScore rates how complete the data for an operator or dataset is on a given date.
+// Each rate is between 0 & 1 and the overall Score is the average of them.
+type DataQualityScore struct {
+	PrimaryIdentifier string `groups:"internal"`
+
+	SubjectType DataQualitySubjectType `groups:"basic"`
+	SubjectRef  string                 `groups:"basic"`
+	Date        time.Time              `groups:"basic"`
+
+	Score float64 `groups:"basic"`
+
+	GeometryCoverage   float64 `groups:"basic"`
+	RealtimeCoverage   float64 `groups:"basic"`
+	StopResolutionRate float64 `groups:"basic"`
+	PlausibleSpeedRate float64 `groups:"basic"`
+
+	Journeys               int `groups:"detailed"`
+	JourneysWithGeometry   int `groups:"detailed"`
+	ScheduledJourneys      int `groups:"detailed"`
+	TrackedJourneys        int `groups:"detailed"`
+	StopReferences         int `groups:"detailed"`
+	ResolvedStopReferences int `groups:"detailed"`
+	PathLegs               int `groups:"detailed"`
+	ImplausibleSpeedLegs   int `groups:"detailed"`
+	InactiveStopReferences int `groups:"detailed"`
+
+	// Warnings are problems worth looking into that don't count towards the score
+	Warnings []*DataQualityWarning `groups:"detailed" bson:",omitempty"`
+
+	ModificationDateTime time.Time `groups:"detailed"`
+}
+
+type DataQualityWarning struct {
+	Message string `groups:"basic"`
+	// Refs of the records the warning is about, eg. stops
+	Refs []string `groups:"basic" bson:",omitempty"`
+}
+
+func (score *DataQualityScore) AddWarning(message string, refs []string) {
+	score.Warnings = append(score.Warnings, &DataQualityWarning{
+		Message: message,
+		Refs:    refs,
+	})
+}
+
+func NewDataQualityScore(subjectType DataQualitySubjectType, subjectRef string, date time.Time) *DataQualityScore {
+	return &DataQualityScore{
+		PrimaryIdentifier: fmt.Sprintf("%s:%s:%s", date.Format(time.DateOnly), subjectType, subjectRef),
+		SubjectType:       subjectType,
+		SubjectRef:        subjectRef,
+		Date:              date,
+	}
+}
+
+// CalculateScore works out the rates from the counts
+func (score *DataQualityScore) CalculateScore() {
+	score.GeometryCoverage = qualityRate(score.JourneysWithGeometry, score.Journeys)
+	score.RealtimeCoverage = qualityRate(score.TrackedJourneys, score.ScheduledJourneys)
+	score.StopResolutionRate = qualityRate(score.ResolvedStopReferences, score.StopReferences)
+	score.PlausibleSpeedRate = qualityRate(score.PathLegs-score.ImplausibleSpeedLegs, score.PathLegs)
+
+	score.Score = (score.GeometryCoverage + score.RealtimeCoverage + score.StopResolutionRate + score.PlausibleSpeedRate) / 4
+}
+
+func qualityRate(count int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(count) / float64(total)
+}
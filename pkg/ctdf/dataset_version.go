@@ -7,4 +7,8 @@ type DatasetVersion struct {
 	Hash         string
 	ETag         string
 	LastModified time.Time
+
+	// Window the datasets timetable is valid for, zero where it's open ended
+	ValidFrom  time.Time
+	ValidUntil time.Time
 }
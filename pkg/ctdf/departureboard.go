@@ -15,6 +15,7 @@ import (
 type DepartureBoard struct {
 	Journey            *Journey                 `groups:"basic,departures-llm"`
 	DestinationDisplay string                   `groups:"basic,departures-llm"`
+	DestinationVia     []string                 `groups:"basic,departures-llm" json:",omitempty"`
 	Type               DepartureBoardRecordType `groups:"basic,departures-llm"`
 
 	Platform     string `groups:"basic,departures-llm"`
@@ -32,12 +33,14 @@ const (
 	DepartureBoardRecordTypeCancelled                                = "Cancelled"
 )
 
-func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string, dateTime time.Time, doEstimates bool) []*DepartureBoard {
+// GenerateDepartureBoardFromJourneys builds the departures from dateTime onwards of the journeys running on the service day.
+// The service day is normally the same day as dateTime but can be earlier for journeys that run past midnight
+func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string, serviceDay time.Time, dateTime time.Time, doEstimates bool) []*DepartureBoard {
 	journeysCollection := database.GetCollection("journeys")
 	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
 	realtimeActiveCutoffDate := GetActiveRealtimeJourneyCutOffDate()
 
-	realtimeJourneyOptions := options.FindOne().SetProjection(bson.D{
+	realtimeJourneyOptions := options.Find().SetProjection(bson.D{
 		bson.E{Key: "activelytracked", Value: 1},
 		bson.E{Key: "modificationdatetime", Value: 1},
 		bson.E{Key: "timeoutdurationminutes", Value: 1},
@@ -47,12 +50,22 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 		// bson.E{Key: "stops.*.departuretime", Value: 1},
 		bson.E{Key: "cancelled", Value: 1},
 		bson.E{Key: "vehiclelocation", Value: 1},
+		bson.E{Key: "journey.primaryidentifier", Value: 1},
 		bson.E{Key: "journey.path.destinationstopref", Value: 1},
 		bson.E{Key: "journey.path.destinationarrivaltime", Value: 1},
 	})
 
 	journeys = FilterIdenticalJourneys(journeys, true)
 
+	// Fetch the realtime journeys for everything running today up front rather than one query per journey
+	var runningJourneys []*Journey
+	for _, journey := range journeys {
+		if journey.RunsOn(serviceDay) {
+			runningJourneys = append(runningJourneys, journey)
+		}
+	}
+	AttachRealtimeJourneys(runningJourneys, realtimeJourneyOptions)
+
 	p := pool.NewWithResults[*DepartureBoard]()
 	p.WithMaxGoroutines(200)
 
@@ -62,16 +75,14 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 			var stopPlatform string
 			var stopPlatformType string
 			var destinationDisplay string
+			var destinationVia []string
 			departureBoardRecordType := DepartureBoardRecordTypeScheduled
 
-			if journey.Availability.MatchDate(dateTime) {
+			if journey.RunsOn(serviceDay) {
 				// Don't even think about it if we're passed 4 hours departure on this stop
 				for _, path := range journey.Path {
 					if slices.Contains(stopRefs, path.OriginStopRef) {
-						journeyDepMins := (path.OriginDepartureTime.Hour() * 60) + path.OriginDepartureTime.Minute()
-						startMins := (dateTime.Hour() * 60) + dateTime.Minute()
-
-						if (startMins - journeyDepMins) > 240 {
+						if dateTime.Sub(path.GetOriginDepartureDateTime(serviceDay)) > 240*time.Minute {
 							return nil
 						}
 
@@ -79,8 +90,6 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 					}
 				}
 
-				journey.GetRealtimeJourney(realtimeJourneyOptions)
-
 				for _, path := range journey.Path {
 					if slices.Contains(stopRefs, path.OriginStopRef) {
 						refTime := path.OriginDepartureTime
@@ -134,11 +143,10 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 							departureBoardRecordType = DepartureBoardRecordTypeCancelled
 						}
 
-						stopDepartureTime = time.Date(
-							dateTime.Year(), dateTime.Month(), dateTime.Day(), refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), dateTime.Location(),
-						)
+						stopDepartureTime = getServiceDayDateTime(serviceDay, refTime, path.OriginDepartureDayOffset)
 
 						destinationDisplay = path.DestinationDisplay
+						destinationVia = path.DestinationVia
 						break
 					}
 				}
@@ -213,6 +221,7 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 					Journey:            journey,
 					Time:               stopDepartureTime,
 					DestinationDisplay: destinationDisplay,
+					DestinationVia:     destinationVia,
 					Type:               departureBoardRecordType,
 					Platform:           stopPlatform,
 					PlatformType:       stopPlatformType,
@@ -234,3 +243,19 @@ func GenerateDepartureBoardFromJourneys(journeys []*Journey, stopRefs []string,
 
 	return departureBoard
 }
+
+// FilterDepartureBoardByTransportTypes removes any departures not of the given transport types, an empty list matches everything
+func FilterDepartureBoardByTransportTypes(departureBoard []*DepartureBoard, transportTypes []TransportType) []*DepartureBoard {
+	if len(transportTypes) == 0 {
+		return departureBoard
+	}
+
+	var filteredDepartureBoard []*DepartureBoard
+	for _, departure := range departureBoard {
+		if MatchesTransportTypes(transportTypes, departure.Journey.GetTransportType()) {
+			filteredDepartureBoard = append(filteredDepartureBoard, departure)
+		}
+	}
+
+	return filteredDepartureBoard
+}
@@ -0,0 +1,139 @@
+package ctdf
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/liip/sheriff"
+)
+
+// DeparturesLLMOptions controls how much is included when rendering for the departures LLM
+type DeparturesLLMOptions struct {
+	// Include the rolling stock, facilities & formation of rail journeys
+	DetailedRailInformation bool
+}
+
+// Keys that get repeated on every departure are shortened to keep the payload small
+var departuresLLMKeys = map[string]string{
+	"Journey":                    "journey",
+	"Service":                    "service",
+	"Operator":                   "operator",
+	"Path":                       "path",
+	"Stops":                      "stops",
+	"Time":                       "time",
+	"Type":                       "type",
+	"PrimaryIdentifier":          "id",
+	"PrimaryName":                "name",
+	"ServiceName":                "name",
+	"TransportType":              "mode",
+	"DestinationDisplay":         "dest",
+	"DestinationVia":             "via",
+	"DepartureTime":              "dep",
+	"ArrivalTime":                "arr",
+	"OriginDepartureTime":        "dep",
+	"DestinationArrivalTime":     "arr",
+	"Platform":                   "plat",
+	"PlatformType":               "platType",
+	"OriginStopRef":              "from",
+	"DestinationStopRef":         "to",
+	"OriginStop":                 "fromStop",
+	"DestinationStop":            "toStop",
+	"OriginPlatform":             "fromPlat",
+	"DestinationPlatform":        "toPlat",
+	"RealtimeJourney":            "realtime",
+	"DetailedRailInformation":    "rail",
+	"VehicleLocationDescription": "location",
+	"DepartedStopRef":            "departed",
+	"NextStopRef":                "next",
+	"Cancelled":                  "cancelled",
+	"Occupancy":                  "occupancy",
+	"OccupancyAvailable":         "available",
+	"TotalPercentageOccupancy":   "pct",
+	"AlertType":                  "type",
+	"Title":                      "title",
+	"Text":                       "text",
+	"ActivePeriods":              "active",
+	"DisruptionPeriods":          "disruption",
+	"From":                       "from",
+	"Until":                      "until",
+}
+
+// RenderDeparturesLLM reduces the value to the departures-llm group and compacts it, shortening the keys and
+// pruning anything empty. False booleans are pruned too so a missing flag should be read as false
+func RenderDeparturesLLM(value interface{}, options DeparturesLLMOptions) (interface{}, error) {
+	groups := []string{"departures-llm"}
+	if options.DetailedRailInformation {
+		groups = append(groups, "departures-llm-rail")
+	}
+
+	reduced, err := sheriff.Marshal(&sheriff.Options{
+		Groups: groups,
+	}, value)
+	if err != nil {
+		return nil, err
+	}
+
+	compacted, _ := compactDeparturesLLM(reduced)
+
+	return compacted, nil
+}
+
+// compactDeparturesLLM returns the compacted value & whether it should be kept
+func compactDeparturesLLM(value interface{}) (interface{}, bool) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		compacted := map[string]interface{}{}
+		for key, element := range typedValue {
+			compactedElement, keep := compactDeparturesLLM(element)
+			if !keep {
+				continue
+			}
+
+			// Maps like the realtime stops are keyed by identifiers so only ever match here for struct fields
+			if shortKey, exists := departuresLLMKeys[key]; exists {
+				key = shortKey
+			}
+			compacted[key] = compactedElement
+		}
+
+		// Occupancy figures are all zero when there isn't any so the whole thing goes
+		if _, isOccupancy := typedValue["OccupancyAvailable"]; isOccupancy && compacted["available"] == nil {
+			return nil, false
+		}
+
+		return compacted, len(compacted) > 0
+	case []interface{}:
+		compacted := []interface{}{}
+		for _, element := range typedValue {
+			if compactedElement, keep := compactDeparturesLLM(element); keep {
+				compacted = append(compacted, compactedElement)
+			}
+		}
+
+		return compacted, len(compacted) > 0
+	case string:
+		return typedValue, typedValue != ""
+	case bool:
+		return typedValue, typedValue
+	case time.Time:
+		if typedValue.IsZero() {
+			return nil, false
+		}
+
+		// Path times only have a time of day
+		if typedValue.Year() == 0 {
+			return typedValue.Format("15:04"), true
+		}
+
+		return typedValue.Format("2006-01-02T15:04Z07:00"), true
+	default:
+		// Typed strings like the transport type come back as they are
+		if reflectedValue := reflect.ValueOf(value); reflectedValue.Kind() == reflect.String {
+			return reflectedValue.String(), reflectedValue.Len() > 0
+		}
+
+		return typedValue, true
+	}
+}
@@ -0,0 +1,43 @@
+package ctdf
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DockAvailability is the realtime state of a bike or scooter share dock, kept separate from the Stop
+// as it changes every minute or so
+type DockAvailability struct {
+	StopRef string `groups:"basic"`
+
+	VehiclesAvailable int `groups:"basic"`
+	DocksAvailable    int `groups:"basic"`
+	// Number of each type of vehicle available, keyed on the transport type
+	VehicleTypesAvailable map[TransportType]int `groups:"basic" bson:",omitempty"`
+
+	IsInstalled bool `groups:"basic"`
+	IsRenting   bool `groups:"basic"`
+	IsReturning bool `groups:"basic"`
+
+	LastReported         time.Time `groups:"basic"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+}
+
+// GetDockAvailability returns the latest availability of the dock, nil if it isn't a dock or nothing has been reported
+func (stop *Stop) GetDockAvailability() *DockAvailability {
+	if stop.StopType != StopTypeDock {
+		return nil
+	}
+
+	var availability *DockAvailability
+
+	collection := database.GetCollection("dock_availabilities")
+	collection.FindOne(context.Background(), bson.M{"stopref": bson.M{"$in": stop.GetAllStopIDs()}}).Decode(&availability)
+
+	return availability
+}
@@ -14,6 +14,8 @@ type EventType string
 
 const (
 	EventTypeServiceAlertCreated EventType = "ServiceAlertCreated"
+	EventTypeServiceAlertUpdated           = "ServiceAlertUpdated"
+	EventTypeServiceAlertExpired           = "ServiceAlertExpired"
 
 	EventTypeRealtimeJourneyCreated             = "RealtimeJourneyCreated"
 	EventTypeRealtimeJourneyActivelyTracked     = "RealtimeJourneyActivelyTracked"
@@ -22,6 +24,13 @@ const (
 	EventTypeRealtimeJourneyCancelled           = "RealtimeJourneyCancelled"
 	EventTypeRealtimeJourneyLocationTextChanged = "RealtimeJourneyLocationTextChanged"
 	EventTypeRealtimeJourneyNextStopChanged     = "RealtimeJourneyNextStopChanged"
+	EventTypeRealtimeJourneyGeofenceEntered     = "RealtimeJourneyGeofenceEntered"
+	EventTypeRealtimeJourneyGeofenceExited      = "RealtimeJourneyGeofenceExited"
+
+	EventTypeOperatorTrackingRateLow = "OperatorTrackingRateLow"
+	EventTypeRealtimeFeedSilent      = "RealtimeFeedSilent"
+
+	EventTypeTimetableChanged = "TimetableChanged"
 )
 
 type EventNotificationData struct {
@@ -0,0 +1,60 @@
+package ctdf
+
+import "time"
+
+// Geofence is a registered area that the vehicle tracker emits events for as tracked vehicles enter & exit it
+type Geofence struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	Name        string `groups:"basic"`
+	Description string `groups:"basic"`
+
+	// Polygon is a single ring of [longitude, latitude] points, the same as a GeoJSON Polygon without holes
+	Polygon [][]float64 `groups:"basic"`
+}
+
+// GeofenceEvent is the body of the entered & exited events
+type GeofenceEvent struct {
+	GeofenceRef  string
+	GeofenceName string
+
+	RealtimeJourneyRef string
+	JourneyRef         string
+	ServiceRef         string
+	OperatorRef        string
+	VehicleRef         string
+
+	Location Location
+}
+
+// Contains checks if the location is inside the polygon by counting how many edges a ray from it crosses
+func (geofence *Geofence) Contains(location *Location) bool {
+	if location == nil || len(location.Coordinates) != 2 || len(geofence.Polygon) < 3 {
+		return false
+	}
+
+	x := location.Coordinates[0]
+	y := location.Coordinates[1]
+
+	inside := false
+	j := len(geofence.Polygon) - 1
+	for i := 0; i < len(geofence.Polygon); i++ {
+		if len(geofence.Polygon[i]) < 2 || len(geofence.Polygon[j]) < 2 {
+			return false
+		}
+
+		xi, yi := geofence.Polygon[i][0], geofence.Polygon[i][1]
+		xj, yj := geofence.Polygon[j][0], geofence.Polygon[j][1]
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+
+		j = i
+	}
+
+	return inside
+}
@@ -5,10 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/identifiers"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -28,12 +31,19 @@ type Journey struct {
 
 	DataSource *DataSourceReference `groups:"detailed" bson:",omitempty"`
 
+	// Stored so imports can match up journeys whose primary identifier changed between releases of the source
+	FunctionalHash string `groups:"internal" bson:",omitempty"`
+
 	ServiceRef string   `groups:"internal,departureboard-cache" bson:",omitempty"`
 	Service    *Service `groups:"basic,departures-llm" json:",omitempty" bson:"-"`
 
 	OperatorRef string    `groups:"internal,departureboard-cache" bson:",omitempty"`
 	Operator    *Operator `groups:"basic,departures-llm" json:",omitempty" bson:"-"`
 
+	BlockRef string `groups:"internal" bson:",omitempty"`
+
+	TransportType TransportType `groups:"basic,departureboard-cache" bson:",omitempty"`
+
 	Direction         string    `groups:"detailed" json:",omitempty" bson:",omitempty"`
 	DepartureTime     time.Time `groups:"basic,departures-llm,departureboard-cache" bson:",omitempty"`
 	DepartureTimezone string    `groups:"basic,departureboard-cache" bson:",omitempty"`
@@ -41,15 +51,22 @@ type Journey struct {
 	Track []Location `groups:"detailed" bson:",omitempty"`
 
 	DestinationDisplay string `groups:"basic,departures-llm,departureboard-cache" bson:",omitempty"`
+	// Places the journey goes via on the way, taken off the end of the destination display
+	DestinationVia []string `groups:"basic,departures-llm,departureboard-cache" bson:",omitempty"`
 
 	Availability *Availability `groups:"internal,departureboard-cache" bson:",omitempty"`
 
-	Path []*JourneyPathItem `groups:"detailed,departureboard-cache" bson:",omitempty"`
+	// Dates the source timetable is valid between, the journey never runs outside them whatever its availability says.
+	// Either can be zero for an open ended window
+	ValidFrom  time.Time `groups:"detailed,departureboard-cache" bson:",omitempty"`
+	ValidUntil time.Time `groups:"detailed,departureboard-cache" bson:",omitempty"`
 
-	RealtimeJourney *RealtimeJourney `groups:"basic" bson:"-" bson:",omitempty"`
+	Path []*JourneyPathItem `groups:"detailed,departures-llm,departureboard-cache" bson:",omitempty"`
+
+	RealtimeJourney *RealtimeJourney `groups:"basic,departures-llm" bson:"-" bson:",omitempty"`
 
 	// Detailed journey information
-	DetailedRailInformation *JourneyDetailedRail `groups:"detailed" bson:",omitempty"`
+	DetailedRailInformation *JourneyDetailedRail `groups:"detailed,departures-llm-rail" bson:",omitempty"`
 }
 
 func (j *Journey) GetReferences() {
@@ -62,9 +79,24 @@ func (j *Journey) GetOperator() {
 	}
 
 	operatorsCollection := database.GetCollection("operators")
-	query := bson.M{"$or": bson.A{bson.M{"primaryidentifier": j.OperatorRef}, bson.M{"otheridentifiers": j.OperatorRef}}}
-	operatorsCollection.FindOne(context.Background(), query).Decode(&j.Operator)
+	operatorsCollection.FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeOperator, j.OperatorRef)).Decode(&j.Operator)
+}
+
+// GetTransportType returns the journeys transport type, falling back to its services for journeys imported
+// before it was stored on them
+func (j *Journey) GetTransportType() TransportType {
+	if j.TransportType != "" {
+		return j.TransportType
+	}
+
+	j.GetService()
+	if j.Service != nil && j.Service.TransportType != "" {
+		return j.Service.TransportType
+	}
+
+	return TransportTypeUnknown
 }
+
 func (j *Journey) GetService() {
 	if j.Service != nil {
 		return
@@ -101,6 +133,83 @@ func (j *Journey) GetRealtimeJourney(opts *options.FindOneOptions) {
 		j.RealtimeJourney = realtimeJourney
 	}
 }
+
+// GetRealtimeJourneysForJourneys fetches the active realtime journeys for all the journey IDs in a single query,
+// returning them keyed by journey ID. The projection must include journey.primaryidentifier
+func GetRealtimeJourneysForJourneys(journeyIDs []string, opts *options.FindOptions) map[string]*RealtimeJourney {
+	realtimeJourneys := map[string]*RealtimeJourney{}
+	if len(journeyIDs) == 0 {
+		return realtimeJourneys
+	}
+
+	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+
+	cursor, err := realtimeJourneysCollection.Find(context.Background(), bson.M{
+		"journey.primaryidentifier": bson.M{"$in": journeyIDs},
+		"modificationdatetime":      bson.M{"$gt": GetActiveRealtimeJourneyCutOffDate()},
+	}, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query Realtime Journeys")
+		return realtimeJourneys
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var realtimeJourney RealtimeJourney
+		if err := cursor.Decode(&realtimeJourney); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Realtime Journey")
+			continue
+		}
+
+		if realtimeJourney.Journey == nil || !realtimeJourney.IsActive() {
+			continue
+		}
+
+		// Prefer the most recently updated if a journey has multiple realtime journeys
+		existing := realtimeJourneys[realtimeJourney.Journey.PrimaryIdentifier]
+		if existing == nil || realtimeJourney.ModificationDateTime.After(existing.ModificationDateTime) {
+			realtimeJourneys[realtimeJourney.Journey.PrimaryIdentifier] = &realtimeJourney
+		}
+	}
+
+	return realtimeJourneys
+}
+
+// AttachRealtimeJourneys sets the active realtime journey on each of the journeys using a single query
+func AttachRealtimeJourneys(journeys []*Journey, opts *options.FindOptions) {
+	var journeyIDs []string
+	for _, journey := range journeys {
+		journeyIDs = append(journeyIDs, journey.PrimaryIdentifier)
+	}
+
+	realtimeJourneys := GetRealtimeJourneysForJourneys(journeyIDs, opts)
+
+	for _, journey := range journeys {
+		if realtimeJourney, exists := realtimeJourneys[journey.PrimaryIdentifier]; exists {
+			journey.RealtimeJourney = realtimeJourney
+		}
+	}
+}
+
+// IsValidOn checks the date falls within the journeys validity window, both ends are inclusive
+func (j *Journey) IsValidOn(dateTime time.Time) bool {
+	date := dateTime.Format(YearMonthDayFormat)
+
+	if !j.ValidFrom.IsZero() && date < j.ValidFrom.Format(YearMonthDayFormat) {
+		return false
+	}
+	if !j.ValidUntil.IsZero() && date > j.ValidUntil.Format(YearMonthDayFormat) {
+		return false
+	}
+
+	return true
+}
+
+// RunsOn checks the journey is both within its validity window & available on the date
+func (j *Journey) RunsOn(dateTime time.Time) bool {
+	return j.IsValidOn(dateTime) && j.Availability != nil && j.Availability.MatchDate(dateTime)
+}
+
 func (j Journey) MarshalBinary() ([]byte, error) {
 	return json.Marshal(j)
 }
@@ -109,15 +218,21 @@ func (j *Journey) GenerateFunctionalHash(includeAvailabilityCondition bool) stri
 
 	hash.Write([]byte(j.ServiceRef))
 	hash.Write([]byte(j.DestinationDisplay))
+	hash.Write([]byte(strings.Join(j.DestinationVia, ",")))
 	hash.Write([]byte(j.Direction))
-	hash.Write([]byte(j.DepartureTime.String()))
+	// Times are normalised to UTC so the hash is the same before and after a round trip through the database
+	hash.Write([]byte(j.DepartureTime.UTC().String()))
 
-	// TODO: REVERT THE CHAGES TO THIS LINE
-	// BUT THINK ABOUT IT - WE SHOULD ALWAYS IGNORE AVAILABILITY CONDITIONS WHEN FINDING IDENTICAL JOURNEYS
-	// IF WE FILTER OUT BASED ON BEING AVAILABLE TODAY THEN WE SHOULDNT CARE ABOUT THE SPECIFICS OF THE CONDITIONS???
-	if includeAvailabilityCondition {
+	// Trips with the same timings can still be separate workings so what identifies the working is included when known
+	if j.BlockRef != "" || j.OtherIdentifiers["BlockNumber"] != "" || j.OtherIdentifiers["TicketMachineJourneyCode"] != "" {
+		hash.Write([]byte(fmt.Sprintf("%s:%s:%s", j.BlockRef, j.OtherIdentifiers["BlockNumber"], j.OtherIdentifiers["TicketMachineJourneyCode"])))
+	}
+
+	// Journeys imported before availability was recorded have none
+	if includeAvailabilityCondition && j.Availability != nil {
 		rules := append(j.Availability.Match, j.Availability.MatchSecondary...)
 		rules = append(rules, j.Availability.Exclude...)
+		rules = append(rules, j.Availability.Include...)
 
 		rules = append(rules, j.Availability.Condition...)
 
@@ -130,14 +245,36 @@ func (j *Journey) GenerateFunctionalHash(includeAvailabilityCondition bool) stri
 
 	for _, pathItem := range j.Path {
 		hash.Write([]byte(pathItem.OriginStopRef))
-		hash.Write([]byte(pathItem.OriginArrivalTime.GoString()))
-		hash.Write([]byte(pathItem.OriginDepartureTime.GoString()))
+		hash.Write([]byte(pathItem.OriginArrivalTime.UTC().GoString()))
+		hash.Write([]byte(pathItem.OriginDepartureTime.UTC().GoString()))
 		hash.Write([]byte(pathItem.DestinationStopRef))
-		hash.Write([]byte(pathItem.DestinationArrivalTime.GoString()))
+		hash.Write([]byte(pathItem.DestinationArrivalTime.UTC().GoString()))
+
+		// Only past midnight items include their offsets so the hash of every other journey is unchanged
+		if pathItem.OriginArrivalDayOffset != 0 || pathItem.OriginDepartureDayOffset != 0 || pathItem.DestinationArrivalDayOffset != 0 {
+			hash.Write([]byte(fmt.Sprintf("%d:%d:%d", pathItem.OriginArrivalDayOffset, pathItem.OriginDepartureDayOffset, pathItem.DestinationArrivalDayOffset)))
+		}
 	}
 
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
+
+// GetUpsertFilter sets the journeys functional hash and returns the filter that matches the same journey in its
+// dataset by that hash, so re-imports where the source changed its identifiers update the journey rather than duplicate
+// it. Separate trips of one import can share a hash, so a match from the same import must also be the same journey.
+func (j *Journey) GetUpsertFilter() bson.M {
+	j.FunctionalHash = j.GenerateFunctionalHash(true)
+
+	return bson.M{
+		"datasource.datasetid": j.DataSource.DatasetID,
+		"functionalhash":       j.FunctionalHash,
+		"$or": bson.A{
+			bson.M{"primaryidentifier": j.PrimaryIdentifier},
+			bson.M{"datasource.timestamp": bson.M{"$ne": j.DataSource.Timestamp}},
+		},
+	}
+}
+
 func (j Journey) FlattenStops() ([]string, map[string]time.Time, map[string]time.Time) {
 	var stops []string
 	arrivalTimes := map[string]time.Time{}
@@ -182,24 +319,48 @@ func FilterIdenticalJourneys(journeys []*Journey, includeAvailabilityCondition b
 	return filtered
 }
 
+// FilterJourneysByTransportTypes removes any journeys not of the given transport types, an empty list matches everything
+func FilterJourneysByTransportTypes(journeys []*Journey, transportTypes []TransportType) []*Journey {
+	if len(transportTypes) == 0 {
+		return journeys
+	}
+
+	var filteredJourneys []*Journey
+	for _, journey := range journeys {
+		if MatchesTransportTypes(transportTypes, journey.GetTransportType()) {
+			filteredJourneys = append(filteredJourneys, journey)
+		}
+	}
+
+	return filteredJourneys
+}
+
 type JourneyPathItem struct {
-	OriginStopRef      string `groups:"basic,departureboard-cache"`
-	DestinationStopRef string `groups:"basic,departureboard-cache"`
+	OriginStopRef      string `groups:"basic,departures-llm,departureboard-cache"`
+	DestinationStopRef string `groups:"basic,departures-llm,departureboard-cache"`
 
-	OriginStop      *Stop `groups:"basic"`
-	DestinationStop *Stop `groups:"basic"`
+	OriginStop      *Stop `groups:"basic,departures-llm"`
+	DestinationStop *Stop `groups:"basic,departures-llm"`
 
-	OriginPlatform      string `groups:"basic"`
-	DestinationPlatform string `groups:"basic"`
+	OriginPlatform      string `groups:"basic,departures-llm"`
+	DestinationPlatform string `groups:"basic,departures-llm"`
 
 	Distance int `groups:"basic"`
+	// Set when covering the distance in the scheduled time would need an unrealistic speed
+	SpeedImplausible bool `groups:"internal" bson:",omitempty"`
 
 	OriginArrivalTime      time.Time `groups:"basic,departureboard-cache"`
-	DestinationArrivalTime time.Time `groups:"basic,departureboard-cache"`
+	DestinationArrivalTime time.Time `groups:"basic,departures-llm,departureboard-cache"`
+
+	OriginDepartureTime time.Time `groups:"basic,departures-llm,departureboard-cache"`
 
-	OriginDepartureTime time.Time `groups:"basic,departureboard-cache"`
+	// Number of days after the journeys service day each time falls on, for journeys running past midnight
+	OriginArrivalDayOffset      int `groups:"basic,departureboard-cache" bson:",omitempty"`
+	OriginDepartureDayOffset    int `groups:"basic,departureboard-cache" bson:",omitempty"`
+	DestinationArrivalDayOffset int `groups:"basic,departureboard-cache" bson:",omitempty"`
 
-	DestinationDisplay string `groups:"basic,departureboard-cache"`
+	DestinationDisplay string   `groups:"basic,departureboard-cache"`
+	DestinationVia     []string `groups:"basic,departureboard-cache" bson:",omitempty"`
 
 	OriginActivity      []JourneyPathItemActivity `groups:"basic,departureboard-cache"`
 	DestinationActivity []JourneyPathItemActivity `groups:"basic"`
@@ -215,21 +376,65 @@ func (jpi *JourneyPathItem) GetReferences() {
 }
 func (jpi *JourneyPathItem) GetOriginStop() {
 	stopsCollection := database.GetCollection("stops")
-	stopsCollection.FindOne(context.Background(), bson.M{
-		"$or": bson.A{
-			bson.M{"primaryidentifier": jpi.OriginStopRef},
-			bson.M{"otheridentifiers": jpi.OriginStopRef},
-		},
-	}).Decode(&jpi.OriginStop)
+	stopsCollection.FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeStop, jpi.OriginStopRef)).Decode(&jpi.OriginStop)
 }
 func (jpi *JourneyPathItem) GetDestinationStop() {
 	stopsCollection := database.GetCollection("stops")
-	stopsCollection.FindOne(context.Background(), bson.M{
-		"$or": bson.A{
-			bson.M{"primaryidentifier": jpi.DestinationStopRef},
-			bson.M{"otheridentifiers": jpi.DestinationStopRef},
-		},
-	}).Decode(&jpi.DestinationStop)
+	stopsCollection.FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeStop, jpi.DestinationStopRef)).Decode(&jpi.DestinationStop)
+}
+
+// GetOriginArrivalDateTime gives the full date time the journey arrives at the origin of this path item when running on the service day
+func (jpi *JourneyPathItem) GetOriginArrivalDateTime(serviceDay time.Time) time.Time {
+	return getServiceDayDateTime(serviceDay, jpi.OriginArrivalTime, jpi.OriginArrivalDayOffset)
+}
+
+// GetOriginDepartureDateTime gives the full date time the journey departs the origin of this path item when running on the service day
+func (jpi *JourneyPathItem) GetOriginDepartureDateTime(serviceDay time.Time) time.Time {
+	return getServiceDayDateTime(serviceDay, jpi.OriginDepartureTime, jpi.OriginDepartureDayOffset)
+}
+
+// GetDestinationArrivalDateTime gives the full date time the journey arrives at the destination of this path item when running on the service day
+func (jpi *JourneyPathItem) GetDestinationArrivalDateTime(serviceDay time.Time) time.Time {
+	return getServiceDayDateTime(serviceDay, jpi.DestinationArrivalTime, jpi.DestinationArrivalDayOffset)
+}
+
+func getServiceDayDateTime(serviceDay time.Time, refTime time.Time, dayOffset int) time.Time {
+	return time.Date(
+		serviceDay.Year(), serviceDay.Month(), serviceDay.Day()+dayOffset, refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), serviceDay.Location(),
+	)
+}
+
+// CalculatePathDayOffsets fills in the day offsets of a path whose times only have a meaningful time of day,
+// counting a day every time the clock goes backwards along the path.
+// A time of exactly midnight is also used by some formats for a missing time so is only treated as crossing midnight after a time past noon
+func CalculatePathDayOffsets(path []*JourneyPathItem) {
+	dayOffset := 0
+	previousTimeOfDay := time.Duration(-1)
+
+	getDayOffset := func(timestamp time.Time) int {
+		timeOfDay := time.Duration(timestamp.Hour())*time.Hour + time.Duration(timestamp.Minute())*time.Minute + time.Duration(timestamp.Second())*time.Second
+
+		if timeOfDay == 0 {
+			if previousTimeOfDay > 12*time.Hour {
+				return dayOffset + 1
+			}
+
+			return dayOffset
+		}
+
+		if timeOfDay < previousTimeOfDay {
+			dayOffset++
+		}
+		previousTimeOfDay = timeOfDay
+
+		return dayOffset
+	}
+
+	for _, pathItem := range path {
+		pathItem.OriginArrivalDayOffset = getDayOffset(pathItem.OriginArrivalTime)
+		pathItem.OriginDepartureDayOffset = getDayOffset(pathItem.OriginDepartureTime)
+		pathItem.DestinationArrivalDayOffset = getDayOffset(pathItem.DestinationArrivalTime)
+	}
 }
 
 type JourneyPathItemActivity string
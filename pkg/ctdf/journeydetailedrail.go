@@ -2,35 +2,46 @@ package ctdf
 
 type JourneyDetailedRail struct {
 	VehicleType     string `groups:"detailed"`
-	VehicleTypeName string `groups:"detailed"`
+	VehicleTypeName string `groups:"detailed,departures-llm-rail"`
 	PowerType       string `groups:"detailed"`
 
-	Carriages []RailCarriage `groups:"detailed"`
+	Carriages []RailCarriage `groups:"detailed,departures-llm-rail"`
 
-	Seating []JourneyDetailedRailSeating `groups:"detailed"`
+	Seating []JourneyDetailedRailSeating `groups:"detailed,departures-llm-rail"`
 
-	SleeperAvailable bool                         `groups:"detailed"`
-	Sleepers         []JourneyDetailedRailSeating `groups:"detailed"`
+	SleeperAvailable bool                         `groups:"detailed,departures-llm-rail"`
+	Sleepers         []JourneyDetailedRailSeating `groups:"detailed,departures-llm-rail"`
 
 	SpeedKMH int `groups:"detailed"`
 
-	AirConditioning bool `groups:"detailed"`
+	AirConditioning bool `groups:"detailed,departures-llm-rail"`
 
-	WiFi           bool `groups:"detailed"`
-	Toilets        bool `groups:"detailed"`
-	PowerPlugs     bool `groups:"detailed"`
-	USBPlugs       bool `groups:"detailed"`
-	DisabledAccess bool `groups:"detailed"`
-	BicycleSpaces  bool `groups:"detailed"`
+	WiFi           bool `groups:"detailed,departures-llm-rail"`
+	Toilets        bool `groups:"detailed,departures-llm-rail"`
+	PowerPlugs     bool `groups:"detailed,departures-llm-rail"`
+	USBPlugs       bool `groups:"detailed,departures-llm-rail"`
+	DisabledAccess bool `groups:"detailed,departures-llm-rail"`
+	BicycleSpaces  bool `groups:"detailed,departures-llm-rail"`
 
-	ReservationRequired     bool `groups:"detailed"`
-	ReservationBikeRequired bool `groups:"detailed"`
-	ReservationRecommended  bool `groups:"detailed"`
+	ReservationRequired     bool `groups:"detailed,departures-llm-rail"`
+	ReservationBikeRequired bool `groups:"detailed,departures-llm-rail"`
+	ReservationRecommended  bool `groups:"detailed,departures-llm-rail"`
+	ReservationPossible     bool `groups:"detailed,departures-llm-rail"`
 
-	CateringAvailable   bool   `groups:"detailed"`
-	CateringDescription string `groups:"detailed"`
+	ReservationWheelchairOnly bool `groups:"detailed"`
 
-	ReplacementBus bool `groups:"detailed"`
+	CateringAvailable   bool   `groups:"detailed,departures-llm-rail"`
+	CateringDescription string `groups:"detailed,departures-llm-rail"`
+
+	ReplacementBus bool `groups:"detailed,departures-llm-rail"`
+
+	// Only set when the train changes en route, eg. gaining a buffet part way along
+	Legs []*JourneyDetailedRailLeg `groups:"detailed,departures-llm-rail" bson:",omitempty"`
+}
+
+type JourneyDetailedRailLeg struct {
+	FromStopRef string               `groups:"detailed,departures-llm-rail"`
+	Details     *JourneyDetailedRail `groups:"detailed,departures-llm-rail"`
 }
 
 type JourneyDetailedRailSeating string
@@ -42,14 +53,14 @@ const (
 )
 
 type RailCarriage struct {
-	ID      string               `groups:"basic"`
-	Class   string               `groups:"basic"`
-	Toilets []RailCarriageToilet `groups:"basic"`
+	ID      string               `groups:"basic,departures-llm-rail"`
+	Class   string               `groups:"basic,departures-llm-rail"`
+	Toilets []RailCarriageToilet `groups:"basic,departures-llm-rail"`
 
-	Occupancy int `groups:"basic"`
+	Occupancy int `groups:"basic,departures-llm-rail"`
 }
 
 type RailCarriageToilet struct {
-	Type   string `groups:"basic"`
-	Status string `groups:"basic"`
+	Type   string `groups:"basic,departures-llm-rail"`
+	Status string `groups:"basic,departures-llm-rail"`
 }
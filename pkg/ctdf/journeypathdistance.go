@@ -0,0 +1,67 @@
+package ctdf
+
+import "time"
+
+const metresPerSecondPerMph = 0.44704
+
+// Fastest a vehicle of each transport type could realistically travel between two stops, in miles per hour.
+// Anything faster is down to bad times or a stop in the wrong place rather than the vehicle
+var maximumPlausibleSpeeds = map[TransportType]float64{
+	TransportTypeBus:       80,
+	TransportTypeCoach:     90,
+	TransportTypeTram:      60,
+	TransportTypeTaxi:      90,
+	TransportTypeRail:      200,
+	TransportTypeMetro:     80,
+	TransportTypeFerry:     50,
+	TransportTypeCableCar:  30,
+	TransportTypeFunicular: 30,
+}
+
+// Used for transport types without their own limit
+const defaultMaximumPlausibleSpeed = 200
+
+// Timetables are only to the minute so legs are assumed to take at least that long
+const minimumLegDuration = time.Minute
+
+// GetMaximumPlausibleSpeed returns the fastest believable speed for the transport type in metres per second
+func GetMaximumPlausibleSpeed(transportType TransportType) float64 {
+	speed, exists := maximumPlausibleSpeeds[transportType]
+	if !exists {
+		speed = defaultMaximumPlausibleSpeed
+	}
+
+	return speed * metresPerSecondPerMph
+}
+
+// CalculateDistance works out the length of the path item in metres, following its track if it has one
+// and otherwise as the crow flies between the stops. Returns 0 if there's nothing to work it out from
+func (jpi *JourneyPathItem) CalculateDistance(originLocation *Location, destinationLocation *Location) float64 {
+	if len(jpi.Track) >= 2 {
+		var distance float64
+		for i := 0; i < len(jpi.Track)-1; i++ {
+			distance += jpi.Track[i].GreatCircleDistance(&jpi.Track[i+1])
+		}
+
+		return distance
+	}
+
+	if originLocation == nil || destinationLocation == nil || len(originLocation.Coordinates) != 2 || len(destinationLocation.Coordinates) != 2 {
+		return 0
+	}
+
+	return originLocation.GreatCircleDistance(destinationLocation)
+}
+
+// GetImpliedSpeed is how fast the vehicle would have to go to cover the path items distance in its scheduled time, in metres per second
+func (jpi *JourneyPathItem) GetImpliedSpeed() float64 {
+	// Any day will do as only the difference between the times matters
+	serviceDay := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	duration := jpi.GetDestinationArrivalDateTime(serviceDay).Sub(jpi.GetOriginDepartureDateTime(serviceDay))
+	if duration < minimumLegDuration {
+		duration = minimumLegDuration
+	}
+
+	return float64(jpi.Distance) / duration.Seconds()
+}
@@ -0,0 +1,41 @@
+package ctdf
+
+import (
+	"time"
+)
+
+const LocalityIDFormat = "gb-nptglocality-%s"
+const AdministrativeAreaIDFormat = "gb-nptgadminarea-%s"
+
+type Locality struct {
+	PrimaryIdentifier string `groups:"basic,search"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	Name      string `groups:"basic,search"`
+	Qualifier string `groups:"basic,search"`
+
+	ParentLocalityRef     string `groups:"detailed"`
+	AdministrativeAreaRef string `groups:"detailed"`
+
+	Location *Location `groups:"detailed"`
+}
+
+type AdministrativeArea struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	Name      string `groups:"basic"`
+	ShortName string `groups:"basic"`
+
+	AtcoAreaCode string `groups:"detailed"`
+	Region       string `groups:"detailed"`
+	National     bool   `groups:"detailed"`
+}
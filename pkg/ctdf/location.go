@@ -41,6 +41,37 @@ func (l *Location) DistanceFromLine(a Location, b Location) float64 {
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
+// ClosestPointOnLine returns the closest point to l on the line segment a-b & how far along the segment it is (0-1)
+func (l *Location) ClosestPointOnLine(a Location, b Location) (Location, float64) {
+	C := b.Coordinates[0] - a.Coordinates[0]
+	D := b.Coordinates[1] - a.Coordinates[1]
+
+	lenSq := C*C + D*D
+
+	var param float64
+	if lenSq != 0 {
+		param = ((l.Coordinates[0]-a.Coordinates[0])*C + (l.Coordinates[1]-a.Coordinates[1])*D) / lenSq
+	}
+	param = math.Max(0, math.Min(1, param))
+
+	return Location{
+		Type:        "Point",
+		Coordinates: []float64{a.Coordinates[0] + param*C, a.Coordinates[1] + param*D},
+	}, param
+}
+
+// Bearing returns the initial compass bearing in degrees from l1 towards l2
+func (l1 *Location) Bearing(l2 *Location) float64 {
+	la1 := l1.Coordinates[1] * math.Pi / 180
+	la2 := l2.Coordinates[1] * math.Pi / 180
+	deltaLon := (l2.Coordinates[0] - l1.Coordinates[0]) * math.Pi / 180
+
+	y := math.Sin(deltaLon) * math.Cos(la2)
+	x := math.Cos(la1)*math.Sin(la2) - math.Sin(la1)*math.Cos(la2)*math.Cos(deltaLon)
+
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
 // Shamelessly stolen from https://gist.github.com/cdipaolo/d3f8db3848278b49db68
 func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
@@ -61,3 +92,17 @@ func (l1 *Location) Distance(l2 *Location) float64 {
 
 	return 2 * r * math.Asin(math.Sqrt(h))
 }
+
+// GreatCircleDistance returns the haversine distance in metres between l1 & l2
+func (l1 *Location) GreatCircleDistance(l2 *Location) float64 {
+	la1 := l1.Coordinates[1] * math.Pi / 180
+	lo1 := l1.Coordinates[0] * math.Pi / 180
+	la2 := l2.Coordinates[1] * math.Pi / 180
+	lo2 := l2.Coordinates[0] * math.Pi / 180
+
+	r := 6378100.0 // Earth radius in METERS
+
+	h := hsin(la2-la1) + math.Cos(la1)*math.Cos(la2)*hsin(lo2-lo1)
+
+	return 2 * r * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,27 @@
+package ctdf
+
+import "time"
+
+// OccupancyRecord is a single occupancy observation kept for building up historical crowding patterns
+type OccupancyRecord struct {
+	ServiceRef         string
+	JourneyRef         string
+	RealtimeJourneyRef string
+	VehicleRef         string
+
+	RecordedAt time.Time
+
+	ActualValues             bool
+	TotalPercentageOccupancy int
+
+	DataSource *DataSourceReference
+}
+
+type ServiceOccupancyPeriod struct {
+	Hour int `groups:"basic"`
+
+	AveragePercentageOccupancy float64 `groups:"basic"`
+	PeakPercentageOccupancy    int     `groups:"basic"`
+
+	Samples int `groups:"detailed"`
+}
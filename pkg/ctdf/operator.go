@@ -29,7 +29,7 @@ type Operator struct {
 	OperatorGroupRef string         `groups:"internal" bson:",omitempty"`
 	OperatorGroup    *OperatorGroup `groups:"detailed" bson:"-"`
 
-	TransportType string `groups:"detailed" bson:",omitempty"`
+	TransportType TransportType `groups:"detailed" bson:",omitempty"`
 
 	Licence string `groups:"internal" bson:",omitempty"`
 
@@ -40,6 +40,8 @@ type Operator struct {
 	SocialMedia map[string]string `groups:"detailed" bson:",omitempty"`
 
 	Regions []string `groups:"detailed" bson:",omitempty"`
+
+	Branding *Branding `groups:"basic" bson:",omitempty"`
 }
 
 func (operator *Operator) GetReferences() {
@@ -4,9 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -36,7 +36,8 @@ func (group *OperatorGroup) GetOperators() {
 		var operator *Operator
 		err := cursor.Decode(&operator)
 		if err != nil {
-			log.Fatal(err)
+			log.Error().Err(err).Msg("Failed to decode Operator")
+			continue
 		}
 
 		group.Operators = append(group.Operators, operator)
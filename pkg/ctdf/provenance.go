@@ -0,0 +1,28 @@
+package ctdf
+
+// FieldProvenance records which source record supplied a field of an object merged from several sources
+type FieldProvenance struct {
+	Field string
+	// Value is only set for fields built up from several records, eg. each of the other identifiers
+	Value string `bson:",omitempty"`
+
+	SourceRef  string
+	DataSource *DataSourceReference
+
+	// Rule is the name of the merge policy rule that picked the source, empty when the oldest record won
+	Rule string `bson:",omitempty"`
+}
+
+// GetFieldProvenance returns the provenance recorded for a field, there will be one for each value
+// of fields built up from several records
+func GetFieldProvenance(provenance []*FieldProvenance, field string) []*FieldProvenance {
+	var fieldProvenance []*FieldProvenance
+
+	for _, record := range provenance {
+		if record.Field == field {
+			fieldProvenance = append(fieldProvenance, record)
+		}
+	}
+
+	return fieldProvenance
+}
@@ -0,0 +1,71 @@
+package ctdf
+
+import "time"
+
+const RailLocationIDFormat = "gb-tiploc-%s"
+
+type RailLocationStopLinkMethod string
+
+const (
+	RailLocationStopLinkTIPLOC    RailLocationStopLinkMethod = "tiploc"
+	RailLocationStopLinkCRS                                  = "crs"
+	RailLocationStopLinkProximity                            = "proximity"
+)
+
+// RailLocation is a point on the national rail network known by its TIPLOC, be it a station, junction or siding.
+// The codes come from CORPUS with the geography, platforms & distances to neighbouring locations from BPLAN.
+type RailLocation struct {
+	PrimaryIdentifier string   `groups:"basic"`
+	OtherIdentifiers  []string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	TIPLOC string `groups:"basic"`
+	CRS    string `groups:"basic" bson:",omitempty"`
+	STANOX string `groups:"basic" bson:",omitempty"`
+	NLC    string `groups:"basic" bson:",omitempty"`
+	UIC    string `groups:"basic" bson:",omitempty"`
+
+	Name      string `groups:"basic"`
+	ShortName string `groups:"detailed" bson:",omitempty"`
+
+	Location *Location `groups:"basic" bson:",omitempty"`
+
+	// Passenger stop the location is part of, only set for stations & the TIPLOCs inside them
+	StopRef        string                     `groups:"basic" bson:",omitempty"`
+	StopLinkMethod RailLocationStopLinkMethod `groups:"internal" bson:",omitempty"`
+
+	Platforms []*RailLocationPlatform `groups:"detailed" bson:",omitempty"`
+	Links     []*RailLocationLink     `groups:"detailed" bson:",omitempty"`
+}
+
+type RailLocationPlatform struct {
+	PlatformID string `groups:"detailed"`
+	// Metres, 0 when unknown
+	Length      int    `groups:"detailed"`
+	PowerSupply string `groups:"detailed" bson:",omitempty"`
+}
+
+// RailLocationLink is a running line from the location to a neighbouring one
+type RailLocationLink struct {
+	DestinationTIPLOC string `groups:"detailed"`
+	RunningLine       string `groups:"detailed" bson:",omitempty"`
+	// Metres
+	Distance int `groups:"detailed"`
+}
+
+// GetDistanceTo is the shortest running line distance in metres to a directly linked location, -1 if they aren't linked
+func (r *RailLocation) GetDistanceTo(tiploc string) int {
+	distance := -1
+
+	for _, link := range r.Links {
+		if link.DestinationTIPLOC == tiploc && (distance == -1 || link.Distance < distance) {
+			distance = link.Distance
+		}
+	}
+
+	return distance
+}
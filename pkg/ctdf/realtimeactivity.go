@@ -0,0 +1,119 @@
+package ctdf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// How long a realtime journey stays active without receiving an update. Rail records are updated far less often
+// than bus locations so remain valid for much longer
+var defaultRealtimeActivityWindows = map[TransportType]time.Duration{
+	TransportTypeRail: 181 * time.Minute,
+}
+
+const defaultRealtimeActivityWindow = 10 * time.Minute
+
+// Queries for active realtime journeys never look back further than this, even if nothing is configured that long
+const minimumRealtimeActiveCutOff = 240 * time.Minute
+
+type realtimeActivityWindows struct {
+	transportTypes map[TransportType]time.Duration
+	datasets       map[string]time.Duration
+}
+
+var activityWindows *realtimeActivityWindows
+var activityWindowsOnce sync.Once
+
+// getRealtimeActivityWindows loads the windows from the environment. Per mode windows are set with
+// TRAVIGO_REALTIME_ACTIVITY_WINDOW_<MODE> (eg. TRAVIGO_REALTIME_ACTIVITY_WINDOW_FERRY=60m) and per dataset ones with
+// TRAVIGO_REALTIME_ACTIVITY_WINDOW_DATASETS as a comma separated list (eg. gb-nationalrail-darwin=240m)
+func getRealtimeActivityWindows() *realtimeActivityWindows {
+	activityWindowsOnce.Do(func() {
+		activityWindows = &realtimeActivityWindows{
+			transportTypes: map[TransportType]time.Duration{},
+			datasets:       map[string]time.Duration{},
+		}
+
+		for transportType, window := range defaultRealtimeActivityWindows {
+			activityWindows.transportTypes[transportType] = window
+		}
+
+		for _, transportType := range []TransportType{
+			TransportTypeBus, TransportTypeCoach, TransportTypeTram, TransportTypeTaxi, TransportTypeRail, TransportTypeMetro,
+			TransportTypeFerry, TransportTypeAirport, TransportTypeCableCar, TransportTypeFunicular,
+		} {
+			value := os.Getenv(fmt.Sprintf("TRAVIGO_REALTIME_ACTIVITY_WINDOW_%s", strings.ToUpper(string(transportType))))
+			if value == "" {
+				continue
+			}
+
+			window, err := time.ParseDuration(value)
+			if err != nil {
+				log.Error().Err(err).Str("type", string(transportType)).Msg("Invalid realtime activity window")
+				continue
+			}
+
+			activityWindows.transportTypes[transportType] = window
+		}
+
+		for _, datasetWindow := range strings.Split(os.Getenv("TRAVIGO_REALTIME_ACTIVITY_WINDOW_DATASETS"), ",") {
+			datasetID, value, found := strings.Cut(strings.TrimSpace(datasetWindow), "=")
+			if !found {
+				continue
+			}
+
+			window, err := time.ParseDuration(value)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", datasetID).Msg("Invalid realtime activity window")
+				continue
+			}
+
+			activityWindows.datasets[datasetID] = window
+		}
+	})
+
+	return activityWindows
+}
+
+// GetRealtimeActivityWindow returns how long a realtime journey stays active without updates, a window configured
+// for the data source takes precedence over the one for the transport type
+func GetRealtimeActivityWindow(transportType TransportType, dataSource *DataSourceReference) time.Duration {
+	windows := getRealtimeActivityWindows()
+
+	if dataSource != nil {
+		if window, exists := windows.datasets[dataSource.DatasetID]; exists {
+			return window
+		}
+	}
+
+	if window, exists := windows.transportTypes[transportType]; exists {
+		return window
+	}
+
+	return defaultRealtimeActivityWindow
+}
+
+// GetRealtimeJourneyTimeoutMinutes is the activity window in the form stored on RealtimeJourney.TimeoutDurationMinutes
+func GetRealtimeJourneyTimeoutMinutes(transportType TransportType, dataSource *DataSourceReference) int {
+	return int(GetRealtimeActivityWindow(transportType, dataSource).Minutes())
+}
+
+// getLongestRealtimeActivityWindow is used for queries across all modes so no still active journeys are missed
+func getLongestRealtimeActivityWindow() time.Duration {
+	windows := getRealtimeActivityWindows()
+
+	longest := minimumRealtimeActiveCutOff
+	for _, window := range windows.transportTypes {
+		longest = max(longest, window)
+	}
+	for _, window := range windows.datasets {
+		longest = max(longest, window)
+	}
+
+	return longest
+}
@@ -26,38 +26,47 @@ type RealtimeJourney struct {
 	DataSource *DataSourceReference `groups:"internal"`
 
 	VehicleLocation            Location `groups:"basic" bson:",omitempty"`
-	VehicleLocationDescription string   `groups:"basic"`
+	VehicleLocationVariance    float64  `groups:"internal"`
+	VehicleLocationDescription string   `groups:"basic,departures-llm"`
 	VehicleBearing             float64  `groups:"basic"`
+	// Set when the location is interpolated along the route between reports rather than reported by the vehicle
+	VehicleLocationEstimated bool `groups:"basic" bson:",omitempty"`
 
-	DepartedStopRef string `groups:"basic"`
-	DepartedStop    *Stop  `groups:"basic" bson:"-"`
+	// Sampled history of VehicleLocation, only returned through the track endpoint as it gets large
+	VehicleTrack []*VehicleTrackPoint `groups:"internal" json:"-"`
 
-	NextStopRef string `groups:"basic"`
+	ProgressPercentage float64 `groups:"basic"`
+
+	DepartedStopRef      string    `groups:"basic,departures-llm"`
+	DepartedStop         *Stop     `groups:"basic" bson:"-"`
+	DepartedStopDateTime time.Time `groups:"internal"`
+
+	NextStopRef string `groups:"basic,departures-llm"`
 	NextStop    *Stop  `groups:"basic" bson:"-"`
 
-	Stops  map[string]*RealtimeJourneyStops `groups:"basic"` // Historic & future estimates
+	Stops  map[string]*RealtimeJourneyStops `groups:"basic,departures-llm"` // Historic & future estimates
 	Offset time.Duration                    `groups:"internal"`
 
 	Reliability RealtimeJourneyReliabilityType `groups:"basic"`
 
 	VehicleRef string `groups:"internal"`
 
-	Cancelled bool `groups:"basic"`
+	Cancelled bool `groups:"basic,departures-llm"`
 
-	Occupancy RealtimeJourneyOccupancy `groups:"detailed"`
+	Occupancy RealtimeJourneyOccupancy `groups:"detailed,departures-llm"`
 
 	// Detailed realtime journey information
-	DetailedRailInformation RealtimeJourneyDetailedRail `groups:"detailed"`
+	DetailedRailInformation RealtimeJourneyDetailedRail `groups:"detailed,departures-llm-rail"`
 }
 
 type RealtimeJourneyOccupancy struct {
-	OccupancyAvailable bool `groups:"basic"`
+	OccupancyAvailable bool `groups:"basic,departures-llm"`
 
 	ActualValues          bool `groups:"basic"`
 	WheelchairInformation bool `groups:"basic"`
 	SeatedInformation     bool `groups:"basic"`
 
-	TotalPercentageOccupancy int `groups:"basic"`
+	TotalPercentageOccupancy int `groups:"basic,departures-llm"`
 
 	Capacity           int `groups:"basic"`
 	SeatedCapacity     int `groups:"basic"`
@@ -110,10 +119,11 @@ func (r *RealtimeJourney) IsActive() bool {
 	}
 
 	now := time.Now()
-	lastPathItemArrivalDateless := lastPathItem.DestinationArrivalTime
-	lastPathItemArrival := time.Date(
-		now.Year(), now.Month(), now.Day(), lastPathItemArrivalDateless.Hour(), lastPathItemArrivalDateless.Minute(), lastPathItemArrivalDateless.Second(), lastPathItemArrivalDateless.Nanosecond(), now.Location(),
-	)
+	serviceDay := now
+	if !r.JourneyRunDate.IsZero() {
+		serviceDay = time.Date(r.JourneyRunDate.Year(), r.JourneyRunDate.Month(), r.JourneyRunDate.Day(), 0, 0, 0, 0, now.Location())
+	}
+	lastPathItemArrival := lastPathItem.GetDestinationArrivalDateTime(serviceDay)
 	timeFromlastPathItemArrival := lastPathItemArrival.Sub(now).Minutes()
 
 	distanceEndStopLocation := r.VehicleLocation.Distance(lastPathItem.DestinationStop.Location)
@@ -126,14 +136,17 @@ type RealtimeJourneyStops struct {
 	StopRef string `groups:"basic"`
 	Stop    *Stop  `groups:"basic" bson:"-"`
 
-	Platform string `groups:"basic"`
+	Platform string `groups:"basic,departures-llm"`
+
+	ArrivalTime   time.Time `groups:"basic,departures-llm"`
+	DepartureTime time.Time `groups:"basic,departures-llm"`
 
-	ArrivalTime   time.Time `groups:"basic"`
-	DepartureTime time.Time `groups:"basic"`
+	ArrivalDelay   time.Duration `groups:"detailed"`
+	DepartureDelay time.Duration `groups:"detailed"`
 
 	TimeType RealtimeJourneyStopTimeType `groups:"basic"`
 
-	Cancelled bool `groups:"basic"`
+	Cancelled bool `groups:"basic,departures-llm"`
 }
 
 type RealtimeJourneyStopTimeType string
@@ -148,6 +161,8 @@ func GetShortActiveRealtimeJourneyCutOffDate() time.Time {
 	return time.Now().Add(-60 * time.Minute)
 }
 
+// GetActiveRealtimeJourneyCutOffDate is the oldest modification time a realtime journey of any mode or data source
+// could have and still be active. IsActive does the per journey check
 func GetActiveRealtimeJourneyCutOffDate() time.Time {
-	return time.Now().Add(-240 * time.Minute)
+	return time.Now().Add(-getLongestRealtimeActivityWindow())
 }
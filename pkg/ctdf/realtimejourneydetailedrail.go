@@ -1,5 +1,11 @@
 package ctdf
 
 type RealtimeJourneyDetailedRail struct {
-	Carriages []RailCarriage `groups:"basic"`
+	FormationID string `groups:"basic" bson:",omitempty"`
+	CoachCount  int    `groups:"basic,departures-llm-rail" bson:",omitempty"`
+
+	Carriages []RailCarriage `groups:"basic,departures-llm-rail"`
+
+	// Where the latest carriage loading was reported from
+	LoadingStopRef string `groups:"basic" bson:",omitempty"`
 }
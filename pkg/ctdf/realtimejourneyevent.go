@@ -0,0 +1,78 @@
+package ctdf
+
+import (
+	"time"
+)
+
+type RealtimeJourneyEventType string
+
+const (
+	RealtimeJourneyEventPosition RealtimeJourneyEventType = "Position"
+	RealtimeJourneyEventDelay                             = "Delay"
+	RealtimeJourneyEventPlatform                          = "Platform"
+)
+
+// RealtimeJourneyEvent is a single change to a realtime journey. Every journey has an append only log of them so
+// what was known about it at any point can be audited. The log is an audit trail alongside the realtime journey
+// documents rather than their source, replaying it rebuilds the same state they had
+type RealtimeJourneyEvent struct {
+	RealtimeJourneyRef string                   `groups:"basic"`
+	Type               RealtimeJourneyEventType `groups:"basic"`
+
+	RecordedAt time.Time            `groups:"basic"`
+	DataSource *DataSourceReference `groups:"internal"`
+
+	// Position
+	Location        *Location `groups:"basic" bson:",omitempty"`
+	Bearing         float64   `groups:"basic" bson:",omitempty"`
+	DepartedStopRef string    `groups:"basic" bson:",omitempty"`
+	NextStopRef     string    `groups:"basic" bson:",omitempty"`
+
+	// Delay
+	Offset time.Duration `groups:"basic" bson:",omitempty"`
+
+	// Platform
+	StopRef  string `groups:"basic" bson:",omitempty"`
+	Platform string `groups:"basic" bson:",omitempty"`
+
+	// Set once the compaction job has thinned out the journeys log up to this event
+	Compacted bool `groups:"internal"`
+}
+
+// ApplyEvent brings the realtime journey up to date with the event, replaying a journeys log in order through it
+// rebuilds the realtime state of the journey
+func (r *RealtimeJourney) ApplyEvent(event *RealtimeJourneyEvent) {
+	switch event.Type {
+	case RealtimeJourneyEventPosition:
+		if event.Location != nil {
+			r.VehicleLocation = *event.Location
+		}
+		r.VehicleBearing = event.Bearing
+
+		if event.DepartedStopRef != "" && event.DepartedStopRef != r.DepartedStopRef {
+			r.DepartedStopRef = event.DepartedStopRef
+			r.DepartedStopDateTime = event.RecordedAt
+		}
+		if event.NextStopRef != "" {
+			r.NextStopRef = event.NextStopRef
+		}
+	case RealtimeJourneyEventDelay:
+		r.Offset = event.Offset
+	case RealtimeJourneyEventPlatform:
+		if r.Stops == nil {
+			r.Stops = map[string]*RealtimeJourneyStops{}
+		}
+		if r.Stops[event.StopRef] == nil {
+			r.Stops[event.StopRef] = &RealtimeJourneyStops{
+				StopRef:  event.StopRef,
+				TimeType: RealtimeJourneyStopTimeEstimatedFuture,
+			}
+		}
+
+		r.Stops[event.StopRef].Platform = event.Platform
+	}
+
+	if event.RecordedAt.After(r.ModificationDateTime) {
+		r.ModificationDateTime = event.RecordedAt
+	}
+}
@@ -0,0 +1,24 @@
+package ctdf
+
+import "time"
+
+// RealtimeJourneyUpdate is the slimmed down state of a realtime journey pushed out to stream subscribers after every update
+type RealtimeJourneyUpdate struct {
+	RealtimeJourneyRef string `groups:"basic"`
+	JourneyRef         string `groups:"basic"`
+	ServiceRef         string `groups:"basic"`
+	OperatorRef        string `groups:"basic"`
+	VehicleRef         string `groups:"basic"`
+
+	VehicleLocation Location `groups:"basic"`
+	VehicleBearing  float64  `groups:"basic"`
+
+	DepartedStopRef string `groups:"basic"`
+	NextStopRef     string `groups:"basic"`
+
+	Offset time.Duration `groups:"basic"`
+
+	Reliability RealtimeJourneyReliabilityType `groups:"basic"`
+
+	ModificationDateTime time.Time `groups:"basic"`
+}
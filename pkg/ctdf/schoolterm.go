@@ -0,0 +1,123 @@
+package ctdf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const SchoolTermCalendarIDFormat = "gb-schoolterms-%s"
+
+// SchoolTermCalendar is the school term dates of a local authority, keyed on the ATCO area code of that authority
+// so journeys can find the calendar from the stops they serve
+type SchoolTermCalendar struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	Name         string `groups:"basic"`
+	AtcoAreaCode string `groups:"basic"`
+
+	Terms []SchoolTerm `groups:"basic"`
+}
+
+// SchoolTerm is a single block of school days, half terms are split into separate terms
+type SchoolTerm struct {
+	Name      string `groups:"basic"`
+	StartDate string `groups:"basic"`
+	EndDate   string `groups:"basic"`
+}
+
+// IsSchoolDay returns whether the date falls within a term and whether the calendar covers that date at all
+func (calendar *SchoolTermCalendar) IsSchoolDay(dateTime time.Time) (bool, bool) {
+	date := dateTime.Format(YearMonthDayFormat)
+
+	earliest := ""
+	latest := ""
+
+	for _, term := range calendar.Terms {
+		if term.StartDate <= date && date <= term.EndDate {
+			return true, true
+		}
+
+		if earliest == "" || term.StartDate < earliest {
+			earliest = term.StartDate
+		}
+		if latest == "" || term.EndDate > latest {
+			latest = term.EndDate
+		}
+	}
+
+	return false, earliest != "" && earliest <= date && date <= latest
+}
+
+// SchoolTermCalendarRefFromAtcoCode uses the ATCO area code prefix of a stop to find its local authority calendar
+func SchoolTermCalendarRefFromAtcoCode(atcoCode string) string {
+	if len(atcoCode) < 3 {
+		return ""
+	}
+
+	return fmt.Sprintf(SchoolTermCalendarIDFormat, atcoCode[:3])
+}
+
+const schoolTermCalendarCacheDuration = 1 * time.Hour
+
+type schoolTermCalendarCacheItem struct {
+	Calendar    *SchoolTermCalendar
+	RetrievedAt time.Time
+}
+
+var schoolTermCalendarCache = map[string]schoolTermCalendarCacheItem{}
+var schoolTermCalendarCacheMutex sync.RWMutex
+
+// GetSchoolTermCalendar returns the calendar from an in memory cache as it is checked for every availability match,
+// nil is cached as well so journeys in areas without term dates don't keep hitting the database
+func GetSchoolTermCalendar(ref string) *SchoolTermCalendar {
+	schoolTermCalendarCacheMutex.RLock()
+	cacheItem, exists := schoolTermCalendarCache[ref]
+	schoolTermCalendarCacheMutex.RUnlock()
+
+	if exists && time.Since(cacheItem.RetrievedAt) < schoolTermCalendarCacheDuration {
+		return cacheItem.Calendar
+	}
+
+	var calendar *SchoolTermCalendar
+	collection := database.GetCollection("school_term_calendars")
+	collection.FindOne(context.Background(), bson.M{"primaryidentifier": ref}).Decode(&calendar)
+
+	schoolTermCalendarCacheMutex.Lock()
+	schoolTermCalendarCache[ref] = schoolTermCalendarCacheItem{
+		Calendar:    calendar,
+		RetrievedAt: time.Now(),
+	}
+	schoolTermCalendarCacheMutex.Unlock()
+
+	return calendar
+}
+
+// checkSchoolTermRule matches school days or school holidays from the referenced calendar.
+// Dates the calendar doesn't cover never match so term time only journeys are hidden rather than shown every day.
+func checkSchoolTermRule(rule *AvailabilityRule, dateTime time.Time) bool {
+	calendar := GetSchoolTermCalendar(rule.Value)
+	if calendar == nil {
+		return false
+	}
+
+	isSchoolDay, covered := calendar.IsSchoolDay(dateTime)
+	if !covered {
+		return false
+	}
+
+	if rule.Type == AvailabilitySchoolTerm {
+		return isSchoolDay
+	}
+
+	return !isSchoolDay
+}
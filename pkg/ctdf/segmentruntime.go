@@ -0,0 +1,83 @@
+package ctdf
+
+import (
+	"fmt"
+	"time"
+)
+
+const SegmentRunTimeProfileIDFormat = "%s:%s:%d"
+
+type SegmentDayType string
+
+const (
+	SegmentDayTypeWeekday  SegmentDayType = "Weekday"
+	SegmentDayTypeSaturday                = "Saturday"
+	SegmentDayTypeSunday                  = "Sunday"
+)
+
+func GetSegmentDayType(date time.Time) SegmentDayType {
+	switch date.Weekday() {
+	case time.Saturday:
+		return SegmentDayTypeSaturday
+	case time.Sunday:
+		return SegmentDayTypeSunday
+	default:
+		return SegmentDayTypeWeekday
+	}
+}
+
+// SegmentRunTimeRecord is a single observed run between two consecutive stops, from the vehicle leaving
+// the origin stop to it leaving the destination stop, kept for building up the run time profiles
+type SegmentRunTimeRecord struct {
+	SegmentRef         string
+	OriginStopRef      string
+	DestinationStopRef string
+
+	JourneyRef         string
+	RealtimeJourneyRef string
+
+	// Day type & hour are of the departure from the origin stop in the journeys local time
+	DayType SegmentDayType
+	Hour    int
+
+	DepartedAt time.Time
+	RunTime    time.Duration
+
+	DataSource *DataSourceReference
+}
+
+// SegmentRunTimeProfile is the learned run time distribution of a segment for a day type & hour
+type SegmentRunTimeProfile struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	SegmentRef         string `groups:"basic"`
+	OriginStopRef      string `groups:"basic"`
+	DestinationStopRef string `groups:"basic"`
+
+	DayType SegmentDayType `groups:"basic"`
+	Hour    int            `groups:"basic"`
+
+	Samples int `groups:"basic"`
+
+	Percentile50 time.Duration `groups:"basic"`
+	Percentile85 time.Duration `groups:"basic"`
+
+	ModificationDateTime time.Time `groups:"detailed"`
+}
+
+func GetSegmentRef(originStopRef string, destinationStopRef string) string {
+	return fmt.Sprintf("%s:%s", originStopRef, destinationStopRef)
+}
+
+func NewSegmentRunTimeProfile(originStopRef string, destinationStopRef string, dayType SegmentDayType, hour int) *SegmentRunTimeProfile {
+	segmentRef := GetSegmentRef(originStopRef, destinationStopRef)
+
+	return &SegmentRunTimeProfile{
+		PrimaryIdentifier:  fmt.Sprintf(SegmentRunTimeProfileIDFormat, dayType, segmentRef, hour),
+		SegmentRef:         segmentRef,
+		OriginStopRef:      originStopRef,
+		DestinationStopRef: destinationStopRef,
+		DayType:            dayType,
+		Hour:               hour,
+	}
+}
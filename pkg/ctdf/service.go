@@ -1,6 +1,9 @@
 package ctdf
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type Service struct {
 	PrimaryIdentifier string   `groups:"basic,search,search-llm,stop-llm,departures-llm"`
@@ -12,6 +15,8 @@ type Service struct {
 	DataSource *DataSourceReference `groups:"detailed"`
 
 	ServiceName string `groups:"basic,search,search-llm,stop-llm,departures-llm"`
+	// Other languages the service name is officially in
+	ServiceNameTranslations Translations `groups:"basic,search" bson:",omitempty"`
 
 	OperatorRef string `groups:"basic"`
 	// Operator *Operator
@@ -23,6 +28,8 @@ type Service struct {
 	BrandIcon            string `groups:"basic,search"`
 	BrandDisplayMode     string `groups:"basic,search"`
 
+	Branding *Branding `groups:"basic,search" bson:",omitempty"`
+
 	StopNameOverrides map[string]string `groups:"internal"`
 
 	TransportType TransportType `groups:"basic,search,search-llm,stop-llm,departures-llm"`
@@ -32,4 +39,31 @@ type Route struct {
 	Origin      string `groups:"basic"`
 	Destination string `groups:"basic"`
 	Description string `groups:"basic"`
+
+	DescriptionTranslations Translations `groups:"basic" bson:",omitempty"`
+
+	// Populated when the routes are materialised from the services journeys
+	Direction      string     `groups:"basic" bson:",omitempty"`
+	StopRefs       []string   `groups:"basic" bson:",omitempty"`
+	Track          []Location `groups:"detailed" bson:",omitempty"`
+	NumberJourneys int        `groups:"basic" bson:",omitempty"`
+}
+
+func (service *Service) Localise(languages []string) {
+	localiseText(&service.ServiceName, &service.ServiceNameTranslations, languages)
+}
+
+func (r *Route) Localise(languages []string) {
+	localiseText(&r.Description, &r.DescriptionTranslations, languages)
+}
+
+// ServesStop returns if the stop is one of the routes stops
+func (r *Route) ServesStop(stopRefs []string) bool {
+	for _, stopRef := range r.StopRefs {
+		if slices.Contains(stopRefs, stopRef) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -1,25 +1,74 @@
 package ctdf
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type ServiceAlert struct {
 	PrimaryIdentifier string            `groups:"basic"`
 	OtherIdentifiers  map[string]string `groups:"basic"`
 
-	CreationDateTime     time.Time `groups:"detailed"`
+	// Left out when empty so updates to an existing alert keep the time it was first created
+	CreationDateTime     time.Time `groups:"detailed" bson:",omitempty"`
 	ModificationDateTime time.Time `groups:"detailed"`
 
 	DataSource *DataSourceReference `groups:"internal"`
 
-	AlertType ServiceAlertType `groups:"basic"`
+	// Status is empty on alerts from sources that don't track their lifecycle, which are treated as active
+	Status ServiceAlertStatus `groups:"basic" bson:",omitempty"`
+	// Version of the situation in the source, changes every time the source updates it
+	Version string `groups:"internal" bson:",omitempty"`
+
+	AlertType ServiceAlertType `groups:"basic,departures-llm"`
+
+	Title string `groups:"basic,departures-llm"`
+	Text  string `groups:"basic,departures-llm"`
 
-	Title string `groups:"basic"`
-	Text  string `groups:"basic"`
+	// Other languages the source provides the title & text in
+	TitleTranslations Translations `groups:"basic" bson:",omitempty"`
+	TextTranslations  Translations `groups:"basic" bson:",omitempty"`
 
 	MatchedIdentifiers []string `groups:"internal"`
 
+	// Scopes widen an alert beyond specific identifiers, eg. every stop served by an operator
+	Scopes []ServiceAlertScope `groups:"internal" bson:",omitempty"`
+
+	// Precomputed from Scopes by GenerateMatchKeys so scoped alerts can be found with a single indexed query
+	MatchKeys []string `groups:"internal" bson:",omitempty"`
+
 	ValidFrom  time.Time `groups:"internal"`
 	ValidUntil time.Time `groups:"internal"`
+
+	// Optional windows within ValidFrom/ValidUntil that the alert actually applies in, eg. overnight closures
+	ActivePeriods []ServiceAlertPeriod `groups:"basic,departures-llm" bson:",omitempty"`
+
+	// When planned disruption takes place, for alerts shown ahead of the disruption itself
+	DisruptionPeriods []ServiceAlertPeriod `groups:"basic,departures-llm" bson:",omitempty"`
+}
+
+type ServiceAlertPeriod struct {
+	From  time.Time `groups:"basic,departures-llm"`
+	Until time.Time `groups:"basic,departures-llm"`
+}
+
+func (p ServiceAlertPeriod) Contains(checkTime time.Time) bool {
+	return !checkTime.Before(p.From) && checkTime.Before(p.Until)
+}
+
+type ServiceAlertStatus string
+
+const (
+	ServiceAlertStatusActive ServiceAlertStatus = "Active"
+	// Closed by the source before it was due to end
+	ServiceAlertStatusClosed = "Closed"
+	// Past its ValidUntil
+	ServiceAlertStatusExpired = "Expired"
+)
+
+// IsEnded is whether the alert has been closed or has expired
+func (a *ServiceAlert) IsEnded() bool {
+	return a.Status == ServiceAlertStatusClosed || a.Status == ServiceAlertStatusExpired
 }
 
 type ServiceAlertType string
@@ -39,6 +88,75 @@ const (
 	ServiceAlertTypeJourneyCancelled                           = "JourneyCancelled"
 )
 
+func (a *ServiceAlert) Localise(languages []string) {
+	localiseText(&a.Title, &a.TitleTranslations, languages)
+	localiseText(&a.Text, &a.TextTranslations, languages)
+}
+
 func (a *ServiceAlert) IsValid(checkTime time.Time) bool {
-	return checkTime.After(a.ValidFrom) && checkTime.Before(a.ValidUntil)
+	if a.IsEnded() {
+		return false
+	}
+
+	if !(checkTime.After(a.ValidFrom) && checkTime.Before(a.ValidUntil)) {
+		return false
+	}
+
+	if len(a.ActivePeriods) == 0 {
+		return true
+	}
+
+	for _, period := range a.ActivePeriods {
+		if period.Contains(checkTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GenerateMatchKeys must be called before storing an alert with Scopes
+func (a *ServiceAlert) GenerateMatchKeys() {
+	a.MatchKeys = GetServiceAlertMatchKeys(a.Scopes...)
+}
+
+type ServiceAlertScope struct {
+	Type       ServiceAlertScopeType `groups:"internal"`
+	Identifier string                `groups:"internal"`
+}
+
+type ServiceAlertScopeType string
+
+const (
+	ServiceAlertScopeTypeWildcard  ServiceAlertScopeType = "Wildcard"
+	ServiceAlertScopeTypeRegion                          = "Region"
+	ServiceAlertScopeTypeOperator                        = "Operator"
+	ServiceAlertScopeTypeService                         = "Service"
+	ServiceAlertScopeTypeStopGroup                       = "StopGroup"
+	ServiceAlertScopeTypeStop                            = "Stop"
+)
+
+// GetServiceAlertMatchKeys turns scopes into the keys stored on alerts and used when looking them up.
+// Both sides going through here is what makes a scoped alert match its targets
+func GetServiceAlertMatchKeys(scopes ...ServiceAlertScope) []string {
+	var keys []string
+	seen := map[string]bool{}
+
+	for _, scope := range scopes {
+		var key string
+		if scope.Type == ServiceAlertScopeTypeWildcard {
+			key = string(ServiceAlertScopeTypeWildcard)
+		} else if scope.Identifier != "" {
+			key = fmt.Sprintf("%s:%s", scope.Type, scope.Identifier)
+		} else {
+			continue
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
 }
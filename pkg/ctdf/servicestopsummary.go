@@ -0,0 +1,39 @@
+package ctdf
+
+import "time"
+
+const ServiceStopSummaryIDFormat = "%s:%s"
+
+type ServiceStopSummaryDayType string
+
+const (
+	ServiceStopSummaryDayTypeWeekday  ServiceStopSummaryDayType = "Weekday"
+	ServiceStopSummaryDayTypeSaturday                           = "Saturday"
+	ServiceStopSummaryDayTypeSunday                             = "Sunday"
+)
+
+// ServiceStopSummary is a precomputed overview of how often a Service departs from a Stop
+type ServiceStopSummary struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	ServiceRef string `groups:"basic"`
+	StopRef    string `groups:"basic"`
+
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	DayTypes []*ServiceStopDayTypeSummary `groups:"basic"`
+}
+
+type ServiceStopDayTypeSummary struct {
+	DayType ServiceStopSummaryDayType `groups:"basic"`
+
+	FirstDeparture time.Time `groups:"basic"`
+	LastDeparture  time.Time `groups:"basic"`
+
+	NumberDepartures int `groups:"basic"`
+
+	// Median gap between consecutive departures, 0 if there's only a single departure
+	TypicalHeadwayMinutes int `groups:"basic"`
+}
@@ -1,16 +1,22 @@
 package ctdf
 
 import (
+	"context"
 	"encoding/binary"
 	"io"
 	"math"
 	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/pluscode"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 const GBStopIDFormat = "gb-atco-%s"
 
 type Stop struct {
-	PrimaryIdentifier string   `groups:"basic,search,search-llm,stop-llm" bson:",omitempty"`
+	PrimaryIdentifier string   `groups:"basic,search,search-llm,stop-llm,departures-llm" bson:",omitempty"`
 	OtherIdentifiers  []string `groups:"basic,search" bson:",omitempty"`
 
 	CreationDateTime     time.Time `groups:"detailed" bson:",omitempty"`
@@ -18,24 +24,87 @@ type Stop struct {
 
 	DataSource *DataSourceReference `groups:"detailed" bson:",omitempty"`
 
-	PrimaryName    string          `groups:"basic,search,search-llm,stop-llm" bson:",omitempty"`
+	PrimaryName    string          `groups:"basic,search,search-llm,stop-llm,departures-llm" bson:",omitempty"`
 	Descriptor     string          `groups:"basic,search" bson:",omitempty"`
 	TransportTypes []TransportType `groups:"detailed,search,search-llm,stop-llm" bson:",omitempty"`
 
+	// Other languages the name is officially in, eg. the Welsh half of a bilingual name
+	NameTranslations Translations `groups:"basic,search" bson:",omitempty"`
+
 	Timezone string `groups:"basic" bson:",omitempty"`
 
 	Location *Location `groups:"basic,stop-llm" bson:",omitempty"`
+	// Open Location Code for the stop, so it can be found from a code someone reads out or types in
+	PlusCode string `groups:"basic" bson:",omitempty"`
+
+	LocalityRef string    `groups:"internal" bson:",omitempty"`
+	Locality    *Locality `groups:"basic,search" bson:"-"`
 
 	Services []*Service `bson:"-" groups:"basic,search,search-llm,stop-llm"`
 
 	Active bool `groups:"basic" bson:",omitempty"`
+	// Status is the stops status in its source, stops without one are assumed to be in use
+	Status StopStatus `groups:"detailed" bson:",omitempty"`
+	// Dates the stop is in use between, either can be zero for an open ended window
+	EffectiveFrom  time.Time `groups:"detailed" bson:",omitempty"`
+	EffectiveUntil time.Time `groups:"detailed" bson:",omitempty"`
 
 	Associations []*Association `groups:"detailed" bson:",omitempty"`
 
+	StopType      StopType `groups:"detailed" bson:",omitempty"`
+	ParentStopRef string   `groups:"detailed" bson:",omitempty"`
+
 	Platforms []*StopPlatform `groups:"detailed" bson:",omitempty"`
-	// Entrances []*StopEntrance `groups:"detailed" bson:",omitempty"`
+	Entrances []*StopEntrance `groups:"detailed" bson:",omitempty"`
+
+	Importance *StopImportance `groups:"basic,search" bson:",omitempty"`
+
+	// Provenance is only set on stops merged from several sources & records where each field was taken from
+	Provenance []*FieldProvenance `groups:"internal" bson:",omitempty"`
+}
+
+type StopStatus string
+
+const (
+	StopStatusActive    StopStatus = "active"
+	StopStatusInactive  StopStatus = "inactive"
+	StopStatusPending   StopStatus = "pending"
+	StopStatusSuspended StopStatus = "suspended"
+)
+
+// InactiveStopStatuses are the statuses of stops that aren't in use
+var InactiveStopStatuses = []StopStatus{StopStatusInactive, StopStatusPending, StopStatusSuspended}
+
+// IsActiveOn checks the stop is both in use & within its effective dates
+func (s *Stop) IsActiveOn(dateTime time.Time) bool {
+	for _, status := range InactiveStopStatuses {
+		if s.Status == status {
+			return false
+		}
+	}
+
+	if !s.EffectiveFrom.IsZero() && dateTime.Before(s.EffectiveFrom) {
+		return false
+	}
+	if !s.EffectiveUntil.IsZero() && dateTime.After(s.EffectiveUntil) {
+		return false
+	}
+
+	return true
 }
 
+type StopType string
+
+const (
+	StopTypeStop         StopType = "stop"
+	StopTypeStation               = "station"
+	StopTypePlatform              = "platform"
+	StopTypeEntrance              = "entrance"
+	StopTypeNode                  = "node"
+	StopTypeBoardingArea          = "boardingarea"
+	StopTypeDock                  = "dock"
+)
+
 type StopPlatform struct {
 	PrimaryIdentifier string `groups:"basic"`
 
@@ -59,7 +128,38 @@ func (stop *Stop) GetAllStopIDs() []string {
 
 	allStopIDs = append(allStopIDs, stop.OtherIdentifiers...)
 
-	return allStopIDs
+	// Anything at a station should also include everything at its platforms & entrances
+	for _, platform := range stop.Platforms {
+		allStopIDs = append(allStopIDs, platform.PrimaryIdentifier)
+	}
+	for _, entrance := range stop.Entrances {
+		allStopIDs = append(allStopIDs, entrance.PrimaryIdentifier)
+	}
+
+	return util.RemoveDuplicateStrings(allStopIDs, []string{})
+}
+
+func (stop *Stop) Localise(languages []string) {
+	localiseText(&stop.PrimaryName, &stop.NameTranslations, languages)
+}
+
+// UpdatePlusCode sets the Plus Code from the stops location
+func (stop *Stop) UpdatePlusCode() {
+	if stop.Location == nil || len(stop.Location.Coordinates) != 2 {
+		stop.PlusCode = ""
+		return
+	}
+
+	stop.PlusCode = pluscode.Encode(stop.Location.Coordinates[1], stop.Location.Coordinates[0], pluscode.DefaultCodeLength)
+}
+
+func (stop *Stop) GetLocality() {
+	if stop.LocalityRef == "" {
+		return
+	}
+
+	localitiesCollection := database.GetCollection("localities")
+	localitiesCollection.FindOne(context.Background(), bson.M{"primaryidentifier": stop.LocalityRef}).Decode(&stop.Locality)
 }
 
 func (stop *Stop) UpdateNameFromServiceOverrides(service *Service) {
@@ -0,0 +1,29 @@
+package ctdf
+
+import "time"
+
+const StopDepartureIDFormat = "%s:%d:%s"
+
+// StopDeparture is a denormalised record of a Journey departing a Stop, materialised from journey paths so
+// departure boards can look up the journeys at a stop with an indexed scan rather than searching every path
+type StopDeparture struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	StopRef string `groups:"basic"`
+	// DayType is the day of the week (eg. Monday) the journey could run on. Journey availability still has to be
+	// checked against the actual date as this doesn't take into account date ranges or exclusions
+	DayType string `groups:"basic"`
+	// MinuteOfDay counts from the start of the service day so is past 1440 for journeys departing after midnight
+	MinuteOfDay int `groups:"basic"`
+
+	JourneyRef  string `groups:"basic"`
+	ServiceRef  string `groups:"basic"`
+	OperatorRef string `groups:"basic"`
+
+	// JourneyHash is the functional hash of the journey at materialisation time so unchanged journeys can be skipped
+	JourneyHash string `groups:"internal"`
+
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+}
@@ -0,0 +1,111 @@
+package ctdf
+
+import (
+	"math"
+	"time"
+)
+
+type StopImportanceClass string
+
+const (
+	StopImportanceMajorInterchange StopImportanceClass = "MajorInterchange"
+	StopImportanceInterchange                          = "Interchange"
+	StopImportanceMajor                                = "Major"
+	StopImportanceLocal                                = "Local"
+	StopImportanceMinor                                = "Minor"
+)
+
+// Counts at which the service & departure parts of the score max out, both are on a log scale
+// so the difference between 1 & 5 services counts for more than 40 & 45
+const (
+	stopImportanceMaximumServices   = 50
+	stopImportanceMaximumDepartures = 2000
+)
+
+// A stop served by this many services is an interchange even if they are all the same mode
+const stopImportanceInterchangeServices = 10
+
+// How much each mode adds, a stop takes the highest of the modes calling at it
+var stopImportanceTransportTypeWeights = map[TransportType]float64{
+	TransportTypeRail:      1,
+	TransportTypeAirport:   1,
+	TransportTypeMetro:     0.8,
+	TransportTypeTram:      0.6,
+	TransportTypeFerry:     0.6,
+	TransportTypeCoach:     0.5,
+	TransportTypeCableCar:  0.4,
+	TransportTypeFunicular: 0.4,
+	TransportTypeBus:       0.3,
+}
+
+// StopImportance ranks how significant a stop is so search can put the busiest stops first
+// and maps can decide which stop labels to show when they don't have room for all of them
+type StopImportance struct {
+	// Score is between 0 & 1
+	Score float64             `groups:"basic,search"`
+	Class StopImportanceClass `groups:"basic,search"`
+
+	Services          int  `groups:"detailed"`
+	WeekdayDepartures int  `groups:"detailed"`
+	Interchange       bool `groups:"detailed"`
+
+	ModificationDateTime time.Time `groups:"detailed"`
+}
+
+// CalculateStopImportance weights the number of services, weekday departures, modes & interchange status of the stop
+func CalculateStopImportance(stop *Stop, services int, weekdayDepartures int) *StopImportance {
+	importance := &StopImportance{
+		Services:             services,
+		WeekdayDepartures:    weekdayDepartures,
+		ModificationDateTime: time.Now(),
+	}
+
+	var transportTypes []TransportType
+	modeWeight := 0.0
+	for _, transportType := range stop.TransportTypes {
+		weight, exists := stopImportanceTransportTypeWeights[transportType]
+		if !exists {
+			continue
+		}
+
+		transportTypes = append(transportTypes, transportType)
+		modeWeight = math.Max(modeWeight, weight)
+	}
+
+	importance.Interchange = len(transportTypes) > 1 ||
+		(stop.StopType == StopTypeStation && len(stop.Platforms) > 1) ||
+		services >= stopImportanceInterchangeServices
+
+	interchangeWeight := 0.0
+	if importance.Interchange {
+		interchangeWeight = 1
+	}
+
+	importance.Score = 0.4*logScale(services, stopImportanceMaximumServices) +
+		0.3*logScale(weekdayDepartures, stopImportanceMaximumDepartures) +
+		0.15*modeWeight +
+		0.15*interchangeWeight
+
+	switch {
+	case importance.Interchange && importance.Score >= 0.6:
+		importance.Class = StopImportanceMajorInterchange
+	case importance.Interchange:
+		importance.Class = StopImportanceInterchange
+	case importance.Score >= 0.5:
+		importance.Class = StopImportanceMajor
+	case importance.Score >= 0.2:
+		importance.Class = StopImportanceLocal
+	default:
+		importance.Class = StopImportanceMinor
+	}
+
+	return importance
+}
+
+func logScale(value int, maximum int) float64 {
+	if value <= 0 {
+		return 0
+	}
+
+	return math.Min(math.Log1p(float64(value))/math.Log1p(float64(maximum)), 1)
+}
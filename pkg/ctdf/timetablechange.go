@@ -0,0 +1,33 @@
+package ctdf
+
+import "time"
+
+// TimetableChange describes how a re-import changed the scheduled journeys of a service
+type TimetableChange struct {
+	DatasetRef string
+	ServiceRef string
+
+	// Run of the dataset the change is from & to
+	PreviousRun string
+	Run         string
+
+	// Earliest date the changed timetable is valid from, zero if it isn't known
+	EffectiveFrom time.Time
+
+	Retimed   []*TimetableChangeJourney
+	Cancelled []*TimetableChangeJourney
+	Added     []*TimetableChangeJourney
+}
+
+// TimetableChangeJourney is a journey before & after the change, Before is nil for added journeys & After for cancelled
+type TimetableChangeJourney struct {
+	JourneyRef string
+
+	Before *TimetableChangeJourneyTimes
+	After  *TimetableChangeJourneyTimes
+}
+
+type TimetableChangeJourneyTimes struct {
+	DepartureTime time.Time
+	PathTimes     []time.Time
+}
@@ -0,0 +1,63 @@
+package ctdf
+
+import (
+	"math"
+	"time"
+)
+
+const TransferIDFormat = "%s-transfer-%s-%s"
+
+// Transfer describes an interchange between 2 stops and how long it takes to make it
+type Transfer struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	FromStopRef string `groups:"basic"`
+	ToStopRef   string `groups:"basic"`
+
+	Type TransferType `groups:"basic"`
+
+	// Minimum time in seconds needed to make the transfer, 0 if unknown
+	MinimumTransferTime int `groups:"basic"`
+	// Walking distance in metres, 0 if unknown
+	WalkingDistance int `groups:"basic" bson:",omitempty"`
+
+	// Inferred transfers were generated from stop locations rather than published by the data source
+	Inferred bool `groups:"basic"`
+}
+
+type TransferType string
+
+const (
+	TransferTypeRecommended TransferType = "Recommended"
+	TransferTypeTimed                    = "Timed"
+	TransferTypeMinimumTime              = "MinimumTime"
+	TransferTypeNotPossible              = "NotPossible"
+	TransferTypeInSeat                   = "InSeat"
+)
+
+const (
+	TransferWalkingSpeed     = 1.2 // metres per second
+	transferMinimumTime      = 60  // seconds
+	transferTimeRoundingUnit = 30  // seconds
+)
+
+// EstimateTransferTime gives a walking time in seconds for a transfer between 2 stops based on the straight line distance
+func EstimateTransferTime(from *Location, to *Location) int {
+	if from == nil || to == nil || len(from.Coordinates) != 2 || len(to.Coordinates) != 2 {
+		return transferMinimumTime
+	}
+
+	return RoundTransferTime(from.GreatCircleDistance(to) / TransferWalkingSpeed)
+}
+
+// RoundTransferTime rounds a walking time in seconds up to the next 30 seconds with a minimum of a minute to allow for finding the way
+func RoundTransferTime(seconds float64) int {
+	seconds = math.Ceil(seconds/transferTimeRoundingUnit) * transferTimeRoundingUnit
+
+	return int(math.Max(seconds, transferMinimumTime))
+}
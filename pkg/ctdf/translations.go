@@ -0,0 +1,162 @@
+package ctdf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// DefaultLanguage is the language primary names & texts are in, their translations are in the other languages
+const DefaultLanguage = "en"
+
+// Translations are alternative versions of a name keyed on the ISO 639-1 code of their language, eg. cy for Welsh
+type Translations map[string]string
+
+// TranslatedText is a text as a source provides it with its language, which may be empty when not given
+type TranslatedText struct {
+	Language string
+	Text     string
+}
+
+// SplitTranslations picks the primary text from the versions a source provides, preferring the default language
+// then one without a language then the first. The rest become its translations.
+func SplitTranslations(texts []TranslatedText) (string, Translations) {
+	if len(texts) == 0 {
+		return "", nil
+	}
+
+	primary := 0
+	for i, text := range texts {
+		language := NormaliseLanguage(text.Language)
+		if language == DefaultLanguage {
+			primary = i
+			break
+		} else if language == "" && NormaliseLanguage(texts[primary].Language) != "" {
+			primary = i
+		}
+	}
+
+	var translations Translations
+	for i, text := range texts {
+		language := NormaliseLanguage(text.Language)
+		if i == primary || language == "" || language == DefaultLanguage || text.Text == "" {
+			continue
+		}
+
+		if translations == nil {
+			translations = Translations{}
+		}
+		translations[language] = text.Text
+	}
+
+	return texts[primary].Text, translations
+}
+
+// NormaliseLanguage reduces a language tag to its lower case ISO 639-1 code, eg. cy-GB to cy
+func NormaliseLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	language, _, _ = strings.Cut(language, "-")
+	language, _, _ = strings.Cut(language, "_")
+
+	return language
+}
+
+// ParseLanguagePreference turns a list of languages like an Accept-Language header (cy-GB,cy;q=0.9,en;q=0.8)
+// into the codes in the order they're preferred. Quality values are ignored as clients already list them in order.
+func ParseLanguagePreference(preference string) []string {
+	var languages []string
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(preference, ",") {
+		language, _, _ := strings.Cut(part, ";")
+		language = NormaliseLanguage(language)
+
+		if language == "" || language == "*" || seen[language] {
+			continue
+		}
+		seen[language] = true
+		languages = append(languages, language)
+	}
+
+	return languages
+}
+
+// localiseText swaps the text for its translation in the first preferred language that it has.
+// The default language counts as always available so nothing after it is used, the replaced text becomes a translation.
+func localiseText(text *string, translations *Translations, languages []string) {
+	for _, language := range languages {
+		if language == DefaultLanguage {
+			return
+		}
+
+		translation, exists := (*translations)[language]
+		if !exists || translation == "" {
+			continue
+		}
+
+		swapped := Translations{DefaultLanguage: *text}
+		for otherLanguage, otherTranslation := range *translations {
+			if otherLanguage != language {
+				swapped[otherLanguage] = otherTranslation
+			}
+		}
+
+		*text = translation
+		*translations = swapped
+
+		return
+	}
+}
+
+// Localisable objects can swap their names & texts for their translations in a preferred language
+type Localisable interface {
+	Localise(languages []string)
+}
+
+// Localise walks the value & localises every Localisable object in it for the preferred languages,
+// falling back to the next language & finally the default language when there isn't a translation
+func Localise(value interface{}, languages []string) {
+	if len(languages) == 0 {
+		return
+	}
+
+	localiseValue(reflect.ValueOf(value), languages, map[uintptr]bool{})
+}
+
+func localiseValue(value reflect.Value, languages []string, visited map[uintptr]bool) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() || visited[value.Pointer()] {
+			return
+		}
+		visited[value.Pointer()] = true
+
+		// The struct it points to is addressable so gets localised there
+		localiseValue(value.Elem(), languages, visited)
+	case reflect.Interface:
+		if !value.IsNil() {
+			localiseValue(value.Elem(), languages, visited)
+		}
+	case reflect.Struct:
+		if value.CanAddr() {
+			if localisable, ok := value.Addr().Interface().(Localisable); ok {
+				localisable.Localise(languages)
+			}
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				localiseValue(value.Field(i), languages, visited)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			localiseValue(value.Index(i), languages, visited)
+		}
+	case reflect.Map:
+		// Map values can't be changed in place so only pointers in them get localised
+		iterator := value.MapRange()
+		for iterator.Next() {
+			localiseValue(iterator.Value(), languages, visited)
+		}
+	}
+}
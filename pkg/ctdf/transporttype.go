@@ -1,18 +1,103 @@
 package ctdf
 
+import (
+	"slices"
+	"strings"
+)
+
 type TransportType string
 
 //goland:noinspection GoUnusedConst
 const (
-	TransportTypeBus       TransportType = "Bus"
-	TransportTypeCoach                   = "Coach"
-	TransportTypeTram                    = "Tram"
-	TransportTypeTaxi                    = "Taxi"
-	TransportTypeRail                    = "Rail"
-	TransportTypeMetro                   = "Metro"
-	TransportTypeFerry                   = "Ferry"
-	TransportTypeAirport                 = "Airport"
-	TransportTypeCableCar                = "CableCar"
-	TransportTypeFunicular               = "Funicular"
-	TransportTypeUnknown                 = "UNKNOWN"
+	TransportTypeBus          TransportType = "Bus"
+	TransportTypeCoach                      = "Coach"
+	TransportTypeTram                       = "Tram"
+	TransportTypeTaxi                       = "Taxi"
+	TransportTypeRail                       = "Rail"
+	TransportTypeMetro                      = "Metro"
+	TransportTypeFerry                      = "Ferry"
+	TransportTypeAirport                    = "Airport"
+	TransportTypeCableCar                   = "CableCar"
+	TransportTypeFunicular                  = "Funicular"
+	TransportTypeBikeShare                  = "BikeShare"
+	TransportTypeScooterShare               = "ScooterShare"
+	TransportTypeUnknown                    = "UNKNOWN"
 )
+
+// TransportTypes is every known transport type, excluding unknown
+var TransportTypes = []TransportType{
+	TransportTypeBus,
+	TransportTypeCoach,
+	TransportTypeTram,
+	TransportTypeTaxi,
+	TransportTypeRail,
+	TransportTypeMetro,
+	TransportTypeFerry,
+	TransportTypeAirport,
+	TransportTypeCableCar,
+	TransportTypeFunicular,
+	TransportTypeBikeShare,
+	TransportTypeScooterShare,
+}
+
+// transportTypeAliases maps the mode names used by the source formats (TransXChange Mode, Traveline NOC Mode) that
+// don't match a transport type name directly
+var transportTypeAliases = map[string]TransportType{
+	"trolleybus":   TransportTypeBus,
+	"drt":          TransportTypeBus,
+	"permit":       TransportTypeBus,
+	"ct operator":  TransportTypeBus,
+	"underground":  TransportTypeMetro,
+	"subway":       TransportTypeMetro,
+	"lightrail":    TransportTypeTram,
+	"boat":         TransportTypeFerry,
+	"water":        TransportTypeFerry,
+	"telecabine":   TransportTypeCableCar,
+	"cable car":    TransportTypeCableCar,
+	"air":          TransportTypeAirport,
+	"airline":      TransportTypeAirport,
+	"train":        TransportTypeRail,
+	"heritage":     TransportTypeRail,
+	"private hire": TransportTypeTaxi,
+	"cycle hire":   TransportTypeBikeShare,
+	"bike share":   TransportTypeBikeShare,
+	"scooter":      TransportTypeScooterShare,
+}
+
+// ParseTransportType turns a mode name from any of the source formats into a transport type, returning
+// TransportTypeUnknown if it isn't recognised
+func ParseTransportType(mode string) TransportType {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+
+	for _, transportType := range TransportTypes {
+		if strings.ToLower(string(transportType)) == mode {
+			return transportType
+		}
+	}
+
+	if transportType, exists := transportTypeAliases[mode]; exists {
+		return transportType
+	}
+
+	return TransportTypeUnknown
+}
+
+// ParseTransportTypeList parses a comma separated list of transport types, ignoring any that aren't recognised
+func ParseTransportTypeList(modes string) []TransportType {
+	var transportTypes []TransportType
+
+	for _, mode := range strings.Split(modes, ",") {
+		transportType := ParseTransportType(mode)
+
+		if transportType != TransportTypeUnknown {
+			transportTypes = append(transportTypes, transportType)
+		}
+	}
+
+	return transportTypes
+}
+
+// MatchesTransportTypes checks a transport type against a filter, an empty filter matches everything
+func MatchesTransportTypes(filter []TransportType, transportType TransportType) bool {
+	return len(filter) == 0 || slices.Contains(filter, transportType)
+}
@@ -0,0 +1,24 @@
+package ctdf
+
+import "time"
+
+const VehicleIDFormat = "%s-vehicle-%s"
+
+type Vehicle struct {
+	PrimaryIdentifier string `groups:"basic"`
+
+	CreationDateTime     time.Time `groups:"detailed"`
+	ModificationDateTime time.Time `groups:"detailed"`
+
+	DataSource *DataSourceReference `groups:"internal"`
+
+	VehicleRef string `groups:"basic"`
+
+	RealtimeJourneyRef string `groups:"basic"`
+	ServiceRef         string `groups:"basic"`
+
+	Location Location `groups:"basic"`
+	Bearing  float64  `groups:"basic"`
+
+	Occupancy RealtimeJourneyOccupancy `groups:"detailed"`
+}
@@ -0,0 +1,41 @@
+package ctdf
+
+import "time"
+
+// Vehicle locations are only kept this often for the track, updates in between are dropped
+const VehicleTrackSampleInterval = 15 * time.Second
+
+// Most points kept for a journey, the oldest are dropped first. At the sample interval this is around 2 hours
+const VehicleTrackMaxLength = 480
+
+type VehicleTrackPoint struct {
+	Location  Location  `groups:"basic"`
+	Bearing   float64   `groups:"basic"`
+	Timestamp time.Time `groups:"basic"`
+}
+
+// VehicleTrack is where a vehicle has been on its current realtime journey, oldest point first
+type VehicleTrack struct {
+	RealtimeJourneyRef string `groups:"basic"`
+	VehicleRef         string `groups:"basic"`
+
+	Points []*VehicleTrackPoint `groups:"basic"`
+}
+
+// NeedsVehicleTrackPoint checks if a new location is far enough after the last point to be sampled into the track
+func NeedsVehicleTrackPoint(lastPoint *VehicleTrackPoint, location Location, timestamp time.Time) bool {
+	if location.Type == "" || len(location.Coordinates) != 2 {
+		return false
+	}
+
+	if lastPoint == nil || len(lastPoint.Location.Coordinates) != 2 {
+		return true
+	}
+
+	if timestamp.Sub(lastPoint.Timestamp) < VehicleTrackSampleInterval {
+		return false
+	}
+
+	// Stationary vehicles would just fill the track with the same point
+	return !(lastPoint.Location.Coordinates[0] == location.Coordinates[0] && lastPoint.Location.Coordinates[1] == location.Coordinates[1])
+}
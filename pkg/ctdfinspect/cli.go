@@ -0,0 +1,73 @@
+package ctdfinspect
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+type inspector func(identifier string, date time.Time) (*Report, error)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "ctdf",
+		Usage: "Tools for working with CTDF objects",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "inspect",
+				Usage: "print a summary of a single object with its references resolved, useful for debugging bad data reports",
+				Subcommands: []*cli.Command{
+					registerInspectCLI("journey", Journey),
+					registerInspectCLI("stop", Stop),
+					registerInspectCLI("service", Service),
+					registerInspectCLI("operator", Operator),
+				},
+			},
+		},
+	}
+}
+
+func registerInspectCLI(objectType string, inspect inspector) *cli.Command {
+	return &cli.Command{
+		Name:      objectType,
+		Usage:     fmt.Sprintf("inspect a %s", objectType),
+		ArgsUsage: "<identifier>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "date",
+				Usage: "Date to evaluate availability for in YYYY-MM-DD format, defaults to today",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				return errors.New(fmt.Sprintf("Expected a single %s identifier", objectType))
+			}
+
+			date := time.Now()
+			if c.String("date") != "" {
+				var err error
+				date, err = time.ParseInLocation(time.DateOnly, c.String("date"), time.Local)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := database.Connect(); err != nil {
+				return err
+			}
+
+			report, err := inspect(c.Args().First(), date)
+			if err != nil {
+				return err
+			}
+
+			report.Print(os.Stdout)
+
+			return nil
+		},
+	}
+}
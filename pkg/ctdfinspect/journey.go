@@ -0,0 +1,137 @@
+package ctdfinspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Journey loads the journey, resolves its service, operator & stops and reports whether it runs on the date
+// along with its current realtime state
+func Journey(identifier string, date time.Time) (*Report, error) {
+	var journey *ctdf.Journey
+	database.GetCollection("journeys").FindOne(context.Background(), bson.M{"primaryidentifier": identifier}).Decode(&journey)
+	if journey == nil {
+		return nil, errors.New(fmt.Sprintf("Could not find journey %s", identifier))
+	}
+
+	journey.GetReferences()
+	journey.GetDeepReferences()
+
+	report := &Report{Title: fmt.Sprintf("Journey %s", journey.PrimaryIdentifier)}
+
+	details := report.AddSection("Details")
+	details.Add("Service", describeReference(journey.ServiceRef, getServiceName(journey.Service), journey.Service != nil))
+	details.Add("Operator", describeReference(journey.OperatorRef, getOperatorName(journey.Operator), journey.Operator != nil))
+	details.Add("Transport type", string(journey.GetTransportType()))
+	details.Add("Direction", journey.Direction)
+	details.Add("Destination", journey.DestinationDisplay)
+	details.Add("Departure time", fmt.Sprintf("%s %s", journey.DepartureTime.Format("15:04:05"), journey.DepartureTimezone))
+	details.Add("Block", journey.BlockRef)
+	details.Add("Data source", describeDataSource(journey.DataSource))
+	details.Add("Modified", describeTime(journey.ModificationDateTime))
+
+	if journey.Service == nil {
+		report.AddProblem("Service %s does not resolve", journey.ServiceRef)
+	}
+	if journey.Operator == nil {
+		report.AddProblem("Operator %s does not resolve", journey.OperatorRef)
+	}
+
+	availability := report.AddSection("Availability")
+	if journey.Availability == nil {
+		availability.Add("", missing)
+		report.AddProblem("Journey has no availability so never runs")
+	} else {
+		availability.Add(date.Format(time.DateOnly), describeRuns(journey.RunsOn(date)))
+		availability.Add("Days of week", fmt.Sprint(journey.Availability.PossibleDaysOfWeek()))
+		availability.Add("Rules", fmt.Sprintf("%d match, %d secondary, %d condition, %d exclude, %d include",
+			len(journey.Availability.Match), len(journey.Availability.MatchSecondary), len(journey.Availability.Condition),
+			len(journey.Availability.Exclude), len(journey.Availability.Include)))
+	}
+
+	path := report.AddSection("Path")
+	if len(journey.Path) == 0 {
+		path.Add("", missing)
+		report.AddProblem("Journey has no path")
+	}
+	for i, pathItem := range journey.Path {
+		path.Add(pathItem.OriginDepartureTime.Format("15:04"), describeStop(pathItem.OriginStopRef, pathItem.OriginStop, pathItem.OriginPlatform))
+		if pathItem.OriginStop == nil {
+			report.AddProblem("Stop %s at position %d does not resolve", pathItem.OriginStopRef, i)
+		}
+
+		if i == len(journey.Path)-1 {
+			path.Add(pathItem.DestinationArrivalTime.Format("15:04"), describeStop(pathItem.DestinationStopRef, pathItem.DestinationStop, pathItem.DestinationPlatform))
+			if pathItem.DestinationStop == nil {
+				report.AddProblem("Stop %s at position %d does not resolve", pathItem.DestinationStopRef, i+1)
+			}
+		}
+	}
+
+	journey.GetRealtimeJourney(nil)
+	realtime := report.AddSection("Realtime")
+	if journey.RealtimeJourney == nil {
+		realtime.Add("", "Not currently tracked")
+	} else {
+		realtimeJourney := journey.RealtimeJourney
+
+		realtime.Add("Identifier", realtimeJourney.PrimaryIdentifier)
+		realtime.Add("Run date", realtimeJourney.JourneyRunDate.Format(time.DateOnly))
+		realtime.Add("Actively tracked", fmt.Sprint(realtimeJourney.ActivelyTracked))
+		realtime.Add("Cancelled", fmt.Sprint(realtimeJourney.Cancelled))
+		realtime.Add("Reliability", string(realtimeJourney.Reliability))
+		realtime.Add("Vehicle", realtimeJourney.VehicleRef)
+		realtime.Add("Offset", realtimeJourney.Offset.String())
+		realtime.Add("Departed stop", realtimeJourney.DepartedStopRef)
+		realtime.Add("Next stop", realtimeJourney.NextStopRef)
+		realtime.Add("Progress", fmt.Sprintf("%.0f%%", realtimeJourney.ProgressPercentage))
+		realtime.Add("Data source", describeDataSource(realtimeJourney.DataSource))
+		realtime.Add("Modified", describeTime(realtimeJourney.ModificationDateTime))
+	}
+
+	return report, nil
+}
+
+func describeRuns(runs bool) string {
+	if runs {
+		return "Runs"
+	}
+
+	return "Does not run"
+}
+
+func describeStop(stopRef string, stop *ctdf.Stop, platform string) string {
+	name := ""
+	if stop != nil {
+		name = stop.PrimaryName
+	}
+
+	description := describeReference(stopRef, name, stop != nil)
+	if platform != "" {
+		description = fmt.Sprintf("%s platform %s", description, platform)
+	}
+
+	return description
+}
+
+func getServiceName(service *ctdf.Service) string {
+	if service == nil {
+		return ""
+	}
+
+	return service.ServiceName
+}
+
+func getOperatorName(operator *ctdf.Operator) string {
+	if operator == nil {
+		return ""
+	}
+
+	return operator.PrimaryName
+}
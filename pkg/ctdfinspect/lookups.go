@@ -0,0 +1,49 @@
+package ctdfinspect
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// countJourneys counts the journeys matching the filter & how many of them run on the date
+func countJourneys(filter bson.M, date time.Time) (int, int, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "availability", Value: 1},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int
+	var running int
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			continue
+		}
+
+		total += 1
+		if journey.RunsOn(date) {
+			running += 1
+		}
+	}
+
+	return total, running, cursor.Err()
+}
+
+// countActiveRealtimeJourneys counts the realtime journeys matching the filter that are still being updated
+func countActiveRealtimeJourneys(filter bson.M) (int64, error) {
+	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+
+	filter["modificationdatetime"] = bson.M{"$gt": ctdf.GetActiveRealtimeJourneyCutOffDate()}
+
+	return realtimeJourneysCollection.CountDocuments(context.Background(), filter)
+}
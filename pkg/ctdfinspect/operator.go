@@ -0,0 +1,72 @@
+package ctdfinspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/identifiers"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Operator loads the operator, resolves its group and reports how many services & journeys it runs on the date
+// along with how many are currently being tracked
+func Operator(identifier string, date time.Time) (*Report, error) {
+	var operator *ctdf.Operator
+	database.GetCollection("operators").FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeOperator, identifier)).Decode(&operator)
+	if operator == nil {
+		return nil, errors.New(fmt.Sprintf("Could not find operator %s", identifier))
+	}
+
+	operator.GetReferences()
+
+	report := &Report{Title: fmt.Sprintf("Operator %s", operator.PrimaryIdentifier)}
+
+	details := report.AddSection("Details")
+	details.Add("Name", operator.PrimaryName)
+	details.Add("Transport type", string(operator.TransportType))
+	details.Add("Other identifiers", fmt.Sprint(operator.OtherIdentifiers))
+	if operator.OperatorGroupRef != "" {
+		groupName := ""
+		if operator.OperatorGroup != nil {
+			groupName = operator.OperatorGroup.Name
+		} else {
+			report.AddProblem("Operator group %s does not resolve", operator.OperatorGroupRef)
+		}
+		details.Add("Group", describeReference(operator.OperatorGroupRef, groupName, operator.OperatorGroup != nil))
+	}
+	details.Add("Licence", operator.Licence)
+	details.Add("Website", operator.Website)
+	details.Add("Data source", describeDataSource(operator.DataSource))
+	details.Add("Modified", describeTime(operator.ModificationDateTime))
+
+	operatorRefs := append([]string{operator.PrimaryIdentifier}, operator.OtherIdentifiers...)
+
+	services, err := database.GetCollection("services").CountDocuments(context.Background(), bson.M{"operatorref": bson.M{"$in": operatorRefs}})
+	if err != nil {
+		return nil, err
+	}
+	total, running, err := countJourneys(bson.M{"operatorref": bson.M{"$in": operatorRefs}}, date)
+	if err != nil {
+		return nil, err
+	}
+	journeys := report.AddSection("Journeys")
+	journeys.Add("Services", fmt.Sprint(services))
+	journeys.Add("Total", fmt.Sprint(total))
+	journeys.Add(date.Format(time.DateOnly), fmt.Sprintf("%d run", running))
+	if services == 0 {
+		report.AddProblem("Operator has no services")
+	}
+
+	activeRealtimeJourneys, err := countActiveRealtimeJourneys(bson.M{"journey.operatorref": bson.M{"$in": operatorRefs}})
+	if err != nil {
+		return nil, err
+	}
+	realtime := report.AddSection("Realtime")
+	realtime.Add("Tracking", fmt.Sprintf("%d realtime journeys", activeRealtimeJourneys))
+
+	return report, nil
+}
@@ -0,0 +1,97 @@
+package ctdfinspect
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+const missing = "<missing>"
+
+// Report is the human readable summary of an inspected object
+type Report struct {
+	Title    string
+	Sections []*Section
+
+	// Anything that looks wrong with the object, eg. references that don't resolve
+	Problems []string
+}
+
+type Section struct {
+	Title string
+	Rows  [][2]string
+}
+
+func (r *Report) AddSection(title string) *Section {
+	section := &Section{Title: title}
+	r.Sections = append(r.Sections, section)
+
+	return section
+}
+
+func (r *Report) AddProblem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func (s *Section) Add(label string, value string) {
+	s.Rows = append(s.Rows, [2]string{label, value})
+}
+
+// Print writes the report out with the values of each section lined up
+func (r *Report) Print(writer io.Writer) {
+	fmt.Fprintln(writer, r.Title)
+
+	for _, section := range r.Sections {
+		fmt.Fprintf(writer, "\n%s\n", section.Title)
+
+		labelWidth := 0
+		for _, row := range section.Rows {
+			labelWidth = max(labelWidth, len(row[0]))
+		}
+
+		for _, row := range section.Rows {
+			if row[0] == "" {
+				fmt.Fprintf(writer, "  %s\n", row[1])
+			} else {
+				fmt.Fprintf(writer, "  %s%s  %s\n", row[0], strings.Repeat(" ", labelWidth-len(row[0])), row[1])
+			}
+		}
+	}
+
+	if len(r.Problems) > 0 {
+		fmt.Fprintf(writer, "\nProblems\n")
+		for _, problem := range r.Problems {
+			fmt.Fprintf(writer, "  - %s\n", problem)
+		}
+	}
+}
+
+func describeReference(ref string, name string, resolved bool) string {
+	if ref == "" {
+		return missing
+	}
+	if !resolved {
+		return fmt.Sprintf("%s (unresolved)", ref)
+	}
+
+	return fmt.Sprintf("%s (%s)", ref, name)
+}
+
+func describeDataSource(dataSource *ctdf.DataSourceReference) string {
+	if dataSource == nil {
+		return missing
+	}
+
+	return fmt.Sprintf("%s from %s (%s)", dataSource.DatasetID, dataSource.ProviderName, dataSource.OriginalFormat)
+}
+
+func describeTime(dateTime time.Time) string {
+	if dateTime.IsZero() {
+		return missing
+	}
+
+	return fmt.Sprintf("%s (%s ago)", dateTime.Local().Format(time.DateTime), time.Since(dateTime).Round(time.Second))
+}
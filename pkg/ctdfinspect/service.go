@@ -0,0 +1,68 @@
+package ctdfinspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/identifiers"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Service loads the service, resolves its operator and reports how many of its journeys run on the date
+// along with how many are currently being tracked
+func Service(identifier string, date time.Time) (*Report, error) {
+	var service *ctdf.Service
+	database.GetCollection("services").FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeService, identifier)).Decode(&service)
+	if service == nil {
+		return nil, errors.New(fmt.Sprintf("Could not find service %s", identifier))
+	}
+
+	var operator *ctdf.Operator
+	database.GetCollection("operators").FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeOperator, service.OperatorRef)).Decode(&operator)
+
+	report := &Report{Title: fmt.Sprintf("Service %s", service.PrimaryIdentifier)}
+
+	details := report.AddSection("Details")
+	details.Add("Name", service.ServiceName)
+	details.Add("Operator", describeReference(service.OperatorRef, getOperatorName(operator), operator != nil))
+	details.Add("Transport type", string(service.TransportType))
+	details.Add("Other identifiers", fmt.Sprint(service.OtherIdentifiers))
+	details.Add("Data source", describeDataSource(service.DataSource))
+	details.Add("Modified", describeTime(service.ModificationDateTime))
+
+	if operator == nil {
+		report.AddProblem("Operator %s does not resolve", service.OperatorRef)
+	}
+
+	routes := report.AddSection("Routes")
+	if len(service.Routes) == 0 {
+		routes.Add("", missing)
+	}
+	for _, route := range service.Routes {
+		routes.Add(route.Direction, fmt.Sprintf("%s to %s (%d stops, %d journeys)", route.Origin, route.Destination, len(route.StopRefs), route.NumberJourneys))
+	}
+
+	total, running, err := countJourneys(bson.M{"serviceref": service.PrimaryIdentifier}, date)
+	if err != nil {
+		return nil, err
+	}
+	journeys := report.AddSection("Journeys")
+	journeys.Add("Total", fmt.Sprint(total))
+	journeys.Add(date.Format(time.DateOnly), fmt.Sprintf("%d run", running))
+	if total == 0 {
+		report.AddProblem("Service has no journeys")
+	}
+
+	activeRealtimeJourneys, err := countActiveRealtimeJourneys(bson.M{"journey.serviceref": service.PrimaryIdentifier})
+	if err != nil {
+		return nil, err
+	}
+	realtime := report.AddSection("Realtime")
+	realtime.Add("Tracking", fmt.Sprintf("%d realtime journeys", activeRealtimeJourneys))
+
+	return report, nil
+}
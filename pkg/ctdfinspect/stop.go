@@ -0,0 +1,91 @@
+package ctdfinspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/identifiers"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Stop loads the stop, resolves its locality & parent and reports how many journeys call there on the date
+// along with the realtime journeys heading to it
+func Stop(identifier string, date time.Time) (*Report, error) {
+	var stop *ctdf.Stop
+	database.GetCollection("stops").FindOne(context.Background(), identifiers.Filter(identifiers.ObjectTypeStop, identifier)).Decode(&stop)
+	if stop == nil {
+		return nil, errors.New(fmt.Sprintf("Could not find stop %s", identifier))
+	}
+
+	stop.GetLocality()
+
+	report := &Report{Title: fmt.Sprintf("Stop %s", stop.PrimaryIdentifier)}
+
+	details := report.AddSection("Details")
+	details.Add("Name", stop.PrimaryName)
+	details.Add("Descriptor", stop.Descriptor)
+	details.Add("Type", string(stop.StopType))
+	details.Add("Transport types", fmt.Sprint(stop.TransportTypes))
+	details.Add("Active", fmt.Sprint(stop.Active))
+	details.Add("Other identifiers", fmt.Sprint(stop.OtherIdentifiers))
+	if stop.Location != nil && len(stop.Location.Coordinates) == 2 {
+		details.Add("Location", fmt.Sprintf("%f, %f", stop.Location.Coordinates[1], stop.Location.Coordinates[0]))
+	} else {
+		details.Add("Location", missing)
+		report.AddProblem("Stop has no location")
+	}
+	if stop.LocalityRef != "" {
+		localityName := ""
+		if stop.Locality != nil {
+			localityName = stop.Locality.Name
+		} else {
+			report.AddProblem("Locality %s does not resolve", stop.LocalityRef)
+		}
+		details.Add("Locality", describeReference(stop.LocalityRef, localityName, stop.Locality != nil))
+	}
+	if stop.ParentStopRef != "" {
+		var parentStop *ctdf.Stop
+		database.GetCollection("stops").FindOne(context.Background(), bson.M{"primaryidentifier": stop.ParentStopRef}).Decode(&parentStop)
+		if parentStop == nil {
+			report.AddProblem("Parent stop %s does not resolve", stop.ParentStopRef)
+		}
+		details.Add("Parent stop", describeStop(stop.ParentStopRef, parentStop, ""))
+	}
+	details.Add("Platforms", fmt.Sprint(len(stop.Platforms)))
+	details.Add("Entrances", fmt.Sprint(len(stop.Entrances)))
+	details.Add("Data source", describeDataSource(stop.DataSource))
+	details.Add("Modified", describeTime(stop.ModificationDateTime))
+
+	if len(stop.Associations) > 0 {
+		associations := report.AddSection("Associations")
+		for _, association := range stop.Associations {
+			associations.Add(association.Type, association.AssociatedIdentifier)
+		}
+	}
+
+	stopIDs := stop.GetAllStopIDs()
+
+	total, running, err := countJourneys(bson.M{"path.originstopref": bson.M{"$in": stopIDs}}, date)
+	if err != nil {
+		return nil, err
+	}
+	journeys := report.AddSection("Journeys")
+	journeys.Add("Departing", fmt.Sprint(total))
+	journeys.Add(date.Format(time.DateOnly), fmt.Sprintf("%d run", running))
+	if running == 0 && stop.Active {
+		report.AddProblem("Stop is active but no journeys depart from it on %s", date.Format(time.DateOnly))
+	}
+
+	activeRealtimeJourneys, err := countActiveRealtimeJourneys(bson.M{"nextstopref": bson.M{"$in": stopIDs}})
+	if err != nil {
+		return nil, err
+	}
+	realtime := report.AddSection("Realtime")
+	realtime.Add("Approaching", fmt.Sprintf("%d realtime journeys", activeRealtimeJourneys))
+
+	return report, nil
+}
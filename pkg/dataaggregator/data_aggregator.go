@@ -4,24 +4,124 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/travigo/travigo/pkg/dataaggregator/source"
 
 	"github.com/rs/zerolog/log"
 )
 
+// SourceOptions control how a source is used when several sources can answer the same query type
+type SourceOptions struct {
+	// Priority orders sources supporting the same type, highest first. Sources with the same priority are tried in
+	// the order they were registered
+	Priority int
+
+	// Timeout is how long the source has to answer before falling back to the next one. Zero waits indefinitely
+	Timeout time.Duration
+}
+
+type registeredSource struct {
+	source  source.DataSource
+	options SourceOptions
+	metrics *sourceMetrics
+}
+
 type Aggregator struct {
-	Sources []source.DataSource
+	mutex   sync.RWMutex
+	sources []*registeredSource
 }
 
 var GlobalAggregator Aggregator
 
 func (a *Aggregator) RegisterSource(source source.DataSource) {
-	a.Sources = append(a.Sources, source)
+	a.RegisterSourceWithOptions(source, SourceOptions{})
+}
+
+// RegisterSourceWithOptions adds a source to the aggregator, it's safe to call while lookups are in progress
+func (a *Aggregator) RegisterSourceWithOptions(source source.DataSource, options SourceOptions) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.sources = append(a.sources, &registeredSource{
+		source:  source,
+		options: options,
+		metrics: &sourceMetrics{Name: source.GetName(), Priority: options.Priority},
+	})
+
+	// Stable so equal priorities keep registration order
+	sort.SliceStable(a.sources, func(i, j int) bool {
+		return a.sources[i].options.Priority > a.sources[j].options.Priority
+	})
+
+	log.Debug().Str("name", source.GetName()).Int("priority", options.Priority).Msg("Registering new Data Source")
+}
+
+// DeregisterSource removes all the sources with the name
+func (a *Aggregator) DeregisterSource(name string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-	log.Debug().Str("name", source.GetName()).Msg("Registering new Data Source")
+	var sources []*registeredSource
+	for _, registered := range a.sources {
+		if registered.source.GetName() != name {
+			sources = append(sources, registered)
+		}
+	}
+	a.sources = sources
+
+	log.Debug().Str("name", name).Msg("Deregistered Data Source")
+}
+
+// getSources returns the sources supporting the lookup type in the order they should be tried
+func (a *Aggregator) getSources(lookupType reflect.Type) []*registeredSource {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var sources []*registeredSource
+	for _, registered := range a.sources {
+		for _, supportedType := range registered.source.Supports() {
+			if lookupType == supportedType {
+				sources = append(sources, registered)
+				break
+			}
+		}
+	}
+
+	return sources
 }
 
+var sourceTimeoutError = errors.New("source timed out")
+
+type lookupResult struct {
+	value any
+	err   error
+}
+
+func (r *registeredSource) lookup(query any) (any, error) {
+	if r.options.Timeout == 0 {
+		return r.source.Lookup(query)
+	}
+
+	// Buffered so a source that eventually answers after the timeout doesn't block forever
+	resultChannel := make(chan lookupResult, 1)
+	go func() {
+		value, err := r.source.Lookup(query)
+		resultChannel <- lookupResult{value: value, err: err}
+	}()
+
+	select {
+	case result := <-resultChannel:
+		return result.value, result.err
+	case <-time.After(r.options.Timeout):
+		return nil, sourceTimeoutError
+	}
+}
+
+// Lookup asks each source that supports T in priority order, falling back to the next one if a source doesn't
+// support the query, errors, times out or has no answer
 func Lookup[T any](query any) (T, error) {
 	var empty T
 
@@ -30,26 +130,62 @@ func Lookup[T any](query any) (T, error) {
 		lookupType = lookupType.Elem()
 	}
 
-	for _, dataSource := range GlobalAggregator.Sources {
-		for _, supportedType := range dataSource.Supports() {
-			if lookupType == supportedType {
-				var returnValue any
-				var returnError error
+	sources := GlobalAggregator.getSources(lookupType)
+	if len(sources) == 0 {
+		return empty, errors.New(fmt.Sprintf("Failed to find a matching Data Source for %s", lookupType.String()))
+	}
+
+	var lastError error
+	attempted := false
 
-				returnValue, returnError = dataSource.Lookup(query)
+	for i, registered := range sources {
+		startTime := time.Now()
+		returnValue, returnError := registered.lookup(query)
+		duration := time.Since(startTime)
 
-				if returnError != nil && returnError.Error() == "unsupported Source for this query" {
-					continue
-				}
+		if returnError == source.UnsupportedSourceError {
+			registered.metrics.record(duration, sourceOutcomeUnsupported)
+			continue
+		}
+		attempted = true
 
-				if returnValue == nil {
-					return empty, returnError
-				} else {
-					return returnValue.(T), returnError
-				}
+		if returnError == sourceTimeoutError {
+			registered.metrics.record(duration, sourceOutcomeTimeout)
+			log.Warn().
+				Str("source", registered.source.GetName()).
+				Str("type", lookupType.String()).
+				Str("timeout", registered.options.Timeout.String()).
+				Msg("Data Source timed out")
+
+			lastError = errors.New(fmt.Sprintf("%s timed out", registered.source.GetName()))
+			continue
+		}
+
+		if returnError != nil {
+			registered.metrics.record(duration, sourceOutcomeError)
+
+			// Only worth logging if theres somewhere else to try
+			if i < len(sources)-1 {
+				log.Debug().Err(returnError).Str("source", registered.source.GetName()).Msg("Data Source failed, falling back")
 			}
+
+			lastError = returnError
+			continue
 		}
+
+		if returnValue == nil {
+			registered.metrics.record(duration, sourceOutcomeEmpty)
+			continue
+		}
+
+		registered.metrics.record(duration, sourceOutcomeAnswered)
+
+		return returnValue.(T), nil
+	}
+
+	if !attempted {
+		return empty, errors.New(fmt.Sprintf("Failed to find a matching Data Source for %s", lookupType.String()))
 	}
 
-	return empty, errors.New(fmt.Sprintf("Failed to find a matching Data Source for %s", lookupType.String()))
+	return empty, lastError
 }
@@ -1,31 +1,63 @@
 package global
 
 import (
+	"time"
+
 	"github.com/travigo/travigo/pkg/dataaggregator"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/databaselookup"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/datasources"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/journeyplanner"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/localdepartureboard"
+	"github.com/travigo/travigo/pkg/dataaggregator/source/nationalrailldb"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/tfl"
 	"github.com/travigo/travigo/pkg/util"
 )
 
+// Upstream live APIs are asked first as they're authoritative for their stops, but are given a timeout so the
+// locally generated data can answer instead if they're slow
+const (
+	upstreamSourcePriority = 20
+	upstreamSourceTimeout  = 5 * time.Second
+
+	// Live departure boards are only used for rail stations without local realtime data, so sit behind the TfL API
+	nationalRailSourcePriority = 15
+
+	localSourcePriority = 10
+)
+
 func Setup() {
 	dataaggregator.GlobalAggregator = dataaggregator.Aggregator{}
 
 	env := util.GetEnvironmentVariables()
 
-	dataaggregator.GlobalAggregator.RegisterSource(tfl.Source{
+	dataaggregator.GlobalAggregator.RegisterSourceWithOptions(tfl.Source{
 		AppKey: env["TRAVIGO_TFL_API_KEY"],
+	}, dataaggregator.SourceOptions{
+		Priority: upstreamSourcePriority,
+		Timeout:  upstreamSourceTimeout,
+	})
+
+	nationalRailLDBSource := nationalrailldb.Source{
+		APIKey:  env["TRAVIGO_NATIONALRAIL_LDB_API_KEY"],
+		BaseURL: env["TRAVIGO_NATIONALRAIL_LDB_URL"],
+	}
+	nationalRailLDBSource.Setup()
+	dataaggregator.GlobalAggregator.RegisterSourceWithOptions(nationalRailLDBSource, dataaggregator.SourceOptions{
+		Priority: nationalRailSourcePriority,
+		Timeout:  upstreamSourceTimeout,
 	})
 
 	databaseLookupSource := databaselookup.Source{}
 	databaseLookupSource.Setup()
-	dataaggregator.GlobalAggregator.RegisterSource(databaseLookupSource)
+	dataaggregator.GlobalAggregator.RegisterSourceWithOptions(databaseLookupSource, dataaggregator.SourceOptions{
+		Priority: localSourcePriority,
+	})
 
 	localdepartureboardSource := localdepartureboard.Source{}
 	localdepartureboardSource.Setup()
-	dataaggregator.GlobalAggregator.RegisterSource(localdepartureboardSource)
+	dataaggregator.GlobalAggregator.RegisterSourceWithOptions(localdepartureboardSource, dataaggregator.SourceOptions{
+		Priority: localSourcePriority,
+	})
 
 	dataaggregator.GlobalAggregator.RegisterSource(journeyplanner.Source{})
 	dataaggregator.GlobalAggregator.RegisterSource(datasources.Source{})
@@ -0,0 +1,111 @@
+package dataaggregator
+
+import (
+	"sync"
+	"time"
+)
+
+type sourceOutcome int
+
+const (
+	sourceOutcomeAnswered sourceOutcome = iota
+	sourceOutcomeEmpty
+	sourceOutcomeError
+	sourceOutcomeTimeout
+	sourceOutcomeUnsupported
+)
+
+// sourceMetrics counts how each source has responded to lookups since the process started
+type sourceMetrics struct {
+	mutex sync.Mutex
+
+	Name     string
+	Priority int
+
+	Lookups     int64
+	Answered    int64
+	Empty       int64
+	Errors      int64
+	Timeouts    int64
+	Unsupported int64
+
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+func (m *sourceMetrics) record(duration time.Duration, outcome sourceOutcome) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Lookups += 1
+
+	switch outcome {
+	case sourceOutcomeAnswered:
+		m.Answered += 1
+	case sourceOutcomeEmpty:
+		m.Empty += 1
+	case sourceOutcomeError:
+		m.Errors += 1
+	case sourceOutcomeTimeout:
+		m.Timeouts += 1
+	case sourceOutcomeUnsupported:
+		m.Unsupported += 1
+	}
+
+	m.TotalDuration += duration
+	if duration > m.MaxDuration {
+		m.MaxDuration = duration
+	}
+}
+
+// SourceMetrics is a point in time copy of how a source has responded to lookups since the process started
+type SourceMetrics struct {
+	Name     string
+	Priority int
+
+	Lookups     int64
+	Answered    int64
+	Empty       int64
+	Errors      int64
+	Timeouts    int64
+	Unsupported int64
+
+	AverageDuration string
+	MaxDuration     string
+}
+
+func (m *sourceMetrics) snapshot() SourceMetrics {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var averageDuration time.Duration
+	if m.Lookups > 0 {
+		averageDuration = m.TotalDuration / time.Duration(m.Lookups)
+	}
+
+	return SourceMetrics{
+		Name:            m.Name,
+		Priority:        m.Priority,
+		Lookups:         m.Lookups,
+		Answered:        m.Answered,
+		Empty:           m.Empty,
+		Errors:          m.Errors,
+		Timeouts:        m.Timeouts,
+		Unsupported:     m.Unsupported,
+		AverageDuration: averageDuration.String(),
+		MaxDuration:     m.MaxDuration.String(),
+	}
+}
+
+// GetSourceMetrics returns the metrics of each registered source in priority order
+func (a *Aggregator) GetSourceMetrics() []SourceMetrics {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var metrics []SourceMetrics
+	for _, registered := range a.sources {
+		metrics = append(metrics, registered.metrics.snapshot())
+	}
+
+	return metrics
+}
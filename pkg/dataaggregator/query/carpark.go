@@ -0,0 +1,51 @@
+package query
+
+import (
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const CarParksNearStopDefaultRadius = 800 // metres
+
+// CarParksNearStop finds the car parks within walking distance of a stop, closest first.
+// Park & ride sites linked to the stop are included regardless of the radius.
+type CarParksNearStop struct {
+	Stop *ctdf.Stop
+
+	// Search radius in metres, defaults to CarParksNearStopDefaultRadius
+	Radius float64
+
+	ParkAndRideOnly bool
+}
+
+func (c *CarParksNearStop) ToBson() bson.M {
+	radius := c.Radius
+	if radius <= 0 {
+		radius = CarParksNearStopDefaultRadius
+	}
+
+	conditions := bson.A{
+		bson.M{"stoprefs": bson.M{"$in": c.Stop.GetAllStopIDs()}},
+	}
+
+	if c.Stop.Location != nil && len(c.Stop.Location.Coordinates) == 2 {
+		conditions = append(conditions, bson.M{
+			"location.coordinates": bson.M{
+				"$geoWithin": bson.M{
+					"$centerSphere": bson.A{
+						bson.A{c.Stop.Location.Coordinates[0], c.Stop.Location.Coordinates[1]},
+						radius / earthRadiusMetres,
+					},
+				},
+			},
+		})
+	}
+
+	query := bson.M{"$or": conditions}
+
+	if c.ParkAndRideOnly {
+		query["parkandride"] = true
+	}
+
+	return query
+}
@@ -0,0 +1,24 @@
+package query
+
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type DataQualityScores struct {
+	SubjectType ctdf.DataQualitySubjectType
+	SubjectRef  string
+
+	// Only include scores from this date onwards
+	Since time.Time
+}
+
+func (d *DataQualityScores) ToBson() bson.M {
+	return bson.M{
+		"subjecttype": d.SubjectType,
+		"subjectref":  d.SubjectRef,
+		"date":        bson.M{"$gte": d.Since},
+	}
+}
@@ -12,4 +12,6 @@ type DepartureBoard struct {
 	Count         int
 	StartDateTime time.Time
 	Filter        *bson.M
+
+	TransportTypes []ctdf.TransportType
 }
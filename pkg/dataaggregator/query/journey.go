@@ -1,6 +1,10 @@
 package query
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type Journey struct {
 	PrimaryIdentifier string
@@ -13,3 +17,16 @@ func (j *Journey) ToBson() bson.M {
 
 	return nil
 }
+
+type JourneyByIDAndDate struct {
+	PrimaryIdentifier string
+	Date              time.Time
+}
+
+func (j *JourneyByIDAndDate) ToBson() bson.M {
+	if j.PrimaryIdentifier != "" {
+		return bson.M{"primaryidentifier": j.PrimaryIdentifier}
+	}
+
+	return nil
+}
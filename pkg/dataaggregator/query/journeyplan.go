@@ -10,4 +10,5 @@ type JourneyPlan struct {
 	DestinationStop *ctdf.Stop
 	Count           int
 	StartDateTime   time.Time
+	TransportTypes  []ctdf.TransportType
 }
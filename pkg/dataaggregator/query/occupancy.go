@@ -0,0 +1,24 @@
+package query
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type OccupancyByService struct {
+	ServiceRef string
+
+	// Only include observations recorded after this time
+	Since time.Time
+
+	// Timezone used to group the observations into hours of the day
+	Timezone string
+}
+
+func (o *OccupancyByService) ToBson() bson.M {
+	return bson.M{
+		"serviceref": o.ServiceRef,
+		"recordedat": bson.M{"$gte": o.Since},
+	}
+}
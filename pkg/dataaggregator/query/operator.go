@@ -1,6 +1,9 @@
 package query
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"github.com/travigo/travigo/pkg/identifiers"
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type Operator struct {
 	PrimaryIdentifier string
@@ -11,10 +14,7 @@ func (o *Operator) ToBson() bson.M {
 	if o.PrimaryIdentifier != "" {
 		return bson.M{"primaryidentifier": o.PrimaryIdentifier}
 	} else if o.AnyIdentifier != "" {
-		return bson.M{"$or": bson.A{
-			bson.M{"primaryidentifier": o.AnyIdentifier},
-			bson.M{"otheridentifiers": o.AnyIdentifier},
-		}}
+		return identifiers.Filter(identifiers.ObjectTypeOperator, o.AnyIdentifier)
 	}
 
 	return nil
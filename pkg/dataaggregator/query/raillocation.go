@@ -0,0 +1,30 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// RailLocationByCode finds a rail location from any of its codes, either prefixed (eg. gb-crs-KGX) or bare (eg. KGX).
+// Bare codes are tried as a TIPLOC, then CRS & then STANOX as some codes are valid as more than one.
+type RailLocationByCode struct {
+	Code string
+}
+
+func (r *RailLocationByCode) GetIdentifiers() []string {
+	code := strings.TrimSpace(r.Code)
+
+	if strings.HasPrefix(code, "gb-") {
+		return []string{code}
+	}
+
+	code = strings.ToUpper(code)
+
+	return []string{
+		fmt.Sprintf(ctdf.RailLocationIDFormat, code),
+		fmt.Sprintf("gb-crs-%s", code),
+		fmt.Sprintf("gb-stanox-%s", code),
+	}
+}
@@ -1,6 +1,11 @@
 package query
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type RealtimeJourney struct {
 	PrimaryIdentifier string
@@ -13,3 +18,55 @@ func (r *RealtimeJourney) ToBson() bson.M {
 
 	return nil
 }
+
+// RealtimeJourneyByLocation finds the vehicle someone is most likely on from where they are & when
+type RealtimeJourneyByLocation struct {
+	Location  ctdf.Location
+	Timestamp time.Time
+
+	// Direction of travel in degrees if the device knows it
+	Bearing *float64
+	// Only consider vehicles on this service
+	ServiceRef string
+
+	// Search radius in metres
+	Radius float64
+}
+
+func (r *RealtimeJourneyByLocation) ToBson() bson.M {
+	query := bson.M{
+		"activelytracked": true,
+		"vehiclelocation.coordinates": bson.M{
+			"$geoWithin": bson.M{
+				"$centerSphere": bson.A{
+					bson.A{r.Location.Coordinates[0], r.Location.Coordinates[1]},
+					r.Radius / earthRadiusMetres,
+				},
+			},
+		},
+		"modificationdatetime": bson.M{
+			"$gte": r.Timestamp.Add(-RealtimeJourneyLocationMaximumAge),
+			"$lte": r.Timestamp.Add(RealtimeJourneyLocationMaximumAge),
+		},
+	}
+
+	if r.ServiceRef != "" {
+		query["journey.serviceref"] = r.ServiceRef
+	}
+
+	return query
+}
+
+// Vehicle locations further than this from the requested time are too out of date to compare against
+const RealtimeJourneyLocationMaximumAge = 3 * time.Minute
+
+const earthRadiusMetres = 6378100
+
+// VehicleTrack is the sampled path a vehicle has taken on its realtime journey
+type VehicleTrack struct {
+	RealtimeJourneyRef string
+}
+
+func (v *VehicleTrack) ToBson() bson.M {
+	return bson.M{"primaryidentifier": v.RealtimeJourneyRef}
+}
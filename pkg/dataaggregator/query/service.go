@@ -1,6 +1,8 @@
 package query
 
 import (
+	"strings"
+
 	"github.com/travigo/travigo/pkg/ctdf"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -18,5 +20,74 @@ func (s *Service) ToBson() bson.M {
 }
 
 type ServicesByStop struct {
-	Stop *ctdf.Stop
+	Stop           *ctdf.Stop
+	TransportTypes []ctdf.TransportType
+}
+
+type ServicesByOperator struct {
+	Operator       *ctdf.Operator
+	TransportTypes []ctdf.TransportType
+}
+
+func (s *ServicesByOperator) ToBson() bson.M {
+	operatorRefs := append([]string{s.Operator.PrimaryIdentifier}, s.Operator.OtherIdentifiers...)
+
+	filter := bson.M{"operatorref": bson.M{"$in": operatorRefs}}
+	addTransportTypesFilter(filter, s.TransportTypes)
+
+	return filter
+}
+
+type ServicesByOperatorGroup struct {
+	OperatorGroup  *ctdf.OperatorGroup
+	TransportTypes []ctdf.TransportType
+}
+
+func (s *ServicesByOperatorGroup) ToBson() bson.M {
+	var operatorRefs []string
+	for _, operator := range s.OperatorGroup.Operators {
+		operatorRefs = append(operatorRefs, operator.PrimaryIdentifier)
+		operatorRefs = append(operatorRefs, operator.OtherIdentifiers...)
+	}
+
+	filter := bson.M{"operatorref": bson.M{"$in": operatorRefs}}
+	addTransportTypesFilter(filter, s.TransportTypes)
+
+	return filter
+}
+
+type ServiceSearch struct {
+	ServiceName    string
+	OperatorRef    string
+	TransportTypes []ctdf.TransportType
+}
+
+func (s *ServiceSearch) ToBson() bson.M {
+	filter := bson.M{"servicename": NormaliseServiceName(s.ServiceName)}
+
+	if s.OperatorRef != "" {
+		filter["operatorref"] = s.OperatorRef
+	}
+	addTransportTypesFilter(filter, s.TransportTypes)
+
+	return filter
+}
+
+// addTransportTypesFilter restricts the query to the given transport types, an empty list matches everything
+func addTransportTypesFilter(filter bson.M, transportTypes []ctdf.TransportType) {
+	if len(transportTypes) > 0 {
+		filter["transporttype"] = bson.M{"$in": transportTypes}
+	}
+}
+
+// NormaliseServiceName tidies up a line name/number so it can be matched against the services collection
+// Case is handled by the collation on the query so "X1" and "x1" both match
+func NormaliseServiceName(serviceName string) string {
+	return strings.Join(strings.Fields(serviceName), " ")
+}
+
+// RouteForService returns the materialised route variants of a Service, optionally only those serving a Stop
+type RouteForService struct {
+	Service *ctdf.Service
+	Stop    *ctdf.Stop
 }
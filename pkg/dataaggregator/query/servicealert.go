@@ -1,6 +1,9 @@
 package query
 
 import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -11,3 +14,31 @@ type ServiceAlertsForMatchingIdentifiers struct {
 func (s *ServiceAlertsForMatchingIdentifiers) ToBson() bson.M {
 	return bson.M{"matchedidentifiers": bson.M{"$in": s.MatchingIdentifiers}}
 }
+
+// AlertsForStop finds alerts for the stop, its platforms & services along with any scoped alerts that cover it
+type AlertsForStop struct {
+	Stop *ctdf.Stop
+	Time time.Time
+}
+
+// AlertsForService finds alerts for the service along with any scoped alerts that cover it
+type AlertsForService struct {
+	Service *ctdf.Service
+	Time    time.Time
+}
+
+// ServiceAlertsForMatchKeys is the expanded form of AlertsForStop & AlertsForService
+type ServiceAlertsForMatchKeys struct {
+	MatchingIdentifiers []string
+	MatchKeys           []string
+	Time                time.Time
+}
+
+func (s *ServiceAlertsForMatchKeys) ToBson() bson.M {
+	return bson.M{
+		"$or": bson.A{
+			bson.M{"matchedidentifiers": bson.M{"$in": s.MatchingIdentifiers}},
+			bson.M{"matchkeys": bson.M{"$in": s.MatchKeys}},
+		},
+	}
+}
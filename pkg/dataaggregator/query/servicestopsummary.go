@@ -0,0 +1,25 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ServiceStopSummary struct {
+	ServiceRef string
+	StopRef    string
+}
+
+func (s *ServiceStopSummary) ToBson() bson.M {
+	return bson.M{"primaryidentifier": fmt.Sprintf(ctdf.ServiceStopSummaryIDFormat, s.ServiceRef, s.StopRef)}
+}
+
+type ServiceStopSummariesByStop struct {
+	Stop *ctdf.Stop
+}
+
+func (s *ServiceStopSummariesByStop) ToBson() bson.M {
+	return bson.M{"stopref": bson.M{"$in": s.Stop.GetAllStopIDs()}}
+}
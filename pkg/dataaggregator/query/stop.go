@@ -1,18 +1,133 @@
 package query
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/identifiers"
+	"github.com/travigo/travigo/pkg/pluscode"
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type Stop struct {
 	Identifier string
+
+	// Stops that aren't in use are left out unless asked for
+	IncludeInactive bool
 }
 
 func (s *Stop) ToBson() bson.M {
 	if s.Identifier != "" {
-		return bson.M{"$or": bson.A{
-			bson.M{"primaryidentifier": s.Identifier},
-			bson.M{"otheridentifiers": s.Identifier},
-		}}
+		filter := identifiers.Filter(identifiers.ObjectTypeStop, s.Identifier)
+		if !s.IncludeInactive {
+			addActiveStopFilter(filter, time.Now())
+		}
+
+		return filter
 	}
 
 	return nil
 }
+
+// addActiveStopFilter limits a stop query to stops in use at the time, stops without a status or dates count as in use
+func addActiveStopFilter(filter bson.M, dateTime time.Time) {
+	filter["status"] = bson.M{"$nin": ctdf.InactiveStopStatuses}
+	filter["effectivefrom"] = bson.M{"$not": bson.M{"$gt": dateTime}}
+	filter["effectiveuntil"] = bson.M{"$not": bson.M{"$lt": dateTime}}
+}
+
+const StopsNearLocationTextDefaultRadius = 1000 // metres
+const StopsNearLocationTextDefaultCount = 5
+
+// StopsNearLocationText finds the stops closest to a location someone has typed or said,
+// either a Plus Code (eg. 9C3XGV2G+75) or a "latitude, longitude" pair
+type StopsNearLocationText struct {
+	Text string
+
+	// Short Plus Codes (eg. GV2G+75) leave off the area they're in so are resolved nearest to this
+	Reference *ctdf.Location
+
+	// Search radius in metres, defaults to StopsNearLocationTextDefaultRadius
+	Radius float64
+	// Maximum number of stops returned, defaults to StopsNearLocationTextDefaultCount
+	Count int
+
+	// Stops that aren't in use are left out unless asked for
+	IncludeInactive bool
+}
+
+// GetLocation reads the Plus Code or latitude & longitude pair into a location
+func (s *StopsNearLocationText) GetLocation() (*ctdf.Location, error) {
+	text := strings.TrimSpace(s.Text)
+	reference := s.Reference
+
+	if matches := latitudeLongitudeRegex.FindStringSubmatch(text); matches != nil {
+		latitude, _ := strconv.ParseFloat(matches[1], 64)
+		longitude, _ := strconv.ParseFloat(matches[2], 64)
+
+		if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			return nil, errors.New(fmt.Sprintf("%s is not a valid latitude & longitude", text))
+		}
+
+		return &ctdf.Location{
+			Type:        "Point",
+			Coordinates: []float64{longitude, latitude},
+		}, nil
+	}
+
+	// Short codes are often followed by the place they're in, eg. "GV2G+75 London", which isn't handled here
+	code := strings.ToUpper(strings.Fields(text + " ")[0])
+
+	if pluscode.IsShort(code) {
+		if reference == nil || len(reference.Coordinates) != 2 {
+			return nil, errors.New(fmt.Sprintf("%s is a short Plus Code so needs a reference location", code))
+		}
+
+		var err error
+		code, err = pluscode.RecoverNearest(code, reference.Coordinates[1], reference.Coordinates[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	area, err := pluscode.Decode(code)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s is not a Plus Code or latitude & longitude", text))
+	}
+	latitude, longitude := area.Center()
+
+	return &ctdf.Location{
+		Type:        "Point",
+		Coordinates: []float64{longitude, latitude},
+	}, nil
+}
+
+func (s *StopsNearLocationText) ToBson(location *ctdf.Location) bson.M {
+	radius := s.Radius
+	if radius <= 0 {
+		radius = StopsNearLocationTextDefaultRadius
+	}
+
+	filter := bson.M{
+		"location.coordinates": bson.M{
+			"$geoWithin": bson.M{
+				"$centerSphere": bson.A{
+					bson.A{location.Coordinates[0], location.Coordinates[1]},
+					radius / earthRadiusMetres,
+				},
+			},
+		},
+	}
+	if !s.IncludeInactive {
+		addActiveStopFilter(filter, time.Now())
+	}
+
+	return filter
+}
+
+var latitudeLongitudeRegex = regexp.MustCompile(`^\s*(-?\d+(?:\.\d+)?)\s*[,\s]\s*(-?\d+(?:\.\d+)?)\s*$`)
@@ -0,0 +1,14 @@
+package query
+
+import (
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type TransfersByStop struct {
+	Stop *ctdf.Stop
+}
+
+func (t *TransfersByStop) ToBson() bson.M {
+	return bson.M{"fromstopref": bson.M{"$in": t.Stop.GetAllStopIDs()}}
+}
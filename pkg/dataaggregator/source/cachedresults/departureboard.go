@@ -0,0 +1,167 @@
+package cachedresults
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+const DepartureBoardInvalidationChannel = "cachedresults/departureboard/invalidate"
+
+// DepartureBoardCache is a short lived in-memory cache of generated departure boards.
+// Entries are indexed by every stop they cover so that a realtime update at a platform also clears its parent stops board
+type DepartureBoardCache struct {
+	TTL time.Duration
+
+	mutex       sync.Mutex
+	entries     map[string]*departureBoardCacheEntry
+	stopEntries map[string]map[string]bool
+}
+
+type departureBoardCacheEntry struct {
+	DepartureBoard []*ctdf.DepartureBoard
+	StopIDs        []string
+	Expiry         time.Time
+}
+
+func NewDepartureBoardCache(ttl time.Duration) *DepartureBoardCache {
+	return &DepartureBoardCache{
+		TTL:         ttl,
+		entries:     map[string]*departureBoardCacheEntry{},
+		stopEntries: map[string]map[string]bool{},
+	}
+}
+
+func (c *DepartureBoardCache) Get(key string) ([]*ctdf.DepartureBoard, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := c.entries[key]
+	if entry == nil {
+		return nil, false
+	}
+	if time.Now().After(entry.Expiry) {
+		c.remove(key)
+		return nil, false
+	}
+
+	return copyDepartureBoard(entry.DepartureBoard), true
+}
+
+func (c *DepartureBoardCache) Set(key string, stopIDs []string, departureBoard []*ctdf.DepartureBoard) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.remove(key)
+
+	c.entries[key] = &departureBoardCacheEntry{
+		DepartureBoard: copyDepartureBoard(departureBoard),
+		StopIDs:        stopIDs,
+		Expiry:         time.Now().Add(c.TTL),
+	}
+
+	for _, stopID := range stopIDs {
+		if c.stopEntries[stopID] == nil {
+			c.stopEntries[stopID] = map[string]bool{}
+		}
+		c.stopEntries[stopID][key] = true
+	}
+}
+
+// InvalidateStops removes every cached departure board that includes any of the stops
+func (c *DepartureBoardCache) InvalidateStops(stopIDs []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, stopID := range stopIDs {
+		for key := range c.stopEntries[stopID] {
+			c.remove(key)
+		}
+	}
+}
+
+// Listen subscribes to departure board invalidations published by the realtime processors and periodically clears
+// out expired entries. Blocks so should be run as a goroutine
+func (c *DepartureBoardCache) Listen() {
+	pubsub := redis_client.Client.Subscribe(context.Background(), DepartureBoardInvalidationChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	sweepTicker := time.NewTicker(c.TTL)
+	defer sweepTicker.Stop()
+
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				log.Error().Msg("Departure board invalidation subscription closed")
+				return
+			}
+
+			c.InvalidateStops(strings.Split(message.Payload, ","))
+		case <-sweepTicker.C:
+			c.removeExpired()
+		}
+	}
+}
+
+func (c *DepartureBoardCache) removeExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.Expiry) {
+			c.remove(key)
+		}
+	}
+}
+
+// remove expects the mutex to already be held
+func (c *DepartureBoardCache) remove(key string) {
+	entry := c.entries[key]
+	if entry == nil {
+		return
+	}
+
+	for _, stopID := range entry.StopIDs {
+		delete(c.stopEntries[stopID], key)
+
+		if len(c.stopEntries[stopID]) == 0 {
+			delete(c.stopEntries, stopID)
+		}
+	}
+
+	delete(c.entries, key)
+}
+
+// copyDepartureBoard gives each caller its own records & journeys as the API sorts & transforms them in place
+func copyDepartureBoard(departureBoard []*ctdf.DepartureBoard) []*ctdf.DepartureBoard {
+	copied := make([]*ctdf.DepartureBoard, 0, len(departureBoard))
+
+	for _, item := range departureBoard {
+		copiedItem := *item
+		if item.Journey != nil {
+			copiedJourney := *item.Journey
+			copiedItem.Journey = &copiedJourney
+		}
+
+		copied = append(copied, &copiedItem)
+	}
+
+	return copied
+}
+
+// PublishDepartureBoardInvalidation tells every departure board cache that the boards for these stops are out of date
+func PublishDepartureBoardInvalidation(stopIDs []string) error {
+	if len(stopIDs) == 0 {
+		return nil
+	}
+
+	return redis_client.Client.Publish(context.Background(), DepartureBoardInvalidationChannel, strings.Join(stopIDs, ",")).Err()
+}
@@ -0,0 +1,130 @@
+package databaselookup
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) AlertsForStopQuery(q query.AlertsForStop) ([]*ctdf.ServiceAlert, error) {
+	if q.Stop == nil {
+		return nil, nil
+	}
+
+	stopIDs := q.Stop.GetAllStopIDs()
+	identifiers := append([]string{}, stopIDs...)
+	scopes := []ctdf.ServiceAlertScope{{Type: ctdf.ServiceAlertScopeTypeWildcard}}
+
+	for _, stopID := range stopIDs {
+		scopes = append(scopes, ctdf.ServiceAlertScope{Type: ctdf.ServiceAlertScopeTypeStop, Identifier: stopID})
+	}
+	for _, association := range q.Stop.Associations {
+		if association.Type == "stop_group" {
+			scopes = append(scopes, ctdf.ServiceAlertScope{Type: ctdf.ServiceAlertScopeTypeStopGroup, Identifier: association.AssociatedIdentifier})
+		}
+	}
+
+	services, _ := s.ServicesByStopQuery(query.ServicesByStop{Stop: q.Stop})
+	var operatorRefs []string
+	for _, service := range services {
+		identifiers = append(identifiers, service.PrimaryIdentifier)
+		scopes = append(scopes, ctdf.ServiceAlertScope{Type: ctdf.ServiceAlertScopeTypeService, Identifier: service.PrimaryIdentifier})
+
+		operatorRefs = append(operatorRefs, service.OperatorRef)
+	}
+	scopes = append(scopes, getOperatorAlertScopes(operatorRefs)...)
+
+	return s.serviceAlertsForMatchKeys(query.ServiceAlertsForMatchKeys{
+		MatchingIdentifiers: identifiers,
+		MatchKeys:           ctdf.GetServiceAlertMatchKeys(scopes...),
+		Time:                q.Time,
+	})
+}
+
+func (s Source) AlertsForServiceQuery(q query.AlertsForService) ([]*ctdf.ServiceAlert, error) {
+	if q.Service == nil {
+		return nil, nil
+	}
+
+	scopes := []ctdf.ServiceAlertScope{
+		{Type: ctdf.ServiceAlertScopeTypeWildcard},
+		{Type: ctdf.ServiceAlertScopeTypeService, Identifier: q.Service.PrimaryIdentifier},
+	}
+	scopes = append(scopes, getOperatorAlertScopes([]string{q.Service.OperatorRef})...)
+
+	return s.serviceAlertsForMatchKeys(query.ServiceAlertsForMatchKeys{
+		MatchingIdentifiers: []string{q.Service.PrimaryIdentifier},
+		MatchKeys:           ctdf.GetServiceAlertMatchKeys(scopes...),
+		Time:                q.Time,
+	})
+}
+
+func (s Source) serviceAlertsForMatchKeys(q query.ServiceAlertsForMatchKeys) ([]*ctdf.ServiceAlert, error) {
+	collection := database.GetCollection("service_alerts")
+	var serviceAlerts []*ctdf.ServiceAlert
+
+	checkTime := q.Time
+	if checkTime.IsZero() {
+		checkTime = time.Now()
+	}
+
+	cursor, err := collection.Find(context.Background(), q.ToBson())
+	if err != nil {
+		return nil, err
+	}
+
+	for cursor.Next(context.Background()) {
+		var serviceAlert ctdf.ServiceAlert
+		if err := cursor.Decode(&serviceAlert); err != nil {
+			log.Error().Err(err).Msg("Failed to decode ServiceAlert")
+			continue
+		}
+
+		if serviceAlert.IsValid(checkTime) {
+			serviceAlerts = append(serviceAlerts, &serviceAlert)
+		}
+	}
+
+	return serviceAlerts, nil
+}
+
+// getOperatorAlertScopes expands operators into their own scopes and the scopes of the regions they run in
+func getOperatorAlertScopes(operatorRefs []string) []ctdf.ServiceAlertScope {
+	var scopes []ctdf.ServiceAlertScope
+
+	operatorRefs = util.RemoveDuplicateStrings(operatorRefs, []string{""})
+	if len(operatorRefs) == 0 {
+		return scopes
+	}
+
+	for _, operatorRef := range operatorRefs {
+		scopes = append(scopes, ctdf.ServiceAlertScope{Type: ctdf.ServiceAlertScopeTypeOperator, Identifier: operatorRef})
+	}
+
+	operatorsCollection := database.GetCollection("operators")
+	opts := options.Find().SetProjection(bson.D{{Key: "regions", Value: 1}})
+	cursor, err := operatorsCollection.Find(context.Background(), bson.M{"primaryidentifier": bson.M{"$in": operatorRefs}}, opts)
+	if err != nil {
+		return scopes
+	}
+
+	for cursor.Next(context.Background()) {
+		var operator ctdf.Operator
+		if err := cursor.Decode(&operator); err != nil {
+			continue
+		}
+
+		for _, region := range operator.Regions {
+			scopes = append(scopes, ctdf.ServiceAlertScope{Type: ctdf.ServiceAlertScopeTypeRegion, Identifier: region})
+		}
+	}
+
+	return scopes
+}
@@ -0,0 +1,48 @@
+package databaselookup
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+)
+
+func (s Source) CarParksNearStopQuery(q query.CarParksNearStop) ([]*ctdf.CarPark, error) {
+	collection := database.GetCollection("car_parks")
+
+	cursor, err := collection.Find(context.Background(), q.ToBson())
+	if err != nil {
+		return nil, err
+	}
+
+	var carParks []*ctdf.CarPark
+	for cursor.Next(context.Background()) {
+		var carPark ctdf.CarPark
+		err := cursor.Decode(&carPark)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode CarPark")
+			continue
+		}
+
+		carParks = append(carParks, &carPark)
+	}
+
+	if q.Stop.Location != nil {
+		sort.SliceStable(carParks, func(i, j int) bool {
+			return carParkDistance(q.Stop, carParks[i]) < carParkDistance(q.Stop, carParks[j])
+		})
+	}
+
+	return carParks, nil
+}
+
+func carParkDistance(stop *ctdf.Stop, carPark *ctdf.CarPark) float64 {
+	if carPark.Location == nil {
+		return 0
+	}
+
+	return stop.Location.GreatCircleDistance(carPark.Location)
+}
@@ -25,14 +25,24 @@ func (s *Source) Setup() {
 func (s Source) Supports() []reflect.Type {
 	return []reflect.Type{
 		reflect.TypeOf(ctdf.Stop{}),
+		reflect.TypeOf([]*ctdf.Stop{}),
 		reflect.TypeOf(ctdf.StopGroup{}),
 		reflect.TypeOf(ctdf.Journey{}),
 		reflect.TypeOf(ctdf.RealtimeJourney{}),
+		reflect.TypeOf(ctdf.VehicleTrack{}),
 		reflect.TypeOf(ctdf.Operator{}),
 		reflect.TypeOf(ctdf.OperatorGroup{}),
 		reflect.TypeOf(ctdf.Service{}),
 		reflect.TypeOf([]*ctdf.Service{}),
+		reflect.TypeOf([]*ctdf.Route{}),
 		reflect.TypeOf([]*ctdf.ServiceAlert{}),
+		reflect.TypeOf(ctdf.ServiceStopSummary{}),
+		reflect.TypeOf([]*ctdf.ServiceStopSummary{}),
+		reflect.TypeOf([]*ctdf.ServiceOccupancyPeriod{}),
+		reflect.TypeOf([]*ctdf.Transfer{}),
+		reflect.TypeOf([]*ctdf.CarPark{}),
+		reflect.TypeOf(ctdf.RailLocation{}),
+		reflect.TypeOf([]*ctdf.DataQualityScore{}),
 	}
 }
 
@@ -40,10 +50,14 @@ func (s Source) Lookup(q any) (interface{}, error) {
 	switch q.(type) {
 	case query.Stop:
 		return s.StopQuery(q.(query.Stop))
+	case query.StopsNearLocationText:
+		return s.StopsNearLocationTextQuery(q.(query.StopsNearLocationText))
 	case query.StopGroup:
 		return s.StopGroupQuery(q.(query.StopGroup))
 	case query.Journey:
 		return s.JourneyQuery(q.(query.Journey))
+	case query.JourneyByIDAndDate:
+		return s.JourneyByIDAndDateQuery(q.(query.JourneyByIDAndDate))
 	case query.Operator:
 		return s.OperatorQuery(q.(query.Operator))
 	case query.OperatorGroup:
@@ -52,10 +66,40 @@ func (s Source) Lookup(q any) (interface{}, error) {
 		return s.ServiceQuery(q.(query.Service))
 	case query.ServicesByStop:
 		return s.ServicesByStopQuery(q.(query.ServicesByStop))
+	case query.ServicesByOperator:
+		return s.ServicesByOperatorQuery(q.(query.ServicesByOperator))
+	case query.ServicesByOperatorGroup:
+		return s.ServicesByOperatorGroupQuery(q.(query.ServicesByOperatorGroup))
+	case query.RouteForService:
+		return s.RouteForServiceQuery(q.(query.RouteForService))
+	case query.ServiceSearch:
+		return s.ServiceSearchQuery(q.(query.ServiceSearch))
+	case query.ServiceStopSummary:
+		return s.ServiceStopSummaryQuery(q.(query.ServiceStopSummary))
+	case query.ServiceStopSummariesByStop:
+		return s.ServiceStopSummariesByStopQuery(q.(query.ServiceStopSummariesByStop))
+	case query.TransfersByStop:
+		return s.TransfersByStopQuery(q.(query.TransfersByStop))
+	case query.CarParksNearStop:
+		return s.CarParksNearStopQuery(q.(query.CarParksNearStop))
+	case query.RailLocationByCode:
+		return s.RailLocationByCodeQuery(q.(query.RailLocationByCode))
+	case query.DataQualityScores:
+		return s.DataQualityScoresQuery(q.(query.DataQualityScores))
+	case query.OccupancyByService:
+		return s.OccupancyByServiceQuery(q.(query.OccupancyByService))
 	case query.RealtimeJourney:
 		return s.RealtimeJourneyQuery(q.(query.RealtimeJourney))
+	case query.RealtimeJourneyByLocation:
+		return s.RealtimeJourneyByLocationQuery(q.(query.RealtimeJourneyByLocation))
+	case query.VehicleTrack:
+		return s.VehicleTrackQuery(q.(query.VehicleTrack))
 	case query.ServiceAlertsForMatchingIdentifiers:
 		return s.ServiceAlertsForMatchingIdentifiersQuery(q.(query.ServiceAlertsForMatchingIdentifiers))
+	case query.AlertsForStop:
+		return s.AlertsForStopQuery(q.(query.AlertsForStop))
+	case query.AlertsForService:
+		return s.AlertsForServiceQuery(q.(query.AlertsForService))
 	}
 
 	return nil, errors.New("unable to lookup")
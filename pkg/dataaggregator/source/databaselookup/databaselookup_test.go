@@ -0,0 +1,66 @@
+package databaselookup_test
+
+import (
+	"testing"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/dataaggregator/source/databaselookup"
+	"github.com/travigo/travigo/pkg/testsupport"
+)
+
+func setupAggregator(t *testing.T) {
+	testsupport.Start(t)
+
+	dataaggregator.GlobalAggregator = dataaggregator.Aggregator{}
+	source := databaselookup.Source{}
+	source.Setup()
+	dataaggregator.GlobalAggregator.RegisterSource(source)
+}
+
+func TestServiceQuery(t *testing.T) {
+	setupAggregator(t)
+	testsupport.LoadFixture(t, "gtfs-schedule")
+
+	service, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+		PrimaryIdentifier: "fixture-gtfs-schedule-service-FIXR1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to look up service: %s", err)
+	}
+
+	if service.ServiceName != "F1" {
+		t.Errorf("Expected service F1 but got %s", service.ServiceName)
+	}
+	if service.OperatorRef != "fixture-gtfs-schedule-operator-FIXA" {
+		t.Errorf("Expected operator fixture-gtfs-schedule-operator-FIXA but got %s", service.OperatorRef)
+	}
+}
+
+func TestOperatorQuery(t *testing.T) {
+	setupAggregator(t)
+	testsupport.LoadFixture(t, "gtfs-schedule")
+
+	operator, err := dataaggregator.Lookup[*ctdf.Operator](query.Operator{
+		AnyIdentifier: "fixture-gtfs-schedule-operator-FIXA",
+	})
+	if err != nil {
+		t.Fatalf("Failed to look up operator: %s", err)
+	}
+
+	if operator.PrimaryIdentifier != "fixture-gtfs-schedule-operator-FIXA" {
+		t.Errorf("Expected operator fixture-gtfs-schedule-operator-FIXA but got %s", operator.PrimaryIdentifier)
+	}
+}
+
+func TestServiceQueryNotFound(t *testing.T) {
+	setupAggregator(t)
+
+	_, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+		PrimaryIdentifier: "fixture-gtfs-schedule-service-MISSING",
+	})
+	if err == nil {
+		t.Error("Expected an error looking up a service that doesn't exist")
+	}
+}
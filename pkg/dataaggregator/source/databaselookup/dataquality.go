@@ -0,0 +1,35 @@
+package databaselookup
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) DataQualityScoresQuery(q query.DataQualityScores) ([]*ctdf.DataQualityScore, error) {
+	collection := database.GetCollection("data_quality_scores")
+
+	cursor, err := collection.Find(context.Background(), q.ToBson(), options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []*ctdf.DataQualityScore
+	for cursor.Next(context.Background()) {
+		var score ctdf.DataQualityScore
+		err := cursor.Decode(&score)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode DataQualityScore")
+			continue
+		}
+
+		scores = append(scores, &score)
+	}
+
+	return scores, nil
+}
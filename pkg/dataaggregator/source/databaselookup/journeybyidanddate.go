@@ -0,0 +1,40 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+)
+
+func (s Source) JourneyByIDAndDateQuery(q query.JourneyByIDAndDate) (*ctdf.Journey, error) {
+	collection := database.GetCollection("journeys")
+	var journey *ctdf.Journey
+	collection.FindOne(context.Background(), q.ToBson()).Decode(&journey)
+
+	if journey == nil {
+		return nil, errors.New("could not find a matching Journey")
+	}
+
+	// No date given means the journey is wanted as-is for today without an availability check
+	date := q.Date
+	if date.IsZero() {
+		date = time.Now()
+	} else if !journey.IsValidOn(date) || (journey.Availability != nil && !journey.Availability.MatchDate(date)) {
+		return nil, errors.New("Journey does not run on the requested date")
+	}
+
+	journey.GetReferences()
+	journey.GetDeepReferences()
+
+	// Realtime journeys only ever exist for the current day
+	now := time.Now().In(date.Location())
+	if now.Year() == date.Year() && now.YearDay() == date.YearDay() {
+		journey.GetRealtimeJourney(nil)
+	}
+
+	return journey, nil
+}
@@ -0,0 +1,58 @@
+package databaselookup
+
+import (
+	"context"
+	"math"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s Source) OccupancyByServiceQuery(q query.OccupancyByService) ([]*ctdf.ServiceOccupancyPeriod, error) {
+	collection := database.GetCollection("occupancy_history")
+
+	timezone := q.Timezone
+	if timezone == "" {
+		timezone = "Europe/London"
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": q.ToBson()},
+		bson.M{"$group": bson.M{
+			"_id":     bson.M{"$hour": bson.M{"date": "$recordedat", "timezone": timezone}},
+			"average": bson.M{"$avg": "$totalpercentageoccupancy"},
+			"peak":    bson.M{"$max": "$totalpercentageoccupancy"},
+			"samples": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []*ctdf.ServiceOccupancyPeriod
+	for cursor.Next(context.Background()) {
+		var result struct {
+			Hour    int     `bson:"_id"`
+			Average float64 `bson:"average"`
+			Peak    int     `bson:"peak"`
+			Samples int     `bson:"samples"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+
+		periods = append(periods, &ctdf.ServiceOccupancyPeriod{
+			Hour:                       result.Hour,
+			AveragePercentageOccupancy: math.Round(result.Average*10) / 10,
+			PeakPercentageOccupancy:    result.Peak,
+			Samples:                    result.Samples,
+		})
+	}
+
+	return periods, nil
+}
@@ -0,0 +1,30 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) RailLocationByCodeQuery(q query.RailLocationByCode) (*ctdf.RailLocation, error) {
+	collection := database.GetCollection("rail_locations")
+
+	// Several TIPLOCs share the CRS of a big station, prefer the one actually linked to it
+	opts := options.FindOne().SetSort(bson.D{{Key: "stopref", Value: -1}, {Key: "primaryidentifier", Value: 1}})
+
+	for _, identifier := range q.GetIdentifiers() {
+		var railLocation *ctdf.RailLocation
+		collection.FindOne(context.Background(), bson.M{"otheridentifiers": identifier}, opts).Decode(&railLocation)
+
+		if railLocation != nil {
+			return railLocation, nil
+		}
+	}
+
+	return nil, errors.New("could not find a matching Rail Location")
+}
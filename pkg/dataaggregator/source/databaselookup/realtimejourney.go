@@ -3,11 +3,16 @@ package databaselookup
 import (
 	"context"
 	"errors"
+	"math"
+
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator/query"
 	"github.com/travigo/travigo/pkg/database"
 )
 
+// Rough speed a vehicle covers, used to allow for how old its last location is compared to the requested time
+const identifyVehicleSpeed = 10.0 // metres per second
+
 func (s Source) RealtimeJourneyQuery(q query.RealtimeJourney) (*ctdf.RealtimeJourney, error) {
 	collection := database.GetCollection("realtime_journeys")
 	var journey *ctdf.RealtimeJourney
@@ -19,3 +24,64 @@ func (s Source) RealtimeJourneyQuery(q query.RealtimeJourney) (*ctdf.RealtimeJou
 		return journey, nil
 	}
 }
+
+// RealtimeJourneyByLocationQuery picks the active vehicle nearest to the location,
+// preferring recently updated vehicles heading the same way
+func (s Source) RealtimeJourneyByLocationQuery(q query.RealtimeJourneyByLocation) (*ctdf.RealtimeJourney, error) {
+	if len(q.Location.Coordinates) != 2 {
+		return nil, errors.New("a location must be provided")
+	}
+
+	collection := database.GetCollection("realtime_journeys")
+	cursor, err := collection.Find(context.Background(), q.ToBson())
+	if err != nil {
+		return nil, err
+	}
+
+	var bestJourney *ctdf.RealtimeJourney
+	bestScore := math.MaxFloat64
+
+	for cursor.Next(context.Background()) {
+		var realtimeJourney *ctdf.RealtimeJourney
+		if err := cursor.Decode(&realtimeJourney); err != nil || len(realtimeJourney.VehicleLocation.Coordinates) != 2 {
+			continue
+		}
+
+		if !realtimeJourney.IsActive() {
+			continue
+		}
+
+		score := scoreRealtimeJourneyLocation(&q, realtimeJourney)
+		if score < bestScore {
+			bestScore = score
+			bestJourney = realtimeJourney
+		}
+	}
+
+	if bestJourney == nil {
+		return nil, errors.New("could not find a matching Realtime Journey")
+	}
+
+	return bestJourney, nil
+}
+
+// scoreRealtimeJourneyLocation is roughly how many metres off the vehicle is from the request, lower is a better match
+func scoreRealtimeJourneyLocation(q *query.RealtimeJourneyByLocation, realtimeJourney *ctdf.RealtimeJourney) float64 {
+	score := q.Location.GreatCircleDistance(&realtimeJourney.VehicleLocation)
+
+	// The vehicle could have moved this far since its location was recorded so older locations are less certain
+	age := math.Abs(q.Timestamp.Sub(realtimeJourney.ModificationDateTime).Seconds())
+	score += age * identifyVehicleSpeed / 2
+
+	// A vehicle heading the opposite way counts as if it was at the edge of the search radius
+	if q.Bearing != nil {
+		bearingDifference := math.Mod(math.Abs(*q.Bearing-realtimeJourney.VehicleBearing), 360)
+		if bearingDifference > 180 {
+			bearingDifference = 360 - bearingDifference
+		}
+
+		score += (bearingDifference / 180) * q.Radius
+	}
+
+	return score
+}
@@ -0,0 +1,36 @@
+package databaselookup
+
+import (
+	"errors"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+)
+
+func (s Source) RouteForServiceQuery(q query.RouteForService) ([]*ctdf.Route, error) {
+	if q.Service == nil {
+		return nil, errors.New("a Service must be provided")
+	}
+
+	var routes []*ctdf.Route
+	for i := range q.Service.Routes {
+		route := &q.Service.Routes[i]
+
+		// Routes straight from the source without materialised stops cant be drawn
+		if len(route.StopRefs) == 0 {
+			continue
+		}
+
+		if q.Stop != nil && !route.ServesStop(q.Stop.GetAllStopIDs()) {
+			continue
+		}
+
+		routes = append(routes, route)
+	}
+
+	if len(routes) == 0 {
+		return nil, errors.New("could not find any matching Routes")
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,56 @@
+package databaselookup
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) ServicesByOperatorQuery(q query.ServicesByOperator) ([]*ctdf.Service, error) {
+	return findServicesByOperatorRefs(q.ToBson())
+}
+
+func (s Source) ServicesByOperatorGroupQuery(q query.ServicesByOperatorGroup) ([]*ctdf.Service, error) {
+	if len(q.OperatorGroup.Operators) == 0 {
+		q.OperatorGroup.GetOperators()
+	}
+
+	// Nothing can be run by a group without any operators & $in doesn't accept a nil list
+	if len(q.OperatorGroup.Operators) == 0 {
+		return []*ctdf.Service{}, nil
+	}
+
+	return findServicesByOperatorRefs(q.ToBson())
+}
+
+func findServicesByOperatorRefs(filter bson.M) ([]*ctdf.Service, error) {
+	servicesCollection := database.GetCollection("services")
+
+	opts := options.Find().
+		SetCollation(database.ServiceNameCollation).
+		SetSort(bson.D{{Key: "servicename", Value: 1}})
+
+	cursor, err := servicesCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*ctdf.Service
+	for cursor.Next(context.Background()) {
+		var service ctdf.Service
+		err := cursor.Decode(&service)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Service")
+			continue
+		}
+
+		services = append(services, &service)
+	}
+
+	return services, nil
+}
@@ -20,7 +20,7 @@ func (s Source) ServicesByStopQuery(q query.ServicesByStop) ([]*ctdf.Service, er
 	cacheItemPath := fmt.Sprintf("cachedresults/servicesbystopquery/%s", q.Stop.PrimaryIdentifier)
 	services, err := cachedresults.Get[[]*ctdf.Service](s.CachedResults, cacheItemPath)
 	if err == nil {
-		return services, nil
+		return filterServicesByTransportTypes(services, q.TransportTypes), nil
 	}
 
 	// If not in cache then fallback to lookup
@@ -63,5 +63,20 @@ func (s Source) ServicesByStopQuery(q query.ServicesByStop) ([]*ctdf.Service, er
 	// Save into cache
 	cachedresults.Set(s.CachedResults, cacheItemPath, services, 24*time.Hour)
 
-	return services, nil
+	return filterServicesByTransportTypes(services, q.TransportTypes), nil
+}
+
+func filterServicesByTransportTypes(services []*ctdf.Service, transportTypes []ctdf.TransportType) []*ctdf.Service {
+	if len(transportTypes) == 0 {
+		return services
+	}
+
+	var filteredServices []*ctdf.Service
+	for _, service := range services {
+		if ctdf.MatchesTransportTypes(transportTypes, service.TransportType) {
+			filteredServices = append(filteredServices, service)
+		}
+	}
+
+	return filteredServices
 }
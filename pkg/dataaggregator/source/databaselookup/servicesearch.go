@@ -0,0 +1,45 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) ServiceSearchQuery(q query.ServiceSearch) ([]*ctdf.Service, error) {
+	if query.NormaliseServiceName(q.ServiceName) == "" {
+		return nil, errors.New("no service name provided to search")
+	}
+
+	servicesCollection := database.GetCollection("services")
+
+	// Collation must match the ServiceNameSearch index for it to be used
+	opts := options.Find().
+		SetCollation(database.ServiceNameCollation).
+		SetSort(bson.D{{Key: "servicename", Value: 1}, {Key: "operatorref", Value: 1}})
+
+	cursor, err := servicesCollection.Find(context.Background(), q.ToBson(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*ctdf.Service
+	for cursor.Next(context.Background()) {
+		var service ctdf.Service
+		err := cursor.Decode(&service)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Service")
+			continue
+		}
+
+		services = append(services, &service)
+	}
+
+	return services, nil
+}
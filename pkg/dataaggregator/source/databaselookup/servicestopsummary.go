@@ -0,0 +1,46 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+)
+
+func (s Source) ServiceStopSummaryQuery(q query.ServiceStopSummary) (*ctdf.ServiceStopSummary, error) {
+	collection := database.GetCollection("service_stop_summaries")
+	var summary *ctdf.ServiceStopSummary
+	collection.FindOne(context.Background(), q.ToBson()).Decode(&summary)
+
+	if summary == nil {
+		return nil, errors.New("could not find a matching Service Stop Summary")
+	} else {
+		return summary, nil
+	}
+}
+
+func (s Source) ServiceStopSummariesByStopQuery(q query.ServiceStopSummariesByStop) ([]*ctdf.ServiceStopSummary, error) {
+	collection := database.GetCollection("service_stop_summaries")
+
+	cursor, err := collection.Find(context.Background(), q.ToBson())
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []*ctdf.ServiceStopSummary
+	for cursor.Next(context.Background()) {
+		var summary ctdf.ServiceStopSummary
+		err := cursor.Decode(&summary)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Service Stop Summary")
+			continue
+		}
+
+		summaries = append(summaries, &summary)
+	}
+
+	return summaries, nil
+}
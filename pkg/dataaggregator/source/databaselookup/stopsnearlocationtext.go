@@ -0,0 +1,56 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+)
+
+func (s Source) StopsNearLocationTextQuery(q query.StopsNearLocationText) ([]*ctdf.Stop, error) {
+	location, err := q.GetLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	count := q.Count
+	if count <= 0 {
+		count = query.StopsNearLocationTextDefaultCount
+	}
+
+	collection := database.GetCollection("stops")
+	cursor, err := collection.Find(context.Background(), q.ToBson(location))
+	if err != nil {
+		return nil, err
+	}
+
+	var stops []*ctdf.Stop
+	for cursor.Next(context.Background()) {
+		var stop ctdf.Stop
+		err := cursor.Decode(&stop)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Stop")
+			continue
+		}
+
+		stops = append(stops, &stop)
+	}
+
+	if len(stops) == 0 {
+		return nil, errors.New("could not find any Stops near the location")
+	}
+
+	sort.SliceStable(stops, func(i, j int) bool {
+		return location.GreatCircleDistance(stops[i].Location) < location.GreatCircleDistance(stops[j].Location)
+	})
+
+	if len(stops) > count {
+		stops = stops[:count]
+	}
+
+	return stops, nil
+}
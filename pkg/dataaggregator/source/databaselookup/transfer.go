@@ -0,0 +1,33 @@
+package databaselookup
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+)
+
+func (s Source) TransfersByStopQuery(q query.TransfersByStop) ([]*ctdf.Transfer, error) {
+	collection := database.GetCollection("transfers")
+
+	cursor, err := collection.Find(context.Background(), q.ToBson())
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []*ctdf.Transfer
+	for cursor.Next(context.Background()) {
+		var transfer ctdf.Transfer
+		err := cursor.Decode(&transfer)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Transfer")
+			continue
+		}
+
+		transfers = append(transfers, &transfer)
+	}
+
+	return transfers, nil
+}
@@ -0,0 +1,57 @@
+package databaselookup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s Source) VehicleTrackQuery(q query.VehicleTrack) (*ctdf.VehicleTrack, error) {
+	collection := database.GetCollection("realtime_journeys")
+
+	opts := options.FindOne().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "vehicleref", Value: 1},
+		{Key: "vehiclelocation", Value: 1},
+		{Key: "vehiclebearing", Value: 1},
+		{Key: "modificationdatetime", Value: 1},
+		{Key: "vehicletrack", Value: 1},
+	})
+
+	var realtimeJourney *ctdf.RealtimeJourney
+	collection.FindOne(context.Background(), q.ToBson(), opts).Decode(&realtimeJourney)
+
+	if realtimeJourney == nil {
+		return nil, errors.New("could not find a matching Realtime Journey")
+	}
+
+	track := &ctdf.VehicleTrack{
+		RealtimeJourneyRef: realtimeJourney.PrimaryIdentifier,
+		VehicleRef:         realtimeJourney.VehicleRef,
+		Points:             realtimeJourney.VehicleTrack,
+	}
+
+	// The track is sampled so finish it at where the vehicle was last seen
+	var lastPoint *ctdf.VehicleTrackPoint
+	if len(track.Points) > 0 {
+		lastPoint = track.Points[len(track.Points)-1]
+	}
+	if realtimeJourney.VehicleLocation.Type != "" && (lastPoint == nil || lastPoint.Timestamp.Before(realtimeJourney.ModificationDateTime)) {
+		track.Points = append(track.Points, &ctdf.VehicleTrackPoint{
+			Location:  realtimeJourney.VehicleLocation,
+			Bearing:   realtimeJourney.VehicleBearing,
+			Timestamp: realtimeJourney.ModificationDateTime,
+		})
+	}
+
+	if track.Points == nil {
+		track.Points = []*ctdf.VehicleTrackPoint{}
+	}
+
+	return track, nil
+}
@@ -22,6 +22,8 @@ func (s Source) JourneyPlanQuery(q query.JourneyPlan) (*ctdf.JourneyPlanResults,
 		Stop:          q.OriginStop,
 		Count:         q.Count * 10,
 		StartDateTime: q.StartDateTime,
+
+		TransportTypes: q.TransportTypes,
 		// Filter:        &bson.M{"path.destinationstopref": bson.M{"$in": append(q.DestinationStop.OtherIdentifiers, q.DestinationStop.PrimaryIdentifier)}},
 	})
 
@@ -53,23 +55,21 @@ func (s Source) JourneyPlanQuery(q query.JourneyPlan) (*ctdf.JourneyPlanResults,
 
 		seenOrigin := false
 		seenDestination := false
+		var originItem *ctdf.JourneyPathItem
 
 		for _, item := range departure.Journey.Path {
 			if item.OriginStopRef == q.OriginStop.PrimaryIdentifier || slices.Contains[[]string](q.OriginStop.OtherIdentifiers, item.OriginStopRef) {
 				seenOrigin = true
+				originItem = item
 			}
 
 			if item.DestinationStopRef == q.DestinationStop.PrimaryIdentifier || slices.Contains[[]string](q.DestinationStop.OtherIdentifiers, item.DestinationStopRef) {
 				seenDestination = true
 
-				refTime := item.DestinationArrivalTime
-				dateTime := q.StartDateTime
-				arrivalTime = time.Date(
-					dateTime.Year(), dateTime.Month(), dateTime.Day(), refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), dateTime.Location(),
-				)
-
-				if arrivalTime.Before(startTime) {
-					arrivalTime = arrivalTime.Add(24 * time.Hour)
+				// Arrival is the departure plus the scheduled time between the stops, the day offsets keep it right past midnight
+				if originItem != nil {
+					var serviceDay time.Time
+					arrivalTime = startTime.Add(item.GetDestinationArrivalDateTime(serviceDay).Sub(originItem.GetOriginDepartureDateTime(serviceDay)))
 				}
 				break
 			}
@@ -20,6 +20,12 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Journeys can still be calling at a stop up to this many days after the service day they started on
+const maximumServiceDayOffset = 2
+
+// Departure boards starting within the same window share their cached journeys
+const journeyCacheWindowMinutes = 15
+
 func (s Source) DepartureBoardQuery(q query.DepartureBoard) ([]*ctdf.DepartureBoard, error) {
 	var departureBoard []*ctdf.DepartureBoard
 
@@ -39,36 +45,54 @@ func (s Source) DepartureBoardQuery(q query.DepartureBoard) ([]*ctdf.DepartureBo
 	filterHash.Write([]byte(pretty.Sprint(q.Filter)))
 	filterHashString := fmt.Sprintf("%x", filterHash.Sum(nil))
 
+	departureBoardCacheKey := fmt.Sprintf(
+		"%s/%s/%s/%d/%v", q.Stop.PrimaryIdentifier, filterHashString, q.StartDateTime.Truncate(time.Minute).Format(time.RFC3339), q.Count, q.TransportTypes,
+	)
+	if cachedDepartureBoard, ok := s.DepartureBoardResults.Get(departureBoardCacheKey); ok {
+		return cachedDepartureBoard, nil
+	}
+
 	currentTime := time.Now()
 
+	// Only journeys departing after the start of the board are needed, rounded down so nearby boards share them
+	startMinute := q.StartDateTime.Hour()*60 + q.StartDateTime.Minute()
+	startMinute -= startMinute % journeyCacheWindowMinutes
+
 	baseCacheItemPath := fmt.Sprintf("cachedresults/departureboardjourneys/%s/%s", q.Stop.PrimaryIdentifier, filterHashString)
-	journeyQuery := bson.M{"path.originstopref": bson.M{"$in": allStopIDs}}
-	if q.Filter != nil {
-		journeyQuery = bson.M{
-			"$and": bson.A{
-				journeyQuery,
-				q.Filter,
-			},
-		}
-	}
-	journeysToday := s.getDateJourneys(baseCacheItemPath, journeyQuery, q.StartDateTime)
+	journeysToday := s.getDateJourneys(baseCacheItemPath, allStopIDs, q.Filter, q.StartDateTime, startMinute)
+	journeysToday = ctdf.FilterJourneysByTransportTypes(journeysToday, q.TransportTypes)
 
 	log.Debug().Str("Length", time.Now().Sub(currentTime).String()).Int("num", len(journeysToday)).Msg("Get cached journeys - today")
 
 	currentTime = time.Now()
-	departureBoardToday := ctdf.GenerateDepartureBoardFromJourneys(journeysToday, allStopIDs, q.StartDateTime, true)
+	departureBoardToday := ctdf.GenerateDepartureBoardFromJourneys(journeysToday, allStopIDs, q.StartDateTime, q.StartDateTime, true)
 	log.Debug().Str("Length", time.Now().Sub(currentTime).String()).Msg("Departure Board generation today")
 
+	// Journeys from earlier service days that run past midnight can still be calling here today
+	overnightFilter := bson.M{"path.origindeparturedayoffset": bson.M{"$gte": 1}}
+	if q.Filter != nil {
+		overnightFilter = bson.M{"$and": bson.A{q.Filter, overnightFilter}}
+	}
+	for daysBefore := 1; daysBefore <= maximumServiceDayOffset; daysBefore++ {
+		serviceDay := q.StartDateTime.AddDate(0, 0, -daysBefore)
+
+		overnightJourneys := s.getDateJourneys(fmt.Sprintf("%s/overnight", baseCacheItemPath), allStopIDs, &overnightFilter, serviceDay, daysBefore*24*60+startMinute)
+		overnightJourneys = ctdf.FilterJourneysByTransportTypes(overnightJourneys, q.TransportTypes)
+
+		departureBoardToday = append(departureBoardToday, ctdf.GenerateDepartureBoardFromJourneys(overnightJourneys, allStopIDs, serviceDay, q.StartDateTime, true)...)
+	}
+
 	// If not enough journeys in todays departure board then look into tomorrows
 	if len(departureBoardToday) < q.Count {
 		currentTime = time.Now()
 
-		journeysTomorrow := s.getDateJourneys(baseCacheItemPath, journeyQuery, dayAfterDateTime)
+		journeysTomorrow := s.getDateJourneys(baseCacheItemPath, allStopIDs, q.Filter, dayAfterDateTime, 0)
+		journeysTomorrow = ctdf.FilterJourneysByTransportTypes(journeysTomorrow, q.TransportTypes)
 
 		log.Debug().Str("Length", time.Now().Sub(currentTime).String()).Int("num", len(journeysToday)).Msg("Get cached journeys - tomorrow")
 		currentTime = time.Now()
 
-		departureBoardTomorrow := ctdf.GenerateDepartureBoardFromJourneys(journeysTomorrow, allStopIDs, dayAfterDateTime, false)
+		departureBoardTomorrow := ctdf.GenerateDepartureBoardFromJourneys(journeysTomorrow, allStopIDs, dayAfterDateTime, dayAfterDateTime, false)
 		log.Debug().Str("Length", time.Now().Sub(currentTime).String()).Msg("Departure Board generation tomorrow")
 
 		departureBoard = append(departureBoardToday, departureBoardTomorrow...)
@@ -76,13 +100,16 @@ func (s Source) DepartureBoardQuery(q query.DepartureBoard) ([]*ctdf.DepartureBo
 		departureBoard = departureBoardToday
 	}
 
+	s.DepartureBoardResults.Set(departureBoardCacheKey, allStopIDs, departureBoard)
+
 	return departureBoard, nil
 }
 
-func (s Source) getDateJourneys(baseCacheItemPath string, journeyQuery bson.M, dateTime time.Time) []*ctdf.Journey {
+// getDateJourneys gets the journeys running on the date that depart the stops from fromMinute of the service day onwards
+func (s Source) getDateJourneys(baseCacheItemPath string, stopIDs []string, filter *bson.M, dateTime time.Time, fromMinute int) []*ctdf.Journey {
 	var journeys []*ctdf.Journey
 
-	cacheItemPath := fmt.Sprintf("%s/%s", baseCacheItemPath, dateTime.Format("2006-01-02"))
+	cacheItemPath := fmt.Sprintf("%s/%s/%d", baseCacheItemPath, dateTime.Format("2006-01-02"), fromMinute)
 
 	journeys, err := cachedresults.Get[[]*ctdf.Journey](s.CachedResults, cacheItemPath)
 
@@ -93,6 +120,16 @@ func (s Source) getDateJourneys(baseCacheItemPath string, journeyQuery bson.M, d
 	journeysCollection := database.GetCollection("journeys")
 	currentTime := time.Now()
 
+	journeyQuery := getJourneysQuery(stopIDs, dateTime, fromMinute)
+	if filter != nil {
+		journeyQuery = bson.M{
+			"$and": bson.A{
+				journeyQuery,
+				filter,
+			},
+		}
+	}
+
 	// This projection excludes values we dont care about - the main ones being path.*
 	// Reduces memory usage and execution time
 	opts := options.Find().SetProjection(bson.D{
@@ -126,7 +163,7 @@ func (s Source) getDateJourneys(baseCacheItemPath string, journeyQuery bson.M, d
 			log.Error().Err(err).Msg("Failed to decode journey")
 		}
 
-		if journey.Availability.MatchDate(dateTime) {
+		if journey.RunsOn(dateTime) {
 			journeys = append(journeys, &journey)
 		}
 	}
@@ -143,3 +180,31 @@ func (s Source) getDateJourneys(baseCacheItemPath string, journeyQuery bson.M, d
 
 	return journeys
 }
+
+// getJourneysQuery finds the journeys that could depart the stops on the date from fromMinute of the service day
+// onwards from the materialised stop departures. Stops without any materialised departures fall back to searching
+// the journey paths
+func getJourneysQuery(stopIDs []string, dateTime time.Time, fromMinute int) bson.M {
+	stopDeparturesCollection := database.GetCollection("stop_departures")
+
+	journeyRefs, err := stopDeparturesCollection.Distinct(context.Background(), "journeyref", bson.M{
+		"stopref": bson.M{"$in": stopIDs},
+		"daytype": dateTime.Weekday().String(),
+		"minuteofday": bson.M{
+			"$gte": fromMinute,
+			"$lt":  (maximumServiceDayOffset + 1) * 24 * 60,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query stop departures")
+	} else if len(journeyRefs) > 0 {
+		return bson.M{"primaryidentifier": bson.M{"$in": journeyRefs}}
+	} else {
+		materialised, err := stopDeparturesCollection.CountDocuments(context.Background(), bson.M{"stopref": bson.M{"$in": stopIDs}}, options.Count().SetLimit(1))
+		if err == nil && materialised > 0 {
+			return bson.M{"primaryidentifier": bson.M{"$in": bson.A{}}}
+		}
+	}
+
+	return bson.M{"path.originstopref": bson.M{"$in": stopIDs}}
+}
@@ -2,6 +2,8 @@ package localdepartureboard
 
 import (
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator/query"
@@ -9,13 +11,27 @@ import (
 	"github.com/travigo/travigo/pkg/dataaggregator/source/cachedresults"
 )
 
+// How long a generated departure board is reused for if no realtime updates affect it
+const departureBoardCacheTTL = 30 * time.Second
+
+// Shared by every Source in the process so there's only ever one invalidation subscription
+var departureBoardResults *cachedresults.DepartureBoardCache
+var departureBoardResultsOnce sync.Once
+
 type Source struct {
-	CachedResults *cachedresults.Cache
+	CachedResults         *cachedresults.Cache
+	DepartureBoardResults *cachedresults.DepartureBoardCache
 }
 
 func (s *Source) Setup() {
 	s.CachedResults = &cachedresults.Cache{}
 	s.CachedResults.Setup()
+
+	departureBoardResultsOnce.Do(func() {
+		departureBoardResults = cachedresults.NewDepartureBoardCache(departureBoardCacheTTL)
+		go departureBoardResults.Listen()
+	})
+	s.DepartureBoardResults = departureBoardResults
 }
 
 func (s Source) GetName() string {
@@ -0,0 +1,81 @@
+package nationalrailldb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type departureBoardResponse struct {
+	GeneratedAt       string `json:"generatedAt"`
+	LocationName      string `json:"locationName"`
+	CRS               string `json:"crs"`
+	PlatformAvailable bool   `json:"platformAvailable"`
+
+	TrainServices []*trainService `json:"trainServices"`
+}
+
+type trainService struct {
+	ServiceID    string `json:"serviceID"`
+	RSID         string `json:"rsid"`
+	ServiceType  string `json:"serviceType"`
+	Operator     string `json:"operator"`
+	OperatorCode string `json:"operatorCode"`
+
+	// STD is the scheduled departure time (HH:MM) and ETD the estimate, which can also be "On time", "Delayed" or "Cancelled"
+	STD string `json:"std"`
+	ETD string `json:"etd"`
+
+	Platform    string `json:"platform"`
+	IsCancelled bool   `json:"isCancelled"`
+	Length      int    `json:"length"`
+
+	Origin      []*serviceLocation `json:"origin"`
+	Destination []*serviceLocation `json:"destination"`
+}
+
+type serviceLocation struct {
+	LocationName string `json:"locationName"`
+	CRS          string `json:"crs"`
+	Via          string `json:"via"`
+}
+
+// getDepartureBoard fetches the departures from a station, timeOffset is in minutes from now
+func (s Source) getDepartureBoard(crs string, numRows int, timeOffset int) (*departureBoardResponse, error) {
+	requestURL := fmt.Sprintf("%s/GetDepartureBoard/%s?numRows=%s&timeOffset=%s",
+		s.BaseURL, url.PathEscape(crs), strconv.Itoa(numRows), strconv.Itoa(timeOffset),
+	)
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-apikey", s.APIKey)
+	req.Header.Set("user-agent", "curl/7.54.1")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Live Departure Boards returned status %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var departureBoard departureBoardResponse
+	if err := json.Unmarshal(body, &departureBoard); err != nil {
+		return nil, err
+	}
+
+	return &departureBoard, nil
+}
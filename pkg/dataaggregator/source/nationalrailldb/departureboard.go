@@ -0,0 +1,251 @@
+package nationalrailldb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/dataaggregator/source"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/transforms"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const journeyIDFormat = "gb-nationalrail-ldb-%s"
+
+// The Live Departure Boards API only accepts offsets within this range and at most this many rows
+const (
+	minimumTimeOffset = -120
+	maximumTimeOffset = 119
+	maximumRows       = 150
+)
+
+// How recently a realtime journey must have been updated for the station to count as having local realtime data
+const localRealtimeCutoff = 10 * time.Minute
+
+func (s Source) DepartureBoardQuery(q query.DepartureBoard) ([]*ctdf.DepartureBoard, error) {
+	if s.APIKey == "" || q.Filter != nil || !ctdf.MatchesTransportTypes(q.TransportTypes, ctdf.TransportTypeRail) {
+		return nil, source.UnsupportedSourceError
+	}
+
+	crs := getStopCRS(q.Stop)
+	if crs == "" {
+		return nil, source.UnsupportedSourceError
+	}
+
+	now := time.Now()
+	timeOffset := int(q.StartDateTime.Sub(now).Minutes())
+	if timeOffset < minimumTimeOffset || timeOffset > maximumTimeOffset {
+		return nil, source.UnsupportedSourceError
+	}
+
+	if hasLocalRealtime(q.Stop.GetAllStopIDs()) {
+		return nil, source.UnsupportedSourceError
+	}
+
+	numRows := min(max(q.Count, 1), maximumRows)
+	response, err := s.getDepartureBoard(crs, numRows, timeOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	stopTimezone, err := time.LoadLocation(q.Stop.Timezone)
+	if err != nil {
+		stopTimezone, _ = time.LoadLocation("Europe/London")
+	}
+
+	localJourneys := getLocalJourneys(q.Stop.GetAllStopIDs(), response.TrainServices, q.StartDateTime)
+	operators := map[string]*ctdf.Operator{}
+
+	var departureBoard []*ctdf.DepartureBoard
+	for _, service := range response.TrainServices {
+		scheduledTime, err := parseServiceTime(service.STD, q.StartDateTime.In(stopTimezone))
+		if err != nil {
+			continue
+		}
+
+		operatorRef := fmt.Sprintf(ctdf.OperatorTOCFormat, service.OperatorCode)
+
+		journey := localJourneys[getLocalJourneyKey(operatorRef, scheduledTime)]
+		if journey == nil {
+			journey = &ctdf.Journey{
+				PrimaryIdentifier:  fmt.Sprintf(journeyIDFormat, service.ServiceID),
+				OperatorRef:        operatorRef,
+				ServiceRef:         operatorRef,
+				DepartureTime:      scheduledTime,
+				DepartureTimezone:  stopTimezone.String(),
+				DestinationDisplay: getDestinationDisplay(service.Destination),
+			}
+		}
+
+		if operators[operatorRef] == nil {
+			journey.GetReferences()
+			if journey.Operator == nil {
+				journey.Operator = &ctdf.Operator{
+					PrimaryIdentifier: operatorRef,
+					PrimaryName:       service.Operator,
+				}
+			}
+			operators[operatorRef] = journey.Operator
+		} else {
+			journey.Operator = operators[operatorRef]
+			journey.GetService()
+		}
+
+		departure := &ctdf.DepartureBoard{
+			Journey:            journey,
+			DestinationDisplay: getDestinationDisplay(service.Destination),
+			Type:               ctdf.DepartureBoardRecordTypeScheduled,
+			Time:               scheduledTime,
+		}
+
+		switch {
+		case service.IsCancelled || service.ETD == "Cancelled":
+			departure.Type = ctdf.DepartureBoardRecordTypeCancelled
+		case service.ETD == "On time":
+			departure.Type = ctdf.DepartureBoardRecordTypeRealtimeTracked
+		case service.ETD == "Delayed":
+			departure.Type = ctdf.DepartureBoardRecordTypeEstimated
+		default:
+			if estimatedTime, err := parseServiceTime(service.ETD, scheduledTime); err == nil {
+				departure.Type = ctdf.DepartureBoardRecordTypeRealtimeTracked
+				departure.Time = estimatedTime
+			}
+		}
+
+		if service.Platform != "" {
+			departure.Platform = service.Platform
+			departure.PlatformType = "ACTUAL"
+		}
+
+		transforms.Transform(departure.Journey.Service, 2)
+		transforms.Transform(departure.Journey.Operator, 2)
+
+		departureBoard = append(departureBoard, departure)
+	}
+
+	return departureBoard, nil
+}
+
+func getStopCRS(stop *ctdf.Stop) string {
+	for _, identifier := range stop.OtherIdentifiers {
+		if strings.HasPrefix(identifier, "gb-crs-") {
+			return strings.TrimPrefix(identifier, "gb-crs-")
+		}
+	}
+
+	return ""
+}
+
+// hasLocalRealtime checks if any recently updated realtime journeys call at the stops
+func hasLocalRealtime(stopIDs []string) bool {
+	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+
+	var stopQueries bson.A
+	for _, stopID := range stopIDs {
+		stopQueries = append(stopQueries, bson.M{fmt.Sprintf("stops.%s", stopID): bson.M{"$exists": true}})
+	}
+
+	count, err := realtimeJourneysCollection.CountDocuments(context.Background(), bson.M{
+		"$or":                  stopQueries,
+		"modificationdatetime": bson.M{"$gt": time.Now().Add(-localRealtimeCutoff)},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check for local realtime journeys")
+		return false
+	}
+
+	return count > 0
+}
+
+func getLocalJourneyKey(operatorRef string, departureTime time.Time) string {
+	return fmt.Sprintf("%s/%s", operatorRef, departureTime.Format("15:04"))
+}
+
+// getLocalJourneys finds the timetabled journeys matching the services by operator and departure time from the stop
+// so departures link up to the journeys we already know about
+func getLocalJourneys(stopIDs []string, services []*trainService, date time.Time) map[string]*ctdf.Journey {
+	journeys := map[string]*ctdf.Journey{}
+
+	var operatorRefs []string
+	for _, service := range services {
+		operatorRefs = append(operatorRefs, fmt.Sprintf(ctdf.OperatorTOCFormat, service.OperatorCode))
+	}
+
+	if len(operatorRefs) == 0 {
+		return journeys
+	}
+
+	journeysCollection := database.GetCollection("journeys")
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "_id", Value: 0},
+		bson.E{Key: "path.track", Value: 0},
+		bson.E{Key: "track", Value: 0},
+		bson.E{Key: "detailedrailinformation", Value: 0},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), bson.M{
+		"path.originstopref": bson.M{"$in": stopIDs},
+		"operatorref":        bson.M{"$in": operatorRefs},
+	}, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query Journeys")
+		return journeys
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			continue
+		}
+
+		if !journey.RunsOn(date) {
+			continue
+		}
+
+		for _, pathItem := range journey.Path {
+			for _, stopID := range stopIDs {
+				if pathItem.OriginStopRef == stopID {
+					journeys[getLocalJourneyKey(journey.OperatorRef, pathItem.OriginDepartureTime)] = &journey
+				}
+			}
+		}
+	}
+
+	return journeys
+}
+
+// parseServiceTime turns a HH:MM time into a full time closest to the reference time, which handles boards spanning
+// midnight
+func parseServiceTime(serviceTime string, reference time.Time) (time.Time, error) {
+	parsedTime, err := time.Parse("15:04", serviceTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	fullTime := time.Date(
+		reference.Year(), reference.Month(), reference.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, reference.Location(),
+	)
+
+	if fullTime.Sub(reference) < -12*time.Hour {
+		fullTime = fullTime.AddDate(0, 0, 1)
+	} else if fullTime.Sub(reference) > 12*time.Hour {
+		fullTime = fullTime.AddDate(0, 0, -1)
+	}
+
+	return fullTime, nil
+}
+
+func getDestinationDisplay(destinations []*serviceLocation) string {
+	var names []string
+	for _, destination := range destinations {
+		names = append(names, destination.LocationName)
+	}
+
+	return strings.Join(names, " & ")
+}
@@ -0,0 +1,52 @@
+package nationalrailldb
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/query"
+	"github.com/travigo/travigo/pkg/dataaggregator/source"
+)
+
+// DefaultBaseURL is the Rail Data Marketplace endpoint for the Live Departure Boards web service
+const DefaultBaseURL = "https://api1.raildata.org.uk/1010-live-departure-board-dep1_2/LDBWS/api/20220120"
+
+// Source answers departure boards for rail stations from Darwin via the Live Departure Boards API, for when we
+// don't have any realtime data of our own for the station
+type Source struct {
+	APIKey  string
+	BaseURL string
+
+	client *http.Client
+}
+
+func (s *Source) Setup() {
+	if s.BaseURL == "" {
+		s.BaseURL = DefaultBaseURL
+	}
+
+	s.client = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+}
+
+func (s Source) GetName() string {
+	return "National Rail Live Departure Boards"
+}
+
+func (s Source) Supports() []reflect.Type {
+	return []reflect.Type{
+		reflect.TypeOf([]*ctdf.DepartureBoard{}),
+	}
+}
+
+func (s Source) Lookup(q any) (interface{}, error) {
+	switch q.(type) {
+	case query.DepartureBoard:
+		return s.DepartureBoardQuery(q.(query.DepartureBoard))
+	default:
+		return nil, source.UnsupportedSourceError
+	}
+}
@@ -129,5 +129,5 @@ func (s Source) DepartureBoardQuery(q query.DepartureBoard) ([]*ctdf.DepartureBo
 		}
 	}
 
-	return departureBoard, nil
+	return ctdf.FilterDepartureBoardByTransportTypes(departureBoard, q.TransportTypes), nil
 }
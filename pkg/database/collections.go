@@ -9,6 +9,14 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ServiceNameCollation is a case-insensitive collation with numeric ordering so service names like "X1" & "x1"
+// match each other and "2" sorts before "10"
+var ServiceNameCollation = &options.Collation{
+	Locale:          "en",
+	Strength:        2,
+	NumericOrdering: true,
+}
+
 func createIndexes() {
 	createStopsIndexes()
 	createOperatorsIndexes()
@@ -74,6 +82,24 @@ func createStopsIndexes() {
 		log.Error().Err(err).Msg("Creating Index")
 	}
 
+	// NaPTAN StopPoints held back until the whole document has been read
+	naptanImportStopPointsCollection := GetCollection("naptan_import_stoppoints")
+	naptanImportStopPointsIndex := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "importref", Value: 1}, {Key: "kind", Value: 1}, {Key: "stopgrouprefs", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "creationdatetime", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60),
+		},
+	}
+
+	opts = options.CreateIndexes()
+	_, err = naptanImportStopPointsCollection.Indexes().CreateMany(context.Background(), naptanImportStopPointsIndex, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
 	// Stop Groups
 	stopGroupsCollection := GetCollection("stop_groups")
 	stopGroupsIndex := []mongo.IndexModel{
@@ -87,6 +113,34 @@ func createStopsIndexes() {
 	if err != nil {
 		log.Error().Err(err).Msg("Creating Index")
 	}
+
+	// Localities
+	localitiesCollection := GetCollection("localities")
+	localitiesIndex := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+	}
+
+	opts = options.CreateIndexes()
+	_, err = localitiesCollection.Indexes().CreateMany(context.Background(), localitiesIndex, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Administrative Areas
+	administrativeAreasCollection := GetCollection("administrative_areas")
+	administrativeAreasIndex := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+	}
+
+	opts = options.CreateIndexes()
+	_, err = administrativeAreasCollection.Indexes().CreateMany(context.Background(), administrativeAreasIndex, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
 }
 
 func createOperatorsIndexes() {
@@ -159,6 +213,9 @@ func createRealtimeIndexes() {
 		{
 			Keys: bson.D{{Key: "activelytracked", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "journeyrundate", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "vehiclelocation.coordinates", Value: "2d"}},
 		},
@@ -166,12 +223,125 @@ func createRealtimeIndexes() {
 	if err != nil {
 		log.Error().Err(err).Msg("Creating Index")
 	}
+
+	// Vehicles
+	vehiclesCollection := GetCollection("vehicles")
+	_, err = vehiclesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "modificationdatetime", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(30 * 24 * 3600), // Expire after 30 days
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Observed Journeys
+	observedJourneysCollection := GetCollection("observed_journeys")
+	_, err = observedJourneysCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "date", Value: 1},
+				{Key: "operatorref", Value: 1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "observeddatetime", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(14 * 24 * 3600), // Expire after 14 days
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Data Quality Scores
+	dataQualityScoresCollection := GetCollection("data_quality_scores")
+	_, err = dataQualityScoresCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "subjecttype", Value: 1},
+				{Key: "subjectref", Value: 1},
+				{Key: "date", Value: 1},
+			},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Operator Tracking Stats
+	operatorTrackingStatsCollection := GetCollection("operator_tracking_stats")
+	_, err = operatorTrackingStatsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "operatorref", Value: 1},
+				{Key: "date", Value: 1},
+			},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Occupancy History
+	occupancyHistoryCollection := GetCollection("occupancy_history")
+	_, err = occupancyHistoryCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "serviceref", Value: 1},
+				{Key: "recordedat", Value: 1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "recordedat", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(56 * 24 * 3600), // Expire after 8 weeks
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Realtime Journey Events
+	realtimeJourneyEventsCollection := GetCollection("realtime_journey_events")
+	_, err = realtimeJourneyEventsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "realtimejourneyref", Value: 1},
+				{Key: "recordedat", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "compacted", Value: 1},
+				{Key: "recordedat", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "recordedat", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
 }
 
 func createJourneysIndexes() {
 	// Services
 	servicesCollection := GetCollection("services")
 	serviceNameOperatorRefIndexName := "ServiceNameOperatorRef"
+	serviceNameSearchIndexName := "ServiceNameSearch"
 	_, err := servicesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
 		{
 			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
@@ -188,6 +358,19 @@ func createJourneysIndexes() {
 				{Key: "operatorref", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{{Key: "operatorref", Value: 1}},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name:      &serviceNameSearchIndexName,
+				Collation: ServiceNameCollation,
+			},
+			Keys: bson.D{
+				{Key: "servicename", Value: 1},
+				{Key: "operatorref", Value: 1},
+			},
+		},
 	}, options.CreateIndexes())
 	if err != nil {
 		log.Error().Err(err).Msg("Creating Index")
@@ -200,6 +383,11 @@ func createJourneysIndexes() {
 	// journeyIdentificationServiceDestinationStopsIndexName := "JourneyIdentificationServiceDestinationStops"
 	journeyIdentificationServiceTicketMachineJourneycodeIndexName := "JourneyIdentificationServiceTicketMachineJourneyCode"
 	journeyIdentificationServiceBlockNumberIndexName := "JourneyIdentificationServiceBlockNumberJourneyCode"
+	journeyDatasetFunctionalHashIndexName := "JourneyDatasetFunctionalHash"
+
+	// Separate trips can share a functional hash so it used to be unique, does nothing once it's gone
+	journeysCollection.Indexes().DropOne(context.Background(), "datasource.datasetid_1_functionalhash_1")
+
 	_, err = journeysCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
 		{
 			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
@@ -216,6 +404,27 @@ func createJourneysIndexes() {
 		{
 			Keys: bson.D{{Key: "datasource.datasetid", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "operatorref", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "blockref", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "datasource.datasetid", Value: 1},
+				{Key: "primaryidentifier", Value: 1},
+			},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name: &journeyDatasetFunctionalHashIndexName,
+			},
+			Keys: bson.D{
+				{Key: "datasource.datasetid", Value: 1},
+				{Key: "functionalhash", Value: 1},
+			},
+		},
 		// {
 		// 	Options: &options.IndexOptions{
 		// 		Name: &journeyIdentificationServiceOriginStopsIndexName,
@@ -255,6 +464,9 @@ func createJourneysIndexes() {
 		{
 			Keys: bson.D{{Key: "otheridentifiers.TrainUID", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "otheridentifiers.DarwinRID", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "otheridentifiers.GTFS-TripID", Value: 1}},
 		},
@@ -262,6 +474,162 @@ func createJourneysIndexes() {
 			Keys:    bson.D{{Key: "expiry", Value: 1}},
 			Options: options.Index().SetExpireAfterSeconds(1), // Expire after 1 second
 		},
+		{
+			Keys: bson.D{{Key: "validuntil", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Service Stop Summaries
+	serviceStopSummariesCollection := GetCollection("service_stop_summaries")
+	_, err = serviceStopSummariesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "stopref", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "serviceref", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "datasource.datasetid", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Stop Departures
+	stopDeparturesCollection := GetCollection("stop_departures")
+	_, err = stopDeparturesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "stopref", Value: 1}, {Key: "daytype", Value: 1}, {Key: "minuteofday", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "journeyref", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "datasource.datasetid", Value: 1}, {Key: "journeyref", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Blocks
+	blocksCollection := GetCollection("blocks")
+	_, err = blocksCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "datasource.datasetid", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Transfers
+	transfersCollection := GetCollection("transfers")
+	_, err = transfersCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "fromstopref", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "datasource.datasetid", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// School Term Calendars
+	schoolTermCalendarsCollection := GetCollection("school_term_calendars")
+	_, err = schoolTermCalendarsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Dataset Run Journeys
+	datasetRunJourneysCollection := GetCollection("dataset_run_journeys")
+	_, err = datasetRunJourneysCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "dataset", Value: 1},
+				{Key: "run", Value: 1},
+			},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Dataset Record Counts
+	datasetRecordCountsCollection := GetCollection("dataset_record_counts")
+	_, err = datasetRecordCountsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "dataset", Value: 1},
+				{Key: "creationdatetime", Value: -1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "creationdatetime", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(90 * 24 * 3600), // Expire after 90 days
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Dataset Archives
+	datasetArchivesCollection := GetCollection("dataset_archives")
+	_, err = datasetArchivesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "dataset", Value: 1},
+				{Key: "run", Value: 1},
+			},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Identifier Translations
+	identifierTranslationsCollection := GetCollection("identifier_translations")
+	_, err = identifierTranslationsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "objecttype", Value: 1},
+				{Key: "identifier", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "objecttype", Value: 1},
+				{Key: "primaryidentifier", Value: 1},
+				{Key: "identifiertype", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "objecttype", Value: 1},
+				{Key: "generation", Value: 1},
+			},
+		},
 	}, options.CreateIndexes())
 	if err != nil {
 		log.Error().Err(err).Msg("Creating Index")
@@ -279,6 +647,44 @@ func createJourneysIndexes() {
 		log.Error().Err(err).Msg("Creating Index")
 	}
 
+	// DatasetsDiscovered
+	datasetsDiscoveredCollection := GetCollection("datasets_discovered")
+	_, err = datasetsDiscoveredCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "identifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "template", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Leases
+	leasesCollection := GetCollection("leases")
+	_, err = leasesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// DatasetsCustom
+	datasetsCustomCollection := GetCollection("datasets_custom")
+	_, err = datasetsCustomCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "identifier", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
 	// ServiceAlerts
 	serviceAlertsCollection := GetCollection("service_alerts")
 	_, err = serviceAlertsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
@@ -288,6 +694,9 @@ func createJourneysIndexes() {
 		{
 			Keys: bson.D{{Key: "matchedidentifiers", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "matchkeys", Value: 1}},
+		},
 		{
 			Keys:    bson.D{{Key: "validuntil", Value: 1}},
 			Options: options.Index().SetExpireAfterSeconds(32 * 3600), // Expire after 32 hours
@@ -333,4 +742,133 @@ func createJourneysIndexes() {
 	if err != nil {
 		log.Error().Err(err).Msg("Creating Index")
 	}
+
+	// Geofences
+	geofencesCollection := GetCollection("geofences")
+	_, err = geofencesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Segment Run Times
+	segmentRunTimesCollection := GetCollection("segment_run_times")
+	_, err = segmentRunTimesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "departedat", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(56 * 24 * 3600), // Expire after 8 weeks
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	segmentRunTimeProfilesCollection := GetCollection("segment_run_time_profiles")
+	_, err = segmentRunTimeProfilesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "daytype", Value: 1},
+				{Key: "segmentref", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "modificationdatetime", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// DB Watch Resume Tokens
+	dbWatchResumeTokensCollection := GetCollection("dbwatch_resume_tokens")
+	_, err = dbWatchResumeTokensCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "watch", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// GTFS Agency Statistics
+	gtfsAgencyStatisticsCollection := GetCollection("gtfs_agency_statistics")
+	_, err = gtfsAgencyStatisticsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "dataset", Value: 1},
+				{Key: "agencyid", Value: 1},
+			},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Dock Availabilities
+	dockAvailabilitiesCollection := GetCollection("dock_availabilities")
+	_, err = dockAvailabilitiesCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "stopref", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Rail Locations
+	railLocationsCollection := GetCollection("rail_locations")
+	_, err = railLocationsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "otheridentifiers", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "stopref", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Bus Registrations
+	busRegistrationsCollection := GetCollection("bus_registrations")
+	_, err = busRegistrationsCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "licencenumber", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "servicerefs", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
+
+	// Car Parks
+	carParksCollection := GetCollection("car_parks")
+	_, err = carParksCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "primaryidentifier", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "location.coordinates", Value: "2d"}},
+		},
+		{
+			Keys: bson.D{{Key: "stoprefs", Value: 1}},
+		},
+	}, options.CreateIndexes())
+	if err != nil {
+		log.Error().Err(err).Msg("Creating Index")
+	}
 }
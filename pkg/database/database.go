@@ -19,6 +19,9 @@ type MongoInstance struct {
 var Instance *MongoInstance
 var RealtimeJourneyInstance *MongoInstance
 
+// sandboxCollections redirects specific collections to a separate database so tooling can write without touching live data
+var sandboxCollections = map[string]*MongoInstance{}
+
 const defaultConnectionString = "mongodb://localhost:27017/"
 const defaultDatabase = "travigo"
 
@@ -115,8 +118,22 @@ func ConnectRealtime() error {
 	return nil
 }
 
+// UseSandbox sends all reads & writes for the given collections to a separate database on the standard connection
+func UseSandbox(dbName string, collectionNames []string) {
+	sandbox := &MongoInstance{
+		Client:   Instance.Client,
+		Database: Instance.Client.Database(dbName),
+	}
+
+	for _, collectionName := range collectionNames {
+		sandboxCollections[collectionName] = sandbox
+	}
+}
+
 func GetInstance(collectionName string) *MongoInstance {
-	if collectionName == "realtime_journeys" && RealtimeJourneyInstance != nil {
+	if sandbox, exists := sandboxCollections[collectionName]; exists {
+		return sandbox
+	} else if collectionName == "realtime_journeys" && RealtimeJourneyInstance != nil {
 		return RealtimeJourneyInstance
 	} else {
 		return Instance
@@ -0,0 +1,201 @@
+package dataexport
+
+import (
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+const dateFormat = "2006-01-02"
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export flat analytical tables (journeys, stop_times, realtime_positions) as CSV or Parquet",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "table",
+				Usage:    "Table to export (journeys, stop_times or realtime_positions)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "output",
+				Usage:    "Path of the file to write",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output file format (csv or parquet)",
+				Value: string(FormatCSV),
+			},
+			&cli.StringSliceFlag{
+				Name:  "column",
+				Usage: "Only include these columns, in this order",
+			},
+			&cli.StringSliceFlag{
+				Name:  "dataset",
+				Usage: "Only include rows from these datasets",
+			},
+			&cli.TimestampFlag{
+				Name:   "from",
+				Usage:  "Only include rows on or after this date (YYYY-MM-DD). Realtime positions use when they were recorded, journeys & stop times the days the journey runs on",
+				Layout: dateFormat,
+			},
+			&cli.TimestampFlag{
+				Name:   "to",
+				Usage:  "Only include rows before this date (YYYY-MM-DD)",
+				Layout: dateFormat,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			table, err := GetTable(c.String("table"))
+			if err != nil {
+				return err
+			}
+
+			options := Options{
+				Columns:  c.StringSlice("column"),
+				Datasets: c.StringSlice("dataset"),
+			}
+			if from := c.Timestamp("from"); from != nil {
+				options.From = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+			}
+			if to := c.Timestamp("to"); to != nil {
+				options.To = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+			}
+
+			if err := database.Connect(); err != nil {
+				return err
+			}
+
+			rowCount, err := Export(c.String("output"), Format(c.String("format")), table, options)
+			if err != nil {
+				return err
+			}
+
+			log.Info().Str("table", table.Name).Str("output", c.String("output")).Int64("rows", rowCount).Msg("Table exported")
+
+			return nil
+		},
+	}
+}
+
+func RegisterTimetableCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "timetable",
+		Usage: "Render the timetable of a service as text, CSV or HTML",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "service",
+				Usage:    "Identifier of the service to render",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "day-type",
+				Usage: "Day of the week to render the journeys that could run on (eg. Monday)",
+				Value: "Monday",
+			},
+			&cli.TimestampFlag{
+				Name:   "date",
+				Usage:  "Render the journeys running on this date (YYYY-MM-DD) instead of a day type",
+				Layout: dateFormat,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (text, csv or html)",
+				Value: string(TimetableFormatText),
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Path of the file to write, defaults to stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			var date time.Time
+			if timestamp := c.Timestamp("date"); timestamp != nil {
+				date = time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, time.Local)
+			}
+
+			if err := database.Connect(); err != nil {
+				return err
+			}
+
+			timetable, err := BuildTimetable(c.String("service"), c.String("day-type"), date)
+			if err != nil {
+				return err
+			}
+
+			output := os.Stdout
+			if c.String("output") != "" {
+				output, err = os.Create(c.String("output"))
+				if err != nil {
+					return err
+				}
+				defer output.Close()
+			}
+
+			return timetable.Render(output, TimetableFormat(c.String("format")))
+		},
+	}
+}
+
+func RegisterDownloadsCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "bulk-downloads",
+		Usage: "Generate & serve the bulk CTDF downloads",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "Dump the stops, services & journeys into the downloads directory",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "dir",
+						Usage:    "Directory the dumps are written to",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					_, err := Dump(c.String("dir"))
+
+					return err
+				},
+			},
+			{
+				Name:  "run",
+				Usage: "run the bulk download server",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "dir",
+						Usage:    "Directory the dumps are served from",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: ":8084",
+						Usage: "listen target for the web server",
+					},
+					&cli.IntFlag{
+						Name:  "requests-per-minute",
+						Value: 10,
+						Usage: "Requests allowed per minute from each client",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					server := &DownloadServer{
+						Directory:         c.String("dir"),
+						RequestsPerMinute: c.Int("requests-per-minute"),
+					}
+
+					return server.Listen(c.String("listen"))
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package dataexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// Clients that haven't made a request in this long have their rate limiter dropped
+const downloadClientExpiry = 10 * time.Minute
+
+// DownloadServer serves the files of the latest dump with ETags & range requests so large downloads can be resumed
+type DownloadServer struct {
+	Directory string
+
+	// Requests per minute allowed from each client
+	RequestsPerMinute int
+
+	mutex   sync.Mutex
+	clients map[string]*downloadClient
+}
+
+type downloadClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func (s *DownloadServer) Listen(listen string) error {
+	s.clients = map[string]*downloadClient{}
+
+	go func() {
+		for range time.Tick(downloadClientExpiry) {
+			s.removeExpiredClients()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /downloads", s.rateLimit(s.serveManifest))
+	mux.HandleFunc("GET /downloads/{name}", s.rateLimit(s.serveFile))
+
+	log.Info().Str("listen", listen).Str("directory", s.Directory).Msg("Bulk download server listening")
+
+	return http.ListenAndServe(listen, mux)
+}
+
+func (s *DownloadServer) getManifest() (*DumpManifest, error) {
+	manifestBytes, err := os.ReadFile(filepath.Join(s.Directory, DumpManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest *DumpManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (s *DownloadServer) serveManifest(writer http.ResponseWriter, request *http.Request) {
+	manifest, err := s.getManifest()
+	if err != nil {
+		http.Error(writer, "No dumps available", http.StatusServiceUnavailable)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(manifest)
+}
+
+func (s *DownloadServer) serveFile(writer http.ResponseWriter, request *http.Request) {
+	manifest, err := s.getManifest()
+	if err != nil {
+		http.Error(writer, "No dumps available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Only files in the manifest are served so nothing else in the directory can be reached
+	dumpFile := manifest.GetFile(request.PathValue("name"))
+	if dumpFile == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(s.Directory, dumpFile.Name))
+	if err != nil {
+		http.Error(writer, "Dump unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer file.Close()
+
+	writer.Header().Set("ETag", fmt.Sprintf(`"%s"`, dumpFile.SHA256))
+	writer.Header().Set("Cache-Control", "public, max-age=3600")
+	writer.Header().Set("Content-Type", "application/gzip")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, dumpFile.Name))
+
+	// Handles Range, If-Range & If-None-Match against the ETag set above
+	http.ServeContent(writer, request, dumpFile.Name, dumpFile.ModificationDateTime, file)
+}
+
+func (s *DownloadServer) rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if !s.getLimiter(getClientIP(request)).Allow() {
+			writer.Header().Set("Retry-After", "60")
+			http.Error(writer, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next(writer, request)
+	}
+}
+
+func (s *DownloadServer) getLimiter(clientIP string) *rate.Limiter {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	client := s.clients[clientIP]
+	if client == nil {
+		client = &downloadClient{
+			limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(max(s.RequestsPerMinute, 1))), max(s.RequestsPerMinute, 1)),
+		}
+		s.clients[clientIP] = client
+	}
+	client.lastSeen = time.Now()
+
+	return client.limiter
+}
+
+func (s *DownloadServer) removeExpiredClients() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for clientIP, client := range s.clients {
+		if time.Since(client.lastSeen) > downloadClientExpiry {
+			delete(s.clients, clientIP)
+		}
+	}
+}
+
+func getClientIP(request *http.Request) string {
+	if cloudflareConnectingIP := request.Header.Get("CF-Connecting-IP"); cloudflareConnectingIP != "" {
+		return cloudflareConnectingIP
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return strings.TrimSpace(request.RemoteAddr)
+	}
+
+	return host
+}
@@ -0,0 +1,214 @@
+package dataexport
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/liip/sheriff"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const DumpManifestName = "manifest.json"
+
+// DumpManifest lists the files of the last successful dump, the download server only serves files listed in it
+type DumpManifest struct {
+	GeneratedAt time.Time
+	Files       []DumpFile
+}
+
+type DumpFile struct {
+	Name    string
+	Size    int64
+	SHA256  string
+	Records int64
+
+	ModificationDateTime time.Time
+}
+
+func (m *DumpManifest) GetFile(name string) *DumpFile {
+	for i := range m.Files {
+		if m.Files[i].Name == name {
+			return &m.Files[i]
+		}
+	}
+
+	return nil
+}
+
+type dumpDefinition struct {
+	Name       string
+	Collection string
+
+	// Newline delimited dumps write a document per line instead of a single JSON array, better suited to the larger collections
+	NewlineDelimited bool
+
+	newRecord func() any
+}
+
+var dumpDefinitions = []dumpDefinition{
+	{
+		Name:       "stops.json.gz",
+		Collection: "stops",
+		newRecord:  func() any { return &ctdf.Stop{} },
+	},
+	{
+		Name:       "services.json.gz",
+		Collection: "services",
+		newRecord:  func() any { return &ctdf.Service{} },
+	},
+	{
+		Name:             "journeys.ndjson.gz",
+		Collection:       "journeys",
+		NewlineDelimited: true,
+		newRecord:        func() any { return &ctdf.Journey{} },
+	},
+}
+
+// Dump writes gzipped CTDF dumps of the core collections into outputDir followed by the manifest.
+// Each file is written under a temporary name & moved into place so the download server never serves a partial file.
+func Dump(outputDir string) (*DumpManifest, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	manifest := &DumpManifest{
+		GeneratedAt: time.Now(),
+	}
+
+	for _, definition := range dumpDefinitions {
+		dumpFile, err := writeDump(outputDir, definition)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info().Str("file", dumpFile.Name).Int64("records", dumpFile.Records).Int64("size", dumpFile.Size).Msg("Dump written")
+
+		manifest.Files = append(manifest.Files, *dumpFile)
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := filepath.Join(outputDir, DumpManifestName)
+	if err := os.WriteFile(manifestPath+".tmp", manifestBytes, 0644); err != nil {
+		return nil, err
+	}
+
+	return manifest, os.Rename(manifestPath+".tmp", manifestPath)
+}
+
+func writeDump(outputDir string, definition dumpDefinition) (*DumpFile, error) {
+	outputPath := filepath.Join(outputDir, definition.Name)
+
+	file, err := os.Create(outputPath + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	gzipWriter := gzip.NewWriter(io.MultiWriter(file, hash))
+
+	records, err := writeDumpRecords(gzipWriter, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(outputPath+".tmp", outputPath); err != nil {
+		return nil, err
+	}
+
+	return &DumpFile{
+		Name:                 definition.Name,
+		Size:                 fileInfo.Size(),
+		SHA256:               hex.EncodeToString(hash.Sum(nil)),
+		Records:              records,
+		ModificationDateTime: fileInfo.ModTime(),
+	}, nil
+}
+
+func writeDumpRecords(writer io.Writer, definition dumpDefinition) (int64, error) {
+	collection := database.GetCollection(definition.Collection)
+	cursor, err := collection.Find(context.Background(), bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	if !definition.NewlineDelimited {
+		if _, err := io.WriteString(writer, "["); err != nil {
+			return 0, err
+		}
+	}
+
+	var records int64
+	for cursor.Next(context.Background()) {
+		record := definition.newRecord()
+		if err := cursor.Decode(record); err != nil {
+			log.Error().Err(err).Str("collection", definition.Collection).Msg("Failed to decode document")
+			continue
+		}
+
+		// Same view of the records as the API gives out, internal fields are left out
+		reduced, err := sheriff.Marshal(&sheriff.Options{
+			Groups: []string{"basic", "detailed"},
+		}, record)
+		if err != nil {
+			return records, err
+		}
+
+		recordBytes, err := json.Marshal(reduced)
+		if err != nil {
+			return records, err
+		}
+
+		separator := ""
+		if definition.NewlineDelimited {
+			recordBytes = append(recordBytes, '\n')
+		} else if records > 0 {
+			separator = ","
+		}
+
+		if _, err := io.WriteString(writer, separator); err != nil {
+			return records, err
+		}
+		if _, err := writer.Write(recordBytes); err != nil {
+			return records, err
+		}
+
+		records += 1
+	}
+
+	if err := cursor.Err(); err != nil {
+		return records, err
+	}
+
+	if !definition.NewlineDelimited {
+		if _, err := io.WriteString(writer, "]"); err != nil {
+			return records, err
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,156 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Options limit an export to a set of columns, datasets and a date range. Empty values export everything
+type Options struct {
+	Columns  []string
+	Datasets []string
+
+	From time.Time
+	To   time.Time
+}
+
+func (o Options) query(table *Table) bson.M {
+	query := bson.M{}
+	for key, value := range table.Filter {
+		query[key] = value
+	}
+
+	if len(o.Datasets) > 0 {
+		query["datasource.datasetid"] = bson.M{"$in": o.Datasets}
+	}
+
+	dateRange := bson.M{}
+	if !o.From.IsZero() {
+		dateRange["$gte"] = o.From
+	}
+	if !o.To.IsZero() {
+		dateRange["$lt"] = o.To
+	}
+	if len(dateRange) > 0 && table.DateField != "" {
+		query[table.DateField] = dateRange
+	}
+
+	return query
+}
+
+// runsInRange is whether the journey runs on any day in the date range
+func (o Options) runsInRange(journey *ctdf.Journey) bool {
+	if o.From.IsZero() && o.To.IsZero() {
+		return true
+	}
+
+	for date := o.From; date.Before(o.To); date = date.AddDate(0, 0, 1) {
+		if journey.RunsOn(date) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o Options) columns(table *Table) ([]Column, error) {
+	if len(o.Columns) == 0 {
+		return table.Columns, nil
+	}
+
+	var columns []Column
+	for _, name := range o.Columns {
+		column := table.GetColumn(name)
+		if column == nil {
+			return nil, errors.New(fmt.Sprintf("Table %s has no column %s (available: %v)", table.Name, name, table.ColumnNames()))
+		}
+
+		columns = append(columns, *column)
+	}
+
+	return columns, nil
+}
+
+// Export writes the flattened rows of a table to outputPath in the given format, returning the number of rows written
+func Export(outputPath string, format Format, table *Table, options Options) (int64, error) {
+	if format != FormatCSV && format != FormatParquet {
+		return 0, errors.New(fmt.Sprintf("Unsupported export format %s", format))
+	}
+
+	// The days a journey runs on are worked out from its availability so the range can't be open ended
+	if table.DateField == "" && options.From.IsZero() != options.To.IsZero() {
+		return 0, errors.New(fmt.Sprintf("Table %s needs both a from & to date to filter by the days journeys run on", table.Name))
+	}
+
+	columns, err := options.columns(table)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer, err := newRowWriter(file, format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	collection := database.GetCollection(table.Collection)
+	cursor, err := collection.Find(context.Background(), options.query(table))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var rowCount int64
+	for cursor.Next(context.Background()) {
+		rows, err := table.rows(cursor.Current, options)
+		if err != nil {
+			log.Error().Err(err).Str("table", table.Name).Msg("Failed to decode document")
+			continue
+		}
+
+		for _, row := range rows {
+			values := make([]any, len(columns))
+			for i, column := range columns {
+				values[i] = normaliseValue(row[column.Name])
+			}
+
+			if err := writer.Write(values); err != nil {
+				return rowCount, err
+			}
+
+			rowCount += 1
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return rowCount, err
+	}
+
+	return rowCount, writer.Close()
+}
+
+func normaliseValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+	}
+
+	return value
+}
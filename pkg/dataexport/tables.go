@@ -0,0 +1,272 @@
+package dataexport
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// timeOfDayFormat is used for timetabled times, which only carry a meaningful time component
+const timeOfDayFormat = "15:04:05"
+
+type ColumnType string
+
+const (
+	ColumnString    ColumnType = "string"
+	ColumnInt                  = "int"
+	ColumnFloat                = "float"
+	ColumnBool                 = "bool"
+	ColumnTimestamp            = "timestamp"
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table describes a flat view over one of the collections. Each document can expand into any number of rows
+type Table struct {
+	Name       string
+	Collection string
+
+	// Filter is always applied on top of the export options
+	Filter bson.M
+	// DateField is the document field the --from/--to range is applied against,
+	// empty when the rows are filtered by the days their journey runs on instead
+	DateField string
+
+	Columns []Column
+
+	rows func(document bson.Raw, options Options) ([]map[string]any, error)
+}
+
+func (t *Table) GetColumn(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+
+	return nil
+}
+
+func (t *Table) ColumnNames() []string {
+	var names []string
+	for _, column := range t.Columns {
+		names = append(names, column.Name)
+	}
+
+	return names
+}
+
+var Tables = []*Table{
+	{
+		Name:       "journeys",
+		Collection: "journeys",
+		Columns: []Column{
+			{Name: "journey_id", Type: ColumnString},
+			{Name: "dataset_id", Type: ColumnString},
+			{Name: "service_ref", Type: ColumnString},
+			{Name: "operator_ref", Type: ColumnString},
+			{Name: "block_ref", Type: ColumnString},
+			{Name: "direction", Type: ColumnString},
+			{Name: "departure_time", Type: ColumnString},
+			{Name: "departure_timezone", Type: ColumnString},
+			{Name: "destination_display", Type: ColumnString},
+			{Name: "origin_stop_ref", Type: ColumnString},
+			{Name: "destination_stop_ref", Type: ColumnString},
+			{Name: "stop_count", Type: ColumnInt},
+			{Name: "creation_datetime", Type: ColumnTimestamp},
+			{Name: "modification_datetime", Type: ColumnTimestamp},
+		},
+		rows: journeyRows,
+	},
+	{
+		Name:       "stop_times",
+		Collection: "journeys",
+		Columns: []Column{
+			{Name: "journey_id", Type: ColumnString},
+			{Name: "dataset_id", Type: ColumnString},
+			{Name: "service_ref", Type: ColumnString},
+			{Name: "operator_ref", Type: ColumnString},
+			{Name: "stop_sequence", Type: ColumnInt},
+			{Name: "stop_ref", Type: ColumnString},
+			{Name: "platform", Type: ColumnString},
+			{Name: "arrival_time", Type: ColumnString},
+			{Name: "departure_time", Type: ColumnString},
+			{Name: "pickup", Type: ColumnBool},
+			{Name: "setdown", Type: ColumnBool},
+			{Name: "distance_to_next", Type: ColumnInt},
+		},
+		rows: stopTimeRows,
+	},
+	{
+		Name: "realtime_positions",
+		// Every position recorded in the event log rather than only where the vehicles are now
+		Collection: "realtime_journey_events",
+		Filter:     bson.M{"type": ctdf.RealtimeJourneyEventPosition},
+		DateField:  "recordedat",
+		Columns: []Column{
+			{Name: "realtime_journey_id", Type: ColumnString},
+			{Name: "dataset_id", Type: ColumnString},
+			{Name: "recorded_at", Type: ColumnTimestamp},
+			{Name: "latitude", Type: ColumnFloat},
+			{Name: "longitude", Type: ColumnFloat},
+			{Name: "bearing", Type: ColumnFloat},
+			{Name: "departed_stop_ref", Type: ColumnString},
+			{Name: "next_stop_ref", Type: ColumnString},
+		},
+		rows: realtimePositionRows,
+	},
+}
+
+func GetTable(name string) (*Table, error) {
+	for _, table := range Tables {
+		if table.Name == name {
+			return table, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unknown export table %s", name))
+}
+
+func journeyRows(document bson.Raw, options Options) ([]map[string]any, error) {
+	var journey ctdf.Journey
+	if err := bson.Unmarshal(document, &journey); err != nil {
+		return nil, err
+	}
+	if !options.runsInRange(&journey) {
+		return nil, nil
+	}
+
+	row := map[string]any{
+		"journey_id":            journey.PrimaryIdentifier,
+		"dataset_id":            getDatasetID(journey.DataSource),
+		"service_ref":           journey.ServiceRef,
+		"operator_ref":          journey.OperatorRef,
+		"block_ref":             journey.BlockRef,
+		"direction":             journey.Direction,
+		"departure_time":        formatTimeOfDay(journey.DepartureTime),
+		"departure_timezone":    journey.DepartureTimezone,
+		"destination_display":   journey.DestinationDisplay,
+		"stop_count":            0,
+		"creation_datetime":     journey.CreationDateTime,
+		"modification_datetime": journey.ModificationDateTime,
+	}
+
+	if len(journey.Path) > 0 {
+		row["origin_stop_ref"] = journey.Path[0].OriginStopRef
+		row["destination_stop_ref"] = journey.Path[len(journey.Path)-1].DestinationStopRef
+		row["stop_count"] = len(journey.Path) + 1
+	}
+
+	return []map[string]any{row}, nil
+}
+
+func stopTimeRows(document bson.Raw, options Options) ([]map[string]any, error) {
+	var journey ctdf.Journey
+	if err := bson.Unmarshal(document, &journey); err != nil {
+		return nil, err
+	}
+	if !options.runsInRange(&journey) {
+		return nil, nil
+	}
+
+	var rows []map[string]any
+	for i, pathItem := range journey.Path {
+		rows = append(rows, map[string]any{
+			"journey_id":       journey.PrimaryIdentifier,
+			"dataset_id":       getDatasetID(journey.DataSource),
+			"service_ref":      journey.ServiceRef,
+			"operator_ref":     journey.OperatorRef,
+			"stop_sequence":    i,
+			"stop_ref":         pathItem.OriginStopRef,
+			"platform":         pathItem.OriginPlatform,
+			"arrival_time":     formatServiceDayTime(pathItem.OriginArrivalTime, pathItem.OriginArrivalDayOffset),
+			"departure_time":   formatServiceDayTime(pathItem.OriginDepartureTime, pathItem.OriginDepartureDayOffset),
+			"pickup":           hasActivity(pathItem.OriginActivity, ctdf.JourneyPathItemActivityPickup),
+			"setdown":          hasActivity(pathItem.OriginActivity, ctdf.JourneyPathItemActivitySetdown),
+			"distance_to_next": pathItem.Distance,
+		})
+
+		// The final stop only appears as the destination of the last path item
+		if i == len(journey.Path)-1 {
+			rows = append(rows, map[string]any{
+				"journey_id":     journey.PrimaryIdentifier,
+				"dataset_id":     getDatasetID(journey.DataSource),
+				"service_ref":    journey.ServiceRef,
+				"operator_ref":   journey.OperatorRef,
+				"stop_sequence":  i + 1,
+				"stop_ref":       pathItem.DestinationStopRef,
+				"platform":       pathItem.DestinationPlatform,
+				"arrival_time":   formatServiceDayTime(pathItem.DestinationArrivalTime, pathItem.DestinationArrivalDayOffset),
+				"departure_time": formatServiceDayTime(pathItem.DestinationArrivalTime, pathItem.DestinationArrivalDayOffset),
+				"pickup":         hasActivity(pathItem.DestinationActivity, ctdf.JourneyPathItemActivityPickup),
+				"setdown":        hasActivity(pathItem.DestinationActivity, ctdf.JourneyPathItemActivitySetdown),
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+func realtimePositionRows(document bson.Raw, options Options) ([]map[string]any, error) {
+	var event ctdf.RealtimeJourneyEvent
+	if err := bson.Unmarshal(document, &event); err != nil {
+		return nil, err
+	}
+
+	row := map[string]any{
+		"realtime_journey_id": event.RealtimeJourneyRef,
+		"dataset_id":          getDatasetID(event.DataSource),
+		"recorded_at":         event.RecordedAt,
+		"bearing":             event.Bearing,
+		"departed_stop_ref":   event.DepartedStopRef,
+		"next_stop_ref":       event.NextStopRef,
+	}
+
+	if event.Location != nil && len(event.Location.Coordinates) == 2 {
+		row["longitude"] = event.Location.Coordinates[0]
+		row["latitude"] = event.Location.Coordinates[1]
+	}
+
+	return []map[string]any{row}, nil
+}
+
+func getDatasetID(dataSource *ctdf.DataSourceReference) string {
+	if dataSource == nil {
+		return ""
+	}
+
+	return dataSource.DatasetID
+}
+
+func formatTimeOfDay(timestamp time.Time) any {
+	if timestamp.IsZero() {
+		return nil
+	}
+
+	return timestamp.Format(timeOfDayFormat)
+}
+
+// formatServiceDayTime formats a timetabled time like GTFS does, with hours past 24 for times after midnight of the service day
+func formatServiceDayTime(timestamp time.Time, dayOffset int) any {
+	if dayOffset == 0 {
+		return formatTimeOfDay(timestamp)
+	}
+
+	return fmt.Sprintf("%02d:%02d:%02d", dayOffset*24+timestamp.Hour(), timestamp.Minute(), timestamp.Second())
+}
+
+func hasActivity(activities []ctdf.JourneyPathItemActivity, activity string) bool {
+	for _, a := range activities {
+		if string(a) == activity {
+			return true
+		}
+	}
+
+	return false
+}
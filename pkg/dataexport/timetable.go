@@ -0,0 +1,288 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Timetable is the stops × journeys matrix of a service for a single day type or date, split by direction
+type Timetable struct {
+	Service  *ctdf.Service
+	Operator *ctdf.Operator
+
+	// Either the day of the week (eg. Monday) the journeys could run on or a specific date they do run on
+	DayType string
+	Date    time.Time
+
+	Directions []*TimetableDirection
+}
+
+// TimetableDirection is one matrix of the timetable, rows are stops & columns are journeys in departure order
+type TimetableDirection struct {
+	Direction string
+
+	Stops    []*TimetableStop
+	Journeys []*ctdf.Journey
+}
+
+type TimetableStop struct {
+	StopRef string
+	Name    string
+
+	// Time the journey in the same column calls at the stop, empty when it doesn't
+	Times []string
+}
+
+// GetDescription is a human readable title for what the timetable covers
+func (t *Timetable) GetDescription() string {
+	description := t.Service.ServiceName
+	if t.Operator != nil && t.Operator.PrimaryName != "" {
+		description = fmt.Sprintf("%s (%s)", description, t.Operator.PrimaryName)
+	}
+
+	if !t.Date.IsZero() {
+		return fmt.Sprintf("%s on %s", description, t.Date.Format("Monday 2 January 2006"))
+	}
+
+	return fmt.Sprintf("%s on %ss", description, t.DayType)
+}
+
+// BuildTimetable puts together the timetable of a service on a day of the week or, if date is set, a specific date
+func BuildTimetable(serviceRef string, dayType string, date time.Time) (*Timetable, error) {
+	if date.IsZero() && !slices.Contains(daysOfWeek, dayType) {
+		return nil, errors.New(fmt.Sprintf("Day type %s should be a day of the week (eg. Monday)", dayType))
+	}
+
+	var service *ctdf.Service
+	err := database.GetCollection("services").FindOne(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"primaryidentifier": serviceRef},
+		bson.M{"otheridentifiers": serviceRef},
+	}}).Decode(&service)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not find service %s", serviceRef))
+	}
+
+	timetable := &Timetable{
+		Service: service,
+		DayType: dayType,
+		Date:    date,
+	}
+	if !date.IsZero() {
+		timetable.DayType = date.Weekday().String()
+	}
+
+	database.GetCollection("operators").FindOne(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"primaryidentifier": service.OperatorRef},
+		bson.M{"otheridentifiers": service.OperatorRef},
+	}}).Decode(&timetable.Operator)
+
+	cursor, err := database.GetCollection("journeys").Find(context.Background(), bson.M{"serviceref": service.PrimaryIdentifier})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	directionJourneys := map[string][]*ctdf.Journey{}
+	for cursor.Next(context.Background()) {
+		var journey *ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		if len(journey.Path) == 0 || journey.Availability == nil {
+			continue
+		}
+
+		if !date.IsZero() && !journey.RunsOn(date) {
+			continue
+		}
+		if date.IsZero() && !slices.Contains(journey.Availability.PossibleDaysOfWeek(), dayType) {
+			continue
+		}
+
+		directionJourneys[journey.Direction] = append(directionJourneys[journey.Direction], journey)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	var directions []string
+	for direction := range directionJourneys {
+		directions = append(directions, direction)
+	}
+	sort.Slice(directions, func(i, j int) bool {
+		return getDirectionOrder(directions[i]) < getDirectionOrder(directions[j]) ||
+			(getDirectionOrder(directions[i]) == getDirectionOrder(directions[j]) && directions[i] < directions[j])
+	})
+
+	for _, direction := range directions {
+		timetable.Directions = append(timetable.Directions, buildTimetableDirection(direction, ctdf.FilterIdenticalJourneys(directionJourneys[direction], false)))
+	}
+
+	timetable.nameStops()
+
+	return timetable, nil
+}
+
+var daysOfWeek = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// Outbound is shown before inbound as thats the order printed timetables usually use
+func getDirectionOrder(direction string) int {
+	switch strings.ToLower(direction) {
+	case "outbound":
+		return 0
+	case "inbound":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func buildTimetableDirection(direction string, journeys []*ctdf.Journey) *TimetableDirection {
+	sort.SliceStable(journeys, func(i, j int) bool {
+		return getMinuteOfDay(journeys[i].Path[0].OriginDepartureTime, journeys[i].Path[0].OriginDepartureDayOffset) <
+			getMinuteOfDay(journeys[j].Path[0].OriginDepartureTime, journeys[j].Path[0].OriginDepartureDayOffset)
+	})
+
+	// Start from the longest stopping pattern so short workings & variants slot in to it
+	byLength := slices.Clone(journeys)
+	sort.SliceStable(byLength, func(i, j int) bool {
+		return len(byLength[i].Path) > len(byLength[j].Path)
+	})
+
+	var stopOrder []string
+	for _, journey := range byLength {
+		stopOrder = mergeStopSequence(stopOrder, getJourneyStopRefs(journey))
+	}
+
+	timetableDirection := &TimetableDirection{
+		Direction: direction,
+		Journeys:  journeys,
+	}
+	for _, stopRef := range stopOrder {
+		timetableDirection.Stops = append(timetableDirection.Stops, &TimetableStop{
+			StopRef: stopRef,
+			Times:   make([]string, len(journeys)),
+		})
+	}
+
+	for column, journey := range journeys {
+		position := -1
+		for i, stopRef := range getJourneyStopRefs(journey) {
+			position = indexAfter(stopOrder, stopRef, position)
+
+			// The last stop only has an arrival time, every other one is shown with when it leaves
+			if i < len(journey.Path) {
+				timetableDirection.Stops[position].Times[column] = journey.Path[i].OriginDepartureTime.Format("15:04")
+			} else {
+				timetableDirection.Stops[position].Times[column] = journey.Path[i-1].DestinationArrivalTime.Format("15:04")
+			}
+		}
+	}
+
+	return timetableDirection
+}
+
+func getJourneyStopRefs(journey *ctdf.Journey) []string {
+	var stopRefs []string
+	for _, pathItem := range journey.Path {
+		stopRefs = append(stopRefs, pathItem.OriginStopRef)
+	}
+
+	return append(stopRefs, journey.Path[len(journey.Path)-1].DestinationStopRef)
+}
+
+// mergeStopSequence adds the stops of sequence to the order that aren't there already, each one just after the
+// stop that comes before it. Stops are only matched going forwards so loops get a second row
+func mergeStopSequence(order []string, sequence []string) []string {
+	position := -1
+
+	for _, stopRef := range sequence {
+		index := indexAfter(order, stopRef, position)
+		if index == -1 {
+			index = position + 1
+			order = slices.Insert(order, index, stopRef)
+		}
+
+		position = index
+	}
+
+	return order
+}
+
+func indexAfter(order []string, stopRef string, position int) int {
+	for i := position + 1; i < len(order); i++ {
+		if order[i] == stopRef {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func getMinuteOfDay(timeOfDay time.Time, dayOffset int) int {
+	return dayOffset*24*60 + timeOfDay.Hour()*60 + timeOfDay.Minute()
+}
+
+// nameStops looks up the names of every stop on the timetable, using the services own name for a stop where it has one
+func (t *Timetable) nameStops() {
+	stopNames := map[string]string{}
+	for _, direction := range t.Directions {
+		for _, stop := range direction.Stops {
+			stopNames[stop.StopRef] = stop.StopRef
+		}
+	}
+
+	var stopRefs []string
+	for stopRef := range stopNames {
+		stopRefs = append(stopRefs, stopRef)
+	}
+
+	cursor, err := database.GetCollection("stops").Find(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"primaryidentifier": bson.M{"$in": stopRefs}},
+		bson.M{"otheridentifiers": bson.M{"$in": stopRefs}},
+	}})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up timetable stops")
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var stop *ctdf.Stop
+		if err := cursor.Decode(&stop); err != nil {
+			continue
+		}
+
+		stop.UpdateNameFromServiceOverrides(t.Service)
+
+		name := stop.PrimaryName
+		if stop.Descriptor != "" && stop.Descriptor != stop.PrimaryName {
+			name = fmt.Sprintf("%s (%s)", stop.PrimaryName, stop.Descriptor)
+		}
+
+		for _, identifier := range stop.GetAllStopIDs() {
+			if _, exists := stopNames[identifier]; exists {
+				stopNames[identifier] = name
+			}
+		}
+	}
+
+	for _, direction := range t.Directions {
+		for _, stop := range direction.Stops {
+			stop.Name = stopNames[stop.StopRef]
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package dataexport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type TimetableFormat string
+
+const (
+	TimetableFormatText TimetableFormat = "text"
+	TimetableFormatCSV                  = "csv"
+	TimetableFormatHTML                 = "html"
+)
+
+// Render writes the timetable out in the given format
+func (t *Timetable) Render(output io.Writer, format TimetableFormat) error {
+	switch format {
+	case TimetableFormatText:
+		return t.renderText(output)
+	case TimetableFormatCSV:
+		return t.renderCSV(output)
+	case TimetableFormatHTML:
+		return t.renderHTML(output)
+	default:
+		return errors.New(fmt.Sprintf("Unsupported timetable format %s", format))
+	}
+}
+
+func (t *Timetable) renderText(output io.Writer) error {
+	fmt.Fprintln(output, t.GetDescription())
+
+	if len(t.Directions) == 0 {
+		fmt.Fprintln(output, "No journeys")
+		return nil
+	}
+
+	for _, direction := range t.Directions {
+		fmt.Fprintln(output)
+		if direction.Direction != "" {
+			fmt.Fprintln(output, strings.ToUpper(direction.Direction[:1])+direction.Direction[1:])
+		}
+
+		writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		for _, stop := range direction.Stops {
+			times := make([]string, len(stop.Times))
+			for i, stopTime := range stop.Times {
+				times[i] = stopTime
+				if times[i] == "" {
+					times[i] = "  -  "
+				}
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\n", stop.Name, strings.Join(times, "\t"))
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderCSV writes a block per direction, each starting with a header row of the journey identifiers
+func (t *Timetable) renderCSV(output io.Writer) error {
+	writer := csv.NewWriter(output)
+
+	for _, direction := range t.Directions {
+		header := []string{"direction", "stop_ref", "stop_name"}
+		for _, journey := range direction.Journeys {
+			header = append(header, journey.PrimaryIdentifier)
+		}
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+
+		for _, stop := range direction.Stops {
+			if err := writer.Write(append([]string{direction.Direction, stop.StopRef, stop.Name}, stop.Times...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+var timetableHTMLTemplate = template.Must(template.New("timetable").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .GetDescription }}</title>
+<style>
+table { border-collapse: collapse; font-family: sans-serif; font-size: 0.9em; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; white-space: nowrap; }
+td.time { text-align: center; font-variant-numeric: tabular-nums; }
+tr:nth-child(even) { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>{{ .GetDescription }}</h1>
+{{- range .Directions }}
+{{- if .Direction }}
+<h2>{{ .Direction }}</h2>
+{{- end }}
+<table>
+{{- range .Stops }}
+<tr><th scope="row">{{ .Name }}</th>{{ range .Times }}<td class="time">{{ if . }}{{ . }}{{ else }}&ndash;{{ end }}</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No journeys</p>
+{{- end }}
+</body>
+</html>
+`))
+
+func (t *Timetable) renderHTML(output io.Writer) error {
+	return timetableHTMLTemplate.Execute(output, t)
+}
@@ -0,0 +1,186 @@
+package dataexport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet        = "parquet"
+)
+
+type rowWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+func newRowWriter(output io.Writer, format Format, columns []Column) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(output, columns)
+	case FormatParquet:
+		return newParquetWriter(output, columns), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported export format %s", format))
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(output io.Writer, columns []Column) (*csvWriter, error) {
+	writer := csv.NewWriter(output)
+
+	var header []string
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{writer: writer}, nil
+}
+
+func (w *csvWriter) Write(row []any) error {
+	record := make([]string, len(row))
+
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+			record[i] = ""
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+// parquetWriter writes every column as optional so missing values come through as nulls rather than zero values
+type parquetWriter struct {
+	writer *parquet.Writer
+
+	// columnIndexes maps the selected column position to its leaf index in the schema, which orders fields by name
+	columnIndexes []int
+	buffer        []parquet.Row
+}
+
+const parquetRowBufferSize = 1000
+
+func newParquetWriter(output io.Writer, columns []Column) *parquetWriter {
+	group := parquet.Group{}
+	for _, column := range columns {
+		var node parquet.Node
+
+		switch column.Type {
+		case ColumnString:
+			node = parquet.String()
+		case ColumnInt:
+			node = parquet.Int(64)
+		case ColumnFloat:
+			node = parquet.Leaf(parquet.DoubleType)
+		case ColumnBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		case ColumnTimestamp:
+			node = parquet.Timestamp(parquet.Millisecond)
+		}
+
+		group[column.Name] = parquet.Optional(node)
+	}
+
+	schema := parquet.NewSchema("travigo", group)
+
+	schemaIndexes := map[string]int{}
+	for i, path := range schema.Columns() {
+		schemaIndexes[path[0]] = i
+	}
+
+	columnIndexes := make([]int, len(columns))
+	for i, column := range columns {
+		columnIndexes[i] = schemaIndexes[column.Name]
+	}
+
+	return &parquetWriter{
+		writer:        parquet.NewWriter(output, schema, parquet.Compression(&parquet.Snappy)),
+		columnIndexes: columnIndexes,
+	}
+}
+
+func (w *parquetWriter) Write(row []any) error {
+	parquetRow := make(parquet.Row, len(row))
+
+	for i, value := range row {
+		columnIndex := w.columnIndexes[i]
+
+		var parquetValue parquet.Value
+		switch v := value.(type) {
+		case nil:
+			parquetRow[columnIndex] = parquet.NullValue().Level(0, 0, columnIndex)
+			continue
+		case string:
+			parquetValue = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			parquetValue = parquet.Int64Value(v)
+		case float64:
+			parquetValue = parquet.DoubleValue(v)
+		case bool:
+			parquetValue = parquet.BooleanValue(v)
+		case time.Time:
+			parquetValue = parquet.Int64Value(v.UnixMilli())
+		}
+
+		parquetRow[columnIndex] = parquetValue.Level(0, 1, columnIndex)
+	}
+
+	w.buffer = append(w.buffer, parquetRow)
+	if len(w.buffer) >= parquetRowBufferSize {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *parquetWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	_, err := w.writer.WriteRows(w.buffer)
+	w.buffer = nil
+
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	return w.writer.Close()
+}
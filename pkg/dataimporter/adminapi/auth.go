@@ -0,0 +1,33 @@
+package adminapi
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EnsureValidToken is a middleware that only lets through requests carrying the configured admin bearer token
+func EnsureValidToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+
+		if authHeader == "" {
+			c.SendStatus(fiber.StatusUnauthorized)
+			return c.JSON(fiber.Map{
+				"error": "Authorization header is required",
+			})
+		}
+
+		requestToken := strings.TrimPrefix(authHeader, "Bearer ")
+
+		if subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) != 1 {
+			c.SendStatus(fiber.StatusUnauthorized)
+			return c.JSON(fiber.Map{
+				"error": "Invalid auth token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/dataimporter/customdatasets"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+)
+
+// customDatasetRegistration is the request body for registering a custom dataset, the refresh interval is a duration like 12h
+type customDatasetRegistration struct {
+	Name    string
+	Website string
+	Country string
+
+	Source       string
+	APIKey       string
+	APIKeyHeader string
+	APIKeyQuery  string
+
+	RefreshInterval string
+}
+
+func CustomDatasetsRouter(router fiber.Router) {
+	router.Get("/", listCustomDatasets)
+	router.Post("/", registerCustomDataset)
+	router.Get("/:identifier", getCustomDataset)
+	router.Post("/:identifier/validate", validateCustomDataset)
+	router.Delete("/:identifier", removeCustomDataset)
+}
+
+func listCustomDatasets(c *fiber.Ctx) error {
+	customDatasets, err := customdatasets.GetCustomDataSets()
+	if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(customDatasets)
+}
+
+func registerCustomDataset(c *fiber.Ctx) error {
+	var request customDatasetRegistration
+	if err := c.BodyParser(&request); err != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var refreshInterval time.Duration
+	if request.RefreshInterval != "" {
+		var err error
+		refreshInterval, err = time.ParseDuration(request.RefreshInterval)
+		if err != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	country := request.Country
+	if country == "" {
+		country = "gb"
+	}
+
+	customDataset, err := manager.RegisterCustomDataSet(customdatasets.Registration{
+		Name:    request.Name,
+		Website: request.Website,
+		Country: country,
+
+		Source:       request.Source,
+		APIKey:       request.APIKey,
+		APIKeyHeader: request.APIKeyHeader,
+		APIKeyQuery:  request.APIKeyQuery,
+
+		RefreshInterval: refreshInterval,
+	})
+	if err != nil && customDataset == nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error":   err.Error(),
+			"dataset": customDataset,
+		})
+	}
+
+	c.Status(fiber.StatusCreated)
+	return c.JSON(customDataset)
+}
+
+func getCustomDataset(c *fiber.Ctx) error {
+	customDataset := customdatasets.GetCustomDataSet(c.Params("identifier"))
+	if customDataset == nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Custom dataset could not be found",
+		})
+	}
+
+	return c.JSON(customDataset)
+}
+
+func validateCustomDataset(c *fiber.Ctx) error {
+	customDataset := customdatasets.GetCustomDataSet(c.Params("identifier"))
+	if customDataset == nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Custom dataset could not be found",
+		})
+	}
+
+	if _, err := manager.ValidateCustomDataSet(customDataset); err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(customDataset)
+}
+
+func removeCustomDataset(c *fiber.Ctx) error {
+	if err := manager.RemoveCustomDataSet(c.Params("identifier")); err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/gtfs"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/importqueue"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/dataimporter/runhistory"
+)
+
+func DatasetsRouter(router fiber.Router) {
+	router.Get("/", listDatasets)
+	router.Get("/:identifier", getDataset)
+	router.Post("/:identifier/import", triggerImport)
+	router.Get("/:identifier/runs", listDatasetRuns)
+	router.Get("/:identifier/runs/diff", diffDatasetRuns)
+	router.Get("/:identifier/agencies", listDatasetAgencies)
+	router.Get("/:identifier/override", getDatasetOverride)
+	router.Put("/:identifier/override", setDatasetOverride)
+	router.Delete("/:identifier/override", deleteDatasetOverride)
+}
+
+func listDatasets(c *fiber.Ctx) error {
+	return c.JSON(manager.GetRegisteredDataSets())
+}
+
+func getDataset(c *fiber.Ctx) error {
+	dataset, err := manager.GetDataset(c.Params("identifier"))
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	override := manager.GetDataSetOverride(dataset.Identifier)
+	active, inactiveReason := manager.IsDataSetActive(&dataset, override, time.Now())
+
+	return c.JSON(fiber.Map{
+		"dataset":        dataset,
+		"override":       override,
+		"active":         active,
+		"inactivereason": inactiveReason,
+		"import":         getDatasetImport(dataset.Identifier),
+		"lastresult":     importerrors.GetLatestResult(dataset.Identifier),
+	})
+}
+
+func triggerImport(c *fiber.Ctx) error {
+	dataset, err := manager.GetDataset(c.Params("identifier"))
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Queued imports are run by whichever import worker picks them up rather than this server
+	if c.QueryBool("queue") {
+		job := importqueue.NewImportJob(dataset.Identifier)
+		job.Force = c.QueryBool("force")
+		job.Staged = c.QueryBool("staged")
+
+		if err := importqueue.Publish(job); err != nil {
+			c.SendStatus(fiber.StatusInternalServerError)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Status(fiber.StatusAccepted)
+		return c.JSON(job)
+	}
+
+	if c.QueryBool("staged") {
+		dataset.StagedImport = true
+	}
+
+	runningImport, err := startImport(dataset, c.QueryBool("force"))
+	if err != nil {
+		c.SendStatus(fiber.StatusConflict)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Status(fiber.StatusAccepted)
+	return c.JSON(runningImport)
+}
+
+func listDatasetRuns(c *fiber.Ctx) error {
+	runs, err := runhistory.GetRuns(c.Params("identifier"))
+	if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(runs)
+}
+
+func diffDatasetRuns(c *fiber.Ctx) error {
+	from := c.Query("from")
+	to := c.Query("to")
+
+	if from == "" || to == "" {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "Query parameters from & to are required",
+		})
+	}
+
+	report, err := runhistory.Diff(c.Params("identifier"), from, to)
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+func listDatasetAgencies(c *fiber.Ctx) error {
+	statistics, err := gtfs.GetAgencyStatistics(c.Params("identifier"))
+	if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(statistics)
+}
+
+func getDatasetOverride(c *fiber.Ctx) error {
+	override := manager.GetDataSetOverride(c.Params("identifier"))
+	if override == nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Dataset has no override",
+		})
+	}
+
+	return c.JSON(override)
+}
+
+func setDatasetOverride(c *fiber.Ctx) error {
+	dataset, err := manager.GetDataset(c.Params("identifier"))
+	if err != nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var override manager.DataSetOverride
+	if err := c.BodyParser(&override); err != nil {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	override.Dataset = dataset.Identifier
+
+	if err := manager.SetDataSetOverride(&override); err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(override)
+}
+
+func deleteDatasetOverride(c *fiber.Ctx) error {
+	if err := manager.DeleteDataSetOverride(c.Params("identifier")); err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
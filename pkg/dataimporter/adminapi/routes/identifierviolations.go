@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+)
+
+// IdentifierViolations reports the malformed primary identifiers seen per collection since the server started
+func IdentifierViolations(c *fiber.Ctx) error {
+	return c.JSON(datasink.GetIdentifierViolations())
+}
@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importqueue"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+)
+
+// How long finished imports are kept around so their result can still be looked up
+const finishedImportRetention = 24 * time.Hour
+
+type ImportStatus string
+
+const (
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusSucceeded              = "succeeded"
+	ImportStatusFailed                 = "failed"
+	ImportStatusCancelled              = "cancelled"
+)
+
+type Import struct {
+	ID      string
+	Dataset string
+
+	Status ImportStatus
+	Error  string `json:",omitempty"`
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	Progress *progress.Update `json:",omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// importProgressReporter keeps the latest progress update against the import so it can be polled
+type importProgressReporter struct {
+	runningImport *Import
+}
+
+func (r importProgressReporter) Report(update progress.Update) {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	r.runningImport.Progress = &update
+}
+
+func (r importProgressReporter) Finish(update progress.Update) {
+	r.Report(update)
+}
+
+var importsMutex sync.Mutex
+var imports = map[string]*Import{}
+
+func ImportsRouter(router fiber.Router) {
+	router.Get("/", listImports)
+	router.Get("/jobs/:runid", getImportJob)
+	router.Get("/:id", getImport)
+	router.Delete("/:id", cancelImport)
+}
+
+func listImports(c *fiber.Ctx) error {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	importsList := []Import{}
+	for _, runningImport := range imports {
+		importsList = append(importsList, *runningImport)
+	}
+
+	sort.Slice(importsList, func(i, j int) bool {
+		return importsList[i].StartTime.After(importsList[j].StartTime)
+	})
+
+	return c.JSON(importsList)
+}
+
+func getImportJob(c *fiber.Ctx) error {
+	job, err := importqueue.GetJob(c.Params("runid"))
+	if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if job == nil {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Import job could not be found",
+		})
+	}
+
+	return c.JSON(job)
+}
+
+func getImport(c *fiber.Ctx) error {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	runningImport, exists := imports[c.Params("id")]
+	if !exists {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Import could not be found",
+		})
+	}
+
+	return c.JSON(*runningImport)
+}
+
+func cancelImport(c *fiber.Ctx) error {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	runningImport, exists := imports[c.Params("id")]
+	if !exists {
+		c.SendStatus(fiber.StatusNotFound)
+		return c.JSON(fiber.Map{
+			"error": "Import could not be found",
+		})
+	}
+
+	if runningImport.Status != ImportStatusRunning {
+		c.SendStatus(fiber.StatusConflict)
+		return c.JSON(fiber.Map{
+			"error": fmt.Sprintf("Import is already %s", runningImport.Status),
+		})
+	}
+
+	runningImport.cancel()
+
+	log.Info().Str("id", runningImport.ID).Str("dataset", runningImport.Dataset).Msg("Cancelling import")
+
+	c.Status(fiber.StatusAccepted)
+	return c.JSON(*runningImport)
+}
+
+// getDatasetImport returns the most recent import of a dataset, if there has been one
+func getDatasetImport(datasetID string) *Import {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	var latest *Import
+	for _, runningImport := range imports {
+		if runningImport.Dataset == datasetID && (latest == nil || runningImport.StartTime.After(latest.StartTime)) {
+			importCopy := *runningImport
+			latest = &importCopy
+		}
+	}
+
+	return latest
+}
+
+func startImport(dataset datasets.DataSet, forceImport bool) (Import, error) {
+	importsMutex.Lock()
+	defer importsMutex.Unlock()
+
+	pruneFinishedImports()
+
+	for _, runningImport := range imports {
+		if runningImport.Dataset == dataset.Identifier && runningImport.Status == ImportStatusRunning {
+			return Import{}, errors.New(fmt.Sprintf("Dataset %s is already being imported by %s", dataset.Identifier, runningImport.ID))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dataset.Context = ctx
+
+	startTime := time.Now()
+	runningImport := &Import{
+		ID:        fmt.Sprintf("%s-%d", dataset.Identifier, startTime.Unix()),
+		Dataset:   dataset.Identifier,
+		Status:    ImportStatusRunning,
+		StartTime: startTime,
+		cancel:    cancel,
+	}
+	imports[runningImport.ID] = runningImport
+
+	dataset.Progress = progress.NewTracker(dataset.Identifier, importProgressReporter{runningImport: runningImport})
+
+	go func() {
+		defer cancel()
+
+		log.Info().Str("id", runningImport.ID).Str("dataset", dataset.Identifier).Msg("Starting import")
+
+		err := manager.ImportDataset(&dataset, forceImport)
+
+		importsMutex.Lock()
+		defer importsMutex.Unlock()
+
+		runningImport.EndTime = time.Now()
+
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			runningImport.Status = ImportStatusCancelled
+		} else if err != nil {
+			runningImport.Status = ImportStatusFailed
+			runningImport.Error = err.Error()
+
+			log.Error().Err(err).Str("id", runningImport.ID).Str("dataset", dataset.Identifier).Msg("Failed to import dataset")
+		} else {
+			runningImport.Status = ImportStatusSucceeded
+		}
+
+		log.Info().Str("id", runningImport.ID).Str("status", string(runningImport.Status)).Msgf("Import took %s", runningImport.EndTime.Sub(startTime).String())
+	}()
+
+	return *runningImport, nil
+}
+
+func pruneFinishedImports() {
+	for id, runningImport := range imports {
+		if runningImport.Status != ImportStatusRunning && time.Since(runningImport.EndTime) > finishedImportRetention {
+			delete(imports, id)
+		}
+	}
+}
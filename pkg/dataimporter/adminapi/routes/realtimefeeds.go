@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
+)
+
+// RealtimeFeeds reports the message rates, parse error rates & last received time of each realtime source
+func RealtimeFeeds(c *fiber.Ctx) error {
+	feeds, err := feedhealth.GetFeeds()
+	if err != nil {
+		c.SendStatus(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(feeds)
+}
@@ -0,0 +1,23 @@
+package adminapi
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/dataimporter/adminapi/routes"
+	"github.com/travigo/travigo/pkg/http_server"
+)
+
+func SetupServer(listen string, token string) error {
+	webApp := fiber.New()
+	webApp.Use(http_server.NewLogger())
+
+	group := webApp.Group("/admin", EnsureValidToken(token))
+
+	routes.DatasetsRouter(group.Group("/datasets"))
+	routes.ImportsRouter(group.Group("/imports"))
+	routes.CustomDatasetsRouter(group.Group("/custom_datasets"))
+
+	group.Get("/identifier_violations", routes.IdentifierViolations)
+	group.Get("/realtime_feeds", routes.RealtimeFeeds)
+
+	return webApp.Listen(listen)
+}
@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store is somewhere the raw payloads of dataset downloads can be kept
+type Store interface {
+	Put(ctx context.Context, key string, reader io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+type DatasetArchive struct {
+	Dataset string
+	Run     string
+
+	Key  string
+	Hash string
+	Size int64
+
+	CreationDateTime time.Time
+}
+
+// GetStore returns the configured archive store, or nil if archiving hasn't been enabled
+func GetStore() (Store, error) {
+	env := util.GetEnvironmentVariables()
+
+	if env["TRAVIGO_DATAIMPORTER_ARCHIVE_URL"] == "" {
+		return nil, nil
+	}
+
+	return NewStore(env["TRAVIGO_DATAIMPORTER_ARCHIVE_URL"])
+}
+
+// NewStore creates the store for an archive URL, either gs://bucket/prefix, s3://bucket/prefix or file:///directory
+func NewStore(rawURL string) (Store, error) {
+	archiveURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch archiveURL.Scheme {
+	case "gs":
+		return NewGCSStore(archiveURL.Host, strings.TrimPrefix(archiveURL.Path, "/"))
+	case "s3":
+		return NewS3Store(archiveURL.Host, strings.TrimPrefix(archiveURL.Path, "/"))
+	case "file":
+		return FileStore{Directory: archiveURL.Path}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported archive store %s", archiveURL.Scheme))
+	}
+}
+
+// Archive stores the payload of a dataset run and records it so the run can be re-imported later
+func Archive(store Store, dataset string, run string, hash string, size int64, reader io.Reader) error {
+	key := fmt.Sprintf("%s/%s", dataset, run)
+
+	if err := store.Put(context.Background(), key, reader); err != nil {
+		return err
+	}
+
+	_, err := database.GetCollection("dataset_archives").InsertOne(context.Background(), DatasetArchive{
+		Dataset:          dataset,
+		Run:              run,
+		Key:              key,
+		Hash:             hash,
+		Size:             size,
+		CreationDateTime: time.Now(),
+	})
+
+	return err
+}
+
+// GetArchive finds the archived payload for a specific run of a dataset
+func GetArchive(dataset string, run string) (*DatasetArchive, error) {
+	var datasetArchive *DatasetArchive
+
+	err := database.GetCollection("dataset_archives").FindOne(context.Background(), bson.M{"dataset": dataset, "run": run}).Decode(&datasetArchive)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("No archive recorded for run %s of dataset %s", run, dataset))
+	}
+
+	return datasetArchive, nil
+}
@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore keeps archives in a local directory, mostly useful for development
+type FileStore struct {
+	Directory string
+}
+
+func (s FileStore) Put(ctx context.Context, key string, reader io.Reader) error {
+	filePath := filepath.Join(s.Directory, key)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, reader)
+
+	return err
+}
+
+func (s FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.Directory, key))
+}
+
+func (s FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(s.Directory, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		key, err := filepath.Rel(s.Directory, filePath)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
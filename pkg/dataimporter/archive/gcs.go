@@ -0,0 +1,66 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore keeps archives in a Google Cloud Storage bucket, authenticating with the default application credentials
+type GCSStore struct {
+	Bucket string
+	Prefix string
+
+	client *storage.Client
+}
+
+func NewGCSStore(bucket string, prefix string) (*GCSStore, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCSStore{
+		Bucket: bucket,
+		Prefix: prefix,
+		client: client,
+	}, nil
+}
+
+func (s *GCSStore) Put(ctx context.Context, key string, reader io.Reader) error {
+	writer := s.client.Bucket(s.Bucket).Object(path.Join(s.Prefix, key)).NewWriter(ctx)
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.client.Bucket(s.Bucket).Object(path.Join(s.Prefix, key)).NewReader(ctx)
+}
+
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	objects := s.client.Bucket(s.Bucket).Objects(ctx, &storage.Query{Prefix: path.Join(s.Prefix, prefix)})
+	for {
+		object, err := objects.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, strings.TrimPrefix(strings.TrimPrefix(object.Name, s.Prefix), "/"))
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Store keeps archives in an S3 bucket, authenticating with the default AWS credential chain.
+// S3 compatible stores can be used by setting AWS_ENDPOINT_URL_S3
+type S3Store struct {
+	Bucket string
+	Prefix string
+
+	client *s3.Client
+}
+
+func NewS3Store(bucket string, prefix string) (*S3Store, error) {
+	awsConfig, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{
+		Bucket: bucket,
+		Prefix: prefix,
+		client: s3.NewFromConfig(awsConfig),
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, reader io.Reader) error {
+	// The upload has to be signed with its length so anything that can't be seeked is copied to disk first
+	body, ok := reader.(io.ReadSeeker)
+	if !ok {
+		tmpFile, err := os.CreateTemp(os.TempDir(), "travigo-archive-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+
+		if _, err := io.Copy(tmpFile, reader); err != nil {
+			return err
+		}
+		if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		body = tmpFile
+	}
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, key)),
+		Body:   body,
+	})
+
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	objects := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(path.Join(s.Prefix, prefix)),
+	})
+	for objects.HasMorePages() {
+		page, err := objects.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(strings.TrimPrefix(aws.ToString(object.Key), s.Prefix), "/"))
+		}
+	}
+
+	return keys, nil
+}
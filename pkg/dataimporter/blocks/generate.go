@@ -0,0 +1,108 @@
+package blocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+// Generate rebuilds the blocks from the journeys in the datasources dataset that have a BlockRef
+func Generate(datasource *ctdf.DataSourceReference, operatorRefs []string) error {
+	journeysCollection := database.GetCollection("journeys")
+	blocksCollection := database.GetCollection("blocks")
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "otheridentifiers.BlockNumber", Value: 1},
+		bson.E{Key: "operatorref", Value: 1},
+		bson.E{Key: "blockref", Value: 1},
+		bson.E{Key: "departuretime", Value: 1},
+		bson.E{Key: "path.origindeparturedayoffset", Value: 1},
+		bson.E{Key: "availability", Value: 1},
+	})
+	filter := bson.M{
+		"datasource.datasetid": datasource.DatasetID,
+		"blockref":             bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(operatorRefs) > 0 {
+		filter["operatorref"] = bson.M{"$in": operatorRefs}
+	}
+	cursor, err := journeysCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	blocks := map[string]*ctdf.Block{}
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		err := cursor.Decode(&journey)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		block, exists := blocks[journey.BlockRef]
+		if !exists {
+			block = &ctdf.Block{
+				PrimaryIdentifier:    journey.BlockRef,
+				BlockNumber:          journey.OtherIdentifiers["BlockNumber"],
+				OperatorRef:          journey.OperatorRef,
+				CreationDateTime:     now,
+				ModificationDateTime: now,
+				DataSource:           datasource,
+			}
+			blocks[journey.BlockRef] = block
+		}
+
+		blockJourney := &ctdf.BlockJourney{
+			JourneyRef:    journey.PrimaryIdentifier,
+			DepartureTime: journey.DepartureTime,
+			Availability:  journey.Availability,
+		}
+		if len(journey.Path) > 0 {
+			blockJourney.DepartureDayOffset = journey.Path[0].OriginDepartureDayOffset
+		}
+		block.Journeys = append(block.Journeys, blockJourney)
+	}
+
+	var operations []mongo.WriteModel
+	for _, block := range blocks {
+		sort.SliceStable(block.Journeys, func(i, j int) bool {
+			return block.Journeys[i].Before(block.Journeys[j])
+		})
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": block.PrimaryIdentifier}).
+			SetUpdate(bson.M{"$set": block}).
+			SetUpsert(true),
+		)
+
+		if len(operations) >= writeBatchSize {
+			if _, err := blocksCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := blocksCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("dataset", datasource.DatasetID).Int("blocks", len(blocks)).Msg("Generated blocks")
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package busregistrations
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+type serviceRecord struct {
+	PrimaryIdentifier string
+	OtherIdentifiers  []string
+	ServiceName       string
+	OperatorRef       string
+	TransportType     ctdf.TransportType
+}
+
+type operatorRecord struct {
+	PrimaryIdentifier string
+	OtherIdentifiers  []string
+	Licence           string
+}
+
+type registrationRecord struct {
+	PrimaryIdentifier string
+	LicenceNumber     string
+	ServiceNumbers    []string
+	OperatorRef       string
+	ServiceRefs       []string
+	ServiceLinkMethod ctdf.BusRegistrationServiceLinkMethod
+}
+
+// servicesIndex is the bus & coach services looked up by the ways a registration can be matched to them
+type servicesIndex struct {
+	byRegistration map[string][]*serviceRecord
+	byOperator     map[string][]*serviceRecord
+	all            []*serviceRecord
+}
+
+// Link sets the services & operator of every bus registration. Services with the registration number as their
+// service code are trusted first, otherwise the services of the operators holding the licence with a matching
+// route number are taken to be the registered ones.
+func Link() error {
+	operatorsByLicence, err := getOperatorsByLicence()
+	if err != nil {
+		return err
+	}
+
+	registrations, err := getRegistrations(bson.M{})
+	if err != nil {
+		return err
+	}
+
+	services, err := getServices(bson.M{})
+	if err != nil {
+		return err
+	}
+
+	return link(registrations, operatorsByLicence, services)
+}
+
+// LinkDataset only relinks the bus registrations that the services of a dataset could be linked to, by either
+// their registration code or the licences of their operators. Registrations left without any services by a
+// dataset dropping an operator entirely are only picked up by the next full Link.
+func LinkDataset(datasetID string) error {
+	operatorsByLicence, err := getOperatorsByLicence()
+	if err != nil {
+		return err
+	}
+
+	datasetServices, err := getServices(bson.M{"datasource.datasetid": datasetID})
+	if err != nil {
+		return err
+	}
+	if len(datasetServices.all) == 0 {
+		return nil
+	}
+
+	// Never nil as $in doesn't accept a null list
+	registrationRefs := []string{}
+	for registrationRef := range datasetServices.byRegistration {
+		registrationRefs = append(registrationRefs, registrationRef)
+	}
+
+	licences := []string{}
+	for licence, operators := range operatorsByLicence {
+		for _, operator := range operators {
+			if len(datasetServices.byOperator[operator.PrimaryIdentifier]) > 0 || slices.ContainsFunc(operator.OtherIdentifiers, func(identifier string) bool {
+				return len(datasetServices.byOperator[identifier]) > 0
+			}) {
+				licences = append(licences, licence)
+				break
+			}
+		}
+	}
+
+	registrations, err := getRegistrations(bson.M{"$or": bson.A{
+		bson.M{"primaryidentifier": bson.M{"$in": registrationRefs}},
+		bson.M{"licencenumber": bson.M{"$in": licences}},
+	}})
+	if err != nil {
+		return err
+	}
+	if len(registrations) == 0 {
+		return nil
+	}
+
+	// The registrations can also be run by services of other datasets so every service they could link to is loaded
+	operatorRefs := []string{}
+	registrationRefs = []string{}
+	for _, registration := range registrations {
+		registrationRefs = append(registrationRefs, registration.PrimaryIdentifier)
+
+		for _, operator := range operatorsByLicence[registration.LicenceNumber] {
+			operatorRefs = append(operatorRefs, operator.PrimaryIdentifier)
+			operatorRefs = append(operatorRefs, operator.OtherIdentifiers...)
+		}
+	}
+
+	services, err := getServices(bson.M{"$or": bson.A{
+		bson.M{"otheridentifiers": bson.M{"$in": registrationRefs}},
+		bson.M{"operatorref": bson.M{"$in": operatorRefs}},
+	}})
+	if err != nil {
+		return err
+	}
+
+	return link(registrations, operatorsByLicence, services)
+}
+
+func link(registrations []*registrationRecord, operatorsByLicence map[string][]*operatorRecord, services *servicesIndex) error {
+	busRegistrationsCollection := database.GetCollection("bus_registrations")
+
+	var operations []mongo.WriteModel
+	linkMethodCounts := map[ctdf.BusRegistrationServiceLinkMethod]int{}
+	updated := 0
+
+	for _, registration := range registrations {
+		operators := operatorsByLicence[registration.LicenceNumber]
+		operatorRef := ""
+		if len(operators) > 0 {
+			operatorRef = operators[0].PrimaryIdentifier
+		}
+
+		serviceRefs, linkMethod := findServices(registration, operators, services)
+		if len(serviceRefs) > 0 {
+			linkMethodCounts[linkMethod] += 1
+		}
+
+		if operatorRef == registration.OperatorRef && linkMethod == registration.ServiceLinkMethod && slices.Equal(serviceRefs, registration.ServiceRefs) {
+			continue
+		}
+
+		set := bson.M{}
+		unset := bson.M{}
+		if operatorRef == "" {
+			unset["operatorref"] = ""
+		} else {
+			set["operatorref"] = operatorRef
+		}
+		if len(serviceRefs) == 0 {
+			unset["servicerefs"] = ""
+			unset["servicelinkmethod"] = ""
+		} else {
+			set["servicerefs"] = serviceRefs
+			set["servicelinkmethod"] = linkMethod
+		}
+
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": registration.PrimaryIdentifier}).
+			SetUpdate(update),
+		)
+		updated += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := busRegistrationsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := busRegistrationsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Int("registrations", len(registrations)).
+		Int("code", linkMethodCounts[ctdf.BusRegistrationServiceLinkCode]).
+		Int("licence", linkMethodCounts[ctdf.BusRegistrationServiceLinkLicence]).
+		Int("updated", updated).
+		Msg("Linked Bus Registrations to services")
+
+	return nil
+}
+
+func getRegistrations(filter bson.M) ([]*registrationRecord, error) {
+	busRegistrationsCollection := database.GetCollection("bus_registrations")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "licencenumber", Value: 1},
+		{Key: "servicenumbers", Value: 1},
+		{Key: "operatorref", Value: 1},
+		{Key: "servicerefs", Value: 1},
+		{Key: "servicelinkmethod", Value: 1},
+	})
+	cursor, err := busRegistrationsCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var registrations []*registrationRecord
+	for cursor.Next(context.Background()) {
+		var registration registrationRecord
+		if err := cursor.Decode(&registration); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Bus Registration")
+			continue
+		}
+
+		registrations = append(registrations, &registration)
+	}
+
+	return registrations, cursor.Err()
+}
+
+// findServices returns the sorted identifiers of the services running under the registration
+func findServices(registration *registrationRecord, operators []*operatorRecord, services *servicesIndex) ([]string, ctdf.BusRegistrationServiceLinkMethod) {
+	var serviceRefs []string
+
+	for _, service := range services.byRegistration[registration.PrimaryIdentifier] {
+		serviceRefs = append(serviceRefs, service.PrimaryIdentifier)
+	}
+	if len(serviceRefs) > 0 {
+		sort.Strings(serviceRefs)
+		return slices.Compact(serviceRefs), ctdf.BusRegistrationServiceLinkCode
+	}
+
+	serviceNumbers := map[string]bool{}
+	for _, serviceNumber := range registration.ServiceNumbers {
+		serviceNumbers[strings.ToLower(serviceNumber)] = true
+	}
+
+	for _, operator := range operators {
+		for _, operatorRef := range append(operator.OtherIdentifiers, operator.PrimaryIdentifier) {
+			for _, service := range services.byOperator[operatorRef] {
+				if serviceNumbers[strings.ToLower(service.ServiceName)] {
+					serviceRefs = append(serviceRefs, service.PrimaryIdentifier)
+				}
+			}
+		}
+	}
+	if len(serviceRefs) > 0 {
+		sort.Strings(serviceRefs)
+		return slices.Compact(serviceRefs), ctdf.BusRegistrationServiceLinkLicence
+	}
+
+	return nil, ""
+}
+
+func getOperatorsByLicence() (map[string][]*operatorRecord, error) {
+	operatorsCollection := database.GetCollection("operators")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "licence", Value: 1},
+	})
+	cursor, err := operatorsCollection.Find(context.Background(), bson.M{"licence": bson.M{"$exists": true, "$ne": ""}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	operatorsByLicence := map[string][]*operatorRecord{}
+	for cursor.Next(context.Background()) {
+		var operator operatorRecord
+		if err := cursor.Decode(&operator); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Operator")
+			continue
+		}
+
+		licence := strings.ToUpper(strings.TrimSpace(operator.Licence))
+		operatorsByLicence[licence] = append(operatorsByLicence[licence], &operator)
+	}
+
+	return operatorsByLicence, cursor.Err()
+}
+
+// getServices loads the bus & coach services matching the filter, the only ones that need registering
+func getServices(filter bson.M) (*servicesIndex, error) {
+	servicesCollection := database.GetCollection("services")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "servicename", Value: 1},
+		{Key: "operatorref", Value: 1},
+		{Key: "transporttype", Value: 1},
+	})
+	query := bson.M{"transporttype": bson.M{"$in": bson.A{ctdf.TransportTypeBus, ctdf.TransportTypeCoach}}}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	cursor, err := servicesCollection.Find(context.Background(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	services := &servicesIndex{
+		byRegistration: map[string][]*serviceRecord{},
+		byOperator:     map[string][]*serviceRecord{},
+	}
+	for cursor.Next(context.Background()) {
+		var service serviceRecord
+		if err := cursor.Decode(&service); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Service")
+			continue
+		}
+
+		for _, identifier := range service.OtherIdentifiers {
+			if strings.HasPrefix(identifier, "gb-busregistration-") {
+				services.byRegistration[identifier] = append(services.byRegistration[identifier], &service)
+			}
+		}
+		services.byOperator[service.OperatorRef] = append(services.byOperator[service.OperatorRef], &service)
+		services.all = append(services.all, &service)
+	}
+
+	return services, cursor.Err()
+}
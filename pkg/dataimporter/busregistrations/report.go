@@ -0,0 +1,85 @@
+package busregistrations
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Gaps are the differences between what's registered with the Traffic Commissioner & the timetable data we have
+type Gaps struct {
+	// Current registrations that no service has been linked to, so have no data on BODS
+	RegistrationsWithoutServices []*ctdf.BusRegistration
+	// Services of licenced operators that no current registration covers
+	ServicesWithoutRegistrations []string
+}
+
+// FindGaps compares the registrations current on the date against the services. Only services of operators
+// we know the licence of are checked, the rest (eg. London buses) aren't registered with the Traffic Commissioner.
+// Link has to have run since either side was last imported.
+func FindGaps(date time.Time) (*Gaps, error) {
+	busRegistrationsCollection := database.GetCollection("bus_registrations")
+	cursor, err := busRegistrationsCollection.Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	gaps := &Gaps{}
+	registeredServices := map[string]bool{}
+
+	for cursor.Next(context.Background()) {
+		var registration ctdf.BusRegistration
+		if err := cursor.Decode(&registration); err != nil {
+			continue
+		}
+
+		if !registration.IsCurrent(date) {
+			continue
+		}
+
+		if len(registration.ServiceRefs) == 0 {
+			gaps.RegistrationsWithoutServices = append(gaps.RegistrationsWithoutServices, &registration)
+		}
+		for _, serviceRef := range registration.ServiceRefs {
+			registeredServices[serviceRef] = true
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	operatorsByLicence, err := getOperatorsByLicence()
+	if err != nil {
+		return nil, err
+	}
+	licencedOperators := map[string]bool{}
+	for _, operators := range operatorsByLicence {
+		for _, operator := range operators {
+			for _, operatorRef := range append(operator.OtherIdentifiers, operator.PrimaryIdentifier) {
+				licencedOperators[operatorRef] = true
+			}
+		}
+	}
+
+	services, err := getServices(bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services.all {
+		if licencedOperators[service.OperatorRef] && !registeredServices[service.PrimaryIdentifier] {
+			gaps.ServicesWithoutRegistrations = append(gaps.ServicesWithoutRegistrations, service.PrimaryIdentifier)
+		}
+	}
+
+	sort.Slice(gaps.RegistrationsWithoutServices, func(i, j int) bool {
+		return gaps.RegistrationsWithoutServices[i].PrimaryIdentifier < gaps.RegistrationsWithoutServices[j].PrimaryIdentifier
+	})
+	sort.Strings(gaps.ServicesWithoutRegistrations)
+
+	return gaps, nil
+}
@@ -1,16 +1,40 @@
 package dataimporter
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/adminapi"
+	"github.com/travigo/travigo/pkg/dataimporter/busregistrations"
+	"github.com/travigo/travigo/pkg/dataimporter/customdatasets"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/discovery"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/gbfs"
+	"github.com/travigo/travigo/pkg/dataimporter/importqueue"
 	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/dataimporter/pathdistances"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"github.com/travigo/travigo/pkg/dataimporter/raillocations"
+	"github.com/travigo/travigo/pkg/dataimporter/runhistory"
+	"github.com/travigo/travigo/pkg/dataimporter/stopdepartures"
+	"github.com/travigo/travigo/pkg/dataimporter/stopimportance"
+	"github.com/travigo/travigo/pkg/dataimporter/walkingtransfers"
 
+	"github.com/travigo/travigo/pkg/consumer"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/elastic_client"
+	"github.com/travigo/travigo/pkg/leader"
+	"github.com/travigo/travigo/pkg/names"
 	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/travigo/travigo/pkg/util"
 	"github.com/urfave/cli/v2"
 
 	"github.com/rs/zerolog/log"
@@ -22,15 +46,27 @@ func RegisterCLI() *cli.Command {
 	return &cli.Command{
 		Name:  "data-importer",
 		Usage: "Download & convert third party datasets into CTDF",
+		// Elasticsearch is only used for the import metrics so it's optional
+		Before: func(c *cli.Context) error {
+			if err := elastic_client.Connect(false); err != nil {
+				log.Error().Err(err).Msg("Failed to connect to Elasticsearch, import metrics won't be recorded")
+			}
+
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			elastic_client.WaitUntilQueueEmpty()
+
+			return nil
+		},
 		Subcommands: []*cli.Command{
 			{
 				Name:  "dataset",
 				Usage: "Import a dataset",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "id",
-						Usage:    "ID of the dataset",
-						Required: true,
+						Name:  "id",
+						Usage: "ID of the dataset",
 					},
 					&cli.StringFlag{
 						Name:     "repeat-every",
@@ -41,6 +77,210 @@ func RegisterCLI() *cli.Command {
 						Name:  "force",
 						Usage: "Force the import of the dataset",
 					},
+					&cli.BoolFlag{
+						Name:  "staged",
+						Usage: "Import into staging collections and promote them once validated",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Download & parse the dataset but only report what would be written",
+					},
+					&cli.StringFlag{
+						Name:  "from-run",
+						Usage: "Re-import the archived download of a previous run instead of fetching the source",
+					},
+					&cli.BoolFlag{
+						Name:  "accept-anomalies",
+						Usage: "Accept the import even if its record counts have dropped against recent runs",
+					},
+					&cli.BoolFlag{
+						Name:  "queue",
+						Usage: "Publish the import as a job for the import workers instead of running it here",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "job",
+						Usage:     "Show the status of a queued import job",
+						ArgsUsage: "<run id>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <run id>")
+							}
+
+							if err := redis_client.Connect(); err != nil {
+								return err
+							}
+
+							job, err := importqueue.GetJob(c.Args().Get(0))
+							if err != nil {
+								return err
+							}
+							if job == nil {
+								return errors.New(fmt.Sprintf("Import job %s could not be found", c.Args().Get(0)))
+							}
+
+							fmt.Printf("%s\t%s\t%s\t%s\t%s\n", job.RunID, job.Dataset, job.Status, job.Worker, job.ModificationDateTime.Format(time.RFC3339))
+							if job.Error != "" {
+								fmt.Printf("error: %s\n", job.Error)
+							}
+
+							return nil
+						},
+					},
+					{
+						Name:      "diff",
+						Usage:     "Compare the journeys produced by two import runs of a dataset",
+						ArgsUsage: "<identifier> <runA> <runB>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 3 {
+								return errors.New("Expected arguments <identifier> <runA> <runB>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							report, err := runhistory.Diff(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2))
+							if err != nil {
+								return err
+							}
+
+							report.Print(os.Stdout)
+
+							return nil
+						},
+					},
+					{
+						Name:      "runs",
+						Usage:     "List the recorded import runs of a dataset",
+						ArgsUsage: "<identifier>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <identifier>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							runs, err := runhistory.GetRuns(c.Args().Get(0))
+							if err != nil {
+								return err
+							}
+
+							for _, run := range runs {
+								fmt.Printf("%s\t%s\t%d journeys\n", run.Run, run.CreationDateTime.Format(time.RFC3339), run.Journeys)
+							}
+
+							return nil
+						},
+					},
+					{
+						Name:      "disable",
+						Usage:     "Pause scheduled imports of a dataset until it is enabled again",
+						ArgsUsage: "<identifier>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "reason",
+								Usage: "Why the dataset is being disabled",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return setDatasetEnabled(c, false)
+						},
+					},
+					{
+						Name:      "enable",
+						Usage:     "Resume scheduled imports of a dataset",
+						ArgsUsage: "<identifier>",
+						Action: func(c *cli.Context) error {
+							return setDatasetEnabled(c, true)
+						},
+					},
+					{
+						Name:      "reset",
+						Usage:     "Remove any runtime override so the dataset follows its datasource definition",
+						ArgsUsage: "<identifier>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <identifier>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return manager.DeleteDataSetOverride(c.Args().Get(0))
+						},
+					},
+					{
+						Name:      "discover",
+						Usage:     "Discover the datasets published for a discovery template and import any that have been revised",
+						ArgsUsage: "<template identifier>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "list",
+								Usage: "Only list the discovered datasets without importing them",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Import every discovered dataset even if it hasn't been revised",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <template identifier>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+							if err := redis_client.Connect(); err != nil {
+								log.Fatal().Err(err).Msg("Failed to connect to Redis")
+							}
+
+							template, err := manager.GetDataset(c.Args().Get(0))
+							if err != nil {
+								return err
+							}
+							if template.Discovery == "" {
+								return errors.New("Dataset is not a discovery template")
+							}
+
+							discovered, err := discovery.Sync(c.Context, template)
+							if err != nil {
+								return err
+							}
+
+							imported := 0
+							for _, discoveredDataset := range discovered {
+								if c.Bool("list") {
+									fmt.Printf("%s\t%s\t%s\trevised=%t\n", discoveredDataset.Identifier, discoveredDataset.Name, discoveredDataset.Revision, discoveredDataset.IsRevised())
+									continue
+								}
+
+								if !discoveredDataset.IsRevised() && !c.Bool("force") {
+									continue
+								}
+
+								dataset := discoveredDataset.ToDataSet(template)
+								if err := manager.ImportDataset(&dataset, c.Bool("force")); err != nil {
+									log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to import discovered dataset")
+									continue
+								}
+
+								if err := discovery.MarkImported(discoveredDataset); err != nil {
+									return err
+								}
+								imported += 1
+							}
+
+							log.Info().Int("discovered", len(discovered)).Int("imported", imported).Msg("Discovery complete")
+
+							return nil
+						},
+					},
 				},
 				Action: func(c *cli.Context) error {
 					if err := database.Connect(); err != nil {
@@ -53,6 +293,10 @@ func RegisterCLI() *cli.Command {
 					datasetid := c.String("id")
 					forceImport := c.Bool("force")
 
+					if datasetid == "" {
+						return errors.New("Required flag \"id\" not set")
+					}
+
 					repeatEvery := c.String("repeat-every")
 					repeat := repeatEvery != ""
 					var repeatDuration time.Duration
@@ -70,14 +314,39 @@ func RegisterCLI() *cli.Command {
 						return err
 					}
 
+					if c.Bool("queue") {
+						if c.Bool("dry-run") || c.String("from-run") != "" || c.Bool("accept-anomalies") {
+							return errors.New("Queued imports can't be dry runs, from a previous run or accept anomalies")
+						}
+
+						return publishImportJobs(dataset.Identifier, forceImport, c.Bool("staged"), repeatDuration)
+					}
+
+					if c.Bool("staged") {
+						dataset.StagedImport = true
+					}
+
+					dataset.FromRun = c.String("from-run")
+					dataset.AcceptAnomalies = c.Bool("accept-anomalies")
+
+					var statisticsSink *datasink.StatisticsSink
+					if c.Bool("dry-run") {
+						statisticsSink = datasink.NewStatisticsSink()
+						dataset.Sink = statisticsSink
+					}
+
 					for {
 						startTime := time.Now()
 
+						dataset.Progress = progress.NewTracker(dataset.Identifier, progress.NewCLIReporter())
 						err := manager.ImportDataset(&dataset, forceImport)
 
 						if err != nil {
 							return err
 						}
+						if statisticsSink != nil {
+							statisticsSink.Print(os.Stdout)
+						}
 						if !repeat {
 							break
 						}
@@ -95,6 +364,607 @@ func RegisterCLI() *cli.Command {
 					return nil
 				},
 			},
+			{
+				Name:  "file",
+				Usage: "Import a local file with any supported format, without it being a registered dataset",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "format",
+						Usage:    "Format of the file (eg. gtfs-schedule)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "source",
+						Usage:    "Path to the file",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "dataset-id",
+						Usage:    "Identifier to record the imported objects against",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "unpack",
+						Value: string(datasets.BundleFormatNone),
+						Usage: "Bundle format to unpack the file from (none, zip or gz)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "supports",
+						Usage: "Objects to import from the file (eg. stops,services,journeys), defaults to everything",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Parse the file but only report what would be written",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					dataset, err := manager.GetLocalFileDataset(c.String("dataset-id"), c.String("format"), c.String("source"), c.String("unpack"), c.StringSlice("supports"))
+					if err != nil {
+						return err
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						log.Fatal().Err(err).Msg("Failed to connect to Redis")
+					}
+
+					var statisticsSink *datasink.StatisticsSink
+					if c.Bool("dry-run") {
+						statisticsSink = datasink.NewStatisticsSink()
+						dataset.Sink = statisticsSink
+					}
+
+					dataset.Progress = progress.NewTracker(dataset.Identifier, progress.NewCLIReporter())
+					err = manager.ImportDataset(&dataset, true)
+					if err != nil {
+						return err
+					}
+
+					if statisticsSink != nil {
+						statisticsSink.Print(os.Stdout)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "reimport-operator",
+				Usage: "Reimport the services & journeys of a single operator from the datasets that supply it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "noc",
+						Usage:    "National Operator Code of the operator",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "list",
+						Usage: "Only list the datasets that supply the operator without importing them",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Download & parse the datasets but only report what would be written",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						log.Fatal().Err(err).Msg("Failed to connect to Redis")
+					}
+
+					operatorRefs := manager.GetOperatorRefs(c.String("noc"))
+
+					operatorDatasets, err := manager.GetOperatorDatasets(operatorRefs)
+					if err != nil {
+						return err
+					}
+					if len(operatorDatasets) == 0 {
+						return errors.New(fmt.Sprintf("Could not find any datasets supplying operator %s", c.String("noc")))
+					}
+
+					for _, dataset := range operatorDatasets {
+						if c.Bool("list") {
+							fmt.Printf("%s\t%s\t%s\n", dataset.Identifier, dataset.Format, dataset.Source)
+							continue
+						}
+
+						var statisticsSink *datasink.StatisticsSink
+						if c.Bool("dry-run") {
+							statisticsSink = datasink.NewStatisticsSink()
+							dataset.Sink = statisticsSink
+						}
+
+						log.Info().Str("dataset", dataset.Identifier).Strs("operators", operatorRefs).Msg("Reimporting operator")
+
+						dataset.Progress = progress.NewTracker(dataset.Identifier, progress.NewCLIReporter())
+						if err := manager.ReimportOperator(&dataset, operatorRefs); err != nil {
+							return err
+						}
+
+						if statisticsSink != nil {
+							statisticsSink.Print(os.Stdout)
+						}
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "custom-dataset",
+				Usage: "Onboard GTFS feeds supplied by operators without adding them to a datasource file",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "register",
+						Usage: "Register an operators GTFS feed, validate it and schedule it for import once it passes",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "Name of the operator, the dataset identifier is made from it",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "website",
+								Usage: "Website of the operator",
+							},
+							&cli.StringFlag{
+								Name:  "country",
+								Value: "gb",
+								Usage: "Country code the dataset is namespaced under",
+							},
+							&cli.StringFlag{
+								Name:     "source",
+								Usage:    "URL of the GTFS schedule",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "api-key",
+								Usage: "API key needed to download the source",
+							},
+							&cli.StringFlag{
+								Name:  "api-key-header",
+								Usage: "Header the API key is sent in, defaults to x-api-key",
+							},
+							&cli.StringFlag{
+								Name:  "api-key-query",
+								Usage: "Query parameter the API key is sent in instead of a header",
+							},
+							&cli.DurationFlag{
+								Name:  "refresh-interval",
+								Value: customdatasets.DefaultRefreshInterval,
+								Usage: "How often the dataset is imported",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							customDataset, err := manager.RegisterCustomDataSet(customdatasets.Registration{
+								Name:    c.String("name"),
+								Website: c.String("website"),
+								Country: c.String("country"),
+
+								Source:       c.String("source"),
+								APIKey:       c.String("api-key"),
+								APIKeyHeader: c.String("api-key-header"),
+								APIKeyQuery:  c.String("api-key-query"),
+
+								RefreshInterval: c.Duration("refresh-interval"),
+							})
+							if err != nil {
+								return err
+							}
+
+							printCustomDataSetValidation(customDataset)
+
+							return nil
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "List the registered custom datasets",
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							customDatasets, err := customdatasets.GetCustomDataSets()
+							if err != nil {
+								return err
+							}
+
+							for _, customDataset := range customDatasets {
+								fmt.Printf("%s\t%s\t%s\t%s\n", customDataset.Identifier, customDataset.Status, customDataset.Name, customDataset.Source)
+							}
+
+							return nil
+						},
+					},
+					{
+						Name:      "validate",
+						Usage:     "Validate the feed of a custom dataset again, eg. after the operator has fixed it",
+						ArgsUsage: "<identifier>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <identifier>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							customDataset := customdatasets.GetCustomDataSet(c.Args().Get(0))
+							if customDataset == nil {
+								return errors.New(fmt.Sprintf("Custom dataset %s could not be found", c.Args().Get(0)))
+							}
+
+							if _, err := manager.ValidateCustomDataSet(customDataset); err != nil {
+								return err
+							}
+
+							printCustomDataSetValidation(customDataset)
+
+							return nil
+						},
+					},
+					{
+						Name:      "remove",
+						Usage:     "Stop importing a custom dataset & remove everything imported from it",
+						ArgsUsage: "<identifier>",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() != 1 {
+								return errors.New("Expected argument <identifier>")
+							}
+
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return manager.RemoveCustomDataSet(c.Args().Get(0))
+						},
+					},
+					{
+						Name:  "schedule",
+						Usage: "Keep importing the validated custom datasets whenever they are due",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "check-every",
+								Value: 5 * time.Minute,
+								Usage: "How often to check for datasets that are due",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+							if err := redis_client.Connect(); err != nil {
+								log.Fatal().Err(err).Msg("Failed to connect to Redis")
+							}
+
+							// Any number of schedulers can be run but only one imports at a time
+							leader.RunAsLeader(leader.MongoStore{}, "custom-dataset-scheduler", c.Duration("check-every"), func(ctx context.Context) {
+								if err := manager.ImportDueCustomDataSets(ctx, time.Now()); err != nil {
+									log.Error().Err(err).Msg("Failed to import custom datasets")
+								}
+							})
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "journeys",
+				Usage: "Maintenance tasks for imported journeys",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "dedupe",
+						Usage: "Backfill journey functional hashes and remove journeys duplicated by an older import of a dataset",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "dataset",
+								Usage: "Only dedupe journeys from this dataset",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							removed, err := dedupeJourneys(c.String("dataset"))
+							if err != nil {
+								return err
+							}
+
+							fmt.Printf("Removed %d duplicate journeys\n", removed)
+
+							return nil
+						},
+					},
+					{
+						Name:  "materialise-departures",
+						Usage: "Bring the materialised stop departures for a dataset up to date with its journeys",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "dataset",
+								Usage:    "Dataset to materialise the stop departures of",
+								Required: true,
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return stopdepartures.GenerateForDataset(c.String("dataset"))
+						},
+					},
+					{
+						Name:  "path-distances",
+						Usage: "Fill in missing journey leg distances for a dataset and flag legs with implausible speeds",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "dataset",
+								Usage:    "Dataset to generate the journey path distances of",
+								Required: true,
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return pathdistances.Generate(&ctdf.DataSourceReference{DatasetID: c.String("dataset")}, nil)
+						},
+					},
+					{
+						Name:  "purge-expired",
+						Usage: "Remove journeys whose timetable validity ended a while ago",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "older-than",
+								Value: 7 * 24 * time.Hour,
+								Usage: "How long after its validity ends a journey is kept for",
+							},
+							&cli.DurationFlag{
+								Name:  "repeat-every",
+								Usage: "Keep purging on this interval instead of running once",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							if c.Duration("repeat-every") == 0 {
+								removed, err := purgeExpiredJourneys(context.Background(), c.Duration("older-than"))
+								if err != nil {
+									return err
+								}
+
+								fmt.Printf("Removed %d expired journeys\n", removed)
+
+								return nil
+							}
+
+							// Any number of these can be run but only one purges at a time
+							leader.RunAsLeader(leader.MongoStore{}, "expired-journey-purge", c.Duration("repeat-every"), func(ctx context.Context) {
+								if _, err := purgeExpiredJourneys(ctx, c.Duration("older-than")); err != nil {
+									log.Error().Err(err).Msg("Failed to purge expired journeys")
+								}
+							})
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "stops",
+				Usage: "Maintenance tasks for stops",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "importance",
+						Usage: "Recalculate the importance score & class of every stop",
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return stopimportance.Refresh()
+						},
+					},
+					{
+						Name:  "rail-locations",
+						Usage: "Link every rail location to the station stop it is part of",
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return raillocations.Link()
+						},
+					},
+					{
+						Name:  "walking-transfers",
+						Usage: "Generate walking transfers between the platforms of stations & between nearby stops",
+						Flags: []cli.Flag{
+							&cli.Float64Flag{
+								Name:  "speed",
+								Value: ctdf.TransferWalkingSpeed,
+								Usage: "walking speed in metres per second for straight line distances",
+							},
+							&cli.Float64Flag{
+								Name:  "radius",
+								Value: walkingtransfers.DefaultMaximumDistance,
+								Usage: "distance in metres that nearby stops are linked within",
+							},
+							&cli.StringFlag{
+								Name:  "osrm",
+								Value: util.GetEnvironmentVariables()["TRAVIGO_OSRM_URL"],
+								Usage: "base URL of an OSRM server with the foot profile to route the walks with",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return walkingtransfers.Generate(walkingtransfers.Options{
+								WalkingSpeed:    c.Float64("speed"),
+								MaximumDistance: c.Float64("radius"),
+								OSRMURL:         c.String("osrm"),
+							})
+						},
+					},
+				},
+			},
+			{
+				Name:  "bus-registrations",
+				Usage: "Maintenance tasks for the Traffic Commissioner bus registrations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "link",
+						Usage: "Link every bus registration to the services running under it",
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							return busregistrations.Link()
+						},
+					},
+					{
+						Name:  "report",
+						Usage: "List current registrations with no services & licenced services with no registration",
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							gaps, err := busregistrations.FindGaps(time.Now())
+							if err != nil {
+								return err
+							}
+
+							fmt.Printf("%d registrations without services\n", len(gaps.RegistrationsWithoutServices))
+							for _, registration := range gaps.RegistrationsWithoutServices {
+								fmt.Printf("%s\t%s\t%s\t%s\n", registration.RegistrationNumber, registration.OperatorName, strings.Join(registration.ServiceNumbers, ","), registration.Status)
+							}
+
+							fmt.Printf("%d services without registrations\n", len(gaps.ServicesWithoutRegistrations))
+							for _, serviceRef := range gaps.ServicesWithoutRegistrations {
+								fmt.Println(serviceRef)
+							}
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "names",
+				Usage: "Maintenance tasks for names",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "normalise",
+						Usage: "Normalise the names of existing records the same way imports do",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "type",
+								Value: cli.NewStringSlice(names.ObjectTypes...),
+								Usage: "object types to normalise",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							if err := database.Connect(); err != nil {
+								return err
+							}
+
+							for _, objectType := range c.StringSlice("type") {
+								if err := names.NormaliseExisting(objectType); err != nil {
+									return err
+								}
+							}
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "worker",
+				Usage: "Import the datasets of the jobs published to the import queue",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "concurrency",
+						Value: 1,
+						Usage: "Number of imports this worker runs at once",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						log.Fatal().Err(err).Msg("Failed to connect to Redis")
+					}
+
+					redisConsumer := consumer.RedisConsumer{
+						QueueName:       importqueue.QueueName,
+						NumberConsumers: c.Int("concurrency"),
+						BatchSize:       1,
+						Timeout:         1 * time.Second,
+						Consumer:        importqueue.NewWorker(),
+					}
+					redisConsumer.Setup()
+
+					go importqueue.RunCleaner()
+
+					signals := make(chan os.Signal, 1)
+					signal.Notify(signals, syscall.SIGINT)
+					defer signal.Stop(signals)
+
+					<-signals // wait for signal
+					go func() {
+						<-signals // hard exit on second signal (in case shutdown gets stuck)
+						os.Exit(1)
+					}()
+
+					<-redis_client.QueueConnection.StopAllConsuming() // wait for all running imports to finish
+
+					return nil
+				},
+			},
+			{
+				Name:  "admin-api",
+				Usage: "Run the admin API for managing imports over HTTP",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: ":8082",
+						Usage: "listen target for the web server",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					env := util.GetEnvironmentVariables()
+					if env["TRAVIGO_DATAIMPORTER_ADMIN_TOKEN"] == "" {
+						return errors.New("TRAVIGO_DATAIMPORTER_ADMIN_TOKEN must be set")
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						log.Fatal().Err(err).Msg("Failed to connect to Redis")
+					}
+
+					return adminapi.SetupServer(c.String("listen"), env["TRAVIGO_DATAIMPORTER_ADMIN_TOKEN"])
+				},
+			},
 			{
 				Name:  "multi-realtime",
 				Usage: "Import mutliple realtime datasets",
@@ -160,9 +1030,108 @@ func RegisterCLI() *cli.Command {
 						os.Exit(1)
 					}()
 
+					return nil
+				},
+			},
+			{
+				Name:  "gbfs-availability",
+				Usage: "Poll the dock availability of all GBFS datasets",
+				Flags: []cli.Flag{},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+
+					for _, dataset := range manager.GetRegisteredDataSets() {
+						if dataset.Format != datasets.DataSetFormatGBFS {
+							continue
+						}
+
+						log.Info().Str("id", dataset.Identifier).Msg("Polling GBFS dock availability")
+
+						go gbfs.PollAvailability(ctx, dataset)
+					}
+
+					signals := make(chan os.Signal, 1)
+					signal.Notify(signals, syscall.SIGINT)
+					defer signal.Stop(signals)
+
+					<-signals // wait for signal
+					go func() {
+						<-signals // hard exit on second signal (in case shutdown gets stuck)
+						os.Exit(1)
+					}()
+
 					return nil
 				},
 			},
 		},
 	}
 }
+
+func setDatasetEnabled(c *cli.Context, enabled bool) error {
+	if c.Args().Len() != 1 {
+		return errors.New("Expected argument <identifier>")
+	}
+
+	if err := database.Connect(); err != nil {
+		return err
+	}
+
+	dataset, err := manager.GetDataset(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	override := manager.GetDataSetOverride(dataset.Identifier)
+	if override == nil {
+		override = &manager.DataSetOverride{
+			Dataset: dataset.Identifier,
+		}
+	}
+	override.Enabled = &enabled
+	override.Reason = c.String("reason")
+
+	return manager.SetDataSetOverride(override)
+}
+
+func printCustomDataSetValidation(customDataset *customdatasets.CustomDataSet) {
+	fmt.Printf("%s: %s\n", customDataset.Identifier, customDataset.Status)
+
+	if customDataset.Validation == nil {
+		return
+	}
+
+	validation := customDataset.Validation
+	fmt.Printf("%d agencies, %d stops, %d routes, %d trips, %d stop times\n", validation.Agencies, validation.Stops, validation.Routes, validation.Trips, validation.StopTimes)
+	for _, validationError := range validation.Errors {
+		fmt.Printf("error: %s\n", validationError)
+	}
+	for _, warning := range validation.Warnings {
+		fmt.Printf("warning: %s\n", warning)
+	}
+}
+
+// publishImportJobs hands the import of a dataset to the workers, publishing a new job every repeatDuration when it's set
+func publishImportJobs(datasetID string, force bool, staged bool, repeatDuration time.Duration) error {
+	for {
+		job := importqueue.NewImportJob(datasetID)
+		job.Force = force
+		job.Staged = staged
+
+		if err := importqueue.Publish(job); err != nil {
+			return err
+		}
+
+		log.Info().Str("run", job.RunID).Str("dataset", datasetID).Msg("Published import job")
+
+		if repeatDuration <= 0 {
+			return nil
+		}
+
+		time.Sleep(repeatDuration)
+	}
+}
@@ -0,0 +1,299 @@
+package customdatasets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/travigo/travigo/pkg/countries"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/gtfs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DataSourceRef is recorded as the datasource of every custom dataset
+const DataSourceRef = "custom"
+
+// DefaultRefreshInterval is how often a custom dataset is imported when the operator didn't ask for anything else
+const DefaultRefreshInterval = 24 * time.Hour
+
+// Header the API key is sent in when neither a header or query parameter is given for it
+const defaultAPIKeyHeader = "x-api-key"
+
+var slugRegex = regexp.MustCompile("[^a-z0-9]+")
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusValidated        = "validated"
+	StatusFailed           = "failed"
+)
+
+// CustomDataSet is a GTFS schedule registered by an operator rather than defined in a datasource file.
+// It's only imported once its feed has passed validation
+type CustomDataSet struct {
+	Identifier string
+
+	Name    string
+	Website string
+	Country string
+
+	Source string
+	// EncryptedAPIKey is decrypted & sent in APIKeyHeader or the APIKeyQuery parameter when downloading the source
+	EncryptedAPIKey string `json:"-"`
+	APIKeyHeader    string
+	APIKeyQuery     string
+
+	RefreshInterval time.Duration
+
+	Status     Status
+	Validation *gtfs.ValidationReport
+
+	CreationDateTime       time.Time
+	ModificationDateTime   time.Time
+	LastValidationDateTime time.Time
+	LastImportDateTime     time.Time
+}
+
+// Registration is what an operator provides to onboard their feed
+type Registration struct {
+	Name    string
+	Website string
+	Country string
+
+	Source       string
+	APIKey       string
+	APIKeyHeader string
+	APIKeyQuery  string
+
+	RefreshInterval time.Duration
+}
+
+// IsDue checks if a validated dataset hasn't been imported within its refresh interval
+func (d *CustomDataSet) IsDue(now time.Time) bool {
+	if d.Status != StatusValidated {
+		return false
+	}
+
+	refreshInterval := d.RefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+
+	return now.Sub(d.LastImportDateTime) >= refreshInterval
+}
+
+// ToDataSet builds the importable dataset. Operators' feeds are always staged so a broken upload never replaces live data
+func (d *CustomDataSet) ToDataSet() (datasets.DataSet, error) {
+	dataset := datasets.DataSet{
+		Identifier:    d.Identifier,
+		DataSourceRef: DataSourceRef,
+		Format:        datasets.DataSetFormatGTFSSchedule,
+		Provider: datasets.Provider{
+			Name:    d.Name,
+			Website: d.Website,
+		},
+		Country:         d.Country,
+		Source:          d.Source,
+		RefreshInterval: d.RefreshInterval,
+		SupportedObjects: datasets.SupportedObjects{
+			Operators: true,
+			Stops:     true,
+			Services:  true,
+			Journeys:  true,
+		},
+		StagedImport: true,
+	}
+
+	if d.EncryptedAPIKey != "" {
+		apiKey, err := decryptAPIKey(d.EncryptedAPIKey)
+		if err != nil {
+			return dataset, errors.New(fmt.Sprintf("Failed to decrypt API key of %s: %s", d.Identifier, err))
+		}
+		apiKeyHeader := d.APIKeyHeader
+		apiKeyQuery := d.APIKeyQuery
+
+		dataset.DownloadHandler = func(r *http.Request) {
+			if apiKeyQuery != "" {
+				query := r.URL.Query()
+				query.Set(apiKeyQuery, apiKey)
+				r.URL.RawQuery = query.Encode()
+			} else if apiKeyHeader != "" {
+				r.Header.Set(apiKeyHeader, apiKey)
+			} else {
+				r.Header.Set(defaultAPIKeyHeader, apiKey)
+			}
+		}
+	}
+
+	return dataset, nil
+}
+
+// Register records a new custom dataset as pending validation, assigning it an identifier within its countries namespace
+func Register(registration Registration, reservedIdentifiers []string) (*CustomDataSet, error) {
+	if registration.Name == "" {
+		return nil, errors.New("A name must be given for the dataset")
+	}
+	if registration.Source == "" {
+		return nil, errors.New("A source URL must be given for the dataset")
+	}
+	if registration.APIKeyHeader != "" && registration.APIKeyQuery != "" {
+		return nil, errors.New("The API key can only be sent in a header or a query parameter, not both")
+	}
+
+	profile, exists := countries.Get(registration.Country)
+	if !exists {
+		return nil, errors.New(fmt.Sprintf("Unknown country %s", registration.Country))
+	}
+
+	identifier, err := getAvailableIdentifier(profile, registration.Name, reservedIdentifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptedAPIKey string
+	if registration.APIKey != "" {
+		encryptedAPIKey, err = encryptAPIKey(registration.APIKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	dataset := &CustomDataSet{
+		Identifier: identifier,
+
+		Name:    registration.Name,
+		Website: registration.Website,
+		Country: profile.Code,
+
+		Source:          registration.Source,
+		EncryptedAPIKey: encryptedAPIKey,
+		APIKeyHeader:    registration.APIKeyHeader,
+		APIKeyQuery:     registration.APIKeyQuery,
+
+		RefreshInterval: registration.RefreshInterval,
+
+		Status: StatusPending,
+
+		CreationDateTime:     now,
+		ModificationDateTime: now,
+	}
+
+	collection := database.GetCollection("datasets_custom")
+	if _, err := collection.InsertOne(context.Background(), dataset); err != nil {
+		return nil, err
+	}
+
+	return dataset, nil
+}
+
+// getAvailableIdentifier namespaces the dataset under its country & a slug of its name, eg. gb-custom-example-buses.
+// A number is added on the end when the identifier is already taken
+func getAvailableIdentifier(profile *countries.Profile, name string, reservedIdentifiers []string) (string, error) {
+	slug := strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "", errors.New(fmt.Sprintf("Could not make an identifier from the name %s", name))
+	}
+
+	baseIdentifier := fmt.Sprintf("%s-custom-%s", profile.IdentifierPrefix, slug)
+
+	reserved := map[string]bool{}
+	for _, identifier := range reservedIdentifiers {
+		reserved[identifier] = true
+	}
+
+	for i := 1; ; i++ {
+		identifier := baseIdentifier
+		if i > 1 {
+			identifier = fmt.Sprintf("%s-%d", baseIdentifier, i)
+		}
+
+		if !reserved[identifier] && GetCustomDataSet(identifier) == nil {
+			return identifier, nil
+		}
+	}
+}
+
+// RecordValidation stores the outcome of validating the datasets feed, which decides whether it gets imported
+func RecordValidation(dataset *CustomDataSet, report *gtfs.ValidationReport) error {
+	collection := database.GetCollection("datasets_custom")
+
+	dataset.Validation = report
+	dataset.LastValidationDateTime = time.Now()
+	dataset.ModificationDateTime = dataset.LastValidationDateTime
+	if report.Valid {
+		dataset.Status = StatusValidated
+	} else {
+		dataset.Status = StatusFailed
+	}
+
+	_, err := collection.UpdateOne(context.Background(), bson.M{"identifier": dataset.Identifier}, bson.M{"$set": bson.M{
+		"status":                 dataset.Status,
+		"validation":             dataset.Validation,
+		"lastvalidationdatetime": dataset.LastValidationDateTime,
+		"modificationdatetime":   dataset.ModificationDateTime,
+	}})
+
+	return err
+}
+
+// MarkImported records that the dataset has been imported so the scheduler waits for its refresh interval
+func MarkImported(dataset *CustomDataSet) error {
+	collection := database.GetCollection("datasets_custom")
+
+	dataset.LastImportDateTime = time.Now()
+
+	_, err := collection.UpdateOne(context.Background(), bson.M{"identifier": dataset.Identifier}, bson.M{"$set": bson.M{
+		"lastimportdatetime": dataset.LastImportDateTime,
+	}})
+
+	return err
+}
+
+func GetCustomDataSet(identifier string) *CustomDataSet {
+	collection := database.GetCollection("datasets_custom")
+
+	var dataset *CustomDataSet
+	collection.FindOne(context.Background(), bson.M{"identifier": identifier}).Decode(&dataset)
+
+	return dataset
+}
+
+func GetCustomDataSets() ([]*CustomDataSet, error) {
+	collection := database.GetCollection("datasets_custom")
+
+	cursor, err := collection.Find(context.Background(), bson.M{}, options.Find().SetSort(bson.M{"identifier": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var customDatasets []*CustomDataSet
+	if err := cursor.All(context.Background(), &customDatasets); err != nil {
+		return nil, err
+	}
+
+	return customDatasets, nil
+}
+
+// Remove stops a custom dataset from being imported, the records imported from it have to be cleaned up separately
+func Remove(identifier string) error {
+	collection := database.GetCollection("datasets_custom")
+
+	result, err := collection.DeleteOne(context.Background(), bson.M{"identifier": identifier})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New(fmt.Sprintf("Custom dataset %s could not be found", identifier))
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package customdatasets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+// Environment variable holding the secret the feeds API keys are encrypted with before they're stored
+const secretKeyEnv = "TRAVIGO_CUSTOM_DATASET_SECRET_KEY"
+
+func getCipher() (cipher.AEAD, error) {
+	secret := os.Getenv(secretKeyEnv)
+	if secret == "" {
+		return nil, errors.New(secretKeyEnv + " must be set to store API keys for custom datasets")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptAPIKey seals the API key with AES-GCM, the nonce is kept at the front of the encoded value
+func encryptAPIKey(apiKey string) (string, error) {
+	aead, err := getCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(apiKey), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptAPIKey(encrypted string) (string, error) {
+	aead, err := getCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("Encrypted API key is too short")
+	}
+
+	apiKey, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(apiKey), nil
+}
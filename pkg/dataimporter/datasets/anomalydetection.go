@@ -0,0 +1,36 @@
+package datasets
+
+type AnomalyAction string
+
+const (
+	AnomalyActionAbort  AnomalyAction = "abort"
+	AnomalyActionFlag                 = "flag"
+	AnomalyActionIgnore               = "ignore"
+)
+
+// AnomalyDetection compares the record counts of each import run against the recent baseline
+// so a truncated upstream file can't wipe out the existing data
+type AnomalyDetection struct {
+	// Fraction of the baseline a collections record count can drop by before the run is anomalous, defaults to 0.4
+	MaximumDrop float64
+	// What to do with an anomalous run, defaults to aborting it
+	Action AnomalyAction
+}
+
+const DefaultAnomalyMaximumDrop = 0.4
+
+func (a AnomalyDetection) GetMaximumDrop() float64 {
+	if a.MaximumDrop <= 0 {
+		return DefaultAnomalyMaximumDrop
+	}
+
+	return a.MaximumDrop
+}
+
+func (a AnomalyDetection) GetAction() AnomalyAction {
+	if a.Action == "" {
+		return AnomalyActionAbort
+	}
+
+	return a.Action
+}
@@ -0,0 +1,22 @@
+package datasets
+
+import (
+	"strings"
+
+	"github.com/travigo/travigo/pkg/countries"
+)
+
+// GetCountryProfile returns the profile of the country the dataset covers. Datasets without a country set
+// fall back on the prefix of their identifier & then the default profile
+func (d *DataSet) GetCountryProfile() *countries.Profile {
+	if profile, exists := countries.Get(d.Country); exists {
+		return profile
+	}
+
+	prefix, _, _ := strings.Cut(d.Identifier, "-")
+	if profile, exists := countries.Get(prefix); exists {
+		return profile
+	}
+
+	return countries.Default
+}
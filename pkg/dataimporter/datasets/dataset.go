@@ -1,10 +1,15 @@
 package datasets
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/adjust/rmq/v5"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/lookup"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
 )
 
 type DataSet struct {
@@ -13,29 +18,70 @@ type DataSet struct {
 	Format        DataSetFormat
 
 	Provider Provider
+	// Country picks the profile for identifiers, timezones & holidays, defaults to the region of the datasource
+	Country string
 
 	Source               string
 	SourceAuthentication SourceAuthentication `json:"-"`
 
+	// Discovery makes this dataset a template for the datasets found by the named discoverer, it isn't imported itself
+	Discovery string
+
 	DatasetSize     string
 	RefreshInterval time.Duration
 
+	// Enabled defaults to true when not set, a runtime override stored in Mongo takes precedence over both fields
+	Enabled  *bool
+	Schedule *Schedule
+
 	UnpackBundle      BundleFormat `json:"-"`
 	SupportedObjects  SupportedObjects
 	IgnoreObjects     IgnoreObjects
 	ImportDestination ImportDestination `json:"-"`
 
-	CustomConfig map[string]string
+	// Import into staging collections & only promote them to live once validated
+	StagedImport bool `json:"-"`
+	// Import the archived download of a previous run instead of fetching the source
+	FromRun string `json:"-"`
+	// Only import the services & journeys of these operators, leaving the rest of the dataset as it is
+	OnlyOperators []string `json:"-"`
+
+	// Drop records with malformed primary identifiers rather than only reporting them
+	RejectInvalidIdentifiers bool
+
+	// Number of records that can fail to parse, resolve or write before the import is aborted
+	FailureThreshold int
+
+	AnomalyDetection AnomalyDetection
+	// Accept this run even if its record counts are anomalous, for when a large drop is expected
+	AcceptAnomalies bool `json:"-"`
+
+	// FormatOptions are the typed parameters of the format, CustomConfig holds the loosely typed ones specific to a format
+	FormatOptions FormatOptions
+	CustomConfig  map[string]string
 
 	LinkedDataset string
 
 	DownloadHandler func(*http.Request) `json:"-"`
 
 	// Internal only
-	Queue *rmq.Queue `json:"-"`
+	Queue *rmq.Queue    `json:"-"`
+	Sink  datasink.Sink `json:"-"`
+
+	// Cancelling this context stops the import at the next safe point
+	Context context.Context `json:"-"`
+	// Progress is optional, formats report the records they have parsed into it
+	Progress *progress.Tracker `json:"-"`
+	// Errors collects per-record failures for the current run, when it isn't set formats fail on the first bad record
+	Errors *importerrors.Collector `json:"-"`
+	// Lookups are in memory identifier tables for resolving references to existing stops, operators & services
+	Lookups *lookup.Tables `json:"-"`
 }
 
 type SourceAuthentication struct {
+	// URL names the environment variable holding the whole source, for feeds only shared on request where the link is the credential
+	URL string
+
 	Query  map[string]string
 	Header map[string]string
 	Basic  struct {
@@ -48,16 +94,27 @@ type SourceAuthentication struct {
 type DataSetFormat string
 
 const (
-	DataSetFormatNaPTAN            DataSetFormat = "gb-naptan"
-	DataSetFormatTransXChange                    = "gb-transxchange"
-	DataSetFormatTravelineNOC                    = "gb-travelinenoc"
-	DataSetFormatCIF                             = "gb-cif"
-	DataSetFormatNationalRailTOC                 = "gb-nationalrailtoc"
-	DataSetFormatNetworkRailCorpus               = "gb-networkrailcorpus"
-	DataSetFormatSiriVM                          = "eu-siri-vm"
-	DataSetFormatSiriSX                          = "eu-siri-sx"
-	DataSetFormatGTFSSchedule                    = "gtfs-schedule"
-	DataSetFormatGTFSRealtime                    = "gtfs-realtime"
+	DataSetFormatNaPTAN                DataSetFormat = "gb-naptan"
+	DataSetFormatNPTG                                = "gb-nptg"
+	DataSetFormatTransXChange                        = "gb-transxchange"
+	DataSetFormatTravelineNOC                        = "gb-travelinenoc"
+	DataSetFormatCIF                                 = "gb-cif"
+	DataSetFormatDarwinTimetable                     = "gb-darwintimetable"
+	DataSetFormatNationalRailTOC                     = "gb-nationalrailtoc"
+	DataSetFormatNetworkRailCorpus                   = "gb-networkrailcorpus"
+	DataSetFormatNetworkRailBPLAN                    = "gb-networkrailbplan"
+	DataSetFormatNationalRailIncidents               = "gb-nationalrailincidents"
+	DataSetFormatSiriVM                              = "eu-siri-vm"
+	DataSetFormatSiriSX                              = "eu-siri-sx"
+	DataSetFormatGTFSSchedule                        = "gtfs-schedule"
+	DataSetFormatGTFSRealtime                        = "gtfs-realtime"
+	DataSetFormatBranding                            = "travigo-branding"
+	DataSetFormatSchoolTerms                         = "travigo-schoolterms"
+	DataSetFormatGBFS                                = "gbfs"
+	DataSetFormatTfLCarParks                         = "gb-tflcarparks"
+	DataSetFormatTfLCarParkOccupancy                 = "gb-tflcarparkoccupancy"
+	DataSetFormatCSVStops                            = "csv-stops"
+	DataSetFormatOTCBusRegistrations                 = "gb-otcbusregistrations"
 )
 
 type Provider struct {
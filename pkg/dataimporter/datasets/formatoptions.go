@@ -0,0 +1,62 @@
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatOptions are the parameters a format imports a dataset with, so several datasets can share a format
+// (eg. a GTFS feed per country) without the format special casing any of them
+type FormatOptions struct {
+	// IdentifierPrefix starts the identifiers of the records the dataset creates instead of the dataset identifier.
+	// Realtime datasets use it as the prefix of the records they reference in their linked dataset
+	IdentifierPrefix string
+	// Timezone of the times in the dataset when it doesn't give one itself, defaults to the timezone of the country
+	Timezone string
+	// DefaultOperator is the operator ref given to records that don't say who operates them
+	DefaultOperator string
+}
+
+// Validate checks the options can be used before anything is imported with them
+func (o *FormatOptions) Validate() error {
+	if strings.ContainsAny(o.IdentifierPrefix, " :") {
+		return errors.New(fmt.Sprintf("Identifier prefix %s can't contain spaces or colons", o.IdentifierPrefix))
+	}
+
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return errors.New(fmt.Sprintf("Unknown timezone %s", o.Timezone))
+		}
+	}
+
+	return nil
+}
+
+// GetIdentifierPrefix is what the identifiers of the records created by the dataset start with
+func (d *DataSet) GetIdentifierPrefix() string {
+	if d.FormatOptions.IdentifierPrefix != "" {
+		return d.FormatOptions.IdentifierPrefix
+	}
+
+	return d.Identifier
+}
+
+// GetLinkedIdentifierPrefix is what the identifiers of the records in the linked dataset start with
+func (d *DataSet) GetLinkedIdentifierPrefix() string {
+	if d.FormatOptions.IdentifierPrefix != "" {
+		return d.FormatOptions.IdentifierPrefix
+	}
+
+	return d.LinkedDataset
+}
+
+// GetTimezone is the timezone of times in the dataset that don't give one
+func (d *DataSet) GetTimezone() string {
+	if d.FormatOptions.Timezone != "" {
+		return d.FormatOptions.Timezone
+	}
+
+	return d.GetCountryProfile().Timezone
+}
@@ -1,5 +1,7 @@
 package datasets
 
+import "github.com/travigo/travigo/pkg/util"
+
 type IgnoreObjects struct {
 	// Operators      bool
 	// OperatorGroups bool
@@ -15,3 +17,13 @@ type IgnoreObjects struct {
 type IgnoreObjectServiceJourney struct {
 	ByOperator []string
 }
+
+// IgnoresOperator is whether an operators records should be skipped, either because they're configured to be ignored
+// or because the import is limited to other operators
+func (d *DataSet) IgnoresOperator(ignore IgnoreObjectServiceJourney, operatorRef string) bool {
+	if len(d.OnlyOperators) > 0 && !util.ContainsString(d.OnlyOperators, operatorRef) {
+		return true
+	}
+
+	return util.ContainsString(ignore.ByOperator, operatorRef)
+}
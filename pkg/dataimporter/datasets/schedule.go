@@ -0,0 +1,69 @@
+package datasets
+
+import (
+	"strings"
+	"time"
+)
+
+// Schedule limits when a dataset is imported, eg. a realtime feed that only runs while services are operating.
+// An empty schedule allows imports at any time
+type Schedule struct {
+	// Weekdays the dataset is imported on (eg. monday), defaults to every day
+	Days []string
+	// Time of day window in HH:MM that imports are allowed in. A window where From is after To runs over midnight
+	From string
+	To   string
+
+	// Timezone the window is in, defaults to Europe/London
+	Timezone string
+}
+
+func (s *Schedule) IsEmpty() bool {
+	return s == nil || (len(s.Days) == 0 && s.From == "" && s.To == "")
+}
+
+// IsActive checks if the given time falls inside the schedule
+func (s *Schedule) IsActive(now time.Time) bool {
+	if s.IsEmpty() {
+		return true
+	}
+
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = "Europe/London"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err == nil {
+		now = now.In(location)
+	}
+
+	if len(s.Days) > 0 {
+		dayMatch := false
+		for _, day := range s.Days {
+			if strings.EqualFold(day, now.Weekday().String()) {
+				dayMatch = true
+				break
+			}
+		}
+
+		if !dayMatch {
+			return false
+		}
+	}
+
+	from, fromErr := time.Parse("15:04", s.From)
+	to, toErr := time.Parse("15:04", s.To)
+	if fromErr != nil || toErr != nil {
+		return true
+	}
+
+	minuteOfDay := now.Hour()*60 + now.Minute()
+	fromMinute := from.Hour()*60 + from.Minute()
+	toMinute := to.Hour()*60 + to.Minute()
+
+	if fromMinute <= toMinute {
+		return minuteOfDay >= fromMinute && minuteOfDay < toMinute
+	}
+
+	return minuteOfDay >= fromMinute || minuteOfDay < toMinute
+}
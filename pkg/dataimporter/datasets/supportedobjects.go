@@ -1,12 +1,20 @@
 package datasets
 
 type SupportedObjects struct {
-	Operators      bool
-	OperatorGroups bool
-	Stops          bool
-	StopGroups     bool
-	Services       bool
-	Journeys       bool
+	Operators           bool
+	OperatorGroups      bool
+	Stops               bool
+	StopGroups          bool
+	Localities          bool
+	AdministrativeAreas bool
+	Services            bool
+	Journeys            bool
+	Transfers           bool
+
+	SchoolTermCalendars bool
+	CarParks            bool
+	RailLocations       bool
+	BusRegistrations    bool
 
 	RealtimeJourneys bool
 	ServiceAlerts    bool
@@ -0,0 +1,97 @@
+package datasink
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CapturedWrite is a single write an importer made
+type CapturedWrite struct {
+	Collection string
+	Operation  string
+	Filter     interface{}
+	// Document is the inserted or replacement record, or the update applied to it
+	Document interface{}
+}
+
+// CaptureSink never writes anything and instead keeps every write so the output of an importer can be inspected
+type CaptureSink struct {
+	writes []CapturedWrite
+
+	mutex sync.Mutex
+}
+
+func NewCaptureSink() *CaptureSink {
+	return &CaptureSink{}
+}
+
+func (c *CaptureSink) BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	result := &mongo.BulkWriteResult{}
+	var writes []CapturedWrite
+
+	for _, operation := range operations {
+		write := CapturedWrite{Collection: collection.Name()}
+
+		switch model := operation.(type) {
+		case *mongo.InsertOneModel:
+			write.Operation = "insert"
+			write.Document = model.Document
+			result.InsertedCount += 1
+		case *mongo.ReplaceOneModel:
+			write.Operation = "replace"
+			write.Filter = model.Filter
+			write.Document = model.Replacement
+			result.ModifiedCount += 1
+		case *mongo.UpdateOneModel:
+			write.Operation = "update"
+			write.Filter = model.Filter
+			write.Document = model.Update
+			result.ModifiedCount += 1
+		case *mongo.UpdateManyModel:
+			write.Operation = "updatemany"
+			write.Filter = model.Filter
+			write.Document = model.Update
+			result.ModifiedCount += 1
+		case *mongo.DeleteOneModel:
+			write.Operation = "delete"
+			write.Filter = model.Filter
+			result.DeletedCount += 1
+		case *mongo.DeleteManyModel:
+			write.Operation = "deletemany"
+			write.Filter = model.Filter
+			result.DeletedCount += 1
+		default:
+			continue
+		}
+
+		writes = append(writes, write)
+	}
+
+	c.mutex.Lock()
+	c.writes = append(c.writes, writes...)
+	c.mutex.Unlock()
+
+	return result, nil
+}
+
+func (c *CaptureSink) DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error) {
+	c.mutex.Lock()
+	c.writes = append(c.writes, CapturedWrite{
+		Collection: collection.Name(),
+		Operation:  "deletemany",
+		Filter:     filter,
+	})
+	c.mutex.Unlock()
+
+	return 0, nil
+}
+
+// Writes returns everything written so far in the order it was received
+func (c *CaptureSink) Writes() []CapturedWrite {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]CapturedWrite{}, c.writes...)
+}
@@ -0,0 +1,49 @@
+package datasink
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sink is where importers send their database writes
+type Sink interface {
+	BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error)
+	DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error)
+}
+
+// MongoSink writes straight through to the given collection
+type MongoSink struct {
+	// Drop records with malformed primary identifiers instead of only reporting them
+	RejectInvalidIdentifiers bool
+}
+
+func (m MongoSink) BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	operations, _ = validateOperations(collection.Name(), operations, m.RejectInvalidIdentifiers)
+	if len(operations) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	return collection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{})
+}
+
+func (m MongoSink) DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error) {
+	result, err := collection.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// IsDryRun reports whether writes to the sink are being discarded
+func IsDryRun(sink Sink) bool {
+	switch sink.(type) {
+	case *StatisticsSink, *CaptureSink:
+		return true
+	default:
+		return false
+	}
+}
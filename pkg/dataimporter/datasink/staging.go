@@ -0,0 +1,51 @@
+package datasink
+
+import (
+	"context"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const StagingCollectionSuffix = "_staging"
+
+// StagingSink redirects writes into the staging version of each collection, ready to be promoted later
+type StagingSink struct {
+	// Drop records with malformed primary identifiers instead of only reporting them
+	RejectInvalidIdentifiers bool
+}
+
+func (s StagingSink) BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	operations, _ = validateOperations(collection.Name(), operations, s.RejectInvalidIdentifiers)
+	if len(operations) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	// Importers decide between insert & replace based on the live collection, so anything that isn't
+	// an insert has to become an upsert as the record won't exist in staging yet
+	for _, operation := range operations {
+		switch model := operation.(type) {
+		case *mongo.UpdateOneModel:
+			model.SetUpsert(true)
+		case *mongo.ReplaceOneModel:
+			model.SetUpsert(true)
+		}
+	}
+
+	return GetStagingCollection(collection.Name()).BulkWrite(context.Background(), operations, &options.BulkWriteOptions{})
+}
+
+func (s StagingSink) DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error) {
+	result, err := GetStagingCollection(collection.Name()).DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func GetStagingCollection(collectionName string) *mongo.Collection {
+	return database.GetCollection(collectionName + StagingCollectionSuffix)
+}
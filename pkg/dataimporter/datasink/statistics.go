@@ -0,0 +1,169 @@
+package datasink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/adjust/rmq/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CollectionStatistics struct {
+	Creates int64
+	Updates int64
+	Deletes int64
+
+	InvalidIdentifiers int64
+}
+
+// StatisticsSink never writes anything and instead records what would have been written
+type StatisticsSink struct {
+	collections map[string]*CollectionStatistics
+	queueEvents map[string]int64
+
+	mutex sync.Mutex
+}
+
+func NewStatisticsSink() *StatisticsSink {
+	return &StatisticsSink{
+		collections: map[string]*CollectionStatistics{},
+		queueEvents: map[string]int64{},
+	}
+}
+
+func (s *StatisticsSink) BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	var creates int64
+	var updates int64
+
+	_, invalidIdentifiers := validateOperations(collection.Name(), operations, false)
+	var deletes int64
+
+	// Existing documents are looked up in a single query so we can tell upserts that create from those that update
+	var existingFilters bson.A
+
+	for _, operation := range operations {
+		switch model := operation.(type) {
+		case *mongo.InsertOneModel:
+			creates += 1
+		case *mongo.UpdateOneModel:
+			if model.Upsert != nil && *model.Upsert {
+				existingFilters = append(existingFilters, model.Filter)
+			} else {
+				updates += 1
+			}
+		case *mongo.UpdateManyModel:
+			updates += 1
+		case *mongo.ReplaceOneModel:
+			if model.Upsert != nil && *model.Upsert {
+				existingFilters = append(existingFilters, model.Filter)
+			} else {
+				updates += 1
+			}
+		case *mongo.DeleteOneModel, *mongo.DeleteManyModel:
+			deletes += 1
+		}
+	}
+
+	if len(existingFilters) > 0 {
+		existing, err := collection.CountDocuments(context.Background(), bson.M{"$or": existingFilters})
+		if err != nil {
+			return nil, err
+		}
+
+		updates += existing
+		creates += int64(len(existingFilters)) - existing
+	}
+
+	s.mutex.Lock()
+	statistics := s.getCollectionStatistics(collection.Name())
+	statistics.Creates += creates
+	statistics.Updates += updates
+	statistics.Deletes += deletes
+	statistics.InvalidIdentifiers += invalidIdentifiers
+	s.mutex.Unlock()
+
+	return &mongo.BulkWriteResult{
+		InsertedCount: creates,
+		ModifiedCount: updates,
+		DeletedCount:  deletes,
+	}, nil
+}
+
+func (s *StatisticsSink) DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error) {
+	count, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	s.getCollectionStatistics(collection.Name()).Deletes += count
+	s.mutex.Unlock()
+
+	return count, nil
+}
+
+func (s *StatisticsSink) RecordQueueEvent(queueName string) {
+	s.mutex.Lock()
+	s.queueEvents[queueName] += 1
+	s.mutex.Unlock()
+}
+
+func (s *StatisticsSink) getCollectionStatistics(collectionName string) *CollectionStatistics {
+	if s.collections[collectionName] == nil {
+		s.collections[collectionName] = &CollectionStatistics{}
+	}
+
+	return s.collections[collectionName]
+}
+
+func (s *StatisticsSink) Print(writer io.Writer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var collectionNames []string
+	for collectionName := range s.collections {
+		collectionNames = append(collectionNames, collectionName)
+	}
+	sort.Strings(collectionNames)
+
+	for _, collectionName := range collectionNames {
+		statistics := s.collections[collectionName]
+		fmt.Fprintf(writer, "%s: %d created, %d updated, %d deleted\n", collectionName, statistics.Creates, statistics.Updates, statistics.Deletes)
+
+		if statistics.InvalidIdentifiers > 0 {
+			fmt.Fprintf(writer, "%s: %d records with malformed primary identifiers\n", collectionName, statistics.InvalidIdentifiers)
+		}
+	}
+
+	for queueName, count := range s.queueEvents {
+		fmt.Fprintf(writer, "%s: %d events published\n", queueName, count)
+	}
+}
+
+// StatisticsQueue stands in for a realtime queue, counting published events rather than sending them
+type StatisticsQueue struct {
+	rmq.Queue
+
+	Name string
+	Sink *StatisticsSink
+}
+
+func (q StatisticsQueue) Publish(payload ...string) error {
+	for range payload {
+		q.Sink.RecordQueueEvent(q.Name)
+	}
+
+	return nil
+}
+
+func (q StatisticsQueue) PublishBytes(payload ...[]byte) error {
+	for range payload {
+		q.Sink.RecordQueueEvent(q.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package datasink
+
+import (
+	"context"
+	"sync"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactionWrite struct {
+	collection *mongo.Collection
+	operations []mongo.WriteModel
+	// Set instead of operations for a DeleteMany
+	deleteFilter bson.M
+}
+
+// TransactionSink holds on to every write until Commit, which applies them all in a single transaction.
+// Everything is kept in memory so it's only suitable for imports limited to a small part of a dataset
+type TransactionSink struct {
+	// Drop records with malformed primary identifiers instead of only reporting them
+	RejectInvalidIdentifiers bool
+
+	writes []transactionWrite
+	mutex  sync.Mutex
+}
+
+func NewTransactionSink(rejectInvalidIdentifiers bool) *TransactionSink {
+	return &TransactionSink{RejectInvalidIdentifiers: rejectInvalidIdentifiers}
+}
+
+func (t *TransactionSink) BulkWrite(collection *mongo.Collection, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	operations, _ = validateOperations(collection.Name(), operations, t.RejectInvalidIdentifiers)
+	if len(operations) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	t.mutex.Lock()
+	t.writes = append(t.writes, transactionWrite{collection: collection, operations: operations})
+	t.mutex.Unlock()
+
+	return &mongo.BulkWriteResult{}, nil
+}
+
+// DeleteMany is only applied on Commit so the deleted count is always 0
+func (t *TransactionSink) DeleteMany(collection *mongo.Collection, filter bson.M) (int64, error) {
+	t.mutex.Lock()
+	t.writes = append(t.writes, transactionWrite{collection: collection, deleteFilter: filter})
+	t.mutex.Unlock()
+
+	return 0, nil
+}
+
+// Commit applies the writes in the order they were made, either all of them go through or none do
+func (t *TransactionSink) Commit() error {
+	t.mutex.Lock()
+	writes := t.writes
+	t.writes = nil
+	t.mutex.Unlock()
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	session, err := database.Instance.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(sessionContext mongo.SessionContext) (interface{}, error) {
+		for _, write := range writes {
+			var err error
+			if write.operations != nil {
+				_, err = write.collection.BulkWrite(sessionContext, write.operations)
+			} else {
+				_, err = write.collection.DeleteMany(sessionContext, write.deleteFilter)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
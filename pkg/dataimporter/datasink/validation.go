@@ -0,0 +1,100 @@
+package datasink
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/identifiers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var identifierViolations = map[string]int64{}
+var identifierViolationsMutex sync.Mutex
+
+// GetIdentifierViolations returns how many writes with malformed primary identifiers each collection has seen
+func GetIdentifierViolations() map[string]int64 {
+	identifierViolationsMutex.Lock()
+	defer identifierViolationsMutex.Unlock()
+
+	violations := map[string]int64{}
+	for collectionName, count := range identifierViolations {
+		violations[collectionName] = count
+	}
+
+	return violations
+}
+
+// validateOperations checks the primary identifier of every record being written, recording any that are malformed.
+// When reject is set the malformed records are dropped from the returned operations.
+func validateOperations(collectionName string, operations []mongo.WriteModel, reject bool) ([]mongo.WriteModel, int64) {
+	var validOperations []mongo.WriteModel
+	var violations int64
+	var lastErr error
+
+	for _, operation := range operations {
+		primaryIdentifier, exists := getOperationPrimaryIdentifier(operation)
+
+		if exists {
+			if err := identifiers.ValidatePrimaryIdentifier(collectionName, primaryIdentifier); err != nil {
+				violations += 1
+				lastErr = err
+
+				if reject {
+					continue
+				}
+			}
+		}
+
+		validOperations = append(validOperations, operation)
+	}
+
+	if violations > 0 {
+		identifierViolationsMutex.Lock()
+		identifierViolations[collectionName] += violations
+		identifierViolationsMutex.Unlock()
+
+		log.Warn().
+			Err(lastErr).
+			Str("collection", collectionName).
+			Int64("violations", violations).
+			Bool("rejected", reject).
+			Msg("Malformed primary identifiers in write")
+	}
+
+	return validOperations, violations
+}
+
+// getOperationPrimaryIdentifier finds the primary identifier of the record an insert, update or replace is writing
+func getOperationPrimaryIdentifier(operation mongo.WriteModel) (string, bool) {
+	switch model := operation.(type) {
+	case *mongo.InsertOneModel:
+		return getDocumentPrimaryIdentifier(model.Document)
+	case *mongo.UpdateOneModel:
+		return getDocumentPrimaryIdentifier(model.Filter)
+	case *mongo.ReplaceOneModel:
+		return getDocumentPrimaryIdentifier(model.Filter)
+	}
+
+	return "", false
+}
+
+func getDocumentPrimaryIdentifier(document interface{}) (string, bool) {
+	if bsonDocument, ok := document.(bson.M); ok {
+		primaryIdentifier, ok := bsonDocument["primaryidentifier"].(string)
+		return primaryIdentifier, ok
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(document))
+	if value.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	field := value.FieldByName("PrimaryIdentifier")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return "", false
+	}
+
+	return field.String(), true
+}
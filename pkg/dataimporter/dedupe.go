@@ -0,0 +1,133 @@
+package dataimporter
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dedupeBatchSize = 1000
+
+// dedupeJourneys backfills the functional hash on existing journeys and removes any journeys left over from an older
+// import of the same dataset that share a hash with a newer one. Returns the number of journeys removed.
+func dedupeJourneys(datasetID string) (int, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	var datasetIDs []string
+	if datasetID == "" {
+		results, err := journeysCollection.Distinct(context.Background(), "datasource.datasetid", bson.M{})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, result := range results {
+			if id, ok := result.(string); ok {
+				datasetIDs = append(datasetIDs, id)
+			}
+		}
+	} else {
+		datasetIDs = []string{datasetID}
+	}
+
+	removed := 0
+	for _, id := range datasetIDs {
+		datasetRemoved, err := dedupeDatasetJourneys(journeysCollection, id)
+		removed += datasetRemoved
+
+		if err != nil {
+			return removed, err
+		}
+
+		log.Info().Str("dataset", id).Int("removed", datasetRemoved).Msg("Deduplicated journeys")
+	}
+
+	return removed, nil
+}
+
+func dedupeDatasetJourneys(journeysCollection *mongo.Collection, datasetID string) (int, error) {
+	// Newest first so that the most recently imported copy of a journey is the one kept
+	opts := options.Find().SetSort(bson.D{{Key: "modificationdatetime", Value: -1}})
+	cursor, err := journeysCollection.Find(context.Background(), bson.M{"datasource.datasetid": datasetID}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	// Timestamp of the newest import each hash was seen in
+	seenHashes := map[string]string{}
+	var duplicateIDs []interface{}
+	var operations []mongo.WriteModel
+	removed := 0
+
+	flush := func() error {
+		if len(duplicateIDs) > 0 {
+			result, err := journeysCollection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": duplicateIDs}})
+			if err != nil {
+				return err
+			}
+			removed += int(result.DeletedCount)
+			duplicateIDs = nil
+		}
+
+		if len(operations) > 0 {
+			if _, err := journeysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+
+		return nil
+	}
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			log.Error().Err(err).Msg("Failed to decode journey")
+			continue
+		}
+		// Primary identifiers aren't guaranteed unique amongst the duplicates so work on the document ID
+		documentID := cursor.Current.Lookup("_id")
+
+		// Journeys from the same import sharing a hash are separate trips with the same timings so are all kept
+		var timestamp string
+		if journey.DataSource != nil {
+			timestamp = journey.DataSource.Timestamp
+		}
+
+		existingHash := journey.FunctionalHash
+		journey.FunctionalHash = journey.GenerateFunctionalHash(true)
+
+		if seenTimestamp, seen := seenHashes[journey.FunctionalHash]; seen && seenTimestamp != timestamp {
+			duplicateIDs = append(duplicateIDs, documentID)
+		} else {
+			if !seen {
+				seenHashes[journey.FunctionalHash] = timestamp
+			}
+
+			if existingHash != journey.FunctionalHash {
+				updateModel := mongo.NewUpdateOneModel()
+				updateModel.SetFilter(bson.M{"_id": documentID})
+				updateModel.SetUpdate(bson.M{"$set": bson.M{"functionalhash": journey.FunctionalHash}})
+
+				operations = append(operations, updateModel)
+			}
+		}
+
+		if len(duplicateIDs)+len(operations) >= dedupeBatchSize {
+			if err := flush(); err != nil {
+				return removed, err
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return removed, err
+	}
+
+	return removed, flush()
+}
@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/util"
+)
+
+const bodsTimetablesAPI = "https://data.bus-data.dft.gov.uk/api/v1/dataset/"
+const bodsPageSize = 100
+
+// BODSTimetables enumerates the per-operator TransXChange timetable datasets published on Bus Open Data
+type BODSTimetables struct {
+	// Overrides the API location, mostly useful for pointing at a mock
+	BaseURL string
+}
+
+type bodsTimetablesResponse struct {
+	Count   int                    `json:"count"`
+	Next    string                 `json:"next"`
+	Results []bodsTimetableDataset `json:"results"`
+}
+
+type bodsTimetableDataset struct {
+	ID           int      `json:"id"`
+	Modified     string   `json:"modified"`
+	OperatorName string   `json:"operatorName"`
+	NOC          []string `json:"noc"`
+	Name         string   `json:"name"`
+	Status       string   `json:"status"`
+	URL          string   `json:"url"`
+	Extension    string   `json:"extension"`
+}
+
+func (b *BODSTimetables) Discover(ctx context.Context, template datasets.DataSet) ([]*DiscoveredDataSet, error) {
+	env := util.GetEnvironmentVariables()
+	apiKey := env[template.SourceAuthentication.Query["api_key"]]
+	if apiKey == "" {
+		return nil, errors.New("BODS discovery requires an api_key in the template source authentication")
+	}
+
+	baseURL := b.BaseURL
+	if baseURL == "" {
+		baseURL = bodsTimetablesAPI
+	}
+
+	var discovered []*DiscoveredDataSet
+
+	for offset := 0; ; offset += bodsPageSize {
+		query := url.Values{}
+		query.Set("api_key", apiKey)
+		query.Set("status", "published")
+		query.Set("limit", strconv.Itoa(bodsPageSize))
+		query.Set("offset", strconv.Itoa(offset))
+
+		page, err := b.getPage(ctx, fmt.Sprintf("%s?%s", baseURL, query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range page.Results {
+			if result.Status != "" && result.Status != "published" {
+				continue
+			}
+
+			var operatorRefs []string
+			for _, noc := range result.NOC {
+				operatorRefs = append(operatorRefs, fmt.Sprintf(ctdf.OperatorNOCFormat, noc))
+			}
+
+			unpackBundle := datasets.BundleFormatNone
+			if strings.EqualFold(result.Extension, "zip") {
+				unpackBundle = datasets.BundleFormatZIP
+			}
+
+			discovered = append(discovered, &DiscoveredDataSet{
+				SourceID:     strconv.Itoa(result.ID),
+				Name:         fmt.Sprintf("%s - %s", result.OperatorName, result.Name),
+				OperatorRefs: operatorRefs,
+				Source:       result.URL,
+				UnpackBundle: unpackBundle,
+				Revision:     result.Modified,
+			})
+		}
+
+		if page.Next == "" || len(page.Results) == 0 {
+			break
+		}
+	}
+
+	return discovered, nil
+}
+
+func (b *BODSTimetables) getPage(ctx context.Context, pageURL string) (*bodsTimetablesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errors.New(fmt.Sprintf("BODS API returned %s", resp.Status))
+	}
+
+	var page bodsTimetablesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DiscoveredDataSet is a dataset found through a providers API rather than defined in a datasource file.
+// It's imported using the template dataset it was discovered from with its own source
+type DiscoveredDataSet struct {
+	Identifier string
+	Template   string
+
+	SourceID     string
+	Name         string
+	OperatorRefs []string
+	Source       string
+	UnpackBundle datasets.BundleFormat
+
+	// Revision changes whenever the provider publishes a new version of the dataset
+	Revision         string
+	ImportedRevision string
+
+	CreationDateTime     time.Time
+	ModificationDateTime time.Time
+	LastImportDateTime   time.Time
+}
+
+func (d *DiscoveredDataSet) IsRevised() bool {
+	return d.Revision != d.ImportedRevision
+}
+
+// ToDataSet builds the importable dataset from its template
+func (d *DiscoveredDataSet) ToDataSet(template datasets.DataSet) datasets.DataSet {
+	dataset := template
+
+	dataset.Identifier = d.Identifier
+	dataset.Discovery = ""
+	dataset.Source = d.Source
+	dataset.UnpackBundle = d.UnpackBundle
+
+	return dataset
+}
+
+// Discoverer lists the datasets a provider currently publishes
+type Discoverer interface {
+	Discover(ctx context.Context, template datasets.DataSet) ([]*DiscoveredDataSet, error)
+}
+
+var discoverers = map[string]Discoverer{
+	"gb-bods-timetables": &BODSTimetables{},
+}
+
+// Sync discovers the datasets for a template, records them & returns every dataset currently published.
+// Datasets that have disappeared from the provider are removed
+func Sync(ctx context.Context, template datasets.DataSet) ([]*DiscoveredDataSet, error) {
+	discoverer := discoverers[template.Discovery]
+	if discoverer == nil {
+		return nil, errors.New(fmt.Sprintf("Unknown discovery %s", template.Discovery))
+	}
+
+	discovered, err := discoverer.Discover(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := database.GetCollection("datasets_discovered")
+	now := time.Now()
+
+	var identifiers []string
+	for _, dataset := range discovered {
+		dataset.Identifier = fmt.Sprintf("%s-%s", template.Identifier, dataset.SourceID)
+		dataset.Template = template.Identifier
+		identifiers = append(identifiers, dataset.Identifier)
+
+		// Keep track of what was last imported from the existing record
+		var existing *DiscoveredDataSet
+		collection.FindOne(ctx, bson.M{"identifier": dataset.Identifier}).Decode(&existing)
+		if existing != nil {
+			dataset.ImportedRevision = existing.ImportedRevision
+			dataset.CreationDateTime = existing.CreationDateTime
+			dataset.LastImportDateTime = existing.LastImportDateTime
+		} else {
+			dataset.CreationDateTime = now
+		}
+		dataset.ModificationDateTime = now
+
+		opts := options.Replace().SetUpsert(true)
+		if _, err := collection.ReplaceOne(ctx, bson.M{"identifier": dataset.Identifier}, dataset, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{
+		"template":   template.Identifier,
+		"identifier": bson.M{"$nin": identifiers},
+	})
+
+	return discovered, err
+}
+
+// MarkImported records that the current revision of a discovered dataset has been imported
+func MarkImported(dataset *DiscoveredDataSet) error {
+	collection := database.GetCollection("datasets_discovered")
+
+	dataset.ImportedRevision = dataset.Revision
+	dataset.LastImportDateTime = time.Now()
+
+	_, err := collection.UpdateOne(context.Background(), bson.M{"identifier": dataset.Identifier}, bson.M{"$set": bson.M{
+		"importedrevision":   dataset.ImportedRevision,
+		"lastimportdatetime": dataset.LastImportDateTime,
+	}})
+
+	return err
+}
+
+func GetDiscoveredDataSet(identifier string) *DiscoveredDataSet {
+	collection := database.GetCollection("datasets_discovered")
+
+	var dataset *DiscoveredDataSet
+	collection.FindOne(context.Background(), bson.M{"identifier": identifier}).Decode(&dataset)
+
+	return dataset
+}
+
+// GetDiscoveredDataSetsForOperator returns the discovered datasets that publish any of the operators
+func GetDiscoveredDataSetsForOperator(operatorRefs []string) ([]*DiscoveredDataSet, error) {
+	collection := database.GetCollection("datasets_discovered")
+
+	cursor, err := collection.Find(context.Background(), bson.M{"operatorrefs": bson.M{"$in": operatorRefs}})
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered []*DiscoveredDataSet
+	if err := cursor.All(context.Background(), &discovered); err != nil {
+		return nil, err
+	}
+
+	return discovered, nil
+}
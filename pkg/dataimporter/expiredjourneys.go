@@ -0,0 +1,80 @@
+package dataimporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const purgeExpiredBatchSize = 1000
+
+// purgeExpiredJourneys removes journeys whose timetable stopped being valid longer ago than the retention, along with
+// their materialised stop departures. Returns the number of journeys removed.
+func purgeExpiredJourneys(ctx context.Context, retention time.Duration) (int, error) {
+	journeysCollection := database.GetCollection("journeys")
+	stopDeparturesCollection := database.GetCollection("stop_departures")
+
+	cutoff := time.Now().Add(-retention)
+	query := bson.M{"validuntil": bson.M{"$lt": cutoff}}
+
+	removed := 0
+	for {
+		opts := options.Find().
+			SetProjection(bson.M{"primaryidentifier": 1}).
+			SetLimit(purgeExpiredBatchSize)
+
+		cursor, err := journeysCollection.Find(ctx, query, opts)
+		if err != nil {
+			return removed, err
+		}
+
+		var journeyRefs []string
+		for cursor.Next(ctx) {
+			var journey struct {
+				PrimaryIdentifier string `bson:"primaryidentifier"`
+			}
+			if err := cursor.Decode(&journey); err != nil {
+				cursor.Close(context.Background())
+				return removed, err
+			}
+
+			journeyRefs = append(journeyRefs, journey.PrimaryIdentifier)
+		}
+		cursorErr := cursor.Err()
+		cursor.Close(context.Background())
+		if cursorErr != nil {
+			return removed, cursorErr
+		}
+
+		if len(journeyRefs) == 0 {
+			break
+		}
+
+		// Departures go first so nothing is left pointing at a journey that no longer exists
+		_, err = stopDeparturesCollection.DeleteMany(ctx, bson.M{"journeyref": bson.M{"$in": journeyRefs}})
+		if err != nil {
+			return removed, err
+		}
+
+		result, err := journeysCollection.DeleteMany(ctx, bson.M{
+			"primaryidentifier": bson.M{"$in": journeyRefs},
+			"validuntil":        bson.M{"$lt": cutoff},
+		})
+		if err != nil {
+			return removed, err
+		}
+		removed += int(result.DeletedCount)
+
+		if len(journeyRefs) < purgeExpiredBatchSize {
+			break
+		}
+	}
+
+	log.Info().Time("cutoff", cutoff).Int("removed", removed).Msg("Purged expired journeys")
+
+	return removed, nil
+}
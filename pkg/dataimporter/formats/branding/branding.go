@@ -0,0 +1,89 @@
+package branding
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Branding is a list of colours & logos to attach to existing Services & Operators.
+// Rows with a service name apply to that service of the operator, rows without one apply to the operator itself.
+type Branding struct {
+	Records []*Record
+}
+
+type Record struct {
+	OperatorRef string `csv:"operatorref"`
+	ServiceName string `csv:"servicename"`
+	Colour      string `csv:"colour"`
+	TextColour  string `csv:"textcolour"`
+	LogoURL     string `csv:"logourl"`
+}
+
+func (b *Branding) ParseFile(reader io.Reader) error {
+	return gocsv.Unmarshal(reader, &b.Records)
+}
+
+func (b *Branding) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Services && !dataset.SupportedObjects.Operators {
+		return errors.New("This format requires services or operators to be enabled")
+	}
+
+	servicesCollection := database.GetCollection("services")
+	operatorsCollection := database.GetCollection("operators")
+
+	var serviceOperations []mongo.WriteModel
+	var operatorOperations []mongo.WriteModel
+
+	for _, record := range b.Records {
+		branding := ctdf.NewBranding(record.Colour, record.TextColour, record.LogoURL)
+		if branding == nil || record.OperatorRef == "" {
+			continue
+		}
+
+		serviceName := strings.TrimSpace(record.ServiceName)
+
+		if serviceName == "" && dataset.SupportedObjects.Operators {
+			operatorOperations = append(operatorOperations, mongo.NewUpdateManyModel().
+				SetFilter(bson.M{"$or": bson.A{
+					bson.M{"primaryidentifier": record.OperatorRef},
+					bson.M{"otheridentifiers": record.OperatorRef},
+				}}).
+				SetUpdate(bson.M{"$set": bson.M{"branding": branding}}),
+			)
+		} else if serviceName != "" && dataset.SupportedObjects.Services {
+			serviceOperations = append(serviceOperations, mongo.NewUpdateManyModel().
+				SetFilter(bson.M{"operatorref": record.OperatorRef, "servicename": serviceName}).
+				SetUpdate(bson.M{"$set": bson.M{"branding": branding}}),
+			)
+		}
+	}
+
+	if len(serviceOperations) > 0 {
+		result, err := dataset.Sink.BulkWrite(servicesCollection, serviceOperations)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Int64("matched", result.MatchedCount).Msg("Updated Service branding")
+	}
+
+	if len(operatorOperations) > 0 {
+		result, err := dataset.Sink.BulkWrite(operatorsCollection, operatorOperations)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Int64("matched", result.MatchedCount).Msg("Updated Operator branding")
+	}
+
+	return nil
+}
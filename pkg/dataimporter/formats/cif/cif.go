@@ -15,13 +15,14 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/countries"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/names"
 	"github.com/travigo/travigo/pkg/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var suffixCheck = regexp.MustCompile(`^[2-9]+$`)
@@ -37,6 +38,9 @@ type CommonInterfaceFormat struct {
 	StationAliases   []StationAlias
 
 	TIPLOCToCrsMap map[string]string
+
+	// tiplocStops has every stop by its TIPLOC & CRS identifiers, loaded once at the start of an import
+	tiplocStops map[string]*ctdf.Stop
 }
 
 type Association struct {
@@ -72,7 +76,7 @@ func (c *CommonInterfaceFormat) ParseFile(reader io.Reader) error {
 
 		file, err := zipFile.Open()
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to open file")
+			return err
 		}
 		defer file.Close()
 
@@ -186,6 +190,9 @@ func (c *CommonInterfaceFormat) Import(dataset datasets.DataSet, datasource *ctd
 	}
 	log.Info().Msg("Converting to CTDF")
 
+	if err := c.loadTIPLOCStops(); err != nil {
+		return err
+	}
 	journeys := c.ConvertToCTDF()
 
 	log.Info().Msgf(" - %d Journeys", len(journeys))
@@ -217,19 +224,21 @@ func (c *CommonInterfaceFormat) Import(dataset datasets.DataSet, datasource *ctd
 			journey.ModificationDateTime = time.Now()
 			journey.DataSource = datasource
 
-			insertModel := mongo.NewInsertOneModel()
+			upsertModel := mongo.NewReplaceOneModel()
+			upsertModel.SetFilter(journey.GetUpsertFilter())
 
 			bsonRep, _ := bson.Marshal(journey)
-			insertModel.SetDocument(bsonRep)
+			upsertModel.SetReplacement(bsonRep)
+			upsertModel.SetUpsert(true)
 
-			operations = append(operations, insertModel)
+			operations = append(operations, upsertModel)
 			operationInsert += 1
 		}
 
 		if len(operations) > 0 {
-			_, err := journeysCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{})
+			_, err := dataset.Sink.BulkWrite(journeysCollection, operations)
 			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to bulk write Journeys")
+				return err
 			}
 		}
 	}
@@ -337,14 +346,16 @@ func (c *CommonInterfaceFormat) CreateJourneyFromTraindef(journeyID string, trai
 			DestinationArrivalTime: destinationArrivalTime,
 			DestinationPlatform:    strings.TrimSpace(destinationPassengerStop.Platform),
 
-			OriginActivity:      convertStopActivity(originPassengerStop.Activity),
-			DestinationActivity: convertStopActivity(destinationPassengerStop.Activity),
+			OriginActivity:      ConvertStopActivity(originPassengerStop.Activity),
+			DestinationActivity: ConvertStopActivity(destinationPassengerStop.Activity),
 		})
 	}
 
+	ctdf.CalculatePathDayOffsets(path)
+
 	destinationDisplay := "See Timetable"
 	if len(path) > 0 {
-		destinationDisplay = path[len(path)-1].DestinationStop.PrimaryName
+		destinationDisplay = names.ParseDestinationDisplay(path[len(path)-1].DestinationStop.PrimaryName).Display
 	}
 
 	// Calculate the base availability for this journey
@@ -374,74 +385,141 @@ func (c *CommonInterfaceFormat) CreateJourneyFromTraindef(journeyID string, trai
 	operatorRef := fmt.Sprintf(ctdf.OperatorTOCFormat, trainDef.BasicScheduleExtraDetails.ATOCCode)
 
 	////// Detailed rail information //////
+	detailedRailInformation := getDetailedRailInformation(trainDef.BasicSchedule.getTrainCharacteristics())
+
+	// Trains that change en route get a leg per change so the details are right for each part of the journey
+	for _, changeEnRoute := range trainDef.ChangesEnRoute {
+		tiploc := strings.TrimSpace(changeEnRoute.Location)
+		if len(tiploc) == 8 && suffixCheck.MatchString(tiploc[7:8]) {
+			tiploc = strings.TrimSpace(tiploc[0:7])
+		}
+
+		changeStop := c.getStopFromTIPLOC(tiploc)
+		if changeStop == nil {
+			continue
+		}
+
+		if len(detailedRailInformation.Legs) == 0 && len(path) > 0 {
+			originDetails := getDetailedRailInformation(trainDef.BasicSchedule.getTrainCharacteristics())
+			detailedRailInformation.Legs = append(detailedRailInformation.Legs, &ctdf.JourneyDetailedRailLeg{
+				FromStopRef: path[0].OriginStopRef,
+				Details:     &originDetails,
+			})
+		}
+
+		legDetails := getDetailedRailInformation(changeEnRoute.getTrainCharacteristics())
+		detailedRailInformation.Legs = append(detailedRailInformation.Legs, &ctdf.JourneyDetailedRailLeg{
+			FromStopRef: changeStop.PrimaryIdentifier,
+			Details:     &legDetails,
+		})
+	}
+
+	var transportType ctdf.TransportType = ctdf.TransportTypeRail
+	if detailedRailInformation.ReplacementBus {
+		transportType = ctdf.TransportTypeBus
+	}
+
+	// Put it all together
+	journey := &ctdf.Journey{
+		PrimaryIdentifier: journeyID,
+		OtherIdentifiers: map[string]string{
+			"TrainUID":         trainDef.BasicSchedule.TrainUID,
+			"TrainIdentity":    trainDef.BasicSchedule.TrainIdentity,
+			"HeadCode":         trainDef.BasicSchedule.Headcode,
+			"TrainServiceCode": trainDef.BasicSchedule.TrainServiceCode,
+		},
+		CreationDateTime:     time.Now(),
+		ModificationDateTime: time.Now(),
+		ServiceRef:           operatorRef,
+		OperatorRef:          operatorRef,
+		TransportType:        transportType,
+		DepartureTime:        departureTime,
+		DepartureTimezone:    countries.GB.Timezone,
+		DestinationDisplay:   destinationDisplay,
+		Availability:         availability,
+		ValidFrom:            dateRunsFrom,
+		ValidUntil:           dateRunsTo,
+		Path:                 path,
+
+		DetailedRailInformation: &detailedRailInformation,
+	}
+
+	return journey
+}
+
+// getDetailedRailInformation decodes the CIF train characteristics into the detailed rail information
+func getDetailedRailInformation(characteristics trainCharacteristics) ctdf.JourneyDetailedRail {
 	detailedRailInformation := ctdf.JourneyDetailedRail{
-		AirConditioning: strings.Contains(trainDef.BasicSchedule.OperatingCharacteristics, "R"),
+		AirConditioning: strings.Contains(characteristics.OperatingCharacteristics, "R"),
 
-		ReservationRequired:     strings.Contains(trainDef.BasicSchedule.Reservations, "A"),
-		ReservationBikeRequired: strings.Contains(trainDef.BasicSchedule.Reservations, "E"),
-		ReservationRecommended:  strings.Contains(trainDef.BasicSchedule.Reservations, "R"),
+		ReservationRequired:     strings.Contains(characteristics.Reservations, "A"),
+		ReservationBikeRequired: strings.Contains(characteristics.Reservations, "E"),
+		ReservationRecommended:  strings.Contains(characteristics.Reservations, "R"),
+		ReservationPossible:     strings.Contains(characteristics.Reservations, "S"),
 	}
 
 	// Seating type
-	if strings.TrimSpace(trainDef.BasicSchedule.SeatingClass) == "" || trainDef.BasicSchedule.SeatingClass == "B" {
+	if strings.TrimSpace(characteristics.SeatingClass) == "" || characteristics.SeatingClass == "B" {
 		detailedRailInformation.Seating = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingFirst, ctdf.JourneyDetailedRailSeatingStandard}
-	} else if trainDef.BasicSchedule.SeatingClass == "S" {
+	} else if characteristics.SeatingClass == "S" {
 		detailedRailInformation.Seating = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingStandard}
 	} else {
 		detailedRailInformation.Seating = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingUnknown}
 	}
 
 	// Sleepers
-	if trainDef.BasicSchedule.Sleepers == "B" {
+	if characteristics.Sleepers == "B" {
 		detailedRailInformation.SleeperAvailable = true
 		detailedRailInformation.Sleepers = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingFirst, ctdf.JourneyDetailedRailSeatingStandard}
-	} else if trainDef.BasicSchedule.Sleepers == "F" {
+	} else if characteristics.Sleepers == "F" {
 		detailedRailInformation.SleeperAvailable = true
 		detailedRailInformation.Sleepers = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingFirst}
-	} else if trainDef.BasicSchedule.Sleepers == "S" {
+	} else if characteristics.Sleepers == "S" {
 		detailedRailInformation.SleeperAvailable = true
 		detailedRailInformation.Sleepers = []ctdf.JourneyDetailedRailSeating{ctdf.JourneyDetailedRailSeatingStandard}
 	} else {
 		detailedRailInformation.SleeperAvailable = false
 	}
 
-	// Catering
+	// Catering, a train can have several codes at once
 	cateringDescriptions := []string{}
-	if strings.Contains(trainDef.BasicSchedule.CateringCode, "C") {
-		cateringDescriptions = append(cateringDescriptions, "Buffet service")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "F") {
-		cateringDescriptions = append(cateringDescriptions, "Restaurant Car available for First Class passengers")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "H") {
-		cateringDescriptions = append(cateringDescriptions, "Hot food available")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "M") {
-		cateringDescriptions = append(cateringDescriptions, "Meal included for First Class passengers")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "P") {
-		cateringDescriptions = append(cateringDescriptions, "Wheelchair only reservations")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "R") {
-		cateringDescriptions = append(cateringDescriptions, "Restaurant")
-		detailedRailInformation.CateringAvailable = true
-	} else if strings.Contains(trainDef.BasicSchedule.CateringCode, "T") {
-		cateringDescriptions = append(cateringDescriptions, "Trolley service")
+	for _, cateringCode := range characteristics.CateringCode {
+		switch cateringCode {
+		case 'C':
+			cateringDescriptions = append(cateringDescriptions, "Buffet service")
+		case 'F':
+			cateringDescriptions = append(cateringDescriptions, "Restaurant Car available for First Class passengers")
+		case 'H':
+			cateringDescriptions = append(cateringDescriptions, "Hot food available")
+		case 'M':
+			cateringDescriptions = append(cateringDescriptions, "Meal included for First Class passengers")
+		case 'R':
+			cateringDescriptions = append(cateringDescriptions, "Restaurant")
+		case 'T':
+			cateringDescriptions = append(cateringDescriptions, "Trolley service")
+		case 'P':
+			// Not actually catering, it marks trains that only take reservations for wheelchairs
+			detailedRailInformation.ReservationWheelchairOnly = true
+			continue
+		default:
+			continue
+		}
+
 		detailedRailInformation.CateringAvailable = true
 	}
 
 	detailedRailInformation.CateringDescription = strings.Join(cateringDescriptions, ". ")
 
 	// Speed
-	speedMPH, _ := strconv.Atoi(trainDef.BasicSchedule.Speed)
+	speedMPH, _ := strconv.Atoi(characteristics.Speed)
 	detailedRailInformation.SpeedKMH = int(float64(speedMPH) * 1.60934)
 
 	// Train class
 	trainClass := "unknown"
-	if trainDef.BasicSchedule.PowerType == "DMU" || trainDef.BasicSchedule.PowerType == "DEM" || trainDef.BasicSchedule.PowerType == "D  " {
+	if characteristics.PowerType == "DMU" || characteristics.PowerType == "DEM" || characteristics.PowerType == "D  " {
 		detailedRailInformation.PowerType = "Diesel"
 
-		switch strings.TrimSpace(trainDef.BasicSchedule.TimingLoad) {
+		switch strings.TrimSpace(characteristics.TimingLoad) {
 		case "69":
 			trainClass = "172"
 		case "A":
@@ -459,14 +537,14 @@ func (c *CommonInterfaceFormat) CreateJourneyFromTraindef(journeyID string, trai
 		case "X":
 			trainClass = "159"
 		case "":
-			trainClass = strings.TrimSpace(trainDef.BasicSchedule.PowerType)
+			trainClass = strings.TrimSpace(characteristics.PowerType)
 		default:
-			trainClass = strings.TrimSpace(trainDef.BasicSchedule.TimingLoad)
+			trainClass = strings.TrimSpace(characteristics.TimingLoad)
 		}
-	} else if trainDef.BasicSchedule.PowerType == "EMU" || trainDef.BasicSchedule.PowerType == "E  " {
+	} else if characteristics.PowerType == "EMU" || characteristics.PowerType == "E  " {
 		detailedRailInformation.PowerType = "Electric"
 
-		switch strings.TrimSpace(trainDef.BasicSchedule.TimingLoad) {
+		switch strings.TrimSpace(characteristics.TimingLoad) {
 		case "AT":
 			trainClass = "AT" // this shouldnt ever exist i believe
 		case "E":
@@ -476,11 +554,11 @@ func (c *CommonInterfaceFormat) CreateJourneyFromTraindef(journeyID string, trai
 		case "506":
 			trainClass = "350/1"
 		case "":
-			trainClass = strings.TrimSpace(trainDef.BasicSchedule.PowerType)
+			trainClass = strings.TrimSpace(characteristics.PowerType)
 		default:
-			trainClass = strings.TrimSpace(trainDef.BasicSchedule.TimingLoad)
+			trainClass = strings.TrimSpace(characteristics.TimingLoad)
 		}
-	} else if trainDef.BasicSchedule.PowerType == "HST" {
+	} else if characteristics.PowerType == "HST" {
 		trainClass = "HST"
 		detailedRailInformation.PowerType = "Diesel"
 	}
@@ -488,37 +566,62 @@ func (c *CommonInterfaceFormat) CreateJourneyFromTraindef(journeyID string, trai
 	detailedRailInformation.VehicleType = fmt.Sprintf("gb-railclass-%s", trainClass)
 
 	// Rail replacement bus
-	if trainDef.BasicSchedule.TrainCategory == "BR" {
+	if characteristics.TrainCategory == "BR" {
 		detailedRailInformation.ReplacementBus = true
 		detailedRailInformation.VehicleType = "gb-railclass-REPLACEMENTBUS"
 	}
 
-	// Put it all together
-	journey := &ctdf.Journey{
-		PrimaryIdentifier: journeyID,
-		OtherIdentifiers: map[string]string{
-			"TrainUID":         trainDef.BasicSchedule.TrainUID,
-			"TrainIdentity":    trainDef.BasicSchedule.TrainIdentity,
-			"HeadCode":         trainDef.BasicSchedule.Headcode,
-			"TrainServiceCode": trainDef.BasicSchedule.TrainServiceCode,
-		},
-		CreationDateTime:     time.Now(),
-		ModificationDateTime: time.Now(),
-		ServiceRef:           operatorRef,
-		OperatorRef:          operatorRef,
-		DepartureTime:        departureTime,
-		DepartureTimezone:    "Europe/London",
-		DestinationDisplay:   destinationDisplay,
-		Availability:         availability,
-		Path:                 path,
+	return detailedRailInformation
+}
 
-		DetailedRailInformation: &detailedRailInformation,
+// loadTIPLOCStops reads every stop with a TIPLOC or CRS code in one go so converting the journeys never has to query them
+func (c *CommonInterfaceFormat) loadTIPLOCStops() error {
+	cursor, err := database.GetCollection("stops").Find(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"otheridentifiers": bson.M{"$regex": "^gb-tiploc-"}},
+		bson.M{"otheridentifiers": bson.M{"$regex": "^gb-crs-"}},
+	}})
+	if err != nil {
+		return err
 	}
+	defer cursor.Close(context.Background())
 
-	return journey
+	c.tiplocStops = map[string]*ctdf.Stop{}
+
+	for cursor.Next(context.Background()) {
+		var stop *ctdf.Stop
+		if err := cursor.Decode(&stop); err != nil {
+			return err
+		}
+
+		for _, identifier := range stop.OtherIdentifiers {
+			if !strings.HasPrefix(identifier, "gb-tiploc-") && !strings.HasPrefix(identifier, "gb-crs-") {
+				continue
+			}
+
+			if _, exists := c.tiplocStops[identifier]; !exists {
+				c.tiplocStops[identifier] = stop
+			}
+		}
+	}
+
+	log.Info().Int("identifiers", len(c.tiplocStops)).Msg("Loaded TIPLOC stops")
+
+	return cursor.Err()
 }
 
 func (c *CommonInterfaceFormat) getStopFromTIPLOC(tiploc string) *ctdf.Stop {
+	if c.tiplocStops != nil {
+		stop := c.tiplocStops[fmt.Sprintf("gb-tiploc-%s", tiploc)]
+
+		// If cant directly find the stop using tiploc then use the MSN map to lookup by CRS
+		if stop == nil && c.TIPLOCToCrsMap[tiploc] != "" {
+			stop = c.tiplocStops[fmt.Sprintf("gb-crs-%s", c.TIPLOCToCrsMap[tiploc])]
+		}
+
+		return stop
+	}
+
+	// Single journeys converted outside of an import (ie. VSTP) don't load every stop so look them up as they go
 	cacheValue := stopTIPLOCCache[tiploc]
 
 	if cacheValue != nil {
@@ -540,7 +643,8 @@ func (c *CommonInterfaceFormat) getStopFromTIPLOC(tiploc string) *ctdf.Stop {
 	return stop
 }
 
-func convertStopActivity(activity string) []ctdf.JourneyPathItemActivity {
+// ConvertStopActivity turns a CIF activity code, also used by Darwin, in to whether passengers can get on or off
+func ConvertStopActivity(activity string) []ctdf.JourneyPathItemActivity {
 	activityList := []ctdf.JourneyPathItemActivity{}
 	if strings.TrimSpace(activity) == "TB" {
 		activityList = []ctdf.JourneyPathItemActivity{
@@ -85,15 +85,15 @@ type ChangesEnRoute struct {
 	// CourseIndicator string
 	// ProfitCentreCode string
 	// BusinessSector   string
-	// PowerType        string
-	// TimingLoad       string
-	// Speed string
-	// OperatingChars   string
-	// TrainClass       string
-	// Sleepers         string
-	// Reservations     string
+	PowerType        string
+	TimingLoad       string
+	Speed            string
+	OperatingChars   string
+	TrainClass       string
+	Sleepers         string
+	Reservations     string
 	ConnectIndicator string
-	// CateringCode     string
+	CateringCode     string
 	// ServiceBranding  string
 	// TractionClass    string
 	// UICCode          string
@@ -235,15 +235,15 @@ func (c *CommonInterfaceFormat) ParseMCA(reader io.Reader) {
 				// CourseIndicator:  line[20:28],
 				// ProfitCentreCode: line[28:29],
 				// BusinessSector:   line[29:30],
-				// PowerType:        line[30:33],
-				// TimingLoad:       line[33:37],
-				// Speed:            line[37:40],
-				// OperatingChars:   line[40:46],
-				// TrainClass:       line[46:47],
-				// Sleepers:         line[47:48],
-				// Reservations:     line[48:49],
+				PowerType:        line[30:33],
+				TimingLoad:       line[33:37],
+				Speed:            line[37:40],
+				OperatingChars:   line[40:46],
+				TrainClass:       line[46:47],
+				Sleepers:         line[47:48],
+				Reservations:     line[48:49],
 				ConnectIndicator: line[49:50],
-				// CateringCode:     line[50:54],
+				CateringCode:     line[50:54],
 				// ServiceBranding:  line[54:58],
 				// TractionClass:    line[58:62],
 				// UICCode:          line[62:67],
@@ -269,3 +269,44 @@ func (c *CommonInterfaceFormat) ParseMCA(reader io.Reader) {
 		}
 	}
 }
+
+// trainCharacteristics are the parts of a schedule describing the train itself, which a CR record can change en route
+type trainCharacteristics struct {
+	TrainCategory            string
+	PowerType                string
+	TimingLoad               string
+	Speed                    string
+	OperatingCharacteristics string
+	SeatingClass             string
+	Sleepers                 string
+	Reservations             string
+	CateringCode             string
+}
+
+func (b *BasicSchedule) getTrainCharacteristics() trainCharacteristics {
+	return trainCharacteristics{
+		TrainCategory:            b.TrainCategory,
+		PowerType:                b.PowerType,
+		TimingLoad:               b.TimingLoad,
+		Speed:                    b.Speed,
+		OperatingCharacteristics: b.OperatingCharacteristics,
+		SeatingClass:             b.SeatingClass,
+		Sleepers:                 b.Sleepers,
+		Reservations:             b.Reservations,
+		CateringCode:             b.CateringCode,
+	}
+}
+
+func (c *ChangesEnRoute) getTrainCharacteristics() trainCharacteristics {
+	return trainCharacteristics{
+		TrainCategory:            c.TrainCategory,
+		PowerType:                c.PowerType,
+		TimingLoad:               c.TimingLoad,
+		Speed:                    c.Speed,
+		OperatingCharacteristics: c.OperatingChars,
+		SeatingClass:             c.TrainClass,
+		Sleepers:                 c.Sleepers,
+		Reservations:             c.Reservations,
+		CateringCode:             c.CateringCode,
+	}
+}
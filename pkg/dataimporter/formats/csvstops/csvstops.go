@@ -0,0 +1,235 @@
+package csvstops
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const importBatchSize = 1000
+
+// The columns each field is read from unless the dataset maps it to another, eg. column-name: "Stop Name"
+var defaultColumns = map[string]string{
+	"id":               "id",
+	"name":             "name",
+	"lat":              "lat",
+	"lon":              "lon",
+	"type":             "type",
+	"otheridentifiers": "otheridentifiers",
+}
+
+// Columns that have to be present in the header, the rest are optional
+var requiredColumns = []string{"id", "name", "lat", "lon"}
+
+// CSVStops is a simple list of stops, one per row, for community maintained stop lists that don't come in any standard format.
+// Other identifiers (eg. gb-crs-XXX) are separated by semicolons and let the stops linker merge the rows with the same
+// stop from other sources
+type CSVStops struct {
+	Header []string
+	Rows   [][]string
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+}
+
+func (c *CSVStops) SetupProgress(tracker *progress.Tracker) {
+	c.progress = tracker
+}
+
+func (c *CSVStops) SetupErrors(collector *importerrors.Collector) {
+	c.errors = collector
+}
+
+func (c *CSVStops) ParseFile(reader io.Reader) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	c.Header = header
+
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if err := c.errors.Add(&importerrors.ParseError{Record: "row", Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.Rows = append(c.Rows, row)
+		c.progress.AddRecords(1)
+	}
+
+	return nil
+}
+
+func (c *CSVStops) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Stops {
+		return errors.New("This format requires stops to be enabled")
+	}
+
+	columns, err := c.getColumnIndexes(dataset)
+	if err != nil {
+		return err
+	}
+
+	// Rows without a type get this one, eg. a list of coach stops
+	defaultTransportType := dataset.CustomConfig["transporttype"]
+
+	stopsCollection := database.GetCollection("stops_raw")
+	var operations []mongo.WriteModel
+	var imported int
+	now := time.Now()
+
+	for i, row := range c.Rows {
+		// Header is the first line
+		rowName := fmt.Sprintf("row %d", i+2)
+
+		getValue := func(field string) string {
+			index, exists := columns[field]
+			if !exists || index >= len(row) {
+				return ""
+			}
+
+			return strings.TrimSpace(row[index])
+		}
+
+		id := getValue("id")
+		name := getValue("name")
+		if id == "" || name == "" {
+			if err := c.errors.Add(&importerrors.ParseError{Record: rowName, Err: errors.New("id & name must be set")}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		latitude, latitudeErr := strconv.ParseFloat(getValue("lat"), 64)
+		longitude, longitudeErr := strconv.ParseFloat(getValue("lon"), 64)
+		if latitudeErr != nil || longitudeErr != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			if err := c.errors.Add(&importerrors.ParseError{Record: rowName, Err: errors.New(fmt.Sprintf("invalid location %q, %q", getValue("lat"), getValue("lon")))}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		stopType := getValue("type")
+		if stopType == "" {
+			stopType = defaultTransportType
+		}
+		var transportTypes []ctdf.TransportType
+		if stopType != "" {
+			transportType := ctdf.ParseTransportType(stopType)
+			if transportType == ctdf.TransportTypeUnknown {
+				if err := c.errors.Add(&importerrors.ParseError{Record: rowName, Err: errors.New(fmt.Sprintf("unknown type %q", stopType))}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			transportTypes = []ctdf.TransportType{transportType}
+		}
+
+		stopID := getStopRef(dataset.GetIdentifierPrefix(), id)
+
+		otherIdentifiers := []string{stopID}
+		for _, otherIdentifier := range strings.Split(getValue("otheridentifiers"), ";") {
+			if otherIdentifier = strings.TrimSpace(otherIdentifier); otherIdentifier != "" {
+				otherIdentifiers = append(otherIdentifiers, otherIdentifier)
+			}
+		}
+
+		stop := &ctdf.Stop{
+			PrimaryIdentifier:    stopID,
+			OtherIdentifiers:     otherIdentifiers,
+			CreationDateTime:     now,
+			ModificationDateTime: now,
+			DataSource:           datasource,
+			PrimaryName:          name,
+			TransportTypes:       transportTypes,
+			Location: &ctdf.Location{
+				Type:        "Point",
+				Coordinates: []float64{longitude, latitude},
+			},
+			Active:   true,
+			StopType: ctdf.StopTypeStop,
+		}
+		stop.UpdatePlusCode()
+
+		bsonRep, _ := bson.Marshal(bson.M{"$set": stop})
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": stopID})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		operations = append(operations, updateModel)
+		imported += 1
+
+		if len(operations) >= importBatchSize {
+			if _, err := dataset.Sink.BulkWrite(stopsCollection, operations); err != nil {
+				return err
+			}
+			operations = []mongo.WriteModel{}
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(stopsCollection, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("stops", imported).Int("rows", len(c.Rows)).Msg("Imported CSV stops")
+
+	return nil
+}
+
+// getColumnIndexes works out which column each field is in from the header, using the datasets column mapping
+func (c *CSVStops) getColumnIndexes(dataset datasets.DataSet) (map[string]int, error) {
+	headerIndexes := map[string]int{}
+	for i, column := range c.Header {
+		headerIndexes[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	columns := map[string]int{}
+	for field, defaultColumn := range defaultColumns {
+		column := defaultColumn
+		if mappedColumn := dataset.CustomConfig[fmt.Sprintf("column-%s", field)]; mappedColumn != "" {
+			column = mappedColumn
+		}
+
+		if index, exists := headerIndexes[strings.ToLower(column)]; exists {
+			columns[field] = index
+		}
+	}
+
+	for _, field := range requiredColumns {
+		if _, exists := columns[field]; !exists {
+			return nil, errors.New(fmt.Sprintf("CSV is missing the %s column", field))
+		}
+	}
+
+	return columns, nil
+}
+
+func getStopRef(datasetIdentifier string, id string) string {
+	return fmt.Sprintf("%s-stop-%s", datasetIdentifier, strings.ReplaceAll(id, " ", "_"))
+}
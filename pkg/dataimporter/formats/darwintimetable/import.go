@@ -0,0 +1,267 @@
+package darwintimetable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/countries"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/cif"
+	"github.com/travigo/travigo/pkg/names"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 200
+const trainUIDLookupBatchSize = 1000
+
+// JourneyIDFormat is the primary identifier of journeys only found in the Darwin timetable, from their RID
+const JourneyIDFormat = "gb-darwin-%s"
+
+// Import only writes the journeys the CIF doesn't already have for the day, so theres never two copies of the
+// same train. Each one carries its RID so realtime messages for it can be matched exactly
+func (t *Timetable) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Journeys {
+		return errors.New("This format requires journeys to be enabled")
+	}
+
+	t.lookups = dataset.Lookups
+
+	scheduledJourneys, err := getScheduledJourneys(t.Journeys, datasource.DatasetID)
+	if err != nil {
+		return err
+	}
+
+	journeysCollection := database.GetCollection("journeys")
+
+	var operations []mongo.WriteModel
+	inserts := 0
+	alreadyScheduled := 0
+
+	for _, darwinJourney := range t.Journeys {
+		if darwinJourney.Deleted == "true" || darwinJourney.IsPassengerService == "false" {
+			continue
+		}
+		if !cif.IsValidPassengerJourney(darwinJourney.TrainCategory, darwinJourney.TOC) {
+			continue
+		}
+
+		runDate, err := time.Parse(time.DateOnly, darwinJourney.SSD)
+		if err != nil {
+			log.Error().Err(err).Str("rid", darwinJourney.RID).Msg("Failed to parse journey start date")
+			continue
+		}
+
+		if isAlreadyScheduled(scheduledJourneys[darwinJourney.UID], runDate) {
+			alreadyScheduled += 1
+			continue
+		}
+
+		journey := t.convertJourney(darwinJourney, runDate)
+		if journey == nil {
+			continue
+		}
+		journey.DataSource = datasource
+
+		bsonRep, _ := bson.Marshal(journey)
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(journey.GetUpsertFilter()).
+			SetReplacement(bsonRep).
+			SetUpsert(true),
+		)
+		inserts += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := dataset.Sink.BulkWrite(journeysCollection, operations); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(journeysCollection, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Str("timetable", t.TimetableID).
+		Int("journeys", len(t.Journeys)).
+		Int("alreadyscheduled", alreadyScheduled).
+		Int("inserts", inserts).
+		Msg("Imported Darwin timetable journeys")
+
+	return nil
+}
+
+func (t *Timetable) convertJourney(darwinJourney *Journey, runDate time.Time) *ctdf.Journey {
+	var passengerStops []Location
+	for _, location := range darwinJourney.Locations {
+		if location.IsPassengerStop() {
+			passengerStops = append(passengerStops, location)
+		}
+	}
+
+	var path []*ctdf.JourneyPathItem
+	for i := 1; i < len(passengerStops); i++ {
+		origin := passengerStops[i-1]
+		destination := passengerStops[i]
+
+		originStop := t.getStopFromTIPLOC(origin.Tiploc)
+		destinationStop := t.getStopFromTIPLOC(destination.Tiploc)
+		if originStop == nil || destinationStop == nil {
+			continue
+		}
+
+		originArrivalTime, _ := time.Parse("15:04", origin.PublicArrival)
+		originDepartureTime, _ := time.Parse("15:04", origin.PublicDeparture)
+		destinationArrivalTime, _ := time.Parse("15:04", destination.PublicArrival)
+
+		// Origins only have a departure time
+		if origin.PublicArrival == "" {
+			originArrivalTime = originDepartureTime
+		}
+
+		path = append(path, &ctdf.JourneyPathItem{
+			OriginStop:          originStop,
+			OriginStopRef:       originStop.PrimaryIdentifier,
+			OriginArrivalTime:   originArrivalTime,
+			OriginDepartureTime: originDepartureTime,
+			OriginPlatform:      origin.Platform,
+
+			DestinationStop:        destinationStop,
+			DestinationStopRef:     destinationStop.PrimaryIdentifier,
+			DestinationArrivalTime: destinationArrivalTime,
+			DestinationPlatform:    destination.Platform,
+
+			OriginActivity:      cif.ConvertStopActivity(origin.Activity),
+			DestinationActivity: cif.ConvertStopActivity(destination.Activity),
+		})
+	}
+
+	if len(path) == 0 {
+		return nil
+	}
+
+	ctdf.CalculatePathDayOffsets(path)
+
+	var transportType ctdf.TransportType = ctdf.TransportTypeRail
+	if darwinJourney.TrainCategory == "BR" {
+		transportType = ctdf.TransportTypeBus
+	}
+
+	operatorRef := fmt.Sprintf(ctdf.OperatorTOCFormat, darwinJourney.TOC)
+	now := time.Now()
+
+	return &ctdf.Journey{
+		PrimaryIdentifier: fmt.Sprintf(JourneyIDFormat, darwinJourney.RID),
+		OtherIdentifiers: map[string]string{
+			"DarwinRID":     darwinJourney.RID,
+			"TrainUID":      darwinJourney.UID,
+			"TrainIdentity": darwinJourney.TrainID,
+		},
+		CreationDateTime:     now,
+		ModificationDateTime: now,
+		ServiceRef:           operatorRef,
+		OperatorRef:          operatorRef,
+		TransportType:        transportType,
+		DepartureTime:        path[0].OriginDepartureTime,
+		DepartureTimezone:    countries.GB.Timezone,
+		DestinationDisplay:   names.ParseDestinationDisplay(path[len(path)-1].DestinationStop.PrimaryName).Display,
+		Availability: &ctdf.Availability{
+			Match: []ctdf.AvailabilityRule{{
+				Type:  ctdf.AvailabilityDate,
+				Value: runDate.Format(time.DateOnly),
+			}},
+		},
+		ValidFrom:  runDate,
+		ValidUntil: runDate,
+		Path:       path,
+	}
+}
+
+func (t *Timetable) getStopFromTIPLOC(tiploc string) *ctdf.Stop {
+	identifier := fmt.Sprintf("gb-tiploc-%s", tiploc)
+
+	if t.lookups != nil {
+		record := t.lookups.Stops().Get(identifier)
+		if record == nil {
+			return nil
+		}
+
+		return &ctdf.Stop{
+			PrimaryIdentifier: record.PrimaryIdentifier,
+			PrimaryName:       record.PrimaryName,
+		}
+	}
+
+	var stop *ctdf.Stop
+	database.GetCollection("stops").FindOne(context.Background(), bson.M{"otheridentifiers": identifier}).Decode(&stop)
+
+	return stop
+}
+
+// getScheduledJourneys loads the journeys other datasets (ie. the CIF) have for the trains in the timetable
+func getScheduledJourneys(darwinJourneys []*Journey, datasetID string) (map[string][]*ctdf.Journey, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	uniqueUIDs := map[string]bool{}
+	for _, darwinJourney := range darwinJourneys {
+		uniqueUIDs[darwinJourney.UID] = true
+	}
+
+	var uids []string
+	for uid := range uniqueUIDs {
+		uids = append(uids, uid)
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "otheridentifiers.TrainUID", Value: 1},
+		bson.E{Key: "availability", Value: 1},
+		bson.E{Key: "validfrom", Value: 1},
+		bson.E{Key: "validuntil", Value: 1},
+	})
+
+	scheduledJourneys := map[string][]*ctdf.Journey{}
+	for lower := 0; lower < len(uids); lower += trainUIDLookupBatchSize {
+		batch := uids[lower:min(lower+trainUIDLookupBatchSize, len(uids))]
+
+		cursor, err := journeysCollection.Find(context.Background(), bson.M{
+			"otheridentifiers.TrainUID": bson.M{"$in": batch},
+			"datasource.datasetid":      bson.M{"$ne": datasetID},
+		}, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next(context.Background()) {
+			var journey *ctdf.Journey
+			if err := cursor.Decode(&journey); err != nil {
+				continue
+			}
+
+			uid := journey.OtherIdentifiers["TrainUID"]
+			scheduledJourneys[uid] = append(scheduledJourneys[uid], journey)
+		}
+		cursor.Close(context.Background())
+	}
+
+	return scheduledJourneys, nil
+}
+
+func isAlreadyScheduled(journeys []*ctdf.Journey, runDate time.Time) bool {
+	for _, journey := range journeys {
+		if journey.RunsOn(runDate) {
+			return true
+		}
+	}
+
+	return false
+}
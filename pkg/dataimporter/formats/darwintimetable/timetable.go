@@ -0,0 +1,93 @@
+package darwintimetable
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/travigo/travigo/pkg/dataimporter/lookup"
+)
+
+// Timetable is a Darwin timetable reference file (PPTimetable), the schedule of every train Darwin knows about
+// for the day including ones planned at short notice (VSTP) that never make it in to the CIF
+type Timetable struct {
+	TimetableID string
+	Journeys    []*Journey
+
+	lookups *lookup.Tables
+}
+
+type Journey struct {
+	RID           string `xml:"rid,attr"`
+	UID           string `xml:"uid,attr"`
+	TrainID       string `xml:"trainId,attr"`
+	SSD           string `xml:"ssd,attr"`
+	TOC           string `xml:"toc,attr"`
+	TrainCategory string `xml:"trainCat,attr"`
+
+	// Both default to true/false when missing
+	IsPassengerService string `xml:"isPassengerSvc,attr"`
+	Deleted            string `xml:"deleted,attr"`
+	Cancelled          string `xml:"can,attr"`
+
+	// Every calling point in order, operational & passing points are mixed in with the passenger ones
+	Locations []Location `xml:",any"`
+}
+
+type Location struct {
+	XMLName xml.Name
+
+	Tiploc   string `xml:"tpl,attr"`
+	Activity string `xml:"act,attr"`
+	Platform string `xml:"plat,attr"`
+
+	PublicArrival   string `xml:"pta,attr"`
+	PublicDeparture string `xml:"ptd,attr"`
+
+	Cancelled string `xml:"can,attr"`
+}
+
+// IsPassengerStop is whether the location is one passengers can use, rather than an operational stop or passing point
+func (l *Location) IsPassengerStop() bool {
+	switch l.XMLName.Local {
+	case "OR", "IP", "DT":
+		return l.Cancelled != "true" && (l.PublicArrival != "" || l.PublicDeparture != "")
+	default:
+		return false
+	}
+}
+
+// ParseFile reads the journeys out of the file one at a time as the full timetable is too big to unmarshal in one go
+func (t *Timetable) ParseFile(reader io.Reader) error {
+	decoder := xml.NewDecoder(reader)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch element.Name.Local {
+		case "PportTimetable":
+			for _, attr := range element.Attr {
+				if attr.Name.Local == "timetableID" {
+					t.TimetableID = attr.Value
+				}
+			}
+		case "Journey":
+			var journey Journey
+			if err := decoder.DecodeElement(&journey, &element); err != nil {
+				return err
+			}
+
+			t.Journeys = append(t.Journeys, &journey)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/adjust/rmq/v5"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
 )
 
 type Format interface {
@@ -17,3 +19,21 @@ type RealtimeQueueFormat interface {
 	Format
 	SetupRealtimeQueue(rmq.Queue)
 }
+
+type ProgressFormat interface {
+	Format
+	SetupProgress(*progress.Tracker)
+}
+
+// ErrorCollectingFormat skips over records that fail to parse, reporting them into the collector instead
+type ErrorCollectingFormat interface {
+	Format
+	SetupErrors(*importerrors.Collector)
+}
+
+// StreamingFormat imports records as they're read instead of parsing the whole file into memory first,
+// when a format implements it ImportStream is used in place of ParseFile & Import
+type StreamingFormat interface {
+	Format
+	ImportStream(io.Reader, datasets.DataSet, *ctdf.DataSourceReference) error
+}
@@ -0,0 +1,160 @@
+package gbfs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultAvailabilityInterval is how often the station status is polled when the dataset doesn't set a refresh interval
+const DefaultAvailabilityInterval = 1 * time.Minute
+
+// The discovery file rarely changes so is only fetched again every so often
+const discoveryRefreshInterval = 1 * time.Hour
+
+// PollAvailability keeps the dock availability of the dataset up to date until the context is cancelled
+func PollAvailability(ctx context.Context, dataset datasets.DataSet) {
+	interval := dataset.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultAvailabilityInterval
+	}
+
+	var stationStatusURL string
+	var vehicleTypes map[string]ctdf.TransportType
+	var discoveredAt time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if stationStatusURL == "" || time.Since(discoveredAt) > discoveryRefreshInterval {
+			var discovery Discovery
+			if err := fetchFeed(ctx, &dataset, dataset.Source, &discovery); err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to fetch GBFS discovery")
+			} else {
+				stationStatusURL = discovery.GetFeedURL("station_status")
+				vehicleTypes = getVehicleTypes(ctx, dataset, &discovery)
+				discoveredAt = time.Now()
+			}
+		}
+
+		if stationStatusURL != "" {
+			updated, err := UpdateAvailability(ctx, dataset, stationStatusURL, vehicleTypes)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to update GBFS dock availability")
+			} else {
+				log.Info().Str("dataset", dataset.Identifier).Int("docks", updated).Msg("Updated GBFS dock availability")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UpdateAvailability fetches the station status & stores the availability of every dock in it
+func UpdateAvailability(ctx context.Context, dataset datasets.DataSet, stationStatusURL string, vehicleTypes map[string]ctdf.TransportType) (int, error) {
+	if stationStatusURL == "" {
+		return 0, errors.New("Feed has no station_status")
+	}
+
+	var stationStatus StationStatus
+	if err := fetchFeed(ctx, &dataset, stationStatusURL, &stationStatus); err != nil {
+		return 0, err
+	}
+
+	datasource := &ctdf.DataSourceReference{
+		OriginalFormat: string(dataset.Format),
+		ProviderName:   dataset.Provider.Name,
+		ProviderID:     dataset.DataSourceRef,
+		DatasetID:      dataset.Identifier,
+	}
+	now := time.Now()
+
+	var operations []mongo.WriteModel
+	for _, status := range stationStatus.Data.Stations {
+		availability := status.toDockAvailability(dataset.Identifier, vehicleTypes)
+		availability.ModificationDateTime = now
+		availability.DataSource = datasource
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"stopref": availability.StopRef}).
+			SetReplacement(availability).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) == 0 {
+		return 0, nil
+	}
+
+	collection := database.GetCollection("dock_availabilities")
+	_, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+
+	return len(operations), err
+}
+
+func (s *StationStatusRecord) toDockAvailability(datasetIdentifier string, vehicleTypes map[string]ctdf.TransportType) *ctdf.DockAvailability {
+	vehiclesAvailable := s.NumVehiclesAvailable
+	if vehiclesAvailable == 0 {
+		vehiclesAvailable = s.NumBikesAvailable
+	}
+
+	availability := &ctdf.DockAvailability{
+		StopRef:           getStopRef(datasetIdentifier, s.ID),
+		VehiclesAvailable: vehiclesAvailable,
+		DocksAvailable:    s.NumDocksAvailable,
+		IsInstalled:       bool(s.IsInstalled),
+		IsRenting:         bool(s.IsRenting),
+		IsReturning:       bool(s.IsReturning),
+		LastReported:      time.Time(s.LastReported),
+	}
+
+	if len(s.VehicleTypesAvailable) > 0 {
+		availability.VehicleTypesAvailable = map[ctdf.TransportType]int{}
+
+		for _, vehicleTypeCount := range s.VehicleTypesAvailable {
+			transportType, exists := vehicleTypes[vehicleTypeCount.VehicleTypeID]
+			if !exists {
+				transportType = ctdf.TransportTypeBikeShare
+			}
+
+			availability.VehicleTypesAvailable[transportType] += vehicleTypeCount.Count
+		}
+	}
+
+	return availability
+}
+
+// getVehicleTypes maps the vehicle types of the feed to transport types, they're rarely published so failing to get them isn't an error
+func getVehicleTypes(ctx context.Context, dataset datasets.DataSet, discovery *Discovery) map[string]ctdf.TransportType {
+	vehicleTypes := map[string]ctdf.TransportType{}
+
+	vehicleTypesURL := discovery.GetFeedURL("vehicle_types")
+	if vehicleTypesURL == "" {
+		return vehicleTypes
+	}
+
+	var vehicleTypesFeed VehicleTypes
+	if err := fetchFeed(ctx, &dataset, vehicleTypesURL, &vehicleTypesFeed); err != nil {
+		log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to fetch GBFS vehicle types")
+		return vehicleTypes
+	}
+
+	for _, vehicleType := range vehicleTypesFeed.Data.VehicleTypes {
+		vehicleTypes[vehicleType.ID] = vehicleType.GetTransportType()
+	}
+
+	return vehicleTypes
+}
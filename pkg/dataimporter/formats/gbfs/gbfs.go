@@ -0,0 +1,154 @@
+package gbfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const feedTimeout = 30 * time.Second
+
+// GBFS imports the docks of a bike or scooter share scheme as stops. The dataset source is the gbfs.json
+// auto-discovery file, it's regenerated with a new last_updated on every publish so the import isn't skipped
+// as unchanged. Dock availability comes from the station status which is polled separately by PollAvailability.
+type GBFS struct {
+	Discovery Discovery
+}
+
+func (g *GBFS) ParseFile(reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(&g.Discovery)
+}
+
+func (g *GBFS) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Stops {
+		return errors.New("This format requires stops to be enabled")
+	}
+
+	stationInformationURL := g.Discovery.GetFeedURL("station_information")
+	if stationInformationURL == "" {
+		return errors.New("Feed has no station_information")
+	}
+
+	var stationInformation StationInformation
+	if err := fetchFeed(dataset.Context, &dataset, stationInformationURL, &stationInformation); err != nil {
+		return err
+	}
+
+	// Vehicle types are optional, without them every dock is assumed to be for bikes
+	vehicleTypes := getVehicleTypes(dataset.Context, dataset, &g.Discovery)
+
+	collection := database.GetCollection("stops_raw")
+	var operations []mongo.WriteModel
+	now := time.Now()
+
+	for _, station := range stationInformation.Data.Stations {
+		stopID := getStopRef(dataset.Identifier, station.ID)
+
+		stop := &ctdf.Stop{
+			PrimaryIdentifier:    stopID,
+			OtherIdentifiers:     []string{stopID},
+			CreationDateTime:     now,
+			ModificationDateTime: now,
+			DataSource:           datasource,
+			PrimaryName:          string(station.Name),
+			Descriptor:           station.Address,
+			TransportTypes:       station.getTransportTypes(vehicleTypes),
+			Location: &ctdf.Location{
+				Type:        "Point",
+				Coordinates: []float64{station.Longitude, station.Latitude},
+			},
+			Active:   true,
+			StopType: ctdf.StopTypeDock,
+		}
+		stop.UpdatePlusCode()
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": stopID}).
+			SetReplacement(stop).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(collection, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("dataset", dataset.Identifier).Int("docks", len(operations)).Msg("Imported GBFS docks")
+
+	return nil
+}
+
+func (s *Station) getTransportTypes(vehicleTypes map[string]ctdf.TransportType) []ctdf.TransportType {
+	var vehicleTypeIDs []string
+	for _, capacity := range s.VehicleTypeCapacity {
+		vehicleTypeIDs = append(vehicleTypeIDs, capacity.VehicleTypeID)
+	}
+	for _, capacity := range s.VehicleTypesCapacity {
+		vehicleTypeIDs = append(vehicleTypeIDs, capacity.VehicleTypeIDs...)
+	}
+
+	var transportTypes []ctdf.TransportType
+	for _, vehicleTypeID := range vehicleTypeIDs {
+		transportType, exists := vehicleTypes[vehicleTypeID]
+		if exists && !slices.Contains(transportTypes, transportType) {
+			transportTypes = append(transportTypes, transportType)
+		}
+	}
+
+	if len(transportTypes) == 0 {
+		return []ctdf.TransportType{ctdf.TransportTypeBikeShare}
+	}
+
+	return transportTypes
+}
+
+func getStopRef(datasetIdentifier string, stationID string) string {
+	return fmt.Sprintf("%s-stop-%s", datasetIdentifier, stationID)
+}
+
+// fetchFeed downloads one of the files listed in the discovery file, using the same headers as the dataset source
+func fetchFeed(ctx context.Context, dataset *datasets.DataSet, url string, destination interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("user-agent", "curl/7.54.1")
+
+	env := util.GetEnvironmentVariables()
+	for headerKey, headerValue := range dataset.SourceAuthentication.Header {
+		req.Header.Set(headerKey, env[headerValue])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Fetching %s returned status %d", url, resp.StatusCode))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(destination)
+}
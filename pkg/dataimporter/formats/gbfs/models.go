@@ -0,0 +1,219 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// Discovery is the gbfs.json auto-discovery file listing the other files in the feed.
+// Version 3 lists the feeds directly while older versions group them by language.
+type Discovery struct {
+	LastUpdated json.RawMessage `json:"last_updated"`
+	Version     string          `json:"version"`
+
+	Data struct {
+		Feeds []Feed `json:"feeds"`
+
+		Languages map[string]struct {
+			Feeds []Feed `json:"feeds"`
+		} `json:"-"`
+	} `json:"data"`
+}
+
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (d *Discovery) UnmarshalJSON(data []byte) error {
+	type discovery Discovery
+	if err := json.Unmarshal(data, (*discovery)(d)); err != nil {
+		return err
+	}
+
+	if len(d.Data.Feeds) > 0 {
+		return nil
+	}
+
+	var languages struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &languages); err != nil {
+		return err
+	}
+
+	d.Data.Languages = map[string]struct {
+		Feeds []Feed `json:"feeds"`
+	}{}
+	for language, languageData := range languages.Data {
+		var languageFeeds struct {
+			Feeds []Feed `json:"feeds"`
+		}
+		if json.Unmarshal(languageData, &languageFeeds) == nil && len(languageFeeds.Feeds) > 0 {
+			d.Data.Languages[language] = languageFeeds
+		}
+	}
+
+	return nil
+}
+
+// GetFeedURL finds the file in the feed, preferring English when it is split by language
+func (d *Discovery) GetFeedURL(name string) string {
+	feeds := d.Data.Feeds
+
+	if len(feeds) == 0 {
+		if english, exists := d.Data.Languages["en"]; exists {
+			feeds = english.Feeds
+		} else {
+			for _, language := range d.Data.Languages {
+				feeds = language.Feeds
+				break
+			}
+		}
+	}
+
+	for _, feed := range feeds {
+		if feed.Name == name {
+			return feed.URL
+		}
+	}
+
+	return ""
+}
+
+type StationInformation struct {
+	Data struct {
+		Stations []Station `json:"stations"`
+	} `json:"data"`
+}
+
+type Station struct {
+	ID        string          `json:"station_id"`
+	Name      LocalisedString `json:"name"`
+	ShortName LocalisedString `json:"short_name"`
+	Latitude  float64         `json:"lat"`
+	Longitude float64         `json:"lon"`
+	Address   string          `json:"address"`
+	Capacity  int             `json:"capacity"`
+
+	VehicleTypeCapacity  []VehicleTypeCount `json:"vehicle_type_capacity"`
+	VehicleTypesCapacity []struct {
+		VehicleTypeIDs []string `json:"vehicle_type_ids"`
+		Count          int      `json:"count"`
+	} `json:"vehicle_types_capacity"`
+}
+
+type StationStatus struct {
+	Data struct {
+		Stations []StationStatusRecord `json:"stations"`
+	} `json:"data"`
+}
+
+type StationStatusRecord struct {
+	ID string `json:"station_id"`
+
+	// Version 3 renamed bikes to vehicles
+	NumBikesAvailable    int `json:"num_bikes_available"`
+	NumVehiclesAvailable int `json:"num_vehicles_available"`
+	NumDocksAvailable    int `json:"num_docks_available"`
+
+	VehicleTypesAvailable []VehicleTypeCount `json:"vehicle_types_available"`
+
+	IsInstalled Boolean `json:"is_installed"`
+	IsRenting   Boolean `json:"is_renting"`
+	IsReturning Boolean `json:"is_returning"`
+
+	LastReported Timestamp `json:"last_reported"`
+}
+
+type VehicleTypeCount struct {
+	VehicleTypeID string `json:"vehicle_type_id"`
+	Count         int    `json:"count"`
+}
+
+type VehicleTypes struct {
+	Data struct {
+		VehicleTypes []VehicleType `json:"vehicle_types"`
+	} `json:"data"`
+}
+
+type VehicleType struct {
+	ID         string `json:"vehicle_type_id"`
+	FormFactor string `json:"form_factor"`
+}
+
+func (v *VehicleType) GetTransportType() ctdf.TransportType {
+	switch v.FormFactor {
+	case "scooter", "scooter_standing", "scooter_seated", "moped":
+		return ctdf.TransportTypeScooterShare
+	default:
+		return ctdf.TransportTypeBikeShare
+	}
+}
+
+// LocalisedString is a plain string in older versions and a list of translations from version 3
+type LocalisedString string
+
+func (l *LocalisedString) UnmarshalJSON(data []byte) error {
+	var plain string
+	if err := json.Unmarshal(data, &plain); err == nil {
+		*l = LocalisedString(plain)
+		return nil
+	}
+
+	var translations []struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return err
+	}
+
+	for _, translation := range translations {
+		if *l == "" || translation.Language == "en" {
+			*l = LocalisedString(translation.Text)
+		}
+	}
+
+	return nil
+}
+
+// Boolean is a JSON boolean in newer versions and 0 or 1 in version 1
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	default:
+		*b = false
+	}
+
+	return nil
+}
+
+// Timestamp is POSIX seconds before version 3 and RFC3339 from then on
+type Timestamp time.Time
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if seconds, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		*t = Timestamp(time.Unix(seconds, 0))
+		return nil
+	}
+
+	var formatted string
+	if err := json.Unmarshal(data, &formatted); err != nil {
+		return err
+	}
+
+	parsed, err := time.Parse(time.RFC3339, formatted)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(parsed)
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package gtfs
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AgencyImportStatistics is how much of a GTFS import came from each agency, mostly useful for composite feeds
+// to see that every agency inside of them made it through
+type AgencyImportStatistics struct {
+	Dataset   string
+	Timestamp string
+
+	Feed        string `bson:",omitempty" json:",omitempty"`
+	AgencyID    string
+	AgencyName  string
+	OperatorRef string
+
+	Routes int
+	Trips  int
+	// Stops are the distinct stops called at by the agencies trips
+	Stops int
+	// MergedStops are the stops called at which are shared with another feed after merging the duplicates
+	MergedStops int
+
+	ModificationDateTime time.Time
+}
+
+// getAgencyStatistics counts up the routes, trips & stops of each agency
+func (g *Schedule) getAgencyStatistics(datasource *ctdf.DataSourceReference, agencyOperatorRefs map[string]string) []*AgencyImportStatistics {
+	statistics := map[string]*AgencyImportStatistics{}
+	for _, agency := range g.Agencies {
+		statistics[agency.ID] = &AgencyImportStatistics{
+			Dataset:              datasource.DatasetID,
+			Timestamp:            datasource.Timestamp,
+			Feed:                 g.agencyFeeds[agency.ID],
+			AgencyID:             agency.ID,
+			AgencyName:           agency.Name,
+			OperatorRef:          agencyOperatorRefs[agency.ID],
+			ModificationDateTime: time.Now(),
+		}
+	}
+
+	// Feeds with a single agency don't need to say which agency a route belongs to
+	getAgencyStatistics := func(agencyID string) *AgencyImportStatistics {
+		if agencyID == "" && len(g.Agencies) == 1 {
+			agencyID = g.Agencies[0].ID
+		}
+
+		return statistics[agencyID]
+	}
+
+	routeAgencies := map[string]string{}
+	for _, route := range g.Routes {
+		routeAgencies[route.ID] = route.AgencyID
+
+		if agencyStatistics := getAgencyStatistics(route.AgencyID); agencyStatistics != nil {
+			agencyStatistics.Routes += 1
+		}
+	}
+
+	tripAgencies := map[string]string{}
+	for _, trip := range g.Trips {
+		tripAgencies[trip.ID] = routeAgencies[trip.RouteID]
+
+		if agencyStatistics := getAgencyStatistics(routeAgencies[trip.RouteID]); agencyStatistics != nil {
+			agencyStatistics.Trips += 1
+		}
+	}
+
+	mergedStops := map[string]bool{}
+	for _, mergedID := range g.stopMerges {
+		mergedStops[mergedID] = true
+	}
+
+	agencyStops := map[string]map[string]bool{}
+	for _, stopTime := range g.StopTimes {
+		agencyStatistics := getAgencyStatistics(tripAgencies[stopTime.TripID])
+		if agencyStatistics == nil {
+			continue
+		}
+
+		if agencyStops[agencyStatistics.AgencyID] == nil {
+			agencyStops[agencyStatistics.AgencyID] = map[string]bool{}
+		}
+		if agencyStops[agencyStatistics.AgencyID][stopTime.StopID] {
+			continue
+		}
+		agencyStops[agencyStatistics.AgencyID][stopTime.StopID] = true
+
+		agencyStatistics.Stops += 1
+		if mergedStops[stopTime.StopID] {
+			agencyStatistics.MergedStops += 1
+		}
+	}
+
+	var statisticsList []*AgencyImportStatistics
+	for _, agency := range g.Agencies {
+		statisticsList = append(statisticsList, statistics[agency.ID])
+	}
+
+	return statisticsList
+}
+
+// saveAgencyStatistics replaces the statistics of the previous import of the dataset
+func saveAgencyStatistics(datasource *ctdf.DataSourceReference, statistics []*AgencyImportStatistics) error {
+	collection := database.GetCollection("gtfs_agency_statistics")
+
+	var operations []mongo.WriteModel
+	for _, agencyStatistics := range statistics {
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"dataset": agencyStatistics.Dataset, "agencyid": agencyStatistics.AgencyID}).
+			SetReplacement(agencyStatistics).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) > 0 {
+		if _, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	_, err := collection.DeleteMany(context.Background(), bson.M{
+		"dataset":   datasource.DatasetID,
+		"timestamp": bson.M{"$ne": datasource.Timestamp},
+	})
+
+	return err
+}
+
+// GetAgencyStatistics returns the statistics of each agency from the latest import of the dataset
+func GetAgencyStatistics(dataset string) ([]*AgencyImportStatistics, error) {
+	collection := database.GetCollection("gtfs_agency_statistics")
+
+	opts := options.Find().SetSort(bson.D{{Key: "agencyid", Value: 1}})
+	cursor, err := collection.Find(context.Background(), bson.M{"dataset": dataset}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var statistics []*AgencyImportStatistics
+	err = cursor.All(context.Background(), &statistics)
+
+	return statistics, err
+}
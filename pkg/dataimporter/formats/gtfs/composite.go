@@ -0,0 +1,283 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// CompositeFeedIDFormat namespaces the GTFS identifiers of each feed in a composite feed as they often overlap
+const CompositeFeedIDFormat = "%s:%s"
+
+// Stops from different feeds with the same name & type within this many metres are treated as the same stop
+const compositeStopMergeDistance = 25
+
+// getArchiveFeeds groups the GTFS files in the archive by the feed they belong to.
+// Composite feeds either put each feed in its own directory or zip each of them up separately,
+// files at the top level of the archive are the single unnamed feed of a normal GTFS archive.
+func getArchiveFeeds(archive *zip.Reader) (map[string]map[string]*zip.File, error) {
+	feeds := map[string]map[string]*zip.File{}
+
+	for _, zipFile := range archive.File {
+		if zipFile.FileInfo().IsDir() {
+			continue
+		}
+
+		directory, fileName := path.Split(zipFile.Name)
+		directory = strings.Trim(directory, "/")
+
+		if strings.HasSuffix(fileName, ".zip") {
+			nestedFeedName := strings.TrimSuffix(fileName, ".zip")
+			if directory != "" {
+				nestedFeedName = fmt.Sprintf("%s/%s", directory, nestedFeedName)
+			}
+
+			nestedFeed, err := getNestedFeed(zipFile)
+			if err != nil {
+				return nil, err
+			}
+
+			feeds[nestedFeedName] = nestedFeed
+			continue
+		}
+
+		if !isScheduleFile(fileName) {
+			log.Error().Str("file", zipFile.Name).Msg("Unknown gtfs file")
+			continue
+		}
+
+		if feeds[directory] == nil {
+			feeds[directory] = map[string]*zip.File{}
+		}
+		feeds[directory][fileName] = zipFile
+	}
+
+	return feeds, nil
+}
+
+// getNestedFeed opens up a feed zipped inside of the composite archive, only the top level of it is used
+func getNestedFeed(zipFile *zip.File) (map[string]*zip.File, error) {
+	reader, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	for _, nestedFile := range archive.File {
+		if isScheduleFile(nestedFile.Name) {
+			files[nestedFile.Name] = nestedFile
+		}
+	}
+
+	return files, nil
+}
+
+// addCompositeFeed namespaces all of the identifiers in the feed & appends it onto this schedule
+func (gtfs *Schedule) addCompositeFeed(feedName string, feed *Schedule) {
+	namespace := func(id string) string {
+		if id == "" {
+			return ""
+		}
+
+		return fmt.Sprintf(CompositeFeedIDFormat, feedName, id)
+	}
+
+	// Routes can leave out the agency when there is only one in the feed so fill it in before it gets namespaced
+	var defaultAgencyID string
+	if len(feed.Agencies) == 1 {
+		if feed.Agencies[0].ID == "" {
+			feed.Agencies[0].ID = feedName
+		}
+		defaultAgencyID = feed.Agencies[0].ID
+	}
+
+	var defaultTimezone string
+	if len(feed.Agencies) > 0 {
+		defaultTimezone = feed.Agencies[0].Timezone
+	}
+
+	for _, agency := range feed.Agencies {
+		agency.ID = namespace(agency.ID)
+		gtfs.agencyFeeds[agency.ID] = feedName
+
+		gtfs.Agencies = append(gtfs.Agencies, agency)
+	}
+
+	for _, stop := range feed.Stops {
+		stop.ID = namespace(stop.ID)
+		stop.Parent = namespace(stop.Parent)
+		// Stops fall back to the first agencies timezone which won't be this feeds agency any more
+		if stop.Timezone == "" {
+			stop.Timezone = defaultTimezone
+		}
+		gtfs.stopFeeds[stop.ID] = feedName
+
+		gtfs.Stops = append(gtfs.Stops, stop)
+	}
+
+	for _, route := range feed.Routes {
+		if route.AgencyID == "" {
+			route.AgencyID = defaultAgencyID
+		}
+
+		route.ID = namespace(route.ID)
+		route.AgencyID = namespace(route.AgencyID)
+
+		gtfs.Routes = append(gtfs.Routes, route)
+	}
+
+	for _, trip := range feed.Trips {
+		trip.ID = namespace(trip.ID)
+		trip.RouteID = namespace(trip.RouteID)
+		trip.ServiceID = namespace(trip.ServiceID)
+		trip.BlockID = namespace(trip.BlockID)
+		trip.ShapeID = namespace(trip.ShapeID)
+
+		gtfs.Trips = append(gtfs.Trips, trip)
+	}
+
+	for _, stopTime := range feed.StopTimes {
+		stopTime.TripID = namespace(stopTime.TripID)
+		stopTime.StopID = namespace(stopTime.StopID)
+
+		gtfs.StopTimes = append(gtfs.StopTimes, stopTime)
+	}
+
+	for _, calendar := range feed.Calendars {
+		calendar.ServiceID = namespace(calendar.ServiceID)
+
+		gtfs.Calendars = append(gtfs.Calendars, calendar)
+	}
+
+	for _, calendarDate := range feed.CalendarDates {
+		calendarDate.ServiceID = namespace(calendarDate.ServiceID)
+
+		gtfs.CalendarDates = append(gtfs.CalendarDates, calendarDate)
+	}
+
+	for _, frequency := range feed.Frequencies {
+		frequency.TripID = namespace(frequency.TripID)
+
+		gtfs.Frequencies = append(gtfs.Frequencies, frequency)
+	}
+
+	for _, shape := range feed.Shapes {
+		shape.ID = namespace(shape.ID)
+
+		gtfs.Shapes = append(gtfs.Shapes, shape)
+	}
+
+	for _, transfer := range feed.Transfers {
+		transfer.FromStopID = namespace(transfer.FromStopID)
+		transfer.ToStopID = namespace(transfer.ToStopID)
+		transfer.FromRouteID = namespace(transfer.FromRouteID)
+		transfer.ToRouteID = namespace(transfer.ToRouteID)
+		transfer.FromTripID = namespace(transfer.FromTripID)
+		transfer.ToTripID = namespace(transfer.ToTripID)
+
+		gtfs.Transfers = append(gtfs.Transfers, transfer)
+	}
+
+	log.Info().
+		Str("feed", feedName).
+		Int("agencies", len(feed.Agencies)).
+		Int("stops", len(feed.Stops)).
+		Int("routes", len(feed.Routes)).
+		Int("trips", len(feed.Trips)).
+		Msg("Added feed to composite")
+}
+
+// mergeCompositeStops collapses the stops that appear in more than one feed onto the first one found,
+// pointing everything that referenced the duplicate at the remaining stop
+func (gtfs *Schedule) mergeCompositeStops() {
+	keptStops := map[string][]*Stop{}
+	var stops []Stop
+
+	for i := range gtfs.Stops {
+		stop := &gtfs.Stops[i]
+		key := fmt.Sprintf("%s/%s", stop.GetStopType(), strings.ToLower(strings.TrimSpace(stop.Name)))
+
+		var mergedInto *Stop
+		for _, keptStop := range keptStops[key] {
+			if gtfs.stopFeeds[keptStop.ID] == gtfs.stopFeeds[stop.ID] {
+				continue
+			}
+
+			if getStopLocation(stop).GreatCircleDistance(getStopLocation(keptStop)) <= compositeStopMergeDistance {
+				mergedInto = keptStop
+				break
+			}
+		}
+
+		if mergedInto != nil {
+			gtfs.stopMerges[stop.ID] = mergedInto.ID
+			continue
+		}
+
+		stops = append(stops, *stop)
+		keptStops[key] = append(keptStops[key], stop)
+	}
+
+	if len(gtfs.stopMerges) == 0 {
+		return
+	}
+
+	getMergedStopID := func(id string) string {
+		if mergedID, exists := gtfs.stopMerges[id]; exists {
+			return mergedID
+		}
+
+		return id
+	}
+
+	for i := range stops {
+		stops[i].Parent = getMergedStopID(stops[i].Parent)
+	}
+	gtfs.Stops = stops
+
+	for i := range gtfs.StopTimes {
+		gtfs.StopTimes[i].StopID = getMergedStopID(gtfs.StopTimes[i].StopID)
+	}
+
+	for i := range gtfs.Transfers {
+		gtfs.Transfers[i].FromStopID = getMergedStopID(gtfs.Transfers[i].FromStopID)
+		gtfs.Transfers[i].ToStopID = getMergedStopID(gtfs.Transfers[i].ToStopID)
+	}
+
+	log.Info().Int("stops", len(gtfs.stopMerges)).Msg("Merged duplicate stops across composite feeds")
+}
+
+func getStopLocation(stop *Stop) *ctdf.Location {
+	return &ctdf.Location{
+		Type:        "Point",
+		Coordinates: []float64{stop.Longitude, stop.Latitude},
+	}
+}
+
+func getSortedFeedNames(feeds map[string]map[string]*zip.File) []string {
+	var feedNames []string
+	for feedName := range feeds {
+		feedNames = append(feedNames, feedName)
+	}
+	sort.Strings(feedNames)
+
+	return feedNames
+}
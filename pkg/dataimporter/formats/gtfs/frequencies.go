@@ -0,0 +1,119 @@
+package gtfs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// expandFrequencies materialises a journey for every headway in the frequency windows of a trip.
+// The stop_times of a frequency based trip are only a template, so the template journey is shifted so it
+// departs at each start time while keeping the same relative times between stops
+func expandFrequencies(template *ctdf.Journey, frequencies []*Frequency) map[string]*ctdf.Journey {
+	journeys := map[string]*ctdf.Journey{}
+
+	// Frequency times count from the start of the service day so the template has to as well
+	templateDeparture := template.DepartureTime.Hour()*3600 + template.DepartureTime.Minute()*60 + template.DepartureTime.Second()
+	if len(template.Path) > 0 {
+		templateDeparture += template.Path[0].OriginDepartureDayOffset * secondsPerDay
+	}
+
+	for _, frequency := range frequencies {
+		startTime, err := parseGTFSTime(frequency.StartTime)
+		if err != nil {
+			log.Error().Err(err).Str("trip", frequency.TripID).Msg("Failed to parse frequency start_time")
+			continue
+		}
+		endTime, err := parseGTFSTime(frequency.EndTime)
+		if err != nil {
+			log.Error().Err(err).Str("trip", frequency.TripID).Msg("Failed to parse frequency end_time")
+			continue
+		}
+
+		if frequency.HeadwaySeconds <= 0 {
+			log.Error().Str("trip", frequency.TripID).Msg("Frequency has no headway")
+			continue
+		}
+
+		for departure := startTime; departure < endTime; departure += frequency.HeadwaySeconds {
+			startTimeID := formatGTFSTime(departure)
+
+			journey := shiftJourney(template, time.Duration(departure-templateDeparture)*time.Second)
+			journey.PrimaryIdentifier = fmt.Sprintf("%s-%s", template.PrimaryIdentifier, strings.ReplaceAll(startTimeID, ":", ""))
+			journey.OtherIdentifiers["GTFS-StartTime"] = startTimeID
+
+			journeys[startTimeID] = journey
+		}
+	}
+
+	return journeys
+}
+
+func shiftJourney(template *ctdf.Journey, offset time.Duration) *ctdf.Journey {
+	journey := *template
+
+	journey.OtherIdentifiers = map[string]string{}
+	for key, value := range template.OtherIdentifiers {
+		journey.OtherIdentifiers[key] = value
+	}
+
+	journey.DepartureTime, _ = shiftTimeOfDay(template.DepartureTime, 0, offset)
+
+	journey.Path = make([]*ctdf.JourneyPathItem, len(template.Path))
+	for i, templatePathItem := range template.Path {
+		pathItem := *templatePathItem
+
+		pathItem.OriginArrivalTime, pathItem.OriginArrivalDayOffset = shiftTimeOfDay(pathItem.OriginArrivalTime, pathItem.OriginArrivalDayOffset, offset)
+		pathItem.OriginDepartureTime, pathItem.OriginDepartureDayOffset = shiftTimeOfDay(pathItem.OriginDepartureTime, pathItem.OriginDepartureDayOffset, offset)
+		pathItem.DestinationArrivalTime, pathItem.DestinationArrivalDayOffset = shiftTimeOfDay(pathItem.DestinationArrivalTime, pathItem.DestinationArrivalDayOffset, offset)
+
+		journey.Path[i] = &pathItem
+	}
+
+	return &journey
+}
+
+// shiftTimeOfDay moves a path time by the offset, keeping it as just a time of day with the days it's
+// now past the start of the service day in the returned day offset
+func shiftTimeOfDay(timeOfDay time.Time, dayOffset int, offset time.Duration) (time.Time, int) {
+	seconds := dayOffset*secondsPerDay + timeOfDay.Hour()*3600 + timeOfDay.Minute()*60 + timeOfDay.Second() + int(offset.Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	secondOfDay := seconds % secondsPerDay
+	shifted := time.Date(timeOfDay.Year(), timeOfDay.Month(), timeOfDay.Day(), secondOfDay/3600, (secondOfDay/60)%60, secondOfDay%60, 0, timeOfDay.Location())
+
+	return shifted, seconds / secondsPerDay
+}
+
+// parseGTFSTime returns the number of seconds since midnight, GTFS times can go past 24:00:00 for trips running after midnight
+func parseGTFSTime(timestamp string) (int, error) {
+	splitTimestamp := strings.Split(strings.TrimSpace(timestamp), ":")
+	if len(splitTimestamp) != 3 {
+		return 0, errors.New(fmt.Sprintf("Invalid GTFS time %s", timestamp))
+	}
+
+	var parts [3]int
+	for i, part := range splitTimestamp {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+
+		parts[i] = value
+	}
+
+	return parts[0]*3600 + parts[1]*60 + parts[2], nil
+}
+
+func formatGTFSTime(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, (seconds/60)%60, seconds%60)
+}
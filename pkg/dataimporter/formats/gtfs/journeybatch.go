@@ -1,18 +1,21 @@
 package gtfs
 
 import (
-	"context"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func NewDatabaseBatchProcessingQueue(collection string, batchTimeout time.Duration, emptyTimeout time.Duration, batchSize int) DatabaseBatchProcessingQueue {
+func NewDatabaseBatchProcessingQueue(collection string, sink datasink.Sink, errors *importerrors.Collector, batchTimeout time.Duration, emptyTimeout time.Duration, batchSize int) DatabaseBatchProcessingQueue {
 	return DatabaseBatchProcessingQueue{
 		Collection:        collection,
+		Sink:              sink,
+		Errors:            errors,
 		BatchTimeout:      batchTimeout,
 		EmptyTimeout:      emptyTimeout,
 		items:             make(chan mongo.WriteModel, batchSize),
@@ -22,12 +25,17 @@ func NewDatabaseBatchProcessingQueue(collection string, batchTimeout time.Durati
 
 type DatabaseBatchProcessingQueue struct {
 	Collection   string
+	Sink         datasink.Sink
+	Errors       *importerrors.Collector
 	BatchTimeout time.Duration
 	EmptyTimeout time.Duration
 
 	items             chan (mongo.WriteModel)
 	lastItemProcessed time.Time
 	ticker            *time.Ticker
+
+	err      error
+	errMutex sync.Mutex
 }
 
 func (b *DatabaseBatchProcessingQueue) Add(item mongo.WriteModel) {
@@ -57,25 +65,34 @@ func (b *DatabaseBatchProcessingQueue) Process() {
 			if len(batchItems) > 0 {
 				b.lastItemProcessed = time.Now()
 				log.Info().Str("collection", b.Collection).Int("Length", len(batchItems)).Msg("Bulk write")
-				_, err := realtimeJourneysCollection.BulkWrite(context.Background(), batchItems, &options.BulkWriteOptions{})
+				_, err := b.Sink.BulkWrite(realtimeJourneysCollection, batchItems)
 				if err != nil {
-					log.Fatal().Str("collection", b.Collection).Err(err).Msg("Failed to bulk write")
+					log.Error().Str("collection", b.Collection).Err(err).Msg("Failed to bulk write")
+
+					if err := b.Errors.Add(&importerrors.WriteError{Collection: b.Collection, Err: err}); err != nil {
+						b.errMutex.Lock()
+						if b.err == nil {
+							b.err = err
+						}
+						b.errMutex.Unlock()
+					}
 				}
 			}
 		}
 	}(b)
 }
 
-func (b *DatabaseBatchProcessingQueue) Wait() {
-	waiting := true
-
-	for waiting {
-		now := time.Now()
-
-		if now.Sub(b.lastItemProcessed) > b.EmptyTimeout {
+// Wait blocks until the queue has been drained, returning an error if too many of its writes failed
+func (b *DatabaseBatchProcessingQueue) Wait() error {
+	for {
+		if time.Since(b.lastItemProcessed) > b.EmptyTimeout {
 			log.Info().Str("collection", b.Collection).Msg("Nothing left to process in queue")
 			b.ticker.Stop()
-			return
+
+			b.errMutex.Lock()
+			defer b.errMutex.Unlock()
+
+			return b.err
 		}
 		time.Sleep(5 * time.Second)
 	}
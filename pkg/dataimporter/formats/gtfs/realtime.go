@@ -3,7 +3,6 @@ package gtfs
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +18,9 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
 	"github.com/travigo/travigo/pkg/realtime/vehicletracker"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"go.mongodb.org/mongo-driver/bson"
@@ -56,9 +58,18 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 		return err
 	}
 
+	// The dry run sink doesn't write anywhere so shouldn't count towards the health of the live feed
+	recordFeedHealth := !datasink.IsDryRun(dataset.Sink)
+
 	feed := gtfs.FeedMessage{}
 	err = proto.Unmarshal(body, &feed)
 	if err != nil {
+		if recordFeedHealth {
+			if err := feedhealth.RecordParseErrors(dataset.Identifier, "gtfs-rt", 1); err != nil {
+				log.Error().Err(err).Str("source", dataset.Identifier).Msg("Failed to record feed health")
+			}
+		}
+
 		return err
 	}
 
@@ -149,8 +160,8 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 				validFrom := time.Unix(int64(validFromTimestamp), 0)
 				validTo := time.Unix(int64(validToTimestamp), 0)
 
-				title := *entity.Alert.HeaderText.GetTranslation()[0].Text // TODO assume we only use the 1 translation
-				description := *entity.Alert.DescriptionText.GetTranslation()[0].Text
+				title, titleTranslations := ctdf.SplitTranslations(getTranslatedTexts(entity.Alert.HeaderText))
+				description, descriptionTranslations := ctdf.SplitTranslations(getTranslatedTexts(entity.Alert.DescriptionText))
 
 				hash := sha256.New()
 				hash.Write([]byte(alertType))
@@ -169,6 +180,9 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 						ValidFrom:   validFrom,
 						ValidUntil:  validTo,
 
+						TitleTranslations:       titleTranslations,
+						DescriptionTranslations: descriptionTranslations,
+
 						IdentifyingInformation: identifyingInformation,
 					},
 
@@ -177,7 +191,7 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 					RecordedAt: recordedAtTime,
 				}
 
-				updateEventJson, _ := json.Marshal(updateEvent)
+				updateEventJson, _ := queuemessage.Marshal(queuemessage.MessageTypeVehicleUpdate, updateEvent)
 				r.queue.PublishBytes(updateEventJson)
 
 				serviceAlertCount += 1
@@ -200,15 +214,22 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 
 			timeframe := timeFrameDateTime.Format("2006-01-02")
 
+			// Frequency based trips share a trip_id so the start time is needed to tell the runs apart
+			localID := fmt.Sprintf("%s-realtime-%s-%s", dataset.Identifier, timeframe, tripID)
+			if trip.GetStartTime() != "" {
+				localID = fmt.Sprintf("%s-%s", localID, trip.GetStartTime())
+			}
+
 			locationEvent := vehicletracker.VehicleUpdateEvent{
 				MessageType: vehicletracker.VehicleUpdateEventTypeTrip,
-				LocalID:     fmt.Sprintf("%s-realtime-%s-%s", dataset.Identifier, timeframe, tripID),
+				LocalID:     localID,
 				SourceType:  "GTFS-RT",
 				VehicleLocationUpdate: &vehicletracker.VehicleLocationUpdate{
 					Timeframe: timeframe,
 
 					IdentifyingInformation: map[string]string{
 						"TripID":        tripID,
+						"StartTime":     trip.GetStartTime(),
 						"RouteID":       trip.GetRouteId(),
 						"LinkedDataset": dataset.LinkedDataset,
 					},
@@ -225,7 +246,8 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 					locationEvent.VehicleLocationUpdate.Occupancy.TotalPercentageOccupancy = int(vehiclePosition.GetOccupancyPercentage())
 				}
 
-				if vehiclePosition.OccupancyStatus != nil {
+				// Only fall back to the less precise status if no actual percentage was provided
+				if vehiclePosition.OccupancyPercentage == nil && vehiclePosition.OccupancyStatus != nil {
 					switch vehiclePosition.GetOccupancyStatus() {
 					case gtfs.VehiclePosition_EMPTY:
 						locationEvent.VehicleLocationUpdate.Occupancy.TotalPercentageOccupancy = 0
@@ -288,7 +310,7 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			if tripUpdate != nil {
 				for _, stopTimeUpdate := range tripUpdate.GetStopTimeUpdate() {
 					locationEvent.VehicleLocationUpdate.StopUpdates = append(locationEvent.VehicleLocationUpdate.StopUpdates, vehicletracker.VehicleLocationEventStopUpdate{
-						StopID:          fmt.Sprintf("%s-stop-%s", dataset.LinkedDataset, stopTimeUpdate.GetStopId()),
+						StopID:          fmt.Sprintf("%s-stop-%s", dataset.GetLinkedIdentifierPrefix(), stopTimeUpdate.GetStopId()),
 						ArrivalTime:     time.Unix(stopTimeUpdate.GetArrival().GetTime(), 0),
 						DepartureTime:   time.Unix(stopTimeUpdate.GetDeparture().GetTime(), 0),
 						ArrivalOffset:   int(stopTimeUpdate.GetArrival().GetDelay()),
@@ -301,7 +323,7 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 				withTripUpdate += 1
 			}
 
-			locationEventJson, _ := json.Marshal(locationEvent)
+			locationEventJson, _ := queuemessage.Marshal(queuemessage.MessageTypeVehicleUpdate, locationEvent)
 
 			r.queue.PublishBytes(locationEventJson)
 
@@ -325,6 +347,12 @@ func (r *Realtime) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 		Int("total", len(feed.Entity)).
 		Msg("Submitted vehicle updates")
 
+	if recordFeedHealth {
+		if err := feedhealth.RecordMessages(dataset.Identifier, "gtfs-rt", len(feed.Entity)); err != nil {
+			log.Error().Err(err).Str("source", dataset.Identifier).Msg("Failed to record feed health")
+		}
+	}
+
 	checkQueueSize()
 
 	return nil
@@ -341,3 +369,12 @@ func checkQueueSize() {
 		checkQueueSize()
 	}
 }
+
+func getTranslatedTexts(translatedString *gtfs.TranslatedString) []ctdf.TranslatedText {
+	var texts []ctdf.TranslatedText
+	for _, translation := range translatedString.GetTranslation() {
+		texts = append(texts, ctdf.TranslatedText{Language: translation.GetLanguage(), Text: translation.GetText()})
+	}
+
+	return texts
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,11 +16,17 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"github.com/travigo/travigo/pkg/identifiermapping"
+	"github.com/travigo/travigo/pkg/names"
 	"github.com/travigo/travigo/pkg/transforms"
 	"github.com/travigo/travigo/pkg/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type Schedule struct {
@@ -32,6 +39,24 @@ type Schedule struct {
 	CalendarDates []CalendarDate
 	Frequencies   []Frequency
 	Shapes        []Shape
+	Transfers     []Transfer
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+
+	// Only filled in for composite feeds, the feed each agency & stop came from
+	agencyFeeds map[string]string
+	stopFeeds   map[string]string
+	// Duplicate stops from a composite feed & the stop they were merged into
+	stopMerges map[string]string
+}
+
+func (gtfs *Schedule) SetupProgress(tracker *progress.Tracker) {
+	gtfs.progress = tracker
+}
+
+func (gtfs *Schedule) SetupErrors(collector *importerrors.Collector) {
+	gtfs.errors = collector
 }
 
 func (gtfs *Schedule) ParseFile(reader io.Reader) error {
@@ -42,18 +67,6 @@ func (gtfs *Schedule) ParseFile(reader io.Reader) error {
 		return r
 	})
 
-	fileMap := map[string]interface{}{
-		"agency.txt":         &gtfs.Agencies,
-		"stops.txt":          &gtfs.Stops,
-		"routes.txt":         &gtfs.Routes,
-		"trips.txt":          &gtfs.Trips,
-		"stop_times.txt":     &gtfs.StopTimes,
-		"calendar.txt":       &gtfs.Calendars,
-		"calendar_dates.txt": &gtfs.CalendarDates,
-		// "frequencies.txt":    &gtfs.Frequencies,
-		"shapes.txt": &gtfs.Shapes,
-	}
-
 	// TODO this uses a load of ram :(
 	body, err := io.ReadAll(reader)
 	if err != nil {
@@ -65,28 +78,91 @@ func (gtfs *Schedule) ParseFile(reader io.Reader) error {
 		return err
 	}
 
-	for _, zipFile := range archive.File {
-		file, err := zipFile.Open()
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to open file")
+	feeds, err := getArchiveFeeds(archive)
+	if err != nil {
+		return err
+	}
+
+	gtfs.agencyFeeds = map[string]string{}
+	gtfs.stopFeeds = map[string]string{}
+	gtfs.stopMerges = map[string]string{}
+
+	if len(feeds) <= 1 {
+		for _, files := range feeds {
+			return gtfs.parseFeedFiles(files)
 		}
-		defer file.Close()
 
-		fileName := zipFile.Name
-		if destination, exists := fileMap[fileName]; exists {
-			log.Info().Str("file", fileName).Msg("Loading file")
+		return nil
+	}
 
-			fileReader, _ := zipFile.Open()
-			defer fileReader.Close()
+	log.Info().Int("feeds", len(feeds)).Msg("Importing composite feed")
 
-			err = gocsv.Unmarshal(fileReader, destination)
-			if err != nil {
-				log.Error().Str("file", fileName).Err(err).Msg("Failed to parse csv file")
-				return err
-			}
-		} else {
-			log.Error().Str("file", fileName).Msg("Unknown gtfs file")
+	for _, feedName := range getSortedFeedNames(feeds) {
+		feed := &Schedule{
+			progress: gtfs.progress,
+			errors:   gtfs.errors,
+		}
+
+		if err := feed.parseFeedFiles(feeds[feedName]); err != nil {
+			return err
+		}
+
+		gtfs.addCompositeFeed(feedName, feed)
+	}
+
+	gtfs.mergeCompositeStops()
+
+	return nil
+}
+
+func (gtfs *Schedule) getFileDestinations() map[string]interface{} {
+	return map[string]interface{}{
+		"agency.txt":         &gtfs.Agencies,
+		"stops.txt":          &gtfs.Stops,
+		"routes.txt":         &gtfs.Routes,
+		"trips.txt":          &gtfs.Trips,
+		"stop_times.txt":     &gtfs.StopTimes,
+		"calendar.txt":       &gtfs.Calendars,
+		"calendar_dates.txt": &gtfs.CalendarDates,
+		"frequencies.txt":    &gtfs.Frequencies,
+		"shapes.txt":         &gtfs.Shapes,
+		"transfers.txt":      &gtfs.Transfers,
+	}
+}
+
+func isScheduleFile(fileName string) bool {
+	_, exists := (&Schedule{}).getFileDestinations()[fileName]
+
+	return exists
+}
+
+func (gtfs *Schedule) parseFeedFiles(files map[string]*zip.File) error {
+	fileMap := gtfs.getFileDestinations()
+
+	for fileName, zipFile := range files {
+		destination := fileMap[fileName]
+		log.Info().Str("file", zipFile.Name).Msg("Loading file")
+
+		fileReader, err := zipFile.Open()
+		if err != nil {
+			return err
+		}
+
+		// Bad values are left empty & reported rather than failing the whole file
+		err = gocsv.UnmarshalWithErrorHandler(fileReader, func(parseError *csv.ParseError) bool {
+			return gtfs.errors.Add(&importerrors.ParseError{
+				File:   zipFile.Name,
+				Record: fmt.Sprintf("line %d column %d", parseError.Line, parseError.Column),
+				Err:    parseError.Err,
+			}) == nil
+		}, destination)
+		fileReader.Close()
+		if err != nil {
+			log.Error().Str("file", zipFile.Name).Err(err).Msg("Failed to parse csv file")
+			return err
 		}
+
+		gtfs.progress.AddRecords(reflect.ValueOf(destination).Elem().Len())
 	}
 
 	return nil
@@ -95,19 +171,50 @@ func (gtfs *Schedule) ParseFile(reader io.Reader) error {
 func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
 	log.Info().Msg("Converting & Importing as CTDF into MongoDB")
 
+	countryProfile := dataset.GetCountryProfile()
+	identifierPrefix := dataset.GetIdentifierPrefix()
+	defaultTimezone := dataset.GetTimezone()
+
+	// Agency timezone is required but not every feed has it, assume they're in the timezone of the dataset
+	for i := range g.Agencies {
+		if g.Agencies[i].Timezone == "" {
+			g.Agencies[i].Timezone = defaultTimezone
+		}
+	}
+
 	// Agencies / Operators
 	// TODO this mapping is hardcoding for the 1 UK datset and will need replacing later on to be more generic
 	agencyNOCMapping := map[string]string{}
+	// Agencies that are already covered by an existing NOC operator so don't get an operator of their own
+	dedupedAgencies := map[string]bool{}
 	for _, agency := range g.Agencies {
+		if agency.NOC == "" {
+			agency.NOC = dataset.CustomConfig[fmt.Sprintf("agencynoc-%s", agency.ID)]
+		}
+
+		if agency.NOC == "" && dataset.CustomConfig["matchoperatorsbyname"] == "true" {
+			if operatorRef := getNOCOperatorByName(dataset, agency.Name); operatorRef != "" {
+				agencyNOCMapping[agency.ID] = operatorRef
+				dedupedAgencies[agency.ID] = true
+
+				log.Info().Str("agency", agency.ID).Str("operator", operatorRef).Msg("Matched agency to NOC operator by name")
+				continue
+			}
+		}
+
 		if agency.NOC == "" {
 			log.Debug().Str("agency", agency.ID).Msg("has no NOC mapping")
 			continue
 		}
-		agencyNOCMapping[agency.ID] = fmt.Sprintf("gb-noc-%s", agency.NOC)
+		agencyNOCMapping[agency.ID] = transforms.GetOperatorRef(fmt.Sprintf(ctdf.OperatorNOCFormat, agency.NOC), dataset.Identifier)
+
+		if dataset.Lookups != nil && !dataset.Lookups.Operators().Exists(agencyNOCMapping[agency.ID]) {
+			log.Warn().Str("agency", agency.ID).Str("operator", agencyNOCMapping[agency.ID]).Msg("Agency NOC mapping refers to an unknown operator")
+		}
 	}
 
 	log.Info().Int("length", len(g.Agencies)).Msg("Starting Operators")
-	agenciesQueue := NewDatabaseBatchProcessingQueue("operators", 1*time.Second, 10*time.Second, 500)
+	agenciesQueue := NewDatabaseBatchProcessingQueue("operators", dataset.Sink, dataset.Errors, 1*time.Second, 10*time.Second, 500)
 
 	if dataset.SupportedObjects.Operators {
 		agenciesQueue.Process()
@@ -115,7 +222,11 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 	agenciesMap := map[string]*Agency{}
 	for _, gtfsAgency := range g.Agencies {
 		agenciesMap[gtfsAgency.ID] = &gtfsAgency
-		operatorID := fmt.Sprintf("%s-operator-%s", dataset.Identifier, gtfsAgency.ID)
+		if dedupedAgencies[gtfsAgency.ID] {
+			continue
+		}
+
+		operatorID := fmt.Sprintf("%s-operator-%s", identifierPrefix, gtfsAgency.ID)
 		ctdfOperator := &ctdf.Operator{
 			PrimaryIdentifier:    operatorID,
 			CreationDateTime:     time.Now(),
@@ -137,24 +248,42 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 	}
 	log.Info().Msg("Finished Operators")
 	if dataset.SupportedObjects.Operators {
-		agenciesQueue.Wait()
+		if err := agenciesQueue.Wait(); err != nil {
+			return err
+		}
 	}
 
 	// Stops
 	log.Info().Int("length", len(g.Stops)).Msg("Starting Stops")
-	stopsQueue := NewDatabaseBatchProcessingQueue("stops_raw", 1*time.Second, 10*time.Second, 500)
+	stopsQueue := NewDatabaseBatchProcessingQueue("stops_raw", dataset.Sink, dataset.Errors, 1*time.Second, 10*time.Second, 500)
 
 	if dataset.SupportedObjects.Stops {
 		stopsQueue.Process()
 	}
+	stopMapping := identifiermapping.NewMapping(dataset.Identifier, identifiermapping.MappingTypeStop)
+
+	// Stops have no mode of their own in GTFS so take it from the routes calling at them
+	stopTransportTypes := g.getStopTransportTypes()
+
+	// Build up the station hierarchy first so stations can reference their platforms & entrances
+	childStops := map[string][]*Stop{}
+	for i := range g.Stops {
+		if g.Stops[i].Parent != "" {
+			childStops[g.Stops[i].Parent] = append(childStops[g.Stops[i].Parent], &g.Stops[i])
+		}
+	}
+
 	for _, gtfsStop := range g.Stops {
 		timezone := gtfsStop.Timezone
 
-		if timezone == "" {
+		if timezone == "" && len(g.Agencies) > 0 {
 			timezone = g.Agencies[0].Timezone
 		}
+		if timezone == "" {
+			timezone = defaultTimezone
+		}
 
-		stopID := fmt.Sprintf("%s-stop-%s", dataset.Identifier, gtfsStop.ID)
+		stopID := fmt.Sprintf("%s-stop-%s", identifierPrefix, gtfsStop.ID)
 		ctdfStop := &ctdf.Stop{
 			PrimaryIdentifier:    stopID,
 			OtherIdentifiers:     []string{stopID},
@@ -166,10 +295,42 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 				Type:        "Point",
 				Coordinates: []float64{gtfsStop.Longitude, gtfsStop.Latitude},
 			},
-			Active:   true,
-			Timezone: timezone,
+			Active:         true,
+			Timezone:       timezone,
+			StopType:       gtfsStop.GetStopType(),
+			TransportTypes: stopTransportTypes[gtfsStop.ID],
+		}
+
+		if gtfsStop.Parent != "" {
+			ctdfStop.ParentStopRef = fmt.Sprintf("%s-stop-%s", identifierPrefix, gtfsStop.Parent)
 		}
 
+		for _, childStop := range childStops[gtfsStop.ID] {
+			childStopID := fmt.Sprintf("%s-stop-%s", identifierPrefix, childStop.ID)
+			childLocation := &ctdf.Location{
+				Type:        "Point",
+				Coordinates: []float64{childStop.Longitude, childStop.Latitude},
+			}
+
+			switch childStop.GetStopType() {
+			case ctdf.StopTypePlatform:
+				ctdfStop.Platforms = append(ctdfStop.Platforms, &ctdf.StopPlatform{
+					PrimaryIdentifier: childStopID,
+					PrimaryName:       childStop.Name,
+					Location:          childLocation,
+				})
+			case ctdf.StopTypeEntrance:
+				ctdfStop.Entrances = append(ctdfStop.Entrances, &ctdf.StopEntrance{
+					PrimaryIdentifier: childStopID,
+					PrimaryName:       childStop.Name,
+					Location:          childLocation,
+				})
+			}
+		}
+
+		names.NormaliseStop(ctdfStop)
+		ctdfStop.UpdatePlusCode()
+
 		if dataset.SupportedObjects.Stops {
 			// Insert
 			bsonRep, _ := bson.Marshal(bson.M{"$set": ctdfStop})
@@ -178,12 +339,58 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			updateModel.SetUpdate(bsonRep)
 			updateModel.SetUpsert(true)
 			stopsQueue.Add(updateModel)
+
+			stopMapping.Add(gtfsStop.ID, stopID)
 		}
 	}
 	log.Info().Msg("Finished Stops")
 	if dataset.SupportedObjects.Stops {
-		stopsQueue.Wait()
+		if err := stopsQueue.Wait(); err != nil {
+			return err
+		}
+	}
+
+	// Transfers
+	log.Info().Int("length", len(g.Transfers)).Msg("Starting Transfers")
+	transfersQueue := NewDatabaseBatchProcessingQueue("transfers", dataset.Sink, dataset.Errors, 1*time.Second, 10*time.Second, 500)
+
+	if dataset.SupportedObjects.Transfers {
+		transfersQueue.Process()
+
+		for _, gtfsTransfer := range g.Transfers {
+			// Route & trip specific transfers aren't stop to stop interchanges so leave them for now
+			if gtfsTransfer.FromStopID == "" || gtfsTransfer.ToStopID == "" || gtfsTransfer.FromTripID != "" || gtfsTransfer.FromRouteID != "" {
+				continue
+			}
+
+			fromStopRef := getStopRef(&dataset, gtfsTransfer.FromStopID)
+			toStopRef := getStopRef(&dataset, gtfsTransfer.ToStopID)
+			transferID := fmt.Sprintf(ctdf.TransferIDFormat, identifierPrefix, fromStopRef, toStopRef)
+
+			ctdfTransfer := &ctdf.Transfer{
+				PrimaryIdentifier:    transferID,
+				CreationDateTime:     time.Now(),
+				ModificationDateTime: time.Now(),
+				DataSource:           datasource,
+				FromStopRef:          fromStopRef,
+				ToStopRef:            toStopRef,
+				Type:                 gtfsTransfer.GetTransferType(),
+				MinimumTransferTime:  gtfsTransfer.MinTransferTime,
+			}
+
+			bsonRep, _ := bson.Marshal(bson.M{"$set": ctdfTransfer})
+			updateModel := mongo.NewUpdateOneModel()
+			updateModel.SetFilter(bson.M{"primaryidentifier": transferID})
+			updateModel.SetUpdate(bsonRep)
+			updateModel.SetUpsert(true)
+			transfersQueue.Add(updateModel)
+		}
+
+		if err := transfersQueue.Wait(); err != nil {
+			return err
+		}
 	}
+	log.Info().Msg("Finished Transfers")
 
 	// Calendars
 	calendarMapping := map[string]*Calendar{}
@@ -191,8 +398,23 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 	for _, calendar := range g.Calendars {
 		calendarMapping[calendar.ServiceID] = &calendar
 	}
+	// Exception dates are usually public holidays so name them after the holiday of the datasets country
+	holidayNames := map[string]string{}
 	for _, calendarDate := range g.CalendarDates {
 		calendarDateMapping[calendarDate.ServiceID] = append(calendarDateMapping[calendarDate.ServiceID], &calendarDate)
+
+		if _, exists := holidayNames[calendarDate.Date]; !exists {
+			date, _ := time.Parse("20060102", calendarDate.Date)
+			holiday, _ := countryProfile.GetHoliday(date)
+
+			holidayNames[calendarDate.Date] = holiday.Name
+		}
+	}
+
+	// Frequencies
+	frequencyMapping := map[string][]*Frequency{}
+	for _, frequency := range g.Frequencies {
+		frequencyMapping[frequency.TripID] = append(frequencyMapping[frequency.TripID], &frequency)
 	}
 
 	// Shapes
@@ -203,7 +425,7 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 
 	// Routes / Services
 	log.Info().Int("length", len(g.Routes)).Msg("Starting Services")
-	servicesQueue := NewDatabaseBatchProcessingQueue("services", 1*time.Second, 10*time.Second, 500)
+	servicesQueue := NewDatabaseBatchProcessingQueue("services", dataset.Sink, dataset.Errors, 1*time.Second, 10*time.Second, 500)
 
 	if dataset.SupportedObjects.Services {
 		servicesQueue.Process()
@@ -211,9 +433,10 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 
 	ctdfServices := map[string]*ctdf.Service{}
 	routeMap := map[string]Route{}
+	routeMapping := identifiermapping.NewMapping(dataset.Identifier, identifiermapping.MappingTypeRoute)
 	for _, gtfsRoute := range g.Routes {
 		routeMap[gtfsRoute.ID] = gtfsRoute
-		serviceID := fmt.Sprintf("%s-service-%s", dataset.Identifier, gtfsRoute.ID)
+		serviceID := fmt.Sprintf("%s-service-%s", identifierPrefix, gtfsRoute.ID)
 
 		serviceName := gtfsRoute.ShortName
 		if serviceName == "" {
@@ -221,11 +444,14 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 		}
 
 		operatorRef := agencyNOCMapping[gtfsRoute.AgencyID]
+		if operatorRef == "" && gtfsRoute.AgencyID == "" {
+			operatorRef = dataset.FormatOptions.DefaultOperator
+		}
 		if operatorRef == "" {
-			operatorRef = fmt.Sprintf("%s-operator-%s", dataset.Identifier, gtfsRoute.AgencyID)
+			operatorRef = fmt.Sprintf("%s-operator-%s", identifierPrefix, gtfsRoute.AgencyID)
 		}
 
-		if util.ContainsString(dataset.IgnoreObjects.Services.ByOperator, operatorRef) {
+		if dataset.IgnoresOperator(dataset.IgnoreObjects.Services, operatorRef) {
 			continue
 		}
 
@@ -242,9 +468,11 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			Routes:               []ctdf.Route{},
 			BrandColour:          gtfsRoute.Colour,
 			SecondaryBrandColour: gtfsRoute.TextColour,
+			Branding:             ctdf.NewBranding(gtfsRoute.Colour, gtfsRoute.TextColour, ""),
 			TransportType:        convertTransportType(gtfsRoute.Type),
 		}
 
+		names.NormaliseService(ctdfService)
 		transforms.Transform(ctdfService, 1, "gb-dft-bods-gtfs-schedule")
 
 		ctdfServices[gtfsRoute.ID] = ctdfService
@@ -257,35 +485,49 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			updateModel.SetUpdate(bsonRep)
 			updateModel.SetUpsert(true)
 			servicesQueue.Add(updateModel)
+
+			routeMapping.Add(gtfsRoute.ID, serviceID)
 		}
 	}
 	log.Info().Msg("Finished Services")
 	if dataset.SupportedObjects.Services {
-		servicesQueue.Wait()
+		if err := servicesQueue.Wait(); err != nil {
+			return err
+		}
 	}
 
 	ctdfJourneys := map[string]*ctdf.Journey{}
 	// fullJourneyTracks := map[string][]ctdf.Location{}
 
 	// Journeys
-	journeysQueue := NewDatabaseBatchProcessingQueue("journeys", 1*time.Second, 1*time.Minute, 1000)
+	journeysQueue := NewDatabaseBatchProcessingQueue("journeys", dataset.Sink, dataset.Errors, 1*time.Second, 1*time.Minute, 1000)
 	if dataset.SupportedObjects.Journeys {
 		journeysQueue.Process()
 	}
 
+	tripMapping := identifiermapping.NewMapping(dataset.Identifier, identifiermapping.MappingTypeTrip)
+
 	log.Info().Int("length", len(g.Trips)).Msg("Starting Journeys")
 	for _, trip := range g.Trips {
-		journeyID := fmt.Sprintf("%s-journey-%s", dataset.Identifier, trip.ID)
-		serviceID := fmt.Sprintf("%s-service-%s", dataset.Identifier, trip.RouteID)
+		journeyID := fmt.Sprintf("%s-journey-%s", identifierPrefix, trip.ID)
+		serviceID := fmt.Sprintf("%s-service-%s", identifierPrefix, trip.RouteID)
 
 		if ctdfServices[trip.RouteID] == nil {
+			// Routes that were deliberately ignored aren't a problem with the data
+			if _, exists := routeMap[trip.RouteID]; !exists {
+				err := dataset.Errors.Add(&importerrors.ReferenceError{Object: "trip", Identifier: trip.ID, Reference: fmt.Sprintf("route %s", trip.RouteID)})
+				if err != nil {
+					return err
+				}
+			}
+
 			log.Debug().Str("trip", trip.ID).Str("route", trip.RouteID).Msg("Cannot find service for this trip")
 			continue
 		}
 
 		operatorRef := ctdfServices[trip.RouteID].OperatorRef
 
-		if util.ContainsString(dataset.IgnoreObjects.Services.ByOperator, operatorRef) {
+		if dataset.IgnoresOperator(dataset.IgnoreObjects.Services, operatorRef) {
 			continue
 		}
 
@@ -303,7 +545,8 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			dateRunsFrom, _ := time.Parse("20060102", calendar.Start)
 			dateRunsTo, _ := time.Parse("20060102", calendar.End)
 
-			availability.Condition = append(availability.Condition, ctdf.AvailabilityRule{
+			// The range only limits the weekly pattern, added dates from calendar_dates can fall outside of it
+			availability.MatchSecondary = append(availability.MatchSecondary, ctdf.AvailabilityRule{
 				Type:  ctdf.AvailabilityDateRange,
 				Value: fmt.Sprintf("%s:%s", dateRunsFrom.Format("2006-01-02"), dateRunsTo.Format("2006-01-02")),
 			})
@@ -312,12 +555,13 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 		for _, calendarDate := range calendarDateMapping[trip.ServiceID] {
 			date, _ := time.Parse("20060102", calendarDate.Date)
 			rule := ctdf.AvailabilityRule{
-				Type:  ctdf.AvailabilityDate,
-				Value: date.Format("2006-01-02"),
+				Type:        ctdf.AvailabilityDate,
+				Value:       date.Format("2006-01-02"),
+				Description: holidayNames[calendarDate.Date],
 			}
 
 			if calendarDate.ExceptionType == 1 {
-				availability.Match = append(availability.Match, rule)
+				availability.Include = append(availability.Include, rule)
 			} else if calendarDate.ExceptionType == 2 {
 				availability.Exclude = append(availability.Exclude, rule)
 			}
@@ -335,6 +579,7 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 			DataSource:           datasource,
 			ServiceRef:           serviceID,
 			OperatorRef:          operatorRef,
+			TransportType:        convertTransportType(routeMap[trip.RouteID].Type),
 			// Direction:            trip.DirectionID,
 			DestinationDisplay: trip.Headsign,
 			DepartureTimezone:  agenciesMap[routeMap[trip.RouteID].AgencyID].Timezone,
@@ -344,6 +589,7 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 
 		if trip.BlockID != "" {
 			ctdfJourneys[trip.ID].OtherIdentifiers["BlockNumber"] = trip.BlockID
+			ctdfJourneys[trip.ID].BlockRef = fmt.Sprintf(ctdf.BlockIDFormat, identifierPrefix, trip.BlockID)
 		}
 
 		if trip.ShapeID != "" {
@@ -378,6 +624,16 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 		tripStopSequenceMap[stopTime.TripID][stopTime.StopSequence] = &stopTime
 	}
 
+	unknownStopRefs := map[string]bool{}
+
+	// Platform codes are used as the platform/berth at calls made at a stations child stops
+	stopPlatformCodes := map[string]string{}
+	for _, gtfsStop := range g.Stops {
+		if gtfsStop.Parent != "" && gtfsStop.PlatformCode != "" {
+			stopPlatformCodes[gtfsStop.ID] = gtfsStop.PlatformCode
+		}
+	}
+
 	for tripID, tripSequencyMap := range tripStopSequenceMap {
 		if ctdfJourneys[tripID] == nil {
 			log.Debug().Str("trip", tripID).Msg("Cannot find journey for this trip")
@@ -407,16 +663,14 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 				log.Error().Err(err).Msg("Failed to parse stopTime.ArrivalTime")
 			}
 
-			var originStopRef string
-			var destinationStopRef string
+			originStopRef := getStopRef(&dataset, previousStopTime.StopID)
+			destinationStopRef := getStopRef(&dataset, stopTime.StopID)
 
-			// TODO no hardocded nonsense!!
-			if dataset.Identifier == "gb-dft-bods-gtfs-schedule" {
-				originStopRef = fmt.Sprintf("gb-atco-%s", previousStopTime.StopID)
-				destinationStopRef = fmt.Sprintf("gb-atco-%s", stopTime.StopID)
-			} else {
-				originStopRef = fmt.Sprintf("%s-stop-%s", dataset.Identifier, previousStopTime.StopID)
-				destinationStopRef = fmt.Sprintf("%s-stop-%s", dataset.Identifier, stopTime.StopID)
+			// Stops from other datasets have to already exist, this datasets own stops are only linked later on
+			for _, stopRef := range []string{originStopRef, destinationStopRef} {
+				if dataset.Lookups != nil && !strings.HasPrefix(stopRef, identifierPrefix) && !dataset.Lookups.Stops().Exists(stopRef) {
+					unknownStopRefs[stopRef] = true
+				}
 			}
 
 			journeyPathItem := &ctdf.JourneyPathItem{
@@ -425,9 +679,16 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 				OriginArrivalTime:      originArrivalTime,
 				DestinationArrivalTime: destinationArrivalTime,
 				OriginDepartureTime:    originDeparturelTime,
-				DestinationDisplay:     stopTime.StopHeadsign,
-				OriginActivity:         []ctdf.JourneyPathItemActivity{},
-				DestinationActivity:    []ctdf.JourneyPathItemActivity{},
+
+				OriginArrivalDayOffset:      getDayOffset(previousStopTime.ArrivalTime),
+				OriginDepartureDayOffset:    getDayOffset(previousStopTime.DepartureTime),
+				DestinationArrivalDayOffset: getDayOffset(stopTime.ArrivalTime),
+
+				OriginPlatform:      stopPlatformCodes[previousStopTime.StopID],
+				DestinationPlatform: stopPlatformCodes[stopTime.StopID],
+				DestinationDisplay:  stopTime.StopHeadsign,
+				OriginActivity:      []ctdf.JourneyPathItemActivity{},
+				DestinationActivity: []ctdf.JourneyPathItemActivity{},
 			}
 
 			if previousStopTime.DropOffType == 0 {
@@ -465,26 +726,148 @@ func (g *Schedule) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceR
 
 		// Insert
 		if dataset.SupportedObjects.Journeys {
-			bsonRep, _ := bson.Marshal(bson.M{"$set": ctdfJourneys[tripID]})
-			updateModel := mongo.NewUpdateOneModel()
-			updateModel.SetFilter(bson.M{"primaryidentifier": ctdfJourneys[tripID].PrimaryIdentifier})
-			updateModel.SetUpdate(bsonRep)
-			updateModel.SetUpsert(true)
+			if frequencies, exists := frequencyMapping[tripID]; exists {
+				// Frequency based trips get a journey per headway instead of the template journey
+				for startTime, journey := range expandFrequencies(ctdfJourneys[tripID], frequencies) {
+					journeysQueue.Add(createJourneyUpdateModel(journey))
 
-			journeysQueue.Add(updateModel)
+					tripMapping.Add(identifiermapping.FrequencyTripID(tripID, startTime), journey.PrimaryIdentifier)
+				}
+			} else {
+				journeysQueue.Add(createJourneyUpdateModel(ctdfJourneys[tripID]))
+
+				tripMapping.Add(tripID, ctdfJourneys[tripID].PrimaryIdentifier)
+			}
 		}
 
 		ctdfJourneys[tripID] = nil
 	}
+	if len(unknownStopRefs) > 0 {
+		log.Warn().Int("stops", len(unknownStopRefs)).Msg("Journeys reference stops that dont exist")
+	}
 	log.Info().Msg("Finished Journeys")
 
 	if dataset.SupportedObjects.Journeys {
-		journeysQueue.Wait()
+		if err := journeysQueue.Wait(); err != nil {
+			return err
+		}
+	}
+
+	// Store the GTFS -> CTDF identifier mappings for the GTFS-RT consumers
+	if !datasink.IsDryRun(dataset.Sink) {
+		for _, mapping := range []*identifiermapping.Mapping{stopMapping, routeMapping, tripMapping} {
+			var err error
+			// A single operator reimport only has that operators identifiers so they're merged into the existing mapping
+			if len(dataset.OnlyOperators) > 0 {
+				err = mapping.Merge()
+			} else {
+				err = mapping.Save()
+			}
+			if err != nil {
+				log.Error().Err(err).Str("type", string(mapping.Type)).Msg("Failed to save identifier mapping")
+			}
+		}
+
+		agencyOperatorRefs := map[string]string{}
+		for _, agency := range g.Agencies {
+			agencyOperatorRefs[agency.ID] = agencyNOCMapping[agency.ID]
+			if agencyOperatorRefs[agency.ID] == "" {
+				agencyOperatorRefs[agency.ID] = fmt.Sprintf("%s-operator-%s", identifierPrefix, agency.ID)
+			}
+		}
+
+		err := saveAgencyStatistics(datasource, g.getAgencyStatistics(datasource, agencyOperatorRefs))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to save agency import statistics")
+		}
 	}
 
 	return nil
 }
 
+func createJourneyUpdateModel(journey *ctdf.Journey) *mongo.UpdateOneModel {
+	filter := journey.GetUpsertFilter()
+
+	bsonRep, _ := bson.Marshal(bson.M{"$set": journey})
+	updateModel := mongo.NewUpdateOneModel()
+	updateModel.SetFilter(filter)
+	updateModel.SetUpdate(bsonRep)
+	updateModel.SetUpsert(true)
+
+	return updateModel
+}
+
+// getNOCOperatorByName finds the NOC operator with the same name as an agency, only when there is exactly one
+func getNOCOperatorByName(dataset datasets.DataSet, name string) string {
+	nocPrefix := strings.TrimSuffix(ctdf.OperatorNOCIDFormat, "%s")
+
+	var operatorRef string
+	for _, record := range dataset.Lookups.Operators().GetByName(name) {
+		if !strings.HasPrefix(record.PrimaryIdentifier, nocPrefix) {
+			continue
+		}
+
+		if operatorRef != "" {
+			return ""
+		}
+		operatorRef = record.PrimaryIdentifier
+	}
+
+	return operatorRef
+}
+
+// getStopTransportTypes works out the modes of the routes calling at each stop, stations also get the modes calling at their platforms
+func (g *Schedule) getStopTransportTypes() map[string][]ctdf.TransportType {
+	routeTransportTypes := map[string]ctdf.TransportType{}
+	for _, route := range g.Routes {
+		routeTransportTypes[route.ID] = convertTransportType(route.Type)
+	}
+
+	tripTransportTypes := map[string]ctdf.TransportType{}
+	for _, trip := range g.Trips {
+		tripTransportTypes[trip.ID] = routeTransportTypes[trip.RouteID]
+	}
+
+	stopParents := map[string]string{}
+	for _, stop := range g.Stops {
+		if stop.Parent != "" {
+			stopParents[stop.ID] = stop.Parent
+		}
+	}
+
+	stopTransportTypes := map[string][]ctdf.TransportType{}
+	for _, stopTime := range g.StopTimes {
+		transportType := tripTransportTypes[stopTime.TripID]
+		if transportType == "" || transportType == ctdf.TransportTypeUnknown {
+			continue
+		}
+
+		// Walk up from boarding area to platform to station, limited in case of a badly formed hierarchy
+		stopID := stopTime.StopID
+		for depth := 0; stopID != "" && depth < 3; depth++ {
+			if !slices.Contains(stopTransportTypes[stopID], transportType) {
+				stopTransportTypes[stopID] = append(stopTransportTypes[stopID], transportType)
+			}
+
+			stopID = stopParents[stopID]
+		}
+	}
+
+	return stopTransportTypes
+}
+
+// getStopRef references the stops of the dataset itself, unless the dataset uses the stop codes of its countries
+// national stop database (eg. ATCO codes in BODS) in which case the stops already exist from that import
+func getStopRef(dataset *datasets.DataSet, stopID string) string {
+	if dataset.CustomConfig["nationalstopidentifiers"] == "true" {
+		if stopRef, exists := dataset.GetCountryProfile().FormatNationalStopID(stopID); exists {
+			return stopRef
+		}
+	}
+
+	return fmt.Sprintf("%s-stop-%s", dataset.GetIdentifierPrefix(), stopID)
+}
+
 func convertTransportType(intType int) ctdf.TransportType {
 	routeTypeMapping := map[int]ctdf.TransportType{
 		0:    ctdf.TransportTypeTram,
@@ -527,6 +910,16 @@ func convertTransportType(intType int) ctdf.TransportType {
 	}
 }
 
+// getDayOffset gives the number of days after the service day a GTFS time past 24:00:00 falls on
+func getDayOffset(timestamp string) int {
+	hour, err := strconv.Atoi(strings.Split(timestamp, ":")[0])
+	if err != nil || hour < 24 {
+		return 0
+	}
+
+	return hour / 24
+}
+
 func fixTimestamp(timestamp string) string {
 	splitTimestamp := strings.Split(timestamp, ":")
 
@@ -1,5 +1,7 @@
 package gtfs
 
+import "github.com/travigo/travigo/pkg/ctdf"
+
 type Agency struct {
 	ID       string `csv:"agency_id"`
 	Name     string `csv:"agency_name"`
@@ -30,6 +32,26 @@ type Stop struct {
 	PlatformCode string  `csv:"platform_code"`
 }
 
+func (s *Stop) GetStopType() ctdf.StopType {
+	switch s.Type {
+	case "1":
+		return ctdf.StopTypeStation
+	case "2":
+		return ctdf.StopTypeEntrance
+	case "3":
+		return ctdf.StopTypeNode
+	case "4":
+		return ctdf.StopTypeBoardingArea
+	default:
+		// A stop with a parent station is a platform of that station
+		if s.Parent != "" {
+			return ctdf.StopTypePlatform
+		}
+
+		return ctdf.StopTypeStop
+	}
+}
+
 type Route struct {
 	ID                string `csv:"route_id"`
 	AgencyID          string `csv:"agency_id"`
@@ -136,3 +158,29 @@ type Shape struct {
 	PointSequence    int     `csv:"shape_pt_sequence"`
 	DistanceTraveled float64 `csv:"shape_dist_traveled"`
 }
+
+type Transfer struct {
+	FromStopID      string `csv:"from_stop_id"`
+	ToStopID        string `csv:"to_stop_id"`
+	FromRouteID     string `csv:"from_route_id"`
+	ToRouteID       string `csv:"to_route_id"`
+	FromTripID      string `csv:"from_trip_id"`
+	ToTripID        string `csv:"to_trip_id"`
+	Type            int    `csv:"transfer_type"`
+	MinTransferTime int    `csv:"min_transfer_time"`
+}
+
+func (t *Transfer) GetTransferType() ctdf.TransferType {
+	switch t.Type {
+	case 1:
+		return ctdf.TransferTypeTimed
+	case 2:
+		return ctdf.TransferTypeMinimumTime
+	case 3, 5:
+		return ctdf.TransferTypeNotPossible
+	case 4:
+		return ctdf.TransferTypeInSeat
+	default:
+		return ctdf.TransferTypeRecommended
+	}
+}
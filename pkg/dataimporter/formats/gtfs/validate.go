@@ -0,0 +1,186 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+)
+
+// Files every feed in an archive must have, it also needs at least one of calendar.txt or calendar_dates.txt
+var requiredScheduleFiles = []string{"agency.txt", "stops.txt", "routes.txt", "trips.txt", "stop_times.txt"}
+
+// Only the first few broken references of each kind are listed so a badly broken feed gives a readable report
+const maxValidationExamples = 5
+
+// ValidationReport is the outcome of checking a schedule before it gets imported.
+// Errors stop the feed from being imported, warnings are worth passing back to whoever publishes it
+type ValidationReport struct {
+	Valid    bool
+	Errors   []string
+	Warnings []string
+
+	Agencies  int
+	Stops     int
+	Routes    int
+	Trips     int
+	StopTimes int
+}
+
+func (r *ValidationReport) addReferenceErrors(description string, missing []string) {
+	if len(missing) == 0 {
+		return
+	}
+
+	examples := missing
+	if len(examples) > maxValidationExamples {
+		examples = examples[:maxValidationExamples]
+	}
+
+	r.Errors = append(r.Errors, fmt.Sprintf("%d %s, eg. %v", len(missing), description, examples))
+}
+
+// Validate checks the feed has the files a schedule needs, parses it & checks that routes, trips & stop times
+// reference agencies, routes, stops & calendars that exist in the feed
+func Validate(reader io.Reader) (*ValidationReport, error) {
+	report := &ValidationReport{}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("Feed is not a zip archive: %s", err))
+		return report, nil
+	}
+
+	feeds, err := getArchiveFeeds(archive)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("Failed to read feed: %s", err))
+		return report, nil
+	}
+	if len(feeds) == 0 {
+		report.Errors = append(report.Errors, "Feed doesn't contain any GTFS files")
+		return report, nil
+	}
+
+	for _, feedName := range getSortedFeedNames(feeds) {
+		files := feeds[feedName]
+
+		location := "Feed"
+		if feedName != "" {
+			location = fmt.Sprintf("Feed %s", feedName)
+		}
+
+		for _, fileName := range requiredScheduleFiles {
+			if files[fileName] == nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s is missing %s", location, fileName))
+			}
+		}
+		if files["calendar.txt"] == nil && files["calendar_dates.txt"] == nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s needs calendar.txt or calendar_dates.txt", location))
+		}
+	}
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	// Bad rows are counted up rather than stopping the parse so the rest of the feed still gets checked
+	collector := importerrors.NewCollector(math.MaxInt)
+	schedule := &Schedule{errors: collector}
+	if err := schedule.ParseFile(bytes.NewReader(body)); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("Failed to parse feed: %s", err))
+		return report, nil
+	}
+	if failures := collector.Failures(); failures > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d values could not be parsed and will be left empty", failures))
+	}
+
+	report.Agencies = len(schedule.Agencies)
+	report.Stops = len(schedule.Stops)
+	report.Routes = len(schedule.Routes)
+	report.Trips = len(schedule.Trips)
+	report.StopTimes = len(schedule.StopTimes)
+
+	if report.Agencies == 0 || report.Stops == 0 || report.Routes == 0 || report.Trips == 0 || report.StopTimes == 0 {
+		report.Errors = append(report.Errors, "Feed must have at least one agency, stop, route, trip & stop time")
+	}
+
+	schedule.validateReferences(report)
+
+	report.Valid = len(report.Errors) == 0
+
+	return report, nil
+}
+
+func (gtfs *Schedule) validateReferences(report *ValidationReport) {
+	agencies := map[string]bool{}
+	for _, agency := range gtfs.Agencies {
+		agencies[agency.ID] = true
+	}
+	stops := map[string]bool{}
+	for _, stop := range gtfs.Stops {
+		stops[stop.ID] = true
+	}
+	routes := map[string]bool{}
+	for _, route := range gtfs.Routes {
+		routes[route.ID] = true
+	}
+	calendars := map[string]bool{}
+	for _, calendar := range gtfs.Calendars {
+		calendars[calendar.ServiceID] = true
+	}
+	for _, calendarDate := range gtfs.CalendarDates {
+		calendars[calendarDate.ServiceID] = true
+	}
+
+	// Routes only have to give their agency when there is more than one in the feed
+	var missingAgencies []string
+	for _, route := range gtfs.Routes {
+		if route.AgencyID == "" && len(gtfs.Agencies) <= 1 {
+			continue
+		}
+		if !agencies[route.AgencyID] {
+			missingAgencies = append(missingAgencies, route.ID)
+		}
+	}
+	report.addReferenceErrors("routes have an unknown agency_id", missingAgencies)
+
+	trips := map[string]bool{}
+	var missingRoutes []string
+	var missingCalendars []string
+	for _, trip := range gtfs.Trips {
+		trips[trip.ID] = true
+
+		if !routes[trip.RouteID] {
+			missingRoutes = append(missingRoutes, trip.ID)
+		}
+		if !calendars[trip.ServiceID] {
+			missingCalendars = append(missingCalendars, trip.ID)
+		}
+	}
+	report.addReferenceErrors("trips have an unknown route_id", missingRoutes)
+
+	// Trips without a calendar never run but don't stop the rest of the feed being imported
+	if len(missingCalendars) > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d trips have a service_id without a calendar and will never run", len(missingCalendars)))
+	}
+
+	var missingTrips []string
+	var missingStops []string
+	for i, stopTime := range gtfs.StopTimes {
+		if !trips[stopTime.TripID] {
+			missingTrips = append(missingTrips, fmt.Sprintf("row %d", i+1))
+		}
+		if !stops[stopTime.StopID] {
+			missingStops = append(missingStops, fmt.Sprintf("row %d", i+1))
+		}
+	}
+	report.addReferenceErrors("stop times have an unknown trip_id", missingTrips)
+	report.addReferenceErrors("stop times have an unknown stop_id", missingStops)
+}
@@ -0,0 +1,107 @@
+package naptan
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NaPTAN has no car parks of its own but park & ride sites are served by stops named after them
+var parkAndRideNameRegex = regexp.MustCompile(`(?i)\b(park\s*(&|and|\+|'n'|n)\s*ride|p\s*&\s*r)\b`)
+
+func isParkAndRideStop(stopPoint *StopPoint) bool {
+	if stopPoint.Descriptor == nil || stopPoint.Status != "active" {
+		return false
+	}
+
+	return parkAndRideNameRegex.MatchString(stopPoint.Descriptor.CommonName) ||
+		parkAndRideNameRegex.MatchString(stopPoint.Descriptor.Landmark)
+}
+
+// inferParkAndRideSites creates a car park for each park & ride site, combining the stops in the same StopArea into one site.
+// Capacity isn't known from NaPTAN so has to come from another dataset.
+func (naptanDoc *NaPTAN) inferParkAndRideSites(dataset datasets.DataSet, datasource *ctdf.DataSourceReference, importRef string) (int, error) {
+	stopAreaNames := map[string]string{}
+	for _, stopArea := range naptanDoc.StopAreas {
+		stopAreaNames[stopArea.StopAreaCode] = stopArea.Name
+	}
+
+	carParks := map[string]*ctdf.CarPark{}
+	var carParkOrder []string
+	now := time.Now()
+
+	err := eachSpilledStopPoint(importRef, spillKindParkAndRide, nil, func(stopPoint *StopPoint) error {
+		if stopPoint.Location == nil {
+			return nil
+		}
+
+		// Sites without a StopArea are kept to just the one stop
+		siteCode := stopPoint.AtcoCode
+		name := stopPoint.Descriptor.CommonName
+		if len(stopPoint.StopAreas) > 0 {
+			siteCode = stopPoint.StopAreas[0].StopAreaCode
+			if stopAreaNames[siteCode] != "" {
+				name = stopAreaNames[siteCode]
+			}
+		}
+
+		carParkID := fmt.Sprintf(ctdf.CarParkIDFormat, "gb-naptan", siteCode)
+		stop := stopPoint.ToCTDF()
+
+		carPark := carParks[carParkID]
+		if carPark == nil {
+			carPark = &ctdf.CarPark{
+				PrimaryIdentifier:    carParkID,
+				CreationDateTime:     now,
+				ModificationDateTime: now,
+				DataSource:           datasource,
+				Name:                 name,
+				Location: &ctdf.Location{
+					Type:        "Point",
+					Coordinates: []float64{0, 0},
+				},
+				ParkAndRide: true,
+			}
+			carParks[carParkID] = carPark
+			carParkOrder = append(carParkOrder, carParkID)
+		}
+
+		carPark.StopRefs = append(carPark.StopRefs, stop.PrimaryIdentifier)
+
+		// Keep a running average so the site sits between its stops
+		count := float64(len(carPark.StopRefs))
+		carPark.Location.Coordinates[0] += (stop.Location.Coordinates[0] - carPark.Location.Coordinates[0]) / count
+		carPark.Location.Coordinates[1] += (stop.Location.Coordinates[1] - carPark.Location.Coordinates[1]) / count
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var carParkOperations []mongo.WriteModel
+	for _, carParkID := range carParkOrder {
+		bsonRep, _ := bson.Marshal(bson.M{"$set": carParks[carParkID]})
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": carParkID})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		carParkOperations = append(carParkOperations, updateModel)
+	}
+
+	if len(carParkOperations) > 0 {
+		_, err := dataset.Sink.BulkWrite(database.GetCollection("car_parks"), carParkOperations)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(carParkOperations), nil
+}
@@ -1,7 +1,6 @@
 package naptan
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -10,15 +9,15 @@ import (
 	"sync/atomic"
 
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
 	"github.com/travigo/travigo/pkg/transforms"
-	"github.com/travigo/travigo/pkg/util"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const DateTimeFormat string = "2006-01-02T15:04:05"
@@ -31,6 +30,17 @@ type NaPTAN struct {
 
 	StopPoints []*StopPoint
 	StopAreas  []*StopArea
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+}
+
+func (naptanDoc *NaPTAN) SetupProgress(tracker *progress.Tracker) {
+	naptanDoc.progress = tracker
+}
+
+func (naptanDoc *NaPTAN) SetupErrors(collector *importerrors.Collector) {
+	naptanDoc.errors = collector
 }
 
 func (naptanDoc *NaPTAN) Validate() error {
@@ -52,8 +62,42 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 		return errors.New("This format requires stops & stopgroups to be enabled")
 	}
 
-	stopsCollection := database.GetCollection("stops_raw")
+	stationStopGroups, err := naptanDoc.importStopGroups(dataset, datasource)
+	if err != nil {
+		return err
+	}
+
+	// StopPoints
+	log.Info().Msg("Converting & Importing CTDF Stops into Mongo")
+	pipeline := newStopPointPipeline(dataset, datasource)
+	defer pipeline.RemoveSpilled()
+	for _, stopPoint := range naptanDoc.StopPoints {
+		pipeline.Add(stopPoint)
+	}
+	if err := pipeline.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Msg(" - Written to MongoDB")
+	log.Info().Msgf(" - %d inserts", pipeline.inserts)
+
+	if err := naptanDoc.importDerivedObjects(dataset, datasource, pipeline, stationStopGroups); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Successfully imported into MongoDB")
+
+	return nil
+}
+
+// importStopGroups writes the StopAreas as StopGroups and returns the identifiers of those that are stations
+func (naptanDoc *NaPTAN) importStopGroups(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) (map[string]bool, error) {
 	stopGroupsCollection := database.GetCollection("stop_groups")
+	stationStopGroups := map[string]bool{}
+
+	if len(naptanDoc.StopAreas) == 0 {
+		return stationStopGroups, nil
+	}
 
 	// StopAreas
 	log.Info().Msg("Converting & Importing CTDF StopGroups into Mongo")
@@ -65,8 +109,8 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 	processingGroup := sync.WaitGroup{}
 	processingGroup.Add(numBatches)
 
-	stationStopGroups := map[string]bool{}
 	stationStopGroupsMutex := sync.Mutex{}
+	var writeErr error
 
 	for i := 0; i < numBatches; i++ {
 		lower := maxBatchSize * i
@@ -108,9 +152,11 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 			atomic.AddUint64(&stopGroupsOperationInsert, localOperationInsert)
 
 			if len(stopGroupOperations) > 0 {
-				_, err := stopGroupsCollection.BulkWrite(context.Background(), stopGroupOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(stopGroupsCollection, stopGroupOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write StopGroups")
+					stationStopGroupsMutex.Lock()
+					writeErr = err
+					stationStopGroupsMutex.Unlock()
 				}
 			}
 
@@ -120,112 +166,48 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", stopGroupsOperationInsert)
 
-	// StopPoints
-	log.Info().Msg("Converting & Importing CTDF Stops into Mongo")
-	var stopOperationInsert uint64
-
-	maxBatchSize = int(math.Ceil(float64(len(naptanDoc.StopPoints)) / float64(runtime.NumCPU()*10)))
-	numBatches = int(math.Ceil(float64(len(naptanDoc.StopPoints)) / float64(maxBatchSize)))
-
-	stationStopGroupContents := map[string][]*StopPoint{}
-	stationStopGroupContentsnMutex := sync.Mutex{}
-	var stationStops []*StopPoint
-	stationStopsMutex := sync.Mutex{}
-
-	processingGroup = sync.WaitGroup{}
-	processingGroup.Add(numBatches)
-
-	for i := 0; i < numBatches; i++ {
-		lower := maxBatchSize * i
-		upper := maxBatchSize * (i + 1)
-
-		if upper > len(naptanDoc.StopPoints) {
-			upper = len(naptanDoc.StopPoints)
-		}
-
-		batchSlice := naptanDoc.StopPoints[lower:upper]
-
-		go func(stopPoints []*StopPoint) {
-			var stopOperations []mongo.WriteModel
-			var localOperationInsert uint64
-
-			for _, naptanStopPoint := range stopPoints {
-				ctdfStop := naptanStopPoint.ToCTDF()
-				ctdfStop.DataSource = datasource
-
-				for _, association := range ctdfStop.Associations {
-					if stationStopGroups[association.AssociatedIdentifier] {
-						stationStopGroupContentsnMutex.Lock()
-						stationStopGroupContents[association.AssociatedIdentifier] = append(stationStopGroupContents[association.AssociatedIdentifier], naptanStopPoint)
-						stationStopGroupContentsnMutex.Unlock()
-					}
-				}
-
-				// Add to list of stations for processing later and then skip it
-				if util.ContainsString([]string{
-					"MET", "RLY", "FER",
-				}, naptanStopPoint.StopClassification.StopType) {
-					stationStopsMutex.Lock()
-					stationStops = append(stationStops, naptanStopPoint)
-					stationStopsMutex.Unlock()
-
-					continue
-				}
-
-				// Also skip any station entrances/platforms
-				if util.ContainsString([]string{
-					"PLT", "RPL", "FBT", "TMU", "RSE", "FTD",
-				}, naptanStopPoint.StopClassification.StopType) {
-					continue
-				}
-
-				transforms.Transform(ctdfStop, 3)
-
-				ctdfStop.DataSource = datasource
-
-				bsonRep, _ := bson.Marshal(bson.M{"$set": ctdfStop})
-				updateModel := mongo.NewUpdateOneModel()
-				updateModel.SetFilter(bson.M{"primaryidentifier": ctdfStop.PrimaryIdentifier})
-				updateModel.SetUpdate(bsonRep)
-				updateModel.SetUpsert(true)
-
-				stopOperations = append(stopOperations, updateModel)
-				localOperationInsert += 1
-			}
-
-			atomic.AddUint64(&stopOperationInsert, localOperationInsert)
-
-			if len(stopOperations) > 0 {
-				_, err := stopsCollection.BulkWrite(context.Background(), stopOperations, &options.BulkWriteOptions{})
-				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write Stops")
-				}
-			}
-
-			processingGroup.Done()
-		}(batchSlice)
-	}
-
-	processingGroup.Wait()
+	return stationStopGroups, nil
+}
 
-	log.Info().Msg(" - Written to MongoDB")
-	log.Info().Msgf(" - %d inserts", stopOperationInsert)
+// importDerivedObjects creates the objects that can only be worked out once every StopPoint has been seen,
+// the station Stops made up of their platforms & entrances along with the parents of the stops around them, Transfers and park & ride CarParks
+func (naptanDoc *NaPTAN) importDerivedObjects(dataset datasets.DataSet, datasource *ctdf.DataSourceReference, pipeline *stopPointPipeline, stationStopGroups map[string]bool) error {
+	stopsCollection := database.GetCollection("stops_raw")
+	stopAreaStops := pipeline.stopAreaStops
 
 	// Specially handle generating new station stops
 	log.Info().Msg("Converting & Importing CTDF station Stops into Mongo")
 	var stationStopOperations []mongo.WriteModel
 	var stationStopOperationInsert int
 
-	for _, stationNaptanStop := range stationStops {
+	err := eachSpilledStopPoint(pipeline.importRef, spillKindStation, nil, func(stationNaptanStop *StopPoint) error {
 		stationStop := stationNaptanStop.ToCTDF()
 		stationStop.DataSource = datasource
 
-		var stopGroupStops []*StopPoint
+		var stationStopGroupRefs []string
 		for _, area := range stationNaptanStop.StopAreas {
-			stopGroupStops = append(stopGroupStops, stationStopGroupContents[fmt.Sprintf("gb-stopgroup-%s", area.StopAreaCode)]...)
+			stopGroupIdentifier := fmt.Sprintf("gb-stopgroup-%s", area.StopAreaCode)
+			if stationStopGroups[stopGroupIdentifier] {
+				stationStopGroupRefs = append(stationStopGroupRefs, stopGroupIdentifier)
+			}
+		}
+
+		var stopGroupStops []*StopPoint
+		if len(stationStopGroupRefs) > 0 {
+			err := eachSpilledStopPoint(pipeline.importRef, spillKindStationContent, bson.M{"stopgrouprefs": bson.M{"$in": stationStopGroupRefs}}, func(stopPoint *StopPoint) error {
+				stopGroupStops = append(stopGroupStops, stopPoint)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
 		}
 
 		// Find all platforms & entrances and add them to the stops
@@ -243,27 +225,29 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 					Location: stop.Location,
 				})
 				stationStop.OtherIdentifiers = append(stationStop.OtherIdentifiers, stop.PrimaryIdentifier)
-			} else {
+			} else if stopPoint.StopClassification.StopType == "TMU" || stopPoint.StopClassification.StopType == "RSE" || stopPoint.StopClassification.StopType == "FTD" {
 				// TMU - Metro/tram
 				// RSE - Rail
 				// FTD - Ferry
-				// if stopPoint.StopClassification.StopType == "TMU" || stopPoint.StopClassification.StopType == "RSE" || stopPoint.StopClassification.StopType == "FTD" {
-				// 	stop := stopPoint.ToCTDF()
-				// 	stationStop.Entrances = append(stationStop.Entrances, &ctdf.StopEntrance{
-				// 		PrimaryIdentifier: stop.PrimaryIdentifier,
+				stop := stopPoint.ToCTDF()
+				stationStop.Entrances = append(stationStop.Entrances, &ctdf.StopEntrance{
+					PrimaryIdentifier: stop.PrimaryIdentifier,
 
-				// 		PrimaryName: stop.PrimaryName,
+					PrimaryName: stop.PrimaryName,
 
-				// 		Location: stop.Location,
-				// 	})
-				// 	stationStop.OtherIdentifiers = append(stationStop.OtherIdentifiers, stop.PrimaryIdentifier)
-				// }
+					Location: stop.Location,
+				})
+				stationStop.OtherIdentifiers = append(stationStop.OtherIdentifiers, stop.PrimaryIdentifier)
 			}
 		}
 
 		transforms.Transform(stationStop, 2)
 
-		bsonRep, _ := bson.Marshal(bson.M{"$set": stationStop})
+		for _, association := range stationStop.Associations {
+			stopAreaStops[association.AssociatedIdentifier] = append(stopAreaStops[association.AssociatedIdentifier], stationStop)
+		}
+
+		bsonRep, _ := bson.Marshal(getStopUpdate(stationStop))
 		updateModel := mongo.NewUpdateOneModel()
 		updateModel.SetFilter(bson.M{"primaryidentifier": stationStop.PrimaryIdentifier})
 		updateModel.SetUpdate(bsonRep)
@@ -271,18 +255,61 @@ func (naptanDoc *NaPTAN) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 		stationStopOperations = append(stationStopOperations, updateModel)
 		stationStopOperationInsert += 1
+
+		// Any other stops in the station's StopAreas (eg. taxi ranks & bus stops outside) are children of the station
+		if len(stationStopGroupRefs) > 0 {
+			childrenModel := mongo.NewUpdateManyModel()
+			childrenModel.SetFilter(bson.M{
+				"associations.associatedidentifier": bson.M{"$in": stationStopGroupRefs},
+				"datasource.datasetid":              datasource.DatasetID,
+				"primaryidentifier":                 bson.M{"$ne": stationStop.PrimaryIdentifier},
+			})
+			childrenModel.SetUpdate(bson.M{"$set": bson.M{"parentstopref": stationStop.PrimaryIdentifier}})
+
+			stationStopOperations = append(stationStopOperations, childrenModel)
+		}
+
+		if len(stationStopOperations) >= stopWriteBatchSize {
+			if _, err := dataset.Sink.BulkWrite(stopsCollection, stationStopOperations); err != nil {
+				return err
+			}
+			stationStopOperations = nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if len(stationStopOperations) > 0 {
-		_, err := stopsCollection.BulkWrite(context.Background(), stationStopOperations, &options.BulkWriteOptions{})
+		_, err := dataset.Sink.BulkWrite(stopsCollection, stationStopOperations)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to bulk write station Stops")
+			return err
 		}
 	}
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", stationStopOperationInsert)
 
-	log.Info().Msgf("Successfully imported into MongoDB")
+	if dataset.SupportedObjects.Transfers {
+		log.Info().Msg("Inferring CTDF Transfers between Stops in the same StopArea")
+		transferInsert, err := inferTransfers(dataset, datasource, stopAreaStops)
+		if err != nil {
+			return err
+		}
+		log.Info().Msg(" - Written to MongoDB")
+		log.Info().Msgf(" - %d inserts", transferInsert)
+	}
+
+	if dataset.SupportedObjects.CarParks {
+		log.Info().Msg("Inferring CTDF CarParks from park & ride Stops")
+		carParkInsert, err := naptanDoc.inferParkAndRideSites(dataset, datasource, pipeline.importRef)
+		if err != nil {
+			return err
+		}
+		log.Info().Msg(" - Written to MongoDB")
+		log.Info().Msgf(" - %d inserts", carParkInsert)
+	}
 
 	return nil
 }
@@ -0,0 +1,216 @@
+package naptan
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/transforms"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const stopWriteBatchSize = 1000
+
+// stopPointPipeline converts StopPoints to CTDF Stops and writes them as they're added, so only the StopPoints
+// waiting in the channels are held in memory. The ones needed once every StopPoint has been seen are spilled to the database
+type stopPointPipeline struct {
+	dataset    datasets.DataSet
+	datasource *ctdf.DataSourceReference
+	importRef  string
+
+	stopPoints chan *StopPoint
+	operations chan pipelineOperation
+
+	transformGroup sync.WaitGroup
+	writeGroup     sync.WaitGroup
+
+	inserts uint64
+	// First write that failed, the rest of the operations are dropped once it's set
+	err error
+
+	// Only the identifier & location of each stop is kept for working out the transfers
+	stopAreaStops map[string][]*ctdf.Stop
+
+	mutex sync.Mutex
+}
+
+type pipelineOperation struct {
+	// Spilled StopPoints go to the scratch collection instead of the stops
+	spill bool
+	model mongo.WriteModel
+}
+
+func newStopPointPipeline(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) *stopPointPipeline {
+	workers := runtime.NumCPU()
+
+	pipeline := &stopPointPipeline{
+		dataset:       dataset,
+		datasource:    datasource,
+		importRef:     getSpillImportRef(datasource),
+		stopPoints:    make(chan *StopPoint, workers*10),
+		operations:    make(chan pipelineOperation, stopWriteBatchSize),
+		stopAreaStops: map[string][]*ctdf.Stop{},
+	}
+
+	pipeline.transformGroup.Add(workers)
+	for i := 0; i < workers; i++ {
+		go pipeline.transform()
+	}
+
+	pipeline.writeGroup.Add(1)
+	go pipeline.write()
+
+	return pipeline
+}
+
+// Add queues a StopPoint, blocking while the transform stage is behind so the parser can't run away with the memory
+func (p *stopPointPipeline) Add(stopPoint *StopPoint) {
+	p.stopPoints <- stopPoint
+}
+
+// Close waits for every added StopPoint to be written, returning the error if any of the writes failed
+func (p *stopPointPipeline) Close() error {
+	close(p.stopPoints)
+	p.transformGroup.Wait()
+
+	close(p.operations)
+	p.writeGroup.Wait()
+
+	return p.err
+}
+
+// RemoveSpilled clears out the StopPoints spilled by this import once they're no longer needed
+func (p *stopPointPipeline) RemoveSpilled() {
+	if err := removeSpilledStopPoints(p.importRef); err != nil {
+		log.Error().Err(err).Str("import", p.importRef).Msg("Failed to remove spilled StopPoints")
+	}
+}
+
+func (p *stopPointPipeline) transform() {
+	defer p.transformGroup.Done()
+
+	for naptanStopPoint := range p.stopPoints {
+		ctdfStop := naptanStopPoint.ToCTDF()
+		ctdfStop.DataSource = p.datasource
+
+		if p.dataset.SupportedObjects.CarParks && isParkAndRideStop(naptanStopPoint) {
+			p.operations <- pipelineOperation{spill: true, model: newSpillOperation(p.importRef, spillKindParkAndRide, naptanStopPoint, nil)}
+		}
+
+		// Spill stations for processing later and then skip it
+		if util.ContainsString([]string{
+			"MET", "RLY", "FER",
+		}, naptanStopPoint.StopClassification.StopType) {
+			p.operations <- pipelineOperation{spill: true, model: newSpillOperation(p.importRef, spillKindStation, naptanStopPoint, nil)}
+
+			continue
+		}
+
+		// Also skip any station entrances/platforms, they're spilled with the StopGroups they're in so the stations can find them
+		if util.ContainsString([]string{
+			"PLT", "RPL", "FBT", "TMU", "RSE", "FTD",
+		}, naptanStopPoint.StopClassification.StopType) {
+			var stopGroupRefs []string
+			for _, association := range ctdfStop.Associations {
+				stopGroupRefs = append(stopGroupRefs, association.AssociatedIdentifier)
+			}
+			if len(stopGroupRefs) > 0 {
+				p.operations <- pipelineOperation{spill: true, model: newSpillOperation(p.importRef, spillKindStationContent, naptanStopPoint, stopGroupRefs)}
+			}
+
+			continue
+		}
+
+		transforms.Transform(ctdfStop, 3)
+		ctdfStop.UpdatePlusCode()
+
+		ctdfStop.DataSource = p.datasource
+
+		if p.dataset.SupportedObjects.Transfers {
+			transferStop := &ctdf.Stop{
+				PrimaryIdentifier: ctdfStop.PrimaryIdentifier,
+				Location:          ctdfStop.Location,
+			}
+
+			p.mutex.Lock()
+			for _, association := range ctdfStop.Associations {
+				p.stopAreaStops[association.AssociatedIdentifier] = append(p.stopAreaStops[association.AssociatedIdentifier], transferStop)
+			}
+			p.mutex.Unlock()
+		}
+
+		bsonRep, _ := bson.Marshal(getStopUpdate(ctdfStop))
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": ctdfStop.PrimaryIdentifier})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		p.operations <- pipelineOperation{model: updateModel}
+	}
+}
+
+func (p *stopPointPipeline) write() {
+	defer p.writeGroup.Done()
+
+	stopsCollection := database.GetCollection("stops_raw")
+	spillCollection := database.GetCollection(spillCollectionName)
+	var stopOperations []mongo.WriteModel
+	var spillOperations []mongo.WriteModel
+
+	flushStops := func() {
+		if len(stopOperations) == 0 || p.err != nil {
+			return
+		}
+
+		_, err := p.dataset.Sink.BulkWrite(stopsCollection, stopOperations)
+		if err != nil {
+			// Keep draining the operations so the transform stage doesn't block
+			p.err = err
+			return
+		}
+
+		atomic.AddUint64(&p.inserts, uint64(len(stopOperations)))
+		stopOperations = []mongo.WriteModel{}
+	}
+
+	flushSpill := func() {
+		if len(spillOperations) == 0 || p.err != nil {
+			return
+		}
+
+		_, err := spillCollection.BulkWrite(context.Background(), spillOperations, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			p.err = err
+			return
+		}
+
+		spillOperations = []mongo.WriteModel{}
+	}
+
+	for operation := range p.operations {
+		if operation.spill {
+			spillOperations = append(spillOperations, operation.model)
+
+			if len(spillOperations) >= stopWriteBatchSize {
+				flushSpill()
+			}
+		} else {
+			stopOperations = append(stopOperations, operation.model)
+
+			if len(stopOperations) >= stopWriteBatchSize {
+				flushStops()
+			}
+		}
+	}
+
+	flushStops()
+	flushSpill()
+}
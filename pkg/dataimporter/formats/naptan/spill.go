@@ -0,0 +1,85 @@
+package naptan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The StopPoints only needed once the whole document has been read are parked here rather than kept in memory.
+// It's only scratch space for the import so is written to directly instead of through the datasets sink
+const spillCollectionName = "naptan_import_stoppoints"
+
+type spillKind string
+
+const (
+	spillKindStation        spillKind = "station"
+	spillKindStationContent spillKind = "stationcontent"
+	spillKindParkAndRide    spillKind = "parkandride"
+)
+
+type spilledStopPoint struct {
+	// Keeps the StopPoints of imports running at the same time apart
+	ImportRef string
+	Kind      spillKind
+	// StopGroups the platform or entrance is in
+	StopGroupRefs []string `bson:",omitempty"`
+
+	StopPoint *StopPoint
+
+	// Anything left behind by an import that died is expired from this
+	CreationDateTime time.Time
+}
+
+func getSpillImportRef(datasource *ctdf.DataSourceReference) string {
+	return fmt.Sprintf("%s-%s", datasource.DatasetID, datasource.Timestamp)
+}
+
+func newSpillOperation(importRef string, kind spillKind, stopPoint *StopPoint, stopGroupRefs []string) mongo.WriteModel {
+	return mongo.NewInsertOneModel().SetDocument(spilledStopPoint{
+		ImportRef:     importRef,
+		Kind:          kind,
+		StopGroupRefs: stopGroupRefs,
+		StopPoint:     stopPoint,
+
+		CreationDateTime: time.Now(),
+	})
+}
+
+// eachSpilledStopPoint calls handler with every StopPoint of the kind, filtered further by the filter if it's set
+func eachSpilledStopPoint(importRef string, kind spillKind, filter bson.M, handler func(*StopPoint) error) error {
+	query := bson.M{"importref": importRef, "kind": kind}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	cursor, err := database.GetCollection(spillCollectionName).Find(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var spilled spilledStopPoint
+		if err := cursor.Decode(&spilled); err != nil {
+			return err
+		}
+
+		if err := handler(spilled.StopPoint); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func removeSpilledStopPoints(importRef string) error {
+	_, err := database.GetCollection(spillCollectionName).DeleteMany(context.Background(), bson.M{"importref": importRef})
+
+	return err
+}
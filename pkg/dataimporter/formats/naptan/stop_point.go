@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/names"
 	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type StopPoint struct {
@@ -27,6 +29,20 @@ type StopPoint struct {
 	StopClassification StopClassification
 
 	StopAreas []StopPointStopAreaRef `xml:"StopAreas>StopAreaRef"`
+
+	StopValidity []StopValidity `xml:"StopAvailability>StopValidity"`
+}
+
+// StopValidity is a period the stop is in use, suspended or has been replaced by another stop
+type StopValidity struct {
+	StartDate string `xml:"DateRange>StartDate"`
+	EndDate   string `xml:"DateRange>EndDate"`
+
+	Active      *struct{}
+	Suspended   *struct{}
+	Transferred *struct {
+		StopPointRef string
+	}
 }
 
 type StopClassification struct {
@@ -194,9 +210,28 @@ func (orig *StopPoint) ToCTDF() *ctdf.Stop {
 		},
 
 		Active:   orig.Status == "active",
+		Status:   getStopStatus(orig.Status),
 		Timezone: "Europe/London",
 	}
 
+	orig.applyStopValidity(&ctdfStop, time.Now())
+	ctdfStop.Active = ctdfStop.Status == ctdf.StopStatusActive
+
+	switch orig.StopClassification.StopType {
+	case "RLY", "MET", "FER": // railAccess, tramMetroOrUndergroundAccess, ferryOrPortAccess
+		ctdfStop.StopType = ctdf.StopTypeStation
+	case "RPL", "PLT", "FBT": // railPlatform, tramMetroOrUndergroundPlatform, ferryBerth
+		ctdfStop.StopType = ctdf.StopTypePlatform
+	case "RSE", "TMU", "FTD", "BCE", "AIR": // railStationEntrance, tramMetroOrUndergroundEntrance, ferryTerminalDockEntrance, busCoachStationEntrance, airportEntrance
+		ctdfStop.StopType = ctdf.StopTypeEntrance
+	default:
+		ctdfStop.StopType = ctdf.StopTypeStop
+	}
+
+	if orig.NptgLocalityRef != "" {
+		ctdfStop.LocalityRef = fmt.Sprintf(ctdf.LocalityIDFormat, orig.NptgLocalityRef)
+	}
+
 	if orig.AtcoCode != "" {
 		ctdfStop.OtherIdentifiers = append(ctdfStop.OtherIdentifiers, fmt.Sprintf("gb-atco-%s", orig.AtcoCode))
 	}
@@ -219,5 +254,87 @@ func (orig *StopPoint) ToCTDF() *ctdf.Stop {
 		})
 	}
 
+	names.NormaliseStop(&ctdfStop)
+
 	return &ctdfStop
 }
+
+func getStopStatus(status string) ctdf.StopStatus {
+	switch status {
+	case "inactive":
+		return ctdf.StopStatusInactive
+	case "pending":
+		return ctdf.StopStatusPending
+	default:
+		return ctdf.StopStatusActive
+	}
+}
+
+// applyStopValidity takes the effective dates from the period the stop is in use now, or the next one if it isn't yet,
+// and marks stops suspended right now as such
+func (orig *StopPoint) applyStopValidity(stop *ctdf.Stop, now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var effective *StopValidity
+	var effectiveFrom time.Time
+
+	for i := range orig.StopValidity {
+		validity := &orig.StopValidity[i]
+
+		startDate, err := time.Parse(time.DateOnly, validity.StartDate)
+		if err != nil {
+			continue
+		}
+		endDate, _ := time.Parse(time.DateOnly, validity.EndDate)
+
+		// Periods that have already finished don't matter any more
+		if !endDate.IsZero() && endDate.Before(today) {
+			continue
+		}
+
+		if validity.Suspended != nil && !startDate.After(today) && stop.Status == ctdf.StopStatusActive {
+			stop.Status = ctdf.StopStatusSuspended
+		}
+
+		if validity.Active != nil && (effective == nil || startDate.Before(effectiveFrom)) {
+			effective = validity
+			effectiveFrom = startDate
+		}
+	}
+
+	if effective == nil {
+		return
+	}
+
+	stop.EffectiveFrom = effectiveFrom
+	if endDate, err := time.Parse(time.DateOnly, effective.EndDate); err == nil {
+		// End dates are inclusive so the stop is in use until the end of the day
+		stop.EffectiveUntil = endDate.Add(24*time.Hour - time.Second)
+	}
+}
+
+// getStopUpdate sets the stop & clears the fields that are left out of it for being empty,
+// otherwise a stop that's no longer active or has lost its effective dates would keep the old values
+func getStopUpdate(stop *ctdf.Stop) bson.M {
+	unset := bson.M{}
+	if !stop.Active {
+		unset["active"] = ""
+	}
+	if stop.EffectiveFrom.IsZero() {
+		unset["effectivefrom"] = ""
+	}
+	if stop.EffectiveUntil.IsZero() {
+		unset["effectiveuntil"] = ""
+	}
+	// Only set once the stations have been imported so it's cleared here in case the stop has left the station
+	if stop.ParentStopRef == "" {
+		unset["parentstopref"] = ""
+	}
+
+	update := bson.M{"$set": stop}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return update
+}
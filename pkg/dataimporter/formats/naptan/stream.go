@@ -0,0 +1,49 @@
+package naptan
+
+import (
+	"errors"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+)
+
+// ImportStream imports the StopPoints while the document is still being read so the full set of them is never
+// in memory at once. The access nodes file is hundreds of MB so this is used in place of ParseFile & Import
+func (naptanDoc *NaPTAN) ImportStream(reader io.Reader, dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Stops || !dataset.SupportedObjects.StopGroups {
+		return errors.New("This format requires stops & stopgroups to be enabled")
+	}
+
+	log.Info().Msg("Streaming CTDF Stops into Mongo")
+	pipeline := newStopPointPipeline(dataset, datasource)
+	defer pipeline.RemoveSpilled()
+	err := naptanDoc.decode(reader, pipeline.Add)
+	if closeErr := pipeline.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Successfully parsed document")
+	log.Info().Msgf(" - Last modified %s", naptanDoc.ModificationDateTime)
+	log.Info().Msgf(" - Contains %d stop areas", len(naptanDoc.StopAreas))
+	log.Info().Msg(" - Written to MongoDB")
+	log.Info().Msgf(" - %d inserts", pipeline.inserts)
+
+	// StopAreas come after the StopPoints in the document so can only be written once it has all been read
+	stationStopGroups, err := naptanDoc.importStopGroups(dataset, datasource)
+	if err != nil {
+		return err
+	}
+
+	if err := naptanDoc.importDerivedObjects(dataset, datasource, pipeline, stationStopGroups); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Successfully imported into MongoDB")
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package naptan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Stops further apart than this are unlikely to be a sensible interchange even if they share a StopArea
+	transferMaximumDistance = 500 // metres
+	// Very large StopAreas (eg. whole town centres) would produce a huge number of pairs so are skipped
+	transferMaximumStopAreaSize = 50
+
+	transferBatchSize = 1000
+)
+
+// inferTransfers generates walking transfers between every pair of stops that share a StopArea.
+// NaPTAN doesn't publish interchange times so they are estimated from the distance between the stops
+func inferTransfers(dataset datasets.DataSet, datasource *ctdf.DataSourceReference, stopAreaStops map[string][]*ctdf.Stop) (int, error) {
+	transfersCollection := database.GetCollection("transfers")
+
+	var transferOperations []mongo.WriteModel
+	var transferInsert int
+	seenTransfers := map[string]bool{}
+
+	flush := func() error {
+		if len(transferOperations) == 0 {
+			return nil
+		}
+
+		_, err := dataset.Sink.BulkWrite(transfersCollection, transferOperations)
+		if err != nil {
+			return err
+		}
+
+		transferOperations = []mongo.WriteModel{}
+
+		return nil
+	}
+
+	for _, stops := range stopAreaStops {
+		if len(stops) < 2 || len(stops) > transferMaximumStopAreaSize {
+			continue
+		}
+
+		for _, fromStop := range stops {
+			for _, toStop := range stops {
+				if fromStop.PrimaryIdentifier == toStop.PrimaryIdentifier {
+					continue
+				}
+
+				transferID := fmt.Sprintf(ctdf.TransferIDFormat, dataset.Identifier, fromStop.PrimaryIdentifier, toStop.PrimaryIdentifier)
+
+				// A pair of stops can be in multiple StopAreas together
+				if seenTransfers[transferID] {
+					continue
+				}
+				seenTransfers[transferID] = true
+
+				if fromStop.Location != nil && toStop.Location != nil && fromStop.Location.GreatCircleDistance(toStop.Location) > transferMaximumDistance {
+					continue
+				}
+
+				transfer := &ctdf.Transfer{
+					PrimaryIdentifier:    transferID,
+					CreationDateTime:     time.Now(),
+					ModificationDateTime: time.Now(),
+					DataSource:           datasource,
+					FromStopRef:          fromStop.PrimaryIdentifier,
+					ToStopRef:            toStop.PrimaryIdentifier,
+					Type:                 ctdf.TransferTypeMinimumTime,
+					MinimumTransferTime:  ctdf.EstimateTransferTime(fromStop.Location, toStop.Location),
+					Inferred:             true,
+				}
+
+				bsonRep, _ := bson.Marshal(bson.M{"$set": transfer})
+				updateModel := mongo.NewUpdateOneModel()
+				updateModel.SetFilter(bson.M{"primaryidentifier": transferID})
+				updateModel.SetUpdate(bsonRep)
+				updateModel.SetUpsert(true)
+
+				transferOperations = append(transferOperations, updateModel)
+				transferInsert += 1
+
+				if len(transferOperations) >= transferBatchSize {
+					if err := flush(); err != nil {
+						return 0, err
+					}
+				}
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return transferInsert, nil
+}
@@ -5,11 +5,31 @@ import (
 	"io"
 
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
 	"golang.org/x/net/html/charset"
 )
 
 func (n *NaPTAN) ParseFile(reader io.Reader) error {
 	n.StopPoints = []*StopPoint{}
+
+	err := n.decode(reader, func(stopPoint *StopPoint) {
+		n.StopPoints = append(n.StopPoints, stopPoint)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Successfully parsed document")
+	log.Info().Msgf(" - Last modified %s", n.ModificationDateTime)
+	log.Info().Msgf(" - Contains %d stops", len(n.StopPoints))
+	log.Info().Msgf(" - Contains %d stop areas", len(n.StopAreas))
+
+	return nil
+}
+
+// decode reads the document one element at a time, handing each StopPoint over as soon as it's decoded.
+// StopAreas are kept on the document as they're needed to work out the stations
+func (n *NaPTAN) decode(reader io.Reader, handleStopPoint func(*StopPoint)) error {
 	n.StopAreas = []*StopArea{}
 
 	d := xml.NewDecoder(reader)
@@ -20,7 +40,6 @@ func (n *NaPTAN) ParseFile(reader io.Reader) error {
 			// EOF means we're done.
 			break
 		} else if err != nil {
-			log.Fatal().Msgf("Error decoding token: %s", err)
 			return err
 		}
 
@@ -48,29 +67,30 @@ func (n *NaPTAN) ParseFile(reader io.Reader) error {
 				var stopPoint StopPoint
 
 				if err = d.DecodeElement(&stopPoint, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := n.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					stopPoint.Location.UpdateCoordinates()
-					n.StopPoints = append(n.StopPoints, &stopPoint)
+					handleStopPoint(&stopPoint)
+					n.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "StopArea" {
 				var stopArea StopArea
 
 				if err = d.DecodeElement(&stopArea, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := n.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					stopArea.Location.UpdateCoordinates()
 					n.StopAreas = append(n.StopAreas, &stopArea)
+					n.progress.AddRecords(1)
 				}
 			}
 		default:
 		}
 	}
 
-	log.Info().Msgf("Successfully parsed document")
-	log.Info().Msgf(" - Last modified %s", n.ModificationDateTime)
-	log.Info().Msgf(" - Contains %d stops", len(n.StopPoints))
-	log.Info().Msgf(" - Contains %d stop areas", len(n.StopAreas))
-
 	return nil
 }
@@ -0,0 +1,69 @@
+package nationalrailincidents
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Incident struct {
+	CreationTime   string
+	IncidentNumber string
+	Version        string
+
+	ValidityPeriod []TimePeriod
+
+	Planned         bool
+	ClearedIncident bool
+
+	Summary        string
+	Description    string
+	RoutesAffected string `xml:"Affects>RoutesAffected"`
+	InfoURL        string `xml:"InfoLinks>InfoLink>Uri"`
+
+	AffectedOperators []AffectedOperator `xml:"Affects>Operators>AffectedOperator"`
+}
+
+type TimePeriod struct {
+	StartTime string
+	EndTime   string
+}
+
+type AffectedOperator struct {
+	OperatorRef  string
+	OperatorName string
+}
+
+// GetPeriods returns each validity period of the incident, skipping any that can't be parsed or have no end
+// as planned works always have a known end
+func (i *Incident) GetPeriods() [][2]time.Time {
+	var periods [][2]time.Time
+
+	for _, period := range i.ValidityPeriod {
+		startTime, err := time.Parse(time.RFC3339, period.StartTime)
+		if err != nil {
+			continue
+		}
+		endTime, err := time.Parse(time.RFC3339, period.EndTime)
+		if err != nil || !endTime.After(startTime) {
+			continue
+		}
+
+		periods = append(periods, [2]time.Time{startTime, endTime})
+	}
+
+	return periods
+}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// stripHTML turns the HTML fragments used in the incident text fields into plain text
+func stripHTML(text string) string {
+	text = htmlTagRegex.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = whitespaceRegex.ReplaceAllString(text, " ")
+
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,199 @@
+package nationalrailincidents
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/html/charset"
+)
+
+// Incidents is the National Rail Knowledgebase incidents feed. Only the planned engineering works are imported
+// as the unplanned disruption arrives much quicker over Darwin
+type Incidents struct {
+	Incidents []*Incident
+
+	errors *importerrors.Collector
+}
+
+func (i *Incidents) SetupErrors(errors *importerrors.Collector) {
+	i.errors = errors
+}
+
+func (i *Incidents) ParseFile(reader io.Reader) error {
+	d := xml.NewDecoder(reader)
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if tok == nil || err == io.EOF {
+			break
+		} else if err != nil {
+			return &importerrors.ParseError{Err: err}
+		}
+
+		switch ty := tok.(type) {
+		case xml.StartElement:
+			if ty.Name.Local == "PtIncident" {
+				var incident Incident
+
+				if err = d.DecodeElement(&incident, &ty); err != nil {
+					if err := i.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
+				} else {
+					i.Incidents = append(i.Incidents, &incident)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i *Incidents) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.ServiceAlerts {
+		return errors.New("This format requires servicealerts to be enabled")
+	}
+
+	datasource.OriginalFormat = "nationalrail-incidents"
+
+	stations, err := loadStationNames()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var operations []mongo.WriteModel
+
+	for _, incident := range i.Incidents {
+		serviceAlert, err := i.convertToCTDF(incident, stations, dataset, datasource, now)
+		if err != nil {
+			return err
+		}
+		if serviceAlert == nil {
+			continue
+		}
+
+		bsonRep, _ := bson.Marshal(bson.M{"$set": serviceAlert})
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": serviceAlert.PrimaryIdentifier})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		operations = append(operations, updateModel)
+	}
+
+	serviceAlertsCollection := database.GetCollection("service_alerts")
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(serviceAlertsCollection, operations); err != nil {
+			return &importerrors.WriteError{Collection: "service_alerts", Err: err}
+		}
+	}
+
+	// Works that have been withdrawn from the feed get removed
+	deleted, err := dataset.Sink.DeleteMany(serviceAlertsCollection, bson.M{
+		"datasource.datasetid": datasource.DatasetID,
+		"datasource.timestamp": bson.M{"$ne": datasource.Timestamp},
+	})
+	if err != nil {
+		return &importerrors.WriteError{Collection: "service_alerts", Err: err}
+	}
+
+	log.Info().
+		Int("incidents", len(i.Incidents)).
+		Int("planned", len(operations)).
+		Int64("removed", deleted).
+		Msg("Imported National Rail planned works")
+
+	return nil
+}
+
+func (i *Incidents) convertToCTDF(incident *Incident, stations []*stationName, dataset datasets.DataSet, datasource *ctdf.DataSourceReference, now time.Time) (*ctdf.ServiceAlert, error) {
+	if !incident.Planned || incident.ClearedIncident || incident.IncidentNumber == "" {
+		return nil, nil
+	}
+
+	periods := incident.GetPeriods()
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	serviceAlert := &ctdf.ServiceAlert{
+		PrimaryIdentifier: fmt.Sprintf("%s-servicealert-%s", dataset.Identifier, incident.IncidentNumber),
+		OtherIdentifiers: map[string]string{
+			"IncidentNumber": incident.IncidentNumber,
+		},
+		CreationDateTime:     now,
+		ModificationDateTime: now,
+		DataSource:           datasource,
+
+		AlertType: ctdf.ServiceAlertTypePlanned,
+		Title:     stripHTML(incident.Summary),
+		Text:      stripHTML(incident.Description),
+	}
+
+	// Works are shown from when they're published so they can be planned around, not just while they're happening
+	serviceAlert.ValidFrom, _ = time.Parse(time.RFC3339, incident.CreationTime)
+	if serviceAlert.ValidFrom.IsZero() {
+		serviceAlert.ValidFrom = now
+	}
+
+	for _, period := range periods {
+		if period[1].After(serviceAlert.ValidUntil) {
+			serviceAlert.ValidUntil = period[1]
+		}
+
+		serviceAlert.DisruptionPeriods = append(serviceAlert.DisruptionPeriods, ctdf.ServiceAlertPeriod{
+			From:  period[0],
+			Until: period[1],
+		})
+	}
+
+	if serviceAlert.ValidUntil.Before(now) {
+		return nil, nil
+	}
+
+	// Alerts are pinned to the affected stations where they can be found, otherwise they apply to the whole operator
+	crs := util.RemoveDuplicateStrings(findStations(stations, stripHTML(incident.RoutesAffected)), []string{})
+	if len(crs) == 0 {
+		crs = util.RemoveDuplicateStrings(findStations(stations, serviceAlert.Title), []string{})
+	}
+
+	for _, stopID := range crs {
+		serviceAlert.Scopes = append(serviceAlert.Scopes, ctdf.ServiceAlertScope{
+			Type:       ctdf.ServiceAlertScopeTypeStop,
+			Identifier: stopID,
+		})
+	}
+	if len(crs) == 0 {
+		for _, operator := range incident.AffectedOperators {
+			if operator.OperatorRef == "" {
+				continue
+			}
+
+			serviceAlert.Scopes = append(serviceAlert.Scopes, ctdf.ServiceAlertScope{
+				Type:       ctdf.ServiceAlertScopeTypeOperator,
+				Identifier: fmt.Sprintf(ctdf.OperatorTOCFormat, operator.OperatorRef),
+			})
+		}
+	}
+
+	if len(serviceAlert.Scopes) == 0 {
+		return nil, i.errors.Add(&importerrors.ReferenceError{Object: "ServiceAlert", Identifier: serviceAlert.PrimaryIdentifier, Reference: "affected stations"})
+	}
+
+	serviceAlert.GenerateMatchKeys()
+
+	return serviceAlert, nil
+}
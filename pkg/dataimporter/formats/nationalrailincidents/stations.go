@@ -0,0 +1,99 @@
+package nationalrailincidents
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Station names shorter than this match too many ordinary words to be picked out of free text
+const minimumStationNameLength = 4
+
+var stationNameSuffixes = []string{" rail station", " railway station", " station"}
+
+type stationName struct {
+	Name  string
+	Regex *regexp.Regexp
+	CRS   []string
+}
+
+// loadStationNames builds the list of rail station names to look for in incident text, longest first so that
+// "London Victoria" is preferred over "Victoria"
+func loadStationNames() ([]*stationName, error) {
+	stopsCollection := database.GetCollection("stops")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryname", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+	})
+	cursor, err := stopsCollection.Find(context.Background(), bson.M{"otheridentifiers": bson.M{"$regex": "^gb-crs-"}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stationsByName := map[string]*stationName{}
+	for cursor.Next(context.Background()) {
+		var stop ctdf.Stop
+		if err := cursor.Decode(&stop); err != nil {
+			continue
+		}
+
+		name := strings.ToLower(stop.PrimaryName)
+		for _, suffix := range stationNameSuffixes {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		if len(name) < minimumStationNameLength {
+			continue
+		}
+
+		if stationsByName[name] == nil {
+			stationsByName[name] = &stationName{
+				Name:  name,
+				Regex: regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`),
+			}
+		}
+
+		for _, identifier := range stop.OtherIdentifiers {
+			if strings.HasPrefix(identifier, "gb-crs-") {
+				stationsByName[name].CRS = append(stationsByName[name].CRS, identifier)
+			}
+		}
+	}
+
+	var stations []*stationName
+	for _, station := range stationsByName {
+		stations = append(stations, station)
+	}
+	sort.Slice(stations, func(i, j int) bool {
+		return len(stations[i].Name) > len(stations[j].Name)
+	})
+
+	return stations, nil
+}
+
+// findStations returns the CRS identifiers of every station named in the text
+func findStations(stations []*stationName, text string) []string {
+	var crs []string
+	text = strings.ToLower(text)
+
+	for _, station := range stations {
+		if !strings.Contains(text, station.Name) {
+			continue
+		}
+
+		if station.Regex.MatchString(text) {
+			crs = append(crs, station.CRS...)
+
+			// Blank out the match so a shorter name inside it doesn't also match
+			text = station.Regex.ReplaceAllString(text, " ")
+		}
+	}
+
+	return crs
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TrainOperatingCompanyList struct {
@@ -101,6 +100,8 @@ func (t *TrainOperatingCompanyList) Import(dataset datasets.DataSet, datasource
 	numBatches := int(math.Ceil(float64(len(operators)) / float64(maxBatchSize)))
 
 	processingGroup := sync.WaitGroup{}
+	var writeErr error
+	writeErrMutex := sync.Mutex{}
 	processingGroup.Add(numBatches)
 
 	for i := 0; i < numBatches; i++ {
@@ -135,9 +136,11 @@ func (t *TrainOperatingCompanyList) Import(dataset datasets.DataSet, datasource
 			atomic.AddUint64(&operatorOperationInsert, localOperationInsert)
 
 			if len(operatorOperations) > 0 {
-				_, err := operatorsCollection.BulkWrite(context.Background(), operatorOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(operatorsCollection, operatorOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write Operators")
+					writeErrMutex.Lock()
+					writeErr = err
+					writeErrMutex.Unlock()
 				}
 			}
 
@@ -147,6 +150,10 @@ func (t *TrainOperatingCompanyList) Import(dataset datasets.DataSet, datasource
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return writeErr
+	}
+
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", operatorOperationInsert)
 
@@ -193,9 +200,11 @@ func (t *TrainOperatingCompanyList) Import(dataset datasets.DataSet, datasource
 			atomic.AddUint64(&servicesOperationInsert, localServicesInsert)
 
 			if len(servicesOperations) > 0 {
-				_, err := servicesCollection.BulkWrite(context.Background(), servicesOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(servicesCollection, servicesOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write Services")
+					writeErrMutex.Lock()
+					writeErr = err
+					writeErrMutex.Unlock()
 				}
 			}
 
@@ -205,6 +214,10 @@ func (t *TrainOperatingCompanyList) Import(dataset datasets.DataSet, datasource
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return writeErr
+	}
+
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", servicesOperationInsert)
 
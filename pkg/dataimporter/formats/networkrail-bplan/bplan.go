@@ -0,0 +1,71 @@
+package networkrailbplan
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const writeBatchSize = 1000
+
+// Import fills in the geography of the rail locations CORPUS has already created, BPLAN on its own doesn't
+// have the CRS codes needed to make a useful location so any TIPLOCs CORPUS doesn't know about are skipped
+func (b *BPLAN) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.RailLocations {
+		return errors.New("This format requires rail locations to be enabled")
+	}
+
+	railLocationsCollection := database.GetCollection("rail_locations")
+	now := time.Now()
+
+	var operations []mongo.WriteModel
+	updated := 0
+
+	for _, location := range b.Locations {
+		update := bson.M{
+			"platforms":            location.Platforms,
+			"links":                location.Links,
+			"modificationdatetime": now,
+		}
+		if ctdfLocation := location.GetLocation(); ctdfLocation != nil {
+			update["location"] = ctdfLocation
+		}
+
+		bsonRep, _ := bson.Marshal(bson.M{"$set": update})
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": fmt.Sprintf(ctdf.RailLocationIDFormat, location.TIPLOC)}).
+			SetUpdate(bsonRep),
+		)
+
+		if len(operations) >= writeBatchSize {
+			result, err := dataset.Sink.BulkWrite(railLocationsCollection, operations)
+			if err != nil {
+				return err
+			}
+			updated += int(result.MatchedCount)
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		result, err := dataset.Sink.BulkWrite(railLocationsCollection, operations)
+		if err != nil {
+			return err
+		}
+		updated += int(result.MatchedCount)
+	}
+
+	log.Info().
+		Int("locations", len(b.Locations)).
+		Int("updated", updated).
+		Msg("Imported BPLAN geography into Rail Locations")
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package networkrailbplan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulcager/osgridref"
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+const dateTimeFormat = "02-01-2006 15:04:05"
+
+// BPLAN is the Network Rail geography extract, a tab separated file with a record per location, platform & link
+// between locations. Only the records that are still current are kept.
+type BPLAN struct {
+	Locations map[string]*Location
+}
+
+type Location struct {
+	TIPLOC string
+	Name   string
+	STANOX string
+
+	Easting  int
+	Northing int
+
+	Platforms []*ctdf.RailLocationPlatform
+	Links     []*ctdf.RailLocationLink
+}
+
+// GetLocation converts the OS grid reference, nil for locations BPLAN doesn't have a position for
+func (l *Location) GetLocation() *ctdf.Location {
+	if l.Easting <= 0 || l.Northing <= 0 {
+		return nil
+	}
+
+	gridRef, err := osgridref.ParseOsGridRef(fmt.Sprintf("%d,%d", l.Easting, l.Northing))
+	if err != nil {
+		return nil
+	}
+
+	lat, lon := gridRef.ToLatLon()
+
+	return &ctdf.Location{
+		Type:        "Point",
+		Coordinates: []float64{lon, lat},
+	}
+}
+
+func (b *BPLAN) ParseFile(reader io.Reader) error {
+	b.Locations = map[string]*Location{}
+
+	now := time.Now()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		// Deleted records are only in the update extracts, the full extract is all inserts
+		if len(fields) < 3 || fields[1] == "D" {
+			continue
+		}
+
+		switch fields[0] {
+		case "LOC":
+			// LOC, action, TIPLOC, name, start date, end date, easting, northing, timing point type, zone, STANOX
+			if len(fields) < 11 || hasEnded(fields[5], now) {
+				continue
+			}
+
+			location := b.getLocation(fields[2])
+			location.Name = fields[3]
+			location.Easting, _ = strconv.Atoi(fields[6])
+			location.Northing, _ = strconv.Atoi(fields[7])
+			location.STANOX = fields[10]
+		case "PLT":
+			// PLT, action, TIPLOC, platform ID, start date, end date, length, power supply
+			if len(fields) < 8 || hasEnded(fields[5], now) {
+				continue
+			}
+
+			length, _ := strconv.Atoi(fields[6])
+
+			location := b.getLocation(fields[2])
+			location.Platforms = append(location.Platforms, &ctdf.RailLocationPlatform{
+				PlatformID:  fields[3],
+				Length:      length,
+				PowerSupply: fields[7],
+			})
+		case "NWK":
+			// NWK, action, origin TIPLOC, destination TIPLOC, running line code, running line description,
+			// start date, end date, initial direction, final direction, distance
+			if len(fields) < 11 || hasEnded(fields[7], now) {
+				continue
+			}
+
+			distance, err := strconv.Atoi(fields[10])
+			if err != nil {
+				continue
+			}
+
+			location := b.getLocation(fields[2])
+			location.Links = append(location.Links, &ctdf.RailLocationLink{
+				DestinationTIPLOC: fields[3],
+				RunningLine:       fields[4],
+				Distance:          distance,
+			})
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (b *BPLAN) getLocation(tiploc string) *Location {
+	location, exists := b.Locations[tiploc]
+	if !exists {
+		location = &Location{TIPLOC: tiploc}
+		b.Locations[tiploc] = location
+	}
+
+	return location
+}
+
+// hasEnded is whether the end date of a record is in the past, records that are still current have none
+func hasEnded(endDate string, now time.Time) bool {
+	if endDate == "" {
+		return false
+	}
+
+	end, err := time.Parse(dateTimeFormat, endDate)
+	if err != nil {
+		return false
+	}
+
+	return end.Before(now)
+}
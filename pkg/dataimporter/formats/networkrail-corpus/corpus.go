@@ -1,9 +1,9 @@
 package networkrailcorpus
 
 import (
-	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,9 +11,9 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/names"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Corpus struct {
@@ -30,23 +30,35 @@ type TiplocData struct {
 	NLCDESC16  string
 }
 
+// Import adds the TIPLOC, STANOX & CRS codes to the stops they belong to and, when enabled, builds the rail
+// location registry keyed on TIPLOC that BPLAN then fills in the geography of
 func (c *Corpus) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
-	if !dataset.SupportedObjects.Stops {
-		return errors.New("This format requires stops to be enabled")
+	if !dataset.SupportedObjects.Stops && !dataset.SupportedObjects.RailLocations {
+		return errors.New("This format requires stops or rail locations to be enabled")
 	}
 
 	now := time.Now()
 
 	stopsCollection := database.GetCollection("stops_raw")
+	railLocationsCollection := database.GetCollection("rail_locations")
 
 	var updateOperations []mongo.WriteModel
+	var railLocationOperations []mongo.WriteModel
 
 	for _, tiplocData := range c.TiplocData {
 		tiploc := strings.TrimSpace(tiplocData.TIPLOC)
 		stanox := strings.TrimSpace(tiplocData.STANOX)
 		threeAlpha := strings.TrimSpace(tiplocData.ThreeAlpha)
 
-		if tiploc == "" || stanox == "" {
+		if tiploc == "" {
+			continue
+		}
+
+		if dataset.SupportedObjects.RailLocations {
+			railLocationOperations = append(railLocationOperations, getRailLocationUpdate(tiplocData, datasource, now))
+		}
+
+		if !dataset.SupportedObjects.Stops || stanox == "" {
 			continue
 		}
 
@@ -82,11 +94,63 @@ func (c *Corpus) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 	}
 
 	if len(updateOperations) > 0 {
-		_, err := stopsCollection.BulkWrite(context.Background(), updateOperations, &options.BulkWriteOptions{})
+		_, err := dataset.Sink.BulkWrite(stopsCollection, updateOperations)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to bulk write Stops")
+			return err
 		}
 	}
 
+	if len(railLocationOperations) > 0 {
+		_, err := dataset.Sink.BulkWrite(railLocationsCollection, railLocationOperations)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Int("length", len(railLocationOperations)).Msg("Imported Rail Locations")
+	}
+
 	return nil
 }
+
+// getRailLocationUpdate only sets the fields CORPUS knows about so the geography from BPLAN & the stop link are kept
+func getRailLocationUpdate(tiplocData TiplocData, datasource *ctdf.DataSourceReference, now time.Time) mongo.WriteModel {
+	tiploc := strings.TrimSpace(tiplocData.TIPLOC)
+	stanox := strings.TrimSpace(tiplocData.STANOX)
+	threeAlpha := strings.TrimSpace(tiplocData.ThreeAlpha)
+
+	primaryID := fmt.Sprintf(ctdf.RailLocationIDFormat, tiploc)
+
+	otherIDs := []string{primaryID}
+	if stanox != "" {
+		otherIDs = append(otherIDs, fmt.Sprintf("gb-stanox-%s", stanox))
+	}
+	if threeAlpha != "" {
+		otherIDs = append(otherIDs, fmt.Sprintf("gb-crs-%s", threeAlpha))
+	}
+
+	railLocation := bson.M{
+		"primaryidentifier":    primaryID,
+		"otheridentifiers":     otherIDs,
+		"tiploc":               tiploc,
+		"crs":                  threeAlpha,
+		"stanox":               stanox,
+		"uic":                  strings.TrimSpace(tiplocData.UIC),
+		"name":                 names.Normalise(tiplocData.NLCDESC, names.LocaleEnglish),
+		"shortname":            names.Normalise(tiplocData.NLCDESC16, names.LocaleEnglish),
+		"datasource":           datasource,
+		"modificationdatetime": now,
+	}
+	if tiplocData.NLC != 0 {
+		railLocation["nlc"] = strconv.Itoa(tiplocData.NLC)
+	}
+
+	bsonRep, _ := bson.Marshal(bson.M{
+		"$set":         railLocation,
+		"$setOnInsert": bson.M{"creationdatetime": now},
+	})
+
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.M{"primaryidentifier": primaryID}).
+		SetUpdate(bsonRep).
+		SetUpsert(true)
+}
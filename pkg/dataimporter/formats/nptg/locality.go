@@ -0,0 +1,55 @@
+package nptg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/naptan"
+)
+
+type NptgLocality struct {
+	CreationDateTime     string `xml:",attr"`
+	ModificationDateTime string `xml:",attr"`
+
+	NptgLocalityCode string
+	LocalityName     string `xml:"Descriptor>LocalityName"`
+	QualifierName    string `xml:"Descriptor>Qualify>QualifierName"`
+
+	ParentNptgLocalityRef string
+	AdministrativeAreaRef string
+	NptgDistrictRef       string
+	SourceLocalityType    string
+
+	Location *naptan.Location
+}
+
+func (orig *NptgLocality) ToCTDF() *ctdf.Locality {
+	creationTime, _ := time.Parse(DateTimeFormat, orig.CreationDateTime)
+	modificationTime, _ := time.Parse(DateTimeFormat, orig.ModificationDateTime)
+
+	ctdfLocality := &ctdf.Locality{
+		PrimaryIdentifier: fmt.Sprintf(ctdf.LocalityIDFormat, orig.NptgLocalityCode),
+
+		CreationDateTime:     creationTime,
+		ModificationDateTime: modificationTime,
+
+		Name:      orig.LocalityName,
+		Qualifier: orig.QualifierName,
+	}
+
+	if orig.ParentNptgLocalityRef != "" {
+		ctdfLocality.ParentLocalityRef = fmt.Sprintf(ctdf.LocalityIDFormat, orig.ParentNptgLocalityRef)
+	}
+	if orig.AdministrativeAreaRef != "" {
+		ctdfLocality.AdministrativeAreaRef = fmt.Sprintf(ctdf.AdministrativeAreaIDFormat, orig.AdministrativeAreaRef)
+	}
+	if orig.Location != nil {
+		ctdfLocality.Location = &ctdf.Location{
+			Type:        "Point",
+			Coordinates: []float64{orig.Location.Longitude, orig.Location.Latitude},
+		}
+	}
+
+	return ctdfLocality
+}
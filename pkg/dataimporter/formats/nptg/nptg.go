@@ -0,0 +1,133 @@
+package nptg
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const DateTimeFormat string = "2006-01-02T15:04:05"
+
+const importBatchSize = 1000
+
+type NPTG struct {
+	CreationDateTime     string `xml:",attr"`
+	ModificationDateTime string `xml:",attr"`
+
+	SchemaVersion string `xml:",attr"`
+
+	Regions    []*Region
+	Localities []*NptgLocality
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+}
+
+func (nptgDoc *NPTG) SetupProgress(tracker *progress.Tracker) {
+	nptgDoc.progress = tracker
+}
+
+func (nptgDoc *NPTG) SetupErrors(collector *importerrors.Collector) {
+	nptgDoc.errors = collector
+}
+
+func (nptgDoc *NPTG) Validate() error {
+	if nptgDoc.CreationDateTime == "" {
+		return errors.New("CreationDateTime must be set")
+	}
+	if nptgDoc.ModificationDateTime == "" {
+		return errors.New("ModificationDateTime must be set")
+	}
+	if nptgDoc.SchemaVersion != "2.4" && nptgDoc.SchemaVersion != "2.5" {
+		return errors.New(fmt.Sprintf("SchemaVersion must be 2.4 or 2.5 but is %s", nptgDoc.SchemaVersion))
+	}
+
+	return nil
+}
+
+func (nptgDoc *NPTG) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.Localities || !dataset.SupportedObjects.AdministrativeAreas {
+		return errors.New("This format requires localities & administrativeareas to be enabled")
+	}
+
+	// AdministrativeAreas
+	log.Info().Msg("Converting & Importing CTDF AdministrativeAreas into Mongo")
+	administrativeAreasCollection := database.GetCollection("administrative_areas")
+	var administrativeAreaOperations []mongo.WriteModel
+
+	for _, region := range nptgDoc.Regions {
+		for _, administrativeArea := range region.AdministrativeAreas {
+			ctdfAdministrativeArea := administrativeArea.ToCTDF()
+			ctdfAdministrativeArea.Region = region.RegionCode
+			ctdfAdministrativeArea.DataSource = datasource
+
+			administrativeAreaOperations = append(administrativeAreaOperations, newUpsertModel(ctdfAdministrativeArea.PrimaryIdentifier, ctdfAdministrativeArea))
+		}
+	}
+
+	err := writeBatches(dataset, administrativeAreasCollection, administrativeAreaOperations)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg(" - Written to MongoDB")
+	log.Info().Msgf(" - %d inserts", len(administrativeAreaOperations))
+
+	// Localities
+	log.Info().Msg("Converting & Importing CTDF Localities into Mongo")
+	localitiesCollection := database.GetCollection("localities")
+	var localityOperations []mongo.WriteModel
+
+	for _, locality := range nptgDoc.Localities {
+		ctdfLocality := locality.ToCTDF()
+		ctdfLocality.DataSource = datasource
+
+		localityOperations = append(localityOperations, newUpsertModel(ctdfLocality.PrimaryIdentifier, ctdfLocality))
+	}
+
+	err = writeBatches(dataset, localitiesCollection, localityOperations)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg(" - Written to MongoDB")
+	log.Info().Msgf(" - %d inserts", len(localityOperations))
+
+	log.Info().Msgf("Successfully imported into MongoDB")
+
+	return nil
+}
+
+func newUpsertModel(primaryIdentifier string, record interface{}) mongo.WriteModel {
+	bsonRep, _ := bson.Marshal(bson.M{"$set": record})
+	updateModel := mongo.NewUpdateOneModel()
+	updateModel.SetFilter(bson.M{"primaryidentifier": primaryIdentifier})
+	updateModel.SetUpdate(bsonRep)
+	updateModel.SetUpsert(true)
+
+	return updateModel
+}
+
+func writeBatches(dataset datasets.DataSet, collection *mongo.Collection, operations []mongo.WriteModel) error {
+	for lower := 0; lower < len(operations); lower += importBatchSize {
+		upper := lower + importBatchSize
+		if upper > len(operations) {
+			upper = len(operations)
+		}
+
+		_, err := dataset.Sink.BulkWrite(collection, operations[lower:upper])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package nptg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+type Region struct {
+	RegionCode string
+	Name       string
+
+	AdministrativeAreas []*AdministrativeArea `xml:"AdministrativeAreas>AdministrativeArea"`
+}
+
+type AdministrativeArea struct {
+	CreationDateTime     string `xml:",attr"`
+	ModificationDateTime string `xml:",attr"`
+
+	AdministrativeAreaCode string
+	AtcoAreaCode           string
+	Name                   string
+	ShortName              string
+	National               bool
+}
+
+func (orig *AdministrativeArea) ToCTDF() *ctdf.AdministrativeArea {
+	creationTime, _ := time.Parse(DateTimeFormat, orig.CreationDateTime)
+	modificationTime, _ := time.Parse(DateTimeFormat, orig.ModificationDateTime)
+
+	return &ctdf.AdministrativeArea{
+		PrimaryIdentifier: fmt.Sprintf(ctdf.AdministrativeAreaIDFormat, orig.AdministrativeAreaCode),
+
+		CreationDateTime:     creationTime,
+		ModificationDateTime: modificationTime,
+
+		Name:         orig.Name,
+		ShortName:    orig.ShortName,
+		AtcoAreaCode: orig.AtcoAreaCode,
+		National:     orig.National,
+	}
+}
@@ -0,0 +1,83 @@
+package nptg
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"golang.org/x/net/html/charset"
+)
+
+func (n *NPTG) ParseFile(reader io.Reader) error {
+	n.Regions = []*Region{}
+	n.Localities = []*NptgLocality{}
+
+	d := xml.NewDecoder(reader)
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if tok == nil || err == io.EOF {
+			// EOF means we're done.
+			break
+		} else if err != nil {
+			return err
+		}
+
+		switch ty := tok.(type) {
+		case xml.StartElement:
+			if ty.Name.Local == "NationalPublicTransportGazetteer" {
+				for i := 0; i < len(ty.Attr); i++ {
+					attr := ty.Attr[i]
+
+					switch attr.Name.Local {
+					case "CreationDateTime":
+						n.CreationDateTime = attr.Value
+					case "ModificationDateTime":
+						n.ModificationDateTime = attr.Value
+					case "SchemaVersion":
+						n.SchemaVersion = attr.Value
+					}
+				}
+
+				validate := n.Validate()
+				if validate != nil {
+					return validate
+				}
+			} else if ty.Name.Local == "Region" {
+				var region Region
+
+				if err = d.DecodeElement(&region, &ty); err != nil {
+					if err := n.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
+				} else {
+					n.Regions = append(n.Regions, &region)
+					n.progress.AddRecords(1)
+				}
+			} else if ty.Name.Local == "NptgLocality" {
+				var locality NptgLocality
+
+				if err = d.DecodeElement(&locality, &ty); err != nil {
+					if err := n.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
+				} else {
+					if locality.Location != nil {
+						locality.Location.UpdateCoordinates()
+					}
+					n.Localities = append(n.Localities, &locality)
+					n.progress.AddRecords(1)
+				}
+			}
+		default:
+		}
+	}
+
+	log.Info().Msgf("Successfully parsed document")
+	log.Info().Msgf(" - Last modified %s", n.ModificationDateTime)
+	log.Info().Msgf(" - Contains %d regions", len(n.Regions))
+	log.Info().Msgf(" - Contains %d localities", len(n.Localities))
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package otcbusregistrations
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const importBatchSize = 1000
+
+// The columns each field is read from unless the dataset maps it to another, eg. column-servicenumber: "Route Number".
+// Headers are matched ignoring case with underscores treated as spaces.
+var defaultColumns = map[string]string{
+	"registrationnumber": "reg no",
+	"variationnumber":    "variation number",
+	"servicenumber":      "service number",
+	"status":             "registration status",
+	"licencenumber":      "lic no",
+	"operatorname":       "op name",
+	"tradingname":        "trading name",
+	"trafficarea":        "current traffic area",
+	"servicetype":        "service type description",
+	"startpoint":         "start point",
+	"finishpoint":        "finish point",
+	"via":                "via",
+	"receiveddate":       "received date",
+	"effectivedate":      "effective date",
+	"enddate":            "end date",
+}
+
+// Columns that have to be present in the header, the rest are optional
+var requiredColumns = []string{"registrationnumber", "servicenumber", "licencenumber"}
+
+var dateFormats = []string{"02/01/2006", "2006-01-02", "02/01/2006 15:04:05", "2006-01-02T15:04:05"}
+
+// BusRegistrations is the Office of the Traffic Commissioner list of registered local bus services, one row per variation
+type BusRegistrations struct {
+	Header []string
+	Rows   [][]string
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+}
+
+func (b *BusRegistrations) SetupProgress(tracker *progress.Tracker) {
+	b.progress = tracker
+}
+
+func (b *BusRegistrations) SetupErrors(collector *importerrors.Collector) {
+	b.errors = collector
+}
+
+func (b *BusRegistrations) ParseFile(reader io.Reader) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.LazyQuotes = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	b.Header = header
+
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if err := b.errors.Add(&importerrors.ParseError{Record: "row", Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b.Rows = append(b.Rows, row)
+		b.progress.AddRecords(1)
+	}
+
+	return nil
+}
+
+func (b *BusRegistrations) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.BusRegistrations {
+		return errors.New("This format requires busregistrations to be enabled")
+	}
+
+	columns, err := b.getColumnIndexes(dataset)
+	if err != nil {
+		return err
+	}
+
+	registrations := map[string]*ctdf.BusRegistration{}
+	now := time.Now()
+
+	for i, row := range b.Rows {
+		// Header is the first line
+		rowName := fmt.Sprintf("row %d", i+2)
+
+		getValue := func(field string) string {
+			index, exists := columns[field]
+			if !exists || index >= len(row) {
+				return ""
+			}
+
+			return strings.TrimSpace(row[index])
+		}
+
+		registrationNumber := strings.ToUpper(getValue("registrationnumber"))
+		identifier := ctdf.GetBusRegistrationIdentifier(registrationNumber)
+		if identifier == "" {
+			if err := b.errors.Add(&importerrors.ParseError{Record: rowName, Err: errors.New(fmt.Sprintf("invalid registration number %q", registrationNumber))}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		variationNumber := 0
+		if variation := getValue("variationnumber"); variation != "" {
+			variationNumber, err = strconv.Atoi(variation)
+			if err != nil {
+				if err := b.errors.Add(&importerrors.ParseError{Record: rowName, Err: errors.New(fmt.Sprintf("invalid variation number %q", variation))}); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		registration := &ctdf.BusRegistration{
+			PrimaryIdentifier:    identifier,
+			CreationDateTime:     now,
+			ModificationDateTime: now,
+			DataSource:           datasource,
+
+			RegistrationNumber: registrationNumber,
+			VariationNumber:    variationNumber,
+			Status:             getValue("status"),
+
+			LicenceNumber: strings.ToUpper(getValue("licencenumber")),
+			OperatorName:  getValue("operatorname"),
+			TradingName:   getValue("tradingname"),
+			TrafficArea:   getValue("trafficarea"),
+
+			ServiceNumbers: parseServiceNumbers(getValue("servicenumber")),
+			ServiceType:    getValue("servicetype"),
+			StartPoint:     getValue("startpoint"),
+			FinishPoint:    getValue("finishpoint"),
+			Via:            getValue("via"),
+
+			ReceivedDate:  parseDate(getValue("receiveddate")),
+			EffectiveDate: parseDate(getValue("effectivedate")),
+			EndDate:       parseDate(getValue("enddate")),
+		}
+
+		// Every variation has its own row, only the latest describes the service as it is now
+		if existing := registrations[identifier]; existing != nil && existing.VariationNumber > registration.VariationNumber {
+			continue
+		}
+		registrations[identifier] = registration
+	}
+
+	busRegistrationsCollection := database.GetCollection("bus_registrations")
+	var operations []mongo.WriteModel
+
+	for _, registration := range registrations {
+		// Replaced whole but the links are set by the linker so are kept
+		bsonRep, _ := bson.Marshal(bson.M{
+			"$set":   registration,
+			"$unset": getUnsetFields(registration),
+		})
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": registration.PrimaryIdentifier})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		operations = append(operations, updateModel)
+
+		if len(operations) >= importBatchSize {
+			if _, err := dataset.Sink.BulkWrite(busRegistrationsCollection, operations); err != nil {
+				return err
+			}
+			operations = []mongo.WriteModel{}
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(busRegistrationsCollection, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("registrations", len(registrations)).Int("rows", len(b.Rows)).Msg("Imported OTC bus registrations")
+
+	return nil
+}
+
+// getColumnIndexes works out which column each field is in from the header, using the datasets column mapping
+func (b *BusRegistrations) getColumnIndexes(dataset datasets.DataSet) (map[string]int, error) {
+	headerIndexes := map[string]int{}
+	for i, column := range b.Header {
+		headerIndexes[normaliseColumnName(column)] = i
+	}
+
+	columns := map[string]int{}
+	for field, defaultColumn := range defaultColumns {
+		column := defaultColumn
+		if mappedColumn := dataset.CustomConfig[fmt.Sprintf("column-%s", field)]; mappedColumn != "" {
+			column = mappedColumn
+		}
+
+		if index, exists := headerIndexes[normaliseColumnName(column)]; exists {
+			columns[field] = index
+		}
+	}
+
+	for _, field := range requiredColumns {
+		if _, exists := columns[field]; !exists {
+			return nil, errors.New(fmt.Sprintf("CSV is missing the %s column", field))
+		}
+	}
+
+	return columns, nil
+}
+
+// getUnsetFields clears the optional fields a previous variation may have had
+func getUnsetFields(registration *ctdf.BusRegistration) bson.M {
+	unset := bson.M{}
+
+	if registration.TradingName == "" {
+		unset["tradingname"] = ""
+	}
+	if registration.TrafficArea == "" {
+		unset["trafficarea"] = ""
+	}
+	if registration.ServiceType == "" {
+		unset["servicetype"] = ""
+	}
+	if registration.StartPoint == "" {
+		unset["startpoint"] = ""
+	}
+	if registration.FinishPoint == "" {
+		unset["finishpoint"] = ""
+	}
+	if registration.Via == "" {
+		unset["via"] = ""
+	}
+	if registration.ReceivedDate.IsZero() {
+		unset["receiveddate"] = ""
+	}
+	if registration.EffectiveDate.IsZero() {
+		unset["effectivedate"] = ""
+	}
+	if registration.EndDate.IsZero() {
+		unset["enddate"] = ""
+	}
+
+	return unset
+}
+
+func normaliseColumnName(column string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(column), "_", " "))
+}
+
+// parseServiceNumbers splits the route numbers of registrations that cover more than one, eg. "X1|X1A"
+func parseServiceNumbers(serviceNumber string) []string {
+	serviceNumbers := []string{}
+
+	for _, number := range strings.FieldsFunc(serviceNumber, func(r rune) bool {
+		return r == '|' || r == ','
+	}) {
+		if number = strings.TrimSpace(number); number != "" {
+			serviceNumbers = append(serviceNumbers, number)
+		}
+	}
+
+	return serviceNumbers
+}
+
+func parseDate(value string) time.Time {
+	for _, format := range dateFormats {
+		if date, err := time.Parse(format, value); err == nil {
+			return date
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,221 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const pluginBatchSize = 500
+
+// Plugin lets other modules add a dataset format without changing the import manager.
+// The plugin only has to turn its source into CTDF objects, tagging them with the datasource,
+// writing them to the database & cleaning up the records of old runs is all handled by the importer.
+//
+// Plugins register themselves from an init function so a blank import of the package is enough to enable them, eg.
+//
+//	func init() {
+//		formats.RegisterPlugin("example-format", func() formats.Plugin {
+//			return &ExampleFormat{}
+//		})
+//	}
+type Plugin interface {
+	// Parse reads the source & calls emit with each CTDF object in it, either *ctdf.Operator, *ctdf.Stop,
+	// *ctdf.StopGroup, *ctdf.Service or *ctdf.Journey. Records that can't be parsed can be emitted as an error
+	// to count them as a failed record. An error returned from emit means the import is being aborted
+	// and should be returned straight away.
+	Parse(reader io.Reader, emit func(object interface{}) error) error
+}
+
+// PluginFactory creates a new instance of a plugin for every file being imported
+type PluginFactory func() Plugin
+
+var plugins = map[datasets.DataSetFormat]PluginFactory{}
+var pluginsMutex sync.RWMutex
+
+// RegisterPlugin adds the plugin for the format, formats can only be registered once
+func RegisterPlugin(format datasets.DataSetFormat, factory PluginFactory) {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+
+	if _, exists := plugins[format]; exists {
+		log.Fatal().Str("format", string(format)).Msg("Format plugin has already been registered")
+	}
+
+	plugins[format] = factory
+}
+
+// GetPlugin returns a new instance of the plugin for the format wrapped up as a Format
+func GetPlugin(format datasets.DataSetFormat) (*PluginFormat, bool) {
+	pluginsMutex.RLock()
+	factory, exists := plugins[format]
+	pluginsMutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	return &PluginFormat{Plugin: factory()}, true
+}
+
+// GetPluginFormats lists the formats provided by registered plugins
+func GetPluginFormats() []datasets.DataSetFormat {
+	pluginsMutex.RLock()
+	defer pluginsMutex.RUnlock()
+
+	var pluginFormats []datasets.DataSetFormat
+	for format := range plugins {
+		pluginFormats = append(pluginFormats, format)
+	}
+
+	sort.Slice(pluginFormats, func(i, j int) bool {
+		return pluginFormats[i] < pluginFormats[j]
+	})
+
+	return pluginFormats
+}
+
+// PluginFormat runs a plugin as part of the import pipeline.
+// The source is only parsed during Import so objects are written as they're emitted rather than held in memory.
+type PluginFormat struct {
+	Plugin Plugin
+
+	reader io.Reader
+	errors *importerrors.Collector
+}
+
+func (p *PluginFormat) SetupErrors(errors *importerrors.Collector) {
+	p.errors = errors
+}
+
+func (p *PluginFormat) ParseFile(reader io.Reader) error {
+	p.reader = reader
+
+	return nil
+}
+
+func (p *PluginFormat) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if p.reader == nil {
+		return errors.New("No file has been parsed")
+	}
+
+	batches := map[string][]mongo.WriteModel{}
+	counts := map[string]int{}
+
+	flush := func(collectionName string) error {
+		if len(batches[collectionName]) == 0 {
+			return nil
+		}
+
+		_, err := dataset.Sink.BulkWrite(database.GetCollection(collectionName), batches[collectionName])
+		batches[collectionName] = nil
+
+		if err != nil {
+			log.Error().Err(err).Str("collection", collectionName).Msg("Failed to bulk write")
+			return p.errors.Add(&importerrors.WriteError{Collection: collectionName, Err: err})
+		}
+
+		return nil
+	}
+
+	emit := func(object interface{}) error {
+		if err := dataset.Context.Err(); err != nil {
+			return err
+		}
+
+		if recordErr, isError := object.(error); isError {
+			return p.errors.Add(recordErr)
+		}
+
+		collectionName, primaryIdentifier, supported, err := preparePluginObject(object, dataset.SupportedObjects, datasource)
+		if err != nil {
+			return p.errors.Add(&importerrors.ParseError{Record: fmt.Sprintf("%T", object), Err: err})
+		}
+		if !supported {
+			return nil
+		}
+
+		batches[collectionName] = append(batches[collectionName], mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": primaryIdentifier}).
+			SetReplacement(object).
+			SetUpsert(true),
+		)
+		counts[collectionName] += 1
+
+		if len(batches[collectionName]) >= pluginBatchSize {
+			return flush(collectionName)
+		}
+
+		return nil
+	}
+
+	if err := p.Plugin.Parse(p.reader, emit); err != nil {
+		return err
+	}
+
+	for collectionName := range batches {
+		if err := flush(collectionName); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("format", string(dataset.Format)).Interface("counts", counts).Msg("Imported plugin objects")
+
+	return nil
+}
+
+// preparePluginObject fills in the fields the importer owns & works out which collection the object belongs in
+func preparePluginObject(object interface{}, supportedObjects datasets.SupportedObjects, datasource *ctdf.DataSourceReference) (string, string, bool, error) {
+	now := time.Now()
+
+	var collectionName, primaryIdentifier string
+	var supported bool
+
+	switch typedObject := object.(type) {
+	case *ctdf.Operator:
+		typedObject.DataSource = datasource
+		typedObject.CreationDateTime = now
+		typedObject.ModificationDateTime = now
+		collectionName, primaryIdentifier, supported = "operators", typedObject.PrimaryIdentifier, supportedObjects.Operators
+	case *ctdf.Stop:
+		typedObject.DataSource = datasource
+		typedObject.CreationDateTime = now
+		typedObject.ModificationDateTime = now
+		// Stops go through the same merging as every other stop dataset
+		collectionName, primaryIdentifier, supported = "stops_raw", typedObject.PrimaryIdentifier, supportedObjects.Stops
+	case *ctdf.StopGroup:
+		typedObject.DataSource = datasource
+		typedObject.CreationDateTime = now
+		typedObject.ModificationDateTime = now
+		collectionName, primaryIdentifier, supported = "stop_groups", typedObject.PrimaryIdentifier, supportedObjects.StopGroups
+	case *ctdf.Service:
+		typedObject.DataSource = datasource
+		typedObject.CreationDateTime = now
+		typedObject.ModificationDateTime = now
+		collectionName, primaryIdentifier, supported = "services", typedObject.PrimaryIdentifier, supportedObjects.Services
+	case *ctdf.Journey:
+		typedObject.DataSource = datasource
+		typedObject.CreationDateTime = now
+		typedObject.ModificationDateTime = now
+		collectionName, primaryIdentifier, supported = "journeys", typedObject.PrimaryIdentifier, supportedObjects.Journeys
+	default:
+		return "", "", false, errors.New(fmt.Sprintf("Unsupported object type %T", object))
+	}
+
+	if primaryIdentifier == "" {
+		return "", "", false, errors.New("Object has no primary identifier")
+	}
+
+	return collectionName, primaryIdentifier, supported, nil
+}
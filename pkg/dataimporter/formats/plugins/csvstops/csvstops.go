@@ -0,0 +1,94 @@
+// Package csvstops is an example format plugin that imports stops from a simple CSV file with the columns
+//
+//	id,name,latitude,longitude
+//
+// It isn't enabled by default, a blank import of this package registers it as the travigo-csv-stops format.
+package csvstops
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+)
+
+const Format datasets.DataSetFormat = "travigo-csv-stops"
+
+func init() {
+	formats.RegisterPlugin(Format, func() formats.Plugin {
+		return &CSVStops{}
+	})
+}
+
+type CSVStops struct{}
+
+func (c *CSVStops) Parse(reader io.Reader, emit func(object interface{}) error) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 4
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(strings.Join(header, ",")) != "id,name,latitude,longitude" {
+		return errors.New("Expected the header id,name,latitude,longitude")
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if emitErr := emit(&importerrors.ParseError{Record: "row", Err: err}); emitErr != nil {
+				return emitErr
+			}
+			continue
+		}
+
+		stop, err := parseStop(record)
+		if err != nil {
+			err = &importerrors.ParseError{Record: fmt.Sprintf("stop %s", record[0]), Err: err}
+			if emitErr := emit(err); emitErr != nil {
+				return emitErr
+			}
+			continue
+		}
+
+		if err := emit(stop); err != nil {
+			return err
+		}
+	}
+}
+
+func parseStop(record []string) (*ctdf.Stop, error) {
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+	if err != nil {
+		return nil, err
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := &ctdf.Stop{
+		PrimaryIdentifier: strings.TrimSpace(record[0]),
+		PrimaryName:       strings.TrimSpace(record[1]),
+		Location: &ctdf.Location{
+			Type:        "Point",
+			Coordinates: []float64{longitude, latitude},
+		},
+		StopType: ctdf.StopTypeStop,
+		Active:   true,
+	}
+	stop.UpdatePlusCode()
+
+	return stop, nil
+}
@@ -0,0 +1,105 @@
+package schoolterms
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocarina/gocsv"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SchoolTerms is a list of school term dates for each local authority.
+// Each row is one block of school days so half terms are written as separate rows.
+type SchoolTerms struct {
+	Records []*Record
+}
+
+type Record struct {
+	AtcoAreaCode   string `csv:"atcoareacode"`
+	LocalAuthority string `csv:"localauthority"`
+	Term           string `csv:"term"`
+	StartDate      string `csv:"startdate"`
+	EndDate        string `csv:"enddate"`
+}
+
+func (s *SchoolTerms) ParseFile(reader io.Reader) error {
+	return gocsv.Unmarshal(reader, &s.Records)
+}
+
+func (s *SchoolTerms) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.SchoolTermCalendars {
+		return errors.New("This format requires schooltermcalendars to be enabled")
+	}
+
+	calendars := map[string]*ctdf.SchoolTermCalendar{}
+	now := time.Now()
+
+	for _, record := range s.Records {
+		atcoAreaCode := strings.TrimSpace(record.AtcoAreaCode)
+		if len(atcoAreaCode) != 3 {
+			log.Error().Str("atcoareacode", record.AtcoAreaCode).Msg("Invalid ATCO area code for school term")
+			continue
+		}
+
+		startDate, startErr := time.Parse(ctdf.YearMonthDayFormat, strings.TrimSpace(record.StartDate))
+		endDate, endErr := time.Parse(ctdf.YearMonthDayFormat, strings.TrimSpace(record.EndDate))
+		if startErr != nil || endErr != nil || endDate.Before(startDate) {
+			log.Error().Str("atcoareacode", atcoAreaCode).Str("term", record.Term).Msg("Invalid dates for school term")
+			continue
+		}
+
+		calendarRef := fmt.Sprintf(ctdf.SchoolTermCalendarIDFormat, atcoAreaCode)
+		calendar := calendars[calendarRef]
+		if calendar == nil {
+			calendar = &ctdf.SchoolTermCalendar{
+				PrimaryIdentifier:    calendarRef,
+				CreationDateTime:     now,
+				ModificationDateTime: now,
+				DataSource:           datasource,
+				Name:                 strings.TrimSpace(record.LocalAuthority),
+				AtcoAreaCode:         atcoAreaCode,
+			}
+			calendars[calendarRef] = calendar
+		}
+
+		calendar.Terms = append(calendar.Terms, ctdf.SchoolTerm{
+			Name:      strings.TrimSpace(record.Term),
+			StartDate: startDate.Format(ctdf.YearMonthDayFormat),
+			EndDate:   endDate.Format(ctdf.YearMonthDayFormat),
+		})
+	}
+
+	collection := database.GetCollection("school_term_calendars")
+	var operations []mongo.WriteModel
+
+	for _, calendar := range calendars {
+		sort.Slice(calendar.Terms, func(i, j int) bool {
+			return calendar.Terms[i].StartDate < calendar.Terms[j].StartDate
+		})
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": calendar.PrimaryIdentifier}).
+			SetReplacement(calendar).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) > 0 {
+		if _, err := dataset.Sink.BulkWrite(collection, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("calendars", len(operations)).Msg("Imported school term calendars")
+
+	return nil
+}
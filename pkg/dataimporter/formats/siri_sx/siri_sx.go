@@ -2,21 +2,28 @@ package siri_sx
 
 import (
 	"crypto/sha256"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/adjust/rmq/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/realtime/vehicletracker"
 	"golang.org/x/net/html/charset"
 )
 
+const defaultLinkedDataset = "gb-dft-bods-gtfs-schedule"
+const stopsLinkedDataset = "gb-dft-naptan"
+
+// Situations with no end time are kept alive for this long after each import, so they expire once they disappear from the feed
+const openEndedValidity = 24 * time.Hour
+
 type SiriSX struct {
 	reader io.Reader
 	queue  rmq.Queue
@@ -48,7 +55,6 @@ func (s *SiriSX) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 			// EOF means we're done.
 			break
 		} else if err != nil {
-			log.Fatal().Msgf("Error decoding token: %s", err)
 			return err
 		}
 
@@ -58,7 +64,7 @@ func (s *SiriSX) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 				var situationElement SituationElement
 
 				if err = d.DecodeElement(&situationElement, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					log.Error().Err(err).Msg("Error decoding item")
 				} else {
 					retrievedRecords += 1
 
@@ -82,11 +88,13 @@ func SubmitToProcessQueue(queue rmq.Queue, situationElement *SituationElement, d
 
 	currentTime := time.Now()
 
-	validityPeriodStart, _ := time.Parse(time.RFC3339, situationElement.ValidityPeriod.StartTime)
-	validityPeriodEnd, err := time.Parse(time.RFC3339, situationElement.ValidityPeriod.EndTime)
+	validityPeriodStart, validityPeriodEnd := situationElement.GetValidity(currentTime)
 	versionedAtTime, _ := time.Parse(time.RFC3339, situationElement.VersionedAtTime)
 
-	if err != nil && validityPeriodEnd.Before(currentTime) {
+	// Closed situations still go through so the existing alert gets closed
+	closed := strings.EqualFold(situationElement.Progress, "closed")
+
+	if validityPeriodEnd.Before(currentTime) && !closed {
 		return false
 	}
 
@@ -96,15 +104,20 @@ func SubmitToProcessQueue(queue rmq.Queue, situationElement *SituationElement, d
 		alertType = ctdf.ServiceAlertTypeWarning
 	}
 
+	linkedDataset := dataset.LinkedDataset
+	if linkedDataset == "" {
+		linkedDataset = defaultLinkedDataset
+	}
+
 	var identifyingInformation []map[string]string
 	for _, consequence := range situationElement.Consequence {
 		for _, network := range consequence.AffectedNetworks {
 			for _, line := range network.AffectedLine {
 				identifyingInformation = append(identifyingInformation, map[string]string{
-					"LineRef":         line.LineRef,
-					"PubishedLineRef": line.PublishedLineRef,
-					"OperatorRef":     line.OperatorRef,
-					"LinkedDataset":   "gb-dft-bods-gtfs-schedule", // not always going to be true in future
+					"LineRef":          line.LineRef,
+					"PublishedLineRef": line.PublishedLineRef,
+					"OperatorRef":      line.OperatorRef,
+					"LinkedDataset":    linkedDataset,
 				})
 			}
 		}
@@ -112,23 +125,30 @@ func SubmitToProcessQueue(queue rmq.Queue, situationElement *SituationElement, d
 		for _, stopPoint := range consequence.AffectedStopPoints {
 			identifyingInformation = append(identifyingInformation, map[string]string{
 				"StopPointRef":  stopPoint.StopPointRef,
-				"LinkedDataset": "gb-dft-naptan", // not always going to be true in future
+				"LinkedDataset": stopsLinkedDataset,
 			})
 		}
 	}
 
-	title := situationElement.Summary
-	description := situationElement.Description
+	title, titleTranslations := ctdf.SplitTranslations(getTranslatedTexts(situationElement.Summary))
+	description, descriptionTranslations := ctdf.SplitTranslations(getTranslatedTexts(situationElement.Description))
 
+	// Situation numbers are stable across versions of the same situation so updates replace the existing alert
+	// Open ended validity periods move on every import so cant be used as part of the identifier
 	hash := sha256.New()
-	hash.Write([]byte(alertType))
-	hash.Write([]byte(title))
-	hash.Write([]byte(description))
+	if situationElement.SituationNumber != "" {
+		hash.Write([]byte(situationElement.ParticipantRef))
+		hash.Write([]byte(situationElement.SituationNumber))
+	} else {
+		hash.Write([]byte(alertType))
+		hash.Write([]byte(title))
+		hash.Write([]byte(description))
+	}
 	localIDhash := fmt.Sprintf("%x", hash.Sum(nil))
 
 	updateEvent := vehicletracker.VehicleUpdateEvent{
 		MessageType: vehicletracker.VehicleUpdateEventTypeServiceAlert,
-		LocalID:     fmt.Sprintf("%s-servicealert-%d-%d-%s", dataset.Identifier, validityPeriodStart.UnixMicro(), validityPeriodEnd.UnixMicro(), localIDhash),
+		LocalID:     fmt.Sprintf("%s-servicealert-%s", dataset.Identifier, localIDhash),
 
 		ServiceAlertUpdate: &vehicletracker.ServiceAlertUpdate{
 			Type:        alertType,
@@ -136,6 +156,11 @@ func SubmitToProcessQueue(queue rmq.Queue, situationElement *SituationElement, d
 			Description: description,
 			ValidFrom:   validityPeriodStart,
 			ValidUntil:  validityPeriodEnd,
+			Version:     situationElement.Version,
+			Closed:      closed,
+
+			TitleTranslations:       titleTranslations,
+			DescriptionTranslations: descriptionTranslations,
 
 			IdentifyingInformation: identifyingInformation,
 		},
@@ -145,7 +170,7 @@ func SubmitToProcessQueue(queue rmq.Queue, situationElement *SituationElement, d
 		RecordedAt: versionedAtTime,
 	}
 
-	updateEventJson, _ := json.Marshal(updateEvent)
+	updateEventJson, _ := queuemessage.Marshal(queuemessage.MessageTypeVehicleUpdate, updateEvent)
 	queue.PublishBytes(updateEventJson)
 
 	return true
@@ -1,5 +1,11 @@
 package siri_sx
 
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
 type SituationElement struct {
 	CreationTime    string
 	ParticipantRef  string
@@ -8,18 +14,33 @@ type SituationElement struct {
 	VersionedAtTime string
 	Progress        string
 
-	ValidityPeriod    TimePeriod
+	ValidityPeriod    []TimePeriod
 	PublicationWindow TimePeriod
 
 	MiscellaneousReason string
 	Planned             bool
-	Summary             string
-	Description         string
+	Summary             []TranslatedString
+	Description         []TranslatedString
 	InfoURL             string `xml:"InfoLinks>InfoLink>Uri"`
 
 	Consequence []Consequence `xml:"Consequences>Consequence"`
 }
 
+// TranslatedString is repeated for each language the text is provided in
+type TranslatedString struct {
+	Language string `xml:"lang,attr"`
+	Text     string `xml:",chardata"`
+}
+
+func getTranslatedTexts(strings []TranslatedString) []ctdf.TranslatedText {
+	var texts []ctdf.TranslatedText
+	for _, translatedString := range strings {
+		texts = append(texts, ctdf.TranslatedText{Language: translatedString.Language, Text: translatedString.Text})
+	}
+
+	return texts
+}
+
 type TimePeriod struct {
 	StartTime string
 	EndTime   string
@@ -52,3 +73,35 @@ type AffectedStopPoint struct {
 	StopPointRef  string
 	StopPointName string
 }
+
+// GetValidity returns the overall span covered by all the validity periods of the situation
+func (s *SituationElement) GetValidity(currentTime time.Time) (time.Time, time.Time) {
+	var validFrom time.Time
+	var validUntil time.Time
+
+	for _, period := range s.ValidityPeriod {
+		startTime, err := time.Parse(time.RFC3339, period.StartTime)
+		if err == nil && (validFrom.IsZero() || startTime.Before(validFrom)) {
+			validFrom = startTime
+		}
+
+		// A period with no end time is open ended
+		endTime, err := time.Parse(time.RFC3339, period.EndTime)
+		if err != nil {
+			endTime = currentTime.Add(openEndedValidity)
+		}
+
+		if endTime.After(validUntil) {
+			validUntil = endTime
+		}
+	}
+
+	if validFrom.IsZero() {
+		validFrom = currentTime
+	}
+	if validUntil.IsZero() {
+		validUntil = currentTime.Add(openEndedValidity)
+	}
+
+	return validFrom, validUntil
+}
@@ -1,7 +1,6 @@
 package siri_vm
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -13,6 +12,9 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
 	"github.com/travigo/travigo/pkg/realtime/vehicletracker"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"golang.org/x/net/html/charset"
@@ -149,7 +151,7 @@ func SubmitToProcessQueue(queue rmq.Queue, vehicle *VehicleActivity, dataset dat
 		}
 	}
 
-	locationEventJson, _ := json.Marshal(locationEvent)
+	locationEventJson, _ := queuemessage.Marshal(queuemessage.MessageTypeVehicleUpdate, locationEvent)
 
 	queue.PublishBytes(locationEventJson)
 
@@ -173,6 +175,7 @@ func (s *SiriVM) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 
 	var retrievedRecords int64
 	var submittedRecords int64
+	var parseErrors int64
 
 	d := xml.NewDecoder(s.reader)
 	d.CharsetReader = charset.NewReaderLabel
@@ -182,7 +185,6 @@ func (s *SiriVM) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 			// EOF means we're done.
 			break
 		} else if err != nil {
-			log.Fatal().Msgf("Error decoding token: %s", err)
 			return err
 		}
 
@@ -192,7 +194,8 @@ func (s *SiriVM) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 				var vehicleActivity VehicleActivity
 
 				if err = d.DecodeElement(&vehicleActivity, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					log.Error().Err(err).Msg("Error decoding item")
+					parseErrors += 1
 				} else {
 					retrievedRecords += 1
 
@@ -206,7 +209,11 @@ func (s *SiriVM) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 		}
 	}
 
-	log.Info().Int64("retrieved", retrievedRecords).Int64("submitted", submittedRecords).Msgf("Parsed latest Siri-VM response")
+	log.Info().Int64("retrieved", retrievedRecords).Int64("submitted", submittedRecords).Int64("parseerrors", parseErrors).Msgf("Parsed latest Siri-VM response")
+
+	if !datasink.IsDryRun(dataset.Sink) {
+		recordFeedHealth(dataset.Identifier, int(retrievedRecords), int(parseErrors))
+	}
 
 	// Wait for queue to empty
 	checkQueueSize()
@@ -214,6 +221,15 @@ func (s *SiriVM) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceRef
 	return nil
 }
 
+func recordFeedHealth(source string, messages int, parseErrors int) {
+	if err := feedhealth.RecordMessages(source, "siri-vm", messages); err != nil {
+		log.Error().Err(err).Str("source", source).Msg("Failed to record feed health")
+	}
+	if err := feedhealth.RecordParseErrors(source, "siri-vm", parseErrors); err != nil {
+		log.Error().Err(err).Str("source", source).Msg("Failed to record feed health")
+	}
+}
+
 func checkQueueSize() {
 	stats, _ := redis_client.QueueConnection.CollectStats([]string{"realtime-queue"})
 	inQueue := stats.QueueStats["realtime-queue"].ReadyCount
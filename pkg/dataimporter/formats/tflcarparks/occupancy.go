@@ -0,0 +1,88 @@
+package tflcarparks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const disabledBayType = "Disabled"
+
+// Occupancy is the realtime count of free & occupied bays in the TfL car parks.
+// It only updates car parks that have already been imported as the feed doesn't include their locations.
+type Occupancy struct {
+	CarParks []*CarParkOccupancy
+}
+
+type CarParkOccupancy struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Bays []struct {
+		BayType  string `json:"bayType"`
+		BayCount int    `json:"bayCount"`
+		Free     int    `json:"free"`
+		Occupied int    `json:"occupied"`
+	} `json:"bays"`
+}
+
+func (o *Occupancy) ParseFile(reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(&o.CarParks)
+}
+
+func (o *Occupancy) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.CarParks {
+		return errors.New("This format requires carparks to be enabled")
+	}
+
+	var carParkOperations []mongo.WriteModel
+	now := time.Now()
+
+	for _, carParkOccupancy := range o.CarParks {
+		if carParkOccupancy.ID == "" || len(carParkOccupancy.Bays) == 0 {
+			continue
+		}
+
+		capacity := &ctdf.CarParkCapacity{}
+		occupancy := &ctdf.CarParkOccupancy{
+			RecordedAt: now,
+		}
+
+		for _, bay := range carParkOccupancy.Bays {
+			capacity.Spaces += bay.BayCount
+			if bay.BayType == disabledBayType {
+				capacity.DisabledSpaces += bay.BayCount
+			}
+
+			occupancy.FreeSpaces += bay.Free
+			occupancy.OccupiedSpaces += bay.Occupied
+		}
+
+		carParkOperations = append(carParkOperations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": getCarParkRef(carParkOccupancy.ID)}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"capacity":             capacity,
+				"occupancy":            occupancy,
+				"modificationdatetime": now,
+			}}),
+		)
+	}
+
+	if len(carParkOperations) > 0 {
+		_, err := dataset.Sink.BulkWrite(database.GetCollection("car_parks"), carParkOperations)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("carparks", len(carParkOperations)).Msg("Updated TfL car park occupancy")
+
+	return nil
+}
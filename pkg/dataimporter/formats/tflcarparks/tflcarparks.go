@@ -0,0 +1,84 @@
+package tflcarparks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CarParks is the list of car park places from the TfL unified API, these are mostly at stations
+type CarParks struct {
+	Places []*Place
+}
+
+type Place struct {
+	ID         string  `json:"id"`
+	CommonName string  `json:"commonName"`
+	PlaceType  string  `json:"placeType"`
+	Latitude   float64 `json:"lat"`
+	Longitude  float64 `json:"lon"`
+}
+
+func (c *CarParks) ParseFile(reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(&c.Places)
+}
+
+func (c *CarParks) Import(dataset datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	if !dataset.SupportedObjects.CarParks {
+		return errors.New("This format requires carparks to be enabled")
+	}
+
+	var carParkOperations []mongo.WriteModel
+	now := time.Now()
+
+	for _, place := range c.Places {
+		if place.ID == "" || (place.Latitude == 0 && place.Longitude == 0) {
+			continue
+		}
+
+		carPark := &ctdf.CarPark{
+			PrimaryIdentifier:    getCarParkRef(place.ID),
+			CreationDateTime:     now,
+			ModificationDateTime: now,
+			DataSource:           datasource,
+			Name:                 place.CommonName,
+			Location: &ctdf.Location{
+				Type:        "Point",
+				Coordinates: []float64{place.Longitude, place.Latitude},
+			},
+		}
+
+		// Capacity & occupancy are left alone as they come from the occupancy feed
+		bsonRep, _ := bson.Marshal(bson.M{"$set": carPark})
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": carPark.PrimaryIdentifier})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		carParkOperations = append(carParkOperations, updateModel)
+	}
+
+	if len(carParkOperations) > 0 {
+		_, err := dataset.Sink.BulkWrite(database.GetCollection("car_parks"), carParkOperations)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("carparks", len(carParkOperations)).Msg("Imported TfL car parks")
+
+	return nil
+}
+
+func getCarParkRef(id string) string {
+	return fmt.Sprintf(ctdf.CarParkIDFormat, "gb-tfl", id)
+}
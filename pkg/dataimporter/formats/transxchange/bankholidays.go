@@ -0,0 +1,46 @@
+package transxchange
+
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/countries"
+)
+
+// Groups of bank holidays that TransXChange allows to be referenced by a single element
+var bankHolidayGroups = map[string][]string{
+	"AllBankHolidays": {
+		"ChristmasDay", "BoxingDay", "GoodFriday", "NewYearsDay", "Jan2ndScotland", "EasterMonday", "MayDay",
+		"SpringBank", "LateSummerBankHolidayNotScotland", "AugustBankHolidayScotland", "StAndrewsDay",
+		"ChristmasDayHoliday", "BoxingDayHoliday", "NewYearsDayHoliday", "Jan2ndScotlandHoliday", "StAndrewsDayHoliday",
+	},
+	"AllHolidaysExceptChristmas": {
+		"NewYearsDay", "Jan2ndScotland", "GoodFriday", "EasterMonday", "MayDay", "SpringBank",
+		"LateSummerBankHolidayNotScotland", "AugustBankHolidayScotland", "StAndrewsDay",
+		"NewYearsDayHoliday", "Jan2ndScotlandHoliday", "StAndrewsDayHoliday",
+	},
+	"Christmas":            {"ChristmasDay", "BoxingDay"},
+	"EarlyRunOff":          {"ChristmasEve", "NewYearsEve"},
+	"HolidayMondays":       {"EasterMonday", "MayDay", "SpringBank", "LateSummerBankHolidayNotScotland", "AugustBankHolidayScotland"},
+	"DisplacementHolidays": {"ChristmasDayHoliday", "BoxingDayHoliday", "NewYearsDayHoliday", "Jan2ndScotlandHoliday", "StAndrewsDayHoliday"},
+}
+
+// getBankHolidayDates resolves a TransXChange bank holiday element into the dates it falls on for each of the given years
+func getBankHolidayDates(name string, years []int) []time.Time {
+	if group, exists := bankHolidayGroups[name]; exists {
+		var dates []time.Time
+		for _, groupName := range group {
+			dates = append(dates, getBankHolidayDates(groupName, years)...)
+		}
+
+		return dates
+	}
+
+	var dates []time.Time
+	for _, year := range years {
+		if date, exists := countries.GetGBBankHoliday(name, year); exists {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
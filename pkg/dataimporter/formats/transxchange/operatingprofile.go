@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
@@ -28,8 +29,17 @@ type DateRange struct {
 	Note      string
 }
 
+// Dates returns the start & end of the range, either is zero if it's missing or can't be parsed
+func (dateRange DateRange) Dates() (time.Time, time.Time) {
+	startDate, _ := time.Parse(ctdf.YearMonthDayFormat, dateRange.StartDate)
+	endDate, _ := time.Parse(ctdf.YearMonthDayFormat, dateRange.EndDate)
+
+	return startDate, endDate
+}
+
 // This is a bit hacky and doesn't seem like the best way of doing it but it works
-func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*ServicedOrganisation) (*ctdf.Availability, error) {
+// schoolTermCalendarRef is the calendar used for serviced organisations that don't list their own dates
+func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*ServicedOrganisation, schoolTermCalendarRef string) (*ctdf.Availability, error) {
 	ctdfAvailability := ctdf.Availability{}
 
 	operatingProfile.RegularDayType = []string{}
@@ -104,41 +114,44 @@ func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*Servic
 				}
 
 				if (elementChain[1] == "DaysOfNonOperation" || elementChain[1] == "DaysOfOperation") && len(elementChain) == 3 {
-					var record ctdf.AvailabilityRule
+					var records []ctdf.AvailabilityRule
 					if elementChain[2] == "OtherPublicHoliday" {
 						var otherPublicHoliday struct {
 							Description string
 							Date        string
 						}
 						if err = d.DecodeElement(&otherPublicHoliday, &ty); err != nil {
-							log.Fatal().Msgf("Error decoding item: %s", err)
+							return nil, err
 						}
-						record = ctdf.AvailabilityRule{
+						records = append(records, ctdf.AvailabilityRule{
 							Type:        ctdf.AvailabilityDate,
 							Value:       otherPublicHoliday.Date,
 							Description: otherPublicHoliday.Description,
-						}
+						})
 
 						elementChain = elementChain[:len(elementChain)-1] // Using decodeElement means we skip the end element for this
 					} else {
-						// bankHolidayName := elementChain[2]
-
-						// if bankHolidayName == "Christmas" {
-						// 	bankHolidayName = "ChristmasDayHoliday"
-						// } else if bankHolidayName == "NewYearsDay" {
-						// 	bankHolidayName = "NewYearsDayHoliday"
-						// }
-
-						// record = ctdf.AvailabilityRule{
-						// 	Type:  ctdf.AvailabilitySpecialDay,
-						// 	Value: fmt.Sprintf("gb-bankholiday-%s", bankHolidayName),
-						// }
+						bankHolidayName := elementChain[2]
+						bankHolidayDates := getBankHolidayDates(bankHolidayName, getBankHolidayYears())
+
+						if len(bankHolidayDates) == 0 {
+							log.Debug().Str("bankholiday", bankHolidayName).Msg("Unknown bank holiday type")
+						}
+
+						for _, date := range bankHolidayDates {
+							records = append(records, ctdf.AvailabilityRule{
+								Type:        ctdf.AvailabilityDate,
+								Value:       date.Format(ctdf.YearMonthDayFormat),
+								Description: bankHolidayName,
+							})
+						}
 					}
 
+					// Days of operation run regardless of the regular day type, but still within the operating period
 					if elementChain[1] == "DaysOfOperation" {
-						ctdfAvailability.Match = append(ctdfAvailability.Match, record)
+						ctdfAvailability.Include = append(ctdfAvailability.Include, records...)
 					} else if elementChain[1] == "DaysOfNonOperation" {
-						ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, record)
+						ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, records...)
 					}
 				}
 			case "SpecialDaysOperation":
@@ -151,11 +164,11 @@ func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*Servic
 					var specialDaysOperation SpecialDaysOperation
 
 					if err = d.DecodeElement(&specialDaysOperation, &ty); err != nil {
-						log.Fatal().Msgf("Error decoding item: %s", err)
+						return nil, err
 					}
 
 					for _, dayOfOperation := range specialDaysOperation.DaysOfOperation {
-						ctdfAvailability.Match = append(ctdfAvailability.Match, ctdf.AvailabilityRule{
+						ctdfAvailability.Include = append(ctdfAvailability.Include, ctdf.AvailabilityRule{
 							Type:        ctdf.AvailabilityDateRange,
 							Value:       fmt.Sprintf("%s:%s", dayOfOperation.StartDate, dayOfOperation.EndDate),
 							Description: dayOfOperation.Note,
@@ -188,7 +201,7 @@ func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*Servic
 					var servicedOrganisationDayType ServicedOrganisationDayType
 
 					if err = d.DecodeElement(&servicedOrganisationDayType, &ty); err != nil {
-						log.Fatal().Msgf("Error decoding item: %s", err)
+						return nil, err
 					}
 
 					operationHolidays := findServicedOrganisation(servicedOrganisationDayType.DaysOfOperation.Holidays, servicedOrganisations)
@@ -197,73 +210,19 @@ func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*Servic
 					nonOperationHolidays := findServicedOrganisation(servicedOrganisationDayType.DaysOfNonOperation.Holidays, servicedOrganisations)
 					nonOperationWorkingDays := findServicedOrganisation(servicedOrganisationDayType.DaysOfNonOperation.WorkingDays, servicedOrganisations)
 
+					// Serviced organisations without their own dates fall back to the local authority school term calendar.
+					// Not running on holidays is the same as only running on working days and vice versa.
 					if operationHolidays != nil {
-						// If the referenced object is empty then just kill off this journey
-						if len(operationHolidays.Holidays.DateRange) == 0 {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityMatchAll,
-								Description: operationHolidays.Name,
-							})
-						}
-
-						for _, dateRange := range operationHolidays.Holidays.DateRange {
-							ctdfAvailability.MatchSecondary = append(ctdfAvailability.MatchSecondary, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityDateRange,
-								Value:       fmt.Sprintf("%s:%s", dateRange.StartDate, dateRange.EndDate),
-								Description: operationHolidays.Name,
-							})
-						}
+						addServicedOrganisationDays(&ctdfAvailability, operationHolidays.GetHolidays(servicedOrganisations), false, operationHolidays.Name, ctdf.AvailabilitySchoolHoliday, schoolTermCalendarRef)
 					}
 					if operationWorkingDays != nil {
-						// If the referenced object is empty then just kill off this journey
-						if len(operationWorkingDays.WorkingDays.DateRange) == 0 {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityMatchAll,
-								Description: operationWorkingDays.Name,
-							})
-						}
-
-						for _, dateRange := range operationWorkingDays.WorkingDays.DateRange {
-							ctdfAvailability.MatchSecondary = append(ctdfAvailability.MatchSecondary, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityDateRange,
-								Value:       fmt.Sprintf("%s:%s", dateRange.StartDate, dateRange.EndDate),
-								Description: operationWorkingDays.Name,
-							})
-						}
+						addServicedOrganisationDays(&ctdfAvailability, operationWorkingDays.GetWorkingDays(servicedOrganisations), false, operationWorkingDays.Name, ctdf.AvailabilitySchoolTerm, schoolTermCalendarRef)
 					}
 					if nonOperationHolidays != nil {
-						// If the referenced object is empty then just kill off this journey
-						if len(nonOperationHolidays.Holidays.DateRange) == 0 {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityMatchAll,
-								Description: nonOperationHolidays.Name,
-							})
-						}
-
-						for _, dateRange := range nonOperationHolidays.Holidays.DateRange {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityDateRange,
-								Value:       fmt.Sprintf("%s:%s", dateRange.StartDate, dateRange.EndDate),
-								Description: nonOperationHolidays.Name,
-							})
-						}
+						addServicedOrganisationDays(&ctdfAvailability, nonOperationHolidays.GetHolidays(servicedOrganisations), true, nonOperationHolidays.Name, ctdf.AvailabilitySchoolTerm, schoolTermCalendarRef)
 					}
 					if nonOperationWorkingDays != nil {
-						// If the referenced object is empty then just kill off this journey
-						if len(nonOperationWorkingDays.WorkingDays.DateRange) == 0 {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityMatchAll,
-								Description: nonOperationWorkingDays.Name,
-							})
-						}
-
-						for _, dateRange := range nonOperationWorkingDays.WorkingDays.DateRange {
-							ctdfAvailability.Exclude = append(ctdfAvailability.Exclude, ctdf.AvailabilityRule{
-								Type:        ctdf.AvailabilityDateRange,
-								Value:       fmt.Sprintf("%s:%s", dateRange.StartDate, dateRange.EndDate),
-								Description: nonOperationWorkingDays.Name,
-							})
-						}
+						addServicedOrganisationDays(&ctdfAvailability, nonOperationWorkingDays.GetWorkingDays(servicedOrganisations), true, nonOperationWorkingDays.Name, ctdf.AvailabilitySchoolHoliday, schoolTermCalendarRef)
 					}
 				}
 			default:
@@ -276,3 +235,45 @@ func (operatingProfile *OperatingProfile) ToCTDF(servicedOrganisations []*Servic
 
 	return &ctdfAvailability, nil
 }
+
+// addServicedOrganisationDays adds the date ranges of a serviced organisation as days the journey runs on, or doesn't if nonOperation.
+// When there are no date ranges the school term calendar rule is used instead, or the journey is killed off if there's no calendar.
+func addServicedOrganisationDays(availability *ctdf.Availability, dateRanges []DateRange, nonOperation bool, name string, calendarRuleType ctdf.AvailabilityRecordType, schoolTermCalendarRef string) {
+	if len(dateRanges) == 0 {
+		if schoolTermCalendarRef == "" {
+			availability.Exclude = append(availability.Exclude, ctdf.AvailabilityRule{
+				Type:        ctdf.AvailabilityMatchAll,
+				Description: name,
+			})
+		} else {
+			availability.MatchSecondary = append(availability.MatchSecondary, ctdf.AvailabilityRule{
+				Type:        calendarRuleType,
+				Value:       schoolTermCalendarRef,
+				Description: name,
+			})
+		}
+
+		return
+	}
+
+	for _, dateRange := range dateRanges {
+		rule := ctdf.AvailabilityRule{
+			Type:        ctdf.AvailabilityDateRange,
+			Value:       fmt.Sprintf("%s:%s", dateRange.StartDate, dateRange.EndDate),
+			Description: name,
+		}
+
+		if nonOperation {
+			availability.Exclude = append(availability.Exclude, rule)
+		} else {
+			availability.MatchSecondary = append(availability.MatchSecondary, rule)
+		}
+	}
+}
+
+// getBankHolidayYears covers the years a currently published timetable could be valid for
+func getBankHolidayYears() []int {
+	currentYear := time.Now().Year()
+
+	return []int{currentYear - 1, currentYear, currentYear + 1}
+}
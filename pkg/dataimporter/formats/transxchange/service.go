@@ -28,6 +28,10 @@ type Line struct {
 	ID       string `xml:"id,attr"`
 	LineName string
 
+	LineColour     string
+	LineFontColour string
+	LineImage      string
+
 	OutboundOrigin      string `xml:"OutboundDescription>Origin"`
 	OutboundDestination string `xml:"OutboundDescription>Destination"`
 	OutboundDescription string `xml:"OutboundDescription>Description"`
@@ -33,3 +33,36 @@ type DatePattern struct {
 
 	DateExclusion string
 }
+
+// GetWorkingDays returns the working day ranges, inheriting them from the parent organisation if it has none itself
+func (org *ServicedOrganisation) GetWorkingDays(servicedOrganisations []*ServicedOrganisation) []DateRange {
+	return org.getInheritedDateRanges(servicedOrganisations, func(o *ServicedOrganisation) []DateRange {
+		return o.WorkingDays.DateRange
+	})
+}
+
+// GetHolidays returns the holiday ranges, inheriting them from the parent organisation if it has none itself
+func (org *ServicedOrganisation) GetHolidays(servicedOrganisations []*ServicedOrganisation) []DateRange {
+	return org.getInheritedDateRanges(servicedOrganisations, func(o *ServicedOrganisation) []DateRange {
+		return o.Holidays.DateRange
+	})
+}
+
+func (org *ServicedOrganisation) getInheritedDateRanges(servicedOrganisations []*ServicedOrganisation, dateRanges func(*ServicedOrganisation) []DateRange) []DateRange {
+	current := org
+
+	// Limit the depth so a badly formed document with a reference loop can't get us stuck
+	for depth := 0; current != nil && depth < 5; depth++ {
+		if ranges := dateRanges(current); len(ranges) > 0 {
+			return ranges
+		}
+
+		if current.ParentServicedOrganisationRef == "" {
+			break
+		}
+
+		current = findServicedOrganisation(current.ParentServicedOrganisationRef, servicedOrganisations)
+	}
+
+	return nil
+}
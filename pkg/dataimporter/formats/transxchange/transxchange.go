@@ -7,7 +7,6 @@ import (
 	"math"
 	"regexp"
 	"runtime"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,10 +17,12 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
-	"github.com/travigo/travigo/pkg/util"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"github.com/travigo/travigo/pkg/names"
+	"github.com/travigo/travigo/pkg/transforms"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	iso8601 "github.com/senseyeio/duration"
 )
@@ -46,6 +47,17 @@ type TransXChange struct {
 	ServicedOrganisations  []*ServicedOrganisation
 
 	SchemaVersion string `xml:",attr"`
+
+	progress *progress.Tracker
+	errors   *importerrors.Collector
+}
+
+func (doc *TransXChange) SetupProgress(tracker *progress.Tracker) {
+	doc.progress = tracker
+}
+
+func (doc *TransXChange) SetupErrors(collector *importerrors.Collector) {
+	doc.errors = collector
 }
 
 func (doc *TransXChange) Validate() error {
@@ -69,6 +81,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 	transportType = ctdf.TransportTypeCoach
 
 	dateTimeFormatWithTimezoneRegex, _ := regexp.Compile(DateTimeFormatWithTimezoneRegex)
+	departureTimezone := dataset.GetCountryProfile().Timezone
 
 	servicesCollection := database.GetCollection("services")
 	journeysCollection := database.GetCollection("journeys")
@@ -80,7 +93,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 		if operator.NationalOperatorCode == "" {
 			continue
 		}
-		operatorLocalMapping[operator.ID] = fmt.Sprintf(ctdf.OperatorNOCFormat, operator.NationalOperatorCode)
+		operatorLocalMapping[operator.ID] = transforms.GetOperatorRef(fmt.Sprintf(ctdf.OperatorNOCFormat, operator.NationalOperatorCode), dataset.Identifier)
 	}
 
 	// Create reference map for JourneyPatternSections
@@ -109,6 +122,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 	journeyPatternReferences := map[string]map[string]*JourneyPattern{}
 	servicesReferences := map[string]*Service{}
+	serviceTransportTypes := map[string]ctdf.TransportType{}
 
 	ignoredServices := map[string]bool{}
 
@@ -129,7 +143,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				}
 			}
 
-			if util.ContainsString(dataset.IgnoreObjects.Services.ByOperator, operatorRef) {
+			if dataset.IgnoresOperator(dataset.IgnoreObjects.Services, operatorRef) {
 				continue
 			}
 
@@ -176,16 +190,11 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 			}
 
 			// Provided transport type is the default fallback one if none is specified in service
-			switch strings.ToLower(txcService.Mode) {
-			case "underground", "metro":
-				transportType = ctdf.TransportTypeMetro
-			case "rail":
-				transportType = ctdf.TransportTypeRail
-			case "boat", "ferry":
-				transportType = ctdf.TransportTypeFerry
-			case "tram":
-				transportType = ctdf.TransportTypeTram
+			serviceTransportType := ctdf.ParseTransportType(txcService.Mode)
+			if serviceTransportType == ctdf.TransportTypeUnknown {
+				serviceTransportType = transportType
 			}
+			serviceTransportTypes[localServiceIdentifier] = serviceTransportType
 
 			ctdfService := ctdf.Service{
 				PrimaryIdentifier: serviceIdentifier,
@@ -199,11 +208,19 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 				OperatorRef: operatorRef,
 
-				TransportType: transportType,
+				TransportType: serviceTransportType,
 
 				Routes: routes,
 
 				StopNameOverrides: stopNameOverrides,
+
+				Branding: ctdf.NewBranding(txcLine.LineColour, txcLine.LineFontColour, txcLine.LineImage),
+			}
+			names.NormaliseService(&ctdfService)
+
+			// Registered services use their registration number as the service code so can be matched to the registration
+			if registrationIdentifier := ctdf.GetBusRegistrationIdentifier(txcService.ServiceCode); registrationIdentifier != "" {
+				ctdfService.OtherIdentifiers = append(ctdfService.OtherIdentifiers, registrationIdentifier)
 			}
 
 			// Check if Service end date is before today and skip over it if that is true
@@ -247,9 +264,9 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 	}
 
 	if len(serviceOperations) > 0 {
-		_, err := servicesCollection.BulkWrite(context.Background(), serviceOperations, &options.BulkWriteOptions{})
+		_, err := dataset.Sink.BulkWrite(servicesCollection, serviceOperations)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to bulk write Services")
+			return err
 		}
 	}
 
@@ -284,6 +301,9 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 		}
 	}
 
+	// Journeys without a block number can still be linked into a vehicle working by stay seated interchanges
+	interchangeBlocks := getInterchangeBlocks(doc.VehicleJourneys)
+
 	var journeyOperationInsert uint64
 	var journeyOperationUpdate uint64
 
@@ -291,6 +311,8 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 	numBatches := int(math.Ceil(float64(len(doc.VehicleJourneys)) / float64(maxBatchSize)))
 
 	processingGroup := sync.WaitGroup{}
+	var writeErr error
+	writeErrMutex := sync.Mutex{}
 	processingGroup.Add(numBatches)
 
 	for i := 0; i < numBatches; i++ {
@@ -345,7 +367,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 					}
 				}
 
-				if util.ContainsString(dataset.IgnoreObjects.Journeys.ByOperator, operatorRef) {
+				if dataset.IgnoresOperator(dataset.IgnoreObjects.Journeys, operatorRef) {
 					continue
 				}
 
@@ -390,8 +412,14 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				// Calculate availability from OperatingProfiles
 				var availability *ctdf.Availability
 
+				// Term time journeys use the school term calendar of the local authority the journey starts in
+				var schoolTermCalendarRef string
+				if len(journeyPatternSection.JourneyPatternTimingLinks) > 0 {
+					schoolTermCalendarRef = ctdf.SchoolTermCalendarRefFromAtcoCode(journeyPatternSection.JourneyPatternTimingLinks[0].From.StopPointRef)
+				}
+
 				if service.OperatingProfile.XMLValue != "" {
-					serviceAvailability, err := service.OperatingProfile.ToCTDF(doc.ServicedOrganisations)
+					serviceAvailability, err := service.OperatingProfile.ToCTDF(doc.ServicedOrganisations, schoolTermCalendarRef)
 					if err != nil {
 						log.Error().Err(err).Msgf("Error parsing availability for vehicle journey %s", txcJourney.VehicleJourneyCode)
 					} else {
@@ -400,7 +428,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				}
 
 				if journeyPattern.OperatingProfile.XMLValue != "" {
-					journeyPatternAvailability, err := journeyPattern.OperatingProfile.ToCTDF(doc.ServicedOrganisations)
+					journeyPatternAvailability, err := journeyPattern.OperatingProfile.ToCTDF(doc.ServicedOrganisations, schoolTermCalendarRef)
 					if err != nil {
 						log.Error().Err(err).Msgf("Error parsing availability for vehicle journey %s", txcJourney.VehicleJourneyCode)
 					} else {
@@ -409,7 +437,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				}
 
 				if txcJourney.OperatingProfile.XMLValue != "" {
-					journeyAvailability, err := txcJourney.OperatingProfile.ToCTDF(doc.ServicedOrganisations)
+					journeyAvailability, err := txcJourney.OperatingProfile.ToCTDF(doc.ServicedOrganisations, schoolTermCalendarRef)
 					if err != nil {
 						log.Error().Err(err).Msgf("Error parsing availability for vehicle journey %s", txcJourney.VehicleJourneyCode)
 					} else {
@@ -427,7 +455,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 					})
 				}
 
-				if availability == nil || (len(availability.Match) == 0 && len(availability.MatchSecondary) == 0) {
+				if availability == nil || (len(availability.Match) == 0 && len(availability.MatchSecondary) == 0 && len(availability.Include) == 0) {
 					log.Error().Msgf("Vehicle journey %s has a nil availability", txcJourney.VehicleJourneyCode)
 				}
 
@@ -453,10 +481,13 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				}
 				modificationTime, _ := time.Parse(modificationDateTimeFormat, modificationDateTimeString)
 
+				validFrom, validUntil := service.OperatingPeriod.Dates()
+
 				destinationDisplay := journeyPattern.DestinationDisplay
 				if txcJourney.DestinationDisplay != "" {
 					destinationDisplay = txcJourney.DestinationDisplay
 				}
+				destination := names.ParseDestinationDisplay(destinationDisplay)
 
 				// Create CTDF Journey record
 				ctdfJourney := ctdf.Journey{
@@ -473,12 +504,16 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 					ServiceRef:         fmt.Sprintf("%s:%s", operatorRef, serviceRef),
 					OperatorRef:        operatorRef,
+					TransportType:      serviceTransportTypes[serviceRef],
 					Direction:          txcJourney.Direction,
 					DepartureTime:      departureTime,
-					DepartureTimezone:  "Europe/London",
-					DestinationDisplay: destinationDisplay,
+					DepartureTimezone:  departureTimezone,
+					DestinationDisplay: destination.Display,
+					DestinationVia:     destination.Via,
 
 					Availability: availability,
+					ValidFrom:    validFrom,
+					ValidUntil:   validUntil,
 
 					Path: []*ctdf.JourneyPathItem{},
 				}
@@ -490,6 +525,9 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				// Get the vehicle block number code
 				if txcJourney.Operational.Block.BlockNumber != "" {
 					ctdfJourney.OtherIdentifiers["BlockNumber"] = txcJourney.Operational.Block.BlockNumber
+					ctdfJourney.BlockRef = fmt.Sprintf(ctdf.BlockIDFormat, datasource.DatasetID, fmt.Sprintf("%s:%s", operatorRef, txcJourney.Operational.Block.BlockNumber))
+				} else if interchangeBlock, exists := interchangeBlocks[txcJourney.VehicleJourneyCode]; exists {
+					ctdfJourney.BlockRef = fmt.Sprintf(ctdf.BlockIDFormat, datasource.DatasetID, fmt.Sprintf("%s:%s:%s", operatorRef, serviceRef, interchangeBlock))
 				}
 
 				timeCursor, _ := time.Parse("15:04:05", txcJourney.DepartureTime)
@@ -552,6 +590,7 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 					if vehicleJourneyTimingLink != nil && vehicleJourneyTimingLink.From.DynamicDestinationDisplay != "" {
 						destinationDisplay = vehicleJourneyTimingLink.From.DynamicDestinationDisplay
 					}
+					pathDestination := names.ParseDestinationDisplay(destinationDisplay)
 
 					// Get the activities at this stop (eg. pickup, setdown, both)
 					var originActivity []ctdf.JourneyPathItemActivity
@@ -645,7 +684,8 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 						DestinationArrivalTime: destinationArrivalTime,
 
-						DestinationDisplay: destinationDisplay,
+						DestinationDisplay: pathDestination.Display,
+						DestinationVia:     pathDestination.Via,
 
 						OriginActivity:      originActivity,
 						DestinationActivity: destinationActivity,
@@ -659,21 +699,26 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 				if len(ctdfJourney.Path) == 0 {
 					log.Error().Msgf("Journey %s has a nil path", ctdfJourney.PrimaryIdentifier)
 				}
+				ctdf.CalculatePathDayOffsets(ctdfJourney.Path)
 
+				upsertFilter := ctdfJourney.GetUpsertFilter()
 				bsonRep, _ := bson.Marshal(ctdfJourney)
 
 				var existingCtdfJourney *ctdf.Journey
-				journeysCollection.FindOne(context.Background(), bson.M{"primaryidentifier": ctdfJourney.PrimaryIdentifier}).Decode(&existingCtdfJourney)
+				journeysCollection.FindOne(context.Background(), upsertFilter).Decode(&existingCtdfJourney)
 
+				// Upsert rather than insert so the same journey appearing in 2 files at once doesn't get duplicated
 				if existingCtdfJourney == nil {
-					insertModel := mongo.NewInsertOneModel()
-					insertModel.SetDocument(bsonRep)
+					upsertModel := mongo.NewReplaceOneModel()
+					upsertModel.SetFilter(upsertFilter)
+					upsertModel.SetReplacement(bsonRep)
+					upsertModel.SetUpsert(true)
 
-					stopOperations = append(stopOperations, insertModel)
+					stopOperations = append(stopOperations, upsertModel)
 					localOperationInsert += 1
 				} else if existingCtdfJourney.ModificationDateTime.Before(ctdfJourney.ModificationDateTime) || existingCtdfJourney.ModificationDateTime.Year() == 0 || existingCtdfJourney.DataSource.Timestamp != ctdfJourney.DataSource.Timestamp {
 					updateModel := mongo.NewReplaceOneModel()
-					updateModel.SetFilter(bson.M{"primaryidentifier": ctdfJourney.PrimaryIdentifier})
+					updateModel.SetFilter(upsertFilter)
 					updateModel.SetReplacement(bsonRep)
 
 					stopOperations = append(stopOperations, updateModel)
@@ -685,9 +730,11 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 			atomic.AddUint64(&journeyOperationUpdate, localOperationUpdate)
 
 			if len(stopOperations) > 0 {
-				_, err := journeysCollection.BulkWrite(context.Background(), stopOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(journeysCollection, stopOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write Journeys")
+					writeErrMutex.Lock()
+					writeErr = err
+					writeErrMutex.Unlock()
 				}
 			}
 
@@ -697,6 +744,10 @@ func (doc *TransXChange) Import(dataset datasets.DataSet, datasource *ctdf.DataS
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return writeErr
+	}
+
 	log.Debug().Msg(" - Written to MongoDB")
 	log.Debug().Msgf(" - %d inserts", journeyOperationInsert)
 	log.Debug().Msgf(" - %d updates", journeyOperationUpdate)
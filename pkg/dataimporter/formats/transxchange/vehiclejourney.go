@@ -35,6 +35,8 @@ type VehicleJourney struct {
 
 	VehicleJourneyTimingLinks []VehicleJourneyTimingLink `xml:"VehicleJourneyTimingLink"`
 
+	VehicleJourneyInterchanges []VehicleJourneyInterchange `xml:"VehicleJourneyInterchange"`
+
 	OperatingProfile OperatingProfile // `xml:",innerxml" json:"-" bson:"-"`
 }
 
@@ -57,3 +59,51 @@ type VehicleJourneyTimingLink struct {
 	From JourneyPatternTimingLinkPoint
 	To   JourneyPatternTimingLinkPoint
 }
+
+type VehicleJourneyInterchange struct {
+	InboundVehicleJourneyRef  string
+	OutboundVehicleJourneyRef string
+	StaySeated                bool
+}
+
+// getInterchangeBlocks groups VehicleJourneys linked by stay seated interchanges, returning the first
+// VehicleJourneyCode in each chain for every journey that is part of one
+func getInterchangeBlocks(vehicleJourneys []*VehicleJourney) map[string]string {
+	parents := map[string]string{}
+
+	var findRoot func(code string) string
+	findRoot = func(code string) string {
+		parent, exists := parents[code]
+		if !exists || parent == code {
+			return code
+		}
+
+		root := findRoot(parent)
+		parents[code] = root
+
+		return root
+	}
+
+	for _, vehicleJourney := range vehicleJourneys {
+		for _, interchange := range vehicleJourney.VehicleJourneyInterchanges {
+			if !interchange.StaySeated || interchange.InboundVehicleJourneyRef == "" || interchange.OutboundVehicleJourneyRef == "" {
+				continue
+			}
+
+			inboundRoot := findRoot(interchange.InboundVehicleJourneyRef)
+			outboundRoot := findRoot(interchange.OutboundVehicleJourneyRef)
+
+			parents[inboundRoot] = inboundRoot
+			if inboundRoot != outboundRoot {
+				parents[outboundRoot] = inboundRoot
+			}
+		}
+	}
+
+	blocks := map[string]string{}
+	for code := range parents {
+		blocks[code] = findRoot(code)
+	}
+
+	return blocks
+}
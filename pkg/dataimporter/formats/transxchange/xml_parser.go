@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
 	"golang.org/x/net/html/charset"
 )
 
@@ -17,7 +18,6 @@ func (transXChange *TransXChange) ParseFile(reader io.Reader) error {
 			// EOF means we're done.
 			break
 		} else if err != nil {
-			log.Fatal().Msgf("Error decoding token: %s", err)
 			return err
 		}
 
@@ -47,65 +47,89 @@ func (transXChange *TransXChange) ParseFile(reader io.Reader) error {
 				var stopPoint StopPoint
 
 				if err = d.DecodeElement(&stopPoint, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.StopPoints = append(transXChange.StopPoints, &stopPoint)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "Operator" || ty.Name.Local == "LicensedOperator" {
 				var operator Operator
 
 				if err = d.DecodeElement(&operator, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.Operators = append(transXChange.Operators, &operator)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "Route" {
 				var route Route
 
 				if err = d.DecodeElement(&route, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.Routes = append(transXChange.Routes, &route)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "Service" {
 				var service Service
 
 				if err = d.DecodeElement(&service, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.Services = append(transXChange.Services, &service)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "JourneyPatternSection" {
 				var jps JourneyPatternSection
 
 				if err = d.DecodeElement(&jps, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.JourneyPatternSections = append(transXChange.JourneyPatternSections, &jps)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "RouteSection" {
 				var routeSection RouteSection
 
 				if err = d.DecodeElement(&routeSection, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.RouteSections = append(transXChange.RouteSections, &routeSection)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "VehicleJourney" {
 				var vehicleJourney VehicleJourney
 
 				if err = d.DecodeElement(&vehicleJourney, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.VehicleJourneys = append(transXChange.VehicleJourneys, &vehicleJourney)
+					transXChange.progress.AddRecords(1)
 				}
 			} else if ty.Name.Local == "ServicedOrganisation" {
 				var org ServicedOrganisation
 
 				if err = d.DecodeElement(&org, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
+					if err := transXChange.errors.Add(&importerrors.ParseError{Record: ty.Name.Local, Err: err}); err != nil {
+						return err
+					}
 				} else {
 					transXChange.ServicedOrganisations = append(transXChange.ServicedOrganisations, &org)
+					transXChange.progress.AddRecords(1)
 				}
 			}
 		default:
@@ -1,7 +1,6 @@
 package travelinenoc
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -15,10 +14,10 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/names"
 	"github.com/travigo/travigo/pkg/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TravelineData struct {
@@ -123,7 +122,7 @@ func (t *TravelineData) convertToCTDF() ([]*ctdf.Operator, []*ctdf.OperatorGroup
 			operator.PrimaryName = nocLineRecord.PublicName
 			operator.OtherNames = append(operator.OtherNames, nocLineRecord.PublicName, nocLineRecord.ReferenceName)
 			operator.Licence = nocLineRecord.Licence
-			operator.TransportType = nocLineRecord.Mode
+			operator.TransportType = ctdf.ParseTransportType(nocLineRecord.Mode)
 
 			if nocLineRecord.London != "" {
 				operator.Regions = append(operator.Regions, "UK:REGION:LONDON")
@@ -198,6 +197,7 @@ func (t *TravelineData) convertToCTDF() ([]*ctdf.Operator, []*ctdf.OperatorGroup
 
 	// Filter the generated CTDF Operators for duplicate strings
 	for _, operator := range operators {
+		names.NormaliseOperator(operator)
 		operator.OtherIdentifiers = util.RemoveDuplicateStrings(operator.OtherIdentifiers, []string{})
 		operator.OtherNames = util.RemoveDuplicateStrings(operator.OtherNames, []string{operator.PrimaryName})
 	}
@@ -229,6 +229,8 @@ func (t *TravelineData) Import(dataset datasets.DataSet, datasource *ctdf.DataSo
 	numBatches := int(math.Ceil(float64(len(operators)) / float64(maxBatchSize)))
 
 	processingGroup := sync.WaitGroup{}
+	var writeErr error
+	writeErrMutex := sync.Mutex{}
 	processingGroup.Add(numBatches)
 
 	for i := 0; i < numBatches; i++ {
@@ -263,9 +265,11 @@ func (t *TravelineData) Import(dataset datasets.DataSet, datasource *ctdf.DataSo
 			atomic.AddUint64(&operatorOperationInsert, localOperationInsert)
 
 			if len(operatorOperations) > 0 {
-				_, err := operatorsCollection.BulkWrite(context.Background(), operatorOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(operatorsCollection, operatorOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write Operators")
+					writeErrMutex.Lock()
+					writeErr = err
+					writeErrMutex.Unlock()
 				}
 			}
 
@@ -275,6 +279,10 @@ func (t *TravelineData) Import(dataset datasets.DataSet, datasource *ctdf.DataSo
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return writeErr
+	}
+
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", operatorOperationInsert)
 
@@ -320,9 +328,11 @@ func (t *TravelineData) Import(dataset datasets.DataSet, datasource *ctdf.DataSo
 			atomic.AddUint64(&operatorGroupOperationInsert, localOperationInsert)
 
 			if len(operatorGroupOperations) > 0 {
-				_, err := operatorGroupsCollection.BulkWrite(context.Background(), operatorGroupOperations, &options.BulkWriteOptions{})
+				_, err := dataset.Sink.BulkWrite(operatorGroupsCollection, operatorGroupOperations)
 				if err != nil {
-					log.Fatal().Err(err).Msg("Failed to bulk write OperatorGroups")
+					writeErrMutex.Lock()
+					writeErr = err
+					writeErrMutex.Unlock()
 				}
 			}
 
@@ -332,6 +342,10 @@ func (t *TravelineData) Import(dataset datasets.DataSet, datasource *ctdf.DataSo
 
 	processingGroup.Wait()
 
+	if writeErr != nil {
+		return writeErr
+	}
+
 	log.Info().Msg(" - Written to MongoDB")
 	log.Info().Msgf(" - %d inserts", operatorGroupOperationInsert)
 
@@ -18,7 +18,6 @@ func (t *TravelineData) ParseFile(reader io.Reader) error {
 			// EOF means we're done.
 			break
 		} else if err != nil {
-			log.Fatal().Msgf("Error decoding token: %s", err)
 			return err
 		}
 
@@ -37,50 +36,44 @@ func (t *TravelineData) ParseFile(reader io.Reader) error {
 				var NOCLinesRecord NOCLinesRecord
 
 				if err = d.DecodeElement(&NOCLinesRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.NOCLinesRecords = append(t.NOCLinesRecords, NOCLinesRecord)
+					return err
 				}
+				t.NOCLinesRecords = append(t.NOCLinesRecords, NOCLinesRecord)
 			} else if ty.Name.Local == "NOCTableRecord" {
 				var NOCTableRecord NOCTableRecord
 
 				if err = d.DecodeElement(&NOCTableRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.NOCTableRecords = append(t.NOCTableRecords, NOCTableRecord)
+					return err
 				}
+				t.NOCTableRecords = append(t.NOCTableRecords, NOCTableRecord)
 			} else if ty.Name.Local == "OperatorsRecord" {
 				var operatorRecord OperatorsRecord
 
 				if err = d.DecodeElement(&operatorRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.OperatorsRecords = append(t.OperatorsRecords, operatorRecord)
+					return err
 				}
+				t.OperatorsRecords = append(t.OperatorsRecords, operatorRecord)
 			} else if ty.Name.Local == "GroupsRecord" {
 				var groupRecord GroupsRecord
 
 				if err = d.DecodeElement(&groupRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.GroupsRecords = append(t.GroupsRecords, groupRecord)
+					return err
 				}
+				t.GroupsRecords = append(t.GroupsRecords, groupRecord)
 			} else if ty.Name.Local == "ManagementDivisionsRecord" {
 				var managementRecord ManagementDivisionsRecord
 
 				if err = d.DecodeElement(&managementRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.ManagementDivisionsRecords = append(t.ManagementDivisionsRecords, managementRecord)
+					return err
 				}
+				t.ManagementDivisionsRecords = append(t.ManagementDivisionsRecords, managementRecord)
 			} else if ty.Name.Local == "PublicNameRecord" {
 				var publicNameRecord PublicNameRecord
 
 				if err = d.DecodeElement(&publicNameRecord, &ty); err != nil {
-					log.Fatal().Msgf("Error decoding item: %s", err)
-				} else {
-					t.PublicNameRecords = append(t.PublicNameRecords, publicNameRecord)
+					return err
 				}
+				t.PublicNameRecords = append(t.PublicNameRecords, publicNameRecord)
 			}
 		default:
 		}
@@ -0,0 +1,108 @@
+package importerrors
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultThreshold is the number of failed records an import tolerates when the dataset doesn't set its own
+const DefaultThreshold = 1000
+
+// Only a sample of the errors are kept so a badly broken file doesn't balloon the recorded result
+const maxSampleErrors = 50
+
+// Collector counts the per-record failures of an import so it can carry on past bad records.
+// All methods are safe to call on a nil Collector, in which case every error is returned straight back
+// so the import fails on the first bad record.
+type Collector struct {
+	Threshold int
+
+	failures       int
+	failuresByType map[ErrorType]int
+	samples        []*Sample
+
+	mutex sync.Mutex
+}
+
+type Sample struct {
+	Type    ErrorType
+	Message string
+}
+
+func NewCollector(threshold int) *Collector {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	return &Collector{
+		Threshold:      threshold,
+		failuresByType: map[ErrorType]int{},
+	}
+}
+
+// Add records a failed record. It only returns an error once the threshold has been exceeded and the import should stop
+func (c *Collector) Add(err error) error {
+	if c == nil {
+		return err
+	}
+
+	errorType := GetErrorType(err)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.failures += 1
+	c.failuresByType[errorType] += 1
+
+	if len(c.samples) < maxSampleErrors {
+		c.samples = append(c.samples, &Sample{
+			Type:    errorType,
+			Message: err.Error(),
+		})
+	}
+
+	log.Warn().Err(err).Str("type", string(errorType)).Msg("Skipping failed record")
+
+	if c.failures > c.Threshold {
+		return errors.New(fmt.Sprintf("Too many failed records (%d failures, threshold %d): %s", c.failures, c.Threshold, err))
+	}
+
+	return nil
+}
+
+func (c *Collector) Failures() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.failures
+}
+
+// Result builds the summary of the failures seen so far
+func (c *Collector) Result() *Result {
+	result := &Result{
+		FailuresByType: map[ErrorType]int{},
+	}
+
+	if c == nil {
+		return result
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result.Failures = c.failures
+	result.Threshold = c.Threshold
+	result.Samples = append(result.Samples, c.samples...)
+	for errorType, count := range c.failuresByType {
+		result.FailuresByType[errorType] = count
+	}
+
+	return result
+}
@@ -0,0 +1,93 @@
+package importerrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorType string
+
+const (
+	ErrorTypeDownload  ErrorType = "download"
+	ErrorTypeParse               = "parse"
+	ErrorTypeReference           = "reference"
+	ErrorTypeWrite               = "write"
+	ErrorTypeUnknown             = "unknown"
+)
+
+// DownloadError is returned when the source of a dataset couldn't be fetched
+type DownloadError struct {
+	Source string
+	Err    error
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("downloading %s: %s", e.Source, e.Err)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is a single record in the source file that couldn't be decoded
+type ParseError struct {
+	File   string
+	Record string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("parsing %s: %s", e.Record, e.Err)
+	}
+	return fmt.Sprintf("parsing %s in %s: %s", e.Record, e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ReferenceError is a record that refers to another object which doesn't exist in the dataset
+type ReferenceError struct {
+	Object     string
+	Identifier string
+	Reference  string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s %s references unknown %s", e.Object, e.Identifier, e.Reference)
+}
+
+// WriteError is a batch of records that couldn't be written to the database
+type WriteError struct {
+	Collection string
+	Err        error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("writing to %s: %s", e.Collection, e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+func GetErrorType(err error) ErrorType {
+	var downloadError *DownloadError
+	var parseError *ParseError
+	var referenceError *ReferenceError
+	var writeError *WriteError
+
+	switch {
+	case errors.As(err, &downloadError):
+		return ErrorTypeDownload
+	case errors.As(err, &parseError):
+		return ErrorTypeParse
+	case errors.As(err, &referenceError):
+		return ErrorTypeReference
+	case errors.As(err, &writeError):
+		return ErrorTypeWrite
+	default:
+		return ErrorTypeUnknown
+	}
+}
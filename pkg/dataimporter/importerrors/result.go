@@ -0,0 +1,48 @@
+package importerrors
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Result is the outcome of a single import of a dataset
+type Result struct {
+	Dataset   string
+	StartTime time.Time
+	EndTime   time.Time
+
+	Succeeded bool
+	// Error is the reason the import was aborted, including an exceeded failure threshold
+	Error string `json:",omitempty" bson:",omitempty"`
+
+	// Anomalies are record count drops against recent runs, the import is only aborted for them if the dataset is configured to
+	Anomalies []string `json:",omitempty" bson:",omitempty"`
+
+	Failures       int
+	Threshold      int
+	FailuresByType map[ErrorType]int
+	Samples        []*Sample
+}
+
+// Record stores the result as the latest for the dataset
+func (r *Result) Record() error {
+	collection := database.GetCollection("dataset_import_results")
+
+	opts := options.Replace().SetUpsert(true)
+	_, err := collection.ReplaceOne(context.Background(), bson.M{"dataset": r.Dataset}, r, opts)
+
+	return err
+}
+
+func GetLatestResult(dataset string) *Result {
+	collection := database.GetCollection("dataset_import_results")
+
+	var result *Result
+	collection.FindOne(context.Background(), bson.M{"dataset": dataset}).Decode(&result)
+
+	return result
+}
@@ -0,0 +1,116 @@
+package importqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+const QueueName = "import-queue"
+
+// How long the status of a job is kept after it was last updated
+const jobStatusRetention = 24 * time.Hour
+
+const jobStatusKeyFormat = "travigo:import-job:%s"
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning             = "running"
+	JobStatusSucceeded           = "succeeded"
+	JobStatusFailed              = "failed"
+	// Skipped jobs were picked up while another worker was already importing the dataset
+	JobStatusSkipped = "skipped"
+)
+
+// ImportJob asks a worker to import a dataset, the run ID identifies this request across the manager & workers
+type ImportJob struct {
+	RunID   string
+	Dataset string
+
+	Force  bool
+	Staged bool
+
+	Status JobStatus
+	Worker string `json:",omitempty"`
+	Error  string `json:",omitempty"`
+
+	CreationDateTime     time.Time
+	ModificationDateTime time.Time
+}
+
+// NewImportJob creates a queued job for the dataset with a new run ID
+func NewImportJob(dataset string) *ImportJob {
+	now := time.Now()
+
+	return &ImportJob{
+		RunID:   fmt.Sprintf("%s-%d", dataset, now.UnixNano()),
+		Dataset: dataset,
+
+		Status: JobStatusQueued,
+
+		CreationDateTime:     now,
+		ModificationDateTime: now,
+	}
+}
+
+// Publish puts the job onto the import queue for the next free worker to pick up
+func Publish(job *ImportJob) error {
+	queue, err := redis_client.QueueConnection.OpenQueue(QueueName)
+	if err != nil {
+		return err
+	}
+
+	jobBytes, err := queuemessage.Marshal(queuemessage.MessageTypeImportJob, job)
+	if err != nil {
+		return err
+	}
+
+	if err := queue.PublishBytes(jobBytes); err != nil {
+		return err
+	}
+
+	return saveJobStatus(job)
+}
+
+// GetJob returns the latest status of a job, or nil once it has expired
+func GetJob(runID string) (*ImportJob, error) {
+	jobBytes, err := redis_client.Client.Get(context.Background(), fmt.Sprintf(jobStatusKeyFormat, runID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var job ImportJob
+	if err := json.Unmarshal(jobBytes, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func setJobStatus(job *ImportJob, status JobStatus, jobErr error) error {
+	job.Status = status
+	job.ModificationDateTime = time.Now()
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+
+	return saveJobStatus(job)
+}
+
+func saveJobStatus(job *ImportJob) error {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return redis_client.Client.Set(context.Background(), fmt.Sprintf(jobStatusKeyFormat, job.RunID), jobBytes, jobStatusRetention).Err()
+}
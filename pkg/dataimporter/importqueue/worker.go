@@ -0,0 +1,141 @@
+package importqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"github.com/travigo/travigo/pkg/leader"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// How often deliveries left unacked by workers that have died are put back on the queue
+const cleanerInterval = 1 * time.Minute
+
+// Only one worker imports a dataset at a time, the lease expires this long after a worker dies
+const datasetLeaseTTL = 5 * time.Minute
+
+const datasetLeaseFormat = "import-dataset-%s"
+
+// Worker imports the datasets of the jobs it takes off the import queue
+type Worker struct {
+	Name string
+}
+
+func NewWorker() *Worker {
+	hostname, _ := os.Hostname()
+
+	return &Worker{
+		Name: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Consume is given one job at a time so a slow import doesn't hold up jobs for other datasets
+func (w *Worker) Consume(batch rmq.Deliveries) {
+	for _, delivery := range batch {
+		var job ImportJob
+		if err := queuemessage.Unmarshal([]byte(delivery.Payload()), queuemessage.MessageTypeImportJob, &job); err != nil {
+			log.Error().Err(err).Msg("Failed to decode import job")
+			delivery.Reject()
+			continue
+		}
+
+		w.runJob(&job)
+
+		// The outcome is in the job status so the delivery is done with whether it succeeded or not
+		if err := delivery.Ack(); err != nil {
+			log.Error().Err(err).Str("run", job.RunID).Msg("Failed to ack import job")
+		}
+	}
+}
+
+func (w *Worker) runJob(job *ImportJob) {
+	job.Worker = w.Name
+
+	// The holder is unique to this worker so a job handed back to the queue can't share the lease with a worker
+	// that's still importing it, it has to wait for the lease to expire instead
+	leaseName := fmt.Sprintf(datasetLeaseFormat, job.Dataset)
+	leaseHolder := fmt.Sprintf("%s-%s", w.Name, job.RunID)
+	lease, err := leader.Acquire(leader.RedisStore{}, leaseName, leaseHolder, datasetLeaseTTL)
+	if err != nil {
+		log.Error().Err(err).Str("run", job.RunID).Str("dataset", job.Dataset).Msg("Failed to take import lease")
+		w.saveStatus(job, JobStatusFailed, err)
+		return
+	}
+	if lease == nil {
+		holder, _ := leader.RedisStore{}.Holder(context.Background(), leaseName)
+		log.Info().Str("run", job.RunID).Str("dataset", job.Dataset).Str("holder", holder).Msg("Dataset is already being imported, skipping job")
+		w.saveStatus(job, JobStatusSkipped, errors.New(fmt.Sprintf("Dataset was already being imported by %s", holder)))
+		return
+	}
+	defer func() {
+		if err := lease.Release(); err != nil {
+			log.Error().Err(err).Str("run", job.RunID).Str("dataset", job.Dataset).Msg("Failed to release import lease")
+		}
+	}()
+
+	w.saveStatus(job, JobStatusRunning, nil)
+
+	dataset, err := manager.GetDataset(job.Dataset)
+	if err != nil {
+		w.saveStatus(job, JobStatusFailed, err)
+		return
+	}
+	if job.Staged {
+		dataset.StagedImport = true
+	}
+	dataset.Progress = progress.NewTracker(dataset.Identifier, progress.NewCLIReporter())
+
+	// Another worker can take over the dataset once the lease is lost so the import has to stop
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dataset.Context = ctx
+	go func() {
+		select {
+		case <-lease.Lost():
+			log.Warn().Str("run", job.RunID).Str("dataset", job.Dataset).Msg("Lost import lease, cancelling import")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log.Info().Str("run", job.RunID).Str("dataset", dataset.Identifier).Str("worker", w.Name).Msg("Starting queued import")
+	startTime := time.Now()
+
+	if err := manager.ImportDataset(&dataset, job.Force); err != nil {
+		log.Error().Err(err).Str("run", job.RunID).Str("dataset", dataset.Identifier).Msg("Failed to import dataset")
+		w.saveStatus(job, JobStatusFailed, err)
+		return
+	}
+
+	log.Info().Str("run", job.RunID).Str("dataset", dataset.Identifier).Msgf("Queued import took %s", time.Since(startTime).String())
+	w.saveStatus(job, JobStatusSucceeded, nil)
+}
+
+func (w *Worker) saveStatus(job *ImportJob, status JobStatus, jobErr error) {
+	if err := setJobStatus(job, status, jobErr); err != nil {
+		log.Error().Err(err).Str("run", job.RunID).Msg("Failed to save import job status")
+	}
+}
+
+// RunCleaner returns the jobs of workers that stopped without finishing them back to the queue.
+// Only one worker cleans at a time, it never returns
+func RunCleaner() {
+	cleaner := rmq.NewCleaner(redis_client.QueueConnection)
+
+	leader.RunAsLeader(leader.RedisStore{}, "import-queue-cleaner", cleanerInterval, func(_ context.Context) {
+		returned, err := cleaner.Clean()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to clean import queue")
+		} else if returned > 0 {
+			log.Info().Int64("returned", returned).Msg("Returned unfinished import jobs to the queue")
+		}
+	})
+}
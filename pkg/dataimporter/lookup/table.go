@@ -0,0 +1,157 @@
+package lookup
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Record is the minimal view of a document needed to resolve a reference to it
+type Record struct {
+	ObjectID          primitive.ObjectID `bson:"_id"`
+	PrimaryIdentifier string
+	PrimaryName       string
+}
+
+// Table maps every primary & other identifier of a collection to its record, so importers can resolve
+// references without a database round trip per record
+type Table struct {
+	Collection string
+
+	records map[string]*Record
+	names   map[string][]*Record
+}
+
+// Load reads the identifiers of every document in the collection matching the filter into memory
+func Load(collectionName string, filter bson.M) (*Table, error) {
+	startTime := time.Now()
+
+	table := &Table{
+		Collection: collectionName,
+		records:    map[string]*Record{},
+		names:      map[string][]*Record{},
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	collection := database.GetCollection(collectionName)
+	opts := options.Find().SetProjection(bson.M{
+		"_id":               1,
+		"primaryidentifier": 1,
+		"primaryname":       1,
+		"otheridentifiers":  1,
+	})
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var document struct {
+			Record           `bson:",inline"`
+			OtherIdentifiers []string
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+
+		record := &Record{
+			ObjectID:          document.ObjectID,
+			PrimaryIdentifier: document.PrimaryIdentifier,
+			PrimaryName:       document.PrimaryName,
+		}
+
+		for _, identifier := range document.OtherIdentifiers {
+			// Primary identifiers always win over another documents other identifiers
+			if _, exists := table.records[identifier]; !exists {
+				table.records[identifier] = record
+			}
+		}
+		table.records[record.PrimaryIdentifier] = record
+
+		if name := normaliseName(record.PrimaryName); name != "" {
+			table.names[name] = append(table.names[name], record)
+		}
+	}
+
+	log.Info().
+		Str("collection", collectionName).
+		Int("identifiers", len(table.records)).
+		Str("duration", time.Since(startTime).String()).
+		Msg("Loaded lookup table")
+
+	return table, cursor.Err()
+}
+
+// Get finds the record that has the identifier as either its primary or one of its other identifiers
+func (t *Table) Get(identifier string) *Record {
+	if t == nil {
+		return nil
+	}
+
+	return t.records[identifier]
+}
+
+// GetByName finds every record whose primary name matches, ignoring case, punctuation & company suffixes
+func (t *Table) GetByName(name string) []*Record {
+	if t == nil {
+		return nil
+	}
+
+	return t.names[normaliseName(name)]
+}
+
+func (t *Table) Exists(identifier string) bool {
+	return t.Get(identifier) != nil
+}
+
+// GetPrimaryIdentifier resolves any identifier to the primary identifier of its document, or an empty string if unknown
+func (t *Table) GetPrimaryIdentifier(identifier string) string {
+	record := t.Get(identifier)
+	if record == nil {
+		return ""
+	}
+
+	return record.PrimaryIdentifier
+}
+
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+
+	return len(t.records)
+}
+
+var ignoredNameWords = map[string]bool{
+	"the":     true,
+	"ltd":     true,
+	"limited": true,
+	"plc":     true,
+	"uk":      true,
+}
+
+func normaliseName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var normalisedWords []string
+	for _, word := range words {
+		if !ignoredNameWords[word] {
+			normalisedWords = append(normalisedWords, word)
+		}
+	}
+
+	return strings.Join(normalisedWords, " ")
+}
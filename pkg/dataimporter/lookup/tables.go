@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TableStops     = "stops"
+	TableOperators = "operators"
+	TableServices  = "services"
+)
+
+// Tables holds the lookup tables for a single import. The manager preloads the tables a format needs before
+// parsing starts, anything else is built the first time it's asked for, and then shared by every file in the import.
+// All methods are safe to call on a nil Tables, which returns nil tables that contain nothing.
+type Tables struct {
+	tables map[string]*lazyTable
+}
+
+type lazyTable struct {
+	collection string
+
+	table *Table
+	once  sync.Once
+}
+
+func (l *lazyTable) get() *Table {
+	l.once.Do(func() {
+		table, err := Load(l.collection, nil)
+		if err != nil {
+			log.Error().Err(err).Str("collection", l.collection).Msg("Failed to load lookup table")
+		}
+
+		l.table = table
+	})
+
+	return l.table
+}
+
+func NewTables() *Tables {
+	return &Tables{
+		tables: map[string]*lazyTable{
+			TableStops:     {collection: "stops"},
+			TableOperators: {collection: "operators"},
+			TableServices:  {collection: "services"},
+		},
+	}
+}
+
+// Preload builds the named tables in parallel
+func (t *Tables) Preload(names ...string) {
+	if t == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			t.get(name)
+			wg.Done()
+		}(name)
+	}
+
+	wg.Wait()
+}
+
+func (t *Tables) get(name string) *Table {
+	if t == nil || t.tables[name] == nil {
+		return nil
+	}
+
+	return t.tables[name].get()
+}
+
+func (t *Tables) Stops() *Table {
+	return t.get(TableStops)
+}
+
+func (t *Tables) Operators() *Table {
+	return t.get(TableOperators)
+}
+
+func (t *Tables) Services() *Table {
+	return t.get(TableServices)
+}
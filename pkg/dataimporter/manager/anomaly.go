@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Number of previous accepted runs the record count baseline is taken from
+const anomalyBaselineRuns = 5
+
+// DatasetRecordCounts are the number of records each collection had after an import run
+type DatasetRecordCounts struct {
+	Dataset string
+	Run     string
+
+	Counts    map[string]int64
+	Anomalies []string `bson:",omitempty"`
+
+	// Only accepted runs make up the baseline so one truncated file doesn't drag it down for the next run
+	Accepted bool
+
+	CreationDateTime time.Time
+}
+
+// checkImportAnomalies compares the record counts of this run against the baseline of recent runs.
+// It must run before anything is promoted or cleaned up so an aborted run leaves the live data alone.
+func checkImportAnomalies(dataset *datasets.DataSet, datasource *ctdf.DataSourceReference, stagedImport bool) ([]string, error) {
+	action := dataset.AnomalyDetection.GetAction()
+	if action == datasets.AnomalyActionIgnore {
+		return nil, nil
+	}
+
+	collections := getSupportedCollections(dataset)
+	if len(collections) == 0 {
+		return nil, nil
+	}
+
+	counts := map[string]int64{}
+	for _, collectionName := range collections {
+		count, err := countRunRecords(collectionName, datasource, stagedImport)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[collectionName] = count
+	}
+
+	baseline, err := getRecordCountBaseline(collections, datasource)
+	if err != nil {
+		return nil, err
+	}
+
+	maximumDrop := dataset.AnomalyDetection.GetMaximumDrop()
+	var anomalies []string
+
+	for _, collectionName := range collections {
+		baselineCount := baseline[collectionName]
+		if baselineCount == 0 {
+			continue
+		}
+
+		drop := 1 - float64(counts[collectionName])/float64(baselineCount)
+		if drop > maximumDrop {
+			anomalies = append(anomalies, fmt.Sprintf(
+				"%s has %d records compared to a baseline of %d (%.0f%% drop)",
+				collectionName, counts[collectionName], baselineCount, drop*100,
+			))
+		}
+	}
+
+	abort := len(anomalies) > 0 && action == datasets.AnomalyActionAbort && !dataset.AcceptAnomalies
+
+	recordCountsCollection := database.GetCollection("dataset_record_counts")
+	_, err = recordCountsCollection.InsertOne(context.Background(), DatasetRecordCounts{
+		Dataset:          datasource.DatasetID,
+		Run:              datasource.Timestamp,
+		Counts:           counts,
+		Anomalies:        anomalies,
+		Accepted:         !abort,
+		CreationDateTime: time.Now(),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to record dataset record counts")
+	}
+
+	if len(anomalies) == 0 {
+		return nil, nil
+	}
+
+	log.Warn().Str("dataset", dataset.Identifier).Strs("anomalies", anomalies).Bool("aborted", abort).Msg("Import record counts are anomalous")
+
+	if abort {
+		return anomalies, errors.New(fmt.Sprintf("Import aborted as record counts are anomalous: %s", strings.Join(anomalies, ", ")))
+	}
+
+	return anomalies, nil
+}
+
+func countRunRecords(collectionName string, datasource *ctdf.DataSourceReference, stagedImport bool) (int64, error) {
+	if stagedImport {
+		return datasink.GetStagingCollection(collectionName).CountDocuments(context.Background(), bson.M{
+			"datasource.datasetid": datasource.DatasetID,
+		})
+	}
+
+	return database.GetCollection(collectionName).CountDocuments(context.Background(), bson.M{
+		"datasource.datasetid": datasource.DatasetID,
+		"datasource.timestamp": datasource.Timestamp,
+	})
+}
+
+// getRecordCountBaseline uses the median count of the recent accepted runs,
+// falling back to what is currently live for datasets that don't have any recorded runs yet
+func getRecordCountBaseline(collections []string, datasource *ctdf.DataSourceReference) (map[string]int64, error) {
+	recordCountsCollection := database.GetCollection("dataset_record_counts")
+
+	opts := options.Find().SetSort(bson.D{{Key: "creationdatetime", Value: -1}}).SetLimit(anomalyBaselineRuns)
+	cursor, err := recordCountsCollection.Find(context.Background(), bson.M{
+		"dataset":  datasource.DatasetID,
+		"run":      bson.M{"$ne": datasource.Timestamp},
+		"accepted": true,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*DatasetRecordCounts
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		return nil, err
+	}
+
+	baseline := map[string]int64{}
+
+	for _, collectionName := range collections {
+		var previousCounts []int64
+		for _, run := range runs {
+			if count, exists := run.Counts[collectionName]; exists {
+				previousCounts = append(previousCounts, count)
+			}
+		}
+
+		if len(previousCounts) > 0 {
+			baseline[collectionName] = medianCount(previousCounts)
+			continue
+		}
+
+		// Without staging this runs records have already been upserted over the old ones so this is everything from both
+		liveCount, err := database.GetCollection(collectionName).CountDocuments(context.Background(), bson.M{"datasource.datasetid": datasource.DatasetID})
+		if err != nil {
+			return nil, err
+		}
+
+		baseline[collectionName] = liveCount
+	}
+
+	return baseline, nil
+}
+
+func medianCount(counts []int64) int64 {
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i] < counts[j]
+	})
+
+	middle := len(counts) / 2
+	if len(counts)%2 == 1 {
+		return counts[middle]
+	}
+
+	return int64(math.Round(float64(counts[middle-1]+counts[middle]) / 2))
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/archive"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+)
+
+func archiveDownload(dataset *datasets.DataSet, datasource *ctdf.DataSourceReference, source string, hash string) {
+	store, err := archive.GetStore()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to setup archive store")
+		return
+	}
+	if store == nil {
+		return
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open download for archiving")
+		return
+	}
+	defer file.Close()
+
+	var size int64
+	if fileInfo, err := file.Stat(); err == nil {
+		size = fileInfo.Size()
+	}
+
+	err = archive.Archive(store, dataset.Identifier, datasource.Timestamp, hash, size, file)
+	if err != nil {
+		log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to archive download")
+		return
+	}
+
+	log.Info().Str("dataset", dataset.Identifier).Str("run", datasource.Timestamp).Int64("size", size).Msg("Archived download")
+}
+
+func tempArchivedFile(dataset *datasets.DataSet) (*os.File, error) {
+	store, err := archive.GetStore()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, errors.New("TRAVIGO_DATAIMPORTER_ARCHIVE_URL must be set to import from an archived run")
+	}
+
+	datasetArchive, err := archive.GetArchive(dataset.Identifier, dataset.FromRun)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := store.Get(context.Background(), datasetArchive.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	tmpFile, err := os.CreateTemp(os.TempDir(), "travigo-data-importer-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer tmpFile.Close()
+
+	if _, err := io.Copy(tmpFile, reader); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	log.Info().Str("dataset", dataset.Identifier).Str("run", dataset.FromRun).Msg("Loaded archived download")
+
+	return tmpFile, nil
+}
@@ -2,22 +2,22 @@ package manager
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 
-	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/util"
 )
 
-func customAuthNationalRailLogin() string {
+func customAuthNationalRailLogin() (string, error) {
 	env := util.GetEnvironmentVariables()
 	if env["TRAVIGO_NATIONALRAIL_USERNAME"] == "" {
-		log.Fatal().Msg("TRAVIGO_NATIONALRAIL_USERNAME must be set")
+		return "", errors.New("TRAVIGO_NATIONALRAIL_USERNAME must be set")
 	}
 	if env["TRAVIGO_NATIONALRAIL_PASSWORD"] == "" {
-		log.Fatal().Msg("TRAVIGO_NATIONALRAIL_PASSWORD must be set")
+		return "", errors.New("TRAVIGO_NATIONALRAIL_PASSWORD must be set")
 	}
 
 	formData := url.Values{
@@ -28,26 +28,28 @@ func customAuthNationalRailLogin() string {
 	client := &http.Client{}
 	req, err := http.NewRequest("POST", "https://opendata.nationalrail.co.uk/authenticate", strings.NewReader(formData.Encode()))
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create auth HTTP request")
+		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to perform auth HTTP request")
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to read auth HTTP request")
+		return "", err
 	}
 
 	var loginResponse struct {
 		Token string `json:"token"`
 	}
-	json.Unmarshal(body, &loginResponse)
+	if err := json.Unmarshal(body, &loginResponse); err != nil {
+		return "", err
+	}
 
-	return loginResponse.Token
+	return loginResponse.Token, nil
 }
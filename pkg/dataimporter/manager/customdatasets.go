@@ -0,0 +1,171 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/customdatasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/gtfs"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RegisterCustomDataSet onboards an operators GTFS feed. The feed is validated straight away and
+// only gets scheduled for import once it passes
+func RegisterCustomDataSet(registration customdatasets.Registration) (*customdatasets.CustomDataSet, error) {
+	if !isValidUrl(registration.Source) {
+		return nil, errors.New(fmt.Sprintf("Source %s is not a valid URL", registration.Source))
+	}
+
+	var reservedIdentifiers []string
+	for _, dataset := range GetRegisteredDataSets() {
+		reservedIdentifiers = append(reservedIdentifiers, dataset.Identifier)
+	}
+
+	customDataset, err := customdatasets.Register(registration, reservedIdentifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("dataset", customDataset.Identifier).Str("source", customDataset.Source).Msg("Registered custom dataset")
+
+	if _, err := ValidateCustomDataSet(customDataset); err != nil {
+		return customDataset, err
+	}
+
+	return customDataset, nil
+}
+
+// ValidateCustomDataSet downloads the feed of a custom dataset & runs it through the GTFS validator, recording the outcome.
+// A feed that can't be downloaded fails validation the same as a broken one
+func ValidateCustomDataSet(customDataset *customdatasets.CustomDataSet) (*gtfs.ValidationReport, error) {
+	dataset, err := customDataset.ToDataSet()
+	if err != nil {
+		return nil, err
+	}
+	dataset.Context = context.Background()
+
+	var report *gtfs.ValidationReport
+
+	_, file, _, err := tempDownloadFile(&dataset, "")
+	if file != nil {
+		defer os.Remove(file.Name())
+		defer file.Close()
+	}
+
+	if err != nil {
+		report = &gtfs.ValidationReport{
+			Errors: []string{fmt.Sprintf("Failed to download feed: %s", err)},
+		}
+	} else if file == nil {
+		report = &gtfs.ValidationReport{
+			Errors: []string{"Failed to download feed"},
+		}
+	} else {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		report, err = gtfs.Validate(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := customdatasets.RecordValidation(customDataset, report); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("dataset", customDataset.Identifier).
+		Str("status", string(customDataset.Status)).
+		Strs("errors", report.Errors).
+		Strs("warnings", report.Warnings).
+		Msg("Validated custom dataset")
+
+	return report, nil
+}
+
+// ImportDueCustomDataSets imports every validated custom dataset that hasn't been imported within its refresh interval.
+// Feeds are validated again first as the operator can change them at any time, one that now fails isn't imported.
+// Cancelling the context stops the import in progress & leaves the rest for the next run
+func ImportDueCustomDataSets(ctx context.Context, now time.Time) error {
+	customDatasets, err := customdatasets.GetCustomDataSets()
+	if err != nil {
+		return err
+	}
+
+	for _, customDataset := range customDatasets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !customDataset.IsDue(now) {
+			continue
+		}
+
+		report, err := ValidateCustomDataSet(customDataset)
+		if err != nil {
+			log.Error().Err(err).Str("dataset", customDataset.Identifier).Msg("Failed to validate custom dataset")
+			continue
+		}
+		if !report.Valid {
+			log.Warn().Str("dataset", customDataset.Identifier).Msg("Custom dataset no longer passes validation, skipping import")
+			continue
+		}
+
+		dataset, err := customDataset.ToDataSet()
+		if err != nil {
+			log.Error().Err(err).Str("dataset", customDataset.Identifier).Msg("Failed to build custom dataset")
+			continue
+		}
+		dataset.Progress = progress.NewTracker(dataset.Identifier, progress.NewCLIReporter())
+		dataset.Context = ctx
+
+		if err := ImportDataset(&dataset, false); err != nil {
+			log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to import custom dataset")
+			continue
+		}
+
+		if err := customdatasets.MarkImported(customDataset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveCustomDataSet stops importing a custom dataset & removes everything that was imported from it
+func RemoveCustomDataSet(identifier string) error {
+	customDataset := customdatasets.GetCustomDataSet(identifier)
+	if customDataset == nil {
+		return errors.New(fmt.Sprintf("Custom dataset %s could not be found", identifier))
+	}
+
+	dataset, err := customDataset.ToDataSet()
+	if err != nil {
+		return err
+	}
+
+	// The records go first so a failure part way through can be retried
+	collectionNames := append(getSupportedCollections(&dataset), "service_stop_summaries", "stop_departures", "blocks")
+	for _, collectionName := range collectionNames {
+		result, err := database.GetCollection(collectionName).DeleteMany(context.Background(), bson.M{
+			"datasource.originalformat": string(dataset.Format),
+			"datasource.datasetid":      dataset.Identifier,
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("collection", collectionName).Int64("num", result.DeletedCount).Msg("Removed custom dataset records")
+	}
+
+	return customdatasets.Remove(identifier)
+}
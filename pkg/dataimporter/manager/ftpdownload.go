@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/util"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const ftpTimeout = 30 * time.Second
+
+// FTP servers dont provide an ETag so we generate one from the modification time & size of the file
+func generateFTPEtag(modificationTime time.Time, size int64) string {
+	return fmt.Sprintf("%d-%d", modificationTime.Unix(), size)
+}
+
+func getBasicAuthentication(dataset *datasets.DataSet) (string, string, error) {
+	env := util.GetEnvironmentVariables()
+
+	if dataset.SourceAuthentication.Basic.Username == "" || dataset.SourceAuthentication.Basic.Password == "" {
+		return "anonymous", "anonymous", nil
+	}
+
+	if env[dataset.SourceAuthentication.Basic.Username] == "" {
+		return "", "", errors.New(fmt.Sprintf("%s must be set", dataset.SourceAuthentication.Basic.Username))
+	}
+	if env[dataset.SourceAuthentication.Basic.Password] == "" {
+		return "", "", errors.New(fmt.Sprintf("%s must be set", dataset.SourceAuthentication.Basic.Password))
+	}
+
+	return env[dataset.SourceAuthentication.Basic.Username], env[dataset.SourceAuthentication.Basic.Password], nil
+}
+
+func tempDownloadFTPFile(dataset *datasets.DataSet, sourceURL *url.URL, etag string) (bool, *os.File, string, error) {
+	host := sourceURL.Host
+	if sourceURL.Port() == "" {
+		host = net.JoinHostPort(sourceURL.Hostname(), "21")
+	}
+
+	conn, err := ftp.Dial(host, ftp.DialWithTimeout(ftpTimeout))
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+	defer conn.Quit()
+
+	username, password, err := getBasicAuthentication(dataset)
+	if err != nil {
+		return false, nil, "", err
+	}
+	if err := conn.Login(username, password); err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+
+	// Not all servers support MDTM/SIZE so only skip the download if we can get both
+	modificationTime, timeErr := conn.GetTime(sourceURL.Path)
+	size, sizeErr := conn.FileSize(sourceURL.Path)
+	var newEtag string
+	if timeErr == nil && sizeErr == nil {
+		newEtag = generateFTPEtag(modificationTime, size)
+
+		if etag != "" && newEtag == etag {
+			return false, nil, "", nil
+		}
+	}
+
+	response, err := conn.Retr(sourceURL.Path)
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+	defer response.Close()
+
+	tmpFile, err := os.CreateTemp(os.TempDir(), "travigo-data-importer-")
+	if err != nil {
+		return false, nil, "", err
+	}
+
+	if _, err := io.Copy(tmpFile, response); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+
+	log.Debug().Str("path", tmpFile.Name()).Msg("Data file downloaded")
+
+	return true, tmpFile, newEtag, nil
+}
+
+func tempDownloadSFTPFile(dataset *datasets.DataSet, sourceURL *url.URL, etag string) (bool, *os.File, string, error) {
+	host := sourceURL.Host
+	if sourceURL.Port() == "" {
+		host = net.JoinHostPort(sourceURL.Hostname(), "22")
+	}
+
+	hostKeyCallback, err := getSFTPHostKeyCallback(dataset)
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+
+	username, password, err := getBasicAuthentication(dataset)
+	if err != nil {
+		return false, nil, "", err
+	}
+	sshClient, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         ftpTimeout,
+	})
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+	defer sshClient.Close()
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+	defer client.Close()
+
+	fileInfo, err := client.Stat(sourceURL.Path)
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+
+	newEtag := generateFTPEtag(fileInfo.ModTime(), fileInfo.Size())
+	if etag != "" && newEtag == etag {
+		return false, nil, "", nil
+	}
+
+	remoteFile, err := client.Open(sourceURL.Path)
+	if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+	defer remoteFile.Close()
+
+	tmpFile, err := os.CreateTemp(os.TempDir(), "travigo-data-importer-")
+	if err != nil {
+		return false, nil, "", err
+	}
+
+	if _, err := remoteFile.WriteTo(tmpFile); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
+
+	log.Debug().Str("path", tmpFile.Name()).Msg("Data file downloaded")
+
+	return true, tmpFile, newEtag, nil
+}
+
+// getSFTPHostKeyCallback verifies the server against either the host key or the known_hosts file configured for the
+// dataset. There's no way of knowing who's on the other end without one of them so the download is refused
+func getSFTPHostKeyCallback(dataset *datasets.DataSet) (ssh.HostKeyCallback, error) {
+	if dataset.CustomConfig["sftphostkey"] != "" {
+		hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(dataset.CustomConfig["sftphostkey"]))
+		if err != nil {
+			return nil, err
+		}
+
+		return ssh.FixedHostKey(hostKey), nil
+	}
+
+	if dataset.CustomConfig["sftpknownhosts"] != "" {
+		return knownhosts.New(dataset.CustomConfig["sftpknownhosts"])
+	}
+
+	return nil, errors.New("SFTP sources need either sftphostkey or sftpknownhosts configured")
+}
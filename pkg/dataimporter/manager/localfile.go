@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats"
+)
+
+// GetLocalFileDataset builds a one-off dataset for importing a local file without it being registered in a datasource
+func GetLocalFileDataset(identifier string, format string, source string, unpackBundle string, supports []string) (datasets.DataSet, error) {
+	dataset := datasets.DataSet{
+		Identifier:        identifier,
+		DataSourceRef:     "local",
+		Format:            datasets.DataSetFormat(format),
+		Source:            source,
+		UnpackBundle:      datasets.BundleFormat(unpackBundle),
+		ImportDestination: datasets.ImportDestinationDatabase,
+		Provider: datasets.Provider{
+			Name: "Local file",
+		},
+	}
+
+	_, knownFormat := datasetFormats[dataset.Format]
+	if _, isPlugin := formats.GetPlugin(dataset.Format); !knownFormat && !isPlugin {
+		return datasets.DataSet{}, errors.New(fmt.Sprintf("Unrecognised format %s", format))
+	}
+
+	if _, err := os.Stat(source); err != nil {
+		return datasets.DataSet{}, err
+	}
+
+	switch dataset.Format {
+	case datasets.DataSetFormatSiriVM, datasets.DataSetFormatSiriSX, datasets.DataSetFormatGTFSRealtime:
+		dataset.ImportDestination = datasets.ImportDestinationRealtimeQueue
+	}
+
+	// Default to everything and let the format pick out what it actually contains
+	if len(supports) == 0 {
+		supports = []string{
+			"operators", "operatorgroups", "stops", "stopgroups", "localities", "administrativeareas",
+			"services", "journeys", "transfers", "schooltermcalendars", "carparks", "raillocations", "busregistrations", "realtimejourneys", "servicealerts",
+		}
+	}
+
+	for _, object := range supports {
+		switch strings.ToLower(strings.TrimSpace(object)) {
+		case "operators":
+			dataset.SupportedObjects.Operators = true
+		case "operatorgroups":
+			dataset.SupportedObjects.OperatorGroups = true
+		case "stops":
+			dataset.SupportedObjects.Stops = true
+		case "stopgroups":
+			dataset.SupportedObjects.StopGroups = true
+		case "localities":
+			dataset.SupportedObjects.Localities = true
+		case "administrativeareas":
+			dataset.SupportedObjects.AdministrativeAreas = true
+		case "services":
+			dataset.SupportedObjects.Services = true
+		case "journeys":
+			dataset.SupportedObjects.Journeys = true
+		case "transfers":
+			dataset.SupportedObjects.Transfers = true
+		case "schooltermcalendars":
+			dataset.SupportedObjects.SchoolTermCalendars = true
+		case "carparks":
+			dataset.SupportedObjects.CarParks = true
+		case "raillocations":
+			dataset.SupportedObjects.RailLocations = true
+		case "busregistrations":
+			dataset.SupportedObjects.BusRegistrations = true
+		case "realtimejourneys":
+			dataset.SupportedObjects.RealtimeJourneys = true
+		case "servicealerts":
+			dataset.SupportedObjects.ServiceAlerts = true
+		default:
+			return datasets.DataSet{}, errors.New(fmt.Sprintf("Unrecognised supported object %s", object))
+		}
+	}
+
+	return dataset, nil
+}
@@ -14,20 +14,47 @@ import (
 	"os"
 	"time"
 
+	"github.com/adjust/rmq/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/blocks"
+	"github.com/travigo/travigo/pkg/dataimporter/busregistrations"
+	"github.com/travigo/travigo/pkg/dataimporter/customdatasets"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/discovery"
 	"github.com/travigo/travigo/pkg/dataimporter/formats"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/branding"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/cif"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/csvstops"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/darwintimetable"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/gbfs"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/gtfs"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/naptan"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/nationalrailincidents"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/nationalrailtoc"
+	networkrailbplan "github.com/travigo/travigo/pkg/dataimporter/formats/networkrail-bplan"
 	networkrailcorpus "github.com/travigo/travigo/pkg/dataimporter/formats/networkrail-corpus"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/nptg"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/otcbusregistrations"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/schoolterms"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/siri_sx"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/siri_vm"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/tflcarparks"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/transxchange"
 	"github.com/travigo/travigo/pkg/dataimporter/formats/travelinenoc"
+	"github.com/travigo/travigo/pkg/dataimporter/importerrors"
+	"github.com/travigo/travigo/pkg/dataimporter/lookup"
+	"github.com/travigo/travigo/pkg/dataimporter/pathdistances"
+	"github.com/travigo/travigo/pkg/dataimporter/progress"
+	"github.com/travigo/travigo/pkg/dataimporter/raillocations"
+	"github.com/travigo/travigo/pkg/dataimporter/runhistory"
+	"github.com/travigo/travigo/pkg/dataimporter/serviceroutes"
+	"github.com/travigo/travigo/pkg/dataimporter/servicestopsummary"
+	"github.com/travigo/travigo/pkg/dataimporter/stopdepartures"
+	"github.com/travigo/travigo/pkg/dataimporter/stopimportance"
+	"github.com/travigo/travigo/pkg/identifiers"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"github.com/travigo/travigo/pkg/util"
 	"go.mongodb.org/mongo-driver/bson"
@@ -62,48 +89,78 @@ func GetDataset(identifier string) (datasets.DataSet, error) {
 		}
 	}
 
+	// Fall back to datasets found through discovery
+	if database.Instance != nil {
+		if discovered := discovery.GetDiscoveredDataSet(identifier); discovered != nil {
+			for _, dataset := range registered {
+				if dataset.Identifier == discovered.Template {
+					return discovered.ToDataSet(dataset), nil
+				}
+			}
+		}
+
+		// And then to the ones operators have registered themselves
+		if customDataset := customdatasets.GetCustomDataSet(identifier); customDataset != nil {
+			return customDataset.ToDataSet()
+		}
+	}
+
 	return datasets.DataSet{}, errors.New("Dataset could not be found")
 }
 
+// datasetFormats creates the built in format for each dataset format, plugins are looked up separately
+var datasetFormats = map[datasets.DataSetFormat]func() formats.Format{
+	datasets.DataSetFormatTravelineNOC:          func() formats.Format { return &travelinenoc.TravelineData{} },
+	datasets.DataSetFormatNaPTAN:                func() formats.Format { return &naptan.NaPTAN{} },
+	datasets.DataSetFormatNPTG:                  func() formats.Format { return &nptg.NPTG{} },
+	datasets.DataSetFormatNationalRailTOC:       func() formats.Format { return &nationalrailtoc.TrainOperatingCompanyList{} },
+	datasets.DataSetFormatNetworkRailCorpus:     func() formats.Format { return &networkrailcorpus.Corpus{} },
+	datasets.DataSetFormatNetworkRailBPLAN:      func() formats.Format { return &networkrailbplan.BPLAN{} },
+	datasets.DataSetFormatNationalRailIncidents: func() formats.Format { return &nationalrailincidents.Incidents{} },
+	datasets.DataSetFormatSiriVM:                func() formats.Format { return &siri_vm.SiriVM{} },
+	datasets.DataSetFormatSiriSX:                func() formats.Format { return &siri_sx.SiriSX{} },
+	datasets.DataSetFormatGTFSSchedule:          func() formats.Format { return &gtfs.Schedule{} },
+	datasets.DataSetFormatGTFSRealtime:          func() formats.Format { return &gtfs.Realtime{} },
+	datasets.DataSetFormatCIF:                   func() formats.Format { return &cif.CommonInterfaceFormat{} },
+	datasets.DataSetFormatDarwinTimetable:       func() formats.Format { return &darwintimetable.Timetable{} },
+	datasets.DataSetFormatTransXChange:          func() formats.Format { return &transxchange.TransXChange{} },
+	datasets.DataSetFormatBranding:              func() formats.Format { return &branding.Branding{} },
+	datasets.DataSetFormatSchoolTerms:           func() formats.Format { return &schoolterms.SchoolTerms{} },
+	datasets.DataSetFormatGBFS:                  func() formats.Format { return &gbfs.GBFS{} },
+	datasets.DataSetFormatTfLCarParks:           func() formats.Format { return &tflcarparks.CarParks{} },
+	datasets.DataSetFormatTfLCarParkOccupancy:   func() formats.Format { return &tflcarparks.Occupancy{} },
+	datasets.DataSetFormatCSVStops:              func() formats.Format { return &csvstops.CSVStops{} },
+	datasets.DataSetFormatOTCBusRegistrations:   func() formats.Format { return &otcbusregistrations.BusRegistrations{} },
+}
+
 func createDatasetFormat(dataset *datasets.DataSet) (formats.Format, error) {
 	var format formats.Format
 
-	switch dataset.Format {
-	case datasets.DataSetFormatTravelineNOC:
-		format = &travelinenoc.TravelineData{}
-	case datasets.DataSetFormatNaPTAN:
-		format = &naptan.NaPTAN{}
-	case datasets.DataSetFormatNationalRailTOC:
-		format = &nationalrailtoc.TrainOperatingCompanyList{}
-	case datasets.DataSetFormatNetworkRailCorpus:
-		format = &networkrailcorpus.Corpus{}
-	case datasets.DataSetFormatSiriVM:
-		format = &siri_vm.SiriVM{}
-	case datasets.DataSetFormatSiriSX:
-		format = &siri_sx.SiriSX{}
-	case datasets.DataSetFormatGTFSSchedule:
-		format = &gtfs.Schedule{}
-	case datasets.DataSetFormatGTFSRealtime:
-		format = &gtfs.Realtime{}
-	case datasets.DataSetFormatCIF:
-		format = &cif.CommonInterfaceFormat{}
-	case datasets.DataSetFormatTransXChange:
-		format = &transxchange.TransXChange{}
-	default:
-		return nil, errors.New(fmt.Sprintf("Unrecognised format %s", dataset.Format))
+	if newFormat, exists := datasetFormats[dataset.Format]; exists {
+		format = newFormat()
+	} else {
+		pluginFormat, exists := formats.GetPlugin(dataset.Format)
+		if !exists {
+			return nil, errors.New(fmt.Sprintf("Unrecognised format %s", dataset.Format))
+		}
+
+		format = pluginFormat
 	}
 
 	if dataset.ImportDestination == datasets.ImportDestinationRealtimeQueue {
 		if dataset.Queue == nil {
 			realtimeQueue, err := redis_client.QueueConnection.OpenQueue("realtime-queue")
 			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to start redis realtime-queue")
+				return nil, err
 			}
 			dataset.Queue = &realtimeQueue
 		}
 
-		var realtimeQueueFormat formats.RealtimeQueueFormat
-		realtimeQueueFormat = format.(formats.RealtimeQueueFormat)
+		// Plugins only import into the database
+		realtimeQueueFormat, ok := format.(formats.RealtimeQueueFormat)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Format %s cannot import into the realtime queue", dataset.Format))
+		}
 
 		realtimeQueueFormat.SetupRealtimeQueue(*dataset.Queue)
 	}
@@ -111,7 +168,89 @@ func createDatasetFormat(dataset *datasets.DataSet) (formats.Format, error) {
 	return format, nil
 }
 
-func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
+// getFormatLookups lists the lookup tables a format resolves references against on every record
+func getFormatLookups(format datasets.DataSetFormat) []string {
+	switch format {
+	case datasets.DataSetFormatDarwinTimetable:
+		return []string{lookup.TableStops}
+	case datasets.DataSetFormatGTFSSchedule:
+		// Stops are only needed by feeds that reference other datasets stops so get loaded on first use
+		return []string{lookup.TableOperators}
+	default:
+		return nil
+	}
+}
+
+// ImportDataset downloads, parses & imports a dataset. Per-record failures are collected rather than
+// aborting the import, up until the datasets failure threshold, and the outcome is recorded against the dataset
+func ImportDataset(dataset *datasets.DataSet, forceImport bool) (err error) {
+	if dataset.Discovery != "" {
+		return errors.New("Dataset is a discovery template, import the datasets discovered from it instead")
+	}
+
+	if dataset.Sink == nil && dataset.StagedImport {
+		dataset.Sink = datasink.StagingSink{RejectInvalidIdentifiers: dataset.RejectInvalidIdentifiers}
+	} else if dataset.Sink == nil {
+		dataset.Sink = datasink.MongoSink{RejectInvalidIdentifiers: dataset.RejectInvalidIdentifiers}
+	}
+	_, stagedImport := dataset.Sink.(datasink.StagingSink)
+
+	if dataset.Context == nil {
+		dataset.Context = context.Background()
+	}
+
+	dataset.Progress.Start()
+	defer dataset.Progress.Stop()
+
+	// A dry run always goes through the full download & parse so the source definition gets validated
+	dryRun := datasink.IsDryRun(dataset.Sink)
+	if dryRun {
+		forceImport = true
+
+		if statisticsSink, ok := dataset.Sink.(*datasink.StatisticsSink); ok && dataset.ImportDestination == datasets.ImportDestinationRealtimeQueue {
+			var queue rmq.Queue = datasink.StatisticsQueue{Name: "realtime-queue", Sink: statisticsSink}
+			dataset.Queue = &queue
+		}
+	}
+
+	// Forced imports are an explicit request so go ahead even if the dataset is paused
+	if !forceImport {
+		if active, reason := IsDataSetActive(dataset, GetDataSetOverride(dataset.Identifier), time.Now()); !active {
+			log.Info().Str("dataset", dataset.Identifier).Str("reason", reason).Msg("Dataset is not active, skipping import")
+			return nil
+		}
+	}
+
+	// Each run starts counting failures afresh as repeating imports reuse the same dataset
+	dataset.Errors = importerrors.NewCollector(dataset.FailureThreshold)
+
+	// Unchanged sources aren't recorded so the result of the last real import is kept
+	var unchanged bool
+	var anomalies []string
+	startTime := time.Now()
+	defer func() {
+		if dryRun || unchanged {
+			return
+		}
+
+		result := dataset.Errors.Result()
+		result.Dataset = dataset.Identifier
+		result.StartTime = startTime
+		result.EndTime = time.Now()
+		result.Succeeded = err == nil
+		result.Anomalies = anomalies
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		if recordErr := result.Record(); recordErr != nil {
+			log.Error().Err(recordErr).Str("dataset", dataset.Identifier).Msg("Failed to record import result")
+		}
+		if result.Failures > 0 {
+			log.Warn().Str("dataset", dataset.Identifier).Int("failures", result.Failures).Interface("types", result.FailuresByType).Msg("Import had failed records")
+		}
+	}()
+
 	datasetVersionCollection := database.GetCollection("dataset_versions")
 
 	var existingDatasetVersion *ctdf.DatasetVersion
@@ -131,20 +270,51 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 		Timestamp:      fmt.Sprintf("%d", time.Now().Unix()),
 	}
 
-	source := dataset.Source
+	source, err := getSourceLocation(dataset)
+	if err != nil {
+		return err
+	}
 	var etag string
+	var downloaded bool
 
-	if isValidUrl(dataset.Source) {
+	if dataset.FromRun != "" {
+		tempFile, err := tempArchivedFile(dataset)
+		if err != nil {
+			return err
+		}
+
+		// Always reprocess an archived run, it was explicitly asked for
+		forceImport = true
+
+		source = tempFile.Name()
+		defer os.Remove(tempFile.Name())
+	} else if isValidUrl(source) {
 		var tempFile *os.File
 		var hasChanged bool
-		hasChanged, tempFile, etag = tempDownloadFile(dataset, existingEtag)
+		var err error
+		hasChanged, tempFile, etag, err = tempDownloadFile(dataset, existingEtag)
+		if err != nil {
+			if tempFile != nil {
+				os.Remove(tempFile.Name())
+			}
+			return err
+		}
+
+		if err := dataset.Context.Err(); err != nil {
+			if tempFile != nil {
+				os.Remove(tempFile.Name())
+			}
+			return err
+		}
 
 		if !hasChanged {
 			log.Info().Str("dataset", dataset.Identifier).Msg("File ETag is not new, skipping processing")
+			unchanged = true
 			return nil
 		}
 
 		source = tempFile.Name()
+		downloaded = true
 		defer os.Remove(tempFile.Name())
 	}
 
@@ -161,9 +331,16 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 	// Check if the file hasn't changed
 	if existingDatasetVersion != nil && existingDatasetVersion.Hash == sourceFileHash && !forceImport {
 		log.Info().Str("dataset", dataset.Identifier).Msg("File hash is not new, skipping processing")
+		unchanged = true
 		return nil
 	}
 
+	// Keep a copy of the raw download so this run can be reproduced even if the upstream feed changes or disappears.
+	// Realtime feeds are skipped as they're replaced every few minutes.
+	if downloaded && !dryRun && dataset.ImportDestination != datasets.ImportDestinationRealtimeQueue {
+		archiveDownload(dataset, datasource, source, sourceFileHash)
+	}
+
 	// Parse the file
 	sourceFileReaders := []io.Reader{}
 
@@ -172,13 +349,19 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 		return err
 	}
 
+	var fileSize int64
+	if fileInfo, err := file.Stat(); err == nil {
+		fileSize = fileInfo.Size()
+	}
+
 	switch dataset.UnpackBundle {
 	case datasets.BundleFormatNone, "":
-		sourceFileReaders = append(sourceFileReaders, file)
+		sourceFileReaders = append(sourceFileReaders, dataset.Progress.WrapReader(file, fileSize))
 	case datasets.BundleFormatGZ:
-		gzipDecoder, err := gzip.NewReader(file)
+		// Count the compressed bytes as the uncompressed size isn't known up front
+		gzipDecoder, err := gzip.NewReader(dataset.Progress.WrapReader(file, fileSize))
 		if err != nil {
-			log.Fatal().Err(err).Msg("cannot decode gzip stream")
+			return err
 		}
 		defer gzipDecoder.Close()
 
@@ -193,11 +376,11 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 		for i, zipFile := range archive.File {
 			zipFileOpen, err := zipFile.Open()
 			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to open file")
+				return err
 			}
 			defer zipFileOpen.Close()
 
-			sourceFileReaders = append(sourceFileReaders, zipFileOpen)
+			sourceFileReaders = append(sourceFileReaders, dataset.Progress.WrapReader(zipFileOpen, int64(zipFile.UncompressedSize64)))
 
 			log.Debug().Int("index", i).Str("path", zipFile.Name).Msg("Storing zip file")
 		}
@@ -205,46 +388,191 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 		return errors.New(fmt.Sprintf("Cannot handle bundle format %s", dataset.UnpackBundle))
 	}
 
+	if stagedImport {
+		emptyStaging(dataset)
+	}
+
+	// Build the in memory reference tables once for the whole import rather than formats querying per record
+	dataset.Lookups = lookup.NewTables()
+	dataset.Lookups.Preload(getFormatLookups(dataset.Format)...)
+
 	for i, sourceFileReader := range sourceFileReaders {
+		if err := dataset.Context.Err(); err != nil {
+			return err
+		}
+
 		format, err := createDatasetFormat(dataset)
 		if err != nil {
 			return err
 		}
 
+		if progressFormat, ok := format.(formats.ProgressFormat); ok {
+			progressFormat.SetupProgress(dataset.Progress)
+		}
+		if errorCollectingFormat, ok := format.(formats.ErrorCollectingFormat); ok {
+			errorCollectingFormat.SetupErrors(dataset.Errors)
+		}
+
+		// Large files are imported as they're read so the whole file never has to be held in memory
+		if streamingFormat, ok := format.(formats.StreamingFormat); ok {
+			dataset.Progress.SetStage(progress.StageImporting)
+			err = streamingFormat.ImportStream(sourceFileReader, *dataset, datasource)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		// Actually import it
+		dataset.Progress.SetStage(progress.StageParsing)
 		err = format.ParseFile(sourceFileReader)
 		if err != nil {
 			return err
 		}
 
 		log.Debug().Int("index", i).Msg("Opening zipped file")
+		dataset.Progress.SetStage(progress.StageImporting)
 		err = format.Import(*dataset, datasource)
 		if err != nil {
 			return err
 		}
 	}
 
-	if dataset.SupportedObjects.Stops {
-		cleanupOldRecords("stops_raw", datasource)
-	}
-	if dataset.SupportedObjects.StopGroups {
-		cleanupOldRecords("stop_groups", datasource)
+	// Bail out before anything gets promoted or cleaned up so a cancelled import leaves the live data alone
+	if err := dataset.Context.Err(); err != nil {
+		return err
 	}
-	if dataset.SupportedObjects.Operators {
-		cleanupOldRecords("operators", datasource)
+
+	// Imports limited to some operators only ever cover part of the dataset so they're never checked or recorded as a run
+	partialImport := len(dataset.OnlyOperators) > 0
+
+	// Compare against recent runs before anything gets promoted or cleaned up so a truncated source can't wipe out the timetable
+	if !dryRun && !partialImport && dataset.ImportDestination != datasets.ImportDestinationRealtimeQueue {
+		anomalies, err = checkImportAnomalies(dataset, datasource, stagedImport)
+		if err != nil {
+			return err
+		}
 	}
-	if dataset.SupportedObjects.OperatorGroups {
-		cleanupOldRecords("operator_groups", datasource)
+
+	if stagedImport {
+		err = validateStaging(dataset)
+		if err != nil {
+			return err
+		}
+
+		err = promoteStaging(dataset, datasource)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, collectionName := range getSupportedCollections(dataset) {
+			cleanupOldRecords(dataset.Sink, collectionName, datasource, dataset.OnlyOperators)
+		}
 	}
-	if dataset.SupportedObjects.Services {
-		cleanupOldRecords("services", datasource)
+
+	// Writes held back by the sink only go out now so the new records & the removal of the old ones land together
+	if transactionSink, ok := dataset.Sink.(*datasink.TransactionSink); ok {
+		err = transactionSink.Commit()
+		if err != nil {
+			return err
+		}
 	}
+
 	if dataset.SupportedObjects.Journeys {
-		cleanupOldRecords("journeys", datasource)
+		if !dryRun {
+			// Refresh the first/last & frequency summaries now the journeys are up to date
+			err = servicestopsummary.Generate(datasource, dataset.OnlyOperators)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to generate service stop summaries")
+			}
+			cleanupOldRecords(datasink.MongoSink{}, "service_stop_summaries", datasource, dataset.OnlyOperators)
+
+			// Group the journeys of each service into the route variants it runs
+			err = serviceroutes.Generate(datasource, dataset.OnlyOperators)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to generate service routes")
+			}
+
+			// Fill in the leg distances the source left out & flag legs with speeds no vehicle could manage
+			err = pathdistances.Generate(datasource, dataset.OnlyOperators)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to generate journey path distances")
+			}
+
+			// Stop departures are updated incrementally so unchanged journeys keep their existing records
+			err = stopdepartures.Generate(datasource, dataset.OnlyOperators)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to materialise stop departures")
+			}
+
+			// Link up the journeys operated consecutively by the same vehicle
+			err = blocks.Generate(datasource, dataset.OnlyOperators)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to generate blocks")
+			}
+			cleanupOldRecords(datasink.MongoSink{}, "blocks", datasource, dataset.OnlyOperators)
+
+			// Service counts at stops come from the summaries so the importance can only be worked out after them
+			err = stopimportance.Refresh()
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to refresh stop importance")
+			}
+
+			// Keep a snapshot of this runs journeys so it can be diffed against other runs
+			if !partialImport {
+				err = runhistory.RecordRun(datasource)
+				if err != nil {
+					log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to record dataset run")
+				} else if err := publishTimetableChanges(datasource); err != nil {
+					log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to publish timetable changes")
+				}
+			}
+		}
+	}
+
+	// New codes & positions can change which station a rail location belongs to
+	if dataset.SupportedObjects.RailLocations && !dryRun {
+		err = raillocations.Link()
+		if err != nil {
+			log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to link rail locations")
+		}
 	}
 
-	// Update dataset version
-	if dataset.ImportDestination != datasets.ImportDestinationRealtimeQueue {
+	// Registrations are linked by the service codes & route numbers so have to follow changes to either side.
+	// New registrations could match any service but new services only need their own registrations relinking
+	if !dryRun && dataset.SupportedObjects.BusRegistrations {
+		err = busregistrations.Link()
+		if err != nil {
+			log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to link bus registrations")
+		}
+	} else if !dryRun && dataset.Format == datasets.DataSetFormatTransXChange && dataset.SupportedObjects.Services {
+		err = busregistrations.LinkDataset(dataset.Identifier)
+		if err != nil {
+			log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to link bus registrations")
+		}
+	}
+
+	if !dryRun {
+		if dataset.SupportedObjects.Operators {
+			if err := identifiers.RebuildTranslations(identifiers.ObjectTypeOperator); err != nil {
+				log.Error().Err(err).Msg("Failed to rebuild operator identifier translations")
+			}
+		}
+		if dataset.SupportedObjects.StopGroups {
+			if err := identifiers.RebuildTranslations(identifiers.ObjectTypeStopGroup); err != nil {
+				log.Error().Err(err).Msg("Failed to rebuild stop group identifier translations")
+			}
+		}
+		if dataset.SupportedObjects.Services {
+			if err := identifiers.RebuildTranslations(identifiers.ObjectTypeService); err != nil {
+				log.Error().Err(err).Msg("Failed to rebuild service identifier translations")
+			}
+		}
+	}
+
+	// Update dataset version, a partial import doesn't bring the rest of the dataset up to date with the source
+	if dataset.ImportDestination != datasets.ImportDestinationRealtimeQueue && !dryRun && !partialImport {
 		datasetVersion := ctdf.DatasetVersion{
 			Dataset:      dataset.Identifier,
 			Hash:         sourceFileHash,
@@ -252,6 +580,13 @@ func ImportDataset(dataset *datasets.DataSet, forceImport bool) error {
 			LastModified: time.Now(),
 		}
 
+		if dataset.SupportedObjects.Journeys {
+			datasetVersion.ValidFrom, datasetVersion.ValidUntil, err = getDatasetValidity(dataset.Identifier)
+			if err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Failed to work out dataset validity")
+			}
+		}
+
 		opts := options.Update().SetUpsert(true)
 		_, err = datasetVersionCollection.UpdateOne(context.Background(), bson.M{"dataset": datasetVersion.Dataset}, bson.M{"$set": datasetVersion}, opts)
 	}
@@ -273,8 +608,34 @@ func isValidUrl(toTest string) bool {
 	return true
 }
 
-func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, string) {
-	req, _ := http.NewRequest("GET", dataset.Source, nil)
+// getSourceLocation resolves the datasets source, reading it from the environment when the link itself is the credential
+func getSourceLocation(dataset *datasets.DataSet) (string, error) {
+	if dataset.SourceAuthentication.URL == "" {
+		return dataset.Source, nil
+	}
+
+	env := util.GetEnvironmentVariables()
+	if env[dataset.SourceAuthentication.URL] == "" {
+		return "", errors.New(fmt.Sprintf("%s must be set", dataset.SourceAuthentication.URL))
+	}
+
+	return env[dataset.SourceAuthentication.URL], nil
+}
+
+func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, string, error) {
+	source, err := getSourceLocation(dataset)
+	if err != nil {
+		return false, nil, "", err
+	}
+	sourceURL, _ := url.Parse(source)
+	switch sourceURL.Scheme {
+	case "ftp":
+		return tempDownloadFTPFile(dataset, sourceURL, etag)
+	case "sftp":
+		return tempDownloadSFTPFile(dataset, sourceURL, etag)
+	}
+
+	req, _ := http.NewRequestWithContext(dataset.Context, "GET", source, nil)
 	req.Header.Set("user-agent", "curl/7.54.1") // TfL is protected by cloudflare and it gets angry when no user agent is set
 
 	if etag != "" {
@@ -286,7 +647,7 @@ func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, s
 	// Query paramaters
 	for queryKey, queryValue := range dataset.SourceAuthentication.Query {
 		if env[queryValue] == "" {
-			log.Fatal().Msgf("%s must be set", queryValue)
+			return false, nil, "", errors.New(fmt.Sprintf("%s must be set", queryValue))
 		}
 
 		q := req.URL.Query()
@@ -296,10 +657,10 @@ func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, s
 	// Basic auth
 	if dataset.SourceAuthentication.Basic.Username != "" && dataset.SourceAuthentication.Basic.Password != "" {
 		if env[dataset.SourceAuthentication.Basic.Username] == "" {
-			log.Fatal().Msgf("%s must be set", dataset.SourceAuthentication.Basic.Username)
+			return false, nil, "", errors.New(fmt.Sprintf("%s must be set", dataset.SourceAuthentication.Basic.Username))
 		}
 		if env[dataset.SourceAuthentication.Basic.Password] == "" {
-			log.Fatal().Msgf("%s must be set", dataset.SourceAuthentication.Basic.Password)
+			return false, nil, "", errors.New(fmt.Sprintf("%s must be set", dataset.SourceAuthentication.Basic.Password))
 		}
 
 		req.SetBasicAuth(env[dataset.SourceAuthentication.Basic.Username], env[dataset.SourceAuthentication.Basic.Password])
@@ -307,7 +668,7 @@ func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, s
 	// Headers
 	for headerKey, headerValue := range dataset.SourceAuthentication.Header {
 		if env[headerValue] == "" {
-			log.Fatal().Msgf("%s must be set", headerValue)
+			return false, nil, "", errors.New(fmt.Sprintf("%s must be set", headerValue))
 		}
 
 		req.Header.Set(headerKey, env[headerValue])
@@ -315,7 +676,10 @@ func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, s
 	// Customs
 	switch dataset.SourceAuthentication.Custom {
 	case "gb-nationalrail-login":
-		token := customAuthNationalRailLogin()
+		token, err := customAuthNationalRailLogin()
+		if err != nil {
+			return false, nil, "", err
+		}
 		req.Header.Set("X-Auth-Token", token)
 	}
 
@@ -327,46 +691,78 @@ func tempDownloadFile(dataset *datasets.DataSet, etag string) (bool, *os.File, s
 	client := &http.Client{}
 	resp, err := client.Do(req)
 
-	if err != nil {
-		log.Fatal().Err(err).Msg("Download file")
+	if err != nil && dataset.Context.Err() != nil {
+		return false, nil, "", nil
+	} else if err != nil {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotModified {
-		return false, nil, ""
+		return false, nil, "", nil
+	}
+	if resp.StatusCode >= 400 {
+		return false, nil, "", &importerrors.DownloadError{Source: dataset.Source, Err: errors.New(resp.Status)}
 	}
 
 	tmpFile, err := os.CreateTemp(os.TempDir(), "travigo-data-importer-")
 	if err != nil {
-		log.Fatal().Err(err).Msg("Cannot create temporary file")
+		return false, nil, "", err
 	}
 
 	log.Debug().Str("path", tmpFile.Name()).Msg("Data file downloaded")
 
-	io.Copy(tmpFile, resp.Body)
+	_, err = io.Copy(tmpFile, resp.Body)
+	if err != nil && dataset.Context.Err() != nil {
+		return false, tmpFile, "", nil
+	} else if err != nil {
+		return false, tmpFile, "", &importerrors.DownloadError{Source: dataset.Source, Err: err}
+	}
 
-	return true, tmpFile, resp.Header.Get("Etag")
+	return true, tmpFile, resp.Header.Get("Etag"), nil
 }
 
-func cleanupOldRecords(collectionName string, datasource *ctdf.DataSourceReference) {
+// cleanupOldRecords removes the records of the dataset that weren't written by this import,
+// only touching the records belonging to onlyOperators when it's set
+func cleanupOldRecords(sink datasink.Sink, collectionName string, datasource *ctdf.DataSourceReference, onlyOperators []string) {
 	collection := database.GetCollection(collectionName)
 
+	conditions := bson.A{
+		bson.M{"datasource.originalformat": datasource.OriginalFormat},
+		bson.M{"datasource.datasetid": datasource.DatasetID},
+		bson.M{"datasource.timestamp": bson.M{
+			"$ne": datasource.Timestamp,
+		}},
+	}
+	if len(onlyOperators) > 0 {
+		switch collectionName {
+		case "services", "journeys", "blocks":
+			conditions = append(conditions, bson.M{"operatorref": bson.M{"$in": onlyOperators}})
+		case "service_stop_summaries":
+			// Summaries don't record the operator so go through the operators services instead
+			serviceRefs, err := database.GetCollection("services").Distinct(context.Background(), "primaryidentifier", bson.M{
+				"datasource.datasetid": datasource.DatasetID,
+				"operatorref":          bson.M{"$in": onlyOperators},
+			})
+			if err != nil {
+				log.Error().Err(err).Str("collection", collectionName).Msg("Failed to find operator services to clean up")
+				return
+			}
+
+			conditions = append(conditions, bson.M{"serviceref": bson.M{"$in": serviceRefs}})
+		}
+	}
+
 	query := bson.M{
-		"$and": bson.A{
-			bson.M{"datasource.originalformat": datasource.OriginalFormat},
-			bson.M{"datasource.datasetid": datasource.DatasetID},
-			bson.M{"datasource.timestamp": bson.M{
-				"$ne": datasource.Timestamp,
-			}},
-		},
+		"$and": conditions,
 	}
 
-	result, _ := collection.DeleteMany(context.Background(), query)
+	deletedCount, err := sink.DeleteMany(collection, query)
 
-	if result != nil {
+	if err == nil {
 		log.Info().
 			Str("collection", collectionName).
-			Int64("num", result.DeletedCount).
+			Int64("num", deletedCount).
 			Msg("Cleaned up old records")
 	}
 }
@@ -0,0 +1,91 @@
+package manager_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/testsupport"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestImportRemovesStaleRecords(t *testing.T) {
+	testsupport.Start(t)
+
+	staleStop := &ctdf.Stop{
+		PrimaryIdentifier: "fixture-gtfs-schedule-stop-REMOVED",
+		DataSource: &ctdf.DataSourceReference{
+			OriginalFormat: "gtfs-schedule",
+			DatasetID:      "fixture-gtfs-schedule",
+			Timestamp:      "1",
+		},
+		CreationDateTime:     time.Now(),
+		ModificationDateTime: time.Now(),
+	}
+	if _, err := database.GetCollection("stops_raw").InsertOne(context.Background(), staleStop); err != nil {
+		t.Fatalf("Failed to insert stale stop: %s", err)
+	}
+
+	testsupport.LoadFixture(t, "gtfs-schedule")
+
+	testsupport.AssertNotExists(t, "stops_raw", staleStop.PrimaryIdentifier)
+	testsupport.AssertExists(t, "stops_raw", "fixture-gtfs-schedule-stop-FIXS1")
+}
+
+func TestReimportDoesNotDuplicateRecords(t *testing.T) {
+	testsupport.Start(t)
+
+	testsupport.LoadFixture(t, "gtfs-schedule")
+	testsupport.LoadFixture(t, "gtfs-schedule")
+
+	datasetFilter := bson.M{"datasource.datasetid": "fixture-gtfs-schedule"}
+	testsupport.AssertCount(t, "journeys", datasetFilter, 2)
+	testsupport.AssertCount(t, "services", datasetFilter, 1)
+	testsupport.AssertCount(t, "stops_raw", datasetFilter, 3)
+}
+
+func TestStagedImportPromotesRecords(t *testing.T) {
+	testsupport.Start(t)
+
+	staleStop := &ctdf.Stop{
+		PrimaryIdentifier: "fixture-gtfs-schedule-stop-REMOVED",
+		DataSource: &ctdf.DataSourceReference{
+			OriginalFormat: "gtfs-schedule",
+			DatasetID:      "fixture-gtfs-schedule",
+			Timestamp:      "1",
+		},
+		CreationDateTime:     time.Now(),
+		ModificationDateTime: time.Now(),
+	}
+	if _, err := database.GetCollection("stops_raw").InsertOne(context.Background(), staleStop); err != nil {
+		t.Fatalf("Failed to insert stale stop: %s", err)
+	}
+
+	fixture, err := testsupport.GetFixture("gtfs-schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Imported twice so the second promotion replaces records that are already live
+	for i := 0; i < 2; i++ {
+		dataset, err := fixture.Dataset(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create fixture dataset: %s", err)
+		}
+		dataset.StagedImport = true
+
+		if err := manager.ImportDataset(&dataset, true); err != nil {
+			t.Fatalf("Failed to import fixture: %s", err)
+		}
+	}
+
+	datasetFilter := bson.M{"datasource.datasetid": "fixture-gtfs-schedule"}
+	testsupport.AssertNotExists(t, "stops_raw", staleStop.PrimaryIdentifier)
+	testsupport.AssertCount(t, "journeys", datasetFilter, 2)
+	testsupport.AssertCount(t, "services", datasetFilter, 1)
+	testsupport.AssertCount(t, "stops_raw", datasetFilter, 3)
+	testsupport.AssertCount(t, "stops_raw_staging", datasetFilter, 0)
+}
@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DataSetOverride lets a dataset be paused or rescheduled at runtime without changing its datasource definition
+type DataSetOverride struct {
+	Dataset string
+
+	Enabled  *bool
+	Schedule *datasets.Schedule
+
+	Reason               string
+	ModificationDateTime time.Time
+}
+
+func GetDataSetOverride(identifier string) *DataSetOverride {
+	collection := database.GetCollection("dataset_overrides")
+
+	var override *DataSetOverride
+	collection.FindOne(context.Background(), bson.M{"dataset": identifier}).Decode(&override)
+
+	return override
+}
+
+func SetDataSetOverride(override *DataSetOverride) error {
+	collection := database.GetCollection("dataset_overrides")
+
+	override.ModificationDateTime = time.Now()
+
+	opts := options.Update().SetUpsert(true)
+	_, err := collection.UpdateOne(context.Background(), bson.M{"dataset": override.Dataset}, bson.M{"$set": override}, opts)
+
+	return err
+}
+
+func DeleteDataSetOverride(identifier string) error {
+	collection := database.GetCollection("dataset_overrides")
+
+	_, err := collection.DeleteOne(context.Background(), bson.M{"dataset": identifier})
+
+	return err
+}
+
+// IsDataSetActive works out if a dataset should be imported right now from its definition & any runtime override
+func IsDataSetActive(dataset *datasets.DataSet, override *DataSetOverride, now time.Time) (bool, string) {
+	enabled := dataset.Enabled == nil || *dataset.Enabled
+	schedule := dataset.Schedule
+	reason := "Disabled in datasource"
+
+	if override != nil {
+		if override.Enabled != nil {
+			enabled = *override.Enabled
+			reason = override.Reason
+		}
+		if override.Schedule != nil {
+			schedule = override.Schedule
+		}
+	}
+
+	if !enabled {
+		return false, reason
+	}
+	if !schedule.IsActive(now) {
+		return false, "Outside of schedule"
+	}
+
+	return true, ""
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/countries"
 	"github.com/travigo/travigo/pkg/dataimporter/datasets"
 	"gopkg.in/yaml.v3"
 )
@@ -66,6 +67,17 @@ func GetRegisteredDataSets() []datasets.DataSet {
 			dataset.DataSourceRef = datasource.Identifier
 			dataset.Provider = datasource.Provider
 
+			if dataset.Country == "" {
+				dataset.Country = datasource.Region
+			}
+			if profile, exists := countries.Get(dataset.Country); exists && !profile.HasIdentifierPrefix(dataset.Identifier) {
+				log.Warn().Str("dataset", dataset.Identifier).Str("country", dataset.Country).Msg("Dataset identifier doesn't start with the prefix of its country")
+			}
+			if err := dataset.FormatOptions.Validate(); err != nil {
+				log.Error().Err(err).Str("dataset", dataset.Identifier).Msg("Dataset has invalid format options, skipping it")
+				continue
+			}
+
 			registeredDatasets = append(registeredDatasets, dataset)
 		}
 	}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/discovery"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetOperatorRefs returns every reference services & journeys may use for the operator with the NOC
+func GetOperatorRefs(noc string) []string {
+	operatorRef := fmt.Sprintf(ctdf.OperatorNOCFormat, noc)
+	operatorRefs := []string{operatorRef}
+
+	var operator *ctdf.Operator
+	database.GetCollection("operators").FindOne(context.Background(), bson.M{"primaryidentifier": operatorRef}).Decode(&operator)
+	if operator != nil {
+		for _, otherIdentifier := range operator.OtherIdentifiers {
+			if otherIdentifier != operatorRef {
+				operatorRefs = append(operatorRefs, otherIdentifier)
+			}
+		}
+	}
+
+	return operatorRefs
+}
+
+// GetOperatorDatasets finds the datasets that supply the services & journeys of an operator.
+// Feeds that publish per operator through discovery (like BODS) come back as just that operators datasets,
+// so only the relevant files have to be downloaded.
+func GetOperatorDatasets(operatorRefs []string) ([]datasets.DataSet, error) {
+	datasetIdentifiers := map[string]bool{}
+
+	for _, collectionName := range []string{"services", "journeys"} {
+		supplying, err := database.GetCollection(collectionName).Distinct(context.Background(), "datasource.datasetid", bson.M{
+			"operatorref": bson.M{"$in": operatorRefs},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, identifier := range supplying {
+			if identifier, ok := identifier.(string); ok {
+				datasetIdentifiers[identifier] = true
+			}
+		}
+	}
+
+	// Discovered datasets may not have been imported yet
+	discovered, err := discovery.GetDiscoveredDataSetsForOperator(operatorRefs)
+	if err != nil {
+		return nil, err
+	}
+	for _, discoveredDataset := range discovered {
+		datasetIdentifiers[discoveredDataset.Identifier] = true
+	}
+
+	var identifiers []string
+	for identifier := range datasetIdentifiers {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	var operatorDatasets []datasets.DataSet
+	for _, identifier := range identifiers {
+		dataset, err := GetDataset(identifier)
+		if err != nil {
+			log.Warn().Str("dataset", identifier).Msg("Operator records come from a dataset that is no longer registered")
+			continue
+		}
+
+		if dataset.ImportDestination == datasets.ImportDestinationRealtimeQueue {
+			continue
+		}
+		if !dataset.SupportedObjects.Services && !dataset.SupportedObjects.Journeys {
+			continue
+		}
+
+		operatorDatasets = append(operatorDatasets, dataset)
+	}
+
+	return operatorDatasets, nil
+}
+
+// ReimportOperator imports just the services & journeys of the operators from a dataset.
+// The operators new records & the removal of their old ones are written in one transaction once the import
+// has finished, the rest of the dataset is left untouched.
+func ReimportOperator(dataset *datasets.DataSet, operatorRefs []string) error {
+	dataset.OnlyOperators = operatorRefs
+	dataset.StagedImport = false
+	if dataset.Sink == nil {
+		dataset.Sink = datasink.NewTransactionSink(dataset.RejectInvalidIdentifiers)
+	}
+	dataset.SupportedObjects = datasets.SupportedObjects{
+		Services: dataset.SupportedObjects.Services,
+		Journeys: dataset.SupportedObjects.Journeys,
+	}
+
+	return ImportDataset(dataset, true)
+}
@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A staged import with fewer records than this fraction of what's currently live is treated as broken
+const stagingMinimumRecordRatio = 0.5
+
+const stagingPromotionBatchSize = 1000
+
+func getSupportedCollections(dataset *datasets.DataSet) []string {
+	var collections []string
+
+	if dataset.SupportedObjects.Stops {
+		collections = append(collections, "stops_raw")
+	}
+	if dataset.SupportedObjects.StopGroups {
+		collections = append(collections, "stop_groups")
+	}
+	if dataset.SupportedObjects.Localities {
+		collections = append(collections, "localities")
+	}
+	if dataset.SupportedObjects.AdministrativeAreas {
+		collections = append(collections, "administrative_areas")
+	}
+	if dataset.SupportedObjects.Operators {
+		collections = append(collections, "operators")
+	}
+	if dataset.SupportedObjects.OperatorGroups {
+		collections = append(collections, "operator_groups")
+	}
+	if dataset.SupportedObjects.Services {
+		collections = append(collections, "services")
+	}
+	if dataset.SupportedObjects.Journeys {
+		collections = append(collections, "journeys")
+	}
+	if dataset.SupportedObjects.Transfers {
+		collections = append(collections, "transfers")
+	}
+	if dataset.SupportedObjects.SchoolTermCalendars {
+		collections = append(collections, "school_term_calendars")
+	}
+	if dataset.SupportedObjects.CarParks {
+		collections = append(collections, "car_parks")
+	}
+	if dataset.SupportedObjects.RailLocations {
+		collections = append(collections, "rail_locations")
+	}
+	if dataset.SupportedObjects.BusRegistrations {
+		collections = append(collections, "bus_registrations")
+	}
+
+	return collections
+}
+
+// getDatasetFilter matches the records in a collection that belong to the dataset, narrowed to its operators
+// services & journeys when the import is limited to some operators
+func getDatasetFilter(dataset *datasets.DataSet, collectionName string) bson.M {
+	filter := bson.M{"datasource.datasetid": dataset.Identifier}
+
+	if len(dataset.OnlyOperators) > 0 && (collectionName == "services" || collectionName == "journeys") {
+		filter["operatorref"] = bson.M{"$in": dataset.OnlyOperators}
+	}
+
+	return filter
+}
+
+// emptyStaging removes anything left over in staging from a previous failed import of this dataset
+func emptyStaging(dataset *datasets.DataSet) {
+	for _, collectionName := range getSupportedCollections(dataset) {
+		datasink.GetStagingCollection(collectionName).DeleteMany(context.Background(), getDatasetFilter(dataset, collectionName))
+	}
+}
+
+func validateStaging(dataset *datasets.DataSet) error {
+	for _, collectionName := range getSupportedCollections(dataset) {
+		filter := getDatasetFilter(dataset, collectionName)
+
+		stagingCount, err := datasink.GetStagingCollection(collectionName).CountDocuments(context.Background(), filter)
+		if err != nil {
+			return err
+		}
+		liveCount, err := database.GetCollection(collectionName).CountDocuments(context.Background(), filter)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("collection", collectionName).
+			Int64("staging", stagingCount).
+			Int64("live", liveCount).
+			Msg("Validating staged records")
+
+		// Datasets can legitimately have nothing for some of their collections, it's only a problem if they used to
+		if float64(stagingCount) < float64(liveCount)*stagingMinimumRecordRatio {
+			return errors.New(fmt.Sprintf("Staging collection %s has %d records compared to %d live", collectionName, stagingCount, liveCount))
+		}
+	}
+
+	return nil
+}
+
+// promoteStaging swaps the datasets staged records into the live collections. Collections are promoted one batch at a
+// time rather than in a single transaction, which would grow too large for the big datasets, so each record is
+// replaced in place and readers see either its old or new version but never a gap. The records left over from the
+// previous import are only cleaned up once every collection is promoted so services never reference missing journeys.
+// Records of other datasets are never touched.
+func promoteStaging(dataset *datasets.DataSet, datasource *ctdf.DataSourceReference) error {
+	for _, collectionName := range getSupportedCollections(dataset) {
+		if err := promoteStagingCollection(dataset, collectionName); err != nil {
+			return err
+		}
+	}
+
+	for _, collectionName := range getSupportedCollections(dataset) {
+		cleanupOldRecords(datasink.MongoSink{}, collectionName, datasource, dataset.OnlyOperators)
+	}
+
+	emptyStaging(dataset)
+
+	return nil
+}
+
+func promoteStagingCollection(dataset *datasets.DataSet, collectionName string) error {
+	log.Info().Str("collection", collectionName).Msg("Promoting staged records")
+
+	filter := getDatasetFilter(dataset, collectionName)
+	liveCollection := database.GetCollection(collectionName)
+
+	cursor, err := datasink.GetStagingCollection(collectionName).Find(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var operations []mongo.WriteModel
+	for cursor.Next(context.Background()) {
+		var document bson.D
+		if err := cursor.Decode(&document); err != nil {
+			return err
+		}
+
+		// The staged record has its own _id which can't replace the one of the live record
+		var replacement bson.D
+		var primaryIdentifier interface{}
+		for _, element := range document {
+			switch element.Key {
+			case "_id":
+				continue
+			case "primaryidentifier":
+				primaryIdentifier = element.Value
+			}
+			replacement = append(replacement, element)
+		}
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": primaryIdentifier, "datasource.datasetid": dataset.Identifier}).
+			SetReplacement(replacement).
+			SetUpsert(true))
+
+		if len(operations) >= stagingPromotionBatchSize {
+			if _, err := liveCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(operations) > 0 {
+		if _, err := liveCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/runhistory"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// publishTimetableChanges diffs the run against the one before it and raises a TimetableChanged event for every
+// service that had journeys retimed or cancelled
+func publishTimetableChanges(datasource *ctdf.DataSourceReference) error {
+	// A full diff of a large dataset isn't worth doing if nobody would be told about it
+	subscriptions, err := database.GetCollection("user_event_subscription").CountDocuments(context.Background(), bson.M{
+		"eventtype": ctdf.EventTypeTimetableChanged,
+	})
+	if err != nil || subscriptions == 0 {
+		return err
+	}
+
+	previousRun, err := runhistory.GetPreviousRun(datasource.DatasetID, datasource.Timestamp)
+	if err != nil || previousRun == nil {
+		return err
+	}
+
+	report, err := runhistory.Diff(datasource.DatasetID, previousRun.Run, datasource.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	changes := report.TimetableChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	eventQueue, err := redis_client.QueueConnection.OpenQueue("events-queue")
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, ctdf.Event{
+			Type:      ctdf.EventTypeTimetableChanged,
+			Timestamp: time.Now(),
+			Body:      change,
+		})
+		if err := eventQueue.PublishBytes(eventBytes); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("dataset", datasource.DatasetID).Int("services", len(changes)).Msg("Published timetable changes")
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// getDatasetValidity works out the window a datasets timetable is valid for from the windows of its journeys.
+// If any journey is open ended then so is the dataset
+func getDatasetValidity(datasetID string) (time.Time, time.Time, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	cursor, err := journeysCollection.Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"datasource.datasetid": datasetID}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"validfrom":  bson.M{"$min": "$validfrom"},
+			"validuntil": bson.M{"$max": "$validuntil"},
+			"openstart":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$validfrom", false}}, 0, 1}}},
+			"openend":    bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$validuntil", false}}, 0, 1}}},
+		}}},
+	})
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer cursor.Close(context.Background())
+
+	var validFrom time.Time
+	var validUntil time.Time
+
+	if cursor.Next(context.Background()) {
+		var result struct {
+			ValidFrom  time.Time `bson:"validfrom"`
+			ValidUntil time.Time `bson:"validuntil"`
+			OpenStart  int       `bson:"openstart"`
+			OpenEnd    int       `bson:"openend"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		if result.OpenStart == 0 {
+			validFrom = result.ValidFrom
+		}
+		if result.OpenEnd == 0 {
+			validUntil = result.ValidUntil
+		}
+	}
+
+	return validFrom, validUntil, cursor.Err()
+}
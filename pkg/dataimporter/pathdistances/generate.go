@@ -0,0 +1,241 @@
+package pathdistances
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+const stopLookupBatchSize = 1000
+
+// Generate fills in the distance of every journey path item in the datasources dataset the source left as zero,
+// then checks the speed each leg implies and flags the ones no vehicle could manage. Only the journeys of
+// operatorRefs are checked when it's set
+func Generate(datasource *ctdf.DataSourceReference, operatorRefs []string) error {
+	journeysCollection := database.GetCollection("journeys")
+	filter := bson.M{"datasource.datasetid": datasource.DatasetID}
+	if len(operatorRefs) > 0 {
+		filter["operatorref"] = bson.M{"$in": operatorRefs}
+	}
+
+	stopLocations, err := getStopLocations(filter)
+	if err != nil {
+		return err
+	}
+
+	serviceTransportTypes, err := getServiceTransportTypes(datasource.DatasetID)
+	if err != nil {
+		return err
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "serviceref", Value: 1},
+		bson.E{Key: "transporttype", Value: 1},
+		bson.E{Key: "path.originstopref", Value: 1},
+		bson.E{Key: "path.destinationstopref", Value: 1},
+		bson.E{Key: "path.distance", Value: 1},
+		bson.E{Key: "path.speedimplausible", Value: 1},
+		bson.E{Key: "path.origindeparturetime", Value: 1},
+		bson.E{Key: "path.origindeparturedayoffset", Value: 1},
+		bson.E{Key: "path.destinationarrivaltime", Value: 1},
+		bson.E{Key: "path.destinationarrivaldayoffset", Value: 1},
+		bson.E{Key: "path.track", Value: 1},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var operations []mongo.WriteModel
+	journeys := 0
+	calculatedLegs := 0
+	implausibleLegs := 0
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+		journeys += 1
+
+		transportType := journey.TransportType
+		if transportType == "" {
+			transportType = serviceTransportTypes[journey.ServiceRef]
+		}
+		maximumSpeed := ctdf.GetMaximumPlausibleSpeed(transportType)
+
+		set := bson.M{}
+		unset := bson.M{}
+
+		for i, pathItem := range journey.Path {
+			if pathItem.Distance == 0 {
+				distance := int(pathItem.CalculateDistance(stopLocations[pathItem.OriginStopRef], stopLocations[pathItem.DestinationStopRef]))
+
+				if distance > 0 {
+					pathItem.Distance = distance
+					set[fmt.Sprintf("path.%d.distance", i)] = distance
+					calculatedLegs += 1
+				}
+			}
+
+			speed := pathItem.GetImpliedSpeed()
+			implausible := speed > maximumSpeed
+			if implausible {
+				implausibleLegs += 1
+
+				log.Debug().
+					Str("journey", journey.PrimaryIdentifier).
+					Str("origin", pathItem.OriginStopRef).
+					Str("destination", pathItem.DestinationStopRef).
+					Int("distance", pathItem.Distance).
+					Float64("speed", speed).
+					Msg("Journey leg has an implausible speed")
+			}
+
+			if implausible && !pathItem.SpeedImplausible {
+				set[fmt.Sprintf("path.%d.speedimplausible", i)] = true
+			} else if !implausible && pathItem.SpeedImplausible {
+				unset[fmt.Sprintf("path.%d.speedimplausible", i)] = ""
+			}
+		}
+
+		if len(set) == 0 && len(unset) == 0 {
+			continue
+		}
+
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": journey.PrimaryIdentifier}).
+			SetUpdate(update),
+		)
+
+		if len(operations) >= writeBatchSize {
+			if _, err := journeysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(operations) > 0 {
+		if _, err := journeysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Str("dataset", datasource.DatasetID).
+		Int("journeys", journeys).
+		Int("calculated", calculatedLegs).
+		Int("implausible", implausibleLegs).
+		Msg("Generated journey path distances")
+
+	return nil
+}
+
+// getStopLocations loads the location of every stop the journeys reference, keyed by each identifier the stop is known by
+func getStopLocations(journeysFilter bson.M) (map[string]*ctdf.Location, error) {
+	journeysCollection := database.GetCollection("journeys")
+	stopsCollection := database.GetCollection("stops")
+
+	stopRefs := map[string]bool{}
+	for _, field := range []string{"path.originstopref", "path.destinationstopref"} {
+		refs, err := journeysCollection.Distinct(context.Background(), field, journeysFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refs {
+			if stopRef, ok := ref.(string); ok && stopRef != "" {
+				stopRefs[stopRef] = true
+			}
+		}
+	}
+
+	var refs []string
+	for ref := range stopRefs {
+		refs = append(refs, ref)
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "otheridentifiers", Value: 1},
+		bson.E{Key: "location", Value: 1},
+	})
+
+	stopLocations := map[string]*ctdf.Location{}
+	for lower := 0; lower < len(refs); lower += stopLookupBatchSize {
+		batch := refs[lower:min(lower+stopLookupBatchSize, len(refs))]
+
+		cursor, err := stopsCollection.Find(context.Background(), bson.M{"$or": bson.A{
+			bson.M{"primaryidentifier": bson.M{"$in": batch}},
+			bson.M{"otheridentifiers": bson.M{"$in": batch}},
+		}}, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next(context.Background()) {
+			var stop ctdf.Stop
+			if err := cursor.Decode(&stop); err != nil || stop.Location == nil {
+				continue
+			}
+
+			for _, identifier := range append(stop.OtherIdentifiers, stop.PrimaryIdentifier) {
+				if stopRefs[identifier] {
+					stopLocations[identifier] = stop.Location
+				}
+			}
+		}
+		cursor.Close(context.Background())
+	}
+
+	return stopLocations, nil
+}
+
+func getServiceTransportTypes(datasetID string) (map[string]ctdf.TransportType, error) {
+	servicesCollection := database.GetCollection("services")
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "transporttype", Value: 1},
+	})
+	cursor, err := servicesCollection.Find(context.Background(), bson.M{"datasource.datasetid": datasetID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	transportTypes := map[string]ctdf.TransportType{}
+	for cursor.Next(context.Background()) {
+		var service ctdf.Service
+		if err := cursor.Decode(&service); err != nil {
+			continue
+		}
+
+		transportTypes[service.PrimaryIdentifier] = service.TransportType
+	}
+
+	return transportTypes, cursor.Err()
+}
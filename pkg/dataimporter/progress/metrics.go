@@ -0,0 +1,47 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/travigo/travigo/pkg/elastic_client"
+)
+
+const metricsIndexName = "import-metrics-1"
+
+type importMetricsElasticEvent struct {
+	Timestamp time.Time
+
+	Dataset string
+
+	Records         int64
+	Bytes           int64
+	DurationSeconds float64
+	PeakHeapBytes   uint64
+}
+
+// MetricsReporter passes updates on to another reporter and records the totals of each finished import in
+// Elasticsearch so memory use & run times can be tracked across runs. Nothing is recorded if Elasticsearch isn't set up
+type MetricsReporter struct {
+	Reporter Reporter
+}
+
+func (r MetricsReporter) Report(update Update) {
+	r.Reporter.Report(update)
+}
+
+func (r MetricsReporter) Finish(update Update) {
+	r.Reporter.Finish(update)
+
+	elasticEvent, _ := json.Marshal(importMetricsElasticEvent{
+		Timestamp:       time.Now(),
+		Dataset:         update.Dataset,
+		Records:         update.RecordsParsed,
+		Bytes:           update.BytesProcessed,
+		DurationSeconds: update.Elapsed.Seconds(),
+		PeakHeapBytes:   update.PeakHeapBytes,
+	})
+
+	elastic_client.IndexRequest(metricsIndexName, bytes.NewReader(elasticEvent))
+}
@@ -0,0 +1,188 @@
+package progress
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const reportInterval = 1 * time.Second
+
+type Stage string
+
+const (
+	StageDownloading Stage = "downloading"
+	StageParsing           = "parsing"
+	StageImporting         = "importing"
+	StageFinished          = "finished"
+)
+
+// Update is a point in time snapshot of how far through an import is
+type Update struct {
+	Dataset string
+	Stage   Stage
+
+	RecordsParsed  int64
+	BytesProcessed int64
+	TotalBytes     int64
+
+	// Percentage of the source file processed, only set when the total size is known
+	Percentage float64
+
+	Elapsed time.Duration
+	ETA     time.Duration
+
+	// Largest heap seen while the import was running, sampled at each report
+	PeakHeapBytes uint64
+}
+
+// Reporter receives regular progress updates while an import is running
+type Reporter interface {
+	Report(Update)
+	Finish(Update)
+}
+
+// Tracker counts the records & bytes processed by an import and periodically hands an Update to its reporter.
+// All methods are safe to call on a nil Tracker so formats don't need to check if progress is being reported.
+type Tracker struct {
+	Dataset  string
+	Reporter Reporter
+
+	recordsParsed  int64
+	bytesProcessed int64
+	totalBytes     int64
+	peakHeapBytes  uint64
+
+	stage     atomic.Value
+	startTime time.Time
+
+	stop     chan bool
+	stopOnce sync.Once
+}
+
+func NewTracker(dataset string, reporter Reporter) *Tracker {
+	tracker := &Tracker{
+		Dataset:  dataset,
+		Reporter: reporter,
+		stop:     make(chan bool),
+	}
+	tracker.stage.Store(StageDownloading)
+
+	return tracker
+}
+
+// Start begins emitting updates to the reporter until Stop is called
+func (t *Tracker) Start() {
+	if t == nil {
+		return
+	}
+
+	t.startTime = time.Now()
+
+	go func() {
+		ticker := time.NewTicker(reportInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.sampleMemory()
+				t.Reporter.Report(t.GetUpdate())
+			}
+		}
+	}()
+}
+
+func (t *Tracker) Stop() {
+	if t == nil {
+		return
+	}
+
+	t.stopOnce.Do(func() {
+		close(t.stop)
+
+		t.sampleMemory()
+		t.SetStage(StageFinished)
+		t.Reporter.Finish(t.GetUpdate())
+	})
+}
+
+func (t *Tracker) SetStage(stage Stage) {
+	if t == nil {
+		return
+	}
+
+	t.stage.Store(stage)
+}
+
+func (t *Tracker) AddRecords(records int) {
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&t.recordsParsed, int64(records))
+}
+
+// WrapReader counts the bytes read through the returned reader towards the progress of the import
+func (t *Tracker) WrapReader(reader io.Reader, size int64) io.Reader {
+	if t == nil {
+		return reader
+	}
+
+	atomic.AddInt64(&t.totalBytes, size)
+
+	return &countingReader{reader: reader, tracker: t}
+}
+
+func (t *Tracker) GetUpdate() Update {
+	update := Update{
+		Dataset:        t.Dataset,
+		Stage:          t.stage.Load().(Stage),
+		RecordsParsed:  atomic.LoadInt64(&t.recordsParsed),
+		BytesProcessed: atomic.LoadInt64(&t.bytesProcessed),
+		TotalBytes:     atomic.LoadInt64(&t.totalBytes),
+		Elapsed:        time.Since(t.startTime),
+		PeakHeapBytes:  atomic.LoadUint64(&t.peakHeapBytes),
+	}
+
+	if update.TotalBytes > 0 {
+		update.Percentage = 100 * float64(update.BytesProcessed) / float64(update.TotalBytes)
+
+		// Only estimate once parsing has properly got going, the first few reads are too noisy
+		if update.Percentage >= 1 && update.Percentage < 100 {
+			update.ETA = time.Duration(float64(update.Elapsed) * (100 - update.Percentage) / update.Percentage).Round(time.Second)
+		}
+	}
+
+	return update
+}
+
+// sampleMemory records the current heap size if it's the largest seen so far.
+// The heap is shared with anything else running in the process so this is an upper bound for the import
+func (t *Tracker) sampleMemory() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	for {
+		peak := atomic.LoadUint64(&t.peakHeapBytes)
+		if memStats.HeapAlloc <= peak || atomic.CompareAndSwapUint64(&t.peakHeapBytes, peak, memStats.HeapAlloc) {
+			return
+		}
+	}
+}
+
+type countingReader struct {
+	reader  io.Reader
+	tracker *Tracker
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	atomic.AddInt64(&c.tracker.bytesProcessed, int64(n))
+
+	return n, err
+}
@@ -0,0 +1,79 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const progressBarWidth = 40
+
+// TerminalReporter renders a single updating progress bar line
+type TerminalReporter struct {
+	Writer io.Writer
+}
+
+func (r TerminalReporter) Report(update Update) {
+	filled := int(update.Percentage / 100 * progressBarWidth)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+
+	eta := "-"
+	if update.ETA > 0 {
+		eta = update.ETA.String()
+	}
+
+	fmt.Fprintf(r.Writer, "\r\033[K%-10s [%s%s] %5.1f%% %d records ETA %s",
+		update.Stage, strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), update.Percentage, update.RecordsParsed, eta)
+}
+
+func (r TerminalReporter) Finish(update Update) {
+	fmt.Fprintf(r.Writer, "\r\033[K%d records processed in %s, peak heap %dMB\n", update.RecordsParsed, update.Elapsed.Round(time.Millisecond).String(), update.PeakHeapBytes/1024/1024)
+}
+
+// LogReporter writes progress as log lines, for when the importer isn't attached to a terminal
+type LogReporter struct {
+	Interval time.Duration
+
+	lastReport time.Time
+}
+
+func (r *LogReporter) Report(update Update) {
+	if time.Since(r.lastReport) < r.Interval {
+		return
+	}
+	r.lastReport = time.Now()
+
+	log.Info().
+		Str("dataset", update.Dataset).
+		Str("stage", string(update.Stage)).
+		Int64("records", update.RecordsParsed).
+		Float64("percentage", update.Percentage).
+		Str("eta", update.ETA.String()).
+		Msg("Import progress")
+}
+
+func (r *LogReporter) Finish(update Update) {
+	log.Info().
+		Str("dataset", update.Dataset).
+		Int64("records", update.RecordsParsed).
+		Str("elapsed", update.Elapsed.String()).
+		Uint64("peakheapbytes", update.PeakHeapBytes).
+		Msg("Import finished")
+}
+
+// NewCLIReporter picks a progress bar when running interactively and falls back to log lines otherwise.
+// Either way the totals are recorded as metrics
+func NewCLIReporter() Reporter {
+	fileInfo, err := os.Stderr.Stat()
+	if err == nil && fileInfo.Mode()&os.ModeCharDevice != 0 {
+		return MetricsReporter{Reporter: TerminalReporter{Writer: os.Stderr}}
+	}
+
+	return MetricsReporter{Reporter: &LogReporter{Interval: 30 * time.Second}}
+}
@@ -0,0 +1,229 @@
+package raillocations
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+// MaximumLinkDistance is how far in metres a rail location can be from a station to be linked to it by position alone
+const MaximumLinkDistance = 400
+
+// Roughly MaximumLinkDistance in degrees of latitude, used to skip the distance calculation for stops that are clearly too far
+const maximumLinkLatitudeDelta = 0.004
+
+type stopRecord struct {
+	PrimaryIdentifier string
+	OtherIdentifiers  []string
+	PrimaryName       string
+	Location          *ctdf.Location
+}
+
+type railLocationRecord struct {
+	PrimaryIdentifier string
+	TIPLOC            string
+	CRS               string
+	Name              string
+	Location          *ctdf.Location
+	StopRef           string
+	StopLinkMethod    ctdf.RailLocationStopLinkMethod
+}
+
+// Link sets the station stop of every rail location. The TIPLOC & CRS codes NaPTAN has for stations are
+// trusted first, anything else close enough to a station with a similar name is taken to be a part of it.
+// It has to run after the stops linker as that rebuilds the stops collection from the raw stops.
+func Link() error {
+	stops, err := getRailStops()
+	if err != nil {
+		return err
+	}
+
+	stopsByIdentifier := map[string]*stopRecord{}
+	for _, stop := range stops {
+		for _, identifier := range stop.OtherIdentifiers {
+			stopsByIdentifier[identifier] = stop
+		}
+	}
+
+	railLocationsCollection := database.GetCollection("rail_locations")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "tiploc", Value: 1},
+		{Key: "crs", Value: 1},
+		{Key: "name", Value: 1},
+		{Key: "location", Value: 1},
+		{Key: "stopref", Value: 1},
+		{Key: "stoplinkmethod", Value: 1},
+	})
+	cursor, err := railLocationsCollection.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var operations []mongo.WriteModel
+	linkMethodCounts := map[ctdf.RailLocationStopLinkMethod]int{}
+	updated := 0
+
+	for cursor.Next(context.Background()) {
+		var railLocation railLocationRecord
+		if err := cursor.Decode(&railLocation); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Rail Location")
+			continue
+		}
+
+		stop, linkMethod := findStop(&railLocation, stopsByIdentifier, stops)
+
+		stopRef := ""
+		if stop != nil {
+			stopRef = stop.PrimaryIdentifier
+			linkMethodCounts[linkMethod] += 1
+		}
+
+		if stopRef == railLocation.StopRef && linkMethod == railLocation.StopLinkMethod {
+			continue
+		}
+
+		var update bson.M
+		if stopRef == "" {
+			update = bson.M{"$unset": bson.M{"stopref": "", "stoplinkmethod": ""}}
+		} else {
+			update = bson.M{"$set": bson.M{"stopref": stopRef, "stoplinkmethod": linkMethod}}
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": railLocation.PrimaryIdentifier}).
+			SetUpdate(update),
+		)
+		updated += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := railLocationsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(operations) > 0 {
+		if _, err := railLocationsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Int("tiploc", linkMethodCounts[ctdf.RailLocationStopLinkTIPLOC]).
+		Int("crs", linkMethodCounts[ctdf.RailLocationStopLinkCRS]).
+		Int("proximity", linkMethodCounts[ctdf.RailLocationStopLinkProximity]).
+		Int("updated", updated).
+		Msg("Linked Rail Locations to stops")
+
+	return nil
+}
+
+func findStop(railLocation *railLocationRecord, stopsByIdentifier map[string]*stopRecord, stops []*stopRecord) (*stopRecord, ctdf.RailLocationStopLinkMethod) {
+	if stop := stopsByIdentifier["gb-tiploc-"+railLocation.TIPLOC]; stop != nil {
+		return stop, ctdf.RailLocationStopLinkTIPLOC
+	}
+
+	if railLocation.CRS != "" {
+		if stop := stopsByIdentifier["gb-crs-"+railLocation.CRS]; stop != nil {
+			return stop, ctdf.RailLocationStopLinkCRS
+		}
+	}
+
+	if railLocation.Location == nil || len(railLocation.Location.Coordinates) != 2 {
+		return nil, ""
+	}
+
+	var closestStop *stopRecord
+	closestDistance := math.MaxFloat64
+
+	for _, stop := range stops {
+		if stop.Location == nil || math.Abs(stop.Location.Coordinates[1]-railLocation.Location.Coordinates[1]) > maximumLinkLatitudeDelta {
+			continue
+		}
+
+		distance := railLocation.Location.GreatCircleDistance(stop.Location)
+		if distance <= MaximumLinkDistance && distance < closestDistance && namesMatch(railLocation.Name, stop.PrimaryName) {
+			closestStop = stop
+			closestDistance = distance
+		}
+	}
+
+	if closestStop == nil {
+		return nil, ""
+	}
+
+	return closestStop, ctdf.RailLocationStopLinkProximity
+}
+
+// namesMatch is whether the first word of the rail location name is in the stop name, enough to tell a station's
+// sidings & junctions apart from an unrelated station that happens to be nearby
+func namesMatch(railLocationName string, stopName string) bool {
+	railLocationWords := getNameWords(railLocationName)
+	if len(railLocationWords) == 0 {
+		return false
+	}
+
+	for _, word := range getNameWords(stopName) {
+		if word == railLocationWords[0] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getNameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func getRailStops() ([]*stopRecord, error) {
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "primaryname", Value: 1},
+		{Key: "location", Value: 1},
+	})
+	cursor, err := database.GetCollection("stops").Find(context.Background(), bson.M{"transporttypes": ctdf.TransportTypeRail}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var stops []*stopRecord
+	for cursor.Next(context.Background()) {
+		var stop *stopRecord
+		if err := cursor.Decode(&stop); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Stop")
+			continue
+		}
+
+		// Only used for linking by position so stops without one can still be linked by code
+		if stop.Location == nil || len(stop.Location.Coordinates) != 2 {
+			stop.Location = nil
+		}
+
+		stops = append(stops, stop)
+	}
+
+	return stops, cursor.Err()
+}
@@ -0,0 +1,112 @@
+package runhistory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// GetPreviousRun returns the run recorded before the given one, nil if it's the first
+func GetPreviousRun(datasetID string, run string) (*DatasetRun, error) {
+	runs, err := GetRuns(datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, datasetRun := range runs {
+		if datasetRun.Run == run && i+1 < len(runs) {
+			return runs[i+1], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// TimetableChanges turns the diff into a change per service that lost or had journeys retimed.
+// Services that only gained journeys aren't included as nobody's regular journey has changed
+func (r *DiffReport) TimetableChanges() []*ctdf.TimetableChange {
+	var serviceRefs []string
+	for serviceRef := range r.Services {
+		serviceRefs = append(serviceRefs, serviceRef)
+	}
+	sort.Strings(serviceRefs)
+
+	var changes []*ctdf.TimetableChange
+	for _, serviceRef := range serviceRefs {
+		serviceDiff := r.Services[serviceRef]
+
+		if len(serviceDiff.Retimed) == 0 && len(serviceDiff.Removed) == 0 {
+			continue
+		}
+
+		change := &ctdf.TimetableChange{
+			DatasetRef:  r.Dataset,
+			ServiceRef:  serviceRef,
+			PreviousRun: r.RunA,
+			Run:         r.RunB,
+		}
+
+		for i, journey := range serviceDiff.Retimed {
+			change.Retimed = append(change.Retimed, &ctdf.TimetableChangeJourney{
+				JourneyRef: journey.PrimaryIdentifier,
+				Before:     serviceDiff.RetimedBefore[i].getTimes(),
+				After:      journey.getTimes(),
+			})
+			change.EffectiveFrom = earliestDate(change.EffectiveFrom, journey.ValidFrom)
+		}
+		for _, journey := range serviceDiff.Removed {
+			change.Cancelled = append(change.Cancelled, &ctdf.TimetableChangeJourney{
+				JourneyRef: journey.PrimaryIdentifier,
+				Before:     journey.getTimes(),
+			})
+		}
+		for _, journey := range serviceDiff.Added {
+			change.Added = append(change.Added, &ctdf.TimetableChangeJourney{
+				JourneyRef: journey.PrimaryIdentifier,
+				After:      journey.getTimes(),
+			})
+			change.EffectiveFrom = earliestDate(change.EffectiveFrom, journey.ValidFrom)
+		}
+
+		sortTimetableChangeJourneys(change.Retimed)
+		sortTimetableChangeJourneys(change.Cancelled)
+		sortTimetableChangeJourneys(change.Added)
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func (j *DatasetRunJourney) getTimes() *ctdf.TimetableChangeJourneyTimes {
+	return &ctdf.TimetableChangeJourneyTimes{
+		DepartureTime: j.DepartureTime,
+		PathTimes:     j.PathTimes,
+	}
+}
+
+func earliestDate(current time.Time, date time.Time) time.Time {
+	if date.IsZero() {
+		return current
+	}
+	if current.IsZero() || date.Before(current) {
+		return date
+	}
+
+	return current
+}
+
+func sortTimetableChangeJourneys(journeys []*ctdf.TimetableChangeJourney) {
+	sort.Slice(journeys, func(i, j int) bool {
+		return getChangeJourneyDeparture(journeys[i]).Before(getChangeJourneyDeparture(journeys[j]))
+	})
+}
+
+func getChangeJourneyDeparture(journey *ctdf.TimetableChangeJourney) time.Time {
+	if journey.Before != nil {
+		return journey.Before.DepartureTime
+	}
+
+	return journey.After.DepartureTime
+}
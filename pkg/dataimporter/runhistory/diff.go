@@ -0,0 +1,150 @@
+package runhistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type DiffReport struct {
+	Dataset string
+	RunA    string
+	RunB    string
+
+	Services map[string]*ServiceDiff
+}
+
+type ServiceDiff struct {
+	Added   []*DatasetRunJourney
+	Removed []*DatasetRunJourney
+	Retimed []*DatasetRunJourney
+	// The retimed journeys as they were before, in the same order as Retimed
+	RetimedBefore []*DatasetRunJourney `json:"-"`
+
+	Unchanged int
+}
+
+// Diff compares the journeys of two runs of the same dataset, reporting the changes going from runA to runB
+func Diff(datasetID string, runA string, runB string) (*DiffReport, error) {
+	journeysA, err := getRunJourneys(datasetID, runA)
+	if err != nil {
+		return nil, err
+	}
+	journeysB, err := getRunJourneys(datasetID, runB)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DiffReport{
+		Dataset:  datasetID,
+		RunA:     runA,
+		RunB:     runB,
+		Services: map[string]*ServiceDiff{},
+	}
+
+	for id, journeyB := range journeysB {
+		serviceDiff := report.getServiceDiff(journeyB.ServiceRef)
+		journeyA, exists := journeysA[id]
+
+		if !exists {
+			serviceDiff.Added = append(serviceDiff.Added, journeyB)
+		} else if journeyTimesDiffer(journeyA, journeyB) {
+			serviceDiff.Retimed = append(serviceDiff.Retimed, journeyB)
+			serviceDiff.RetimedBefore = append(serviceDiff.RetimedBefore, journeyA)
+		} else {
+			serviceDiff.Unchanged += 1
+		}
+	}
+
+	for id, journeyA := range journeysA {
+		if _, exists := journeysB[id]; !exists {
+			serviceDiff := report.getServiceDiff(journeyA.ServiceRef)
+			serviceDiff.Removed = append(serviceDiff.Removed, journeyA)
+		}
+	}
+
+	return report, nil
+}
+
+func (r *DiffReport) getServiceDiff(serviceRef string) *ServiceDiff {
+	if r.Services[serviceRef] == nil {
+		r.Services[serviceRef] = &ServiceDiff{}
+	}
+
+	return r.Services[serviceRef]
+}
+
+func (r *DiffReport) Print(writer io.Writer) {
+	fmt.Fprintf(writer, "Dataset %s: run %s -> run %s\n", r.Dataset, r.RunA, r.RunB)
+
+	var serviceRefs []string
+	for serviceRef := range r.Services {
+		serviceRefs = append(serviceRefs, serviceRef)
+	}
+	sort.Strings(serviceRefs)
+
+	for _, serviceRef := range serviceRefs {
+		serviceDiff := r.Services[serviceRef]
+
+		if len(serviceDiff.Added) == 0 && len(serviceDiff.Removed) == 0 && len(serviceDiff.Retimed) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(writer, "\n%s: %d added, %d removed, %d retimed, %d unchanged\n",
+			serviceRef, len(serviceDiff.Added), len(serviceDiff.Removed), len(serviceDiff.Retimed), serviceDiff.Unchanged)
+
+		for _, journey := range serviceDiff.Added {
+			fmt.Fprintf(writer, "  + %s %s\n", journey.DepartureTime.Format("15:04"), journey.PrimaryIdentifier)
+		}
+		for _, journey := range serviceDiff.Removed {
+			fmt.Fprintf(writer, "  - %s %s\n", journey.DepartureTime.Format("15:04"), journey.PrimaryIdentifier)
+		}
+		for _, journey := range serviceDiff.Retimed {
+			fmt.Fprintf(writer, "  ~ %s %s\n", journey.DepartureTime.Format("15:04"), journey.PrimaryIdentifier)
+		}
+	}
+}
+
+func getRunJourneys(datasetID string, run string) (map[string]*DatasetRunJourney, error) {
+	collection := database.GetCollection("dataset_run_journeys")
+
+	cursor, err := collection.Find(context.Background(), bson.M{"dataset": datasetID, "run": run})
+	if err != nil {
+		return nil, err
+	}
+
+	journeys := map[string]*DatasetRunJourney{}
+	for cursor.Next(context.Background()) {
+		var journey DatasetRunJourney
+		if err := cursor.Decode(&journey); err != nil {
+			return nil, err
+		}
+
+		journeys[journey.PrimaryIdentifier] = &journey
+	}
+
+	if len(journeys) == 0 {
+		return nil, errors.New(fmt.Sprintf("No journeys recorded for run %s of dataset %s", run, datasetID))
+	}
+
+	return journeys, nil
+}
+
+func journeyTimesDiffer(a *DatasetRunJourney, b *DatasetRunJourney) bool {
+	if !a.DepartureTime.Equal(b.DepartureTime) || len(a.PathTimes) != len(b.PathTimes) {
+		return true
+	}
+
+	for i := range a.PathTimes {
+		if !a.PathTimes[i].Equal(b.PathTimes[i]) {
+			return true
+		}
+	}
+
+	return false
+}
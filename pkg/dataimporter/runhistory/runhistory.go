@@ -0,0 +1,117 @@
+package runhistory
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Number of previous runs to keep journey snapshots for per dataset
+const runsToKeep = 5
+
+type DatasetRun struct {
+	Dataset  string
+	Run      string
+	Journeys int64
+
+	CreationDateTime time.Time
+}
+
+type DatasetRunJourney struct {
+	Dataset string
+	Run     string
+
+	PrimaryIdentifier string
+	ServiceRef        string
+	DepartureTime     time.Time
+	PathTimes         []time.Time
+	ValidFrom         time.Time `bson:",omitempty"`
+}
+
+// RecordRun snapshots the journeys produced by an import run so they can be compared against later runs
+func RecordRun(datasource *ctdf.DataSourceReference) error {
+	journeysCollection := database.GetCollection("journeys")
+	runsCollection := database.GetCollection("dataset_runs")
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"datasource.datasetid": datasource.DatasetID,
+			"datasource.timestamp": datasource.Timestamp,
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"dataset":           datasource.DatasetID,
+			"run":               datasource.Timestamp,
+			"primaryidentifier": 1,
+			"serviceref":        1,
+			"departuretime":     1,
+			"pathtimes":         "$path.origindeparturetime",
+			"validfrom":         1,
+		}}},
+		bson.D{{Key: "$merge", Value: bson.M{
+			"into": "dataset_run_journeys",
+		}}},
+	}
+
+	cursor, err := journeysCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return err
+	}
+	cursor.Close(context.Background())
+
+	numberJourneys, _ := database.GetCollection("dataset_run_journeys").CountDocuments(context.Background(), bson.M{
+		"dataset": datasource.DatasetID,
+		"run":     datasource.Timestamp,
+	})
+
+	_, err = runsCollection.InsertOne(context.Background(), DatasetRun{
+		Dataset:          datasource.DatasetID,
+		Run:              datasource.Timestamp,
+		Journeys:         numberJourneys,
+		CreationDateTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("dataset", datasource.DatasetID).Str("run", datasource.Timestamp).Int64("journeys", numberJourneys).Msg("Recorded dataset run")
+
+	pruneRuns(datasource.DatasetID)
+
+	return nil
+}
+
+func GetRuns(datasetID string) ([]*DatasetRun, error) {
+	runsCollection := database.GetCollection("dataset_runs")
+
+	opts := options.Find().SetSort(bson.D{{Key: "creationdatetime", Value: -1}})
+	cursor, err := runsCollection.Find(context.Background(), bson.M{"dataset": datasetID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*DatasetRun
+	err = cursor.All(context.Background(), &runs)
+
+	return runs, err
+}
+
+func pruneRuns(datasetID string) {
+	runs, err := GetRuns(datasetID)
+	if err != nil || len(runs) <= runsToKeep {
+		return
+	}
+
+	for _, run := range runs[runsToKeep:] {
+		database.GetCollection("dataset_run_journeys").DeleteMany(context.Background(), bson.M{"dataset": datasetID, "run": run.Run})
+		database.GetCollection("dataset_runs").DeleteOne(context.Background(), bson.M{"dataset": datasetID, "run": run.Run})
+
+		log.Debug().Str("dataset", datasetID).Str("run", run.Run).Msg("Pruned old dataset run")
+	}
+}
@@ -0,0 +1,274 @@
+package serviceroutes
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+// Generate rebuilds the route variants of every service in the datasources dataset from their journeys,
+// only the services of operatorRefs when it's set
+func Generate(datasource *ctdf.DataSourceReference, operatorRefs []string) error {
+	servicesCollection := database.GetCollection("services")
+
+	filter := bson.M{"datasource.datasetid": datasource.DatasetID}
+	if len(operatorRefs) > 0 {
+		filter["operatorref"] = bson.M{"$in": operatorRefs}
+	}
+
+	serviceRefs, err := servicesCollection.Distinct(context.Background(), "primaryidentifier", filter)
+	if err != nil {
+		return err
+	}
+
+	var operations []mongo.WriteModel
+	for _, serviceRef := range serviceRefs {
+		serviceID := serviceRef.(string)
+
+		routes, err := generateServiceRoutes(serviceID)
+		if err != nil {
+			log.Error().Err(err).Str("service", serviceID).Msg("Failed to generate service routes")
+			continue
+		}
+
+		// Leave whatever the source provided alone if theres no journeys to work from
+		if len(routes) == 0 {
+			continue
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": serviceID}).
+			SetUpdate(bson.M{"$set": bson.M{"routes": routes}}),
+		)
+
+		if len(operations) >= writeBatchSize {
+			if _, err := servicesCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := servicesCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("dataset", datasource.DatasetID).Int("services", len(serviceRefs)).Msg("Generated service routes")
+
+	return nil
+}
+
+type routeVariant struct {
+	route *ctdf.Route
+
+	// destinationDisplays counts the destination displays used by journeys on the variant to pick the most common
+	destinationDisplays map[string]int
+	hasTrack            bool
+}
+
+func generateServiceRoutes(serviceID string) ([]ctdf.Route, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "direction", Value: 1},
+		bson.E{Key: "destinationdisplay", Value: 1},
+		bson.E{Key: "path.originstopref", Value: 1},
+		bson.E{Key: "path.destinationstopref", Value: 1},
+		bson.E{Key: "path.track", Value: 1},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), bson.M{"serviceref": serviceID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	variants := map[string]*routeVariant{}
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		err := cursor.Decode(&journey)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		if len(journey.Path) == 0 {
+			continue
+		}
+
+		stopRefs := []string{journey.Path[0].OriginStopRef}
+		for _, pathItem := range journey.Path {
+			stopRefs = append(stopRefs, pathItem.DestinationStopRef)
+		}
+
+		variantKey := strings.Join(stopRefs, ",")
+		variant, exists := variants[variantKey]
+		if !exists {
+			variant = &routeVariant{
+				route: &ctdf.Route{
+					Origin:      stopRefs[0],
+					Destination: stopRefs[len(stopRefs)-1],
+					Direction:   journey.Direction,
+					StopRefs:    stopRefs,
+				},
+				destinationDisplays: map[string]int{},
+			}
+			variants[variantKey] = variant
+		}
+
+		variant.route.NumberJourneys += 1
+		variant.destinationDisplays[journey.DestinationDisplay] += 1
+
+		if !variant.hasTrack {
+			if track := getJourneyTrack(&journey); track != nil {
+				variant.route.Track = track
+				variant.hasTrack = true
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	canonicalVariants := mergeShortWorkings(variants)
+
+	var routes []ctdf.Route
+	for _, variant := range canonicalVariants {
+		variant.route.Description = getMostCommonDestinationDisplay(variant.destinationDisplays)
+
+		if !variant.hasTrack {
+			variant.route.Track = getStopsTrack(variant.route.StopRefs)
+		}
+
+		routes = append(routes, *variant.route)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].NumberJourneys > routes[j].NumberJourneys
+	})
+
+	return routes, nil
+}
+
+// mergeShortWorkings folds variants whose stops are a contiguous part of a longer variant into it, so short workings
+// and part route journeys don't show up as separate routes
+func mergeShortWorkings(variants map[string]*routeVariant) []*routeVariant {
+	var sortedVariants []*routeVariant
+	for _, variant := range variants {
+		sortedVariants = append(sortedVariants, variant)
+	}
+
+	sort.SliceStable(sortedVariants, func(i, j int) bool {
+		if len(sortedVariants[i].route.StopRefs) != len(sortedVariants[j].route.StopRefs) {
+			return len(sortedVariants[i].route.StopRefs) > len(sortedVariants[j].route.StopRefs)
+		}
+
+		return sortedVariants[i].route.NumberJourneys > sortedVariants[j].route.NumberJourneys
+	})
+
+	var canonicalVariants []*routeVariant
+	for _, variant := range sortedVariants {
+		merged := false
+
+		for _, canonicalVariant := range canonicalVariants {
+			if containsSequence(canonicalVariant.route.StopRefs, variant.route.StopRefs) {
+				canonicalVariant.route.NumberJourneys += variant.route.NumberJourneys
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			canonicalVariants = append(canonicalVariants, variant)
+		}
+	}
+
+	return canonicalVariants
+}
+
+func containsSequence(stopRefs []string, sequence []string) bool {
+	for i := 0; i+len(sequence) <= len(stopRefs); i++ {
+		if slices.Equal(stopRefs[i:i+len(sequence)], sequence) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getJourneyTrack joins up the tracks of each path item, returning nil if any of them are missing
+func getJourneyTrack(journey *ctdf.Journey) []ctdf.Location {
+	var track []ctdf.Location
+
+	for _, pathItem := range journey.Path {
+		if len(pathItem.Track) == 0 {
+			return nil
+		}
+
+		track = append(track, pathItem.Track...)
+	}
+
+	return track
+}
+
+// getStopsTrack is a straight line between the stops for when the journeys don't have tracks
+func getStopsTrack(stopRefs []string) []ctdf.Location {
+	stopsCollection := database.GetCollection("stops")
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "location", Value: 1},
+	})
+	cursor, err := stopsCollection.Find(context.Background(), bson.M{"primaryidentifier": bson.M{"$in": stopRefs}}, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query stops")
+		return nil
+	}
+	defer cursor.Close(context.Background())
+
+	stopLocations := map[string]*ctdf.Location{}
+	for cursor.Next(context.Background()) {
+		var stop ctdf.Stop
+		if err := cursor.Decode(&stop); err != nil {
+			continue
+		}
+
+		stopLocations[stop.PrimaryIdentifier] = stop.Location
+	}
+
+	var track []ctdf.Location
+	for _, stopRef := range stopRefs {
+		if location := stopLocations[stopRef]; location != nil {
+			track = append(track, *location)
+		}
+	}
+
+	return track
+}
+
+func getMostCommonDestinationDisplay(destinationDisplays map[string]int) string {
+	var mostCommon string
+	mostCommonCount := 0
+
+	for destinationDisplay, count := range destinationDisplays {
+		if count > mostCommonCount || (count == mostCommonCount && destinationDisplay < mostCommon) {
+			mostCommon = destinationDisplay
+			mostCommonCount = count
+		}
+	}
+
+	return mostCommon
+}
@@ -0,0 +1,190 @@
+package servicestopsummary
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type departure struct {
+	minuteOfDay int
+	time        time.Time
+}
+
+// Generate rebuilds the service stop summaries for every service in the datasources dataset,
+// only the services of operatorRefs when it's set
+func Generate(datasource *ctdf.DataSourceReference, operatorRefs []string) error {
+	servicesCollection := database.GetCollection("services")
+	summariesCollection := database.GetCollection("service_stop_summaries")
+
+	filter := bson.M{"datasource.datasetid": datasource.DatasetID}
+	if len(operatorRefs) > 0 {
+		filter["operatorref"] = bson.M{"$in": operatorRefs}
+	}
+
+	serviceRefs, err := servicesCollection.Distinct(context.Background(), "primaryidentifier", filter)
+	if err != nil {
+		return err
+	}
+
+	dayTypeDates := getDayTypeReferenceDates(time.Now())
+
+	for _, serviceRef := range serviceRefs {
+		serviceID := serviceRef.(string)
+
+		summaries, err := generateServiceSummaries(serviceID, dayTypeDates)
+		if err != nil {
+			log.Error().Err(err).Str("service", serviceID).Msg("Failed to generate service stop summaries")
+			continue
+		}
+
+		var operations []mongo.WriteModel
+		for _, summary := range summaries {
+			summary.DataSource = datasource
+
+			operations = append(operations, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"primaryidentifier": summary.PrimaryIdentifier}).
+				SetUpdate(bson.M{"$set": summary}).
+				SetUpsert(true),
+			)
+		}
+
+		if len(operations) > 0 {
+			_, err = summariesCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{})
+			if err != nil {
+				log.Error().Err(err).Str("service", serviceID).Msg("Failed to save service stop summaries")
+			}
+		}
+	}
+
+	log.Info().Str("dataset", datasource.DatasetID).Int("services", len(serviceRefs)).Msg("Generated service stop summaries")
+
+	return nil
+}
+
+func generateServiceSummaries(serviceID string, dayTypeDates map[ctdf.ServiceStopSummaryDayType]time.Time) ([]*ctdf.ServiceStopSummary, error) {
+	journeysCollection := database.GetCollection("journeys")
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "availability", Value: 1},
+		bson.E{Key: "path.originstopref", Value: 1},
+		bson.E{Key: "path.origindeparturetime", Value: 1},
+		bson.E{Key: "path.origindeparturedayoffset", Value: 1},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), bson.M{"serviceref": serviceID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// stop -> day type -> departures
+	stopDepartures := map[string]map[ctdf.ServiceStopSummaryDayType][]departure{}
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		err := cursor.Decode(&journey)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		for dayType, date := range dayTypeDates {
+			if journey.Availability == nil || !journey.Availability.MatchDate(date) {
+				continue
+			}
+
+			for _, pathItem := range journey.Path {
+				if stopDepartures[pathItem.OriginStopRef] == nil {
+					stopDepartures[pathItem.OriginStopRef] = map[ctdf.ServiceStopSummaryDayType][]departure{}
+				}
+
+				stopDepartures[pathItem.OriginStopRef][dayType] = append(stopDepartures[pathItem.OriginStopRef][dayType], departure{
+					minuteOfDay: pathItem.OriginDepartureDayOffset*24*60 + pathItem.OriginDepartureTime.Hour()*60 + pathItem.OriginDepartureTime.Minute(),
+					time:        pathItem.OriginDepartureTime,
+				})
+			}
+		}
+	}
+
+	now := time.Now()
+	var summaries []*ctdf.ServiceStopSummary
+
+	for stopRef, dayTypes := range stopDepartures {
+		summary := &ctdf.ServiceStopSummary{
+			PrimaryIdentifier:    fmt.Sprintf(ctdf.ServiceStopSummaryIDFormat, serviceID, stopRef),
+			ServiceRef:           serviceID,
+			StopRef:              stopRef,
+			ModificationDateTime: now,
+		}
+
+		for _, dayType := range []ctdf.ServiceStopSummaryDayType{ctdf.ServiceStopSummaryDayTypeWeekday, ctdf.ServiceStopSummaryDayTypeSaturday, ctdf.ServiceStopSummaryDayTypeSunday} {
+			departures := dayTypes[dayType]
+			if len(departures) == 0 {
+				continue
+			}
+
+			summary.DayTypes = append(summary.DayTypes, summariseDepartures(dayType, departures))
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func summariseDepartures(dayType ctdf.ServiceStopSummaryDayType, departures []departure) *ctdf.ServiceStopDayTypeSummary {
+	sort.Slice(departures, func(i, j int) bool {
+		return departures[i].minuteOfDay < departures[j].minuteOfDay
+	})
+
+	var headways []int
+	for i := 1; i < len(departures); i++ {
+		gap := departures[i].minuteOfDay - departures[i-1].minuteOfDay
+
+		// Identical departure times are usually duplicate journeys rather than a real headway
+		if gap > 0 {
+			headways = append(headways, gap)
+		}
+	}
+
+	typicalHeadway := 0
+	if len(headways) > 0 {
+		sort.Ints(headways)
+		typicalHeadway = headways[len(headways)/2]
+	}
+
+	return &ctdf.ServiceStopDayTypeSummary{
+		DayType:               dayType,
+		FirstDeparture:        departures[0].time,
+		LastDeparture:         departures[len(departures)-1].time,
+		NumberDepartures:      len(departures),
+		TypicalHeadwayMinutes: typicalHeadway,
+	}
+}
+
+// getDayTypeReferenceDates picks the next Wednesday, Saturday & Sunday as representative dates for each day type
+func getDayTypeReferenceDates(from time.Time) map[ctdf.ServiceStopSummaryDayType]time.Time {
+	dates := map[ctdf.ServiceStopSummaryDayType]time.Time{}
+
+	for i := 0; i < 7; i++ {
+		date := from.AddDate(0, 0, i)
+
+		switch date.Weekday() {
+		case time.Wednesday:
+			dates[ctdf.ServiceStopSummaryDayTypeWeekday] = date
+		case time.Saturday:
+			dates[ctdf.ServiceStopSummaryDayTypeSaturday] = date
+		case time.Sunday:
+			dates[ctdf.ServiceStopSummaryDayTypeSunday] = date
+		}
+	}
+
+	return dates
+}
@@ -0,0 +1,226 @@
+package stopdepartures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/leader"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+// The fields of a journey its stop departures are built from
+var journeyProjection = bson.D{
+	bson.E{Key: "primaryidentifier", Value: 1},
+	bson.E{Key: "functionalhash", Value: 1},
+	bson.E{Key: "serviceref", Value: 1},
+	bson.E{Key: "operatorref", Value: 1},
+	bson.E{Key: "departuretime", Value: 1},
+	bson.E{Key: "destinationdisplay", Value: 1},
+	bson.E{Key: "direction", Value: 1},
+	bson.E{Key: "availability", Value: 1},
+	bson.E{Key: "validfrom", Value: 1},
+	bson.E{Key: "validuntil", Value: 1},
+	bson.E{Key: "path.originstopref", Value: 1},
+	bson.E{Key: "path.originarrivaltime", Value: 1},
+	bson.E{Key: "path.origindeparturetime", Value: 1},
+	bson.E{Key: "path.origindeparturedayoffset", Value: 1},
+	bson.E{Key: "path.destinationstopref", Value: 1},
+	bson.E{Key: "path.destinationarrivaltime", Value: 1},
+}
+
+// Generate brings the materialised stop departures for the datasources dataset up to date. Journeys whose functional
+// hash hasn't changed since they were last materialised are left alone so re-imports only rewrite what changed.
+// Only the journeys of operatorRefs are touched when it's set. A dataset is only materialised by one process at a time
+func Generate(datasource *ctdf.DataSourceReference, operatorRefs []string) error {
+	lease, err := leader.Acquire(leader.MongoStore{}, fmt.Sprintf("stop-departures-%s", datasource.DatasetID), leader.HolderID(), leader.DefaultTTL)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return errors.New(fmt.Sprintf("Stop departures for %s are already being materialised", datasource.DatasetID))
+	}
+	defer lease.Release()
+
+	journeysCollection := database.GetCollection("journeys")
+	stopDeparturesCollection := database.GetCollection("stop_departures")
+
+	filter := bson.M{"datasource.datasetid": datasource.DatasetID}
+	if len(operatorRefs) > 0 {
+		filter["operatorref"] = bson.M{"$in": operatorRefs}
+	}
+
+	existingHashes, err := getMaterialisedJourneyHashes(filter)
+	if err != nil {
+		return err
+	}
+
+	opts := options.Find().SetProjection(journeyProjection)
+	cursor, err := journeysCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	now := time.Now()
+	seenJourneys := map[string]bool{}
+	updatedJourneys := 0
+
+	var operations []mongo.WriteModel
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		err := cursor.Decode(&journey)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		seenJourneys[journey.PrimaryIdentifier] = true
+
+		journeyHash := journey.FunctionalHash
+		if journeyHash == "" {
+			journeyHash = journey.GenerateFunctionalHash(true)
+		}
+
+		if existingHash, exists := existingHashes[journey.PrimaryIdentifier]; exists && existingHash == journeyHash {
+			continue
+		}
+
+		if _, exists := existingHashes[journey.PrimaryIdentifier]; exists {
+			operations = append(operations, mongo.NewDeleteManyModel().SetFilter(bson.M{"journeyref": journey.PrimaryIdentifier}))
+		}
+
+		for _, stopDeparture := range getStopDepartures(&journey, journeyHash, datasource, now) {
+			operations = append(operations, mongo.NewInsertOneModel().SetDocument(stopDeparture))
+		}
+		updatedJourneys += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := stopDeparturesCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(true)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	var removedJourneys []string
+	for journeyRef := range existingHashes {
+		if !seenJourneys[journeyRef] {
+			removedJourneys = append(removedJourneys, journeyRef)
+		}
+	}
+
+	for start := 0; start < len(removedJourneys); start += writeBatchSize {
+		end := min(start+writeBatchSize, len(removedJourneys))
+
+		operations = append(operations, mongo.NewDeleteManyModel().SetFilter(bson.M{
+			"datasource.datasetid": datasource.DatasetID,
+			"journeyref":           bson.M{"$in": removedJourneys[start:end]},
+		}))
+	}
+
+	if len(operations) > 0 {
+		if _, err := stopDeparturesCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(true)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Str("dataset", datasource.DatasetID).
+		Int("updated", updatedJourneys).
+		Int("removed", len(removedJourneys)).
+		Int("unchanged", len(seenJourneys)-updatedJourneys).
+		Msg("Materialised stop departures")
+
+	return nil
+}
+
+// GenerateForDataset materialises the stop departures of an already imported dataset, taking the data source from
+// one of its journeys
+func GenerateForDataset(datasetID string) error {
+	journeysCollection := database.GetCollection("journeys")
+
+	var journey ctdf.Journey
+	err := journeysCollection.FindOne(context.Background(), bson.M{"datasource.datasetid": datasetID}).Decode(&journey)
+	if err != nil {
+		return errors.New(fmt.Sprintf("No journeys found for dataset %s", datasetID))
+	}
+
+	return Generate(journey.DataSource, nil)
+}
+
+// getMaterialisedJourneyHashes returns the journey hash each journey matching the filter was last materialised with
+func getMaterialisedJourneyHashes(filter bson.M) (map[string]string, error) {
+	stopDeparturesCollection := database.GetCollection("stop_departures")
+
+	cursor, err := stopDeparturesCollection.Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$match", Value: filter}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":         "$journeyref",
+			"journeyhash": bson.M{"$first": "$journeyhash"},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	hashes := map[string]string{}
+	for cursor.Next(context.Background()) {
+		var result struct {
+			JourneyRef  string `bson:"_id"`
+			JourneyHash string `bson:"journeyhash"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+
+		hashes[result.JourneyRef] = result.JourneyHash
+	}
+
+	return hashes, cursor.Err()
+}
+
+func getStopDepartures(journey *ctdf.Journey, journeyHash string, datasource *ctdf.DataSourceReference, now time.Time) []*ctdf.StopDeparture {
+	if journey.Availability == nil {
+		return nil
+	}
+
+	// Nothing to depart if the timetable has already run out
+	if !journey.ValidUntil.IsZero() && journey.ValidUntil.Format(ctdf.YearMonthDayFormat) < now.Format(ctdf.YearMonthDayFormat) {
+		return nil
+	}
+
+	var stopDepartures []*ctdf.StopDeparture
+
+	for _, dayType := range journey.Availability.PossibleDaysOfWeek() {
+		for i, pathItem := range journey.Path {
+			stopDepartures = append(stopDepartures, &ctdf.StopDeparture{
+				PrimaryIdentifier:    fmt.Sprintf(ctdf.StopDepartureIDFormat, journey.PrimaryIdentifier, i, dayType),
+				StopRef:              pathItem.OriginStopRef,
+				DayType:              dayType,
+				MinuteOfDay:          pathItem.OriginDepartureDayOffset*24*60 + pathItem.OriginDepartureTime.Hour()*60 + pathItem.OriginDepartureTime.Minute(),
+				JourneyRef:           journey.PrimaryIdentifier,
+				ServiceRef:           journey.ServiceRef,
+				OperatorRef:          journey.OperatorRef,
+				JourneyHash:          journeyHash,
+				ModificationDateTime: now,
+				DataSource:           datasource,
+			})
+		}
+	}
+
+	return stopDepartures
+}
@@ -0,0 +1,57 @@
+package stopdepartures_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/stopdepartures"
+	"github.com/travigo/travigo/pkg/testsupport"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newTestJourney(primaryIdentifier string, datasource *ctdf.DataSourceReference, validUntil time.Time) *ctdf.Journey {
+	return &ctdf.Journey{
+		PrimaryIdentifier: primaryIdentifier,
+		ServiceRef:        "test-service",
+		OperatorRef:       "test-operator",
+		Availability: &ctdf.Availability{
+			Match: []ctdf.AvailabilityRule{{Type: ctdf.AvailabilityDayOfWeek, Value: "Monday"}},
+		},
+		ValidFrom:  time.Now().AddDate(-1, 0, 0),
+		ValidUntil: validUntil,
+		Path: []*ctdf.JourneyPathItem{
+			{
+				OriginStopRef:       "test-stop-A",
+				OriginDepartureTime: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),
+				DestinationStopRef:  "test-stop-B",
+			},
+		},
+		DataSource:           datasource,
+		CreationDateTime:     time.Now(),
+		ModificationDateTime: time.Now(),
+	}
+}
+
+func TestGenerateSkipsExpiredJourneys(t *testing.T) {
+	testsupport.Start(t)
+
+	datasource := &ctdf.DataSourceReference{DatasetID: "test-stop-departures"}
+
+	journeys := []interface{}{
+		newTestJourney("test-journey-expired", datasource, time.Now().AddDate(0, 0, -7)),
+		newTestJourney("test-journey-valid", datasource, time.Now().AddDate(0, 1, 0)),
+	}
+	if _, err := database.GetCollection("journeys").InsertMany(context.Background(), journeys); err != nil {
+		t.Fatalf("Failed to insert journeys: %s", err)
+	}
+
+	if err := stopdepartures.Generate(datasource, nil); err != nil {
+		t.Fatalf("Failed to generate stop departures: %s", err)
+	}
+
+	testsupport.AssertCount(t, "stop_departures", bson.M{"journeyref": "test-journey-expired"}, 0)
+	testsupport.AssertCount(t, "stop_departures", bson.M{"journeyref": "test-journey-valid"}, 1)
+}
@@ -0,0 +1,139 @@
+package stopdepartures
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// project keeps only the projected fields of the document the same way Mongo does, including through arrays
+func project(t *testing.T, journey *ctdf.Journey, projection bson.D) *ctdf.Journey {
+	t.Helper()
+
+	encoded, err := bson.Marshal(journey)
+	if err != nil {
+		t.Fatalf("Failed to encode journey: %s", err)
+	}
+	var document bson.M
+	if err := bson.Unmarshal(encoded, &document); err != nil {
+		t.Fatalf("Failed to decode journey document: %s", err)
+	}
+
+	projected := bson.M{}
+	for _, field := range projection {
+		projectField(document, projected, strings.Split(field.Key, "."))
+	}
+
+	encoded, err = bson.Marshal(projected)
+	if err != nil {
+		t.Fatalf("Failed to encode projected journey: %s", err)
+	}
+	var projectedJourney ctdf.Journey
+	if err := bson.Unmarshal(encoded, &projectedJourney); err != nil {
+		t.Fatalf("Failed to decode projected journey: %s", err)
+	}
+
+	return &projectedJourney
+}
+
+func projectField(from bson.M, to bson.M, path []string) {
+	value, exists := from[path[0]]
+	if !exists {
+		return
+	}
+	if len(path) == 1 {
+		to[path[0]] = value
+		return
+	}
+
+	switch value := value.(type) {
+	case bson.M:
+		projectedValue, _ := to[path[0]].(bson.M)
+		if projectedValue == nil {
+			projectedValue = bson.M{}
+		}
+		projectField(value, projectedValue, path[1:])
+		to[path[0]] = projectedValue
+	case bson.A:
+		projectedValue, _ := to[path[0]].(bson.A)
+		if projectedValue == nil {
+			projectedValue = make(bson.A, len(value))
+		}
+		for i, item := range value {
+			itemDocument, ok := item.(bson.M)
+			if !ok {
+				continue
+			}
+			projectedItem, _ := projectedValue[i].(bson.M)
+			if projectedItem == nil {
+				projectedItem = bson.M{}
+			}
+			projectField(itemDocument, projectedItem, path[1:])
+			projectedValue[i] = projectedItem
+		}
+		to[path[0]] = projectedValue
+	}
+}
+
+func TestOvernightJourneyStopDepartures(t *testing.T) {
+	journey := &ctdf.Journey{
+		PrimaryIdentifier: "test-journey-overnight",
+		ServiceRef:        "test-service",
+		OperatorRef:       "test-operator",
+		Availability: &ctdf.Availability{
+			Match: []ctdf.AvailabilityRule{{Type: ctdf.AvailabilityDayOfWeek, Value: "Friday"}},
+		},
+		Path: []*ctdf.JourneyPathItem{
+			{
+				OriginStopRef:       "test-stop-A",
+				OriginDepartureTime: time.Date(0, 1, 1, 23, 50, 0, 0, time.UTC),
+				DestinationStopRef:  "test-stop-B",
+			},
+			{
+				OriginStopRef:            "test-stop-B",
+				OriginDepartureTime:      time.Date(0, 1, 1, 0, 10, 0, 0, time.UTC),
+				OriginDepartureDayOffset: 1,
+				DestinationStopRef:       "test-stop-C",
+			},
+		},
+	}
+
+	stopDepartures := getStopDepartures(project(t, journey, journeyProjection), "hash", &ctdf.DataSourceReference{}, time.Now())
+
+	if len(stopDepartures) != 2 {
+		t.Fatalf("Expected 2 stop departures but got %d", len(stopDepartures))
+	}
+	if stopDepartures[0].MinuteOfDay != 23*60+50 {
+		t.Errorf("Expected the first departure at minute %d but got %d", 23*60+50, stopDepartures[0].MinuteOfDay)
+	}
+	// Still counted from the start of the service day so it sorts after the 23:50
+	if stopDepartures[1].MinuteOfDay != 24*60+10 {
+		t.Errorf("Expected the after midnight departure at minute %d but got %d", 24*60+10, stopDepartures[1].MinuteOfDay)
+	}
+}
+
+func TestExpiredJourneyStopDepartures(t *testing.T) {
+	journey := &ctdf.Journey{
+		PrimaryIdentifier: "test-journey-expired",
+		Availability: &ctdf.Availability{
+			Match: []ctdf.AvailabilityRule{{Type: ctdf.AvailabilityDayOfWeek, Value: "Monday"}},
+		},
+		ValidUntil: time.Now().AddDate(0, 0, -7),
+		Path: []*ctdf.JourneyPathItem{
+			{
+				OriginStopRef:       "test-stop-A",
+				OriginDepartureTime: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),
+				DestinationStopRef:  "test-stop-B",
+			},
+		},
+	}
+
+	stopDepartures := getStopDepartures(project(t, journey, journeyProjection), "hash", &ctdf.DataSourceReference{}, time.Now())
+
+	if len(stopDepartures) != 0 {
+		t.Errorf("Expected no stop departures for an expired journey but got %d", len(stopDepartures))
+	}
+}
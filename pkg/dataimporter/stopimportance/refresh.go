@@ -0,0 +1,162 @@
+package stopimportance
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const writeBatchSize = 1000
+
+type stopRefSummary struct {
+	StopRef           string `bson:"_id"`
+	ServiceRefs       []string
+	WeekdayDepartures int
+}
+
+type stopRecord struct {
+	PrimaryIdentifier string
+	OtherIdentifiers  []string
+	TransportTypes    []ctdf.TransportType
+	StopType          ctdf.StopType
+	Platforms         []*ctdf.StopPlatform
+	Importance        *ctdf.StopImportance
+}
+
+// Refresh recalculates the importance of every stop from the service stop summaries.
+// It has to run after the stops linker as that rebuilds the stops collection from the raw stops.
+func Refresh() error {
+	summaries, err := getStopRefSummaries()
+	if err != nil {
+		return err
+	}
+
+	stopsCollection := database.GetCollection("stops")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "transporttypes", Value: 1},
+		{Key: "stoptype", Value: 1},
+		{Key: "platforms.primaryidentifier", Value: 1},
+		{Key: "importance", Value: 1},
+	})
+	cursor, err := stopsCollection.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+
+	var operations []mongo.WriteModel
+	updated := 0
+
+	for cursor.Next(context.Background()) {
+		var record stopRecord
+		if err := cursor.Decode(&record); err != nil {
+			log.Error().Err(err).Msg("Failed to decode stop")
+			continue
+		}
+
+		// Journeys reference a stop by any of its identifiers so the summaries are spread across all of them
+		serviceRefs := map[string]bool{}
+		weekdayDepartures := 0
+		seenIdentifiers := map[string]bool{}
+		for _, identifier := range append(record.OtherIdentifiers, record.PrimaryIdentifier) {
+			// Stop identifiers often repeat the primary identifier so don't count it twice
+			summary := summaries[identifier]
+			if summary == nil || seenIdentifiers[identifier] {
+				continue
+			}
+			seenIdentifiers[identifier] = true
+
+			for _, serviceRef := range summary.ServiceRefs {
+				serviceRefs[serviceRef] = true
+			}
+			weekdayDepartures += summary.WeekdayDepartures
+		}
+
+		importance := ctdf.CalculateStopImportance(&ctdf.Stop{
+			TransportTypes: record.TransportTypes,
+			StopType:       record.StopType,
+			Platforms:      record.Platforms,
+		}, len(serviceRefs), weekdayDepartures)
+
+		if record.Importance != nil &&
+			record.Importance.Class == importance.Class &&
+			record.Importance.Services == importance.Services &&
+			record.Importance.WeekdayDepartures == importance.WeekdayDepartures &&
+			record.Importance.Score == importance.Score {
+			continue
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": record.PrimaryIdentifier}).
+			SetUpdate(bson.M{"$set": bson.M{"importance": importance}}),
+		)
+		updated += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := stopsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := stopsCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("updated", updated).Msg("Refreshed stop importance")
+
+	return nil
+}
+
+// getStopRefSummaries totals up the services & weekday departures at each stop reference
+func getStopRefSummaries() (map[string]*stopRefSummary, error) {
+	summariesCollection := database.GetCollection("service_stop_summaries")
+
+	cursor, err := summariesCollection.Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$project", Value: bson.M{
+			"stopref":    1,
+			"serviceref": 1,
+			"weekdaydepartures": bson.M{"$sum": bson.M{"$map": bson.M{
+				"input": bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$daytypes", bson.A{}}},
+					"as":    "daytype",
+					"cond":  bson.M{"$eq": bson.A{"$$daytype.daytype", ctdf.ServiceStopSummaryDayTypeWeekday}},
+				}},
+				"as": "daytype",
+				"in": "$$daytype.numberdepartures",
+			}}},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":               "$stopref",
+			"servicerefs":       bson.M{"$addToSet": "$serviceref"},
+			"weekdaydepartures": bson.M{"$sum": "$weekdaydepartures"},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := map[string]*stopRefSummary{}
+	for cursor.Next(context.Background()) {
+		var summary stopRefSummary
+		if err := cursor.Decode(&summary); err != nil {
+			continue
+		}
+
+		summaries[summary.StopRef] = &summary
+	}
+
+	log.Info().Int("stops", len(summaries)).Msg("Loaded service stop summaries")
+
+	return summaries, nil
+}
@@ -0,0 +1,165 @@
+package walkingtransfers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Number of destinations sent in a single OSRM table request, the default server limit is 100 coordinates
+const osrmMaximumDestinations = 99
+
+type walkRoute struct {
+	Walk *walk
+
+	// Metres
+	Distance float64
+	// Seconds
+	Duration float64
+}
+
+type walkingRouter interface {
+	Route(walks []*walk) []walkRoute
+}
+
+// straightLineRouter assumes the walk is the straight line distance between the 2 points
+type straightLineRouter struct {
+	WalkingSpeed float64
+}
+
+func (r straightLineRouter) Route(walks []*walk) []walkRoute {
+	var routes []walkRoute
+
+	for _, currentWalk := range walks {
+		routes = append(routes, r.route(currentWalk))
+	}
+
+	return routes
+}
+
+func (r straightLineRouter) route(currentWalk *walk) walkRoute {
+	distance := currentWalk.From.GreatCircleDistance(currentWalk.To)
+
+	return walkRoute{
+		Walk:     currentWalk,
+		Distance: distance,
+		Duration: distance / r.WalkingSpeed,
+	}
+}
+
+// osrmRouter uses the table service of an OSRM server with the foot profile to follow the actual paths between the points.
+// Walks it can't route, eg. platforms inside a station the map doesn't have paths for, use the fallback instead.
+type osrmRouter struct {
+	URL      string
+	Fallback straightLineRouter
+
+	client *http.Client
+}
+
+type osrmTableResponse struct {
+	Code      string
+	Message   string
+	Durations [][]*float64
+	Distances [][]*float64
+}
+
+func (r *osrmRouter) Route(walks []*walk) []walkRoute {
+	r.client = &http.Client{Timeout: 30 * time.Second}
+
+	// Group the walks by where they start so each origin is a single request
+	var origins []string
+	originWalks := map[string][]*walk{}
+	for _, currentWalk := range walks {
+		if originWalks[currentWalk.FromStopRef] == nil {
+			origins = append(origins, currentWalk.FromStopRef)
+		}
+		originWalks[currentWalk.FromStopRef] = append(originWalks[currentWalk.FromStopRef], currentWalk)
+	}
+
+	var routes []walkRoute
+	failed := 0
+
+	for _, origin := range origins {
+		walks := originWalks[origin]
+
+		for lower := 0; lower < len(walks); lower += osrmMaximumDestinations {
+			upper := min(lower+osrmMaximumDestinations, len(walks))
+			batch := walks[lower:upper]
+
+			batchRoutes, err := r.routeFromOrigin(batch)
+			if err != nil {
+				failed += 1
+				log.Debug().Err(err).Str("origin", origin).Msg("Failed to route walks with OSRM")
+
+				batchRoutes = r.Fallback.Route(batch)
+			}
+
+			routes = append(routes, batchRoutes...)
+		}
+	}
+
+	if failed > 0 {
+		log.Warn().Int("failed", failed).Msg("Some walks couldn't be routed with OSRM and used straight line distances")
+	}
+
+	return routes
+}
+
+// routeFromOrigin routes a batch of walks that all start at the same point
+func (r *osrmRouter) routeFromOrigin(walks []*walk) ([]walkRoute, error) {
+	coordinates := []string{formatCoordinates(walks[0].From.Coordinates)}
+	for _, currentWalk := range walks {
+		coordinates = append(coordinates, formatCoordinates(currentWalk.To.Coordinates))
+	}
+
+	requestURL := fmt.Sprintf(
+		"%s/table/v1/foot/%s?sources=0&annotations=duration,distance",
+		strings.TrimSuffix(r.URL, "/"), strings.Join(coordinates, ";"),
+	)
+
+	resp, err := r.client.Get(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var table osrmTableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&table); err != nil {
+		return nil, err
+	}
+
+	if table.Code != "Ok" {
+		return nil, errors.New(fmt.Sprintf("OSRM returned %s: %s", table.Code, table.Message))
+	}
+	if len(table.Durations) != 1 || len(table.Distances) != 1 || len(table.Durations[0]) != len(coordinates) || len(table.Distances[0]) != len(coordinates) {
+		return nil, errors.New("OSRM returned a table of the wrong size")
+	}
+
+	var routes []walkRoute
+	for i, currentWalk := range walks {
+		duration := table.Durations[0][i+1]
+		distance := table.Distances[0][i+1]
+
+		if duration == nil || distance == nil {
+			routes = append(routes, r.Fallback.route(currentWalk))
+			continue
+		}
+
+		routes = append(routes, walkRoute{
+			Walk:     currentWalk,
+			Distance: *distance,
+			Duration: *duration,
+		})
+	}
+
+	return routes, nil
+}
+
+func formatCoordinates(coordinates []float64) string {
+	return fmt.Sprintf("%f,%f", coordinates[0], coordinates[1])
+}
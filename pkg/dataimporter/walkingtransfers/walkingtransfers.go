@@ -0,0 +1,323 @@
+package walkingtransfers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DatasetID = "travigo-walkingtransfers"
+
+	DefaultMaximumDistance = 250 // metres
+
+	// Stations with more platforms than this are skipped as every pair of them would be generated
+	maximumPlatforms = 50
+	writeBatchSize   = 1000
+
+	metresPerDegreeLatitude = 111320
+)
+
+type Options struct {
+	// Walking speed in metres per second, defaults to ctdf.TransferWalkingSpeed
+	WalkingSpeed float64
+	// Straight line distance in metres that separate stops are linked within, defaults to DefaultMaximumDistance
+	MaximumDistance float64
+
+	// Base URL of an OSRM server running the foot profile, straight line distances are used when it isn't set
+	OSRMURL string
+}
+
+type stopRecord struct {
+	PrimaryIdentifier string
+	Location          *ctdf.Location
+	Platforms         []*ctdf.StopPlatform
+}
+
+type walk struct {
+	From *ctdf.Location
+	To   *ctdf.Location
+
+	FromStopRef string
+	ToStopRef   string
+}
+
+// Generate works out the walking transfers between the platforms of each station and between separate stops near each other.
+// It has to run after the stops linker as the transfers reference the linked stops.
+func Generate(opts Options) error {
+	if opts.WalkingSpeed <= 0 {
+		opts.WalkingSpeed = ctdf.TransferWalkingSpeed
+	}
+	if opts.MaximumDistance <= 0 {
+		opts.MaximumDistance = DefaultMaximumDistance
+	}
+
+	stops, err := getStops()
+	if err != nil {
+		return err
+	}
+
+	// Transfers published by a dataset know better than an estimate so those pairs are left alone
+	publishedTransfers, err := getPublishedTransfers()
+	if err != nil {
+		return err
+	}
+
+	var platformWalks []*walk
+	for _, stop := range stops {
+		platformWalks = append(platformWalks, getPlatformWalks(stop)...)
+	}
+	nearbyStopWalks := getNearbyStopWalks(stops, opts.MaximumDistance)
+
+	var walks []*walk
+	for _, currentWalk := range append(platformWalks, nearbyStopWalks...) {
+		if !publishedTransfers[transferKey(currentWalk.FromStopRef, currentWalk.ToStopRef)] {
+			walks = append(walks, currentWalk)
+		}
+	}
+
+	log.Info().
+		Int("platforms", len(platformWalks)).
+		Int("nearby", len(nearbyStopWalks)).
+		Int("published", len(platformWalks)+len(nearbyStopWalks)-len(walks)).
+		Msg("Found walking transfers")
+
+	straightLine := straightLineRouter{WalkingSpeed: opts.WalkingSpeed}
+
+	var router walkingRouter = straightLine
+	if opts.OSRMURL != "" {
+		router = &osrmRouter{URL: opts.OSRMURL, Fallback: straightLine}
+	}
+
+	datasource := &ctdf.DataSourceReference{
+		OriginalFormat: DatasetID,
+		ProviderName:   "Travigo",
+		DatasetID:      DatasetID,
+		Timestamp:      fmt.Sprintf("%d", time.Now().Unix()),
+	}
+
+	transfersCollection := database.GetCollection("transfers")
+	var operations []mongo.WriteModel
+	inserted := 0
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+
+		_, err := transfersCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+		operations = []mongo.WriteModel{}
+
+		return err
+	}
+
+	for _, walkRoutes := range router.Route(walks) {
+		currentWalk := walkRoutes.Walk
+		now := time.Now()
+		transferID := fmt.Sprintf(ctdf.TransferIDFormat, DatasetID, currentWalk.FromStopRef, currentWalk.ToStopRef)
+
+		transfer := &ctdf.Transfer{
+			PrimaryIdentifier:    transferID,
+			CreationDateTime:     now,
+			ModificationDateTime: now,
+			DataSource:           datasource,
+			FromStopRef:          currentWalk.FromStopRef,
+			ToStopRef:            currentWalk.ToStopRef,
+			Type:                 ctdf.TransferTypeMinimumTime,
+			MinimumTransferTime:  ctdf.RoundTransferTime(walkRoutes.Duration),
+			WalkingDistance:      int(math.Round(walkRoutes.Distance)),
+			Inferred:             true,
+		}
+
+		bsonRep, _ := bson.Marshal(bson.M{"$set": transfer})
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": transferID}).
+			SetUpdate(bsonRep).
+			SetUpsert(true),
+		)
+		inserted += 1
+
+		if len(operations) >= writeBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	deleted, err := transfersCollection.DeleteMany(context.Background(), bson.M{
+		"datasource.datasetid": DatasetID,
+		"datasource.timestamp": bson.M{"$ne": datasource.Timestamp},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("inserted", inserted).Int64("deleted", deleted.DeletedCount).Msg("Generated walking transfers")
+
+	return nil
+}
+
+func getStops() ([]*stopRecord, error) {
+	stopsCollection := database.GetCollection("stops")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "location", Value: 1},
+		{Key: "platforms.primaryidentifier", Value: 1},
+		{Key: "platforms.location", Value: 1},
+	})
+	cursor, err := stopsCollection.Find(context.Background(), bson.M{"active": true, "location": bson.M{"$ne": nil}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var stops []*stopRecord
+	for cursor.Next(context.Background()) {
+		var stop stopRecord
+		if err := cursor.Decode(&stop); err != nil {
+			log.Error().Err(err).Msg("Failed to decode stop")
+			continue
+		}
+
+		if !hasCoordinates(stop.Location) {
+			continue
+		}
+
+		stops = append(stops, &stop)
+	}
+
+	return stops, nil
+}
+
+func getPublishedTransfers() (map[string]bool, error) {
+	transfersCollection := database.GetCollection("transfers")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "fromstopref", Value: 1},
+		{Key: "tostopref", Value: 1},
+	})
+	cursor, err := transfersCollection.Find(context.Background(), bson.M{"datasource.datasetid": bson.M{"$ne": DatasetID}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	publishedTransfers := map[string]bool{}
+	for cursor.Next(context.Background()) {
+		var transfer ctdf.Transfer
+		if err := cursor.Decode(&transfer); err != nil {
+			continue
+		}
+
+		publishedTransfers[transferKey(transfer.FromStopRef, transfer.ToStopRef)] = true
+	}
+
+	return publishedTransfers, nil
+}
+
+// getPlatformWalks links every platform of a station to every other one
+func getPlatformWalks(stop *stopRecord) []*walk {
+	if len(stop.Platforms) < 2 || len(stop.Platforms) > maximumPlatforms {
+		return nil
+	}
+
+	var walks []*walk
+	for _, from := range stop.Platforms {
+		for _, to := range stop.Platforms {
+			if from.PrimaryIdentifier == to.PrimaryIdentifier || !hasCoordinates(from.Location) || !hasCoordinates(to.Location) {
+				continue
+			}
+
+			walks = append(walks, &walk{
+				From:        from.Location,
+				To:          to.Location,
+				FromStopRef: from.PrimaryIdentifier,
+				ToStopRef:   to.PrimaryIdentifier,
+			})
+		}
+	}
+
+	return walks
+}
+
+// getNearbyStopWalks links stops within the maximum distance of each other.
+// Stops are bucketed into a grid with cells at least the maximum distance wide so only the neighbouring cells need checking,
+// each row of the grid has its own cell width as a degree of longitude gets shorter away from the equator.
+func getNearbyStopWalks(stops []*stopRecord, maximumDistance float64) []*walk {
+	rowHeight := maximumDistance / metresPerDegreeLatitude
+
+	type cell struct {
+		Row    int
+		Column int
+	}
+
+	getRow := func(location *ctdf.Location) int {
+		return int(math.Floor(location.Coordinates[1] / rowHeight))
+	}
+	getColumnWidth := func(row int) float64 {
+		// Use the latitude a row further towards the pole than this one so stops in the neighbouring rows are covered too
+		latitude := math.Min((math.Max(math.Abs(float64(row)), math.Abs(float64(row+1)))+1)*rowHeight, 89)
+
+		return rowHeight / math.Cos(latitude*math.Pi/180)
+	}
+	getColumn := func(location *ctdf.Location, row int) int {
+		return int(math.Floor(location.Coordinates[0] / getColumnWidth(row)))
+	}
+
+	grid := map[cell][]*stopRecord{}
+	for _, stop := range stops {
+		row := getRow(stop.Location)
+		key := cell{Row: row, Column: getColumn(stop.Location, row)}
+
+		grid[key] = append(grid[key], stop)
+	}
+
+	var walks []*walk
+	for _, stop := range stops {
+		stopRow := getRow(stop.Location)
+
+		for row := stopRow - 1; row <= stopRow+1; row++ {
+			column := getColumn(stop.Location, row)
+
+			for neighbourColumn := column - 1; neighbourColumn <= column+1; neighbourColumn++ {
+				for _, nearbyStop := range grid[cell{Row: row, Column: neighbourColumn}] {
+					if nearbyStop.PrimaryIdentifier == stop.PrimaryIdentifier {
+						continue
+					}
+
+					if stop.Location.GreatCircleDistance(nearbyStop.Location) > maximumDistance {
+						continue
+					}
+
+					walks = append(walks, &walk{
+						From:        stop.Location,
+						To:          nearbyStop.Location,
+						FromStopRef: stop.PrimaryIdentifier,
+						ToStopRef:   nearbyStop.PrimaryIdentifier,
+					})
+				}
+			}
+		}
+	}
+
+	return walks
+}
+
+func hasCoordinates(location *ctdf.Location) bool {
+	return location != nil && len(location.Coordinates) == 2
+}
+
+func transferKey(fromStopRef string, toStopRef string) string {
+	return fmt.Sprintf("%s|%s", fromStopRef, toStopRef)
+}
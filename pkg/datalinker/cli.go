@@ -2,11 +2,17 @@ package datalinker
 
 import (
 	"errors"
+	"os"
 
 	"github.com/travigo/travigo/pkg/dataimporter/insertrecords"
+	"github.com/travigo/travigo/pkg/dataimporter/raillocations"
+	"github.com/travigo/travigo/pkg/dataimporter/stopimportance"
+	"github.com/travigo/travigo/pkg/dataimporter/walkingtransfers"
+	"github.com/travigo/travigo/pkg/identifiers"
 
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/travigo/travigo/pkg/util"
 	"github.com/urfave/cli/v2"
 
 	"github.com/rs/zerolog/log"
@@ -42,7 +48,15 @@ func RegisterCLI() *cli.Command {
 
 					var linker Linker
 
-					if dataType == "stops" {
+					if dataType == "identifiers" {
+						for _, objectType := range identifiers.GetObjectTypes() {
+							if err := identifiers.RebuildTranslations(objectType); err != nil {
+								return err
+							}
+						}
+
+						return nil
+					} else if dataType == "stops" {
 						linker = NewStopsLinker()
 					} else {
 						return errors.New("Unknown type")
@@ -50,9 +64,49 @@ func RegisterCLI() *cli.Command {
 
 					linker.Run()
 
+					// Linking changes which stops own which identifiers so the translations have to follow
+					if err := identifiers.RebuildTranslations(identifiers.ObjectTypeStop); err != nil {
+						return err
+					}
+
+					// The linked stops are rebuilt from the raw stops so lose their importance
+					if dataType == "stops" {
+						if err := stopimportance.Refresh(); err != nil {
+							return err
+						}
+
+						// Rail locations point at the linked stops so have to follow any that were merged
+						if err := raillocations.Link(); err != nil {
+							return err
+						}
+
+						// Linking can merge stops so the walks between them have to be worked out again
+						if err := walkingtransfers.Generate(walkingtransfers.Options{
+							OSRMURL: util.GetEnvironmentVariables()["TRAVIGO_OSRM_URL"],
+						}); err != nil {
+							return err
+						}
+					}
+
 					return nil
 				},
 			},
+			{
+				Name:      "explain",
+				Usage:     "Explain which sources the fields of a linked stop came from",
+				ArgsUsage: "<stop identifier> [field]",
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() < 1 || c.Args().Len() > 2 {
+						return errors.New("Expected arguments <stop identifier> [field]")
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					return ExplainStop(os.Stdout, c.Args().Get(0), c.Args().Get(1))
+				},
+			},
 		},
 	}
 }
@@ -0,0 +1,110 @@
+package datalinker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ExplainStop prints where the fields of a linked stop came from. For merged stops the value every raw record
+// had is listed alongside so conflicting sources can be spotted. All fields are explained when field is empty.
+func ExplainStop(writer io.Writer, identifier string, field string) error {
+	if field != "" && field != stopFieldOtherIdentifiers && getStopField(field) == nil {
+		var fieldNames []string
+		for _, stopField := range stopFields {
+			fieldNames = append(fieldNames, stopField.Name)
+		}
+
+		return errors.New(fmt.Sprintf("Unknown field %s, expected one of %s or %s", field, strings.Join(fieldNames, ", "), stopFieldOtherIdentifiers))
+	}
+
+	var stop *ctdf.Stop
+	database.GetCollection("stops").FindOne(context.Background(), bson.M{
+		"$or": bson.A{
+			bson.M{"primaryidentifier": identifier},
+			bson.M{"otheridentifiers": identifier},
+		},
+	}).Decode(&stop)
+	if stop == nil {
+		return errors.New(fmt.Sprintf("Could not find stop %s", identifier))
+	}
+
+	fmt.Fprintf(writer, "Stop %s\n", stop.PrimaryIdentifier)
+
+	if len(stop.Provenance) == 0 {
+		fmt.Fprintf(writer, "Not merged, every field comes from %s\n", describeProvenanceSource(stop.PrimaryIdentifier, stop.DataSource))
+		return nil
+	}
+
+	// The raw records it was merged from show what every source had for the field
+	var sourceRefs []string
+	for _, provenance := range ctdf.GetFieldProvenance(stop.Provenance, stopFieldOtherIdentifiers) {
+		if provenance.Value == provenance.SourceRef {
+			sourceRefs = append(sourceRefs, provenance.SourceRef)
+		}
+	}
+
+	var rawRecords []*ctdf.Stop
+	cursor, err := database.GetCollection("stops_raw").Find(context.Background(), bson.M{"primaryidentifier": bson.M{"$in": sourceRefs}})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(context.Background(), &rawRecords); err != nil {
+		return err
+	}
+
+	for _, stopField := range stopFields {
+		if field != "" && field != stopField.Name {
+			continue
+		}
+
+		fmt.Fprintf(writer, "\n%s: %s\n", stopField.Name, stopField.Value(stop))
+
+		fieldProvenance := ctdf.GetFieldProvenance(stop.Provenance, stopField.Name)
+		if len(fieldProvenance) == 0 {
+			fmt.Fprintln(writer, "  not set by any source")
+			continue
+		}
+		fmt.Fprintf(writer, "  from %s\n", describeProvenanceSource(fieldProvenance[0].SourceRef, fieldProvenance[0].DataSource))
+		if fieldProvenance[0].Rule != "" {
+			fmt.Fprintf(writer, "  picked by merge policy rule %s\n", fieldProvenance[0].Rule)
+		} else {
+			fmt.Fprintln(writer, "  picked as the oldest record with it set")
+		}
+
+		for _, rawRecord := range rawRecords {
+			if rawRecord.PrimaryIdentifier == fieldProvenance[0].SourceRef {
+				continue
+			}
+
+			value := stopField.Value(rawRecord)
+			if value == "" {
+				value = "(not set)"
+			}
+			fmt.Fprintf(writer, "  %s had %s\n", describeProvenanceSource(rawRecord.PrimaryIdentifier, rawRecord.DataSource), value)
+		}
+	}
+
+	if field == "" || field == stopFieldOtherIdentifiers {
+		fmt.Fprintf(writer, "\n%s:\n", stopFieldOtherIdentifiers)
+		for _, provenance := range ctdf.GetFieldProvenance(stop.Provenance, stopFieldOtherIdentifiers) {
+			fmt.Fprintf(writer, "  %s from %s\n", provenance.Value, describeProvenanceSource(provenance.SourceRef, provenance.DataSource))
+		}
+	}
+
+	return nil
+}
+
+func describeProvenanceSource(sourceRef string, datasource *ctdf.DataSourceReference) string {
+	if datasource == nil {
+		return sourceRef
+	}
+
+	return fmt.Sprintf("%s (%s, %s)", sourceRef, datasource.DatasetID, datasource.ProviderName)
+}
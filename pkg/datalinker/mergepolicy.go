@@ -0,0 +1,169 @@
+package datalinker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/countries"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+const mergePoliciesDirectory = "data/merge-policies/"
+
+// MergePolicy decides which source wins when the records being merged into one object disagree on a field.
+// Without a matching rule the oldest record that has the field set wins.
+type MergePolicy struct {
+	Rules []*MergePolicyRule
+}
+
+// MergePolicyRule orders the sources for some fields, the most specific matching rule is used for each field.
+// A rule for the field beats one for every field & a rule for the country beats one for everywhere.
+type MergePolicyRule struct {
+	Name string
+
+	// Fields the rule applies to, every field when empty
+	Fields []string
+	// Country code the rule is limited to, eg. ie
+	Country string
+
+	// Sources in the order they win, sources not listed come after in age order
+	Precedence []MergePolicySource
+}
+
+type MergePolicySource struct {
+	Format  string
+	Dataset string
+}
+
+func (s MergePolicySource) matches(datasource *ctdf.DataSourceReference) bool {
+	if datasource == nil {
+		return false
+	}
+
+	return (s.Format == "" || s.Format == datasource.OriginalFormat) && (s.Dataset == "" || s.Dataset == datasource.DatasetID)
+}
+
+// LoadMergePolicy reads the policy for an object type, eg. stops. No policy file means no rules.
+func LoadMergePolicy(objectType string) (*MergePolicy, error) {
+	policyYaml, err := os.ReadFile(filepath.Join(mergePoliciesDirectory, fmt.Sprintf("%s.yaml", objectType)))
+	if errors.Is(err, os.ErrNotExist) {
+		return &MergePolicy{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var policy MergePolicy
+	if err := yaml.Unmarshal(policyYaml, &policy); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range policy.Rules {
+		for _, field := range rule.Fields {
+			if getStopField(field) == nil {
+				return nil, errors.New(fmt.Sprintf("Merge policy rule %s has unknown field %s", rule.Name, field))
+			}
+		}
+		if rule.Country != "" {
+			if _, exists := countries.Get(rule.Country); !exists {
+				return nil, errors.New(fmt.Sprintf("Merge policy rule %s has unknown country %s", rule.Name, rule.Country))
+			}
+		}
+		for _, source := range rule.Precedence {
+			if source.Format == "" && source.Dataset == "" {
+				return nil, errors.New(fmt.Sprintf("Merge policy rule %s has a source without a format or dataset", rule.Name))
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// getRule returns the most specific rule for the field of the stops being merged, or nil if none match
+func (p *MergePolicy) getRule(field string, records []ctdf.Stop) *MergePolicyRule {
+	var bestRule *MergePolicyRule
+	bestScore := -1
+
+	for _, rule := range p.Rules {
+		score := 0
+
+		if len(rule.Fields) > 0 {
+			if !util.ContainsString(rule.Fields, field) {
+				continue
+			}
+			score += 2
+		}
+
+		if rule.Country != "" {
+			if !stopsInCountry(records, rule.Country) {
+				continue
+			}
+			score += 1
+		}
+
+		// Earlier rules win ties
+		if score > bestScore {
+			bestRule = rule
+			bestScore = score
+		}
+	}
+
+	return bestRule
+}
+
+// orderRecords returns the records in the order they win for the field along with the rule used.
+// The records must already be sorted oldest first.
+func (p *MergePolicy) orderRecords(field string, records []ctdf.Stop) ([]*ctdf.Stop, *MergePolicyRule) {
+	ordered := make([]*ctdf.Stop, len(records))
+	for i := range records {
+		ordered[i] = &records[i]
+	}
+
+	rule := p.getRule(field, records)
+	if rule == nil {
+		return ordered, nil
+	}
+
+	precedence := func(record *ctdf.Stop) int {
+		for i, source := range rule.Precedence {
+			if source.matches(record.DataSource) {
+				return i
+			}
+		}
+
+		return len(rule.Precedence)
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return precedence(ordered[i]) < precedence(ordered[j])
+	})
+
+	return ordered, rule
+}
+
+// stopsInCountry checks if any of the records were created for the country
+func stopsInCountry(records []ctdf.Stop, countryCode string) bool {
+	profile, exists := countries.Get(countryCode)
+	if !exists {
+		return false
+	}
+
+	for _, record := range records {
+		for _, identifier := range append([]string{record.PrimaryIdentifier}, record.OtherIdentifiers...) {
+			if profile.HasIdentifierPrefix(identifier) {
+				return true
+			}
+		}
+
+		if record.DataSource != nil && strings.HasPrefix(record.DataSource.ProviderID, profile.IdentifierPrefix+"-") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,177 @@
+package datalinker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// stopField is a field of a stop that gets merged from the raw records.
+// The name matches the stored field name so it can be used when explaining a stop.
+type stopField struct {
+	Name  string
+	IsSet func(stop *ctdf.Stop) bool
+	Copy  func(from *ctdf.Stop, to *ctdf.Stop)
+	Value func(stop *ctdf.Stop) string
+}
+
+var stopFields = []stopField{
+	{
+		Name:  "primaryname",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.PrimaryName != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.PrimaryName = from.PrimaryName },
+		Value: func(stop *ctdf.Stop) string { return stop.PrimaryName },
+	},
+	{
+		Name:  "descriptor",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.Descriptor != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.Descriptor = from.Descriptor },
+		Value: func(stop *ctdf.Stop) string { return stop.Descriptor },
+	},
+	{
+		Name:  "nametranslations",
+		IsSet: func(stop *ctdf.Stop) bool { return len(stop.NameTranslations) > 0 },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.NameTranslations = from.NameTranslations },
+		Value: func(stop *ctdf.Stop) string { return fmt.Sprint(map[string]string(stop.NameTranslations)) },
+	},
+	{
+		Name:  "location",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.Location != nil && len(stop.Location.Coordinates) == 2 },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.Location = from.Location },
+		Value: func(stop *ctdf.Stop) string {
+			if stop.Location == nil || len(stop.Location.Coordinates) != 2 {
+				return ""
+			}
+
+			return fmt.Sprintf("%f,%f", stop.Location.Coordinates[1], stop.Location.Coordinates[0])
+		},
+	},
+	{
+		Name:  "transporttypes",
+		IsSet: func(stop *ctdf.Stop) bool { return len(stop.TransportTypes) > 0 },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.TransportTypes = from.TransportTypes },
+		Value: func(stop *ctdf.Stop) string {
+			var transportTypes []string
+			for _, transportType := range stop.TransportTypes {
+				transportTypes = append(transportTypes, string(transportType))
+			}
+
+			return strings.Join(transportTypes, ",")
+		},
+	},
+	{
+		Name:  "timezone",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.Timezone != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.Timezone = from.Timezone },
+		Value: func(stop *ctdf.Stop) string { return stop.Timezone },
+	},
+	{
+		Name:  "localityref",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.LocalityRef != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.LocalityRef = from.LocalityRef },
+		Value: func(stop *ctdf.Stop) string { return stop.LocalityRef },
+	},
+	{
+		Name:  "stoptype",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.StopType != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.StopType = from.StopType },
+		Value: func(stop *ctdf.Stop) string { return string(stop.StopType) },
+	},
+	{
+		Name:  "parentstopref",
+		IsSet: func(stop *ctdf.Stop) bool { return stop.ParentStopRef != "" },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.ParentStopRef = from.ParentStopRef },
+		Value: func(stop *ctdf.Stop) string { return stop.ParentStopRef },
+	},
+	{
+		Name:  "platforms",
+		IsSet: func(stop *ctdf.Stop) bool { return len(stop.Platforms) > 0 },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.Platforms = from.Platforms },
+		Value: func(stop *ctdf.Stop) string {
+			var platforms []string
+			for _, platform := range stop.Platforms {
+				platforms = append(platforms, platform.PrimaryIdentifier)
+			}
+
+			return strings.Join(platforms, ",")
+		},
+	},
+	{
+		Name:  "entrances",
+		IsSet: func(stop *ctdf.Stop) bool { return len(stop.Entrances) > 0 },
+		Copy:  func(from *ctdf.Stop, to *ctdf.Stop) { to.Entrances = from.Entrances },
+		Value: func(stop *ctdf.Stop) string {
+			var entrances []string
+			for _, entrance := range stop.Entrances {
+				entrances = append(entrances, entrance.PrimaryIdentifier)
+			}
+
+			return strings.Join(entrances, ",")
+		},
+	},
+}
+
+// The codes are built up from every record rather than taken from one
+const stopFieldOtherIdentifiers = "otheridentifiers"
+
+func getStopField(name string) *stopField {
+	for i := range stopFields {
+		if stopFields[i].Name == name {
+			return &stopFields[i]
+		}
+	}
+
+	return nil
+}
+
+// mergeStops builds a single stop from the raw records, which must be sorted oldest first.
+// The oldest record is the base & each field is taken from the first record with it set in the order the policy
+// gives for that field, recording where every field & identifier came from.
+func mergeStops(records []ctdf.Stop, policy *MergePolicy) ctdf.Stop {
+	merged := records[0]
+	merged.Provenance = nil
+
+	for _, field := range stopFields {
+		orderedRecords, rule := policy.orderRecords(field.Name, records)
+
+		for _, record := range orderedRecords {
+			if !field.IsSet(record) {
+				continue
+			}
+
+			field.Copy(record, &merged)
+
+			provenance := &ctdf.FieldProvenance{
+				Field:      field.Name,
+				SourceRef:  record.PrimaryIdentifier,
+				DataSource: record.DataSource,
+			}
+			if rule != nil {
+				provenance.Rule = rule.Name
+			}
+			merged.Provenance = append(merged.Provenance, provenance)
+
+			break
+		}
+	}
+
+	seenIdentifiers := map[string]bool{}
+	for _, record := range records {
+		for _, identifier := range append([]string{record.PrimaryIdentifier}, record.OtherIdentifiers...) {
+			if seenIdentifiers[identifier] {
+				continue
+			}
+			seenIdentifiers[identifier] = true
+
+			merged.Provenance = append(merged.Provenance, &ctdf.FieldProvenance{
+				Field:      stopFieldOtherIdentifiers,
+				Value:      identifier,
+				SourceRef:  record.PrimaryIdentifier,
+				DataSource: record.DataSource,
+			})
+		}
+	}
+
+	return merged
+}
@@ -36,6 +36,11 @@ func (l StopsLinker) Run() {
 	rawCollection := database.GetCollection(rawCollectionName)
 	stagingCollection := database.GetCollection(stagingCollectionName)
 
+	policy, err := LoadMergePolicy(liveCollectionName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load stops merge policy")
+	}
+
 	copyCollection(rawCollectionName, stagingCollectionName)
 
 	// A location based aggregation
@@ -202,18 +207,19 @@ func (l StopsLinker) Run() {
 			operations = append(operations, deleteModel)
 		}
 
-		// Sort them by creation date and for now we'll just base it on the oldest record
-		// TODO some actual deep merging
+		// Sort them by creation date, the oldest record is the base & the policy picks which record each field comes from
 		sort.SliceStable(primaryRecords, func(i, j int) bool {
 			return primaryRecords[i].CreationDateTime.Before(primaryRecords[j].CreationDateTime)
 		})
 
-		// Create new record
-		newRecord := primaryRecords[0]
-
-		// Generate an ID for the record
+		// Generate an ID for the record from the oldest one so it stays the same however the fields get merged
 		idHasher := sha256.New()
-		newRecord.GenerateDeterministicID(idHasher)
+		primaryRecords[0].GenerateDeterministicID(idHasher)
+
+		// Create new record
+		newRecord := mergeStops(primaryRecords, policy)
+		// The location may have come from a different record to the base so the code is worked out again
+		newRecord.UpdatePlusCode()
 
 		idHash := fmt.Sprintf("%x", idHasher.Sum(nil))[:28]
 		newRecord.PrimaryIdentifier = fmt.Sprintf("tmr-stop-%s", idHash)
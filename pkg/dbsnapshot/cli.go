@@ -0,0 +1,120 @@
+package dbsnapshot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "snapshot",
+		Usage: "Export & restore portable snapshots of the CTDF database",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "export the CTDF collections to a snapshot archive",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "output",
+						Usage:    "Path of the snapshot archive to write",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Document format inside the archive (bson or ndjson)",
+						Value: string(FormatBSON),
+					},
+					&cli.StringSliceFlag{
+						Name:  "dataset",
+						Usage: "Only include objects from these datasets",
+					},
+					&cli.StringSliceFlag{
+						Name:  "region",
+						Usage: "Only include objects from datasets in these regions (eg. gb)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "collection",
+						Usage: "Only include these collections",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					collections := Collections
+					if len(c.StringSlice("collection")) > 0 {
+						collections = c.StringSlice("collection")
+
+						for _, collection := range collections {
+							if !isSnapshotCollection(collection) {
+								return errors.New(fmt.Sprintf("%s is not a CTDF collection", collection))
+							}
+						}
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					filter := Filter{
+						Datasets: c.StringSlice("dataset"),
+						Regions:  c.StringSlice("region"),
+					}
+
+					err := Export(c.String("output"), Format(c.String("format")), filter, collections)
+					if err != nil {
+						return err
+					}
+
+					log.Info().Str("output", c.String("output")).Msg("Snapshot exported")
+
+					return nil
+				},
+			},
+			{
+				Name:  "restore",
+				Usage: "restore a snapshot archive into the database",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "input",
+						Usage:    "Path of the snapshot archive to restore",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "clean",
+						Usage: "Remove existing documents covered by the snapshot before restoring",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					manifest, err := Restore(c.String("input"), c.Bool("clean"))
+					if err != nil {
+						return err
+					}
+
+					log.Info().
+						Int("version", manifest.Version).
+						Time("created", manifest.CreationDateTime).
+						Int("collections", len(manifest.Collections)).
+						Msg("Snapshot restored")
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func isSnapshotCollection(collectionName string) bool {
+	for _, collection := range Collections {
+		if collection == collectionName {
+			return true
+		}
+	}
+
+	return false
+}
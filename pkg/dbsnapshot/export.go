@@ -0,0 +1,112 @@
+package dbsnapshot
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Export writes the CTDF collections matching the filter into a compressed zip archive at outputPath
+func Export(outputPath string, format Format, filter Filter, collections []string) error {
+	if format != FormatBSON && format != FormatNDJSON {
+		return errors.New(fmt.Sprintf("Unsupported snapshot format %s", format))
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	manifest := &Manifest{
+		Version:          Version,
+		Format:           format,
+		CreationDateTime: time.Now(),
+		Filter:           filter,
+	}
+
+	for _, collectionName := range collections {
+		query := filter.Query(collectionName)
+		if query == nil {
+			log.Info().Str("collection", collectionName).Msg("Skipping collection that cant be filtered")
+			continue
+		}
+
+		manifestCollection := &ManifestCollection{
+			Name: collectionName,
+			File: getCollectionFileName(collectionName, format),
+		}
+
+		writer, err := archive.Create(manifestCollection.File)
+		if err != nil {
+			return err
+		}
+
+		manifestCollection.Documents, err = exportCollection(writer, collectionName, format, query)
+		if err != nil {
+			return err
+		}
+
+		manifest.Collections = append(manifest.Collections, manifestCollection)
+
+		log.Info().Str("collection", collectionName).Int64("documents", manifestCollection.Documents).Msg("Exported collection")
+	}
+
+	manifestWriter, err := archive.Create(manifestFileName)
+	if err != nil {
+		return err
+	}
+
+	manifestEncoder := json.NewEncoder(manifestWriter)
+	manifestEncoder.SetIndent("", "  ")
+	if err := manifestEncoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func exportCollection(writer io.Writer, collectionName string, format Format, query bson.M) (int64, error) {
+	collection := database.GetCollection(collectionName)
+
+	cursor, err := collection.Find(context.Background(), query)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var documents int64
+	for cursor.Next(context.Background()) {
+		switch format {
+		case FormatBSON:
+			if _, err := writer.Write(cursor.Current); err != nil {
+				return documents, err
+			}
+		case FormatNDJSON:
+			// Canonical extended JSON keeps the BSON types intact for the restore
+			document, err := bson.MarshalExtJSON(cursor.Current, true, false)
+			if err != nil {
+				return documents, err
+			}
+
+			if _, err := writer.Write(append(document, '\n')); err != nil {
+				return documents, err
+			}
+		}
+
+		documents += 1
+	}
+
+	return documents, cursor.Err()
+}
@@ -0,0 +1,182 @@
+package dbsnapshot
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const restoreBatchSize = 1000
+
+// Restore loads a snapshot archive back into the database. Documents are upserted by _id so a restore can be re-run,
+// and if clean is set any existing documents matching the snapshots filter are removed first
+func Restore(inputPath string, clean bool) (*Manifest, error) {
+	archive, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifest, err := readManifest(files[manifestFileName])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifestCollection := range manifest.Collections {
+		file := files[manifestCollection.File]
+		if file == nil {
+			return manifest, errors.New(fmt.Sprintf("Snapshot is missing %s", manifestCollection.File))
+		}
+
+		collection := database.GetCollection(manifestCollection.Name)
+
+		if clean {
+			result, err := collection.DeleteMany(context.Background(), manifest.Filter.Query(manifestCollection.Name))
+			if err != nil {
+				return manifest, err
+			}
+
+			log.Info().Str("collection", manifestCollection.Name).Int64("deleted", result.DeletedCount).Msg("Cleaned collection")
+		}
+
+		restored, err := restoreCollection(file, collection, manifest.Format)
+		if err != nil {
+			return manifest, err
+		}
+
+		log.Info().Str("collection", manifestCollection.Name).Int64("documents", restored).Msg("Restored collection")
+	}
+
+	return manifest, nil
+}
+
+func readManifest(file *zip.File) (*Manifest, error) {
+	if file == nil {
+		return nil, errors.New("Snapshot has no manifest")
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var manifest *Manifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, err
+	}
+
+	if manifest.Version > Version {
+		return nil, errors.New(fmt.Sprintf("Snapshot version %d is newer than the supported version %d", manifest.Version, Version))
+	}
+
+	return manifest, nil
+}
+
+func restoreCollection(file *zip.File, collection *mongo.Collection, format Format) (int64, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	var restored int64
+	var operations []mongo.WriteModel
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+
+		_, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+		restored += int64(len(operations))
+		operations = nil
+
+		return err
+	}
+
+	nextDocument := getDocumentReader(reader, format)
+	for {
+		document, err := nextDocument()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return restored, err
+		}
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": document.Lookup("_id")}).
+			SetReplacement(document).
+			SetUpsert(true),
+		)
+
+		if len(operations) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return restored, err
+			}
+		}
+	}
+
+	return restored, flush()
+}
+
+func getDocumentReader(reader io.Reader, format Format) func() (bson.Raw, error) {
+	switch format {
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+		return func() (bson.Raw, error) {
+			if !scanner.Scan() {
+				if scanner.Err() != nil {
+					return nil, scanner.Err()
+				}
+				return nil, io.EOF
+			}
+
+			var document bson.Raw
+			err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &document)
+
+			return document, err
+		}
+	default:
+		bufferedReader := bufio.NewReader(reader)
+
+		return func() (bson.Raw, error) {
+			// Each BSON document starts with its total length as a little endian int32
+			lengthBytes := make([]byte, 4)
+			if _, err := io.ReadFull(bufferedReader, lengthBytes); err != nil {
+				return nil, err
+			}
+
+			length := binary.LittleEndian.Uint32(lengthBytes)
+			if length < 5 {
+				return nil, errors.New("Invalid BSON document length")
+			}
+
+			document := make([]byte, length)
+			copy(document, lengthBytes)
+			if _, err := io.ReadFull(bufferedReader, document[4:]); err != nil {
+				return nil, err
+			}
+
+			return bson.Raw(document), nil
+		}
+	}
+}
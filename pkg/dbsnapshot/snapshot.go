@@ -0,0 +1,99 @@
+package dbsnapshot
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Version is bumped whenever the layout of the snapshot archive changes in a way older restores can't handle
+const Version = 1
+
+const manifestFileName = "manifest.json"
+
+type Format string
+
+const (
+	FormatBSON   Format = "bson"
+	FormatNDJSON        = "ndjson"
+)
+
+// Collections are the CTDF collections included in a snapshot. Anything derived at runtime (realtime, stats, users) is left out
+var Collections = []string{
+	"stops_raw",
+	"stops",
+	"stop_groups",
+	"localities",
+	"administrative_areas",
+	"operators",
+	"operator_groups",
+	"services",
+	"journeys",
+	"blocks",
+	"transfers",
+	"school_term_calendars",
+	"rail_locations",
+	"bus_registrations",
+	"geofences",
+	"service_stop_summaries",
+	"identifier_translations",
+	"dataset_versions",
+}
+
+type Manifest struct {
+	Version          int
+	Format           Format
+	CreationDateTime time.Time
+
+	Filter      Filter
+	Collections []*ManifestCollection
+}
+
+type ManifestCollection struct {
+	Name      string
+	File      string
+	Documents int64
+}
+
+// Filter limits a snapshot to specific datasets and/or regions. Regions match on the dataset identifier prefix (eg. gb)
+type Filter struct {
+	Datasets []string
+	Regions  []string
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.Datasets) == 0 && len(f.Regions) == 0
+}
+
+// Query builds the mongo query for a collection, returning nil if the collection can't be filtered
+func (f Filter) Query(collectionName string) bson.M {
+	if f.IsEmpty() {
+		return bson.M{}
+	}
+
+	var field string
+	switch collectionName {
+	case "identifier_translations":
+		return nil
+	case "dataset_versions":
+		field = "dataset"
+	default:
+		field = "datasource.datasetid"
+	}
+
+	var conditions bson.A
+	if len(f.Datasets) > 0 {
+		conditions = append(conditions, bson.M{field: bson.M{"$in": f.Datasets}})
+	}
+	for _, region := range f.Regions {
+		conditions = append(conditions, bson.M{field: bson.M{"$regex": fmt.Sprintf("^%s-", regexp.QuoteMeta(region))}})
+	}
+
+	return bson.M{"$or": conditions}
+}
+
+func getCollectionFileName(collectionName string, format Format) string {
+	return fmt.Sprintf("%s.%s", collectionName, format)
+}
@@ -31,6 +31,7 @@ func RegisterCLI() *cli.Command {
 
 					serviceAlerts := NewServiceAlertsWatch()
 					go serviceAlerts.Run()
+					go serviceAlerts.RunExpiry()
 
 					realtimeJourneys := NewRealtimeJourneysWatch()
 					go realtimeJourneys.Run()
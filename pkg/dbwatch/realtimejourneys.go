@@ -1,14 +1,11 @@
 package dbwatch
 
 import (
-	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/adjust/rmq/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
-	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -40,8 +37,6 @@ func NewRealtimeJourneysWatch() *RealtimeJourneysWatch {
 }
 
 func (w *RealtimeJourneysWatch) Run() {
-	log.Info().Msg("Starting dbwatch on collection realtime_journeys")
-	collection := database.GetCollection("realtime_journeys")
 	matchPipeline := bson.D{
 		{
 			Key: "$match", Value: bson.D{
@@ -90,107 +85,102 @@ func (w *RealtimeJourneysWatch) Run() {
 			},
 		},
 	}
-	opts := options.ChangeStream().SetFullDocumentBeforeChange(options.WhenAvailable).SetFullDocument(options.WhenAvailable)
-	stream, err := collection.Watch(context.Background(), mongo.Pipeline{matchPipeline, projectPipeline}, opts)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to watch collection")
+	watch := &collectionWatch{
+		Name:       "realtime-journeys",
+		Collection: "realtime_journeys",
+		Pipeline:   mongo.Pipeline{matchPipeline, projectPipeline},
+		Options:    options.ChangeStream().SetFullDocumentBeforeChange(options.WhenAvailable).SetFullDocument(options.WhenAvailable),
+		EventQueue: w.EventQueue,
+		GetEvents: func(stream *mongo.ChangeStream) ([]*ctdf.Event, error) {
+			var data realtimeJourneyUpdate
+			if err := stream.Decode(&data); err != nil {
+				return nil, err
+			}
+
+			return w.getEvents(&data), nil
+		},
+	}
+
+	watch.Run()
+}
+
+func (w *RealtimeJourneysWatch) getEvents(data *realtimeJourneyUpdate) []*ctdf.Event {
+	if data.OperationType == "insert" {
+		log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Msg("New RealtimeJourney inserted")
+
+		return []*ctdf.Event{{
+			Type:      ctdf.EventTypeRealtimeJourneyCreated,
+			Timestamp: time.Now(),
+			Body:      data.FullDocument,
+		}}
 	}
 
-	defer stream.Close(context.Background())
+	if data.OperationType != "update" || data.FullDocument.PrimaryIdentifier == "" {
+		return nil
+	}
+
+	// Detect newly cancelled journeys
+	if data.UpdateDescription.UpdatedFields.Cancelled == true && !data.FullDocumentBeforeChange.Cancelled {
+		log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Msg("RealtimeJourney has been cancelled")
 
-	for stream.Next(context.Background()) {
-		var data realtimeJourneyUpdate
+		return []*ctdf.Event{{
+			Type:      ctdf.EventTypeRealtimeJourneyCancelled,
+			Timestamp: time.Now(),
+			Body:      data.FullDocument,
+		}}
+	}
 
-		if err := stream.Decode(&data); err != nil {
-			log.Error().Err(err).Msg("Failed to decode event")
+	var events []*ctdf.Event
+
+	// Checks for set or changed platforms
+	for id, journeyStop := range data.FullDocument.Stops {
+		// This shouldnt happen as why would a historical stop change platforms
+		if journeyStop.TimeType == ctdf.RealtimeJourneyStopTimeHistorical {
 			continue
 		}
 
-		go func(data *realtimeJourneyUpdate) {
-			if data.OperationType == "insert" {
-				log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Msg("New RealtimeJourney inserted")
-
-				eventBytes, _ := json.Marshal(ctdf.Event{
-					Type:      ctdf.EventTypeRealtimeJourneyCreated,
-					Timestamp: time.Now(),
-					Body:      data.FullDocument,
-				})
-				w.EventQueue.PublishBytes(eventBytes)
-			} else if data.OperationType == "update" {
-				if data.FullDocument.PrimaryIdentifier == "" {
-					return
-				}
-
-				// Detect newly cancelled journeys
-				if data.UpdateDescription.UpdatedFields.Cancelled == true && !data.FullDocumentBeforeChange.Cancelled {
-					log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Msg("RealtimeJourney has been cancelled")
-
-					eventBytes, _ := json.Marshal(ctdf.Event{
-						Type:      ctdf.EventTypeRealtimeJourneyCancelled,
-						Timestamp: time.Now(),
-						Body:      data.FullDocument,
-					})
-					w.EventQueue.PublishBytes(eventBytes)
-
-					return
-				}
-
-				// Checks for set or changed platforms
-				for id, journeyStop := range data.FullDocument.Stops {
-					// This shouldnt happen as why would a historical stop change platforms
-					if journeyStop.TimeType == ctdf.RealtimeJourneyStopTimeHistorical {
-						continue
-					}
-
-					newPlatform := journeyStop.Platform
-
-					oldJourneyPlatform := data.FullDocumentBeforeChange.Stops[id]
-					if oldJourneyPlatform == nil {
-						continue
-					}
-					oldPlatform := oldJourneyPlatform.Platform
-
-					if oldPlatform == "" && newPlatform != oldPlatform {
-						log.Info().
-							Str("id", data.FullDocument.PrimaryIdentifier).
-							Str("platform", newPlatform).
-							Msg("RealtimeJourney stop platform set")
-
-						eventBytes, _ := json.Marshal(ctdf.Event{
-							Type:      ctdf.EventTypeRealtimeJourneyPlatformSet,
-							Timestamp: time.Now(),
-							Body: map[string]interface{}{
-								"RealtimeJourney": data.FullDocument,
-								"Stop":            id,
-								"NewPlatform":     newPlatform,
-							},
-						})
-						w.EventQueue.PublishBytes(eventBytes)
-					} else if oldPlatform != "" && newPlatform != oldPlatform {
-						log.Info().
-							Str("id", data.FullDocument.PrimaryIdentifier).
-							Str("oldplatform", oldPlatform).
-							Str("newplatform", newPlatform).
-							Msg("RealtimeJourney stop platform changed")
-
-						eventBytes, _ := json.Marshal(ctdf.Event{
-							Type:      ctdf.EventTypeRealtimeJourneyPlatformChanged,
-							Timestamp: time.Now(),
-							Body: map[string]interface{}{
-								"RealtimeJourney": data.FullDocument,
-								"Stop":            id,
-								"OldPlatform":     oldPlatform,
-								"NewPlatform":     newPlatform,
-							},
-						})
-						w.EventQueue.PublishBytes(eventBytes)
-					}
-				}
-			}
-		}(&data)
-	}
+		newPlatform := journeyStop.Platform
 
-	log.Error().Err(stream.Err()).Msg("realtime journey watch fell over")
+		oldJourneyPlatform := data.FullDocumentBeforeChange.Stops[id]
+		if oldJourneyPlatform == nil {
+			continue
+		}
+		oldPlatform := oldJourneyPlatform.Platform
+
+		if oldPlatform == "" && newPlatform != oldPlatform {
+			log.Info().
+				Str("id", data.FullDocument.PrimaryIdentifier).
+				Str("platform", newPlatform).
+				Msg("RealtimeJourney stop platform set")
+
+			events = append(events, &ctdf.Event{
+				Type:      ctdf.EventTypeRealtimeJourneyPlatformSet,
+				Timestamp: time.Now(),
+				Body: map[string]interface{}{
+					"RealtimeJourney": data.FullDocument,
+					"Stop":            id,
+					"NewPlatform":     newPlatform,
+				},
+			})
+		} else if oldPlatform != "" && newPlatform != oldPlatform {
+			log.Info().
+				Str("id", data.FullDocument.PrimaryIdentifier).
+				Str("oldplatform", oldPlatform).
+				Str("newplatform", newPlatform).
+				Msg("RealtimeJourney stop platform changed")
+
+			events = append(events, &ctdf.Event{
+				Type:      ctdf.EventTypeRealtimeJourneyPlatformChanged,
+				Timestamp: time.Now(),
+				Body: map[string]interface{}{
+					"RealtimeJourney": data.FullDocument,
+					"Stop":            id,
+					"OldPlatform":     oldPlatform,
+					"NewPlatform":     newPlatform,
+				},
+			})
+		}
+	}
 
-	w.Run() // this is a hack and a half
+	return events
 }
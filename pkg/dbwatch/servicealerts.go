@@ -2,22 +2,40 @@ package dbwatch
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/adjust/rmq/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/leader"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// How often alerts that have gone past their ValidUntil are marked as expired
+const serviceAlertExpiryInterval = 1 * time.Minute
+
+// Changes to any of these fields are raised as the alert being updated, anything else (eg. the modification time) isn't
+var serviceAlertContentFields = []string{
+	"alerttype", "title", "text", "validfrom", "validuntil", "activeperiods", "disruptionperiods", "matchedidentifiers", "matchkeys",
+}
+
 type ServiceAlertsWatch struct {
 	EventQueue rmq.Queue
 }
 
+type serviceAlertChange struct {
+	OperationType     string `bson:"operationType"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+	FullDocument             *ctdf.ServiceAlert `bson:"fullDocument"`
+	FullDocumentBeforeChange *ctdf.ServiceAlert `bson:"fullDocumentBeforeChange"`
+}
+
 func NewServiceAlertsWatch() *ServiceAlertsWatch {
 	eventQueue, err := redis_client.QueueConnection.OpenQueue("events-queue")
 	if err != nil {
@@ -30,43 +48,113 @@ func NewServiceAlertsWatch() *ServiceAlertsWatch {
 }
 
 func (w *ServiceAlertsWatch) Run() {
-	log.Info().Msg("Starting dbwatch on collection service_alerts")
-	collection := database.GetCollection("service_alerts")
 	matchPipeline := bson.D{
 		{
 			Key: "$match", Value: bson.D{
-				{Key: "operationType", Value: "insert"},
+				{Key: "operationType", Value: bson.M{"$in": bson.A{"insert", "update"}}},
 			},
 		},
 	}
-	stream, err := collection.Watch(context.Background(), mongo.Pipeline{matchPipeline})
-	if err != nil {
-		panic(err)
-	}
 
-	defer stream.Close(context.Background())
+	watch := &collectionWatch{
+		Name:       "service-alerts",
+		Collection: "service_alerts",
+		Pipeline:   mongo.Pipeline{matchPipeline},
+		Options:    options.ChangeStream().SetFullDocumentBeforeChange(options.WhenAvailable).SetFullDocument(options.UpdateLookup),
+		EventQueue: w.EventQueue,
+		GetEvents: func(stream *mongo.ChangeStream) ([]*ctdf.Event, error) {
+			var data serviceAlertChange
+			if err := stream.Decode(&data); err != nil {
+				return nil, err
+			}
 
-	for stream.Next(context.Background()) {
-		var data struct {
-			OperationType string             `bson:"operationType"`
-			FullDocument  *ctdf.ServiceAlert `bson:"fullDocument"`
-		}
-		if err := stream.Decode(&data); err != nil {
-			log.Error().Err(err).Msg("Failed to decode event")
-			continue
-		}
+			return w.getEvents(&data), nil
+		},
+	}
 
-		if data.OperationType != "insert" {
-			continue
-		}
+	watch.Run()
+}
+
+func (w *ServiceAlertsWatch) getEvents(data *serviceAlertChange) []*ctdf.Event {
+	// The alert may have already been deleted by the time an update is looked up
+	if data.FullDocument == nil {
+		return nil
+	}
 
+	if data.OperationType == "insert" {
 		log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Msg("New ServiceAlert inserted")
 
-		eventBytes, _ := json.Marshal(ctdf.Event{
+		return []*ctdf.Event{{
 			Type:      ctdf.EventTypeServiceAlertCreated,
 			Timestamp: time.Now(),
 			Body:      data.FullDocument,
-		})
-		w.EventQueue.PublishBytes(eventBytes)
+		}}
 	}
+
+	if data.OperationType != "update" {
+		return nil
+	}
+
+	// Closing an alert early ends it the same as it expiring
+	wasEnded := data.FullDocumentBeforeChange != nil && data.FullDocumentBeforeChange.IsEnded()
+	if data.FullDocument.IsEnded() && !wasEnded {
+		if _, statusChanged := data.UpdateDescription.UpdatedFields["status"]; statusChanged {
+			log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Str("status", string(data.FullDocument.Status)).Msg("ServiceAlert has ended")
+
+			return []*ctdf.Event{{
+				Type:      ctdf.EventTypeServiceAlertExpired,
+				Timestamp: time.Now(),
+				Body:      data.FullDocument,
+			}}
+		}
+	}
+
+	if data.FullDocument.IsEnded() {
+		return nil
+	}
+
+	for _, field := range serviceAlertContentFields {
+		if _, changed := data.UpdateDescription.UpdatedFields[field]; changed {
+			log.Info().Str("id", data.FullDocument.PrimaryIdentifier).Str("field", field).Msg("ServiceAlert updated")
+
+			return []*ctdf.Event{{
+				Type:      ctdf.EventTypeServiceAlertUpdated,
+				Timestamp: time.Now(),
+				Body:      data.FullDocument,
+			}}
+		}
+	}
+
+	return nil
+}
+
+// RunExpiry periodically marks alerts that have gone past their ValidUntil as expired.
+// The watch picks up the change & raises the expired event. Only one replica runs it at a time.
+func (w *ServiceAlertsWatch) RunExpiry() {
+	leader.RunAsLeader(leader.MongoStore{}, "service-alert-expiry", serviceAlertExpiryInterval, func(ctx context.Context) {
+		if err := expireServiceAlerts(ctx, time.Now()); err != nil {
+			log.Error().Err(err).Msg("Failed to expire service alerts")
+		}
+	})
+}
+
+func expireServiceAlerts(ctx context.Context, now time.Time) error {
+	result, err := database.GetCollection("service_alerts").UpdateMany(ctx, bson.M{
+		"status":     bson.M{"$nin": bson.A{ctdf.ServiceAlertStatusClosed, ctdf.ServiceAlertStatusExpired}},
+		"validuntil": bson.M{"$lt": now},
+	}, bson.M{
+		"$set": bson.M{
+			"status":               ctdf.ServiceAlertStatusExpired,
+			"modificationdatetime": now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Info().Int64("expired", result.ModifiedCount).Msg("Expired service alerts")
+	}
+
+	return nil
 }
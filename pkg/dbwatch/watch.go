@@ -0,0 +1,185 @@
+package dbwatch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Number of ready events above which the watches stop reading changes until the events consumers catch up.
+// Changes aren't lost while paused as the change stream picks up from where it was left.
+const maximumEventQueueLength = 20000
+
+const backPressureCheckInterval = 5 * time.Second
+const backPressureWaitInterval = 10 * time.Second
+
+// Resume tokens are only saved this often to avoid a write for every change
+const resumeTokenSaveInterval = 5 * time.Second
+
+const streamRestartDelay = 5 * time.Second
+
+// Error code returned when the resume token has fallen off the end of the oplog
+const changeStreamHistoryLostCode = 286
+
+type watchResumeToken struct {
+	Watch       string
+	ResumeToken bson.Raw
+
+	ModificationDateTime time.Time
+}
+
+// collectionWatch converts the changes on a collection into events, saving its position in the change stream so restarts
+// carry on from where they left off rather than missing changes
+type collectionWatch struct {
+	Name       string
+	Collection string
+
+	Pipeline mongo.Pipeline
+	Options  *options.ChangeStreamOptions
+
+	EventQueue rmq.Queue
+
+	GetEvents func(stream *mongo.ChangeStream) ([]*ctdf.Event, error)
+
+	lastBackPressureCheck time.Time
+	lastResumeTokenSave   time.Time
+}
+
+func (w *collectionWatch) Run() {
+	log.Info().Str("watch", w.Name).Msgf("Starting dbwatch on collection %s", w.Collection)
+
+	for {
+		err := w.watch()
+
+		var commandError mongo.CommandError
+		if errors.As(err, &commandError) && commandError.Code == changeStreamHistoryLostCode {
+			log.Error().Err(err).Str("watch", w.Name).Msg("Resume token no longer in the oplog, starting from the latest change")
+			w.clearResumeToken()
+		} else {
+			log.Error().Err(err).Str("watch", w.Name).Msg("Watch fell over, restarting")
+		}
+
+		time.Sleep(streamRestartDelay)
+	}
+}
+
+func (w *collectionWatch) watch() error {
+	opts := w.Options
+	if opts == nil {
+		opts = options.ChangeStream()
+	}
+
+	if resumeToken := w.getResumeToken(); resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+		log.Info().Str("watch", w.Name).Msg("Resuming watch from saved position")
+	} else {
+		opts.SetResumeAfter(nil)
+	}
+
+	stream, err := database.GetCollection(w.Collection).Watch(context.Background(), w.Pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for {
+		w.waitForEventQueue()
+
+		if !stream.Next(context.Background()) {
+			break
+		}
+
+		events, err := w.GetEvents(stream)
+		if err != nil {
+			log.Error().Err(err).Str("watch", w.Name).Msg("Failed to decode change")
+		}
+
+		for _, event := range events {
+			eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, event)
+			if err := w.EventQueue.PublishBytes(eventBytes); err != nil {
+				return err
+			}
+		}
+
+		if time.Since(w.lastResumeTokenSave) > resumeTokenSaveInterval {
+			w.saveResumeToken(stream.ResumeToken())
+		}
+	}
+
+	// Keep the position of whatever was processed before it fell over
+	w.saveResumeToken(stream.ResumeToken())
+
+	return stream.Err()
+}
+
+// waitForEventQueue blocks while the events queue is backed up
+func (w *collectionWatch) waitForEventQueue() {
+	if time.Since(w.lastBackPressureCheck) < backPressureCheckInterval {
+		return
+	}
+
+	for {
+		w.lastBackPressureCheck = time.Now()
+
+		stats, err := redis_client.QueueConnection.CollectStats([]string{"events-queue"})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get events queue size")
+			return
+		}
+
+		readyCount := stats.QueueStats["events-queue"].ReadyCount
+		if readyCount < maximumEventQueueLength {
+			return
+		}
+
+		log.Info().Str("watch", w.Name).Int64("queuesize", readyCount).Msg("Events queue too long, pausing watch")
+		time.Sleep(backPressureWaitInterval)
+	}
+}
+
+func (w *collectionWatch) getResumeToken() bson.Raw {
+	var resumeToken *watchResumeToken
+	database.GetCollection("dbwatch_resume_tokens").FindOne(context.Background(), bson.M{"watch": w.Name}).Decode(&resumeToken)
+
+	if resumeToken == nil {
+		return nil
+	}
+
+	return resumeToken.ResumeToken
+}
+
+func (w *collectionWatch) saveResumeToken(token bson.Raw) {
+	if token == nil {
+		return
+	}
+
+	w.lastResumeTokenSave = time.Now()
+
+	_, err := database.GetCollection("dbwatch_resume_tokens").ReplaceOne(
+		context.Background(),
+		bson.M{"watch": w.Name},
+		watchResumeToken{
+			Watch:                w.Name,
+			ResumeToken:          token,
+			ModificationDateTime: time.Now(),
+		},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		log.Error().Err(err).Str("watch", w.Name).Msg("Failed to save resume token")
+	}
+}
+
+func (w *collectionWatch) clearResumeToken() {
+	database.GetCollection("dbwatch_resume_tokens").DeleteOne(context.Background(), bson.M{"watch": w.Name})
+}
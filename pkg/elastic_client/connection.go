@@ -95,5 +95,9 @@ func IndexRequest(indexName string, document io.ReadSeeker) {
 }
 
 func WaitUntilQueueEmpty() {
+	if bulkIndexer == nil {
+		return
+	}
+
 	bulkIndexer.Close(context.Background())
 }
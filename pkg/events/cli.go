@@ -1,7 +1,6 @@
 package events
 
 import (
-	"encoding/json"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,6 +11,7 @@ import (
 	"github.com/travigo/travigo/pkg/ctdf"
 	dataaggregator "github.com/travigo/travigo/pkg/dataaggregator/global"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"github.com/urfave/cli/v2"
 )
@@ -88,7 +88,7 @@ func RegisterCLI() *cli.Command {
 						Body:      serviceAlert,
 					}
 
-					eventBytes, _ := json.Marshal(event)
+					eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, event)
 
 					eventsQueue.PublishBytes(eventBytes)
 
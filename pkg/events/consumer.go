@@ -2,13 +2,13 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/expr-lang/expr"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"go.mongodb.org/mongo-driver/bson"
 
@@ -35,7 +35,7 @@ func (c *EventsBatchConsumer) Consume(batch rmq.Deliveries) {
 
 	for _, payload := range payloads {
 		var event ctdf.Event
-		err := json.Unmarshal([]byte(payload), &event)
+		err := queuemessage.Unmarshal([]byte(payload), queuemessage.MessageTypeEvent, &event)
 
 		if err != nil {
 			continue
@@ -77,7 +77,7 @@ func (c *EventsBatchConsumer) Consume(batch rmq.Deliveries) {
 					Message:    notificationData.Message,
 				}
 
-				notificationBytes, _ := json.Marshal(notification)
+				notificationBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeNotification, notification)
 				c.NotifyQueue.PublishBytes(notificationBytes)
 
 				log.Info().Str("user", userEventSubscription.UserID).Msg("Sending notification")
@@ -2,6 +2,7 @@ package events
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -16,7 +17,7 @@ func GetNotificationData(e *ctdf.Event) ctdf.EventNotificationData {
 	eventBody := e.Body.(map[string]interface{})
 
 	switch e.Type {
-	case ctdf.EventTypeServiceAlertCreated:
+	case ctdf.EventTypeServiceAlertCreated, ctdf.EventTypeServiceAlertUpdated:
 		eventNotificationData.Title = eventBody["AlertType"].(string)
 		eventNotificationData.Message = eventBody["Text"].(string)
 
@@ -24,6 +25,16 @@ func GetNotificationData(e *ctdf.Event) ctdf.EventNotificationData {
 		if title != "" {
 			eventNotificationData.Title = title
 		}
+		if e.Type == ctdf.EventTypeServiceAlertUpdated {
+			eventNotificationData.Title = fmt.Sprintf("Updated: %s", eventNotificationData.Title)
+		}
+	case ctdf.EventTypeServiceAlertExpired:
+		eventNotificationData.Title = "Alert ended"
+		eventNotificationData.Message = eventBody["Text"].(string)
+
+		if title := eventBody["Title"].(string); title != "" {
+			eventNotificationData.Message = fmt.Sprintf("%s is no longer in effect", title)
+		}
 	case ctdf.EventTypeRealtimeJourneyCancelled:
 		eventNotificationData.Title = "Journey cancelled"
 
@@ -53,7 +64,8 @@ func GetNotificationData(e *ctdf.Event) ctdf.EventNotificationData {
 
 		var stop *ctdf.Stop
 		stop, err := dataaggregator.Lookup[*ctdf.Stop](query.Stop{
-			Identifier: originStopID,
+			Identifier:      originStopID,
+			IncludeInactive: true,
 		})
 		if err != nil {
 			log.Error().Err(err).Str("stop", originStopID).Msg("Failed to lookup stop")
@@ -74,6 +86,60 @@ func GetNotificationData(e *ctdf.Event) ctdf.EventNotificationData {
 			oldPlatform := eventBody["OldPlatform"]
 			eventNotificationData.Message = fmt.Sprintf("The %s service to %s from %s will now be departing from platform %s instead of %s", departureTimeText, destination, originStop, platform, oldPlatform)
 		}
+	case ctdf.EventTypeRealtimeJourneyGeofenceEntered, ctdf.EventTypeRealtimeJourneyGeofenceExited:
+		eventNotificationData.Title = fmt.Sprintf("%s", eventBody["GeofenceName"])
+
+		action := "entered"
+		if e.Type == ctdf.EventTypeRealtimeJourneyGeofenceExited {
+			action = "exited"
+		}
+		eventNotificationData.Message = fmt.Sprintf("Vehicle %s on %s has %s %s", eventBody["VehicleRef"], eventBody["ServiceRef"], action, eventBody["GeofenceName"])
+	case ctdf.EventTypeOperatorTrackingRateLow:
+		eventNotificationData.Title = "Low realtime tracking rate"
+
+		trackingRate, ok := eventBody["TrackingRate"].(float64)
+		if !ok {
+			log.Error().Interface("rate", eventBody["TrackingRate"]).Msg("Tracking rate event has an invalid rate")
+			eventNotificationData.Message = fmt.Sprintf("Few of %s journeys have been tracked today (%v of %v)",
+				eventBody["OperatorRef"], eventBody["TrackedJourneys"], eventBody["ScheduledJourneys"])
+			break
+		}
+		eventNotificationData.Message = fmt.Sprintf("Only %.0f%% of %s journeys have been tracked today (%v of %v)",
+			trackingRate*100, eventBody["OperatorRef"], eventBody["TrackedJourneys"], eventBody["ScheduledJourneys"])
+	case ctdf.EventTypeRealtimeFeedSilent:
+		eventNotificationData.Title = "Realtime feed silent"
+		lastReceived, _ := time.Parse(time.RFC3339, eventBody["LastReceived"].(string))
+		eventNotificationData.Message = fmt.Sprintf("Nothing has been received from the %s feed since %s", eventBody["Source"], lastReceived.Format("15:04 02/01"))
+	case ctdf.EventTypeTimetableChanged:
+		eventNotificationData.Title = "Timetable change"
+
+		serviceRef := eventBody["ServiceRef"].(string)
+		serviceName := serviceRef
+		service, err := dataaggregator.Lookup[*ctdf.Service](query.Service{
+			PrimaryIdentifier: serviceRef,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("service", serviceRef).Msg("Failed to lookup service")
+		} else {
+			serviceName = service.ServiceName
+		}
+
+		retimed, _ := eventBody["Retimed"].([]interface{})
+		cancelled, _ := eventBody["Cancelled"].([]interface{})
+
+		var changes []string
+		if len(retimed) > 0 {
+			changes = append(changes, fmt.Sprintf("%d journeys retimed", len(retimed)))
+		}
+		if len(cancelled) > 0 {
+			changes = append(changes, fmt.Sprintf("%d journeys withdrawn", len(cancelled)))
+		}
+		eventNotificationData.Message = fmt.Sprintf("The %s timetable is changing: %s", serviceName, strings.Join(changes, " & "))
+
+		effectiveFrom, _ := time.Parse(time.RFC3339, eventBody["EffectiveFrom"].(string))
+		if effectiveFrom.After(time.Now()) {
+			eventNotificationData.Message = fmt.Sprintf("%s from %s", eventNotificationData.Message, effectiveFrom.Format("02/01"))
+		}
 	}
 
 	return eventNotificationData
@@ -0,0 +1,122 @@
+package identifiermapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+type MappingType string
+
+const (
+	MappingTypeTrip  MappingType = "trip"
+	MappingTypeRoute             = "route"
+	MappingTypeStop              = "stop"
+)
+
+const keyFormat = "identifiermapping/%s/%s"
+
+// FrequencyTripID is the local ID used for each run of a frequency based GTFS trip, as they share a trip_id
+func FrequencyTripID(tripID string, startTime string) string {
+	return fmt.Sprintf("%s@%s", tripID, startTime)
+}
+
+// Number of fields written to redis in each HSET when saving
+const saveBatchSize = 5000
+
+// Mapping of the identifiers used in a source dataset (eg. GTFS trip_id) to the CTDF identifiers they were imported as.
+// Stored as one redis hash per dataset & type so realtime feeds can resolve them with a single HGET
+type Mapping struct {
+	Dataset string
+	Type    MappingType
+
+	items map[string]string
+	mutex sync.Mutex
+}
+
+func NewMapping(dataset string, mappingType MappingType) *Mapping {
+	return &Mapping{
+		Dataset: dataset,
+		Type:    mappingType,
+		items:   map[string]string{},
+	}
+}
+
+func (m *Mapping) Add(localID string, ctdfID string) {
+	m.mutex.Lock()
+	m.items[localID] = ctdfID
+	m.mutex.Unlock()
+}
+
+// Save writes the mapping into a temporary key and then renames it over the live one so lookups
+// never see a partially written mapping or stale entries from the previous import
+func (m *Mapping) Save() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.items) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf(keyFormat, m.Dataset, m.Type)
+	temporaryKey := fmt.Sprintf("%s/importing", key)
+
+	redis_client.Client.Del(context.Background(), temporaryKey)
+
+	if err := m.write(temporaryKey); err != nil {
+		return err
+	}
+
+	return redis_client.Client.Rename(context.Background(), temporaryKey, key).Err()
+}
+
+// Merge writes the mapping into the live one, keeping the entries it doesn't contain.
+// Used when only part of a dataset was imported so the rest of its mappings aren't lost
+func (m *Mapping) Merge() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.items) == 0 {
+		return nil
+	}
+
+	return m.write(fmt.Sprintf(keyFormat, m.Dataset, m.Type))
+}
+
+func (m *Mapping) write(key string) error {
+	var fields []interface{}
+	for localID, ctdfID := range m.items {
+		fields = append(fields, localID, ctdfID)
+
+		if len(fields) >= saveBatchSize*2 {
+			if err := redis_client.Client.HSet(context.Background(), key, fields...).Err(); err != nil {
+				return err
+			}
+			fields = []interface{}{}
+		}
+	}
+	if len(fields) > 0 {
+		if err := redis_client.Client.HSet(context.Background(), key, fields...).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Lookup(dataset string, mappingType MappingType, localID string) (string, error) {
+	if redis_client.Client == nil {
+		return "", errors.New("Redis client not connected")
+	}
+
+	ctdfID, err := redis_client.Client.HGet(context.Background(), fmt.Sprintf(keyFormat, dataset, mappingType), localID).Result()
+	if err == redis.Nil {
+		return "", errors.New("No identifier mapping found")
+	}
+
+	return ctdfID, err
+}
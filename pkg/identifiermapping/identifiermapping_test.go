@@ -0,0 +1,81 @@
+package identifiermapping_test
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/identifiermapping"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+func startRedis(t *testing.T) {
+	t.Helper()
+
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start Redis: %s", err)
+	}
+	t.Cleanup(server.Close)
+
+	redis_client.Client = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { redis_client.Client = nil })
+}
+
+func assertLookup(t *testing.T, localID string, expected string) {
+	t.Helper()
+
+	ctdfID, err := identifiermapping.Lookup("test-dataset", identifiermapping.MappingTypeTrip, localID)
+	if expected == "" {
+		if err == nil {
+			t.Errorf("Expected no mapping for %s but got %s", localID, ctdfID)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Failed to lookup %s: %s", localID, err)
+	}
+	if ctdfID != expected {
+		t.Errorf("Expected %s to map to %s but got %s", localID, expected, ctdfID)
+	}
+}
+
+func TestSaveReplacesMapping(t *testing.T) {
+	startRedis(t)
+
+	mapping := identifiermapping.NewMapping("test-dataset", identifiermapping.MappingTypeTrip)
+	mapping.Add("trip-A", "journey-A")
+	mapping.Add("trip-B", "journey-B")
+	if err := mapping.Save(); err != nil {
+		t.Fatalf("Failed to save mapping: %s", err)
+	}
+
+	mapping = identifiermapping.NewMapping("test-dataset", identifiermapping.MappingTypeTrip)
+	mapping.Add("trip-A", "journey-A2")
+	if err := mapping.Save(); err != nil {
+		t.Fatalf("Failed to save mapping: %s", err)
+	}
+
+	assertLookup(t, "trip-A", "journey-A2")
+	assertLookup(t, "trip-B", "")
+}
+
+func TestMergeKeepsExistingMapping(t *testing.T) {
+	startRedis(t)
+
+	mapping := identifiermapping.NewMapping("test-dataset", identifiermapping.MappingTypeTrip)
+	mapping.Add("trip-A", "journey-A")
+	mapping.Add("trip-B", "journey-B")
+	if err := mapping.Save(); err != nil {
+		t.Fatalf("Failed to save mapping: %s", err)
+	}
+
+	mapping = identifiermapping.NewMapping("test-dataset", identifiermapping.MappingTypeTrip)
+	mapping.Add("trip-A", "journey-A2")
+	if err := mapping.Merge(); err != nil {
+		t.Fatalf("Failed to merge mapping: %s", err)
+	}
+
+	assertLookup(t, "trip-A", "journey-A2")
+	assertLookup(t, "trip-B", "journey-B")
+}
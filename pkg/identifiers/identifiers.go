@@ -0,0 +1,64 @@
+package identifiers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type IdentifierType string
+
+const (
+	IdentifierTypeATCO      IdentifierType = "gb-atco"
+	IdentifierTypeCRS                      = "gb-crs"
+	IdentifierTypeTIPLOC                   = "gb-tiploc"
+	IdentifierTypeSTANOX                   = "gb-stanox"
+	IdentifierTypeNOC                      = "gb-noc"
+	IdentifierTypeNOCID                    = "gb-nocid"
+	IdentifierTypeTOC                      = "gb-toc"
+	IdentifierTypeGTFSRoute                = "gtfs-route"
+	IdentifierTypeGTFS                     = "gtfs"
+	IdentifierTypeUnknown                  = "unknown"
+)
+
+// Prefixed types in the order they're checked, longer prefixes have to come before any they start with
+var prefixedTypes = []IdentifierType{
+	IdentifierTypeATCO,
+	IdentifierTypeCRS,
+	IdentifierTypeTIPLOC,
+	IdentifierTypeSTANOX,
+	IdentifierTypeNOCID,
+	IdentifierTypeNOC,
+	IdentifierTypeTOC,
+	IdentifierTypeGTFSRoute,
+}
+
+// GTFS identifiers are only unique within their dataset so are scoped by it, eg. gb-dft-bods-gtfs-schedule-stop-123
+var gtfsIdentifierRegex = regexp.MustCompile("^(.+)-(stop|operator|route|trip)-([^-]+)$")
+
+// Format builds the canonical identifier for a value of the given type
+func Format(identifierType IdentifierType, value string) string {
+	return fmt.Sprintf("%s-%s", identifierType, value)
+}
+
+// FormatGTFS builds the canonical identifier for a GTFS object (stop, operator, route, trip) within a dataset
+func FormatGTFS(dataset string, object string, value string) string {
+	return fmt.Sprintf("%s-%s-%s", dataset, object, value)
+}
+
+// Parse splits a canonical identifier into its type and the value it was created from
+func Parse(identifier string) (IdentifierType, string) {
+	for _, identifierType := range prefixedTypes {
+		prefix := string(identifierType) + "-"
+
+		if strings.HasPrefix(identifier, prefix) {
+			return identifierType, strings.TrimPrefix(identifier, prefix)
+		}
+	}
+
+	if matches := gtfsIdentifierRegex.FindStringSubmatch(identifier); matches != nil {
+		return IdentifierTypeGTFS, matches[3]
+	}
+
+	return IdentifierTypeUnknown, identifier
+}
@@ -0,0 +1,243 @@
+package identifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ObjectType string
+
+const (
+	ObjectTypeStop      ObjectType = "stop"
+	ObjectTypeStopGroup            = "stopgroup"
+	ObjectTypeOperator             = "operator"
+	ObjectTypeService              = "service"
+)
+
+var objectTypeCollections = map[ObjectType]string{
+	ObjectTypeStop:      "stops",
+	ObjectTypeStopGroup: "stop_groups",
+	ObjectTypeOperator:  "operators",
+	ObjectTypeService:   "services",
+}
+
+const rebuildBatchSize = 5000
+
+func GetObjectTypes() []ObjectType {
+	return []ObjectType{ObjectTypeStop, ObjectTypeStopGroup, ObjectTypeOperator, ObjectTypeService}
+}
+
+// Translation maps one of the identifiers an object is known by to its primary identifier
+type Translation struct {
+	ObjectType     ObjectType
+	Identifier     string
+	IdentifierType IdentifierType
+
+	PrimaryIdentifier string
+
+	Generation string
+}
+
+// ToPrimary resolves any identifier of an object to its primary identifier.
+// Identifiers with no translation are returned as they are, so primary identifiers pass straight through.
+func ToPrimary(objectType ObjectType, identifier string) string {
+	if primaryIdentifier, exists := lookupPrimary(objectType, identifier); exists {
+		return primaryIdentifier
+	}
+
+	return identifier
+}
+
+// Filter matches the object known by any of its identifiers. The identifier is also searched for as it is, so lookups
+// keep working for objects imported since the translations were rebuilt or whose translation has gone stale.
+func Filter(objectType ObjectType, identifier string) bson.M {
+	conditions := bson.A{
+		bson.M{"primaryidentifier": identifier},
+		bson.M{"otheridentifiers": identifier},
+	}
+
+	if primaryIdentifier, exists := lookupPrimary(objectType, identifier); exists && primaryIdentifier != identifier {
+		conditions = append(bson.A{bson.M{"primaryidentifier": primaryIdentifier}}, conditions...)
+	}
+
+	return bson.M{"$or": conditions}
+}
+
+// How long translations are cached for, they only change when the translations are rebuilt after an import
+const translationCacheTTL = 10 * time.Minute
+
+// The cache is emptied when it grows past this rather than tracking which translations were used least
+const translationCacheMaxSize = 100000
+
+type translationCacheEntry struct {
+	PrimaryIdentifier string
+	Exists            bool
+	Expiry            time.Time
+}
+
+var translationCache = map[string]translationCacheEntry{}
+var translationCacheMutex sync.Mutex
+
+func lookupPrimary(objectType ObjectType, identifier string) (string, bool) {
+	cacheKey := fmt.Sprintf("%s/%s", objectType, identifier)
+
+	translationCacheMutex.Lock()
+	entry, cached := translationCache[cacheKey]
+	translationCacheMutex.Unlock()
+
+	if cached && time.Now().Before(entry.Expiry) {
+		return entry.PrimaryIdentifier, entry.Exists
+	}
+
+	var translation Translation
+	err := database.GetCollection("identifier_translations").FindOne(context.Background(), bson.M{
+		"objecttype": objectType,
+		"identifier": identifier,
+	}).Decode(&translation)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("identifier", identifier).Msg("Failed to lookup identifier translation")
+		return "", false
+	}
+
+	// Identifiers without a translation are cached too so they don't hit the database every time
+	entry = translationCacheEntry{
+		PrimaryIdentifier: translation.PrimaryIdentifier,
+		Exists:            err == nil,
+		Expiry:            time.Now().Add(translationCacheTTL),
+	}
+
+	translationCacheMutex.Lock()
+	if len(translationCache) >= translationCacheMaxSize {
+		translationCache = map[string]translationCacheEntry{}
+	}
+	translationCache[cacheKey] = entry
+	translationCacheMutex.Unlock()
+
+	return entry.PrimaryIdentifier, entry.Exists
+}
+
+// Translate returns the identifiers of the given type that the object referenced by identifier is also known by
+func Translate(objectType ObjectType, identifier string, identifierType IdentifierType) ([]string, error) {
+	primaryIdentifier := ToPrimary(objectType, identifier)
+
+	cursor, err := database.GetCollection("identifier_translations").Find(context.Background(), bson.M{
+		"objecttype":        objectType,
+		"primaryidentifier": primaryIdentifier,
+		"identifiertype":    identifierType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var translations []Translation
+	if err := cursor.All(context.Background(), &translations); err != nil {
+		return nil, err
+	}
+
+	var translated []string
+	for _, translation := range translations {
+		translated = append(translated, translation.Identifier)
+	}
+
+	return translated, nil
+}
+
+// RebuildTranslations regenerates the translations of an object type from the primary & other identifiers
+// currently in its collection, removing any left over from objects that no longer exist
+func RebuildTranslations(objectType ObjectType) error {
+	collectionName, exists := objectTypeCollections[objectType]
+	if !exists {
+		return errors.New(fmt.Sprintf("Unknown object type %s", objectType))
+	}
+
+	translationsCollection := database.GetCollection("identifier_translations")
+	generation := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	opts := options.Find().SetProjection(bson.D{
+		bson.E{Key: "primaryidentifier", Value: 1},
+		bson.E{Key: "otheridentifiers", Value: 1},
+	})
+	cursor, err := database.GetCollection(collectionName).Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var operations []mongo.WriteModel
+	var numTranslations int
+
+	for cursor.Next(context.Background()) {
+		var record struct {
+			PrimaryIdentifier string
+			OtherIdentifiers  []string
+		}
+		if err := cursor.Decode(&record); err != nil {
+			// Some collections use a map for their other identifiers, they have nothing to translate
+			continue
+		}
+
+		for _, identifier := range append([]string{record.PrimaryIdentifier}, record.OtherIdentifiers...) {
+			if identifier == "" {
+				continue
+			}
+
+			identifierType, _ := Parse(identifier)
+
+			operations = append(operations, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"objecttype": objectType, "identifier": identifier}).
+				SetReplacement(Translation{
+					ObjectType:        objectType,
+					Identifier:        identifier,
+					IdentifierType:    identifierType,
+					PrimaryIdentifier: record.PrimaryIdentifier,
+					Generation:        generation,
+				}).
+				SetUpsert(true),
+			)
+		}
+
+		if len(operations) >= rebuildBatchSize {
+			if _, err := translationsCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+				return err
+			}
+			numTranslations += len(operations)
+			operations = []mongo.WriteModel{}
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := translationsCollection.BulkWrite(context.Background(), operations, &options.BulkWriteOptions{}); err != nil {
+			return err
+		}
+		numTranslations += len(operations)
+	}
+
+	deleted, err := translationsCollection.DeleteMany(context.Background(), bson.M{
+		"objecttype": objectType,
+		"generation": bson.M{"$ne": generation},
+	})
+	if err != nil {
+		return err
+	}
+
+	translationCacheMutex.Lock()
+	translationCache = map[string]translationCacheEntry{}
+	translationCacheMutex.Unlock()
+
+	log.Info().
+		Str("type", string(objectType)).
+		Int("translations", numTranslations).
+		Int64("removed", deleted.DeletedCount).
+		Msg("Rebuilt identifier translations")
+
+	return nil
+}
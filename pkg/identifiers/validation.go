@@ -0,0 +1,55 @@
+package identifiers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Anything written as a primary identifier has to at least be a single token
+var genericPrimaryIdentifierRegex = regexp.MustCompile(`^[^\s]{1,256}$`)
+
+// The primary identifier formats each collection is expected to contain, collections not listed only get the generic check
+var primaryIdentifierFormats = map[string][]*regexp.Regexp{
+	"operators": {
+		regexp.MustCompile(`^gb-(noc|nocid|toc)-[^\s]+$`),
+		regexp.MustCompile(`^[^\s]+-operator-[^\s]+$`),
+	},
+	"stops":     stopPrimaryIdentifierFormats,
+	"stops_raw": stopPrimaryIdentifierFormats,
+	"stop_groups": {
+		regexp.MustCompile(`^gb-stopgroup-[0-9A-Za-z]+$`),
+	},
+	"localities": {
+		regexp.MustCompile(`^gb-nptglocality-[0-9A-Za-z]+$`),
+	},
+	"administrative_areas": {
+		regexp.MustCompile(`^gb-nptgadminarea-[0-9A-Za-z]+$`),
+	},
+}
+
+var stopPrimaryIdentifierFormats = []*regexp.Regexp{
+	regexp.MustCompile(`^gb-atco-[0-9A-Za-z]+$`),
+	regexp.MustCompile(`^[^\s]+-stop-[^\s]+$`),
+	regexp.MustCompile(`^travigo-internalmerge-[^\s]+$`),
+}
+
+// ValidatePrimaryIdentifier checks an identifier about to be written into a collection is in one of the formats expected there
+func ValidatePrimaryIdentifier(collectionName string, identifier string) error {
+	if !genericPrimaryIdentifierRegex.MatchString(identifier) {
+		return errors.New(fmt.Sprintf("Primary identifier %q is empty, too long or contains whitespace", identifier))
+	}
+
+	formats, exists := primaryIdentifierFormats[collectionName]
+	if !exists {
+		return nil
+	}
+
+	for _, format := range formats {
+		if format.MatchString(identifier) {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf("Primary identifier %q is not a valid %s identifier", identifier, collectionName))
+}
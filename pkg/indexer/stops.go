@@ -39,6 +39,16 @@ func createStopIndex(indexName string) {
 		},
 		"mappings": {
 			"properties": {
+				"Importance": {
+					"properties": {
+						"Score": {
+							"type": "float"
+						},
+						"Class": {
+							"type": "keyword"
+						}
+					}
+				},
 				"Location": {
 					"properties": {
 						"coordinates": {
@@ -94,6 +104,14 @@ func createStopIndex(indexName string) {
 						}
 					}
 				},
+				"TranslatedNames": {
+					"type": "text",
+					"fields": {
+						"search_as_you_type": {
+							"type": "search_as_you_type"
+						}
+					}
+				},
 				"TransportTypes": {
 					"type": "text",
 					"fields": {
@@ -174,14 +192,32 @@ func indexStopsFromMongo(indexName string) {
 			})
 		}
 
+		var locality map[string]string
+		stop.GetLocality()
+		if stop.Locality != nil {
+			locality = map[string]string{
+				"PrimaryIdentifier": stop.Locality.PrimaryIdentifier,
+				"Name":              stop.Locality.Name,
+				"Qualifier":         stop.Locality.Qualifier,
+			}
+		}
+
+		var translatedNames []string
+		for _, translation := range stop.NameTranslations {
+			translatedNames = append(translatedNames, translation)
+		}
+
 		jsonStop, _ := json.Marshal(map[string]interface{}{
 			"PrimaryIdentifier": stop.PrimaryIdentifier,
 			"OtherIdentifiers":  stop.OtherIdentifiers,
 			"PrimaryName":       stop.PrimaryName,
+			"TranslatedNames":   translatedNames,
 			"Descriptor":        stop.Descriptor,
 			"TransportTypes":    stop.TransportTypes,
 			"Location":          stop.Location,
+			"Locality":          locality,
 			"Services":          basicServices,
+			"Importance":        stop.Importance,
 		})
 
 		elastic_client.IndexRequest(indexName, bytes.NewReader(jsonStop))
@@ -0,0 +1,187 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTTL is how long a lease lasts without being renewed, a replica that stops takes this long to be replaced
+const DefaultTTL = 30 * time.Second
+
+// Store keeps the leases somewhere every replica can see them
+type Store interface {
+	// Acquire takes the lease if it's free or has expired, or renews it if the holder already has it.
+	// It returns false when someone else holds the lease
+	Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease, only if the holder still has it
+	Release(ctx context.Context, name string, holder string) error
+	// Holder returns who currently holds the lease, empty if nobody does
+	Holder(ctx context.Context, name string) (string, error)
+}
+
+var holderID string
+var holderIDOnce sync.Once
+
+// HolderID identifies this process when it holds a lease, it's different for every run even on the same host
+func HolderID() string {
+	holderIDOnce.Do(func() {
+		hostname, _ := os.Hostname()
+
+		randomBytes := make([]byte, 4)
+		rand.Read(randomBytes)
+
+		holderID = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(randomBytes))
+	})
+
+	return holderID
+}
+
+// Lease is held for as long as the process keeps running, it's renewed in the background until released or lost
+type Lease struct {
+	Name   string
+	Holder string
+
+	store Store
+	ttl   time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	lost     chan struct{}
+}
+
+// Acquire takes the named lease, returning nil if someone else already holds it
+func Acquire(store Store, name string, holder string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	acquired, err := store.Acquire(context.Background(), name, holder, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, nil
+	}
+
+	lease := &Lease{
+		Name:   name,
+		Holder: holder,
+
+		store: store,
+		ttl:   ttl,
+
+		stop: make(chan struct{}),
+		lost: make(chan struct{}),
+	}
+	go lease.keepAlive()
+
+	return lease, nil
+}
+
+// Lost is closed if the lease couldn't be renewed before it expired, work relying on it should stop
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// IsHeld checks the lease hasn't been lost
+func (l *Lease) IsHeld() bool {
+	select {
+	case <-l.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// Release stops renewing the lease & gives it up so another replica can take it straight away
+func (l *Lease) Release() error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+
+	return l.store.Release(context.Background(), l.Name, l.Holder)
+}
+
+func (l *Lease) keepAlive() {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	lastRenewed := time.Now()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewed, err := l.store.Acquire(context.Background(), l.Name, l.Holder, l.ttl)
+			if err != nil {
+				log.Error().Err(err).Str("lease", l.Name).Msg("Failed to renew lease")
+
+				// Keep trying until it would have expired anyway
+				if time.Since(lastRenewed) < l.ttl {
+					continue
+				}
+			}
+
+			if !renewed {
+				log.Warn().Str("lease", l.Name).Str("holder", l.Holder).Msg("Lease was lost")
+				close(l.lost)
+				return
+			}
+
+			lastRenewed = time.Now()
+		}
+	}
+}
+
+// RunAsLeader runs the task every interval on whichever replica holds the named lease, it never returns.
+// The other replicas keep trying for the lease so one of them takes over if the leader stops.
+// The task's context is cancelled if the lease is lost while it's running
+func RunAsLeader(store Store, name string, interval time.Duration, task func(ctx context.Context)) {
+	var lease *Lease
+
+	for {
+		if lease != nil && !lease.IsHeld() {
+			lease = nil
+		}
+
+		if lease == nil {
+			var err error
+			lease, err = Acquire(store, name, HolderID(), DefaultTTL)
+			if err != nil {
+				log.Error().Err(err).Str("lease", name).Msg("Failed to acquire lease")
+			} else if lease != nil {
+				log.Info().Str("lease", name).Str("holder", lease.Holder).Msg("Became leader")
+			}
+		}
+
+		if lease != nil {
+			runLeaderTask(lease, task)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func runLeaderTask(lease *Lease, task func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lease.Lost():
+			log.Warn().Str("lease", lease.Name).Msg("Lost lease, cancelling task")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	task(ctx)
+}
@@ -0,0 +1,76 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps leases in the leases collection, for services that only use Mongo.
+// The collection has a unique index on name so two replicas can't both insert the same lease
+type MongoStore struct{}
+
+type mongoLease struct {
+	Name   string
+	Holder string
+
+	ExpiryDateTime       time.Time
+	ModificationDateTime time.Time
+}
+
+func (s MongoStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	collection := database.GetCollection("leases")
+	now := time.Now()
+
+	filter := bson.M{
+		"name": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expirydatetime": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"holder":               holder,
+		"expirydatetime":       now.Add(ttl),
+		"modificationdatetime": now,
+	}}
+
+	// When someone else holds the lease the filter doesn't match & the upsert clashes with their document
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s MongoStore) Release(ctx context.Context, name string, holder string) error {
+	collection := database.GetCollection("leases")
+
+	_, err := collection.DeleteOne(ctx, bson.M{"name": name, "holder": holder})
+
+	return err
+}
+
+func (s MongoStore) Holder(ctx context.Context, name string) (string, error) {
+	collection := database.GetCollection("leases")
+
+	var lease *mongoLease
+	err := collection.FindOne(ctx, bson.M{
+		"name":           name,
+		"expirydatetime": bson.M{"$gte": time.Now()},
+	}).Decode(&lease)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return lease.Holder, nil
+}
@@ -0,0 +1,58 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+const redisLeaseKeyFormat = "travigo:lease:%s"
+
+// The lease is only changed when it's free or still held by the same holder, so one whose lease expired can't take
+// back or remove anyone else's
+var redisAcquireScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if not holder then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+elseif holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore keeps leases as expiring Redis keys, for services that already need Redis
+type RedisStore struct{}
+
+func (s RedisStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	acquired, err := redisAcquireScript.Run(ctx, redis_client.Client, []string{fmt.Sprintf(redisLeaseKeyFormat, name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return acquired == 1, nil
+}
+
+func (s RedisStore) Release(ctx context.Context, name string, holder string) error {
+	return redisReleaseScript.Run(ctx, redis_client.Client, []string{fmt.Sprintf(redisLeaseKeyFormat, name)}, holder).Err()
+}
+
+func (s RedisStore) Holder(ctx context.Context, name string) (string, error) {
+	holder, err := redis_client.Client.Get(ctx, fmt.Sprintf(redisLeaseKeyFormat, name)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+
+	return holder, err
+}
@@ -0,0 +1,94 @@
+package loadtest
+
+import (
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/realtime/vehicletracker"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "loadtest",
+		Usage: "Benchmark the realtime pipeline in a sandbox",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "replay",
+				Usage: "replay a recorded SIRI-VM archive into the realtime queue and measure latency & throughput",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "archive",
+						Usage:    "Directory containing the recorded SIRI-VM responses (xml or zip files)",
+						Required: true,
+					},
+					&cli.Float64Flag{
+						Name:  "speed",
+						Usage: "Speed multiplier applied to the gaps between recorded responses",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "dataset",
+						Usage: "Dataset the replayed records are attributed to",
+						Value: "gb-dft-bods-sirivm-all",
+					},
+					&cli.StringFlag{
+						Name:  "sandbox-database",
+						Usage: "Database the realtime journeys & vehicles are written to instead of the live one",
+						Value: "travigo-loadtest",
+					},
+					&cli.DurationFlag{
+						Name:  "drain-timeout",
+						Usage: "How long to wait for the queue to drain after the last response",
+						Value: 5 * time.Minute,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					dataset, err := manager.GetDataset(c.String("dataset"))
+					if err != nil {
+						return err
+					}
+					dataset.SupportedObjects = datasets.SupportedObjects{RealtimeJourneys: true}
+
+					if err := vehicletracker.StartSandboxConsumers(queueName, c.String("sandbox-database")); err != nil {
+						return err
+					}
+
+					snapshots, err := LoadSnapshots(c.String("archive"))
+					if err != nil {
+						return err
+					}
+					log.Info().Int("snapshots", len(snapshots)).Msg("Loaded SIRI-VM archive")
+
+					replayer := &Replayer{
+						Snapshots:    snapshots,
+						Speed:        c.Float64("speed"),
+						Dataset:      dataset,
+						DrainTimeout: c.Duration("drain-timeout"),
+					}
+
+					report, err := replayer.Run()
+					if err != nil {
+						return err
+					}
+
+					report.Print(os.Stdout)
+
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package loadtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const benchmarkVehicleActivities = 1000
+
+func writeBenchmarkSnapshot(b *testing.B) *Snapshot {
+	b.Helper()
+
+	var document strings.Builder
+	document.WriteString(`<?xml version="1.0" encoding="UTF-8"?><Siri><ServiceDelivery><ResponseTimestamp>2024-01-01T10:00:00+00:00</ResponseTimestamp><VehicleMonitoringDelivery>`)
+	for i := 0; i < benchmarkVehicleActivities; i++ {
+		fmt.Fprintf(&document, `<VehicleActivity>
+			<RecordedAtTime>2024-01-01T09:59:30+00:00</RecordedAtTime>
+			<ItemIdentifier>item-%d</ItemIdentifier>
+			<ValidUntilTime>2024-01-01T10:05:00+00:00</ValidUntilTime>
+			<MonitoredVehicleJourney>
+				<LineRef>%d</LineRef>
+				<DirectionRef>outbound</DirectionRef>
+				<PublishedLineName>%d</PublishedLineName>
+				<OperatorRef>OP%d</OperatorRef>
+				<VehicleRef>vehicle-%d</VehicleRef>
+			</MonitoredVehicleJourney>
+		</VehicleActivity>`, i, i%50, i%50, i%10, i)
+	}
+	document.WriteString(`</VehicleMonitoringDelivery></ServiceDelivery></Siri>`)
+
+	path := filepath.Join(b.TempDir(), "snapshot.xml")
+	if err := os.WriteFile(path, []byte(document.String()), 0644); err != nil {
+		b.Fatalf("Failed to write snapshot: %s", err)
+	}
+
+	return &Snapshot{Path: path}
+}
+
+func BenchmarkSnapshotVehicleActivities(b *testing.B) {
+	snapshot := writeBenchmarkSnapshot(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		activities, err := snapshot.VehicleActivities()
+		if err != nil {
+			b.Fatalf("Failed to decode snapshot: %s", err)
+		}
+		if len(activities) != benchmarkVehicleActivities {
+			b.Fatalf("Decoded %d vehicle activities, expected %d", len(activities), benchmarkVehicleActivities)
+		}
+	}
+}
+
+func BenchmarkLoadSnapshots(b *testing.B) {
+	snapshot := writeBenchmarkSnapshot(b)
+	directory := filepath.Dir(snapshot.Path)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadSnapshots(directory); err != nil {
+			b.Fatalf("Failed to load snapshots: %s", err)
+		}
+	}
+}
+
+func BenchmarkShiftTimestamp(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		shiftTimestamp("2024-01-01T09:59:30+00:00", 90*time.Minute)
+	}
+}
+
+func BenchmarkReportPercentiles(b *testing.B) {
+	report := &Report{}
+	for i := 0; i < 100000; i++ {
+		report.Latencies = append(report.Latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		report.LatencyPercentile(50)
+		report.LatencyPercentile(99)
+	}
+}
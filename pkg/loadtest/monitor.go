@@ -0,0 +1,187 @@
+package loadtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The replay goes through its own queue & consumers so it never mixes with the live realtime pipeline
+const queueName = "realtime-queue-loadtest"
+
+type publishedBatch struct {
+	publishedAt time.Time
+	cumulative  int64
+}
+
+type serverStatus struct {
+	Opcounters struct {
+		Insert int64 `bson:"insert"`
+		Update int64 `bson:"update"`
+		Delete int64 `bson:"delete"`
+	} `bson:"opcounters"`
+}
+
+func (s *serverStatus) writes() int64 {
+	return s.Opcounters.Insert + s.Opcounters.Update + s.Opcounters.Delete
+}
+
+// Monitor tracks how long each published batch takes to be consumed off the realtime queue
+// and how many writes the realtime Mongo instance performs while the replay is running
+type Monitor struct {
+	PollInterval time.Duration
+
+	mutex     sync.Mutex
+	published int64
+	consumed  int64
+	pending   []publishedBatch
+
+	baseline  int64
+	latencies []time.Duration
+
+	startTime    time.Time
+	startWrites  int64
+	endWrites    int64
+	writeSamples []float64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		PollInterval: 250 * time.Millisecond,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (m *Monitor) Start() {
+	m.baseline = getOutstanding()
+	if m.baseline > 0 {
+		log.Warn().Int64("outstanding", m.baseline).Msg("Realtime queue is not empty, latency measurements will include the existing backlog")
+	}
+
+	m.startTime = time.Now()
+	m.startWrites = getWriteCount()
+
+	go m.run()
+}
+
+// Published records that a batch of count events has just been pushed onto the queue
+func (m *Monitor) Published(count int64) {
+	if count == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.published += count
+	m.pending = append(m.pending, publishedBatch{
+		publishedAt: time.Now(),
+		cumulative:  m.published,
+	})
+}
+
+// Wait blocks until every published batch has been consumed or the timeout passes, then stops monitoring
+func (m *Monitor) Wait(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		m.mutex.Lock()
+		remaining := len(m.pending)
+		m.mutex.Unlock()
+
+		if remaining == 0 {
+			break
+		}
+
+		time.Sleep(m.PollInterval)
+	}
+
+	close(m.stop)
+	<-m.done
+
+	m.endWrites = getWriteCount()
+}
+
+func (m *Monitor) run() {
+	defer close(m.done)
+
+	pollTicker := time.NewTicker(m.PollInterval)
+	defer pollTicker.Stop()
+
+	writeTicker := time.NewTicker(5 * time.Second)
+	defer writeTicker.Stop()
+
+	lastWrites := m.startWrites
+	lastWriteSample := time.Now()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-pollTicker.C:
+			m.checkConsumed()
+		case now := <-writeTicker.C:
+			writes := getWriteCount()
+			m.mutex.Lock()
+			m.writeSamples = append(m.writeSamples, float64(writes-lastWrites)/now.Sub(lastWriteSample).Seconds())
+			m.mutex.Unlock()
+
+			lastWrites = writes
+			lastWriteSample = now
+		}
+	}
+}
+
+func (m *Monitor) checkConsumed() {
+	outstanding := getOutstanding() - m.baseline
+	if outstanding < 0 {
+		outstanding = 0
+	}
+
+	now := time.Now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	consumed := m.published - outstanding
+
+	for len(m.pending) > 0 && m.pending[0].cumulative <= consumed {
+		m.latencies = append(m.latencies, now.Sub(m.pending[0].publishedAt))
+		m.consumed = m.pending[0].cumulative
+		m.pending = m.pending[1:]
+	}
+}
+
+func getOutstanding() int64 {
+	stats, err := redis_client.QueueConnection.CollectStats([]string{queueName})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to collect queue stats")
+		return 0
+	}
+
+	queueStats := stats.QueueStats[queueName]
+
+	return queueStats.ReadyCount + queueStats.UnackedCount()
+}
+
+func getWriteCount() int64 {
+	var status serverStatus
+	err := database.GetInstance("realtime_journeys").Database.RunCommand(context.Background(), bson.D{
+		{Key: "serverStatus", Value: 1},
+	}).Decode(&status)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get Mongo server status")
+		return 0
+	}
+
+	return status.writes()
+}
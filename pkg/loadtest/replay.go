@@ -0,0 +1,117 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/siri_vm"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// Replayer pushes recorded SIRI-VM snapshots onto the realtime queue, keeping the original gaps
+// between snapshots divided by the speed multiplier
+type Replayer struct {
+	Snapshots []*Snapshot
+	Speed     float64
+	Dataset   datasets.DataSet
+
+	// DrainTimeout is how long to wait for the queue to be consumed after the last snapshot
+	DrainTimeout time.Duration
+}
+
+func (r *Replayer) Run() (*Report, error) {
+	if len(r.Snapshots) == 0 {
+		return nil, errors.New("No snapshots to replay")
+	}
+	if r.Speed <= 0 {
+		return nil, errors.New("Speed multiplier must be greater than 0")
+	}
+
+	queue, err := redis_client.QueueConnection.OpenQueue(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := NewMonitor()
+	monitor.Start()
+
+	replayStart := time.Now()
+	archiveStart := r.Snapshots[0].ResponseTime
+
+	var retrieved int64
+
+	for i, snapshot := range r.Snapshots {
+		offset := time.Duration(float64(snapshot.ResponseTime.Sub(archiveStart)) / r.Speed)
+		time.Sleep(time.Until(replayStart.Add(offset)))
+
+		activities, err := snapshot.VehicleActivities()
+		if err != nil {
+			log.Error().Err(err).Str("path", snapshot.Path).Str("entry", snapshot.Entry).Msg("Failed to decode snapshot")
+			continue
+		}
+
+		published := r.publish(queue, snapshot, activities)
+		monitor.Published(published)
+		retrieved += int64(len(activities))
+
+		log.Info().
+			Int("snapshot", i+1).
+			Int("total", len(r.Snapshots)).
+			Int("retrieved", len(activities)).
+			Int64("published", published).
+			Msg("Replayed snapshot")
+	}
+
+	log.Info().Msg("Waiting for realtime queue to drain")
+	monitor.Wait(r.DrainTimeout)
+
+	report := monitor.Report()
+	report.Snapshots = len(r.Snapshots)
+	report.Retrieved = retrieved
+	report.Speed = r.Speed
+
+	return report, nil
+}
+
+func (r *Replayer) publish(queue rmq.Queue, snapshot *Snapshot, activities []*siri_vm.VehicleActivity) int64 {
+	// Shift the recorded times to now so the records aren't dropped as stale by the queue submitter
+	shift := time.Since(snapshot.ResponseTime)
+
+	var published int64
+	for _, activity := range activities {
+		if activity.MonitoredVehicleJourney == nil {
+			continue
+		}
+
+		activity.RecordedAtTime = shiftTimestamp(activity.RecordedAtTime, shift)
+		activity.ValidUntilTime = shiftTimestamp(activity.ValidUntilTime, shift)
+
+		datasource := &ctdf.DataSourceReference{
+			OriginalFormat: string(r.Dataset.Format),
+			ProviderName:   r.Dataset.Provider.Name,
+			ProviderID:     r.Dataset.DataSourceRef,
+			DatasetID:      r.Dataset.Identifier,
+			Timestamp:      fmt.Sprintf("%d", time.Now().Unix()),
+		}
+
+		if siri_vm.SubmitToProcessQueue(queue, activity, r.Dataset, datasource) {
+			published += 1
+		}
+	}
+
+	return published
+}
+
+func shiftTimestamp(value string, shift time.Duration) string {
+	timestamp, err := time.Parse(ctdf.XSDDateTimeFormat, value)
+	if err != nil {
+		return value
+	}
+
+	return timestamp.Add(shift).Format(ctdf.XSDDateTimeFormat)
+}
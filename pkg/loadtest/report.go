@@ -0,0 +1,81 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type Report struct {
+	Snapshots int
+	Retrieved int64
+	Published int64
+	Duration  time.Duration
+	Speed     float64
+
+	Latencies        []time.Duration
+	UnconsumedEvents int64
+
+	MongoWrites       int64
+	MongoWriteSamples []float64
+}
+
+func (m *Monitor) Report() *Report {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	latencies := make([]time.Duration, len(m.latencies))
+	copy(latencies, m.latencies)
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	return &Report{
+		Published:         m.published,
+		Duration:          time.Since(m.startTime),
+		Latencies:         latencies,
+		UnconsumedEvents:  m.published - m.consumed,
+		MongoWrites:       m.endWrites - m.startWrites,
+		MongoWriteSamples: append([]float64{}, m.writeSamples...),
+	}
+}
+
+func (r *Report) LatencyPercentile(percentile float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	index := int(float64(len(r.Latencies)-1) * percentile / 100)
+
+	return r.Latencies[index]
+}
+
+func (r *Report) PeakMongoWriteRate() float64 {
+	var peak float64
+	for _, sample := range r.MongoWriteSamples {
+		if sample > peak {
+			peak = sample
+		}
+	}
+
+	return peak
+}
+
+func (r *Report) Print(writer io.Writer) {
+	seconds := r.Duration.Seconds()
+
+	fmt.Fprintf(writer, "Replayed %d snapshots at %.1fx speed in %s\n", r.Snapshots, r.Speed, r.Duration.Round(time.Second))
+	fmt.Fprintf(writer, "Events: %d retrieved, %d published (%.1f/s)\n", r.Retrieved, r.Published, float64(r.Published)/seconds)
+
+	fmt.Fprintf(writer, "\nQueue latency (publish -> consumed) over %d batches\n", len(r.Latencies))
+	fmt.Fprintf(writer, "  p50: %s\n", r.LatencyPercentile(50).Round(time.Millisecond))
+	fmt.Fprintf(writer, "  p95: %s\n", r.LatencyPercentile(95).Round(time.Millisecond))
+	fmt.Fprintf(writer, "  p99: %s\n", r.LatencyPercentile(99).Round(time.Millisecond))
+	fmt.Fprintf(writer, "  max: %s\n", r.LatencyPercentile(100).Round(time.Millisecond))
+	if r.UnconsumedEvents > 0 {
+		fmt.Fprintf(writer, "  %d events were still queued when the replay finished\n", r.UnconsumedEvents)
+	}
+
+	fmt.Fprintf(writer, "\nMongo writes: %d (%.1f/s average, %.1f/s peak)\n", r.MongoWrites, float64(r.MongoWrites)/seconds, r.PeakMongoWriteRate())
+}
@@ -0,0 +1,189 @@
+package loadtest
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataimporter/formats/siri_vm"
+	"golang.org/x/net/html/charset"
+)
+
+// Snapshot is a single recorded SIRI-VM response, either a plain XML file or an XML file within a zip
+type Snapshot struct {
+	Path  string
+	Entry string
+
+	ResponseTime time.Time
+}
+
+// LoadSnapshots finds all the recorded SIRI-VM responses in a directory and orders them by response time
+func LoadSnapshots(directory string) ([]*Snapshot, error) {
+	var snapshots []*Snapshot
+
+	err := filepath.WalkDir(directory, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".zip":
+			archive, err := zip.OpenReader(path)
+			if err != nil {
+				return err
+			}
+			defer archive.Close()
+
+			for _, file := range archive.File {
+				if file.FileInfo().IsDir() || strings.ToLower(filepath.Ext(file.Name)) != ".xml" {
+					continue
+				}
+
+				snapshot := &Snapshot{Path: path, Entry: file.Name}
+				snapshot.ResponseTime = snapshot.readResponseTime(file.Modified)
+				snapshots = append(snapshots, snapshot)
+			}
+		case ".xml":
+			fileInfo, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			snapshot := &Snapshot{Path: path}
+			snapshot.ResponseTime = snapshot.readResponseTime(fileInfo.ModTime())
+			snapshots = append(snapshots, snapshot)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		return nil, errors.New("No SIRI-VM snapshots found")
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].ResponseTime.Before(snapshots[j].ResponseTime)
+	})
+
+	return snapshots, nil
+}
+
+func (s *Snapshot) Open() (io.ReadCloser, error) {
+	if s.Entry == "" {
+		return os.Open(s.Path)
+	}
+
+	archive, err := zip.OpenReader(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range archive.File {
+		if file.Name == s.Entry {
+			reader, err := file.Open()
+			if err != nil {
+				archive.Close()
+				return nil, err
+			}
+
+			return &zipEntryReader{ReadCloser: reader, archive: archive}, nil
+		}
+	}
+
+	archive.Close()
+	return nil, errors.New("Snapshot entry missing from zip")
+}
+
+// VehicleActivities decodes every VehicleActivity element in the snapshot
+func (s *Snapshot) VehicleActivities() ([]*siri_vm.VehicleActivity, error) {
+	reader, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var activities []*siri_vm.VehicleActivity
+
+	d := xml.NewDecoder(reader)
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if tok == nil || err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch ty := tok.(type) {
+		case xml.StartElement:
+			if ty.Name.Local == "VehicleActivity" {
+				var vehicleActivity siri_vm.VehicleActivity
+
+				if err = d.DecodeElement(&vehicleActivity, &ty); err != nil {
+					return nil, err
+				}
+
+				activities = append(activities, &vehicleActivity)
+			}
+		}
+	}
+
+	return activities, nil
+}
+
+// readResponseTime uses the first ResponseTimestamp in the document, falling back to the file modification time
+func (s *Snapshot) readResponseTime(fallback time.Time) time.Time {
+	reader, err := s.Open()
+	if err != nil {
+		log.Error().Err(err).Str("path", s.Path).Msg("Failed to open snapshot")
+		return fallback
+	}
+	defer reader.Close()
+
+	d := xml.NewDecoder(reader)
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if tok == nil || err != nil {
+			return fallback
+		}
+
+		if ty, ok := tok.(xml.StartElement); ok && ty.Name.Local == "ResponseTimestamp" {
+			var value string
+			if err := d.DecodeElement(&value, &ty); err != nil {
+				return fallback
+			}
+
+			responseTime, err := time.Parse(ctdf.XSDDateTimeFormat, strings.TrimSpace(value))
+			if err != nil {
+				return fallback
+			}
+
+			return responseTime
+		}
+	}
+}
+
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z *zipEntryReader) Close() error {
+	z.ReadCloser.Close()
+	return z.archive.Close()
+}
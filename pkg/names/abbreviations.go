@@ -0,0 +1,53 @@
+package names
+
+import "strings"
+
+var abbreviations = map[string]string{
+	"rd":     "Road",
+	"stn":    "Station",
+	"ave":    "Avenue",
+	"sq":     "Square",
+	"ln":     "Lane",
+	"cres":   "Crescent",
+	"gdns":   "Gardens",
+	"pde":    "Parade",
+	"terr":   "Terrace",
+	"hosp":   "Hospital",
+	"ctr":    "Centre",
+	"cntr":   "Centre",
+	"jct":    "Junction",
+	"jcn":    "Junction",
+	"rbt":    "Roundabout",
+	"rdbt":   "Roundabout",
+	"pk":     "Park",
+	"hse":    "House",
+	"rly":    "Railway",
+	"univ":   "University",
+	"opp":    "opposite",
+	"adj":    "adjacent",
+	"nr":     "near",
+	"o/s":    "outside",
+	"o/side": "outside",
+}
+
+// Abbreviations that mean something else at the start of a name, eg. St Albans but Old St
+var trailingAbbreviations = map[string]string{
+	"st": "Street",
+	"dr": "Drive",
+}
+
+func expandAbbreviations(name string) string {
+	words := strings.Split(name, " ")
+
+	for i, word := range words {
+		key := strings.ToLower(strings.TrimSuffix(word, "."))
+
+		if expansion, exists := abbreviations[key]; exists {
+			words[i] = expansion
+		} else if expansion, exists := trailingAbbreviations[key]; exists && i > 0 && i == len(words)-1 {
+			words[i] = expansion
+		}
+	}
+
+	return strings.Join(words, " ")
+}
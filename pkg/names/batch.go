@@ -0,0 +1,171 @@
+package names
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const batchWriteSize = 1000
+
+var ObjectTypes = []string{"stops", "operators", "services", "journeys"}
+
+// NormaliseExisting runs the normalisation over the records already in the database
+// so they match what an import would now produce without having to re-import everything
+func NormaliseExisting(objectType string) error {
+	switch objectType {
+	case "stops":
+		// The linked stops are rebuilt from the raw stops so both need updating
+		for _, collectionName := range []string{"stops_raw", "stops"} {
+			if err := normaliseCollection(collectionName, func(stop *ctdf.Stop) bson.M {
+				NormaliseStop(stop)
+
+				update := bson.M{"primaryname": stop.PrimaryName}
+				if stop.NameTranslations != nil {
+					update["nametranslations"] = stop.NameTranslations
+				}
+				if stop.Platforms != nil {
+					update["platforms"] = stop.Platforms
+				}
+				if stop.Entrances != nil {
+					update["entrances"] = stop.Entrances
+				}
+
+				return update
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case "operators":
+		return normaliseCollection("operators", func(operator *ctdf.Operator) bson.M {
+			NormaliseOperator(operator)
+
+			return bson.M{"primaryname": operator.PrimaryName}
+		})
+	case "services":
+		return normaliseCollection("services", func(service *ctdf.Service) bson.M {
+			NormaliseService(service)
+
+			update := bson.M{
+				"servicename":       service.ServiceName,
+				"routes":            service.Routes,
+				"stopnameoverrides": service.StopNameOverrides,
+			}
+			if service.ServiceNameTranslations != nil {
+				update["servicenametranslations"] = service.ServiceNameTranslations
+			}
+
+			return update
+		})
+	case "journeys":
+		return normaliseDestinationDisplays()
+	default:
+		return errors.New(fmt.Sprintf("Unknown object type %s", objectType))
+	}
+}
+
+// normaliseCollection only writes back the records the normalisation actually changed
+func normaliseCollection[T any](collectionName string, normalise func(record *T) bson.M) error {
+	collection := database.GetCollection(collectionName)
+
+	cursor, err := collection.Find(context.Background(), bson.M{})
+	if err != nil {
+		return err
+	}
+
+	var operations []mongo.WriteModel
+	updated := 0
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+
+		_, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+		operations = []mongo.WriteModel{}
+
+		return err
+	}
+
+	for cursor.Next(context.Background()) {
+		var record T
+		if err := cursor.Decode(&record); err != nil {
+			log.Error().Err(err).Str("collection", collectionName).Msg("Failed to decode record")
+			continue
+		}
+
+		var original T
+		cursor.Decode(&original)
+
+		update := normalise(&record)
+		if reflect.DeepEqual(record, original) {
+			continue
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": cursor.Current.Lookup("_id")}).
+			SetUpdate(bson.M{"$set": update}),
+		)
+		updated += 1
+
+		if len(operations) >= batchWriteSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Info().Str("collection", collectionName).Int("updated", updated).Msg("Normalised names")
+
+	return nil
+}
+
+// normaliseDestinationDisplays works on the distinct values as there are far fewer of them than journeys
+func normaliseDestinationDisplays() error {
+	collection := database.GetCollection("journeys")
+
+	destinationDisplays, err := collection.Distinct(context.Background(), "destinationdisplay", bson.M{})
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for _, value := range destinationDisplays {
+		destinationDisplay, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		destination := ParseDestinationDisplay(destinationDisplay)
+		if destination.Display == destinationDisplay && len(destination.Via) == 0 {
+			continue
+		}
+
+		_, err := collection.UpdateMany(context.Background(),
+			bson.M{"destinationdisplay": destinationDisplay},
+			bson.M{"$set": bson.M{"destinationdisplay": destination.Display, "destinationvia": destination.Via}},
+		)
+		if err != nil {
+			return err
+		}
+		updated += 1
+	}
+
+	log.Info().Int("destinations", updated).Msg("Normalised journey destination displays")
+
+	return nil
+}
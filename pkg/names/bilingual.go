@@ -0,0 +1,91 @@
+package names
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+var bilingualSeparatorRegex = regexp.MustCompile(`\s+[/|]\s+`)
+
+// Common words & place name parts that only turn up in that language
+var languageMarkers = map[Locale][]string{
+	LocaleWelsh: {
+		"heol", "ffordd", "stryd", "gorsaf", "canolog", "canol", "ysgol", "ysbyty", "eglwys", "capel", "maes",
+		"y", "yr", "tref", "dref", "prifysgol", "caerdydd", "abertawe", "casnewydd", "bws", "sgwar", "parc",
+		"llan", "aber", "caer", "pont", "cwm", "bryn", "tŷ", "gogledd", "de", "dwyrain", "gorllewin",
+	},
+	LocaleGaelic: {
+		"rathad", "sràid", "stèisean", "baile", "inbhir", "cille", "ceann", "gleann", "beinn", "allt",
+		"na", "nan", "an", "am", "mòr", "mhòr", "beag", "bheag", "ospadal", "sgoil", "eaglais", "drochaid",
+	},
+}
+
+// Place name prefixes that are part of a longer word, eg. Llandudno & Aberystwyth
+var languagePrefixes = map[Locale][]string{
+	LocaleWelsh:  {"llan", "aber", "caer", "pen", "pont", "tre", "cwm", "ystrad"},
+	LocaleGaelic: {"inbhir", "cill", "baile", "dùn", "gleann"},
+}
+
+// SplitBilingual separates names written in both English & Welsh or Gaelic, eg. "Cardiff Central / Caerdydd Canolog".
+// The half with the most Welsh or Gaelic words is taken as the translation, names that don't look bilingual are left whole.
+func SplitBilingual(name string) (string, ctdf.Translations) {
+	parts := bilingualSeparatorRegex.Split(strings.TrimSpace(name), -1)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return name, nil
+	}
+
+	bestLocale := Locale("")
+	bestPart := -1
+	bestScore := 0
+	otherScore := 0
+
+	for _, locale := range []Locale{LocaleWelsh, LocaleGaelic} {
+		firstScore := languageScore(parts[0], locale)
+		secondScore := languageScore(parts[1], locale)
+
+		if firstScore > bestScore && firstScore > secondScore {
+			bestLocale, bestPart, bestScore, otherScore = locale, 0, firstScore, secondScore
+		}
+		if secondScore > bestScore && secondScore > firstScore {
+			bestLocale, bestPart, bestScore, otherScore = locale, 1, secondScore, firstScore
+		}
+	}
+
+	// Both halves looking like the same language means it's 2 places rather than 1 place in 2 languages
+	if bestPart == -1 || otherScore >= bestScore {
+		return name, nil
+	}
+
+	return parts[1-bestPart], ctdf.Translations{
+		string(bestLocale): parts[bestPart],
+	}
+}
+
+func languageScore(name string, locale Locale) int {
+	score := 0
+
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		word = strings.Trim(word, ".,()'’")
+
+		matched := false
+		for _, marker := range languageMarkers[locale] {
+			if word == marker {
+				matched = true
+				break
+			}
+		}
+		for _, prefix := range languagePrefixes[locale] {
+			if !matched && len(word) > len(prefix)+2 && strings.HasPrefix(word, prefix) {
+				matched = true
+			}
+		}
+
+		if matched {
+			score += 1
+		}
+	}
+
+	return score
+}
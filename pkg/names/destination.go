@@ -0,0 +1,94 @@
+package names
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/travigo/travigo/pkg/transforms"
+)
+
+// Destination is a destination display split into where the vehicle is going & the places it goes via
+type Destination struct {
+	// Raw is the destination as the source gave it, it's what transform rules match on
+	Raw string
+
+	Display string
+	Via     []string
+}
+
+// Transform rules in this group can override the parsed destination, eg. Type: names.Destination, Match: {Raw: "..."}
+const destinationTransformGroup = "destination-displays"
+
+var viaRegex = regexp.MustCompile(`(?i)^(.*?)[\s(\-]+(?:via|vía)[\s.:]+(.+?)\)?$`)
+var viaSeparatorRegex = regexp.MustCompile(`(?i)\s*(?:,|/)\s*|\s+(?:&|\+|and)\s+`)
+
+// Shorthand used on destination blinds, only expanded when the whole word matches. Lookups are made after the
+// abbreviations have been expanded so the pairs are written with them expanded (eg. Ret Pk is ret park)
+var destinationCodes = map[string]string{
+	"cc":       "City Centre",
+	"city ct":  "City Centre",
+	"tc":       "Town Centre",
+	"int":      "Interchange",
+	"intc":     "Interchange",
+	"bs":       "Bus Station",
+	"p&r":      "Park & Ride",
+	"pr":       "Park & Ride",
+	"ret park": "Retail Park",
+	"ind est":  "Industrial Estate",
+}
+
+// ParseDestinationDisplay cleans up a destination display, title casing shouted names, expanding shorthand
+// and moving any via points into their own list. Transform rules in the destination-displays group are applied
+// last so can correct anything the heuristics get wrong
+func ParseDestinationDisplay(destinationDisplay string) Destination {
+	destination := Destination{
+		Raw:     destinationDisplay,
+		Display: strings.Join(strings.Fields(destinationDisplay), " "),
+	}
+
+	if match := viaRegex.FindStringSubmatch(destination.Display); match != nil && strings.TrimSpace(match[1]) != "" {
+		destination.Display = strings.TrimSpace(match[1])
+
+		for _, via := range viaSeparatorRegex.Split(match[2], -1) {
+			if via = strings.Trim(via, " ."); via != "" {
+				destination.Via = append(destination.Via, normaliseDestinationPart(via))
+			}
+		}
+	}
+
+	destination.Display = normaliseDestinationPart(destination.Display)
+
+	transforms.Transform(&destination, 0, destinationTransformGroup)
+
+	return destination
+}
+
+// normaliseDestinationPart fixes the casing first as the expanded shorthand would stop a shouted name being seen as one
+func normaliseDestinationPart(part string) string {
+	return expandDestinationCodes(Normalise(part, LocaleEnglish))
+}
+
+// expandDestinationCodes swaps shorthand for its name, codes can span two words so pairs are checked first
+func expandDestinationCodes(destination string) string {
+	words := strings.Fields(destination)
+	var expanded []string
+
+	for i := 0; i < len(words); i++ {
+		if i+1 < len(words) {
+			if name, exists := destinationCodes[strings.ToLower(words[i]+" "+words[i+1])]; exists {
+				expanded = append(expanded, name)
+				i += 1
+				continue
+			}
+		}
+
+		if name, exists := destinationCodes[strings.ToLower(strings.TrimSuffix(words[i], "."))]; exists {
+			expanded = append(expanded, name)
+			continue
+		}
+
+		expanded = append(expanded, words[i])
+	}
+
+	return strings.Join(expanded, " ")
+}
@@ -0,0 +1,142 @@
+package names
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Locale is the ISO 639-1 code of the language a name is in, it changes which small words are left in lower case
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleWelsh          = "cy"
+	LocaleGaelic         = "gd"
+)
+
+// Words that stay lower case unless they start the name, eg. Stoke-on-Trent or Pen-y-bont.
+// English names are common in Wales & Scotland so the English words apply everywhere.
+var minorWords = map[Locale]map[string]bool{
+	LocaleEnglish: {
+		"and": true, "of": true, "the": true, "on": true, "upon": true, "in": true,
+		"at": true, "by": true, "for": true, "to": true, "under": true, "le": true,
+	},
+	LocaleWelsh: {
+		"y": true, "yr": true, "ar": true, "ac": true, "a": true,
+	},
+	LocaleGaelic: {
+		"na": true, "nan": true, "an": true, "am": true, "a": true,
+	},
+}
+
+// Short all caps words that are initialisms rather than shouted names
+var acronyms = map[string]bool{
+	"NHS": true, "UK": true, "LUL": true, "DLR": true, "YMCA": true, "HMP": true,
+	"RAF": true, "TFL": true, "BBC": true, "ASDA": true, "IKEA": true, "II": true,
+	"III": true, "IV": true, "P&R": true,
+}
+
+var nameTokenRegex = regexp.MustCompile(`[\p{L}\p{M}'’&]+|[^\p{L}\p{M}'’&]+`)
+
+// Normalise tidies up a name for display. Names that are entirely in capitals are put into title case
+// and common abbreviations are expanded, names that already use mixed case are trusted to be right.
+func Normalise(name string, locale Locale) string {
+	name = strings.Join(strings.Fields(name), " ")
+
+	if isAllCaps(name) {
+		name = fixCase(name, locale)
+	}
+
+	return expandAbbreviations(name)
+}
+
+// isAllCaps only counts names with a word long enough that it can't just be an initialism
+func isAllCaps(name string) bool {
+	hasLongWord := false
+
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if strings.ToUpper(word) != word {
+			return false
+		}
+
+		if len([]rune(word)) >= 4 && !acronyms[word] {
+			hasLongWord = true
+		}
+	}
+
+	return hasLongWord
+}
+
+func fixCase(name string, locale Locale) string {
+	tokens := nameTokenRegex.FindAllString(name, -1)
+
+	startOfPhrase := true
+	var builder strings.Builder
+
+	for i, token := range tokens {
+		if !unicode.IsLetter([]rune(token)[0]) {
+			builder.WriteString(token)
+
+			// A new phrase starts after brackets & slashes so a word following them is capitalised
+			if strings.ContainsAny(token, "(/:") {
+				startOfPhrase = true
+			}
+			continue
+		}
+
+		lower := strings.ToLower(token)
+		followedByNumber := i+1 < len(tokens) && unicode.IsDigit([]rune(tokens[i+1])[0])
+
+		switch {
+		case isAcronym(token), followedByNumber:
+			// Road numbers like A38 are split into a letter & a number so the letter is left alone
+			builder.WriteString(token)
+		case !startOfPhrase && (minorWords[LocaleEnglish][lower] || minorWords[locale][lower]):
+			builder.WriteString(lower)
+		default:
+			builder.WriteString(titleWord(token))
+		}
+
+		startOfPhrase = false
+	}
+
+	return builder.String()
+}
+
+func isAcronym(word string) bool {
+	if acronyms[word] {
+		return true
+	}
+
+	// Abbreviations like ST & RD don't have vowels either
+	lower := strings.ToLower(word)
+	if _, exists := abbreviations[lower]; exists {
+		return false
+	}
+	if _, exists := trailingAbbreviations[lower]; exists {
+		return false
+	}
+
+	// Words without any vowels can't be said so are initialisms, w & y count as vowels for Welsh words like Cwm & Bryn
+	return len([]rune(word)) >= 2 && !strings.ContainsAny(lower, "aeiouwy'’&")
+}
+
+func titleWord(word string) string {
+	lower := []rune(strings.ToLower(word))
+
+	// McDonald & O'Brien style names capitalise the letter after the prefix too
+	upperIndexes := []int{0}
+	if len(lower) > 3 && lower[0] == 'm' && lower[1] == 'c' {
+		upperIndexes = append(upperIndexes, 2)
+	}
+	if len(lower) > 2 && (lower[1] == '\'' || lower[1] == '’') && (lower[0] == 'o' || lower[0] == 'd') {
+		upperIndexes = append(upperIndexes, 2)
+	}
+
+	for _, index := range upperIndexes {
+		lower[index] = unicode.ToUpper(lower[index])
+	}
+
+	return string(lower)
+}
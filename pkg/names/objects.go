@@ -0,0 +1,94 @@
+package names
+
+import (
+	"strings"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// GetStopLocale uses the ATCO area code of the stop to tell if it is in Wales (5xx) or Scotland (6xx)
+func GetStopLocale(stop *ctdf.Stop) Locale {
+	for _, identifier := range append(stop.OtherIdentifiers, stop.PrimaryIdentifier) {
+		atcoCode, isAtco := strings.CutPrefix(identifier, "gb-atco-")
+		if !isAtco || atcoCode == "" {
+			continue
+		}
+
+		switch atcoCode[0] {
+		case '5':
+			return LocaleWelsh
+		case '6':
+			return LocaleGaelic
+		}
+	}
+
+	return LocaleEnglish
+}
+
+// NormaliseStop tidies the stop name & moves the other half of a bilingual name into its translations
+func NormaliseStop(stop *ctdf.Stop) {
+	locale := GetStopLocale(stop)
+
+	stop.PrimaryName = normaliseTranslated(stop.PrimaryName, locale, &stop.NameTranslations)
+
+	for _, platform := range stop.Platforms {
+		platform.PrimaryName = Normalise(platform.PrimaryName, locale)
+	}
+	for _, entrance := range stop.Entrances {
+		entrance.PrimaryName = Normalise(entrance.PrimaryName, locale)
+	}
+}
+
+func NormaliseOperator(operator *ctdf.Operator) {
+	operator.PrimaryName = Normalise(operator.PrimaryName, LocaleEnglish)
+}
+
+// NormaliseService only splits bilingual names as service names are usually route numbers that shouldn't be changed
+func NormaliseService(service *ctdf.Service) {
+	primaryName, translations := SplitBilingual(service.ServiceName)
+	if translations != nil {
+		service.ServiceName = primaryName
+		service.ServiceNameTranslations = mergeTranslations(service.ServiceNameTranslations, translations)
+	}
+
+	for i := range service.Routes {
+		route := &service.Routes[i]
+		route.Description = normaliseTranslated(route.Description, LocaleEnglish, &route.DescriptionTranslations)
+	}
+
+	// Overrides replace the stop names so have to be tidied the same way
+	for stopRef, stopName := range service.StopNameOverrides {
+		service.StopNameOverrides[stopRef] = Normalise(stopName, LocaleEnglish)
+	}
+}
+
+func normaliseTranslated(name string, locale Locale, existingTranslations *ctdf.Translations) string {
+	primaryName, translations := SplitBilingual(name)
+
+	// The English half of a bilingual name uses English casing rules
+	if translations != nil {
+		locale = LocaleEnglish
+	}
+
+	for language, translation := range translations {
+		translations[language] = Normalise(translation, Locale(language))
+	}
+	*existingTranslations = mergeTranslations(*existingTranslations, translations)
+
+	return Normalise(primaryName, locale)
+}
+
+func mergeTranslations(existing ctdf.Translations, translations ctdf.Translations) ctdf.Translations {
+	if len(translations) == 0 {
+		return existing
+	}
+	if existing == nil {
+		existing = ctdf.Translations{}
+	}
+
+	for language, translation := range translations {
+		existing[language] = translation
+	}
+
+	return existing
+}
@@ -1,7 +1,6 @@
 package notify
 
 import (
-	"encoding/json"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,6 +10,7 @@ import (
 	"github.com/travigo/travigo/pkg/consumer"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"github.com/urfave/cli/v2"
 )
@@ -82,7 +82,7 @@ func RegisterCLI() *cli.Command {
 						Message:    "Northern Line has been suspended due to a fault on the line",
 					}
 
-					notificationBytes, _ := json.Marshal(notification)
+					notificationBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeNotification, notification)
 
 					notifyQueue.PublishBytes(notificationBytes)
 
@@ -1,11 +1,11 @@
 package notify
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/queuemessage"
 
 	"github.com/adjust/rmq/v5"
 )
@@ -25,7 +25,7 @@ func (c *NotifyBatchConsumer) Consume(batch rmq.Deliveries) {
 
 	for _, payload := range payloads {
 		var notification ctdf.Notification
-		err := json.Unmarshal([]byte(payload), &notification)
+		err := queuemessage.Unmarshal([]byte(payload), queuemessage.MessageTypeNotification, &notification)
 
 		if err != nil {
 			continue
@@ -0,0 +1,297 @@
+// Package pluscode encodes & decodes Open Location Codes (Plus Codes), eg. 9C3XGV4C+XV
+package pluscode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	alphabet  = "23456789CFGHJMPQRVWX"
+	separator = '+'
+	padding   = '0'
+
+	separatorPosition = 8
+	encodingBase      = 20
+
+	pairCodeLength = 10
+	maxCodeLength  = 15
+	gridColumns    = 4
+	gridRows       = 5
+
+	latitudeMax  = 90
+	longitudeMax = 180
+
+	// Place value of the first pair digit & the precision of the last, in degrees
+	pairFirstPlaceValue = 160000 // encodingBase^(pairCodeLength/2 - 1)
+	pairPrecision       = 8000   // encodingBase^3
+
+	// Place value of the first grid digit
+	gridLatFirstPlaceValue = 625 // gridRows^(maxCodeLength - pairCodeLength - 1)
+	gridLngFirstPlaceValue = 256 // gridColumns^(maxCodeLength - pairCodeLength - 1)
+
+	finalLatPrecision = pairPrecision * 3125 // gridRows^(maxCodeLength - pairCodeLength)
+	finalLngPrecision = pairPrecision * 1024 // gridColumns^(maxCodeLength - pairCodeLength)
+)
+
+// DefaultCodeLength is the length used for stops, an area of roughly 14 metres square
+const DefaultCodeLength = 10
+
+// CodeArea is the area a code covers
+type CodeArea struct {
+	LatitudeLow   float64
+	LongitudeLow  float64
+	LatitudeHigh  float64
+	LongitudeHigh float64
+
+	CodeLength int
+}
+
+// Center returns the latitude & longitude in the middle of the area
+func (a CodeArea) Center() (float64, float64) {
+	latitude := math.Min(a.LatitudeLow+(a.LatitudeHigh-a.LatitudeLow)/2, latitudeMax)
+	longitude := math.Min(a.LongitudeLow+(a.LongitudeHigh-a.LongitudeLow)/2, longitudeMax)
+
+	return latitude, longitude
+}
+
+// Encode returns the full code of the given length for a location. Lengths below 10 must be even
+func Encode(latitude float64, longitude float64, codeLength int) string {
+	if codeLength < 2 || (codeLength < pairCodeLength && codeLength%2 == 1) {
+		codeLength = DefaultCodeLength
+	}
+	if codeLength > maxCodeLength {
+		codeLength = maxCodeLength
+	}
+
+	latitude = math.Max(-latitudeMax, math.Min(latitudeMax, latitude))
+	for longitude < -longitudeMax {
+		longitude += 2 * longitudeMax
+	}
+	for longitude >= longitudeMax {
+		longitude -= 2 * longitudeMax
+	}
+
+	// Working in integers avoids floating point errors creeping in to the lower digits
+	latitudeValue := int64(math.Floor(math.Round((latitude+latitudeMax)*finalLatPrecision*1e6) / 1e6))
+	longitudeValue := int64(math.Floor(math.Round((longitude+longitudeMax)*finalLngPrecision*1e6) / 1e6))
+
+	// The north pole is encoded as being just south of it so it stays within the grid
+	if latitudeValue >= 2*latitudeMax*finalLatPrecision {
+		latitudeValue = 2*latitudeMax*finalLatPrecision - 1
+	}
+	if longitudeValue >= 2*longitudeMax*finalLngPrecision {
+		longitudeValue = 0
+	}
+
+	code := make([]byte, maxCodeLength)
+
+	if codeLength > pairCodeLength {
+		for i := maxCodeLength - 1; i >= pairCodeLength; i-- {
+			latitudeDigit := latitudeValue % gridRows
+			longitudeDigit := longitudeValue % gridColumns
+			code[i] = alphabet[latitudeDigit*gridColumns+longitudeDigit]
+
+			latitudeValue /= gridRows
+			longitudeValue /= gridColumns
+		}
+	} else {
+		latitudeValue /= 3125
+		longitudeValue /= 1024
+	}
+
+	for i := pairCodeLength/2 - 1; i >= 0; i-- {
+		code[i*2] = alphabet[latitudeValue%encodingBase]
+		code[i*2+1] = alphabet[longitudeValue%encodingBase]
+
+		latitudeValue /= encodingBase
+		longitudeValue /= encodingBase
+	}
+
+	// Short lengths pad out the rest of the digits before the separator
+	if codeLength < separatorPosition {
+		return string(code[:codeLength]) + strings.Repeat(string(padding), separatorPosition-codeLength) + string(separator)
+	}
+
+	var builder strings.Builder
+	builder.Write(code[:separatorPosition])
+	builder.WriteByte(separator)
+	builder.Write(code[separatorPosition:codeLength])
+
+	return builder.String()
+}
+
+// IsValid checks the code is either a valid full or short code
+func IsValid(code string) bool {
+	code = strings.ToUpper(code)
+
+	separatorIndex := strings.IndexRune(code, separator)
+	if separatorIndex == -1 || separatorIndex != strings.LastIndexByte(code, separator) {
+		return false
+	}
+	if separatorIndex > separatorPosition || separatorIndex%2 == 1 {
+		return false
+	}
+	// A single digit after the separator isn't allowed
+	if len(code)-separatorIndex-1 == 1 {
+		return false
+	}
+
+	paddingIndex := strings.IndexRune(code, padding)
+	if paddingIndex != -1 {
+		if separatorIndex < separatorPosition || paddingIndex == 0 || paddingIndex%2 == 1 {
+			return false
+		}
+
+		paddingEnd := paddingIndex
+		for paddingEnd < len(code) && code[paddingEnd] == padding {
+			paddingEnd++
+		}
+		if paddingEnd != separatorIndex || (paddingEnd-paddingIndex)%2 == 1 {
+			return false
+		}
+		// Padded codes have nothing after the separator
+		if len(code) > separatorIndex+1 {
+			return false
+		}
+	}
+
+	for i, character := range code {
+		if i == separatorIndex || (paddingIndex != -1 && i >= paddingIndex && i < separatorIndex) {
+			continue
+		}
+		if !strings.ContainsRune(alphabet, character) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsShort checks for a valid code with some of its leading digits removed, it needs a reference location to decode
+func IsShort(code string) bool {
+	return IsValid(code) && strings.IndexRune(code, separator) < separatorPosition
+}
+
+// IsFull checks for a valid code that can be decoded on its own
+func IsFull(code string) bool {
+	if !IsValid(code) || IsShort(code) {
+		return false
+	}
+
+	code = strings.ToUpper(code)
+
+	// The first digits can't point beyond the poles or the antimeridian
+	if strings.IndexByte(alphabet, code[0])*encodingBase >= 2*latitudeMax {
+		return false
+	}
+	if len(code) > 1 && strings.IndexByte(alphabet, code[1])*encodingBase >= 2*longitudeMax {
+		return false
+	}
+
+	return true
+}
+
+// Decode returns the area covered by a full code
+func Decode(code string) (CodeArea, error) {
+	if !IsFull(code) {
+		return CodeArea{}, errors.New(fmt.Sprintf("%s is not a valid full Plus Code", code))
+	}
+
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, string(separator), "")
+	code = strings.TrimRight(code, string(padding))
+	if len(code) > maxCodeLength {
+		code = code[:maxCodeLength]
+	}
+
+	normalLatitude := int64(-latitudeMax * pairPrecision)
+	normalLongitude := int64(-longitudeMax * pairPrecision)
+	var gridLatitude int64
+	var gridLongitude int64
+
+	digits := min(len(code), pairCodeLength)
+	placeValue := int64(pairFirstPlaceValue)
+	for i := 0; i < digits; i += 2 {
+		normalLatitude += int64(strings.IndexByte(alphabet, code[i])) * placeValue
+		normalLongitude += int64(strings.IndexByte(alphabet, code[i+1])) * placeValue
+		if i < digits-2 {
+			placeValue /= encodingBase
+		}
+	}
+
+	latitudePrecision := float64(placeValue) / pairPrecision
+	longitudePrecision := float64(placeValue) / pairPrecision
+
+	if len(code) > pairCodeLength {
+		rowPlaceValue := int64(gridLatFirstPlaceValue)
+		columnPlaceValue := int64(gridLngFirstPlaceValue)
+
+		digits = min(len(code), maxCodeLength)
+		for i := pairCodeLength; i < digits; i++ {
+			digitValue := int64(strings.IndexByte(alphabet, code[i]))
+			gridLatitude += (digitValue / gridColumns) * rowPlaceValue
+			gridLongitude += (digitValue % gridColumns) * columnPlaceValue
+
+			if i < digits-1 {
+				rowPlaceValue /= gridRows
+				columnPlaceValue /= gridColumns
+			}
+		}
+
+		latitudePrecision = float64(rowPlaceValue) / finalLatPrecision
+		longitudePrecision = float64(columnPlaceValue) / finalLngPrecision
+	}
+
+	latitude := float64(normalLatitude)/pairPrecision + float64(gridLatitude)/finalLatPrecision
+	longitude := float64(normalLongitude)/pairPrecision + float64(gridLongitude)/finalLngPrecision
+
+	return CodeArea{
+		LatitudeLow:   latitude,
+		LongitudeLow:  longitude,
+		LatitudeHigh:  latitude + latitudePrecision,
+		LongitudeHigh: longitude + longitudePrecision,
+		CodeLength:    min(len(code), maxCodeLength),
+	}, nil
+}
+
+// RecoverNearest turns a short code into the full code nearest to the reference location, full codes are returned as is
+func RecoverNearest(code string, referenceLatitude float64, referenceLongitude float64) (string, error) {
+	if !IsShort(code) {
+		if IsFull(code) {
+			return strings.ToUpper(code), nil
+		}
+
+		return "", errors.New(fmt.Sprintf("%s is not a valid Plus Code", code))
+	}
+
+	code = strings.ToUpper(code)
+	referenceLatitude = math.Max(-latitudeMax, math.Min(latitudeMax, referenceLatitude))
+
+	// The missing digits are taken from the reference location
+	paddingLength := separatorPosition - strings.IndexRune(code, separator)
+	resolution := math.Pow(encodingBase, float64(2-paddingLength/2))
+	halfResolution := resolution / 2
+
+	area, err := Decode(Encode(referenceLatitude, referenceLongitude, maxCodeLength)[:paddingLength] + code)
+	if err != nil {
+		return "", err
+	}
+	latitude, longitude := area.Center()
+
+	// The reference may be closer to the neighbouring cell than the one it's in
+	if referenceLatitude+halfResolution < latitude && latitude-resolution >= -latitudeMax {
+		latitude -= resolution
+	} else if referenceLatitude-halfResolution > latitude && latitude+resolution <= latitudeMax {
+		latitude += resolution
+	}
+	if referenceLongitude+halfResolution < longitude {
+		longitude -= resolution
+	} else if referenceLongitude-halfResolution > longitude {
+		longitude += resolution
+	}
+
+	return Encode(latitude, longitude, area.CodeLength), nil
+}
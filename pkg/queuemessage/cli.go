@@ -0,0 +1,109 @@
+package queuemessage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "queue",
+		Usage: "Inspect & convert the messages waiting in the Redis queues",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "inspect",
+				Usage: "print the next messages in a queue without consuming them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "queue",
+						Usage:    fmt.Sprintf("Queue to inspect (%s)", strings.Join(GetQueueNames(), ", ")),
+						Required: true,
+					},
+					&cli.Int64Flag{
+						Name:  "count",
+						Usage: "Number of messages to print",
+						Value: 10,
+					},
+					&cli.BoolFlag{
+						Name:  "raw",
+						Usage: "Print the messages as they are stored rather than the upgraded payload",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Int64("count") <= 0 {
+						return errors.New("Count must be greater than 0")
+					}
+
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					messages, err := Peek(c.String("queue"), c.Int64("count"))
+					if err != nil {
+						return err
+					}
+
+					for _, message := range messages {
+						if message.Error != nil {
+							fmt.Printf("#%d invalid: %s\n%s\n", message.Position, message.Error, message.Raw)
+							continue
+						}
+
+						fmt.Printf("#%d %s v%d\n", message.Position, message.Envelope.Type, message.Envelope.SchemaVersion)
+						if c.Bool("raw") {
+							fmt.Println(message.Raw)
+						} else {
+							fmt.Println(string(message.Envelope.Payload))
+						}
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "convert",
+				Usage: "rewrite the messages in a queue in another schema version",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "queue",
+						Usage:    fmt.Sprintf("Queue to convert (%s)", strings.Join(GetQueueNames(), ", ")),
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "version",
+						Usage: fmt.Sprintf("Schema version to write, %d for legacy consumers", LegacySchemaVersion),
+						Value: CurrentSchemaVersion,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Count the messages that would be converted without changing the queue",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					result, err := Convert(c.String("queue"), c.Int("version"), c.Bool("dry-run"))
+					if result != nil {
+						log.Info().
+							Str("queue", c.String("queue")).
+							Int("version", c.Int("version")).
+							Int("converted", result.Converted).
+							Int("unchanged", result.Unchanged).
+							Int("failed", result.Failed).
+							Bool("dryrun", c.Bool("dry-run")).
+							Msg("Converted queue messages")
+					}
+
+					return err
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,194 @@
+package queuemessage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/util"
+)
+
+// Envelope wraps every payload written to a Redis queue so consumers can tell what it is
+// & which version of it was written, letting old & new releases share a queue during a deployment
+type Envelope struct {
+	SchemaVersion int
+	Type          MessageType
+	Payload       json.RawMessage
+}
+
+type MessageType string
+
+const (
+	MessageTypeVehicleUpdate MessageType = "VehicleUpdate"
+	MessageTypeEvent                     = "Event"
+	MessageTypeNotification              = "Notification"
+	MessageTypeTfLBusMonitor             = "TfLBusMonitor"
+	MessageTypeImportJob                 = "ImportJob"
+)
+
+const (
+	// LegacySchemaVersion is the bare JSON payloads written before the envelope existed
+	LegacySchemaVersion  = 0
+	CurrentSchemaVersion = 1
+)
+
+// The type of message each queue carries, needed to decode legacy payloads as they don't say what they are
+var queueMessageTypes = map[string]MessageType{
+	"realtime-queue": MessageTypeVehicleUpdate,
+	"events-queue":   MessageTypeEvent,
+	"notify-queue":   MessageTypeNotification,
+	"tfl-bus-queue":  MessageTypeTfLBusMonitor,
+	"import-queue":   MessageTypeImportJob,
+}
+
+// upgrader converts the payload of a message from the version it is keyed on to the next version
+type upgrader func(payload json.RawMessage) (json.RawMessage, error)
+
+// Upgraders for each message type, keyed on the version they upgrade from. Version 1 only added the envelope
+// so legacy payloads don't need changing, add an entry here when a payload changes shape & bump CurrentSchemaVersion
+var upgraders = map[MessageType]map[int]upgrader{}
+
+var writeSchemaVersion int
+var writeSchemaVersionOnce sync.Once
+
+// GetQueueMessageType returns the type of message a queue carries
+func GetQueueMessageType(queueName string) (MessageType, bool) {
+	messageType, exists := queueMessageTypes[queueName]
+
+	return messageType, exists
+}
+
+// GetQueueNames returns every queue with enveloped messages
+func GetQueueNames() []string {
+	var queueNames []string
+	for queueName := range queueMessageTypes {
+		queueNames = append(queueNames, queueName)
+	}
+	sort.Strings(queueNames)
+
+	return queueNames
+}
+
+// GetWriteSchemaVersion is the version new messages are written in. TRAVIGO_QUEUE_SCHEMA_VERSION can pin it to
+// an older version while consumers that don't understand the current one are still running
+func GetWriteSchemaVersion() int {
+	writeSchemaVersionOnce.Do(func() {
+		writeSchemaVersion = CurrentSchemaVersion
+
+		configuredVersion := util.GetEnvironmentVariables()["TRAVIGO_QUEUE_SCHEMA_VERSION"]
+		if configuredVersion == "" {
+			return
+		}
+
+		version, err := strconv.Atoi(configuredVersion)
+		if err != nil || !canEncodeVersion(version) {
+			log.Error().Str("version", configuredVersion).Msg("Unsupported TRAVIGO_QUEUE_SCHEMA_VERSION, using the current version")
+			return
+		}
+
+		writeSchemaVersion = version
+	})
+
+	return writeSchemaVersion
+}
+
+// Marshal encodes a message for publishing onto a queue
+func Marshal(messageType MessageType, message any) ([]byte, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return Encode(messageType, payload, GetWriteSchemaVersion())
+}
+
+// Encode wraps an already encoded payload in the envelope for a schema version.
+// Only the legacy & current versions can be written as payloads are never downgraded
+func Encode(messageType MessageType, payload json.RawMessage, version int) ([]byte, error) {
+	if !canEncodeVersion(version) {
+		return nil, errors.New(fmt.Sprintf("Cannot write schema version %d", version))
+	}
+
+	if version == LegacySchemaVersion {
+		return payload, nil
+	}
+
+	return json.Marshal(Envelope{
+		SchemaVersion: version,
+		Type:          messageType,
+		Payload:       payload,
+	})
+}
+
+// Decode reads a message of any version & upgrades it to the current version.
+// Legacy messages don't record their type so are assumed to be the expected type
+func Decode(data []byte, expectedType MessageType) (*Envelope, error) {
+	envelope, err := decodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope.SchemaVersion == LegacySchemaVersion {
+		envelope.Type = expectedType
+	}
+	if expectedType != "" && envelope.Type != expectedType {
+		return nil, errors.New(fmt.Sprintf("Expected a %s message but got %s", expectedType, envelope.Type))
+	}
+	if envelope.SchemaVersion > CurrentSchemaVersion {
+		return nil, errors.New(fmt.Sprintf("Schema version %d is newer than the supported version %d", envelope.SchemaVersion, CurrentSchemaVersion))
+	}
+
+	for envelope.SchemaVersion < CurrentSchemaVersion {
+		if upgrade, exists := upgraders[envelope.Type][envelope.SchemaVersion]; exists {
+			envelope.Payload, err = upgrade(envelope.Payload)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		envelope.SchemaVersion += 1
+	}
+
+	return envelope, nil
+}
+
+// Unmarshal decodes a message of any version into the current version of its payload
+func Unmarshal(data []byte, messageType MessageType, message any) error {
+	envelope, err := Decode(data, messageType)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(envelope.Payload, message)
+}
+
+// decodeEnvelope only checks for the envelope fields so a legacy payload that happens to be an object isn't mistaken for one
+func decodeEnvelope(data []byte) (*Envelope, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	_, hasVersion := fields["SchemaVersion"]
+	_, hasType := fields["Type"]
+	_, hasPayload := fields["Payload"]
+
+	if !hasVersion || !hasType || !hasPayload || len(fields) != 3 {
+		return &Envelope{SchemaVersion: LegacySchemaVersion, Payload: data}, nil
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	return &envelope, nil
+}
+
+func canEncodeVersion(version int) bool {
+	return version == LegacySchemaVersion || version == CurrentSchemaVersion
+}
@@ -0,0 +1,129 @@
+package queuemessage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// The list rmq keeps the deliveries waiting to be consumed in, the right hand end is the oldest
+const readyKeyFormat = "rmq::queue::[%s]::ready"
+
+type QueuedMessage struct {
+	// Position in the queue, 0 is the next to be consumed
+	Position int64
+	Raw      string
+
+	Envelope *Envelope
+	Error    error
+}
+
+// Peek decodes the next messages waiting in a queue without consuming them
+func Peek(queueName string, count int64) ([]QueuedMessage, error) {
+	messageType, exists := GetQueueMessageType(queueName)
+	if !exists {
+		return nil, errors.New(fmt.Sprintf("Unknown queue %s", queueName))
+	}
+
+	payloads, err := redis_client.Client.LRange(context.Background(), fmt.Sprintf(readyKeyFormat, queueName), -count, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []QueuedMessage
+	for i := len(payloads) - 1; i >= 0; i-- {
+		queuedMessage := QueuedMessage{
+			Position: int64(len(payloads) - 1 - i),
+			Raw:      payloads[i],
+		}
+		queuedMessage.Envelope, queuedMessage.Error = Decode([]byte(payloads[i]), messageType)
+
+		messages = append(messages, queuedMessage)
+	}
+
+	return messages, nil
+}
+
+type ConvertResult struct {
+	Converted int
+	Unchanged int
+	Failed    int
+}
+
+// Convert rewrites the messages waiting in a queue in the given schema version. Each message is taken off the front of
+// the queue & put back on the end so it runs alongside live consumers, messages that can't be decoded are put back as they were
+func Convert(queueName string, version int, dryRun bool) (*ConvertResult, error) {
+	messageType, exists := GetQueueMessageType(queueName)
+	if !exists {
+		return nil, errors.New(fmt.Sprintf("Unknown queue %s", queueName))
+	}
+	if !canEncodeVersion(version) {
+		return nil, errors.New(fmt.Sprintf("Cannot write schema version %d", version))
+	}
+
+	readyKey := fmt.Sprintf(readyKeyFormat, queueName)
+	result := &ConvertResult{}
+
+	if dryRun {
+		payloads, err := redis_client.Client.LRange(context.Background(), readyKey, 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, payload := range payloads {
+			convertPayload(payload, messageType, version, result)
+		}
+
+		return result, nil
+	}
+
+	// Only go through the messages that were there at the start, anything published since is already in the write version
+	length, err := redis_client.Client.LLen(context.Background(), readyKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := int64(0); i < length; i++ {
+		payload, err := redis_client.Client.RPop(context.Background(), readyKey).Result()
+		if err == redis.Nil {
+			// Consumers have emptied the queue
+			break
+		} else if err != nil {
+			return result, err
+		}
+
+		converted := convertPayload(payload, messageType, version, result)
+
+		if err := redis_client.Client.LPush(context.Background(), readyKey, converted).Err(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func convertPayload(payload string, messageType MessageType, version int, result *ConvertResult) string {
+	currentEnvelope, err := decodeEnvelope([]byte(payload))
+	if err == nil && currentEnvelope.SchemaVersion == version {
+		result.Unchanged += 1
+		return payload
+	}
+
+	envelope, err := Decode([]byte(payload), messageType)
+	if err != nil {
+		result.Failed += 1
+		return payload
+	}
+
+	converted, err := Encode(messageType, envelope.Payload, version)
+	if err != nil {
+		result.Failed += 1
+		return payload
+	}
+
+	result.Converted += 1
+	return string(converted)
+}
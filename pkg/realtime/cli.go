@@ -1,6 +1,9 @@
 package realtime
 
 import (
+	"github.com/travigo/travigo/pkg/realtime/eventlog"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
+	"github.com/travigo/travigo/pkg/realtime/journeystream"
 	"github.com/travigo/travigo/pkg/realtime/nationalrail"
 	"github.com/travigo/travigo/pkg/realtime/tflarrivals"
 	"github.com/travigo/travigo/pkg/realtime/vehicletracker"
@@ -13,8 +16,12 @@ func RegisterCLI() *cli.Command {
 		Usage: "Realtime sources",
 		Subcommands: []*cli.Command{
 			vehicletracker.RegisterCLI(),
+			vehicletracker.RegisterReplayCLI(),
 			tflarrivals.RegisterCLI(),
 			nationalrail.RegisterCLI(),
+			journeystream.RegisterCLI(),
+			feedhealth.RegisterCLI(),
+			eventlog.RegisterCLI(),
 		},
 	}
 }
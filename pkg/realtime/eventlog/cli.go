@@ -0,0 +1,147 @@
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/liip/sheriff"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/leader"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "event-log",
+		Usage: "Append only audit log of the changes made to realtime journeys",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "compact",
+				Usage: "Thin out & expire the events of finished journeys",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "after",
+						Value: 6 * time.Hour,
+						Usage: "how old events have to be before they're compacted",
+					},
+					&cli.DurationFlag{
+						Name:  "position-interval",
+						Value: 1 * time.Minute,
+						Usage: "minimum time between the position events that are kept",
+					},
+					&cli.DurationFlag{
+						Name:  "retention",
+						Value: 90 * 24 * time.Hour,
+						Usage: "how long events are kept for, 0 keeps them forever",
+					},
+					&cli.DurationFlag{
+						Name:  "repeat-every",
+						Usage: "keep running & compact again every interval, only on the replica holding the lease",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					opts := CompactOptions{
+						After:            c.Duration("after"),
+						PositionInterval: c.Duration("position-interval"),
+						Retention:        c.Duration("retention"),
+					}
+
+					if c.Duration("repeat-every") == 0 {
+						return Compact(context.Background(), opts)
+					}
+
+					leader.RunAsLeader(leader.MongoStore{}, "realtime-event-log-compaction", c.Duration("repeat-every"), func(ctx context.Context) {
+						if err := Compact(ctx, opts); err != nil {
+							log.Error().Err(err).Msg("Failed to compact realtime journey events")
+						}
+					})
+
+					return nil
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Print the event log of a realtime journey",
+				ArgsUsage: "<realtime journey identifier>",
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("Expected argument <realtime journey identifier>")
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					events, err := GetEvents(c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					for _, event := range events {
+						fmt.Println(describeEvent(event))
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "project",
+				Usage:     "Rebuild the realtime state of a journey from its event log",
+				ArgsUsage: "<realtime journey identifier>",
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("Expected argument <realtime journey identifier>")
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					projection, err := Project(c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					reducedProjection, _ := sheriff.Marshal(&sheriff.Options{
+						Groups: []string{"basic", "detailed"},
+					}, projection)
+
+					encoder := json.NewEncoder(os.Stdout)
+					encoder.SetIndent("", "  ")
+
+					return encoder.Encode(reducedProjection)
+				},
+			},
+		},
+	}
+}
+
+func describeEvent(event *ctdf.RealtimeJourneyEvent) string {
+	timestamp := event.RecordedAt.Format(time.RFC3339)
+
+	switch event.Type {
+	case ctdf.RealtimeJourneyEventPosition:
+		location := ""
+		if event.Location != nil && len(event.Location.Coordinates) == 2 {
+			location = fmt.Sprintf("%.5f,%.5f ", event.Location.Coordinates[1], event.Location.Coordinates[0])
+		}
+
+		return fmt.Sprintf("%s Position %s(%s -> %s)", timestamp, location, event.DepartedStopRef, event.NextStopRef)
+	case ctdf.RealtimeJourneyEventDelay:
+		return fmt.Sprintf("%s Delay %s", timestamp, event.Offset)
+	case ctdf.RealtimeJourneyEventPlatform:
+		return fmt.Sprintf("%s Platform %s at %s", timestamp, event.Platform, event.StopRef)
+	default:
+		return fmt.Sprintf("%s %s", timestamp, event.Type)
+	}
+}
@@ -0,0 +1,127 @@
+package eventlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const compactionWriteBatchSize = 1000
+
+type CompactOptions struct {
+	// Events are only compacted once they're this old, by then the journey they're for has finished
+	After time.Duration
+	// Only one position event is kept in every interval unless the vehicle moves on to another stop
+	PositionInterval time.Duration
+	// Events older than this are deleted altogether, 0 keeps them forever
+	Retention time.Duration
+}
+
+type compactionEvent struct {
+	ID                        primitive.ObjectID `bson:"_id"`
+	ctdf.RealtimeJourneyEvent `bson:",inline"`
+}
+
+// Compact thins out the log of finished journeys to what's needed to audit them & train the prediction model.
+// Every platform change is kept, delays only where they changed & positions at most once a PositionInterval
+// It stops early if the context is cancelled, anything not yet compacted is picked up by the next run
+func Compact(ctx context.Context, opts CompactOptions) error {
+	collection := database.GetCollection(collectionName)
+	now := time.Now()
+
+	if opts.Retention > 0 {
+		result, err := collection.DeleteMany(ctx, bson.M{"recordedat": bson.M{"$lt": now.Add(-opts.Retention)}})
+		if err != nil {
+			return err
+		}
+
+		log.Info().Int64("deleted", result.DeletedCount).Msg("Deleted expired realtime journey events")
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "realtimejourneyref", Value: 1}, {Key: "recordedat", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{
+		"compacted":  false,
+		"recordedat": bson.M{"$lt": now.Add(-opts.After)},
+	}, findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var operations []mongo.WriteModel
+	kept := 0
+	removed := 0
+
+	var journeyRef string
+	var lastPosition *ctdf.RealtimeJourneyEvent
+	var lastDelay *ctdf.RealtimeJourneyEvent
+
+	for cursor.Next(ctx) {
+		var event compactionEvent
+		if err := cursor.Decode(&event); err != nil {
+			log.Error().Err(err).Msg("Failed to decode realtime journey event")
+			continue
+		}
+
+		if event.RealtimeJourneyRef != journeyRef {
+			journeyRef = event.RealtimeJourneyRef
+			lastPosition = nil
+			lastDelay = nil
+		}
+
+		keep := true
+		switch event.Type {
+		case ctdf.RealtimeJourneyEventPosition:
+			keep = lastPosition == nil ||
+				event.RecordedAt.Sub(lastPosition.RecordedAt) >= opts.PositionInterval ||
+				event.DepartedStopRef != lastPosition.DepartedStopRef
+			if keep {
+				lastPosition = &event.RealtimeJourneyEvent
+			}
+		case ctdf.RealtimeJourneyEventDelay:
+			keep = lastDelay == nil || event.Offset != lastDelay.Offset
+			if keep {
+				lastDelay = &event.RealtimeJourneyEvent
+			}
+		}
+
+		if keep {
+			operations = append(operations, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": event.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"compacted": true}}),
+			)
+			kept += 1
+		} else {
+			operations = append(operations, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": event.ID}))
+			removed += 1
+		}
+
+		if len(operations) >= compactionWriteBatchSize {
+			if _, err := collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(operations) > 0 {
+		if _, err := collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("kept", kept).Int("removed", removed).Msg("Compacted realtime journey events")
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collectionName = "realtime_journey_events"
+
+// Enabled is whether the realtime sources should record their changes to the event log,
+// it's opt in with TRAVIGO_REALTIME_EVENT_LOG=true as it writes an event for most vehicle updates
+func Enabled() bool {
+	return util.GetEnvironmentVariables()["TRAVIGO_REALTIME_EVENT_LOG"] == "true"
+}
+
+// Append adds the events to the log, nothing is written when the event log isn't enabled
+func Append(events []*ctdf.RealtimeJourneyEvent) error {
+	if len(events) == 0 || !Enabled() {
+		return nil
+	}
+
+	documents := make([]interface{}, len(events))
+	for i, event := range events {
+		documents[i] = event
+	}
+
+	_, err := database.GetCollection(collectionName).InsertMany(context.Background(), documents, options.InsertMany().SetOrdered(false))
+
+	return err
+}
+
+// GetEvents returns the log of a realtime journey in the order the events were recorded
+func GetEvents(realtimeJourneyRef string) ([]*ctdf.RealtimeJourneyEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "recordedat", Value: 1}})
+
+	cursor, err := database.GetCollection(collectionName).Find(context.Background(), bson.M{"realtimejourneyref": realtimeJourneyRef}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*ctdf.RealtimeJourneyEvent
+	if err := cursor.All(context.Background(), &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Project rebuilds the realtime state of a journey from its log on top of the journey it's tracking. The log is an
+// audit trail, the realtime journey documents are still written directly by the realtime sources, so this is for
+// looking back at what was known about a journey including ones that have since expired from realtime_journeys.
+func Project(realtimeJourneyRef string) (*ctdf.RealtimeJourney, error) {
+	events, err := GetEvents(realtimeJourneyRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored *ctdf.RealtimeJourney
+	err = database.GetCollection("realtime_journeys").FindOne(context.Background(), bson.M{"primaryidentifier": realtimeJourneyRef}).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		if len(events) == 0 {
+			return nil, errors.New("could not find a matching Realtime Journey or any events for it")
+		}
+
+		stored, err = getExpiredRealtimeJourney(realtimeJourneyRef)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	projection := &ctdf.RealtimeJourney{
+		PrimaryIdentifier:      stored.PrimaryIdentifier,
+		OtherIdentifiers:       stored.OtherIdentifiers,
+		Journey:                stored.Journey,
+		Service:                stored.Service,
+		JourneyRunDate:         stored.JourneyRunDate,
+		CreationDateTime:       stored.CreationDateTime,
+		TimeoutDurationMinutes: stored.TimeoutDurationMinutes,
+		DataSource:             stored.DataSource,
+		Reliability:            stored.Reliability,
+		VehicleRef:             stored.VehicleRef,
+	}
+
+	for _, event := range events {
+		projection.ApplyEvent(event)
+	}
+
+	return projection, nil
+}
+
+// getExpiredRealtimeJourney recreates what's known about a realtime journey from its identifier, the run date & journey
+func getExpiredRealtimeJourney(realtimeJourneyRef string) (*ctdf.RealtimeJourney, error) {
+	// Journey identifiers can contain colons so only the first one separates them from the run date
+	runDate, journeyRef, found := strings.Cut(strings.TrimPrefix(realtimeJourneyRef, "realtime-"), ":")
+	if !found {
+		return nil, errors.New(fmt.Sprintf("%s is not a Realtime Journey identifier", realtimeJourneyRef))
+	}
+
+	stored := &ctdf.RealtimeJourney{PrimaryIdentifier: realtimeJourneyRef}
+	stored.JourneyRunDate, _ = time.Parse("2006-01-02", runDate)
+	database.GetCollection("journeys").FindOne(context.Background(), bson.M{"primaryidentifier": journeyRef}).Decode(&stored.Journey)
+
+	return stored, nil
+}
@@ -0,0 +1,80 @@
+package feedhealth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "feed-health",
+		Usage: "Monitors the message rates & parse errors of each realtime source",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "monitor",
+				Usage: "run the monitor, emitting an event when a feed goes silent",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "silence-period",
+						Value: 10 * time.Minute,
+						Usage: "How long a feed can go without receiving anything before an event is emitted",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Value: 1 * time.Minute,
+						Usage: "How often the feeds are checked",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					eventQueue, err := redis_client.QueueConnection.OpenQueue("events-queue")
+					if err != nil {
+						return err
+					}
+
+					monitor := Monitor{
+						EventQueue:    eventQueue,
+						SilencePeriod: c.Duration("silence-period"),
+					}
+					monitor.Start(c.Duration("interval"))
+
+					return nil
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "print the current health of each feed",
+				Action: func(c *cli.Context) error {
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					feeds, err := GetFeeds()
+					if err != nil {
+						return err
+					}
+
+					for _, feed := range feeds {
+						lastReceived := "never"
+						if !feed.LastReceived.IsZero() {
+							lastReceived = time.Since(feed.LastReceived).Round(time.Second).String() + " ago"
+						}
+
+						fmt.Printf("%s (%s)\n", feed.Source, feed.Format)
+						fmt.Printf("  last received: %s\n", lastReceived)
+						fmt.Printf("  rate: %.1f/min, parse errors: %.1f%%\n", feed.MessageRate, feed.ParseErrorRate*100)
+						fmt.Printf("  total: %d messages, %d parse errors\n", feed.Messages, feed.ParseErrors)
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
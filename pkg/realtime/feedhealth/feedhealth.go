@@ -0,0 +1,172 @@
+package feedhealth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// Rates are worked out over the minute buckets in this window
+const RateWindow = 15 * time.Minute
+
+const sourcesKey = "feedhealth/sources"
+const sourceKeyFormat = "feedhealth/source/%s"
+const rateBucketKeyFormat = "feedhealth/rate/%s/%d"
+
+// Feed is the health of a single realtime source. The counters live in Redis as the feeds are received by the
+// importers while the monitor & admin API run elsewhere
+type Feed struct {
+	Source string
+	Format string
+
+	Messages    int64
+	ParseErrors int64
+
+	LastReceived   time.Time
+	LastParseError time.Time
+
+	// Messages per minute & the proportion of records that failed to parse over the rate window
+	MessageRate    float64
+	ParseErrorRate float64
+
+	// Set once the monitor has emitted an event for the feed going silent, cleared when it starts receiving again
+	SilentSince time.Time
+}
+
+// RecordMessages counts records successfully received from a source
+func RecordMessages(source string, format string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	sourceKey := fmt.Sprintf(sourceKeyFormat, source)
+
+	pipeline := redis_client.Client.TxPipeline()
+	pipeline.SAdd(context.Background(), sourcesKey, source)
+	pipeline.HSet(context.Background(), sourceKey, "format", format, "lastreceived", now.Format(time.RFC3339))
+	pipeline.HIncrBy(context.Background(), sourceKey, "messages", int64(count))
+	pipeline.HDel(context.Background(), sourceKey, "silentsince")
+	incrementRateBucket(pipeline, source, "messages", count, now)
+
+	_, err := pipeline.Exec(context.Background())
+
+	return err
+}
+
+// RecordParseErrors counts records from a source that couldn't be parsed
+func RecordParseErrors(source string, format string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	sourceKey := fmt.Sprintf(sourceKeyFormat, source)
+
+	pipeline := redis_client.Client.TxPipeline()
+	pipeline.SAdd(context.Background(), sourcesKey, source)
+	pipeline.HSet(context.Background(), sourceKey, "format", format, "lastparseerror", now.Format(time.RFC3339))
+	pipeline.HIncrBy(context.Background(), sourceKey, "parseerrors", int64(count))
+	incrementRateBucket(pipeline, source, "parseerrors", count, now)
+
+	_, err := pipeline.Exec(context.Background())
+
+	return err
+}
+
+func incrementRateBucket(pipeline redis.Pipeliner, source string, field string, count int, now time.Time) {
+	bucketKey := fmt.Sprintf(rateBucketKeyFormat, source, now.Unix()/60)
+
+	pipeline.HIncrBy(context.Background(), bucketKey, field, int64(count))
+	pipeline.Expire(context.Background(), bucketKey, RateWindow+time.Minute)
+}
+
+// GetFeeds returns the health of every source that has been recorded, sorted by source
+func GetFeeds() ([]*Feed, error) {
+	sources, err := redis_client.Client.SMembers(context.Background(), sourcesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(sources)
+
+	feeds := []*Feed{}
+	for _, source := range sources {
+		feed, err := GetFeed(source)
+		if err != nil {
+			return nil, err
+		}
+
+		feeds = append(feeds, feed)
+	}
+
+	return feeds, nil
+}
+
+func GetFeed(source string) (*Feed, error) {
+	values, err := redis_client.Client.HGetAll(context.Background(), fmt.Sprintf(sourceKeyFormat, source)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Source:         source,
+		Format:         values["format"],
+		Messages:       parseInt(values["messages"]),
+		ParseErrors:    parseInt(values["parseerrors"]),
+		LastReceived:   parseTime(values["lastreceived"]),
+		LastParseError: parseTime(values["lastparseerror"]),
+		SilentSince:    parseTime(values["silentsince"]),
+	}
+
+	// The current minute is only partially filled so the window starts a minute further back
+	now := time.Now()
+	pipeline := redis_client.Client.Pipeline()
+	var buckets []*redis.MapStringStringCmd
+	for minute := now.Add(-RateWindow).Unix() / 60; minute <= now.Unix()/60; minute++ {
+		buckets = append(buckets, pipeline.HGetAll(context.Background(), fmt.Sprintf(rateBucketKeyFormat, source, minute)))
+	}
+	if _, err := pipeline.Exec(context.Background()); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var windowMessages int64
+	var windowParseErrors int64
+	for _, bucket := range buckets {
+		windowMessages += parseInt(bucket.Val()["messages"])
+		windowParseErrors += parseInt(bucket.Val()["parseerrors"])
+	}
+
+	feed.MessageRate = float64(windowMessages) / RateWindow.Minutes()
+	if windowMessages+windowParseErrors > 0 {
+		feed.ParseErrorRate = float64(windowParseErrors) / float64(windowMessages+windowParseErrors)
+	}
+
+	return feed, nil
+}
+
+// IsSilent is true when the feed hasn't received anything in the period
+func (f *Feed) IsSilent(period time.Duration, now time.Time) bool {
+	return now.Sub(f.LastReceived) > period
+}
+
+// markSilent records that the feed has been reported as silent, returning false if it already had been
+func markSilent(source string, since time.Time) (bool, error) {
+	return redis_client.Client.HSetNX(context.Background(), fmt.Sprintf(sourceKeyFormat, source), "silentsince", since.Format(time.RFC3339)).Result()
+}
+
+func parseInt(value string) int64 {
+	parsed, _ := strconv.ParseInt(value, 10, 64)
+
+	return parsed
+}
+
+func parseTime(value string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339, value)
+
+	return parsed
+}
@@ -0,0 +1,79 @@
+package feedhealth
+
+import (
+	"context"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/leader"
+	"github.com/travigo/travigo/pkg/queuemessage"
+)
+
+type Monitor struct {
+	EventQueue rmq.Queue
+
+	// How long a feed can go without receiving anything before an event is emitted for it
+	SilencePeriod time.Duration
+}
+
+// Run checks every feed once, emitting an event for each one that has newly gone silent.
+// Only a single event is emitted per silence as the feed is marked until it starts receiving again
+func (m *Monitor) Run() error {
+	feeds, err := GetFeeds()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, feed := range feeds {
+		log.Debug().
+			Str("source", feed.Source).
+			Float64("rate", feed.MessageRate).
+			Float64("parseerrorrate", feed.ParseErrorRate).
+			Time("lastreceived", feed.LastReceived).
+			Msg("Checked realtime feed")
+
+		if !feed.IsSilent(m.SilencePeriod, now) {
+			continue
+		}
+
+		newlySilent, err := markSilent(feed.Source, feed.LastReceived)
+		if err != nil {
+			log.Error().Err(err).Str("source", feed.Source).Msg("Failed to mark feed as silent")
+			continue
+		}
+		if !newlySilent {
+			continue
+		}
+		feed.SilentSince = feed.LastReceived
+
+		log.Warn().
+			Str("source", feed.Source).
+			Time("lastreceived", feed.LastReceived).
+			Msg("Realtime feed has gone silent")
+
+		if m.EventQueue != nil {
+			eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, ctdf.Event{
+				Type:      ctdf.EventTypeRealtimeFeedSilent,
+				Timestamp: now,
+				Body:      feed,
+			})
+			m.EventQueue.PublishBytes(eventBytes)
+		}
+	}
+
+	return nil
+}
+
+// Start runs the monitor on every interval, it never returns.
+// Only the leading replica checks the feeds so silent feeds aren't reported more than once
+func (m *Monitor) Start(interval time.Duration) {
+	leader.RunAsLeader(leader.RedisStore{}, "feed-health-monitor", interval, func(_ context.Context) {
+		if err := m.Run(); err != nil {
+			log.Error().Err(err).Msg("Failed to check realtime feeds")
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package journeystream
+
+import (
+	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/urfave/cli/v2"
+)
+
+func RegisterCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "journey-stream",
+		Usage: "Streams realtime journey updates from the vehicle tracker to subscribed clients",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "run",
+				Usage: "run the journey stream server",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: ":8083",
+						Usage: "listen target for the web server",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					return SetupServer(c.String("listen"))
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package journeystream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// Number of updates a subscriber can fall behind by before updates to it get dropped
+const subscriberBufferSize = 256
+
+// Filter selects the updates a subscriber receives, an update has to match every field that is set
+type Filter struct {
+	JourneyRef  string
+	ServiceRef  string
+	BoundingBox []float64 // min longitude, min latitude, max longitude, max latitude
+}
+
+func (filter *Filter) IsEmpty() bool {
+	return filter.JourneyRef == "" && filter.ServiceRef == "" && len(filter.BoundingBox) != 4
+}
+
+func (filter *Filter) Matches(update *ctdf.RealtimeJourneyUpdate) bool {
+	if filter.JourneyRef != "" && filter.JourneyRef != update.JourneyRef && filter.JourneyRef != update.RealtimeJourneyRef {
+		return false
+	}
+
+	if filter.ServiceRef != "" && filter.ServiceRef != update.ServiceRef {
+		return false
+	}
+
+	if len(filter.BoundingBox) == 4 {
+		if len(update.VehicleLocation.Coordinates) != 2 {
+			return false
+		}
+
+		longitude := update.VehicleLocation.Coordinates[0]
+		latitude := update.VehicleLocation.Coordinates[1]
+
+		if longitude < filter.BoundingBox[0] || latitude < filter.BoundingBox[1] || longitude > filter.BoundingBox[2] || latitude > filter.BoundingBox[3] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type Subscription struct {
+	Filter  Filter
+	Updates chan *ctdf.RealtimeJourneyUpdate
+}
+
+// Hub fans the updates published by the vehicle tracker out to the subscribers connected to this instance
+type Hub struct {
+	mutex         sync.RWMutex
+	subscriptions map[*Subscription]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscriptions: map[*Subscription]bool{},
+	}
+}
+
+func (hub *Hub) Subscribe(filter Filter) *Subscription {
+	subscription := &Subscription{
+		Filter:  filter,
+		Updates: make(chan *ctdf.RealtimeJourneyUpdate, subscriberBufferSize),
+	}
+
+	hub.mutex.Lock()
+	hub.subscriptions[subscription] = true
+	hub.mutex.Unlock()
+
+	return subscription
+}
+
+func (hub *Hub) Unsubscribe(subscription *Subscription) {
+	hub.mutex.Lock()
+	delete(hub.subscriptions, subscription)
+	hub.mutex.Unlock()
+}
+
+func (hub *Hub) Subscribers() int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	return len(hub.subscriptions)
+}
+
+// Listen subscribes to the published realtime journey updates. Blocks so should be run as a goroutine
+func (hub *Hub) Listen() {
+	pubsub := redis_client.Client.Subscribe(context.Background(), RealtimeJourneyUpdatesChannel)
+	defer pubsub.Close()
+
+	for message := range pubsub.Channel() {
+		var update *ctdf.RealtimeJourneyUpdate
+		if err := json.Unmarshal([]byte(message.Payload), &update); err != nil {
+			log.Error().Err(err).Msg("Failed to decode realtime journey update")
+			continue
+		}
+
+		hub.dispatch(update)
+	}
+
+	log.Error().Msg("Realtime journey update subscription closed")
+}
+
+func (hub *Hub) dispatch(update *ctdf.RealtimeJourneyUpdate) {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	for subscription := range hub.subscriptions {
+		if !subscription.Filter.Matches(update) {
+			continue
+		}
+
+		// Never block the other subscribers on a slow client, it'll just miss some movements
+		select {
+		case subscription.Updates <- update:
+		default:
+		}
+	}
+}
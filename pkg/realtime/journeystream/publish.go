@@ -0,0 +1,32 @@
+package journeystream
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+const RealtimeJourneyUpdatesChannel = "realtime-journey-updates"
+
+// Publish sends the updates to every stream server over Redis pub/sub in a single round trip
+func Publish(updates []*ctdf.RealtimeJourneyUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	pipeline := redis_client.Client.Pipeline()
+	for _, update := range updates {
+		updateBytes, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+
+		pipeline.Publish(context.Background(), RealtimeJourneyUpdatesChannel, updateBytes)
+	}
+
+	_, err := pipeline.Exec(context.Background())
+
+	return err
+}
@@ -0,0 +1,124 @@
+package journeystream
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/travigo/travigo/pkg/http_server"
+)
+
+// Comment lines are sent this often so proxies don't close quiet streams
+const keepaliveInterval = 30 * time.Second
+
+func SetupServer(listen string) error {
+	hub := NewHub()
+	go hub.Listen()
+
+	webApp := fiber.New()
+	webApp.Use(http_server.NewLogger())
+
+	group := webApp.Group("/realtime_journeys")
+	group.Get("/stream", func(c *fiber.Ctx) error {
+		return streamRealtimeJourneys(c, hub)
+	})
+	group.Get("/stream/subscribers", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"subscribers": hub.Subscribers(),
+		})
+	})
+
+	return webApp.Listen(listen)
+}
+
+// streamRealtimeJourneys sends the matching realtime journey updates as server sent events until the client disconnects
+func streamRealtimeJourneys(c *fiber.Ctx, hub *Hub) error {
+	filter := Filter{
+		JourneyRef: c.Query("journey"),
+		ServiceRef: c.Query("service"),
+	}
+
+	if c.Query("bbox") != "" {
+		boundingBox, err := parseBoundingBox(c.Query("bbox"))
+		if err != nil {
+			c.SendStatus(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		filter.BoundingBox = boundingBox
+	}
+
+	if filter.IsEmpty() {
+		c.SendStatus(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "At least one of journey, service or bbox must be provided",
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	subscription := hub.Subscribe(filter)
+
+	c.Context().SetBodyStreamWriter(func(writer *bufio.Writer) {
+		defer hub.Unsubscribe(subscription)
+
+		keepalive := time.NewTicker(keepaliveInterval)
+		defer keepalive.Stop()
+
+		// Let the client know the stream is open straight away
+		fmt.Fprint(writer, ": connected\n\n")
+
+		for {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case update := <-subscription.Updates:
+				updateBytes, err := json.Marshal(update)
+				if err != nil {
+					continue
+				}
+
+				fmt.Fprintf(writer, "event: update\ndata: %s\n\n", updateBytes)
+			case <-keepalive.C:
+				fmt.Fprint(writer, ": keepalive\n\n")
+			}
+		}
+	})
+
+	return nil
+}
+
+func parseBoundingBox(value string) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("Parameter bbox should be min longitude, min latitude, max longitude, max latitude")
+	}
+
+	var boundingBox []float64
+	for _, part := range parts {
+		coordinate, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.New("Parameter bbox should only contain numbers")
+		}
+
+		boundingBox = append(boundingBox, coordinate)
+	}
+
+	if boundingBox[0] > boundingBox[2] || boundingBox[1] > boundingBox[3] {
+		return nil, errors.New("Parameter bbox minimums should be smaller than the maximums")
+	}
+
+	return boundingBox, nil
+}
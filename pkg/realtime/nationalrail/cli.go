@@ -1,14 +1,17 @@
 package nationalrail
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/leader"
 	"github.com/travigo/travigo/pkg/realtime/nationalrail/darwin"
 	"github.com/travigo/travigo/pkg/realtime/nationalrail/nrod"
+	"github.com/travigo/travigo/pkg/realtime/nationalrail/positions"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"github.com/travigo/travigo/pkg/util"
 	"github.com/urfave/cli/v2"
@@ -100,6 +103,33 @@ func RegisterCLI() *cli.Command {
 					// bytes, _ := io.ReadAll(file)
 					// stompClient.ParseMessages(bytes)
 
+					return nil
+				},
+			},
+			{
+				Name:  "positions",
+				Usage: "run the interpolator that moves trains along their route between reports",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "interval",
+						Value: positions.DefaultInterval,
+						Usage: "how often the train positions are updated",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					log.Info().Msg("Starting train position interpolator")
+
+					interpolator := positions.NewInterpolator()
+					leader.RunAsLeader(leader.MongoStore{}, "train-position-interpolator", c.Duration("interval"), func(_ context.Context) {
+						if err := interpolator.Update(); err != nil {
+							log.Error().Err(err).Msg("Failed to interpolate train positions")
+						}
+					})
+
 					return nil
 				},
 			},
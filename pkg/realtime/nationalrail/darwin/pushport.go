@@ -11,6 +11,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/realtime/eventlog"
 	"github.com/travigo/travigo/pkg/realtime/nationalrail/railutils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -36,11 +37,11 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 	}
 
 	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
-	journeysCollection := database.GetCollection("journeys")
 	stopsCollection := database.GetCollection("stops")
 	retryRecordsCollection := database.GetCollection("retry_records")
 
 	// Parse Train Statuses
+	var events []*ctdf.RealtimeJourneyEvent
 	for _, trainStatus := range p.TrainStatuses {
 		realtimeJourneyID := fmt.Sprintf("gb-nationalrailrealtime-%s:%s", trainStatus.SSD, trainStatus.UID)
 		searchQuery := bson.M{"primaryidentifier": realtimeJourneyID}
@@ -52,22 +53,8 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 		newRealtimeJourney := false
 		if realtimeJourney == nil {
 			// Find the journey for this train
-			var journey *ctdf.Journey
-			cursor, _ := journeysCollection.Find(context.Background(), bson.M{"otheridentifiers.TrainUID": trainStatus.UID})
-
 			journeyDate, _ := time.Parse("2006-01-02", trainStatus.SSD)
-
-			for cursor.Next(context.Background()) {
-				var potentialJourney *ctdf.Journey
-				err := cursor.Decode(&potentialJourney)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to decode Journey")
-				}
-
-				if potentialJourney.Availability.MatchDate(journeyDate) {
-					journey = potentialJourney
-				}
-			}
+			journey := findJourney(trainStatus.RID, trainStatus.UID, journeyDate)
 
 			if journey == nil {
 				log.Debug().Str("uid", trainStatus.UID).Msg("Failed to find respective Journey for this train status update")
@@ -80,7 +67,7 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 			realtimeJourney = &ctdf.RealtimeJourney{
 				PrimaryIdentifier:      realtimeJourneyID,
 				ActivelyTracked:        true,
-				TimeoutDurationMinutes: 181,
+				TimeoutDurationMinutes: ctdf.GetRealtimeJourneyTimeoutMinutes(ctdf.TransportTypeRail, datasource),
 				CreationDateTime:       now,
 				Reliability:            ctdf.RealtimeJourneyReliabilityExternalProvided,
 
@@ -163,6 +150,17 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 			}
 
 			if location.Platform != nil && location.Platform.CISPLATSUP != "true" && location.Platform.PLATSUP != "true" {
+				if location.Platform.Name != "" && location.Platform.Name != journeyStop.Platform && journeyStopUpdated {
+					events = append(events, &ctdf.RealtimeJourneyEvent{
+						RealtimeJourneyRef: realtimeJourney.PrimaryIdentifier,
+						Type:               ctdf.RealtimeJourneyEventPlatform,
+						RecordedAt:         now,
+						DataSource:         datasource,
+						StopRef:            stop.PrimaryIdentifier,
+						Platform:           location.Platform.Name,
+					})
+				}
+
 				journeyStop.Platform = location.Platform.Name
 			}
 
@@ -200,6 +198,10 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 		queue.Add(updateModel)
 	}
 
+	if err := eventlog.Append(events); err != nil {
+		log.Error().Err(err).Msg("Failed to append to the realtime journey event log")
+	}
+
 	// Schedules
 	for _, schedule := range p.Schedules {
 		realtimeJourneyID := fmt.Sprintf("gb-nationalrailrealtime-%s:%s", schedule.SSD, schedule.UID)
@@ -212,22 +214,8 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 		newRealtimeJourney := false
 		if realtimeJourney == nil {
 			// Find the journey for this train
-			var journey *ctdf.Journey
-			cursor, _ := journeysCollection.Find(context.Background(), bson.M{"otheridentifiers.TrainUID": schedule.UID})
-
 			journeyDate, _ := time.Parse("2006-01-02", schedule.SSD)
-
-			for cursor.Next(context.Background()) {
-				var potentialJourney *ctdf.Journey
-				err := cursor.Decode(&potentialJourney)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to decode Journey")
-				}
-
-				if potentialJourney.Availability.MatchDate(journeyDate) {
-					journey = potentialJourney
-				}
-			}
+			journey := findJourney(schedule.RID, schedule.UID, journeyDate)
 
 			if journey == nil {
 				log.Debug().Str("uid", schedule.UID).Msg("Failed to find respective Journey for this train schedule update")
@@ -240,7 +228,7 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 			realtimeJourney = &ctdf.RealtimeJourney{
 				PrimaryIdentifier:      realtimeJourneyID,
 				ActivelyTracked:        false,
-				TimeoutDurationMinutes: 181,
+				TimeoutDurationMinutes: ctdf.GetRealtimeJourneyTimeoutMinutes(ctdf.TransportTypeRail, datasource),
 				CreationDateTime:       now,
 				Reliability:            ctdf.RealtimeJourneyReliabilityExternalProvided,
 
@@ -476,7 +464,12 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 			var realtimeCarriages []ctdf.RailCarriage
 
 			// TODO: only handing the 1 formation here :(
-			for _, carriage := range scheduleFormation.Formations[0].Coaches {
+			if len(scheduleFormation.Formations) == 0 {
+				continue
+			}
+			formation := scheduleFormation.Formations[0]
+
+			for _, carriage := range formation.Coaches {
 				var toilets []ctdf.RailCarriageToilet
 
 				for _, toilet := range carriage.Toilets {
@@ -493,6 +486,8 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 				})
 			}
 			realtimeJourney.DetailedRailInformation.Carriages = realtimeCarriages
+			realtimeJourney.DetailedRailInformation.FormationID = formation.FID
+			realtimeJourney.DetailedRailInformation.CoachCount = len(realtimeCarriages)
 
 			updateMap := bson.M{}
 			updateMap["detailedrailinformation"] = realtimeJourney.DetailedRailInformation
@@ -530,9 +525,9 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 				occupancy, _ := strconv.Atoi(loading.LoadingPercentage)
 				carriageFound := false
 
-				for _, carriage := range realtimeJourney.DetailedRailInformation.Carriages {
-					if carriage.ID == loading.CoachNumber {
-						carriage.Occupancy = occupancy
+				for i := range realtimeJourney.DetailedRailInformation.Carriages {
+					if realtimeJourney.DetailedRailInformation.Carriages[i].ID == loading.CoachNumber {
+						realtimeJourney.DetailedRailInformation.Carriages[i].Occupancy = occupancy
 						carriageFound = true
 						break
 					}
@@ -550,10 +545,23 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 			totalOccupancy := 0
 			totalCapacity := 0
 
+			// Carriages without a loading yet are left out rather than counted as empty
 			for _, carriage := range realtimeJourney.DetailedRailInformation.Carriages {
+				if carriage.Occupancy < 0 {
+					continue
+				}
+
 				totalCapacity += 100
 				totalOccupancy += carriage.Occupancy
 			}
+			if totalCapacity == 0 {
+				continue
+			}
+
+			realtimeJourney.DetailedRailInformation.CoachCount = len(realtimeJourney.DetailedRailInformation.Carriages)
+			if loadingStop := stopCache.Get(fmt.Sprintf("gb-tiploc-%s", formationLoading.TPL)); loadingStop != nil {
+				realtimeJourney.DetailedRailInformation.LoadingStopRef = loadingStop.PrimaryIdentifier
+			}
 
 			realtimeJourney.Occupancy = ctdf.RealtimeJourneyOccupancy{
 				OccupancyAvailable:       true,
@@ -575,3 +583,34 @@ func (p *PushPortData) UpdateRealtimeJourneys(queue *railutils.BatchProcessingQu
 		}
 	}
 }
+
+// findJourney gets the timetabled journey for a train. Journeys from the Darwin timetable are matched exactly
+// on their RID, otherwise its the journey with the trains UID that runs on the day
+func findJourney(rid string, uid string, journeyDate time.Time) *ctdf.Journey {
+	journeysCollection := database.GetCollection("journeys")
+
+	var journey *ctdf.Journey
+	if rid != "" {
+		journeysCollection.FindOne(context.Background(), bson.M{"otheridentifiers.DarwinRID": rid}).Decode(&journey)
+		if journey != nil {
+			return journey
+		}
+	}
+
+	cursor, _ := journeysCollection.Find(context.Background(), bson.M{"otheridentifiers.TrainUID": uid})
+
+	for cursor.Next(context.Background()) {
+		var potentialJourney *ctdf.Journey
+		err := cursor.Decode(&potentialJourney)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode Journey")
+			continue
+		}
+
+		if potentialJourney.RunsOn(journeyDate) {
+			journey = potentialJourney
+		}
+	}
+
+	return journey
+}
@@ -20,6 +20,6 @@ type FormationCoach struct {
 }
 
 type FormationCoachToilet struct {
-	Status string `xml:"coachNumber,attr"`
+	Status string `xml:"status,attr"`
 	Type   string `xml:",chardata"`
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-stomp/stomp/v3"
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
 	"github.com/travigo/travigo/pkg/realtime/nationalrail/railutils"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -20,6 +21,8 @@ type StompClient struct {
 
 var stopCache railutils.StopCache
 
+const feedHealthSource = "gb-nationalrail-darwin"
+
 func (s *StompClient) Run() {
 	railutils.LoadLateAndCancelledReasons()
 
@@ -71,9 +74,14 @@ func (s *StompClient) Run() {
 			pushPortData, err := ParseXMLFile(gzipDecoder)
 			gzipDecoder.Close()
 			if err != nil {
+				feedhealth.RecordParseErrors(feedHealthSource, "darwin", 1)
 				log.Fatal().Err(err).Msg("Failed to parse push port data xml")
 			}
 
+			if err := feedhealth.RecordMessages(feedHealthSource, "darwin", 1); err != nil {
+				log.Error().Err(err).Msg("Failed to record feed health")
+			}
+
 			go pushPortData.UpdateRealtimeJourneys(queue)
 		}
 	}
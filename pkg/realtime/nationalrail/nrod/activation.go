@@ -69,10 +69,11 @@ func (a *TrustActivation) Process(stompClient *StompClient) {
 			err := cursor.Decode(&potentialJourney)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to decode Journey")
+				continue
 			}
 			journeyPotentials += 1
 
-			if potentialJourney.Availability.MatchDate(journeyDate) {
+			if potentialJourney.RunsOn(journeyDate) {
 				journey = potentialJourney
 			}
 		}
@@ -91,7 +92,7 @@ func (a *TrustActivation) Process(stompClient *StompClient) {
 				"TrainID":  a.TrainID,
 				"TrainUID": a.TrainUID,
 			},
-			TimeoutDurationMinutes: 181,
+			TimeoutDurationMinutes: ctdf.GetRealtimeJourneyTimeoutMinutes(ctdf.TransportTypeRail, datasource),
 			ActivelyTracked:        false,
 			CreationDateTime:       now,
 			Reliability:            ctdf.RealtimeJourneyReliabilityExternalProvided,
@@ -3,6 +3,7 @@ package nrod
 import (
 	"fmt"
 
+	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/dataaggregator/source/cachedresults"
 	"github.com/travigo/travigo/pkg/util"
@@ -25,4 +26,8 @@ func cacheBustJourney(journey *ctdf.Journey) {
 		cacheItemPath := fmt.Sprintf("cachedresults/departureboardjourneys/%s/*", stopID)
 		cachedresults.DeletePrefix(cacheItemPath)
 	}
+
+	if err := cachedresults.PublishDepartureBoardInvalidation(stopIDs); err != nil {
+		log.Error().Err(err).Msg("Failed to publish departure board invalidation")
+	}
 }
@@ -0,0 +1,177 @@
+package positions
+
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// Estimate is where a train is expected to be between two reports
+type Estimate struct {
+	Location ctdf.Location
+	Bearing  float64
+
+	PathIndex          int
+	ProgressPercentage float64
+}
+
+// StopLocations gives the location of a stop from any of its identifiers, nil when it isn't known
+type StopLocations func(stopRef string) *ctdf.Location
+
+// EstimatePosition works out where along its route the train should be at the time. The latest estimated or actual
+// times at each stop are used where the journey has them, otherwise the scheduled time moved by the journeys offset,
+// and the train is assumed to cover each leg at a steady speed along the track between the stops
+func EstimatePosition(realtimeJourney *ctdf.RealtimeJourney, stopLocations StopLocations, now time.Time) *Estimate {
+	if realtimeJourney.Journey == nil || len(realtimeJourney.Journey.Path) == 0 {
+		return nil
+	}
+
+	path := realtimeJourney.Journey.Path
+
+	serviceDay := now
+	if !realtimeJourney.JourneyRunDate.IsZero() {
+		serviceDay = time.Date(realtimeJourney.JourneyRunDate.Year(), realtimeJourney.JourneyRunDate.Month(), realtimeJourney.JourneyRunDate.Day(), 0, 0, 0, 0, now.Location())
+	}
+
+	// Stops the train is known to have left can't be gone back to even if their times say otherwise
+	startIndex := 0
+	if realtimeJourney.DepartedStopRef != "" {
+		for i, pathItem := range path {
+			if pathItem.OriginStopRef == realtimeJourney.DepartedStopRef {
+				startIndex = i
+				break
+			}
+		}
+	}
+
+	pathIndex := len(path) - 1
+	legProgress := 1.0
+
+	for i := startIndex; i < len(path); i++ {
+		pathItem := path[i]
+
+		departure := getStopTime(realtimeJourney, pathItem.OriginStopRef, false, pathItem.GetOriginDepartureDateTime(serviceDay))
+		arrival := getStopTime(realtimeJourney, pathItem.DestinationStopRef, true, pathItem.GetDestinationArrivalDateTime(serviceDay))
+
+		// A late departure without a new arrival estimate yet still takes the scheduled run time
+		if !arrival.After(departure) {
+			arrival = departure.Add(pathItem.GetDestinationArrivalDateTime(serviceDay).Sub(pathItem.GetOriginDepartureDateTime(serviceDay)))
+		}
+
+		if now.After(arrival) {
+			continue
+		}
+
+		pathIndex = i
+		legProgress = 0
+
+		if now.After(departure) && arrival.After(departure) {
+			legProgress = float64(now.Sub(departure)) / float64(arrival.Sub(departure))
+		}
+
+		break
+	}
+
+	legGeometry := getLegGeometry(path[pathIndex], stopLocations)
+	if legGeometry == nil {
+		return nil
+	}
+
+	location, bearing := getPointAlong(legGeometry, legProgress)
+
+	estimate := &Estimate{
+		Location:  location,
+		Bearing:   bearing,
+		PathIndex: pathIndex,
+	}
+
+	// Route progress is only given when every leg has a length, otherwise it would jump around
+	var totalDistance, travelledDistance float64
+	for i, pathItem := range path {
+		legDistance := float64(pathItem.Distance)
+		if legDistance == 0 {
+			legDistance = pathItem.CalculateDistance(stopLocations(pathItem.OriginStopRef), stopLocations(pathItem.DestinationStopRef))
+		}
+		if legDistance == 0 {
+			return estimate
+		}
+
+		if i < pathIndex {
+			travelledDistance += legDistance
+		} else if i == pathIndex {
+			travelledDistance += legDistance * legProgress
+		}
+		totalDistance += legDistance
+	}
+	estimate.ProgressPercentage = (travelledDistance / totalDistance) * 100
+
+	return estimate
+}
+
+// getStopTime is the latest time we have for the train arriving at or departing from a stop
+func getStopTime(realtimeJourney *ctdf.RealtimeJourney, stopRef string, arrival bool, scheduled time.Time) time.Time {
+	if stop := realtimeJourney.Stops[stopRef]; stop != nil {
+		if arrival && !stop.ArrivalTime.IsZero() {
+			return stop.ArrivalTime
+		}
+		if !arrival && !stop.DepartureTime.IsZero() {
+			return stop.DepartureTime
+		}
+	}
+
+	return scheduled.Add(realtimeJourney.Offset)
+}
+
+// getLegGeometry is the track of the leg or, when it doesn't have one, the straight line between its stops
+func getLegGeometry(pathItem *ctdf.JourneyPathItem, stopLocations StopLocations) []ctdf.Location {
+	if len(pathItem.Track) >= 2 {
+		return pathItem.Track
+	}
+
+	origin := stopLocations(pathItem.OriginStopRef)
+	destination := stopLocations(pathItem.DestinationStopRef)
+	if origin == nil || destination == nil {
+		return nil
+	}
+
+	return []ctdf.Location{*origin, *destination}
+}
+
+// getPointAlong gives the point the fraction of the way along the line & the bearing of the line there
+func getPointAlong(line []ctdf.Location, fraction float64) (ctdf.Location, float64) {
+	var lineLength float64
+	for i := 0; i < len(line)-1; i++ {
+		lineLength += line[i].GreatCircleDistance(&line[i+1])
+	}
+
+	target := lineLength * fraction
+
+	var travelled float64
+	for i := 0; i < len(line)-1; i++ {
+		a := line[i]
+		b := line[i+1]
+		segmentLength := a.GreatCircleDistance(&b)
+
+		if travelled+segmentLength >= target && segmentLength > 0 {
+			segmentFraction := (target - travelled) / segmentLength
+
+			return ctdf.Location{
+				Type: "Point",
+				Coordinates: []float64{
+					a.Coordinates[0] + segmentFraction*(b.Coordinates[0]-a.Coordinates[0]),
+					a.Coordinates[1] + segmentFraction*(b.Coordinates[1]-a.Coordinates[1]),
+				},
+			}, a.Bearing(&b)
+		}
+
+		travelled += segmentLength
+	}
+
+	last := line[len(line)-1]
+	bearing := 0.0
+	if len(line) >= 2 {
+		bearing = line[len(line)-2].Bearing(&last)
+	}
+
+	return ctdf.Location{Type: "Point", Coordinates: []float64{last.Coordinates[0], last.Coordinates[1]}}, bearing
+}
@@ -0,0 +1,182 @@
+package positions
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DefaultInterval = 15 * time.Second
+
+const writeBatchSize = 500
+
+// Interpolator moves the trains that only report at stations & signal berths along their route between reports.
+// Journeys with their own vehicle positions are left alone
+type Interpolator struct {
+	stopLocations map[string]*ctdf.Location
+}
+
+func NewInterpolator() *Interpolator {
+	return &Interpolator{
+		stopLocations: map[string]*ctdf.Location{},
+	}
+}
+
+// Update estimates the position of every actively tracked train & writes them to their realtime journeys
+func (i *Interpolator) Update() error {
+	now := time.Now()
+	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "modificationdatetime", Value: 1},
+		{Key: "timeoutdurationminutes", Value: 1},
+		{Key: "journeyrundate", Value: 1},
+		{Key: "departedstopref", Value: 1},
+		{Key: "stops", Value: 1},
+		{Key: "offset", Value: 1},
+		{Key: "journey.path", Value: 1},
+	})
+	cursor, err := realtimeJourneysCollection.Find(context.Background(), bson.M{
+		"activelytracked":       true,
+		"cancelled":             bson.M{"$ne": true},
+		"reliability":           ctdf.RealtimeJourneyReliabilityExternalProvided,
+		"journey.transporttype": ctdf.TransportTypeRail,
+		"modificationdatetime":  bson.M{"$gt": ctdf.GetActiveRealtimeJourneyCutOffDate()},
+	}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var realtimeJourneys []*ctdf.RealtimeJourney
+	for cursor.Next(context.Background()) {
+		var realtimeJourney *ctdf.RealtimeJourney
+		if err := cursor.Decode(&realtimeJourney); err != nil {
+			log.Error().Err(err).Msg("Failed to decode Realtime Journey")
+			continue
+		}
+
+		if now.Sub(realtimeJourney.ModificationDateTime) > time.Duration(realtimeJourney.TimeoutDurationMinutes)*time.Minute {
+			continue
+		}
+
+		realtimeJourneys = append(realtimeJourneys, realtimeJourney)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	i.loadStopLocations(realtimeJourneys)
+
+	var operations []mongo.WriteModel
+	estimated := 0
+
+	for _, realtimeJourney := range realtimeJourneys {
+		estimate := EstimatePosition(realtimeJourney, i.getStopLocation, now)
+		if estimate == nil {
+			continue
+		}
+
+		// The modification time is left alone so an interpolated train still times out when its reports stop
+		update := bson.M{
+			"vehiclelocation":          estimate.Location,
+			"vehiclebearing":           estimate.Bearing,
+			"vehiclelocationestimated": true,
+		}
+		if estimate.ProgressPercentage > 0 {
+			update["progresspercentage"] = estimate.ProgressPercentage
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": realtimeJourney.PrimaryIdentifier}).
+			SetUpdate(bson.M{"$set": update}),
+		)
+		estimated += 1
+
+		if len(operations) >= writeBatchSize {
+			if _, err := realtimeJourneysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := realtimeJourneysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	log.Debug().
+		Int("journeys", len(realtimeJourneys)).
+		Int("estimated", estimated).
+		Dur("duration", time.Since(now)).
+		Msg("Interpolated train positions")
+
+	return nil
+}
+
+func (i *Interpolator) getStopLocation(stopRef string) *ctdf.Location {
+	return i.stopLocations[stopRef]
+}
+
+// loadStopLocations looks up the stops of the journeys that haven't been seen before, stops don't move so
+// they're kept for as long as the interpolator runs
+func (i *Interpolator) loadStopLocations(realtimeJourneys []*ctdf.RealtimeJourney) {
+	missing := map[string]bool{}
+	for _, realtimeJourney := range realtimeJourneys {
+		for _, pathItem := range realtimeJourney.Journey.Path {
+			for _, stopRef := range []string{pathItem.OriginStopRef, pathItem.DestinationStopRef} {
+				if _, exists := i.stopLocations[stopRef]; !exists {
+					missing[stopRef] = true
+				}
+			}
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	var stopRefs []string
+	for stopRef := range missing {
+		stopRefs = append(stopRefs, stopRef)
+		// Remembered as unknown unless it turns up below so it isn't looked up again every update
+		i.stopLocations[stopRef] = nil
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "location", Value: 1},
+	})
+	cursor, err := database.GetCollection("stops").Find(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"primaryidentifier": bson.M{"$in": stopRefs}},
+		bson.M{"otheridentifiers": bson.M{"$in": stopRefs}},
+	}}, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up stop locations")
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var stop *ctdf.Stop
+		if err := cursor.Decode(&stop); err != nil || stop.Location == nil || len(stop.Location.Coordinates) != 2 {
+			continue
+		}
+
+		for _, identifier := range stop.GetAllStopIDs() {
+			if missing[identifier] {
+				i.stopLocations[identifier] = stop.Location
+			}
+		}
+	}
+}
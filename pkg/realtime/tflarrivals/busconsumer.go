@@ -2,7 +2,6 @@ package tflarrivals
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,6 +11,7 @@ import (
 	"github.com/kr/pretty"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
 
 	"github.com/adjust/rmq/v5"
 )
@@ -48,7 +48,7 @@ func (c *BusBatchConsumer) Consume(batch rmq.Deliveries) {
 
 	for _, payload := range payloads {
 		var event BusMonitorEvent
-		err := json.Unmarshal([]byte(payload), &event)
+		err := queuemessage.Unmarshal([]byte(payload), queuemessage.MessageTypeTfLBusMonitor, &event)
 
 		if err != nil {
 			continue
@@ -210,7 +210,7 @@ func (l *ModeArrivalTracker) parseGroupedArrivals(realtimeJourneyID string, pred
 
 		realtimeJourney = &ctdf.RealtimeJourney{
 			PrimaryIdentifier:      realtimeJourneyID,
-			TimeoutDurationMinutes: 10,
+			TimeoutDurationMinutes: ctdf.GetRealtimeJourneyTimeoutMinutes(l.Mode.TransportType, datasource),
 			ActivelyTracked:        true,
 			CreationDateTime:       now,
 			VehicleRef:             predictions[0].VehicleID,
@@ -227,6 +227,8 @@ func (l *ModeArrivalTracker) parseGroupedArrivals(realtimeJourneyID string, pred
 				Service:    line.Service,
 				ServiceRef: line.Service.PrimaryIdentifier,
 
+				TransportType: l.Mode.TransportType,
+
 				DepartureTimezone: "Europe/London",
 			},
 			Service:        line.Service,
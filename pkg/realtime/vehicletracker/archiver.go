@@ -0,0 +1,100 @@
+package vehicletracker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/dataimporter/archive"
+	"github.com/travigo/travigo/pkg/util"
+)
+
+const eventArchivePrefix = "vehicle-updates"
+const eventArchiveFlushInterval = time.Minute
+
+// eventArchiver keeps the raw vehicle update events so a day can be replayed through the tracker later.
+// Events are written as gzipped newline delimited JSON, one file per instance per minute
+type eventArchiver struct {
+	store    archive.Store
+	hostname string
+
+	mutex    sync.Mutex
+	payloads []string
+}
+
+// newEventArchiver returns nil if TRAVIGO_REALTIME_ARCHIVE_URL hasn't been set
+func newEventArchiver() (*eventArchiver, error) {
+	env := util.GetEnvironmentVariables()
+
+	if env["TRAVIGO_REALTIME_ARCHIVE_URL"] == "" {
+		return nil, nil
+	}
+
+	store, err := archive.NewStore(env["TRAVIGO_REALTIME_ARCHIVE_URL"])
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	archiver := &eventArchiver{
+		store:    store,
+		hostname: hostname,
+	}
+
+	go func() {
+		for range time.Tick(eventArchiveFlushInterval) {
+			archiver.Flush()
+		}
+	}()
+
+	return archiver, nil
+}
+
+func (a *eventArchiver) Add(payloads []string) {
+	if a == nil {
+		return
+	}
+
+	a.mutex.Lock()
+	a.payloads = append(a.payloads, payloads...)
+	a.mutex.Unlock()
+}
+
+func (a *eventArchiver) Flush() {
+	a.mutex.Lock()
+	payloads := a.payloads
+	a.payloads = nil
+	a.mutex.Unlock()
+
+	if len(payloads) == 0 {
+		return
+	}
+
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	for _, payload := range payloads {
+		gzipWriter.Write([]byte(payload))
+		gzipWriter.Write([]byte("\n"))
+	}
+	gzipWriter.Close()
+
+	now := time.Now()
+	key := getEventArchiveKey(now, fmt.Sprintf("%s-%s.ndjson.gz", now.Format("150405"), a.hostname))
+
+	if err := a.store.Put(context.Background(), key, &buffer); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to archive realtime events")
+		return
+	}
+
+	log.Debug().Str("key", key).Int("events", len(payloads)).Msg("Archived realtime events")
+}
+
+func getEventArchiveKey(date time.Time, name string) string {
+	return fmt.Sprintf("%s/%s/%s", eventArchivePrefix, date.Format("2006-01-02"), name)
+}
@@ -1,13 +1,19 @@
 package vehicletracker
 
 import (
+	"errors"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/archive"
 	"github.com/travigo/travigo/pkg/elastic_client"
 	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/travigo/travigo/pkg/util"
 	"github.com/urfave/cli/v2"
 )
 
@@ -74,6 +80,70 @@ func RegisterCLI() *cli.Command {
 					return nil
 				},
 			},
+			registerGeofencesCLI(),
+		},
+	}
+}
+
+func RegisterReplayCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "replay",
+		Usage: "Replay archived vehicle updates through the vehicle tracker into a sandbox database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "date",
+				Usage:    "Date of the archived events to replay (YYYY-MM-DD)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "speed",
+				Usage: "Speed multiplier, eg. 10x",
+				Value: "1x",
+			},
+			&cli.StringFlag{
+				Name:  "sandbox-database",
+				Usage: "Database the replayed realtime journeys are written to",
+				Value: "travigo_replay",
+			},
+			&cli.StringFlag{
+				Name:  "archive",
+				Usage: "Archive URL to read from, defaults to TRAVIGO_REALTIME_ARCHIVE_URL",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			date, err := time.ParseInLocation("2006-01-02", c.String("date"), time.Local)
+			if err != nil {
+				return err
+			}
+
+			speed, err := strconv.ParseFloat(strings.TrimSuffix(c.String("speed"), "x"), 64)
+			if err != nil {
+				return err
+			}
+
+			archiveURL := c.String("archive")
+			if archiveURL == "" {
+				archiveURL = util.GetEnvironmentVariables()["TRAVIGO_REALTIME_ARCHIVE_URL"]
+			}
+			if archiveURL == "" {
+				return errors.New("TRAVIGO_REALTIME_ARCHIVE_URL must be set")
+			}
+
+			store, err := archive.NewStore(archiveURL)
+			if err != nil {
+				return err
+			}
+
+			if err := database.Connect(); err != nil {
+				return err
+			}
+
+			return Replay(ReplayOptions{
+				Date:            date,
+				Speed:           speed,
+				Store:           store,
+				SandboxDatabase: c.String("sandbox-database"),
+			})
 		},
 	}
 }
@@ -0,0 +1,330 @@
+package vehicletracker
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/dataaggregator/source/cachedresults"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/realtime/eventlog"
+	"github.com/travigo/travigo/pkg/realtime/journeystream"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How long updates are held for before being written, feeds often send several updates for a vehicle in quick succession
+const realtimeJourneyWriteWindow = 2 * time.Second
+
+// Unchanged journeys are still written this often so their modification time keeps them from timing out
+const realtimeJourneyHeartbeat = 1 * time.Minute
+
+// Fields that change on every update so don't count towards whether anything material has changed
+var realtimeJourneyVolatileFields = []string{"modificationdatetime", "datasource.timestamp"}
+
+// realtimeJourneyWrite is the change an update makes to a realtime journey
+type realtimeJourneyWrite struct {
+	PrimaryIdentifier string
+	Set               bson.M
+	// Sampled location to add to the vehicle track, nil if it's too soon after the last one
+	TrackPoint *ctdf.VehicleTrackPoint
+
+	// Stops whose departure boards need refreshing once the write has gone out
+	AffectedStopIDs []string
+	JourneyUpdate   *ctdf.RealtimeJourneyUpdate
+
+	// Changes to record in the event log, empty when it isn't enabled
+	Events []*ctdf.RealtimeJourneyEvent
+}
+
+type writtenRealtimeJourney struct {
+	fingerprint []byte
+	writtenAt   time.Time
+}
+
+// realtimeJourneyCoalescer batches up the writes to realtime_journeys. Updates to the same journey within the write window
+// are merged into a single upsert and journeys that haven't materially changed since they were last written are skipped.
+// A window of 0 writes everything as soon as it's added
+type realtimeJourneyCoalescer struct {
+	window    time.Duration
+	heartbeat time.Duration
+	// Whether departure board invalidations & journey updates are sent out once written, off for sandboxed replays
+	publish bool
+
+	pending      map[string]*realtimeJourneyWrite
+	pendingOrder []string
+	added        int
+	// Called with the outcome once the writes added alongside them have gone out
+	pendingCallbacks []func(error)
+	// Writes taken by the flush in progress, still read through until they're in the database
+	inFlight map[string]*realtimeJourneyWrite
+	mutex    sync.Mutex
+
+	// Only touched while flushing so is guarded by flushMutex
+	lastWritten map[string]writtenRealtimeJourney
+	flushMutex  sync.Mutex
+}
+
+func newRealtimeJourneyCoalescer(window time.Duration, heartbeat time.Duration) *realtimeJourneyCoalescer {
+	return &realtimeJourneyCoalescer{
+		window:      window,
+		heartbeat:   heartbeat,
+		publish:     true,
+		pending:     map[string]*realtimeJourneyWrite{},
+		lastWritten: map[string]writtenRealtimeJourney{},
+	}
+}
+
+// Start flushes the pending writes at the end of every window
+func (c *realtimeJourneyCoalescer) Start() {
+	if c.window == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.window)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			c.Flush()
+		}
+	}()
+}
+
+// Add queues up the writes, written is called once they've gone out (or failed to) & may be nil
+func (c *realtimeJourneyCoalescer) Add(writes []*realtimeJourneyWrite, written func(error)) {
+	c.mutex.Lock()
+	if written != nil {
+		c.pendingCallbacks = append(c.pendingCallbacks, written)
+	}
+	for _, write := range writes {
+		c.added += 1
+
+		existing, exists := c.pending[write.PrimaryIdentifier]
+		if !exists {
+			c.pending[write.PrimaryIdentifier] = write
+			c.pendingOrder = append(c.pendingOrder, write.PrimaryIdentifier)
+			continue
+		}
+
+		// Later updates win but anything only set by an earlier one (eg. the journey on creation) is kept
+		for key, value := range write.Set {
+			existing.Set[key] = value
+		}
+		// Points are sampled against what's already in the database so the first one in a window is kept
+		if existing.TrackPoint == nil {
+			existing.TrackPoint = write.TrackPoint
+		}
+		existing.AffectedStopIDs = append(existing.AffectedStopIDs, write.AffectedStopIDs...)
+		if write.JourneyUpdate != nil {
+			existing.JourneyUpdate = write.JourneyUpdate
+		}
+		existing.Events = append(existing.Events, write.Events...)
+	}
+	c.mutex.Unlock()
+
+	if c.window == 0 {
+		c.Flush()
+	}
+}
+
+// Flush writes out everything that's pending
+func (c *realtimeJourneyCoalescer) Flush() {
+	// Only one flush at a time so writes to the same journey can't overtake each other
+	c.flushMutex.Lock()
+	defer c.flushMutex.Unlock()
+
+	c.mutex.Lock()
+	pending := c.pending
+	pendingOrder := c.pendingOrder
+	added := c.added
+	callbacks := c.pendingCallbacks
+	c.pending = map[string]*realtimeJourneyWrite{}
+	c.pendingOrder = nil
+	c.added = 0
+	c.pendingCallbacks = nil
+	c.inFlight = pending
+	c.mutex.Unlock()
+
+	err := c.write(pending, pendingOrder, added)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to bulk write Realtime Journeys")
+	}
+
+	c.mutex.Lock()
+	c.inFlight = nil
+	c.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(err)
+	}
+}
+
+func (c *realtimeJourneyCoalescer) write(pending map[string]*realtimeJourneyWrite, pendingOrder []string, added int) error {
+	if len(pendingOrder) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var operations []mongo.WriteModel
+	var departureBoardStopIDs []string
+	var journeyUpdates []*ctdf.RealtimeJourneyUpdate
+	var events []*ctdf.RealtimeJourneyEvent
+	written := map[string]writtenRealtimeJourney{}
+	var skipped int
+
+	for _, primaryIdentifier := range pendingOrder {
+		write := pending[primaryIdentifier]
+		fingerprint := getRealtimeJourneyFingerprint(write.Set)
+
+		lastWritten, exists := c.lastWritten[primaryIdentifier]
+		if exists && write.TrackPoint == nil && bytes.Equal(lastWritten.fingerprint, fingerprint) && now.Sub(lastWritten.writtenAt) < c.heartbeat {
+			skipped += 1
+			continue
+		}
+
+		update := bson.M{"$set": write.Set}
+		if write.TrackPoint != nil {
+			update["$push"] = bson.M{
+				"vehicletrack": bson.M{
+					"$each":  bson.A{write.TrackPoint},
+					"$slice": -ctdf.VehicleTrackMaxLength,
+				},
+			}
+		}
+
+		bsonRep, _ := bson.Marshal(update)
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.M{"primaryidentifier": primaryIdentifier})
+		updateModel.SetUpdate(bsonRep)
+		updateModel.SetUpsert(true)
+
+		operations = append(operations, updateModel)
+		departureBoardStopIDs = append(departureBoardStopIDs, write.AffectedStopIDs...)
+		if write.JourneyUpdate != nil {
+			journeyUpdates = append(journeyUpdates, write.JourneyUpdate)
+		}
+		events = append(events, write.Events...)
+		written[primaryIdentifier] = writtenRealtimeJourney{fingerprint: fingerprint, writtenAt: now}
+	}
+
+	if len(operations) > 0 {
+		realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+
+		startTime := time.Now()
+		_, err := realtimeJourneysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+		log.Info().
+			Int("Updates", added).
+			Int("Length", len(operations)).
+			Int("Skipped", skipped).
+			Str("Time", time.Now().Sub(startTime).String()).
+			Msg("Bulk write realtime_journeys")
+
+		if err != nil {
+			return err
+		}
+
+		for primaryIdentifier, writtenJourney := range written {
+			c.lastWritten[primaryIdentifier] = writtenJourney
+		}
+
+		if c.publish {
+			departureBoardStopIDs = util.RemoveDuplicateStrings(departureBoardStopIDs, []string{})
+			if err := cachedresults.PublishDepartureBoardInvalidation(departureBoardStopIDs); err != nil {
+				log.Error().Err(err).Msg("Failed to publish departure board invalidation")
+			}
+
+			if err := journeystream.Publish(journeyUpdates); err != nil {
+				log.Error().Err(err).Msg("Failed to publish realtime journey updates")
+			}
+		}
+
+		if err := eventlog.Append(events); err != nil {
+			log.Error().Err(err).Msg("Failed to append to the realtime journey event log")
+		}
+	}
+
+	// Journeys that haven't been seen for a while have most likely finished
+	for primaryIdentifier, writtenJourney := range c.lastWritten {
+		if now.Sub(writtenJourney.writtenAt) > 2*c.heartbeat {
+			delete(c.lastWritten, primaryIdentifier)
+		}
+	}
+
+	return nil
+}
+
+// ApplyPending brings a realtime journey read from the database up to date with the writes to it that haven't
+// gone out yet, so updates within the write window build on each other. A nil journey is one not in the database.
+func (c *realtimeJourneyCoalescer) ApplyPending(primaryIdentifier string, realtimeJourney *ctdf.RealtimeJourney) *ctdf.RealtimeJourney {
+	type pendingState struct {
+		document   []byte
+		creates    bool
+		trackPoint *ctdf.VehicleTrackPoint
+	}
+
+	// Encoded while locked as later updates get merged into the pending write
+	var states []pendingState
+	c.mutex.Lock()
+	for _, write := range []*realtimeJourneyWrite{c.inFlight[primaryIdentifier], c.pending[primaryIdentifier]} {
+		if write == nil {
+			continue
+		}
+
+		document, err := bson.Marshal(write.Set)
+		if err != nil {
+			continue
+		}
+		_, creates := write.Set["journey"]
+
+		states = append(states, pendingState{document: document, creates: creates, trackPoint: write.TrackPoint})
+	}
+	c.mutex.Unlock()
+
+	for _, state := range states {
+		if realtimeJourney == nil {
+			if !state.creates {
+				continue
+			}
+			realtimeJourney = &ctdf.RealtimeJourney{}
+		}
+
+		// Dotted keys (eg. stops.X) don't decode but none of them are needed to work out the next update
+		if err := bson.Unmarshal(state.document, realtimeJourney); err != nil {
+			log.Error().Err(err).Str("journey", primaryIdentifier).Msg("Failed to apply pending realtime journey write")
+			continue
+		}
+
+		if state.trackPoint != nil {
+			realtimeJourney.VehicleTrack = append(realtimeJourney.VehicleTrack, state.trackPoint)
+		}
+	}
+
+	return realtimeJourney
+}
+
+// getRealtimeJourneyFingerprint encodes the material fields of an update with the keys in a stable order
+func getRealtimeJourneyFingerprint(set bson.M) []byte {
+	var keys []string
+	for key := range set {
+		if !util.ContainsString(realtimeJourneyVolatileFields, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	document := bson.D{}
+	for _, key := range keys {
+		document = append(document, bson.E{Key: key, Value: set[key]})
+	}
+
+	fingerprint, _ := bson.Marshal(document)
+
+	return fingerprint
+}
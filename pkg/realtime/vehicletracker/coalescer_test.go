@@ -0,0 +1,62 @@
+package vehicletracker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const benchmarkJourneys = 1000
+
+func newBenchmarkWrite(journey int, update int) *realtimeJourneyWrite {
+	return &realtimeJourneyWrite{
+		PrimaryIdentifier: fmt.Sprintf("realtime-journey-%d", journey),
+		Set: bson.M{
+			"modificationdatetime":   time.Now(),
+			"datasource.timestamp":   fmt.Sprintf("%d", update),
+			"vehiclelocation":        ctdf.Location{Type: "Point", Coordinates: []float64{-0.1 + float64(update)/10000, 51.5}},
+			"vehiclebearing":         float64(update % 360),
+			"departedstopref":        fmt.Sprintf("stop-%d", update%20),
+			"nextstopref":            fmt.Sprintf("stop-%d", update%20+1),
+			"offset":                 time.Duration(update) * time.Second,
+			"vehicleref":             fmt.Sprintf("vehicle-%d", journey),
+			"activelytracked":        true,
+			"timeoutdurationminutes": 10,
+		},
+		AffectedStopIDs: []string{fmt.Sprintf("stop-%d", update%20)},
+	}
+}
+
+// No Start so nothing is flushed, only the merging of updates within the window is measured
+func BenchmarkCoalescerAdd(b *testing.B) {
+	coalescer := newRealtimeJourneyCoalescer(time.Hour, realtimeJourneyHeartbeat)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		coalescer.Add([]*realtimeJourneyWrite{newBenchmarkWrite(i%benchmarkJourneys, i)}, nil)
+	}
+}
+
+func BenchmarkCoalescerApplyPending(b *testing.B) {
+	coalescer := newRealtimeJourneyCoalescer(time.Hour, realtimeJourneyHeartbeat)
+	for i := 0; i < benchmarkJourneys; i++ {
+		coalescer.Add([]*realtimeJourneyWrite{newBenchmarkWrite(i, i)}, nil)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		coalescer.ApplyPending(fmt.Sprintf("realtime-journey-%d", i%benchmarkJourneys), &ctdf.RealtimeJourney{})
+	}
+}
+
+func BenchmarkRealtimeJourneyFingerprint(b *testing.B) {
+	write := newBenchmarkWrite(0, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getRealtimeJourneyFingerprint(write.Set)
+	}
+}
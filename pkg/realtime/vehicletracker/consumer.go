@@ -12,8 +12,10 @@ import (
 	"github.com/eko/gocache/lib/v4/store"
 	redisstore "github.com/eko/gocache/store/redis/v4"
 	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/elastic_client"
+	"github.com/travigo/travigo/pkg/queuemessage"
 	"github.com/travigo/travigo/pkg/realtime/vehicletracker/identifiers"
 	"github.com/travigo/travigo/pkg/redis_client"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,7 +24,18 @@ import (
 
 var identificationCache *cache.Cache[string]
 
+// getCurrentTime is swapped out when replaying archived events so identification happens at the recorded time
+var getCurrentTime = time.Now
+
+var eventArchive *eventArchiver
+
+var realtimeJourneyWriter *realtimeJourneyCoalescer
+
 const numConsumers = 5
+
+// A vehicle is only carried forward through its block if its previous journey was matched this recently
+// and the next journey in the block departs within this long of now
+const blockCarryForwardWindow = 1 * time.Hour
 const batchSize = 200
 
 type localJourneyIDMap struct {
@@ -43,6 +56,17 @@ func StartConsumers() {
 	// Create Cache
 	CreateIdentificationCache()
 
+	// Archive the raw events so they can be replayed later
+	archiver, err := newEventArchiver()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to setup realtime event archive")
+	}
+	eventArchive = archiver
+
+	// Shared by all the consumers so updates to the same journey from different batches get merged
+	realtimeJourneyWriter = newRealtimeJourneyCoalescer(realtimeJourneyWriteWindow, realtimeJourneyHeartbeat)
+	realtimeJourneyWriter.Start()
+
 	// Run the background consumers
 	log.Info().Msg("Starting realtime consumers")
 
@@ -69,6 +93,12 @@ func startRealtimeConsumer(queue rmq.Queue, id int) {
 type BatchConsumer struct {
 	id          int
 	TfLBusQueue rmq.Queue
+	EventQueue  rmq.Queue
+
+	// Stream out the journey updates once they have been written
+	PublishJourneyUpdates bool
+
+	realtimeJourneyWriter *realtimeJourneyCoalescer
 }
 
 func NewBatchConsumer(id int) *BatchConsumer {
@@ -77,33 +107,82 @@ func NewBatchConsumer(id int) *BatchConsumer {
 		log.Fatal().Err(err).Msg("Failed to start notify queue")
 	}
 
-	return &BatchConsumer{id: id, TfLBusQueue: tfLBusQueue}
+	eventQueue, err := redis_client.QueueConnection.OpenQueue("events-queue")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to start event queue")
+	}
+
+	return &BatchConsumer{
+		id:                    id,
+		TfLBusQueue:           tfLBusQueue,
+		EventQueue:            eventQueue,
+		PublishJourneyUpdates: true,
+		realtimeJourneyWriter: realtimeJourneyWriter,
+	}
 }
 
 func (consumer *BatchConsumer) Consume(batch rmq.Deliveries) {
 	payloads := batch.Payloads()
 
-	var realtimeJourneyOperations []mongo.WriteModel
-	var serviceAlertOperations []mongo.WriteModel
+	eventArchive.Add(payloads)
 
-	for _, payload := range payloads {
-		var vehicleUpdateEvent *VehicleUpdateEvent
-		if err := json.Unmarshal([]byte(payload), &vehicleUpdateEvent); err != nil {
+	valid, realtimeJourneyWrites := consumer.processPayloads(payloads)
+
+	// Only acked once the realtime journeys have been written so a crash in the write window redelivers them
+	consumer.realtimeJourneyWriter.Add(realtimeJourneyWrites, func(err error) {
+		if !valid || err != nil {
 			if batchErrors := batch.Reject(); len(batchErrors) > 0 {
 				for _, err := range batchErrors {
 					log.Error().Err(err).Msg("Failed to reject realtime event")
 				}
 			}
+		} else if ackErrors := batch.Ack(); len(ackErrors) > 0 {
+			for _, err := range ackErrors {
+				log.Error().Err(err).Msg("Failed to consume realtime event")
+			}
+		}
+	})
+}
+
+// processPayloads identifies & applies a batch of vehicle update events, returning false if any of them couldn't be decoded
+// along with the realtime journey writes to hand to the coalescer
+func (consumer *BatchConsumer) processPayloads(payloads []string) (bool, []*realtimeJourneyWrite) {
+	valid := true
+
+	var realtimeJourneyWrites []*realtimeJourneyWrite
+	var serviceAlertOperations []mongo.WriteModel
+	var vehicleOperations []mongo.WriteModel
+	var occupancyOperations []mongo.WriteModel
+	var segmentRunTimeOperations []mongo.WriteModel
+
+	for _, payload := range payloads {
+		var vehicleUpdateEvent *VehicleUpdateEvent
+		if err := queuemessage.Unmarshal([]byte(payload), queuemessage.MessageTypeVehicleUpdate, &vehicleUpdateEvent); err != nil {
+			valid = false
+			continue
 		}
 
 		if vehicleUpdateEvent.MessageType == VehicleUpdateEventTypeTrip {
 			identifiedJourneyID := consumer.identifyVehicle(vehicleUpdateEvent, vehicleUpdateEvent.SourceType, vehicleUpdateEvent.VehicleLocationUpdate.IdentifyingInformation)
 
 			if identifiedJourneyID != "" {
-				writeModel, _ := consumer.updateRealtimeJourney(identifiedJourneyID, vehicleUpdateEvent)
+				realtimeJourneyWrite, segmentRunTimeModel, _ := consumer.updateRealtimeJourney(identifiedJourneyID, vehicleUpdateEvent)
 
-				if writeModel != nil {
-					realtimeJourneyOperations = append(realtimeJourneyOperations, writeModel)
+				if realtimeJourneyWrite != nil {
+					realtimeJourneyWrites = append(realtimeJourneyWrites, realtimeJourneyWrite)
+
+					if segmentRunTimeModel != nil {
+						segmentRunTimeOperations = append(segmentRunTimeOperations, segmentRunTimeModel)
+					}
+
+					serviceRef := consumer.getJourneyServiceRef(identifiedJourneyID)
+
+					if vehicleModel := consumer.updateVehicle(identifiedJourneyID, serviceRef, vehicleUpdateEvent); vehicleModel != nil {
+						vehicleOperations = append(vehicleOperations, vehicleModel)
+					}
+					if occupancyModel := consumer.recordOccupancy(identifiedJourneyID, serviceRef, vehicleUpdateEvent); occupancyModel != nil {
+						occupancyOperations = append(occupancyOperations, occupancyModel)
+					}
 				}
 			} else {
 				log.Debug().Interface("event", vehicleUpdateEvent.VehicleLocationUpdate.IdentifyingInformation).Msg("Couldnt identify journey")
@@ -133,15 +212,30 @@ func (consumer *BatchConsumer) Consume(batch rmq.Deliveries) {
 		}
 	}
 
-	if len(realtimeJourneyOperations) > 0 {
-		realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+	if len(vehicleOperations) > 0 {
+		vehiclesCollection := database.GetCollection("vehicles")
 
-		startTime := time.Now()
-		_, err := realtimeJourneysCollection.BulkWrite(context.Background(), realtimeJourneyOperations, &options.BulkWriteOptions{})
-		log.Info().Int("Length", len(realtimeJourneyOperations)).Str("Time", time.Now().Sub(startTime).String()).Msg("Bulk write realtime_journeys")
+		_, err := vehiclesCollection.BulkWrite(context.Background(), vehicleOperations, &options.BulkWriteOptions{})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to bulk write Vehicles")
+		}
+	}
+
+	if len(occupancyOperations) > 0 {
+		occupancyHistoryCollection := database.GetCollection("occupancy_history")
 
+		_, err := occupancyHistoryCollection.BulkWrite(context.Background(), occupancyOperations, options.BulkWrite().SetOrdered(false))
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to bulk write Realtime Journeys")
+			log.Error().Err(err).Msg("Failed to bulk write Occupancy History")
+		}
+	}
+
+	if len(segmentRunTimeOperations) > 0 {
+		segmentRunTimesCollection := database.GetCollection("segment_run_times")
+
+		_, err := segmentRunTimesCollection.BulkWrite(context.Background(), segmentRunTimeOperations, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to bulk write Segment Run Times")
 		}
 	}
 
@@ -157,11 +251,55 @@ func (consumer *BatchConsumer) Consume(batch rmq.Deliveries) {
 		}
 	}
 
-	if ackErrors := batch.Ack(); len(ackErrors) > 0 {
-		for _, err := range ackErrors {
-			log.Fatal().Err(err).Msg("Failed to consume realtime event")
-		}
+	return valid, realtimeJourneyWrites
+}
+
+// identifyFromBlock carries a vehicles previous match forward to the next journey in its block
+// when the journey it's now reporting can't be identified directly
+func (consumer *BatchConsumer) identifyFromBlock(vehicleUpdateEvent *VehicleUpdateEvent, identifyingInformation map[string]string) string {
+	if vehicleUpdateEvent.VehicleLocationUpdate == nil || vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier == "" {
+		return ""
+	}
+
+	previousJourneyJSON, _ := identificationCache.Get(context.Background(), fmt.Sprintf("vehiclejourney/%s/%s", identifyingInformation["LinkedDataset"], vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier))
+	if previousJourneyJSON == "" {
+		return ""
+	}
+
+	var previousJourneyMap localJourneyIDMap
+	if err := json.Unmarshal([]byte(previousJourneyJSON), &previousJourneyMap); err != nil {
+		return ""
+	}
+
+	// A match from hours ago could be from a different working of the vehicle
+	if vehicleUpdateEvent.RecordedAt.Sub(previousJourneyMap.LastUpdated) > blockCarryForwardWindow {
+		return ""
 	}
+	previousJourney := previousJourneyMap.JourneyID
+
+	block := ctdf.GetJourneyBlock(previousJourney)
+	if block == nil {
+		return ""
+	}
+
+	now := getCurrentTime()
+	nextJourney := block.GetNextJourney(previousJourney, now)
+	if nextJourney == nil {
+		return ""
+	}
+
+	nextDepartureTime := nextJourney.GetDepartureDateTime(now)
+	if nextDepartureTime.Sub(now).Abs() > blockCarryForwardWindow {
+		return ""
+	}
+
+	log.Debug().
+		Str("previous", previousJourney).
+		Str("next", nextJourney.JourneyRef).
+		Str("block", block.PrimaryIdentifier).
+		Msg("Carried vehicle forward to next journey in block")
+
+	return nextJourney.JourneyRef
 }
 
 func (consumer *BatchConsumer) identifyStop(sourceType string, identifyingInformation map[string]string) string {
@@ -223,7 +361,7 @@ func (consumer *BatchConsumer) identifyService(sourceType string, identifyingInf
 }
 
 func (consumer *BatchConsumer) identifyVehicle(vehicleUpdateEvent *VehicleUpdateEvent, sourceType string, identifyingInformation map[string]string) string {
-	currentTime := time.Now()
+	currentTime := getCurrentTime()
 	yearNumber, weekNumber := currentTime.ISOWeek()
 	identifyEventsIndexName := fmt.Sprintf("realtime-identify-events-%d-%d", yearNumber, weekNumber)
 
@@ -255,12 +393,13 @@ func (consumer *BatchConsumer) identifyVehicle(vehicleUpdateEvent *VehicleUpdate
 			// perform the actual sirivm
 			journeyIdentifier := identifiers.SiriVM{
 				IdentifyingInformation: identifyingInformation,
+				CurrentTime:            currentTime,
 			}
 			journey, err = journeyIdentifier.IdentifyJourney()
 
 			// TODO yet another special TfL only thing that shouldn't be here
-			if err != nil && identifyingInformation["OperatorRef"] == "gb-noc-TFLO" {
-				tflEventBytes, _ := json.Marshal(map[string]string{
+			if err != nil && identifyingInformation["OperatorRef"] == "gb-noc-TFLO" && consumer.TfLBusQueue != nil {
+				tflEventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeTfLBusMonitor, map[string]string{
 					"Line":                     identifyingInformation["PublishedLineName"],
 					"DirectionRef":             identifyingInformation["DirectionRef"],
 					"NumberPlate":              vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier,
@@ -282,6 +421,13 @@ func (consumer *BatchConsumer) identifyVehicle(vehicleUpdateEvent *VehicleUpdate
 			return ""
 		}
 
+		if err != nil {
+			if blockJourney := consumer.identifyFromBlock(vehicleUpdateEvent, identifyingInformation); blockJourney != "" {
+				journey = blockJourney
+				err = nil
+			}
+		}
+
 		if err != nil {
 			// Save a cache value of N/A to stop us from constantly rechecking for journeys we cant identify
 			identificationCache.Set(context.Background(), vehicleUpdateEvent.LocalID, "N/A")
@@ -311,7 +457,7 @@ func (consumer *BatchConsumer) identifyVehicle(vehicleUpdateEvent *VehicleUpdate
 
 			// Record the failed identification event
 			elasticEvent, _ := json.Marshal(RealtimeIdentifyFailureElasticEvent{
-				Timestamp: time.Now(),
+				Timestamp: currentTime,
 
 				Success:    false,
 				FailReason: errorCode,
@@ -339,6 +485,9 @@ func (consumer *BatchConsumer) identifyVehicle(vehicleUpdateEvent *VehicleUpdate
 		// Set cross dataset ID
 		if vehicleUpdateEvent.VehicleLocationUpdate != nil && vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier != "" {
 			identificationCache.Set(context.Background(), fmt.Sprintf("successvehicleid/%s/%s", identifyingInformation["LinkedDataset"], vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier), sourceType)
+
+			// Remember the vehicles current journey so it can be carried forward through its block
+			identificationCache.Set(context.Background(), fmt.Sprintf("vehiclejourney/%s/%s", identifyingInformation["LinkedDataset"], vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier), string(journeyMapJson))
 		}
 
 		// Record the successful identification event
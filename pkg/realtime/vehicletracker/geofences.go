@@ -0,0 +1,103 @@
+package vehicletracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/elastic_client"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Registered geofences are reloaded this often so new ones get picked up without restarting the tracker
+const geofenceRefreshInterval = 5 * time.Minute
+
+var geofences []*ctdf.Geofence
+var geofencesLastLoaded time.Time
+var geofencesMutex sync.Mutex
+
+func getGeofences() []*ctdf.Geofence {
+	geofencesMutex.Lock()
+	defer geofencesMutex.Unlock()
+
+	if time.Since(geofencesLastLoaded) < geofenceRefreshInterval {
+		return geofences
+	}
+	geofencesLastLoaded = time.Now()
+
+	cursor, err := database.GetCollection("geofences").Find(context.Background(), bson.M{})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load geofences")
+		return geofences
+	}
+
+	var loadedGeofences []*ctdf.Geofence
+	if err := cursor.All(context.Background(), &loadedGeofences); err != nil {
+		log.Error().Err(err).Msg("Failed to decode geofences")
+		return geofences
+	}
+	geofences = loadedGeofences
+
+	return geofences
+}
+
+// publishGeofenceEvents emits an event for every geofence the vehicle has crossed into or out of since its last location
+func (consumer *BatchConsumer) publishGeofenceEvents(realtimeJourney *ctdf.RealtimeJourney, previousLocation *ctdf.Location, currentLocation *ctdf.Location, vehicleRef string, currentTime time.Time) {
+	if consumer.EventQueue == nil || len(previousLocation.Coordinates) != 2 || len(currentLocation.Coordinates) != 2 {
+		return
+	}
+
+	yearNumber, weekNumber := currentTime.ISOWeek()
+	geofenceEventsIndexName := fmt.Sprintf("realtime-geofence-events-%d-%d", yearNumber, weekNumber)
+
+	for _, geofence := range getGeofences() {
+		wasInside := geofence.Contains(previousLocation)
+		isInside := geofence.Contains(currentLocation)
+
+		if wasInside == isInside {
+			continue
+		}
+
+		var eventType ctdf.EventType = ctdf.EventTypeRealtimeJourneyGeofenceEntered
+		if wasInside {
+			eventType = ctdf.EventTypeRealtimeJourneyGeofenceExited
+		}
+
+		geofenceEvent := ctdf.GeofenceEvent{
+			GeofenceRef:        geofence.PrimaryIdentifier,
+			GeofenceName:       geofence.Name,
+			RealtimeJourneyRef: realtimeJourney.PrimaryIdentifier,
+			JourneyRef:         realtimeJourney.Journey.PrimaryIdentifier,
+			ServiceRef:         realtimeJourney.Journey.ServiceRef,
+			OperatorRef:        realtimeJourney.Journey.OperatorRef,
+			VehicleRef:         vehicleRef,
+			Location:           *currentLocation,
+		}
+
+		eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, ctdf.Event{
+			Type:      eventType,
+			Timestamp: currentTime,
+			Body:      geofenceEvent,
+		})
+		consumer.EventQueue.PublishBytes(eventBytes)
+
+		// Also kept in Elasticsearch for analysing how long vehicles spend in each zone
+		elasticEvent, _ := json.Marshal(struct {
+			Timestamp time.Time
+			Type      ctdf.EventType
+			ctdf.GeofenceEvent
+		}{
+			Timestamp:     currentTime,
+			Type:          eventType,
+			GeofenceEvent: geofenceEvent,
+		})
+		elastic_client.IndexRequest(geofenceEventsIndexName, bytes.NewReader(elasticEvent))
+	}
+}
@@ -0,0 +1,174 @@
+package vehicletracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type geoJSONPolygon struct {
+	Type        string
+	Coordinates [][][]float64
+	Geometry    *geoJSONPolygon
+}
+
+func registerGeofencesCLI() *cli.Command {
+	return &cli.Command{
+		Name:  "geofences",
+		Usage: "Manage the geofences vehicles emit enter & exit events for",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "register",
+				Usage: "Register or replace a geofence from a GeoJSON polygon",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "id",
+						Usage:    "Identifier of the geofence",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "Name of the geofence",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "description",
+						Usage: "Description of the geofence",
+					},
+					&cli.StringFlag{
+						Name:     "polygon",
+						Usage:    "Path to a GeoJSON Polygon geometry or Feature",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					polygon, err := readGeoJSONPolygon(c.String("polygon"))
+					if err != nil {
+						return err
+					}
+
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					now := time.Now()
+					geofence := ctdf.Geofence{
+						PrimaryIdentifier:    c.String("id"),
+						CreationDateTime:     now,
+						ModificationDateTime: now,
+						Name:                 c.String("name"),
+						Description:          c.String("description"),
+						Polygon:              polygon,
+					}
+
+					var existingGeofence *ctdf.Geofence
+					geofencesCollection := database.GetCollection("geofences")
+					geofencesCollection.FindOne(context.Background(), bson.M{"primaryidentifier": geofence.PrimaryIdentifier}).Decode(&existingGeofence)
+					if existingGeofence != nil {
+						geofence.CreationDateTime = existingGeofence.CreationDateTime
+					}
+
+					_, err = geofencesCollection.ReplaceOne(
+						context.Background(),
+						bson.M{"primaryidentifier": geofence.PrimaryIdentifier},
+						geofence,
+						options.Replace().SetUpsert(true),
+					)
+					if err != nil {
+						return err
+					}
+
+					log.Info().Str("id", geofence.PrimaryIdentifier).Int("points", len(polygon)).Msg("Registered geofence")
+
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List the registered geofences",
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					cursor, err := database.GetCollection("geofences").Find(context.Background(), bson.M{})
+					if err != nil {
+						return err
+					}
+
+					var geofences []*ctdf.Geofence
+					if err := cursor.All(context.Background(), &geofences); err != nil {
+						return err
+					}
+
+					for _, geofence := range geofences {
+						fmt.Printf("%s\t%s\t%d points\t%s\n", geofence.PrimaryIdentifier, geofence.Name, len(geofence.Polygon), geofence.Description)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "delete",
+				Usage: "Delete a registered geofence",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "id",
+						Usage:    "Identifier of the geofence",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					result, err := database.GetCollection("geofences").DeleteOne(context.Background(), bson.M{"primaryidentifier": c.String("id")})
+					if err != nil {
+						return err
+					}
+					if result.DeletedCount == 0 {
+						return errors.New("Geofence not found")
+					}
+
+					log.Info().Str("id", c.String("id")).Msg("Deleted geofence")
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// readGeoJSONPolygon reads the outer ring of either a bare Polygon geometry or a Feature containing one
+func readGeoJSONPolygon(path string) ([][]float64, error) {
+	polygonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var polygon geoJSONPolygon
+	if err := json.Unmarshal(polygonBytes, &polygon); err != nil {
+		return nil, err
+	}
+
+	if polygon.Type == "Feature" && polygon.Geometry != nil {
+		polygon = *polygon.Geometry
+	}
+
+	if polygon.Type != "Polygon" || len(polygon.Coordinates) == 0 || len(polygon.Coordinates[0]) < 3 {
+		return nil, errors.New("Geofence must be a GeoJSON Polygon with at least 3 points")
+	}
+
+	return polygon.Coordinates[0], nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/identifiermapping"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -27,6 +28,10 @@ func (r *GTFSRT) IdentifyStop() (string, error) {
 		return "", errors.New("Missing field linkedDataset")
 	}
 
+	if stopRef, err := identifiermapping.Lookup(linkedDataset, identifiermapping.MappingTypeStop, stopID); err == nil {
+		return stopRef, nil
+	}
+
 	var potentialStops []ctdf.Stop
 
 	formatedStopID := fmt.Sprintf("%s-stop-%s", linkedDataset, stopID)
@@ -62,6 +67,10 @@ func (r *GTFSRT) IdentifyService() (string, error) {
 		return "", errors.New("Missing field linkedDataset")
 	}
 
+	if serviceRef, err := identifiermapping.Lookup(linkedDataset, identifiermapping.MappingTypeRoute, routeID); err == nil {
+		return serviceRef, nil
+	}
+
 	var potentialServices []ctdf.Stop
 
 	formatedServiceID := fmt.Sprintf("%s-service-%s", linkedDataset, routeID)
@@ -97,6 +106,19 @@ func (r *GTFSRT) IdentifyJourney() (string, error) {
 		return "", errors.New("Missing field linkedDataset")
 	}
 
+	// Frequency based trips are stored per start time
+	if startTime := r.IdentifyingInformation["StartTime"]; startTime != "" {
+		frequencyTripID := identifiermapping.FrequencyTripID(tripID, startTime)
+		if journeyRef, err := identifiermapping.Lookup(linkedDataset, identifiermapping.MappingTypeTrip, frequencyTripID); err == nil {
+			return journeyRef, nil
+		}
+	}
+
+	if journeyRef, err := identifiermapping.Lookup(linkedDataset, identifiermapping.MappingTypeTrip, tripID); err == nil {
+		return journeyRef, nil
+	}
+
+	// Fallback to searching the database if the dataset hasn't got a mapping stored yet
 	var potentialJourneys []ctdf.Journey
 
 	cursor, _ := journeysCollection.Find(context.Background(), bson.M{
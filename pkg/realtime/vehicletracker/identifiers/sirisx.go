@@ -52,7 +52,11 @@ func (r *SiriSX) IdentifyStop() (string, error) {
 func (r *SiriSX) IdentifyService() (string, error) {
 	servicesCollection := database.GetCollection("services")
 
-	lineRef := r.IdentifyingInformation["LineRef"]
+	// The published line name is what matches the service name, LineRef is often an internal reference
+	lineRef := r.IdentifyingInformation["PublishedLineRef"]
+	if lineRef == "" {
+		lineRef = r.IdentifyingInformation["LineRef"]
+	}
 	if lineRef == "" {
 		return "", errors.New("Missing field LineRef")
 	}
@@ -67,7 +71,7 @@ func (r *SiriSX) IdentifyService() (string, error) {
 		return "", errors.New("Missing field linkedDataset")
 	}
 
-	var potentialServices []ctdf.Stop
+	var potentialServices []ctdf.Service
 
 	cursor, _ := servicesCollection.Find(context.Background(), bson.M{
 		"servicename":          lineRef,
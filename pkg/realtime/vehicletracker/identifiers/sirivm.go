@@ -9,6 +9,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/transforms"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -21,7 +22,7 @@ type SiriVM struct {
 
 func (i *SiriVM) getOperator() *ctdf.Operator {
 	var operator *ctdf.Operator
-	operatorRef := i.IdentifyingInformation["OperatorRef"]
+	operatorRef := transforms.GetOperatorRef(i.IdentifyingInformation["OperatorRef"], i.IdentifyingInformation["LinkedDataset"])
 	operatorsCollection := database.GetCollection("operators")
 	query := bson.M{"$or": bson.A{bson.M{"primaryidentifier": operatorRef}, bson.M{"otheridentifiers": operatorRef}}}
 	operatorsCollection.FindOne(context.Background(), query).Decode(&operator)
@@ -86,7 +87,9 @@ func (i *SiriVM) getServices() []string {
 }
 
 func (i *SiriVM) IdentifyJourney() (string, error) {
-	i.CurrentTime = time.Now()
+	if i.CurrentTime.IsZero() {
+		i.CurrentTime = time.Now()
+	}
 
 	// Get the directly referenced Operator
 	i.Operator = i.getOperator()
@@ -105,13 +108,13 @@ func (i *SiriVM) IdentifyJourney() (string, error) {
 	// Get the relevant Journeys
 	var framedVehicleJourneyDate time.Time
 	if i.IdentifyingInformation["FramedVehicleJourneyDate"] == "" {
-		framedVehicleJourneyDate = time.Now()
+		framedVehicleJourneyDate = i.CurrentTime
 	} else {
 		framedVehicleJourneyDate, _ = time.Parse(ctdf.YearMonthDayFormat, i.IdentifyingInformation["FramedVehicleJourneyDate"])
 
 		// Fallback for dodgy formatted frames
 		if framedVehicleJourneyDate.Year() < 2024 {
-			framedVehicleJourneyDate = time.Now()
+			framedVehicleJourneyDate = i.CurrentTime
 		}
 	}
 
@@ -32,10 +32,11 @@ func getAvailableJourneys(journeysCollection *mongo.Collection, framedVehicleJou
 		err := cursor.Decode(&journey)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to decode journey")
+			continue
 		}
 
-		// if it has no availability then we'll just ignore it
-		if journey.Availability != nil && journey.Availability.MatchDate(framedVehicleJourneyDate) {
+		// Journeys without availability or outside their validity window are ignored
+		if journey.RunsOn(framedVehicleJourneyDate) {
 			journeys = append(journeys, journey)
 		}
 	}
@@ -0,0 +1,126 @@
+package vehicletracker
+
+import (
+	"math"
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// Positions further than this from the journey track are treated as not being on it
+const maxTrackSnapDistanceMetres = 150.0
+
+// Expected noise of raw vehicle GPS positions
+const positionMeasurementNoiseMetres = 20.0
+
+// How much uncertainty a vehicle's position gains every second it isn't observed
+const positionProcessNoiseMetresPerSecond = 5.0
+
+// Vehicles must move at least this far before we calculate a new bearing from their movement
+const minimumBearingMovementMetres = 10.0
+
+type trackMatch struct {
+	Location ctdf.Location
+
+	PathIndex        int
+	PathItemProgress float64 // How far along the path item (0-1)
+
+	RouteProgressPercentage float64
+}
+
+// matchJourneyTrack snaps a location onto the closest point of the journey track
+// Returns nil if the journey has no track or the location is too far away from it
+func matchJourneyTrack(path []*ctdf.JourneyPathItem, location *ctdf.Location) *trackMatch {
+	var bestMatch *trackMatch
+	bestDistance := math.MaxFloat64
+
+	var totalTrackLength float64
+	var bestMatchTrackPosition float64
+
+	for pathIndex, pathItem := range path {
+		pathItemStart := totalTrackLength
+
+		var pathItemLength float64
+		for i := 0; i < len(pathItem.Track)-1; i++ {
+			pathItemLength += pathItem.Track[i].GreatCircleDistance(&pathItem.Track[i+1])
+		}
+
+		var travelled float64
+		for i := 0; i < len(pathItem.Track)-1; i++ {
+			a := pathItem.Track[i]
+			b := pathItem.Track[i+1]
+			segmentLength := a.GreatCircleDistance(&b)
+
+			snapped, segmentProgress := location.ClosestPointOnLine(a, b)
+			distance := location.GreatCircleDistance(&snapped)
+
+			if distance < bestDistance {
+				bestDistance = distance
+
+				pathItemProgress := 0.0
+				if pathItemLength > 0 {
+					pathItemProgress = (travelled + segmentProgress*segmentLength) / pathItemLength
+				}
+
+				bestMatch = &trackMatch{
+					Location:         snapped,
+					PathIndex:        pathIndex,
+					PathItemProgress: pathItemProgress,
+				}
+				bestMatchTrackPosition = pathItemStart + travelled + segmentProgress*segmentLength
+			}
+
+			travelled += segmentLength
+		}
+
+		totalTrackLength += pathItemLength
+	}
+
+	if bestMatch == nil || bestDistance > maxTrackSnapDistanceMetres {
+		return nil
+	}
+
+	if totalTrackLength > 0 {
+		bestMatch.RouteProgressPercentage = (bestMatchTrackPosition / totalTrackLength) * 100
+	}
+
+	return bestMatch
+}
+
+// smoothLocation applies a simple Kalman filter to a raw location using the previous estimate
+// The variance of the estimate is returned so it can be carried over to the next update
+func smoothLocation(previous *ctdf.Location, previousVariance float64, elapsed time.Duration, measured *ctdf.Location) (ctdf.Location, float64) {
+	measurementVariance := positionMeasurementNoiseMetres * positionMeasurementNoiseMetres
+
+	if previous == nil || previous.Type != "Point" || len(previous.Coordinates) != 2 || previousVariance <= 0 || elapsed < 0 {
+		return *measured, measurementVariance
+	}
+
+	processNoise := positionProcessNoiseMetresPerSecond * elapsed.Seconds()
+	predictedVariance := previousVariance + processNoise*processNoise
+
+	gain := predictedVariance / (predictedVariance + measurementVariance)
+
+	smoothed := ctdf.Location{
+		Type: "Point",
+		Coordinates: []float64{
+			previous.Coordinates[0] + gain*(measured.Coordinates[0]-previous.Coordinates[0]),
+			previous.Coordinates[1] + gain*(measured.Coordinates[1]-previous.Coordinates[1]),
+		},
+	}
+
+	return smoothed, (1 - gain) * predictedVariance
+}
+
+// movementBearing calculates the bearing from the vehicles movement, falling back to the reported one if it has barely moved
+func movementBearing(previous *ctdf.Location, current *ctdf.Location, reportedBearing float64) float64 {
+	if previous == nil || previous.Type != "Point" || len(previous.Coordinates) != 2 {
+		return reportedBearing
+	}
+
+	if previous.GreatCircleDistance(current) < minimumBearingMovementMetres {
+		return reportedBearing
+	}
+
+	return previous.Bearing(current)
+}
@@ -0,0 +1,90 @@
+package vehicletracker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// updateVehicle records the latest known state of the physical vehicle running the journey
+func (consumer *BatchConsumer) updateVehicle(journeyID string, serviceRef string, vehicleUpdateEvent *VehicleUpdateEvent) mongo.WriteModel {
+	vehicleRef := vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier
+	if vehicleRef == "" {
+		return nil
+	}
+
+	vehicleID := fmt.Sprintf(ctdf.VehicleIDFormat, vehicleUpdateEvent.DataSource.DatasetID, vehicleRef)
+
+	updateMap := bson.M{
+		"primaryidentifier":    vehicleID,
+		"modificationdatetime": vehicleUpdateEvent.RecordedAt,
+		"datasource":           vehicleUpdateEvent.DataSource,
+		"vehicleref":           vehicleRef,
+		"realtimejourneyref":   fmt.Sprintf(ctdf.RealtimeJourneyIDFormat, vehicleUpdateEvent.VehicleLocationUpdate.Timeframe, journeyID),
+		"serviceref":           serviceRef,
+		"bearing":              vehicleUpdateEvent.VehicleLocationUpdate.Bearing,
+		"occupancy":            vehicleUpdateEvent.VehicleLocationUpdate.Occupancy,
+	}
+	if vehicleUpdateEvent.VehicleLocationUpdate.Location.Type != "" {
+		updateMap["location"] = vehicleUpdateEvent.VehicleLocationUpdate.Location
+	}
+
+	updateModel := mongo.NewUpdateOneModel()
+	updateModel.SetFilter(bson.M{"primaryidentifier": vehicleID})
+	updateModel.SetUpdate(bson.M{
+		"$set":         updateMap,
+		"$setOnInsert": bson.M{"creationdatetime": vehicleUpdateEvent.RecordedAt},
+	})
+	updateModel.SetUpsert(true)
+
+	return updateModel
+}
+
+// recordOccupancy keeps a history of occupancy observations so typical crowding can be aggregated later
+func (consumer *BatchConsumer) recordOccupancy(journeyID string, serviceRef string, vehicleUpdateEvent *VehicleUpdateEvent) mongo.WriteModel {
+	occupancy := vehicleUpdateEvent.VehicleLocationUpdate.Occupancy
+	if !occupancy.OccupancyAvailable || serviceRef == "" {
+		return nil
+	}
+
+	record := ctdf.OccupancyRecord{
+		ServiceRef:               serviceRef,
+		JourneyRef:               journeyID,
+		RealtimeJourneyRef:       fmt.Sprintf(ctdf.RealtimeJourneyIDFormat, vehicleUpdateEvent.VehicleLocationUpdate.Timeframe, journeyID),
+		VehicleRef:               vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier,
+		RecordedAt:               vehicleUpdateEvent.RecordedAt,
+		ActualValues:             occupancy.ActualValues,
+		TotalPercentageOccupancy: occupancy.TotalPercentageOccupancy,
+		DataSource:               vehicleUpdateEvent.DataSource,
+	}
+
+	return mongo.NewInsertOneModel().SetDocument(record)
+}
+
+func (consumer *BatchConsumer) getJourneyServiceRef(journeyID string) string {
+	cacheKey := fmt.Sprintf("journeyserviceref/%s", journeyID)
+
+	serviceRef, _ := identificationCache.Get(context.Background(), cacheKey)
+	if serviceRef != "" {
+		return serviceRef
+	}
+
+	var journey struct {
+		ServiceRef string
+	}
+	opts := options.FindOne().SetProjection(bson.D{{Key: "serviceref", Value: 1}})
+	journeysCollection := database.GetCollection("journeys")
+	err := journeysCollection.FindOne(context.Background(), bson.M{"primaryidentifier": journeyID}, opts).Decode(&journey)
+	if err != nil {
+		return ""
+	}
+
+	identificationCache.Set(context.Background(), cacheKey, journey.ServiceRef)
+
+	return journey.ServiceRef
+}
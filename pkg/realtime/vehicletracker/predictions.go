@@ -0,0 +1,88 @@
+package vehicletracker
+
+import (
+	"time"
+
+	"github.com/travigo/travigo/pkg/ctdf"
+)
+
+// Shortest time a vehicle is expected to spend at a stop it picks up or sets down at
+const minimumDwellTime = 20 * time.Second
+
+// calculateStopPredictions propagates the observed delay along the remaining journey path starting at pathIndex.
+// Segments with a learned run time profile for the hour use the typical observed run time, otherwise scheduled
+// run times between stops are kept as-is. Any scheduled dwell beyond the minimum dwell time can be used to recover
+// the delay as a vehicle running late wont wait out its full scheduled dwell.
+// A vehicle running early is assumed to wait at each stop until its scheduled departure.
+func calculateStopPredictions(path []*ctdf.JourneyPathItem, pathIndex int, delay time.Duration, runTimes segmentRunTimes) map[string]*ctdf.RealtimeJourneyStops {
+	predictions := map[string]*ctdf.RealtimeJourneyStops{}
+
+	// Path times only carry a time of day so work against a nominal service day to keep the day offsets
+	var serviceDay time.Time
+
+	for i := pathIndex; i < len(path); i++ {
+		pathItem := path[i]
+
+		dwellTime := time.Duration(0)
+		if i < len(path)-1 && !isPassingPoint(path[i+1].OriginActivity) {
+			dwellTime = minimumDwellTime
+		}
+
+		scheduledArrival := pathItem.GetDestinationArrivalDateTime(serviceDay)
+		arrivalTime := scheduledArrival.Add(delay)
+
+		// The vehicle is already part way along the first segment so its delay there is as observed.
+		// Learned run times are measured departure to departure so take the dwell back off to get the arrival
+		if i > pathIndex {
+			predictedDeparture := pathItem.GetOriginDepartureDateTime(serviceDay).Add(delay)
+
+			if runTime, exists := runTimes.get(pathItem, predictedDeparture.Hour()); exists {
+				arrivalTime = predictedDeparture.Add(runTime - dwellTime)
+				if arrivalTime.Before(predictedDeparture) {
+					arrivalTime = predictedDeparture
+				}
+			}
+		}
+
+		prediction := &ctdf.RealtimeJourneyStops{
+			StopRef:  pathItem.DestinationStopRef,
+			TimeType: ctdf.RealtimeJourneyStopTimeEstimatedFuture,
+
+			ArrivalTime:  arrivalTime.Round(time.Minute),
+			ArrivalDelay: arrivalTime.Sub(scheduledArrival),
+		}
+
+		// Final stop has no departure
+		if i < len(path)-1 {
+			scheduledDeparture := path[i+1].GetOriginDepartureDateTime(serviceDay)
+
+			departureTime := arrivalTime.Add(dwellTime)
+			if departureTime.Before(scheduledDeparture) {
+				departureTime = scheduledDeparture
+			}
+
+			delay = departureTime.Sub(scheduledDeparture)
+
+			prediction.DepartureTime = departureTime.Round(time.Minute)
+			prediction.DepartureDelay = delay
+		}
+
+		predictions[pathItem.DestinationStopRef] = prediction
+	}
+
+	return predictions
+}
+
+func isPassingPoint(activities []ctdf.JourneyPathItemActivity) bool {
+	if len(activities) == 0 {
+		return false
+	}
+
+	for _, activity := range activities {
+		if activity != ctdf.JourneyPathItemActivityPass {
+			return false
+		}
+	}
+
+	return true
+}
@@ -9,12 +9,16 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/ctdf"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/realtime/eventlog"
+	"github.com/travigo/travigo/pkg/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUpdateEvent *VehicleUpdateEvent) (mongo.WriteModel, error) {
+// updateRealtimeJourney returns the change to the realtime journey along with the stops whose departure boards
+// are affected by it, and the write model for the segment run time if the vehicle has just completed one
+func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUpdateEvent *VehicleUpdateEvent) (*realtimeJourneyWrite, mongo.WriteModel, error) {
 	currentTime := vehicleUpdateEvent.RecordedAt
 
 	realtimeJourneyIdentifier := fmt.Sprintf(ctdf.RealtimeJourneyIDFormat, vehicleUpdateEvent.VehicleLocationUpdate.Timeframe, journeyID)
@@ -24,14 +28,26 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 	var realtimeJourneyReliability ctdf.RealtimeJourneyReliabilityType
 
 	opts := options.FindOne().SetProjection(bson.D{
+		{Key: "journey.primaryidentifier", Value: 1},
+		{Key: "journey.serviceref", Value: 1},
+		{Key: "journey.operatorref", Value: 1},
 		{Key: "journey.path", Value: 1},
 		{Key: "journey.departuretimezone", Value: 1},
+		{Key: "departedstopref", Value: 1},
+		{Key: "departedstopdatetime", Value: 1},
 		{Key: "nextstopref", Value: 1},
 		{Key: "offset", Value: 1},
+		{Key: "vehiclelocation", Value: 1},
+		{Key: "vehiclelocationvariance", Value: 1},
+		{Key: "vehicletrack", Value: bson.M{"$slice": -1}},
+		{Key: "modificationdatetime", Value: 1},
 	})
 
 	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
 	realtimeJourneysCollection.FindOne(context.Background(), searchQuery, opts).Decode(&realtimeJourney)
+	if consumer.realtimeJourneyWriter != nil {
+		realtimeJourney = consumer.realtimeJourneyWriter.ApplyPending(realtimeJourneyIdentifier, realtimeJourney)
+	}
 
 	newRealtimeJourney := false
 	if realtimeJourney == nil {
@@ -40,7 +56,7 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		err := journeysCollection.FindOne(context.Background(), bson.M{"primaryidentifier": journeyID}).Decode(&journey)
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		for _, pathItem := range journey.Path {
@@ -51,10 +67,15 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 
 		journeyDate, _ := time.Parse("2006-01-02", vehicleUpdateEvent.VehicleLocationUpdate.Timeframe)
 
+		transportType := ctdf.TransportTypeBus
+		if journey.Service != nil && journey.Service.TransportType != "" {
+			transportType = journey.Service.TransportType
+		}
+
 		realtimeJourney = &ctdf.RealtimeJourney{
 			PrimaryIdentifier:      realtimeJourneyIdentifier,
 			ActivelyTracked:        true,
-			TimeoutDurationMinutes: 10,
+			TimeoutDurationMinutes: ctdf.GetRealtimeJourneyTimeoutMinutes(transportType, vehicleUpdateEvent.DataSource),
 			Journey:                journey,
 			JourneyRunDate:         journeyDate,
 			Service:                journey.Service,
@@ -70,43 +91,34 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 	if realtimeJourney.Journey == nil {
 		log.Error().Msg("RealtimeJourney without a Journey found, deleting")
 		realtimeJourneysCollection.DeleteOne(context.Background(), searchQuery)
-		return nil, errors.New("RealtimeJourney without a Journey found, deleting")
+		return nil, nil, errors.New("RealtimeJourney without a Journey found, deleting")
 	}
 
 	var offset time.Duration
+	var segmentRunTimeModel mongo.WriteModel
 	journeyStopUpdates := map[string]*ctdf.RealtimeJourneyStops{}
+
+	cleanedLocation := vehicleUpdateEvent.VehicleLocationUpdate.Location
+	var cleanedLocationVariance float64
+	var routeProgressPercentage float64
 	var closestDistanceJourneyPath *ctdf.JourneyPathItem // TODO maybe not here?
 
 	// Calculate everything based on location if we aren't provided with updates
 	if len(vehicleUpdateEvent.VehicleLocationUpdate.StopUpdates) == 0 && vehicleUpdateEvent.VehicleLocationUpdate.Location.Type == "Point" {
 		closestDistance := 999999999999.0
 		var closestDistanceJourneyPathIndex int
-		var closestDistanceJourneyPathPercentComplete float64 // TODO: this is a hack, replace with actual distance
-
-		// Attempt to calculate using closest journey track
-		for i, journeyPathItem := range realtimeJourney.Journey.Path {
-			journeyPathClosestDistance := 99999999999999.0 // TODO do this better
-
-			for i := 0; i < len(journeyPathItem.Track)-1; i++ {
-				a := journeyPathItem.Track[i]
-				b := journeyPathItem.Track[i+1]
-
-				distance := vehicleUpdateEvent.VehicleLocationUpdate.Location.DistanceFromLine(a, b)
-
-				if distance < journeyPathClosestDistance {
-					journeyPathClosestDistance = distance
-				}
-			}
-
-			if journeyPathClosestDistance < closestDistance {
-				closestDistance = journeyPathClosestDistance
-				closestDistanceJourneyPath = journeyPathItem
-				closestDistanceJourneyPathIndex = i
-
-				// TODO: this is a hack, replace with actual distance
-				// this is a rough estimation based on what part of path item track we are on
-				closestDistanceJourneyPathPercentComplete = float64(i) / float64(len(journeyPathItem.Track))
-			}
+		var closestDistanceJourneyPathPercentComplete float64
+
+		// Attempt to snap the vehicle onto the journey track
+		match := matchJourneyTrack(realtimeJourney.Journey.Path, &vehicleUpdateEvent.VehicleLocationUpdate.Location)
+		if match != nil {
+			closestDistanceJourneyPath = realtimeJourney.Journey.Path[match.PathIndex]
+			closestDistanceJourneyPathIndex = match.PathIndex
+			closestDistanceJourneyPathPercentComplete = match.PathItemProgress
+
+			cleanedLocation = match.Location
+			cleanedLocationVariance = 0
+			routeProgressPercentage = match.RouteProgressPercentage
 		}
 
 		// If we fail to identify closest journey path item using track use fallback stop location method
@@ -114,7 +126,7 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 			closestDistance = 999999999999.0
 			for i, journeyPathItem := range realtimeJourney.Journey.Path {
 				if journeyPathItem.DestinationStop == nil {
-					return nil, errors.New(fmt.Sprintf("Cannot get stop %s", journeyPathItem.DestinationStopRef))
+					return nil, nil, errors.New(fmt.Sprintf("Cannot get stop %s", journeyPathItem.DestinationStopRef))
 				}
 
 				distance := journeyPathItem.DestinationStop.Location.Distance(&vehicleUpdateEvent.VehicleLocationUpdate.Location)
@@ -133,7 +145,7 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 				previousJourneyPath := realtimeJourney.Journey.Path[len(realtimeJourney.Journey.Path)-1]
 
 				if previousJourneyPath.DestinationStop == nil {
-					return nil, errors.New(fmt.Sprintf("Cannot get stop %s", previousJourneyPath.DestinationStopRef))
+					return nil, nil, errors.New(fmt.Sprintf("Cannot get stop %s", previousJourneyPath.DestinationStopRef))
 				}
 
 				previousJourneyPathDistance := previousJourneyPath.DestinationStop.Location.Distance(&vehicleUpdateEvent.VehicleLocationUpdate.Location)
@@ -142,6 +154,15 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 			}
 
 			realtimeJourneyReliability = ctdf.RealtimeJourneyReliabilityLocationWithoutTrack
+
+			// Without a track to snap to smooth out the noise in the raw positions instead
+			cleanedLocation, cleanedLocationVariance = smoothLocation(
+				&realtimeJourney.VehicleLocation,
+				realtimeJourney.VehicleLocationVariance,
+				currentTime.Sub(realtimeJourney.ModificationDateTime),
+				&vehicleUpdateEvent.VehicleLocationUpdate.Location,
+			)
+			routeProgressPercentage = ((float64(closestDistanceJourneyPathIndex) + closestDistanceJourneyPathPercentComplete) / float64(len(realtimeJourney.Journey.Path))) * 100
 		} else {
 			realtimeJourneyReliability = ctdf.RealtimeJourneyReliabilityLocationWithTrack
 		}
@@ -153,32 +174,15 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		}
 
 		if closestDistanceJourneyPath == nil {
-			return nil, errors.New("nil closestdistancejourneypath")
+			return nil, nil, errors.New("nil closestdistancejourneypath")
 		}
 
 		journeyTimezone, _ := time.LoadLocation(realtimeJourney.Journey.DepartureTimezone)
 
 		// Get the arrival & departure times with date of the journey
-		destinationArrivalTimeWithDate := time.Date(
-			realtimeTimeframe.Year(),
-			realtimeTimeframe.Month(),
-			realtimeTimeframe.Day(),
-			closestDistanceJourneyPath.DestinationArrivalTime.Hour(),
-			closestDistanceJourneyPath.DestinationArrivalTime.Minute(),
-			closestDistanceJourneyPath.DestinationArrivalTime.Second(),
-			closestDistanceJourneyPath.DestinationArrivalTime.Nanosecond(),
-			journeyTimezone,
-		)
-		originDepartureTimeWithDate := time.Date(
-			realtimeTimeframe.Year(),
-			realtimeTimeframe.Month(),
-			realtimeTimeframe.Day(),
-			closestDistanceJourneyPath.OriginDepartureTime.Hour(),
-			closestDistanceJourneyPath.OriginDepartureTime.Minute(),
-			closestDistanceJourneyPath.OriginDepartureTime.Second(),
-			closestDistanceJourneyPath.OriginDepartureTime.Nanosecond(),
-			journeyTimezone,
-		)
+		serviceDay := time.Date(realtimeTimeframe.Year(), realtimeTimeframe.Month(), realtimeTimeframe.Day(), 0, 0, 0, 0, journeyTimezone)
+		destinationArrivalTimeWithDate := closestDistanceJourneyPath.GetDestinationArrivalDateTime(serviceDay)
+		originDepartureTimeWithDate := closestDistanceJourneyPath.GetOriginDepartureDateTime(serviceDay)
 
 		// How long it take to travel between origin & destination
 		currentPathTraversalTime := destinationArrivalTimeWithDate.Sub(originDepartureTimeWithDate)
@@ -199,34 +203,23 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 			offset = time.Duration(0)
 		}
 
-		// Calculate all the estimated stop arrival & departure times
-		for i := closestDistanceJourneyPathIndex; i < len(realtimeJourney.Journey.Path); i++ {
-			// Don't update the database if theres no actual change
-			if (offset.Seconds() == realtimeJourney.Offset.Seconds()) && !newRealtimeJourney {
-				break
-			}
-
-			path := realtimeJourney.Journey.Path[i]
-
-			arrivalTime := path.DestinationArrivalTime.Add(offset).Round(time.Minute)
-			var departureTime time.Time
+		// Recalculate all the estimated stop arrival & departure times for the rest of the journey
+		// Skip it if nothing has changed since the last update to avoid unnecessary database writes
+		if offset.Seconds() != realtimeJourney.Offset.Seconds() || newRealtimeJourney || realtimeJourney.NextStopRef != closestDistanceJourneyPath.DestinationStopRef {
+			runTimes := getSegmentRunTimes(journeyID, realtimeJourney.Journey.Path, ctdf.GetSegmentDayType(serviceDay))
+			journeyStopUpdates = calculateStopPredictions(realtimeJourney.Journey.Path, closestDistanceJourneyPathIndex, offset, runTimes)
+		}
 
-			if i < len(realtimeJourney.Journey.Path)-1 {
-				nextPath := realtimeJourney.Journey.Path[i+1]
+		// Moving on by exactly one segment means the vehicle has just completed the previous one
+		if !newRealtimeJourney && !realtimeJourney.DepartedStopDateTime.IsZero() && closestDistanceJourneyPathIndex > 0 &&
+			closestDistanceJourneyPath.OriginStopRef == realtimeJourney.NextStopRef && closestDistanceJourneyPath.DestinationStopRef != realtimeJourney.NextStopRef {
+			completedPathItem := realtimeJourney.Journey.Path[closestDistanceJourneyPathIndex-1]
 
-				if arrivalTime.Before(nextPath.OriginDepartureTime) {
-					departureTime = nextPath.OriginDepartureTime
-				} else {
-					departureTime = arrivalTime
-				}
-			}
-
-			journeyStopUpdates[path.DestinationStopRef] = &ctdf.RealtimeJourneyStops{
-				StopRef:  path.DestinationStopRef,
-				TimeType: ctdf.RealtimeJourneyStopTimeEstimatedFuture,
-
-				ArrivalTime:   arrivalTime,
-				DepartureTime: departureTime,
+			if completedPathItem.OriginStopRef == realtimeJourney.DepartedStopRef {
+				segmentRunTimeModel = recordSegmentRunTime(
+					journeyID, realtimeJourney.PrimaryIdentifier, completedPathItem, serviceDay,
+					realtimeJourney.DepartedStopDateTime, currentTime, vehicleUpdateEvent.DataSource,
+				)
 			}
 		}
 	} else {
@@ -261,7 +254,7 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		}
 
 		closestPathTime := 9999999 * time.Minute
-		now := time.Now()
+		now := getCurrentTime()
 		realtimeTimeframe, err := time.Parse("2006-01-02", vehicleUpdateEvent.VehicleLocationUpdate.Timeframe)
 
 		journeyTimezone, _ := time.LoadLocation(realtimeJourney.Journey.DepartureTimezone)
@@ -269,17 +262,9 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to parse realtime time frame")
 		}
+		serviceDay := time.Date(realtimeTimeframe.Year(), realtimeTimeframe.Month(), realtimeTimeframe.Day(), 0, 0, 0, 0, journeyTimezone)
 		for _, path := range realtimeJourney.Journey.Path {
-			refTime := time.Date(
-				realtimeTimeframe.Year(),
-				realtimeTimeframe.Month(),
-				realtimeTimeframe.Day(),
-				path.OriginArrivalTime.Hour(),
-				path.OriginArrivalTime.Minute(),
-				path.OriginArrivalTime.Second(),
-				path.OriginArrivalTime.Nanosecond(),
-				journeyTimezone,
-			)
+			refTime := path.GetOriginArrivalDateTime(serviceDay)
 
 			if journeyStopUpdates[path.OriginStopRef] != nil {
 				refTime = journeyStopUpdates[path.OriginStopRef].ArrivalTime
@@ -296,20 +281,43 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 	}
 
 	if closestDistanceJourneyPath == nil {
-		return nil, errors.New("unable to find next journeypath")
+		return nil, nil, errors.New("unable to find next journeypath")
 	}
 
 	// Update database
 	updateMap := bson.M{
 		"modificationdatetime": currentTime,
 		"vehiclebearing":       vehicleUpdateEvent.VehicleLocationUpdate.Bearing,
+		"progresspercentage":   routeProgressPercentage,
 		"departedstopref":      closestDistanceJourneyPath.OriginStopRef,
 		"nextstopref":          closestDistanceJourneyPath.DestinationStopRef,
 		"occupancy":            vehicleUpdateEvent.VehicleLocationUpdate.Occupancy,
 		// "vehiclelocationdescription": fmt.Sprintf("Passed %s", closestDistanceJourneyPath.OriginStop.PrimaryName),
 	}
+	var trackPoint *ctdf.VehicleTrackPoint
 	if vehicleUpdateEvent.VehicleLocationUpdate.Location.Type != "" {
-		updateMap["vehiclelocation"] = vehicleUpdateEvent.VehicleLocationUpdate.Location
+		updateMap["vehiclelocation"] = cleanedLocation
+		updateMap["vehiclelocationvariance"] = cleanedLocationVariance
+		updateMap["vehiclebearing"] = movementBearing(&realtimeJourney.VehicleLocation, &cleanedLocation, vehicleUpdateEvent.VehicleLocationUpdate.Bearing)
+
+		var lastTrackPoint *ctdf.VehicleTrackPoint
+		if len(realtimeJourney.VehicleTrack) > 0 {
+			lastTrackPoint = realtimeJourney.VehicleTrack[len(realtimeJourney.VehicleTrack)-1]
+		}
+		if ctdf.NeedsVehicleTrackPoint(lastTrackPoint, cleanedLocation, currentTime) {
+			trackPoint = &ctdf.VehicleTrackPoint{
+				Location:  cleanedLocation,
+				Bearing:   updateMap["vehiclebearing"].(float64),
+				Timestamp: currentTime,
+			}
+		}
+
+		if !newRealtimeJourney {
+			consumer.publishGeofenceEvents(realtimeJourney, &realtimeJourney.VehicleLocation, &cleanedLocation, vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier, currentTime)
+		}
+	}
+	if !newRealtimeJourney && realtimeJourney.DepartedStopRef != closestDistanceJourneyPath.OriginStopRef {
+		updateMap["departedstopdatetime"] = currentTime
 	}
 	if newRealtimeJourney {
 		updateMap["primaryidentifier"] = realtimeJourney.PrimaryIdentifier
@@ -335,6 +343,12 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		updateMap["offset"] = offset
 	}
 
+	// Departure boards only show times to the minute so only changes that move a time are worth invalidating for
+	var affectedStopIDs []string
+	if newRealtimeJourney || len(journeyStopUpdates) > 0 || offset.Round(time.Minute) != realtimeJourney.Offset.Round(time.Minute) {
+		affectedStopIDs = upcomingStopIDs(realtimeJourney.Journey.Path, closestDistanceJourneyPath, journeyStopUpdates)
+	}
+
 	if realtimeJourney.NextStopRef != closestDistanceJourneyPath.DestinationStopRef {
 		journeyStopUpdates[realtimeJourney.NextStopRef] = &ctdf.RealtimeJourneyStops{
 			StopRef:  realtimeJourney.NextStopRef,
@@ -352,11 +366,87 @@ func (consumer *BatchConsumer) updateRealtimeJourney(journeyID string, vehicleUp
 		}
 	}
 
-	bsonRep, _ := bson.Marshal(bson.M{"$set": updateMap})
-	updateModel := mongo.NewUpdateOneModel()
-	updateModel.SetFilter(searchQuery)
-	updateModel.SetUpdate(bsonRep)
-	updateModel.SetUpsert(true)
+	write := &realtimeJourneyWrite{
+		PrimaryIdentifier: realtimeJourneyIdentifier,
+		Set:               updateMap,
+		TrackPoint:        trackPoint,
+		AffectedStopIDs:   affectedStopIDs,
+	}
+
+	if eventlog.Enabled() {
+		positionEvent := &ctdf.RealtimeJourneyEvent{
+			RealtimeJourneyRef: realtimeJourneyIdentifier,
+			Type:               ctdf.RealtimeJourneyEventPosition,
+			RecordedAt:         currentTime,
+			DataSource:         vehicleUpdateEvent.DataSource,
+			Bearing:            updateMap["vehiclebearing"].(float64),
+			DepartedStopRef:    closestDistanceJourneyPath.OriginStopRef,
+			NextStopRef:        closestDistanceJourneyPath.DestinationStopRef,
+		}
+		if vehicleUpdateEvent.VehicleLocationUpdate.Location.Type != "" {
+			positionEvent.Location = &cleanedLocation
+		}
+		write.Events = append(write.Events, positionEvent)
+
+		if _, offsetChanged := updateMap["offset"]; offsetChanged {
+			write.Events = append(write.Events, &ctdf.RealtimeJourneyEvent{
+				RealtimeJourneyRef: realtimeJourneyIdentifier,
+				Type:               ctdf.RealtimeJourneyEventDelay,
+				RecordedAt:         currentTime,
+				DataSource:         vehicleUpdateEvent.DataSource,
+				Offset:             offset,
+			})
+		}
+	}
+
+	if consumer.PublishJourneyUpdates {
+		journeyUpdate := &ctdf.RealtimeJourneyUpdate{
+			RealtimeJourneyRef:   realtimeJourney.PrimaryIdentifier,
+			JourneyRef:           realtimeJourney.Journey.PrimaryIdentifier,
+			ServiceRef:           realtimeJourney.Journey.ServiceRef,
+			OperatorRef:          realtimeJourney.Journey.OperatorRef,
+			VehicleRef:           vehicleUpdateEvent.VehicleLocationUpdate.VehicleIdentifier,
+			VehicleLocation:      realtimeJourney.VehicleLocation,
+			DepartedStopRef:      closestDistanceJourneyPath.OriginStopRef,
+			NextStopRef:          closestDistanceJourneyPath.DestinationStopRef,
+			Offset:               offset,
+			Reliability:          realtimeJourneyReliability,
+			ModificationDateTime: currentTime,
+		}
+		if vehicleUpdateEvent.VehicleLocationUpdate.Location.Type != "" {
+			journeyUpdate.VehicleLocation = cleanedLocation
+		}
+		if bearing, ok := updateMap["vehiclebearing"].(float64); ok {
+			journeyUpdate.VehicleBearing = bearing
+		}
+
+		write.JourneyUpdate = journeyUpdate
+	}
+
+	return write, segmentRunTimeModel, nil
+}
+
+// upcomingStopIDs lists the stops the vehicle has yet to reach plus any stops that had explicit updates
+func upcomingStopIDs(path []*ctdf.JourneyPathItem, currentPathItem *ctdf.JourneyPathItem, stopUpdates map[string]*ctdf.RealtimeJourneyStops) []string {
+	var stopIDs []string
+
+	reachedCurrent := false
+	for _, pathItem := range path {
+		if pathItem == currentPathItem {
+			reachedCurrent = true
+			stopIDs = append(stopIDs, pathItem.OriginStopRef)
+		}
+
+		if reachedCurrent {
+			stopIDs = append(stopIDs, pathItem.DestinationStopRef)
+		}
+	}
+
+	for stopID := range stopUpdates {
+		if stopID != "" {
+			stopIDs = append(stopIDs, stopID)
+		}
+	}
 
-	return updateModel, nil
+	return util.RemoveDuplicateStrings(stopIDs, []string{})
 }
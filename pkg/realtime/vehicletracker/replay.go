@@ -0,0 +1,244 @@
+package vehicletracker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/dataimporter/archive"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"github.com/travigo/travigo/pkg/redis_client"
+)
+
+// ReplayCollections are the collections the vehicle tracker writes to, which get redirected to the sandbox database during a replay
+var ReplayCollections = []string{"realtime_journeys", "realtime_journey_events", "vehicles", "occupancy_history", "segment_run_times", "service_alerts"}
+
+type ReplayOptions struct {
+	Date  time.Time
+	Speed float64
+
+	Store           archive.Store
+	SandboxDatabase string
+}
+
+// Replay feeds the archived vehicle update events for a day back through the tracker into a sandbox database.
+// Identification runs against the recorded time of the events rather than the current time
+func Replay(replayOptions ReplayOptions) error {
+	if replayOptions.Speed <= 0 {
+		return errors.New("Speed multiplier must be greater than 0")
+	}
+
+	keys, err := replayOptions.Store.List(context.Background(), getEventArchiveKey(replayOptions.Date, ""))
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New(fmt.Sprintf("No archived realtime events found for %s", replayOptions.Date.Format("2006-01-02")))
+	}
+	sort.Strings(keys)
+
+	database.UseSandbox(replayOptions.SandboxDatabase, ReplayCollections)
+
+	// Keep identification state out of the live cache
+	identificationCache = cache.New[string](newMemoryStore())
+
+	// Replayed events are written straight away as the speed they arrive at is controlled by the replay.
+	// Nothing is published as the live departure boards & journey streams have nothing to do with the sandbox
+	realtimeJourneyWriter := newRealtimeJourneyCoalescer(0, realtimeJourneyHeartbeat)
+	realtimeJourneyWriter.publish = false
+	consumer := &BatchConsumer{id: 0, realtimeJourneyWriter: realtimeJourneyWriter}
+
+	var replayStart time.Time
+	var archiveStart time.Time
+	var replayed int
+
+	for _, key := range keys {
+		events, err := readArchivedEvents(replayOptions.Store, key)
+		if err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to read archived realtime events")
+			continue
+		}
+
+		for i := 0; i < len(events); i += batchSize {
+			batch := events[i:min(i+batchSize, len(events))]
+			recordedAt := batch[len(batch)-1].recordedAt
+
+			if archiveStart.IsZero() {
+				archiveStart = recordedAt
+				replayStart = time.Now()
+			}
+
+			offset := time.Duration(float64(recordedAt.Sub(archiveStart)) / replayOptions.Speed)
+			time.Sleep(time.Until(replayStart.Add(offset)))
+
+			getCurrentTime = func() time.Time {
+				return recordedAt
+			}
+
+			payloads := make([]string, len(batch))
+			for j, event := range batch {
+				payloads[j] = event.payload
+			}
+			_, realtimeJourneyWrites := consumer.processPayloads(payloads)
+			consumer.realtimeJourneyWriter.Add(realtimeJourneyWrites, nil)
+
+			replayed += len(batch)
+		}
+
+		log.Info().Str("key", key).Int("replayed", replayed).Msg("Replayed archived realtime events")
+	}
+
+	getCurrentTime = time.Now
+
+	log.Info().
+		Int("events", replayed).
+		Str("database", replayOptions.SandboxDatabase).
+		Msg("Finished replaying realtime events")
+
+	return nil
+}
+
+// StartSandboxConsumers consumes the named queue into a sandbox database the same way a replay does, so the pipeline
+// can be load tested without touching live data. Nothing is archived or published
+func StartSandboxConsumers(queueName string, sandboxDatabase string) error {
+	database.UseSandbox(sandboxDatabase, ReplayCollections)
+
+	identificationCache = cache.New[string](newMemoryStore())
+
+	realtimeJourneyWriter = newRealtimeJourneyCoalescer(realtimeJourneyWriteWindow, realtimeJourneyHeartbeat)
+	realtimeJourneyWriter.publish = false
+	realtimeJourneyWriter.Start()
+
+	queue, err := redis_client.QueueConnection.OpenQueue(queueName)
+	if err != nil {
+		return err
+	}
+	if err := queue.StartConsuming(numConsumers*batchSize, 1*time.Second); err != nil {
+		return err
+	}
+
+	for i := 0; i < numConsumers; i++ {
+		consumer := &BatchConsumer{id: i, realtimeJourneyWriter: realtimeJourneyWriter}
+		if _, err := queue.AddBatchConsumer(fmt.Sprintf("%s-%d", queueName, i), batchSize, 2*time.Second, consumer); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("queue", queueName).Str("database", sandboxDatabase).Msg("Started sandbox realtime consumers")
+
+	return nil
+}
+
+type archivedEvent struct {
+	payload    string
+	recordedAt time.Time
+}
+
+func readArchivedEvents(archiveStore archive.Store, key string) ([]archivedEvent, error) {
+	reader, err := archiveStore.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	var events []archivedEvent
+
+	scanner := bufio.NewScanner(gzipReader)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		payload := scanner.Text()
+
+		var event struct {
+			RecordedAt time.Time
+		}
+		if err := queuemessage.Unmarshal([]byte(payload), queuemessage.MessageTypeVehicleUpdate, &event); err != nil {
+			continue
+		}
+
+		events = append(events, archivedEvent{payload: payload, recordedAt: event.RecordedAt})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].recordedAt.Before(events[j].recordedAt)
+	})
+
+	return events, scanner.Err()
+}
+
+// memoryStore is a minimal in process cache store so replays don't share identification state with the live tracker
+type memoryStore struct {
+	mutex  sync.Mutex
+	values map[any]any
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: map[any]any{}}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key any) (any, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	value, exists := s.values[key]
+	if !exists {
+		return nil, store.NotFoundWithCause(errors.New("key not found"))
+	}
+
+	return value, nil
+}
+
+func (s *memoryStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	value, err := s.Get(ctx, key)
+
+	return value, 0, err
+}
+
+func (s *memoryStore) Set(ctx context.Context, key any, value any, options ...store.Option) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = value
+
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func (s *memoryStore) Invalidate(ctx context.Context, options ...store.InvalidateOption) error {
+	return nil
+}
+
+func (s *memoryStore) Clear(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values = map[any]any{}
+
+	return nil
+}
+
+func (s *memoryStore) GetType() string {
+	return "memory"
+}
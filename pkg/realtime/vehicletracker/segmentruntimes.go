@@ -0,0 +1,100 @@
+package vehicletracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Anything longer than this between two consecutive stops is a vehicle that stopped reporting rather than a slow run
+const maximumSegmentRunTime = 1 * time.Hour
+
+// segmentRunTimes are the median run times of a journeys segments for a single day type, keyed on segment & hour
+type segmentRunTimes map[string]time.Duration
+
+func (runTimes segmentRunTimes) get(pathItem *ctdf.JourneyPathItem, hour int) (time.Duration, bool) {
+	runTime, exists := runTimes[fmt.Sprintf("%s:%d", ctdf.GetSegmentRef(pathItem.OriginStopRef, pathItem.DestinationStopRef), hour)]
+
+	return runTime, exists
+}
+
+// getSegmentRunTimes loads the learned profiles for every segment of the journey, cached alongside the identification
+// state as it's needed every time the predictions are recalculated
+func getSegmentRunTimes(journeyID string, path []*ctdf.JourneyPathItem, dayType ctdf.SegmentDayType) segmentRunTimes {
+	cacheKey := fmt.Sprintf("segmentruntimes/%s/%s", journeyID, dayType)
+
+	runTimes := segmentRunTimes{}
+
+	cachedRunTimes, _ := identificationCache.Get(context.Background(), cacheKey)
+	if cachedRunTimes != "" {
+		json.Unmarshal([]byte(cachedRunTimes), &runTimes)
+		return runTimes
+	}
+
+	var segmentRefs []string
+	for _, pathItem := range path {
+		segmentRefs = append(segmentRefs, ctdf.GetSegmentRef(pathItem.OriginStopRef, pathItem.DestinationStopRef))
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "segmentref", Value: 1},
+		{Key: "hour", Value: 1},
+		{Key: "percentile50", Value: 1},
+	})
+
+	profilesCollection := database.GetCollection("segment_run_time_profiles")
+	cursor, err := profilesCollection.Find(context.Background(), bson.M{
+		"daytype":    dayType,
+		"segmentref": bson.M{"$in": segmentRefs},
+	}, opts)
+	if err != nil {
+		log.Error().Err(err).Str("journey", journeyID).Msg("Failed to load segment run time profiles")
+		return runTimes
+	}
+
+	for cursor.Next(context.Background()) {
+		var profile ctdf.SegmentRunTimeProfile
+		if err := cursor.Decode(&profile); err != nil {
+			continue
+		}
+
+		runTimes[fmt.Sprintf("%s:%d", profile.SegmentRef, profile.Hour)] = profile.Percentile50
+	}
+
+	// Journeys without any profiles get cached as well so they don't keep hitting the database
+	runTimesJSON, _ := json.Marshal(runTimes)
+	identificationCache.Set(context.Background(), cacheKey, string(runTimesJSON))
+
+	return runTimes
+}
+
+// recordSegmentRunTime keeps the observed run of a segment so the profiles can be learned from it later
+func recordSegmentRunTime(journeyID string, realtimeJourneyID string, pathItem *ctdf.JourneyPathItem, serviceDay time.Time, departedAt time.Time, passedAt time.Time, dataSource *ctdf.DataSourceReference) mongo.WriteModel {
+	runTime := passedAt.Sub(departedAt)
+	if runTime <= 0 || runTime > maximumSegmentRunTime {
+		return nil
+	}
+
+	record := ctdf.SegmentRunTimeRecord{
+		SegmentRef:         ctdf.GetSegmentRef(pathItem.OriginStopRef, pathItem.DestinationStopRef),
+		OriginStopRef:      pathItem.OriginStopRef,
+		DestinationStopRef: pathItem.DestinationStopRef,
+		JourneyRef:         journeyID,
+		RealtimeJourneyRef: realtimeJourneyID,
+		DayType:            ctdf.GetSegmentDayType(serviceDay),
+		Hour:               departedAt.In(serviceDay.Location()).Hour(),
+		DepartedAt:         departedAt,
+		RunTime:            runTime,
+		DataSource:         dataSource,
+	}
+
+	return mongo.NewInsertOneModel().SetDocument(record)
+}
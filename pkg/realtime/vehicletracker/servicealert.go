@@ -14,21 +14,41 @@ func (consumer *BatchConsumer) updateServiceAlert(matchedIdentifiers []string, v
 		return nil, errors.New("No matching identifiers")
 	}
 
+	now := time.Now()
+	serviceAlertUpdate := vehicleUpdateEvent.ServiceAlertUpdate
+
+	// The same situation keeps the same identifier so later versions update the existing alert
 	serviceAlert := ctdf.ServiceAlert{
 		PrimaryIdentifier:    vehicleUpdateEvent.LocalID,
 		OtherIdentifiers:     map[string]string{},
-		CreationDateTime:     time.Time{},
 		ModificationDateTime: vehicleUpdateEvent.RecordedAt,
 		DataSource:           vehicleUpdateEvent.DataSource,
-		AlertType:            vehicleUpdateEvent.ServiceAlertUpdate.Type,
-		Title:                vehicleUpdateEvent.ServiceAlertUpdate.Title,
-		Text:                 vehicleUpdateEvent.ServiceAlertUpdate.Description,
+		Status:               ctdf.ServiceAlertStatusActive,
+		Version:              serviceAlertUpdate.Version,
+		AlertType:            serviceAlertUpdate.Type,
+		Title:                serviceAlertUpdate.Title,
+		Text:                 serviceAlertUpdate.Description,
+		TitleTranslations:    serviceAlertUpdate.TitleTranslations,
+		TextTranslations:     serviceAlertUpdate.DescriptionTranslations,
 		MatchedIdentifiers:   matchedIdentifiers,
-		ValidFrom:            vehicleUpdateEvent.ServiceAlertUpdate.ValidFrom,
-		ValidUntil:           vehicleUpdateEvent.ServiceAlertUpdate.ValidUntil,
+		ValidFrom:            serviceAlertUpdate.ValidFrom,
+		ValidUntil:           serviceAlertUpdate.ValidUntil,
+	}
+
+	if serviceAlertUpdate.Closed {
+		serviceAlert.Status = ctdf.ServiceAlertStatusClosed
+
+		if serviceAlert.ValidUntil.After(now) {
+			serviceAlert.ValidUntil = now
+		}
+	} else if serviceAlert.ValidUntil.Before(now) {
+		serviceAlert.Status = ctdf.ServiceAlertStatusExpired
 	}
 
-	bsonRep, _ := bson.Marshal(bson.M{"$set": serviceAlert})
+	bsonRep, _ := bson.Marshal(bson.M{
+		"$set":         serviceAlert,
+		"$setOnInsert": bson.M{"creationdatetime": now},
+	})
 	updateModel := mongo.NewUpdateOneModel()
 	updateModel.SetFilter(bson.M{"primaryidentifier": vehicleUpdateEvent.LocalID})
 	updateModel.SetUpdate(bsonRep)
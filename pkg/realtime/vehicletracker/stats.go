@@ -2,17 +2,20 @@ package vehicletracker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/adjust/rmq/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/realtime/feedhealth"
 	"github.com/travigo/travigo/pkg/redis_client"
 )
 
 func StartStatsServer() {
 	http.Handle("/realtime-stats/queue", NewStatsHandler(redis_client.QueueConnection))
+	http.Handle("/realtime-stats/feeds", NewFeedsHandler())
 	http.Handle("/health", NewHealthHandler())
 
 	log.Info().Msg("Stats server listening on http://localhost:3333/realtime-stats/queue")
@@ -46,6 +49,25 @@ func (handler *StatsServerHandler) ServeHTTP(writer http.ResponseWriter, request
 	fmt.Fprint(writer, stats.GetHtml(layout, refresh))
 }
 
+type FeedsHandler struct {
+}
+
+func NewFeedsHandler() *FeedsHandler {
+	return &FeedsHandler{}
+}
+func (handler *FeedsHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	feeds, err := feedhealth.GetFeeds()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(feeds)
+}
+
 type HealthHandler struct {
 }
 
@@ -57,8 +57,16 @@ type ServiceAlertUpdate struct {
 	Title       string
 	Description string
 
+	TitleTranslations       ctdf.Translations
+	DescriptionTranslations ctdf.Translations
+
 	ValidFrom  time.Time
 	ValidUntil time.Time
 
+	// Version of the situation in the source, empty if the source doesn't version them
+	Version string
+	// Closed is set when the source has ended the situation early
+	Closed bool
+
 	IdentifyingInformation []map[string]string
 }
@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/travigo/travigo/pkg/database"
 	"github.com/travigo/travigo/pkg/elastic_client"
+	"github.com/travigo/travigo/pkg/redis_client"
 	"github.com/travigo/travigo/pkg/stats/calculator"
+	"github.com/travigo/travigo/pkg/stats/dataquality"
+	"github.com/travigo/travigo/pkg/stats/operatorcodes"
+	"github.com/travigo/travigo/pkg/stats/segmentruntimes"
+	"github.com/travigo/travigo/pkg/stats/trackingmonitor"
 	"github.com/travigo/travigo/pkg/stats/web_api"
+	"github.com/travigo/travigo/pkg/transforms"
 	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -103,6 +111,211 @@ func RegisterCLI() *cli.Command {
 						elastic_client.IndexRequest("overall-stats-1", bytes.NewReader(elasticEvent))
 					}
 
+					return nil
+				},
+			},
+			{
+				Name:  "tracking-monitor",
+				Usage: "compare scheduled journeys against tracked realtime journeys for each operator",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "date",
+						Usage: "Date to check in YYYY-MM-DD format, defaults to today",
+					},
+					&cli.Float64Flag{
+						Name:  "threshold",
+						Value: 0.5,
+						Usage: "Tracking rate below which an event is emitted for the operator",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+					if err := redis_client.Connect(); err != nil {
+						return err
+					}
+
+					date := time.Now()
+					if c.String("date") != "" {
+						var err error
+						date, err = time.ParseInLocation(time.DateOnly, c.String("date"), time.Local)
+						if err != nil {
+							return err
+						}
+					}
+
+					eventQueue, err := redis_client.QueueConnection.OpenQueue("events-queue")
+					if err != nil {
+						return err
+					}
+
+					monitor := trackingmonitor.Monitor{
+						EventQueue: eventQueue,
+						Threshold:  c.Float64("threshold"),
+					}
+
+					return monitor.Run(date)
+				},
+			},
+			{
+				Name:  "data-quality",
+				Usage: "score the data quality of each operator & dataset and print a report",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "date",
+						Usage: "Date to score in YYYY-MM-DD format, defaults to yesterday so realtime coverage is for a whole day",
+					},
+					&cli.StringFlag{
+						Name:  "subject",
+						Usage: "Only report on Operator or DataSet scores",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the report without saving the scores",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					date := time.Now().AddDate(0, 0, -1)
+					if c.String("date") != "" {
+						var err error
+						date, err = time.ParseInLocation(time.DateOnly, c.String("date"), time.Local)
+						if err != nil {
+							return err
+						}
+					}
+
+					scores, err := dataquality.Calculate(date)
+					if err != nil {
+						return err
+					}
+
+					if !c.Bool("dry-run") {
+						if err := dataquality.Save(scores); err != nil {
+							return err
+						}
+					}
+
+					for _, score := range scores {
+						if c.String("subject") != "" && !strings.EqualFold(string(score.SubjectType), c.String("subject")) {
+							continue
+						}
+
+						fmt.Printf(
+							"%s\t%s\tscore=%.2f\tgeometry=%.2f\trealtime=%.2f\tstops=%.2f\tspeeds=%.2f\t%d journeys\t%d implausible legs\n",
+							score.SubjectType, score.SubjectRef, score.Score,
+							score.GeometryCoverage, score.RealtimeCoverage, score.StopResolutionRate, score.PlausibleSpeedRate, score.Journeys, score.ImplausibleSpeedLegs,
+						)
+						for _, warning := range score.Warnings {
+							fmt.Printf("\twarning: %s\n", warning.Message)
+						}
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "segment-run-times",
+				Usage: "learn the run time profiles of each stop to stop segment from the observed realtime journeys",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "weeks",
+						Value: 8,
+						Usage: "Number of weeks of observed run times to learn from",
+					},
+					&cli.IntFlag{
+						Name:  "minimum-samples",
+						Value: 5,
+						Usage: "Minimum number of observed runs needed for a segment, day type & hour to get a profile",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					since := time.Now().AddDate(0, 0, -7*c.Int("weeks"))
+
+					_, err := segmentruntimes.Refresh(since, c.Int("minimum-samples"))
+
+					return err
+				},
+			},
+			{
+				Name:  "operator-codes",
+				Usage: "report operator references that don't match the NOC registry with suggested matches",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "days",
+						Value: 7,
+						Usage: "Number of days of realtime identification failures to include",
+					},
+					&cli.BoolFlag{
+						Name:  "realtime",
+						Usage: "Include operator codes from realtime feeds that failed identification",
+					},
+					&cli.IntFlag{
+						Name:  "suggestions",
+						Value: 3,
+						Usage: "Maximum number of suggested operators for each unmatched code",
+					},
+					&cli.StringFlag{
+						Name:  "overrides",
+						Usage: "Write the top suggestions as OperatorRef transforms to this file for review",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := database.Connect(); err != nil {
+						return err
+					}
+
+					reconcileOptions := operatorcodes.Options{
+						MaximumSuggestions: c.Int("suggestions"),
+					}
+
+					if c.Bool("realtime") {
+						if err := elastic_client.Connect(false); err != nil {
+							return err
+						}
+						transforms.SetupClient()
+
+						reconcileOptions.RealtimeDays = c.Int("days")
+					}
+
+					unmatchedCodes, err := operatorcodes.Reconcile(reconcileOptions)
+					if err != nil {
+						return err
+					}
+
+					for _, unmatchedCode := range unmatchedCodes {
+						var suggestions []string
+						for _, suggestion := range unmatchedCode.Suggestions {
+							suggestions = append(suggestions, fmt.Sprintf("%s (%s, %.2f)", suggestion.OperatorRef, suggestion.Name, suggestion.Similarity))
+						}
+
+						fmt.Printf(
+							"%s\t%d journeys\t%d services\t%d realtime failures\tdatasets=%s\tsuggestions=%s\n",
+							unmatchedCode.OperatorRef, unmatchedCode.Journeys, unmatchedCode.Services, unmatchedCode.RealtimeFailures,
+							strings.Join(unmatchedCode.Datasets, ","), strings.Join(suggestions, ", "),
+						)
+					}
+
+					log.Info().Int("unmatched", len(unmatchedCodes)).Msg("Reconciled operator codes")
+
+					if c.String("overrides") != "" {
+						overridesFile, err := os.Create(c.String("overrides"))
+						if err != nil {
+							return err
+						}
+						defer overridesFile.Close()
+
+						return operatorcodes.WriteOverrides(overridesFile, unmatchedCodes)
+					}
+
 					return nil
 				},
 			},
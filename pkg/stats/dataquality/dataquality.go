@@ -0,0 +1,299 @@
+package dataquality
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const stopResolutionBatchSize = 1000
+
+// Warnings only list this many of the records they're about so the score stays a reasonable size
+const maxWarningRefs = 50
+
+type journeyQualityRecord struct {
+	PrimaryIdentifier string
+	OperatorRef       string
+	DataSource        *ctdf.DataSourceReference
+	Availability      *ctdf.Availability
+	HasGeometry       bool
+	Path              []struct {
+		OriginStopRef      string
+		DestinationStopRef string
+		SpeedImplausible   bool
+	}
+}
+
+// Calculate scores every operator & dataset for the date.
+// Realtime coverage relies on the observed journeys recorded by the tracking monitor so that needs to be running too.
+func Calculate(date time.Time) ([]*ctdf.DataQualityScore, error) {
+	// Realtime journey run dates are stored as midnight UTC
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	observed, err := getObservedJourneys(date)
+	if err != nil {
+		return nil, err
+	}
+
+	journeysCollection := database.GetCollection("journeys")
+	cursor, err := journeysCollection.Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$project", Value: bson.M{
+			"primaryidentifier":       1,
+			"operatorref":             1,
+			"datasource":              1,
+			"availability":            1,
+			"path.originstopref":      1,
+			"path.destinationstopref": 1,
+			"path.speedimplausible":   1,
+			"hasgeometry": bson.M{"$or": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$track", bson.A{}}}}, 0}},
+				bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$path", bson.A{}}},
+					"as":    "item",
+					"in":    bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$$item.track", bson.A{}}}}, 0}},
+				}}}},
+			}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[string]*ctdf.DataQualityScore{}
+	// Counts of each stop reference per score, kept unique per stop as journeys mostly share the same stops
+	scoreStopRefs := map[string]map[string]int{}
+	// Same again but only for the journeys scheduled on the date, which shouldn't be calling at stops out of use
+	scoreScheduledStopRefs := map[string]map[string]int{}
+	stopRefs := map[string]bool{}
+
+	for cursor.Next(context.Background()) {
+		var journey journeyQualityRecord
+		if err := cursor.Decode(&journey); err != nil {
+			continue
+		}
+
+		var journeyScores []*ctdf.DataQualityScore
+		if journey.OperatorRef != "" {
+			journeyScores = append(journeyScores, getScore(scores, ctdf.DataQualitySubjectOperator, journey.OperatorRef, date))
+		}
+		if journey.DataSource != nil && journey.DataSource.DatasetID != "" {
+			journeyScores = append(journeyScores, getScore(scores, ctdf.DataQualitySubjectDataSet, journey.DataSource.DatasetID, date))
+		}
+
+		scheduled := journey.Availability != nil && journey.Availability.MatchDate(date)
+
+		var refs []string
+		implausibleLegs := 0
+		for _, item := range journey.Path {
+			refs = append(refs, item.OriginStopRef, item.DestinationStopRef)
+
+			if item.SpeedImplausible {
+				implausibleLegs += 1
+			}
+		}
+		for _, ref := range refs {
+			stopRefs[ref] = true
+		}
+
+		for _, score := range journeyScores {
+			score.Journeys += 1
+			if journey.HasGeometry {
+				score.JourneysWithGeometry += 1
+			}
+
+			if scheduled {
+				score.ScheduledJourneys += 1
+				if observed[journey.PrimaryIdentifier] {
+					score.TrackedJourneys += 1
+				}
+			}
+
+			score.StopReferences += len(refs)
+			score.PathLegs += len(journey.Path)
+			score.ImplausibleSpeedLegs += implausibleLegs
+
+			if scoreStopRefs[score.PrimaryIdentifier] == nil {
+				scoreStopRefs[score.PrimaryIdentifier] = map[string]int{}
+			}
+			for _, ref := range refs {
+				scoreStopRefs[score.PrimaryIdentifier][ref] += 1
+			}
+
+			if scheduled {
+				if scoreScheduledStopRefs[score.PrimaryIdentifier] == nil {
+					scoreScheduledStopRefs[score.PrimaryIdentifier] = map[string]int{}
+				}
+				for _, ref := range refs {
+					scoreScheduledStopRefs[score.PrimaryIdentifier][ref] += 1
+				}
+			}
+		}
+	}
+
+	resolvedStops, inactiveStops, err := resolveStops(stopRefs, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var scoreList []*ctdf.DataQualityScore
+	for _, score := range scores {
+		for ref, count := range scoreStopRefs[score.PrimaryIdentifier] {
+			if resolvedStops[ref] {
+				score.ResolvedStopReferences += count
+			}
+		}
+
+		var inactiveRefs []string
+		for ref, count := range scoreScheduledStopRefs[score.PrimaryIdentifier] {
+			if inactiveStops[ref] {
+				score.InactiveStopReferences += count
+				inactiveRefs = append(inactiveRefs, ref)
+			}
+		}
+		if len(inactiveRefs) > 0 {
+			sort.Strings(inactiveRefs)
+			message := fmt.Sprintf("%d scheduled journey stop references are to %d stops that aren't in use", score.InactiveStopReferences, len(inactiveRefs))
+			if len(inactiveRefs) > maxWarningRefs {
+				inactiveRefs = inactiveRefs[:maxWarningRefs]
+			}
+			score.AddWarning(message, inactiveRefs)
+
+			log.Warn().
+				Str("subject", score.SubjectRef).
+				Int("references", score.InactiveStopReferences).
+				Strs("stops", inactiveRefs).
+				Msg("Scheduled journeys call at stops that aren't in use")
+		}
+
+		score.CalculateScore()
+		score.ModificationDateTime = time.Now()
+
+		scoreList = append(scoreList, score)
+	}
+
+	sort.Slice(scoreList, func(i, j int) bool {
+		return scoreList[i].Score < scoreList[j].Score
+	})
+
+	return scoreList, nil
+}
+
+// Save stores the scores, one record per subject & date so they can be tracked over time
+func Save(scores []*ctdf.DataQualityScore) error {
+	var operations []mongo.WriteModel
+
+	for _, score := range scores {
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": score.PrimaryIdentifier}).
+			SetReplacement(score).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) == 0 {
+		return nil
+	}
+
+	scoresCollection := database.GetCollection("data_quality_scores")
+	_, err := scoresCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+
+	return err
+}
+
+func getScore(scores map[string]*ctdf.DataQualityScore, subjectType ctdf.DataQualitySubjectType, subjectRef string, date time.Time) *ctdf.DataQualityScore {
+	score := ctdf.NewDataQualityScore(subjectType, subjectRef, date)
+
+	if existing := scores[score.PrimaryIdentifier]; existing != nil {
+		return existing
+	}
+
+	scores[score.PrimaryIdentifier] = score
+
+	return score
+}
+
+func getObservedJourneys(date time.Time) (map[string]bool, error) {
+	observedJourneysCollection := database.GetCollection("observed_journeys")
+
+	journeyRefs, err := observedJourneysCollection.Distinct(context.Background(), "journeyref", bson.M{"date": date})
+	if err != nil {
+		return nil, err
+	}
+
+	observed := map[string]bool{}
+	for _, journeyRef := range journeyRefs {
+		if ref, ok := journeyRef.(string); ok {
+			observed[ref] = true
+		}
+	}
+
+	return observed, nil
+}
+
+// resolveStops returns which of the stop references point at a stop we know about & which of those aren't in use on the date
+func resolveStops(stopRefs map[string]bool, date time.Time) (map[string]bool, map[string]bool, error) {
+	stopsCollection := database.GetCollection("stops")
+	resolved := map[string]bool{}
+	inactive := map[string]bool{}
+
+	var refs []string
+	for ref := range stopRefs {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "status", Value: 1},
+		{Key: "effectivefrom", Value: 1},
+		{Key: "effectiveuntil", Value: 1},
+	})
+
+	for lower := 0; lower < len(refs); lower += stopResolutionBatchSize {
+		upper := lower + stopResolutionBatchSize
+		if upper > len(refs) {
+			upper = len(refs)
+		}
+		batch := refs[lower:upper]
+
+		cursor, err := stopsCollection.Find(context.Background(), bson.M{"$or": bson.A{
+			bson.M{"primaryidentifier": bson.M{"$in": batch}},
+			bson.M{"otheridentifiers": bson.M{"$in": batch}},
+		}}, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for cursor.Next(context.Background()) {
+			var stop ctdf.Stop
+			if err := cursor.Decode(&stop); err != nil {
+				continue
+			}
+
+			stopInactive := !stop.IsActiveOn(date)
+
+			for _, identifier := range append(stop.OtherIdentifiers, stop.PrimaryIdentifier) {
+				if stopRefs[identifier] {
+					resolved[identifier] = true
+					if stopInactive {
+						inactive[identifier] = true
+					}
+				}
+			}
+		}
+	}
+
+	log.Info().Int("references", len(refs)).Int("resolved", len(resolved)).Int("inactive", len(inactive)).Msg("Resolved journey stop references")
+
+	return resolved, inactive, nil
+}
@@ -0,0 +1,346 @@
+package operatorcodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/elastic_client"
+	"github.com/travigo/travigo/pkg/transforms"
+	"github.com/travigo/travigo/pkg/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Suggestions below this similarity are too far off to be worth looking at
+const minimumSuggestionSimilarity = 0.5
+
+// UnmatchedOperatorCode is an operator reference used by journeys, services or realtime feeds that isn't in the NOC registry
+type UnmatchedOperatorCode struct {
+	OperatorRef string
+
+	Journeys         int64
+	Services         int64
+	RealtimeFailures int64
+
+	Datasets []string
+
+	Suggestions []Suggestion
+}
+
+type Suggestion struct {
+	OperatorRef string
+	Name        string
+	Similarity  float64
+}
+
+type Options struct {
+	// Number of days of realtime identification failures to include, 0 skips the realtime feeds
+	RealtimeDays int
+
+	MaximumSuggestions int
+}
+
+type operatorRecord struct {
+	PrimaryIdentifier string
+	OtherIdentifiers  []string
+	PrimaryName       string
+}
+
+// Reconcile cross references the operator references in use against the imported operators
+func Reconcile(reconcileOptions Options) ([]*UnmatchedOperatorCode, error) {
+	operators, knownRefs, err := getOperators()
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched := map[string]*UnmatchedOperatorCode{}
+	getUnmatched := func(operatorRef string) *UnmatchedOperatorCode {
+		if unmatched[operatorRef] == nil {
+			unmatched[operatorRef] = &UnmatchedOperatorCode{OperatorRef: operatorRef}
+		}
+
+		return unmatched[operatorRef]
+	}
+
+	for _, collectionName := range []string{"journeys", "services"} {
+		counts, err := getOperatorRefCounts(collectionName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, count := range counts {
+			if count.ID.OperatorRef == "" || knownRefs[count.ID.OperatorRef] {
+				continue
+			}
+
+			unmatchedCode := getUnmatched(count.ID.OperatorRef)
+			if collectionName == "journeys" {
+				unmatchedCode.Journeys += count.Count
+			} else {
+				unmatchedCode.Services += count.Count
+			}
+
+			if count.ID.DatasetID != "" && !util.ContainsString(unmatchedCode.Datasets, count.ID.DatasetID) {
+				unmatchedCode.Datasets = append(unmatchedCode.Datasets, count.ID.DatasetID)
+			}
+		}
+	}
+
+	if reconcileOptions.RealtimeDays > 0 {
+		realtimeFailures, err := getRealtimeOperatorFailures(reconcileOptions.RealtimeDays)
+		if err != nil {
+			return nil, err
+		}
+
+		for operatorRef, failures := range realtimeFailures {
+			// Feeds that already have an override are resolved by the identifier so aren't unmatched
+			if operatorRef == "" || knownRefs[operatorRef] || knownRefs[transforms.GetOperatorRef(operatorRef, "")] {
+				continue
+			}
+
+			getUnmatched(operatorRef).RealtimeFailures += failures
+		}
+	}
+
+	var unmatchedCodes []*UnmatchedOperatorCode
+	for _, unmatchedCode := range unmatched {
+		unmatchedCode.Suggestions = getSuggestions(unmatchedCode.OperatorRef, operators, reconcileOptions.MaximumSuggestions)
+		sort.Strings(unmatchedCode.Datasets)
+
+		unmatchedCodes = append(unmatchedCodes, unmatchedCode)
+	}
+
+	// Biggest impact first
+	sort.Slice(unmatchedCodes, func(i, j int) bool {
+		iTotal := unmatchedCodes[i].Journeys + unmatchedCodes[i].Services + unmatchedCodes[i].RealtimeFailures
+		jTotal := unmatchedCodes[j].Journeys + unmatchedCodes[j].Services + unmatchedCodes[j].RealtimeFailures
+
+		if iTotal == jTotal {
+			return unmatchedCodes[i].OperatorRef < unmatchedCodes[j].OperatorRef
+		}
+
+		return iTotal > jTotal
+	})
+
+	return unmatchedCodes, nil
+}
+
+func getOperators() ([]*operatorRecord, map[string]bool, error) {
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "otheridentifiers", Value: 1},
+		{Key: "primaryname", Value: 1},
+	})
+
+	cursor, err := database.GetCollection("operators").Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var operators []*operatorRecord
+	if err := cursor.All(context.Background(), &operators); err != nil {
+		return nil, nil, err
+	}
+
+	knownRefs := map[string]bool{}
+	for _, operator := range operators {
+		knownRefs[operator.PrimaryIdentifier] = true
+		for _, identifier := range operator.OtherIdentifiers {
+			knownRefs[identifier] = true
+		}
+	}
+
+	return operators, knownRefs, nil
+}
+
+type operatorRefCount struct {
+	ID struct {
+		OperatorRef string
+		DatasetID   string
+	} `bson:"_id"`
+	Count int64
+}
+
+func getOperatorRefCounts(collectionName string) ([]*operatorRefCount, error) {
+	cursor, err := database.GetCollection(collectionName).Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"operatorref": "$operatorref",
+				"datasetid":   "$datasource.datasetid",
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []*operatorRefCount
+	err = cursor.All(context.Background(), &counts)
+
+	return counts, err
+}
+
+type realtimeOperatorFailuresESResponse struct {
+	Error map[string]interface{}
+
+	Aggregations struct {
+		Operators struct {
+			Buckets []struct {
+				Key      string
+				DocCount int64 `json:"doc_count"`
+			}
+		}
+	}
+}
+
+// getRealtimeOperatorFailures counts the realtime identification failures caused by an unknown operator for each feed operator code
+func getRealtimeOperatorFailures(days int) (map[string]int64, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []map[string]interface{}{
+					{
+						"range": map[string]interface{}{
+							"Timestamp": map[string]interface{}{
+								"gte": fmt.Sprintf("now-%dd/d", days),
+							},
+						},
+					},
+					{
+						"match": map[string]interface{}{
+							"FailReason.keyword": "NONREF_OPERATOR",
+						},
+					},
+				},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"operators": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": "Operator.keyword",
+					"size":  10000,
+				},
+			},
+		},
+	}
+
+	var queryBytes bytes.Buffer
+	json.NewEncoder(&queryBytes).Encode(query)
+
+	res, err := elastic_client.Client.Search(
+		elastic_client.Client.Search.WithContext(context.Background()),
+		elastic_client.Client.Search.WithIndex("realtime-identify-events-*"),
+		elastic_client.Client.Search.WithBody(&queryBytes),
+		elastic_client.Client.Search.WithSize(0),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	responseBytes, _ := io.ReadAll(res.Body)
+	var response realtimeOperatorFailuresESResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, errors.New(fmt.Sprintf("Realtime identification events query failed: %v", response.Error["reason"]))
+	}
+
+	failures := map[string]int64{}
+	for _, bucket := range response.Aggregations.Operators.Buckets {
+		failures[bucket.Key] = bucket.DocCount
+	}
+
+	log.Info().Int("operators", len(failures)).Msg("Retrieved realtime operator identification failures")
+
+	return failures, nil
+}
+
+// getSuggestions compares the bare code against the NOC codes of every operator
+func getSuggestions(operatorRef string, operators []*operatorRecord, maximumSuggestions int) []Suggestion {
+	code := strings.ToUpper(getBareCode(operatorRef))
+
+	var suggestions []Suggestion
+	for _, operator := range operators {
+		bestSimilarity := 0.0
+
+		for _, identifier := range append(operator.OtherIdentifiers, operator.PrimaryIdentifier) {
+			if !strings.HasPrefix(identifier, strings.TrimSuffix(ctdf.OperatorNOCFormat, "%s")) {
+				continue
+			}
+
+			similarity := util.StringSimilarity(code, strings.ToUpper(getBareCode(identifier)))
+			if similarity > bestSimilarity {
+				bestSimilarity = similarity
+			}
+		}
+
+		if bestSimilarity >= minimumSuggestionSimilarity {
+			suggestions = append(suggestions, Suggestion{
+				OperatorRef: operator.PrimaryIdentifier,
+				Name:        operator.PrimaryName,
+				Similarity:  bestSimilarity,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Similarity == suggestions[j].Similarity {
+			return suggestions[i].OperatorRef < suggestions[j].OperatorRef
+		}
+
+		return suggestions[i].Similarity > suggestions[j].Similarity
+	})
+
+	if len(suggestions) > maximumSuggestions {
+		suggestions = suggestions[:maximumSuggestions]
+	}
+
+	return suggestions
+}
+
+// getBareCode strips the identifier prefix off, eg. gb-noc-ABCD becomes ABCD
+func getBareCode(operatorRef string) string {
+	index := strings.LastIndex(operatorRef, "-")
+	if index == -1 {
+		return operatorRef
+	}
+
+	return operatorRef[index+1:]
+}
+
+// WriteOverrides writes the top suggestion for each unmatched code as an OperatorRef transform.
+// They still need checking by hand before being moved into the transforms directory.
+func WriteOverrides(writer io.Writer, unmatchedCodes []*UnmatchedOperatorCode) error {
+	for _, unmatchedCode := range unmatchedCodes {
+		if len(unmatchedCode.Suggestions) == 0 {
+			continue
+		}
+
+		suggestion := unmatchedCode.Suggestions[0]
+
+		_, err := fmt.Fprintf(writer,
+			"# %s (%.0f%% similar) - %d journeys, %d services, %d realtime failures\n---\nType: %s\nMatch:\n  OperatorRef: %q\nData:\n  OperatorRef: %q\n",
+			suggestion.Name, suggestion.Similarity*100,
+			unmatchedCode.Journeys, unmatchedCode.Services, unmatchedCode.RealtimeFailures,
+			transforms.OperatorRefTransformType, unmatchedCode.OperatorRef, suggestion.OperatorRef,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
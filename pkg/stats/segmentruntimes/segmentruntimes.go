@@ -0,0 +1,119 @@
+package segmentruntimes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const profileWriteBatchSize = 1000
+
+type segmentRunTimeGroup struct {
+	ID struct {
+		SegmentRef string
+		DayType    ctdf.SegmentDayType
+		Hour       int
+	} `bson:"_id"`
+
+	OriginStopRef      string
+	DestinationStopRef string
+
+	RunTimes []time.Duration
+}
+
+// Refresh rebuilds the run time profiles from the segment run times observed by the vehicle tracker since the given time.
+// Profiles that no longer have enough samples are removed so predictions fall back to the schedule for them.
+func Refresh(since time.Time, minimumSamples int) (int, error) {
+	refreshStart := time.Now()
+
+	segmentRunTimesCollection := database.GetCollection("segment_run_times")
+	cursor, err := segmentRunTimesCollection.Aggregate(context.Background(), mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"departedat": bson.M{"$gte": since}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"segmentref": "$segmentref",
+				"daytype":    "$daytype",
+				"hour":       "$hour",
+			},
+			"originstopref":      bson.M{"$first": "$originstopref"},
+			"destinationstopref": bson.M{"$first": "$destinationstopref"},
+			"runtimes":           bson.M{"$push": "$runtime"},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+
+	profilesCollection := database.GetCollection("segment_run_time_profiles")
+
+	var operations []mongo.WriteModel
+	profiles := 0
+
+	for cursor.Next(context.Background()) {
+		var group segmentRunTimeGroup
+		if err := cursor.Decode(&group); err != nil {
+			log.Error().Err(err).Msg("Failed to decode segment run times")
+			continue
+		}
+
+		if len(group.RunTimes) < minimumSamples {
+			continue
+		}
+
+		sort.Slice(group.RunTimes, func(i, j int) bool {
+			return group.RunTimes[i] < group.RunTimes[j]
+		})
+
+		profile := ctdf.NewSegmentRunTimeProfile(group.OriginStopRef, group.DestinationStopRef, group.ID.DayType, group.ID.Hour)
+		profile.Samples = len(group.RunTimes)
+		profile.Percentile50 = percentile(group.RunTimes, 50)
+		profile.Percentile85 = percentile(group.RunTimes, 85)
+		profile.ModificationDateTime = refreshStart
+
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"primaryidentifier": profile.PrimaryIdentifier}).
+			SetReplacement(profile).
+			SetUpsert(true),
+		)
+		profiles += 1
+
+		if len(operations) >= profileWriteBatchSize {
+			if _, err := profilesCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+				return profiles, err
+			}
+			operations = nil
+		}
+	}
+
+	if len(operations) > 0 {
+		if _, err := profilesCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false)); err != nil {
+			return profiles, err
+		}
+	}
+
+	deleted, err := profilesCollection.DeleteMany(context.Background(), bson.M{"modificationdatetime": bson.M{"$lt": refreshStart}})
+	if err != nil {
+		return profiles, err
+	}
+
+	log.Info().Int("profiles", profiles).Int64("removed", deleted.DeletedCount).Msg("Refreshed segment run time profiles")
+
+	return profiles, nil
+}
+
+// percentile uses the nearest rank of the already sorted run times
+func percentile(runTimes []time.Duration, percent int) time.Duration {
+	rank := (percent*len(runTimes) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return runTimes[rank-1]
+}
@@ -0,0 +1,279 @@
+package trackingmonitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/travigo/travigo/pkg/ctdf"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/queuemessage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Journeys are only counted once they should have departed this long ago, giving feeds time to pick them up
+const departureGracePeriod = 30 * time.Minute
+
+// Only operators that have been tracked at all in this period are monitored
+const activeOperatorPeriod = 7 * 24 * time.Hour
+
+type ObservedJourney struct {
+	PrimaryIdentifier string
+
+	Date        time.Time
+	JourneyRef  string
+	ServiceRef  string
+	OperatorRef string
+
+	ObservedDateTime time.Time
+}
+
+type OperatorTrackingStats struct {
+	PrimaryIdentifier string
+
+	OperatorRef string
+	Date        time.Time
+
+	ScheduledJourneys int
+	TrackedJourneys   int
+	UntrackedJourneys int
+
+	TrackingRate float64
+	// Set once the low tracking rate event has gone out so it's only sent once per operator & day
+	LowRateNotified bool `bson:",omitempty"`
+
+	ModificationDateTime time.Time
+}
+
+type Monitor struct {
+	EventQueue rmq.Queue
+
+	// Tracking rate below which an event is emitted for the operator
+	Threshold float64
+}
+
+// Run records the journeys currently being tracked & recalculates each operators tracking rate for the date
+// Realtime journeys expire a few hours after they finish so this needs running regularly throughout the day
+func (m *Monitor) Run(date time.Time) error {
+	// Realtime journey run dates are stored as midnight UTC
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	err := recordObservedJourneys(date)
+	if err != nil {
+		return err
+	}
+
+	operators, err := getActiveOperators(date)
+	if err != nil {
+		return err
+	}
+
+	statsCollection := database.GetCollection("operator_tracking_stats")
+
+	for _, operatorRef := range operators {
+		stats, err := calculateOperatorTrackingStats(operatorRef, date)
+		if err != nil {
+			log.Error().Err(err).Str("operator", operatorRef).Msg("Failed to calculate tracking stats")
+			continue
+		}
+
+		if stats.ScheduledJourneys == 0 {
+			continue
+		}
+
+		_, err = statsCollection.UpdateOne(context.Background(), bson.M{"primaryidentifier": stats.PrimaryIdentifier}, bson.M{"$set": stats}, options.Update().SetUpsert(true))
+		if err != nil {
+			log.Error().Err(err).Str("operator", operatorRef).Msg("Failed to save tracking stats")
+		}
+
+		log.Info().
+			Str("operator", operatorRef).
+			Int("scheduled", stats.ScheduledJourneys).
+			Int("untracked", stats.UntrackedJourneys).
+			Float64("rate", stats.TrackingRate).
+			Msg("Calculated operator tracking rate")
+
+		if stats.TrackingRate < m.Threshold && m.EventQueue != nil {
+			newlyLow, err := markLowRateNotified(stats.PrimaryIdentifier)
+			if err != nil {
+				log.Error().Err(err).Str("operator", operatorRef).Msg("Failed to mark low tracking rate as notified")
+				continue
+			}
+			if !newlyLow {
+				continue
+			}
+			stats.LowRateNotified = true
+
+			eventBytes, _ := queuemessage.Marshal(queuemessage.MessageTypeEvent, ctdf.Event{
+				Type:      ctdf.EventTypeOperatorTrackingRateLow,
+				Timestamp: time.Now(),
+				Body:      stats,
+			})
+			m.EventQueue.PublishBytes(eventBytes)
+		}
+	}
+
+	return nil
+}
+
+// markLowRateNotified flags the stats as having had their low tracking rate event sent,
+// returning false if it already had been
+func markLowRateNotified(statsIdentifier string) (bool, error) {
+	result, err := database.GetCollection("operator_tracking_stats").UpdateOne(context.Background(), bson.M{
+		"primaryidentifier": statsIdentifier,
+		"lowratenotified":   bson.M{"$ne": true},
+	}, bson.M{"$set": bson.M{"lowratenotified": true}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// recordObservedJourneys keeps a record of every journey that's had a realtime journey on the date, whether or not
+// it's still being tracked as realtime journeys stop being actively tracked once they've finished
+func recordObservedJourneys(date time.Time) error {
+	realtimeJourneysCollection := database.GetCollection("realtime_journeys")
+	observedJourneysCollection := database.GetCollection("observed_journeys")
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "journey.primaryidentifier", Value: 1},
+		{Key: "journey.serviceref", Value: 1},
+		{Key: "journey.operatorref", Value: 1},
+	})
+	cursor, err := realtimeJourneysCollection.Find(context.Background(), bson.M{
+		"journeyrundate": date,
+	}, opts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var operations []mongo.WriteModel
+
+	for cursor.Next(context.Background()) {
+		var realtimeJourney ctdf.RealtimeJourney
+		if err := cursor.Decode(&realtimeJourney); err != nil || realtimeJourney.Journey == nil {
+			continue
+		}
+
+		observedJourney := ObservedJourney{
+			PrimaryIdentifier: fmt.Sprintf("%s:%s", date.Format(time.DateOnly), realtimeJourney.Journey.PrimaryIdentifier),
+			Date:              date,
+			JourneyRef:        realtimeJourney.Journey.PrimaryIdentifier,
+			ServiceRef:        realtimeJourney.Journey.ServiceRef,
+			OperatorRef:       realtimeJourney.Journey.OperatorRef,
+			ObservedDateTime:  now,
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"primaryidentifier": observedJourney.PrimaryIdentifier}).
+			SetUpdate(bson.M{"$setOnInsert": observedJourney}).
+			SetUpsert(true),
+		)
+	}
+
+	if len(operations) > 0 {
+		_, err = observedJourneysCollection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+	}
+
+	return err
+}
+
+func getActiveOperators(date time.Time) ([]string, error) {
+	observedJourneysCollection := database.GetCollection("observed_journeys")
+
+	operatorRefs, err := observedJourneysCollection.Distinct(context.Background(), "operatorref", bson.M{
+		"date": bson.M{"$gte": date.Add(-activeOperatorPeriod)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var operators []string
+	for _, operatorRef := range operatorRefs {
+		if operator, ok := operatorRef.(string); ok && operator != "" {
+			operators = append(operators, operator)
+		}
+	}
+
+	return operators, nil
+}
+
+func calculateOperatorTrackingStats(operatorRef string, date time.Time) (*OperatorTrackingStats, error) {
+	journeysCollection := database.GetCollection("journeys")
+	observedJourneysCollection := database.GetCollection("observed_journeys")
+
+	observedJourneyRefs, err := observedJourneysCollection.Distinct(context.Background(), "journeyref", bson.M{
+		"date":        date,
+		"operatorref": operatorRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+	observed := map[string]bool{}
+	for _, journeyRef := range observedJourneyRefs {
+		observed[journeyRef.(string)] = true
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "primaryidentifier", Value: 1},
+		{Key: "availability", Value: 1},
+		{Key: "departuretime", Value: 1},
+		{Key: "departuretimezone", Value: 1},
+	})
+	cursor, err := journeysCollection.Find(context.Background(), bson.M{"operatorref": operatorRef}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	departureCutoff := time.Now().Add(-departureGracePeriod)
+
+	stats := &OperatorTrackingStats{
+		PrimaryIdentifier:    fmt.Sprintf("%s:%s", date.Format(time.DateOnly), operatorRef),
+		OperatorRef:          operatorRef,
+		Date:                 date,
+		ModificationDateTime: time.Now(),
+	}
+
+	for cursor.Next(context.Background()) {
+		var journey ctdf.Journey
+		if err := cursor.Decode(&journey); err != nil {
+			continue
+		}
+
+		if journey.Availability == nil || !journey.Availability.MatchDate(date) {
+			continue
+		}
+
+		journeyTimezone, err := time.LoadLocation(journey.DepartureTimezone)
+		if err != nil {
+			journeyTimezone = time.Local
+		}
+		departureDateTime := time.Date(
+			date.Year(), date.Month(), date.Day(),
+			journey.DepartureTime.Hour(), journey.DepartureTime.Minute(), journey.DepartureTime.Second(), 0,
+			journeyTimezone,
+		)
+		if departureDateTime.After(departureCutoff) {
+			continue
+		}
+
+		stats.ScheduledJourneys += 1
+		if observed[journey.PrimaryIdentifier] {
+			stats.TrackedJourneys += 1
+		} else {
+			stats.UntrackedJourneys += 1
+		}
+	}
+
+	if stats.ScheduledJourneys > 0 {
+		stats.TrackingRate = float64(stats.TrackedJourneys) / float64(stats.ScheduledJourneys)
+	}
+
+	return stats, nil
+}
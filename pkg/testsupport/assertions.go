@@ -0,0 +1,65 @@
+package testsupport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/travigo/travigo/pkg/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Count returns the number of records in a collection matching the filter
+func Count(t testing.TB, collection string, filter bson.M) int64 {
+	t.Helper()
+
+	count, err := database.GetCollection(collection).CountDocuments(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("Failed to count %s: %s", collection, err)
+	}
+
+	return count
+}
+
+// AssertCount fails the test if the collection doesn't have the expected number of records matching the filter
+func AssertCount(t testing.TB, collection string, filter bson.M, expected int64) {
+	t.Helper()
+
+	if count := Count(t, collection, filter); count != expected {
+		t.Errorf("Expected %d records in %s matching %v but found %d", expected, collection, filter, count)
+	}
+}
+
+// AssertExists fails the test if there is no record with the primary identifier
+func AssertExists(t testing.TB, collection string, primaryIdentifier string) {
+	t.Helper()
+
+	AssertCount(t, collection, bson.M{"primaryidentifier": primaryIdentifier}, 1)
+}
+
+// AssertNotExists fails the test if there is a record with the primary identifier
+func AssertNotExists(t testing.TB, collection string, primaryIdentifier string) {
+	t.Helper()
+
+	AssertCount(t, collection, bson.M{"primaryidentifier": primaryIdentifier}, 0)
+}
+
+// Get decodes the record with the primary identifier, failing the test if there isn't one
+func Get[T any](t testing.TB, collection string, primaryIdentifier string) *T {
+	t.Helper()
+
+	var record T
+	err := database.GetCollection(collection).FindOne(context.Background(), bson.M{"primaryidentifier": primaryIdentifier}).Decode(&record)
+	if err != nil {
+		t.Fatalf("Failed to find %s in %s: %s", primaryIdentifier, collection, err)
+	}
+
+	return &record
+}
+
+// AssertField fails the test if the record with the primary identifier doesn't have the expected value for a field.
+// The field uses the Mongo dot notation, eg. location.coordinates
+func AssertField(t testing.TB, collection string, primaryIdentifier string, field string, expected any) {
+	t.Helper()
+
+	AssertCount(t, collection, bson.M{"primaryidentifier": primaryIdentifier, field: expected}, 1)
+}
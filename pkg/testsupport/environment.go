@@ -0,0 +1,142 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/travigo/travigo/pkg/database"
+	"github.com/travigo/travigo/pkg/redis_client"
+	"github.com/travigo/travigo/pkg/util"
+)
+
+const mongoStartTimeout = 30 * time.Second
+
+// Environment is an ephemeral MongoDB & Redis that the database & redis_client packages are connected to for a single test
+type Environment struct {
+	MongoConnection string
+	MongoDatabase   string
+	RedisAddress    string
+
+	Redis *miniredis.Miniredis
+
+	mongod *exec.Cmd
+}
+
+// Start connects the database & redis_client packages to a fresh MongoDB database & Redis that are removed when the test finishes.
+// Redis runs in process. MongoDB uses the server in TRAVIGO_TEST_MONGODB_CONNECTION if it's set, otherwise a mongod binary
+// on the PATH is started. The test is skipped when neither is available
+func Start(t testing.TB) *Environment {
+	t.Helper()
+
+	environment := &Environment{
+		MongoDatabase: fmt.Sprintf("travigo-test-%d", time.Now().UnixNano()),
+	}
+
+	if err := environment.startMongo(t); err != nil {
+		t.Skipf("No MongoDB available for integration tests: %s", err)
+	}
+	t.Cleanup(environment.stopMongo)
+
+	redis, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start Redis: %s", err)
+	}
+	environment.Redis = redis
+	environment.RedisAddress = redis.Addr()
+	t.Cleanup(redis.Close)
+
+	t.Setenv("TRAVIGO_MONGODB_CONNECTION", environment.MongoConnection)
+	t.Setenv("TRAVIGO_MONGODB_DATABASE", environment.MongoDatabase)
+	t.Setenv("TRAVIGO_REDIS_ADDRESS", environment.RedisAddress)
+	t.Setenv("TRAVIGO_REDIS_PASSWORD", "")
+	t.Setenv("TRAVIGO_REDIS_DATABASE", "0")
+
+	if err := database.Connect(); err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %s", err)
+	}
+	if err := redis_client.Connect(); err != nil {
+		t.Fatalf("Failed to connect to Redis: %s", err)
+	}
+
+	return environment
+}
+
+func (environment *Environment) startMongo(t testing.TB) error {
+	if connection := util.GetEnvironmentVariables()["TRAVIGO_TEST_MONGODB_CONNECTION"]; connection != "" {
+		environment.MongoConnection = connection
+		return nil
+	}
+
+	mongodPath, err := exec.LookPath("mongod")
+	if err != nil {
+		return errors.New("TRAVIGO_TEST_MONGODB_CONNECTION isn't set & mongod isn't on the PATH")
+	}
+
+	port, err := getFreePort()
+	if err != nil {
+		return err
+	}
+
+	environment.mongod = exec.Command(mongodPath,
+		"--dbpath", t.TempDir(),
+		"--bind_ip", "127.0.0.1",
+		"--port", strconv.Itoa(port),
+		"--quiet",
+	)
+	environment.mongod.Stdout = os.Stderr
+	environment.mongod.Stderr = os.Stderr
+	if err := environment.mongod.Start(); err != nil {
+		return err
+	}
+
+	environment.MongoConnection = fmt.Sprintf("mongodb://127.0.0.1:%d/", port)
+
+	// Wait for it to accept connections
+	deadline := time.Now().Add(mongoStartTimeout)
+	for {
+		connection, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), time.Second)
+		if err == nil {
+			connection.Close()
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			environment.stopMongo()
+			return errors.New(fmt.Sprintf("mongod didn't start listening within %s", mongoStartTimeout))
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (environment *Environment) stopMongo() {
+	if database.Instance != nil && database.Instance.Database.Name() == environment.MongoDatabase {
+		database.Instance.Database.Drop(context.Background())
+		database.Instance.Client.Disconnect(context.Background())
+		database.Instance = nil
+	}
+
+	if environment.mongod != nil && environment.mongod.Process != nil {
+		environment.mongod.Process.Kill()
+		environment.mongod.Wait()
+		environment.mongod = nil
+	}
+}
+
+func getFreePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
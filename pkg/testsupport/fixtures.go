@@ -0,0 +1,146 @@
+package testsupport
+
+import (
+	"archive/zip"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/travigo/travigo/pkg/dataimporter/datasets"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+)
+
+//go:embed fixtures
+var fixtureFiles embed.FS
+
+// Fixture is a small sample input for a format, with just enough records to exercise each of the objects it creates
+type Fixture struct {
+	Name   string
+	Format datasets.DataSetFormat
+	// Path within the fixtures directory, a directory is zipped up before being imported
+	Path     string
+	Supports []string
+}
+
+var Fixtures = []Fixture{
+	{
+		Name:     "gtfs-schedule",
+		Format:   datasets.DataSetFormatGTFSSchedule,
+		Path:     "gtfs-schedule",
+		Supports: []string{"operators", "stops", "services", "journeys"},
+	},
+	{
+		Name:     "gb-naptan",
+		Format:   datasets.DataSetFormatNaPTAN,
+		Path:     "gb-naptan/naptan.xml",
+		Supports: []string{"stops", "stopgroups", "carparks"},
+	},
+	{
+		Name:     "gb-tflcarparks",
+		Format:   datasets.DataSetFormatTfLCarParks,
+		Path:     "gb-tflcarparks/carparks.json",
+		Supports: []string{"carparks"},
+	},
+}
+
+// GetFixture finds a fixture by name
+func GetFixture(name string) (*Fixture, error) {
+	for i := range Fixtures {
+		if Fixtures[i].Name == name {
+			return &Fixtures[i], nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unknown fixture %s", name))
+}
+
+// Materialise writes the fixture out to a file in the directory so it can be imported like any other local file
+func (fixture *Fixture) Materialise(directory string) (string, error) {
+	fixturePath := path.Join("fixtures", fixture.Path)
+
+	fileInfo, err := fs.Stat(fixtureFiles, fixturePath)
+	if err != nil {
+		return "", err
+	}
+
+	if !fileInfo.IsDir() {
+		contents, err := fixtureFiles.ReadFile(fixturePath)
+		if err != nil {
+			return "", err
+		}
+
+		outputPath := filepath.Join(directory, path.Base(fixture.Path))
+		return outputPath, os.WriteFile(outputPath, contents, 0644)
+	}
+
+	outputPath := filepath.Join(directory, fmt.Sprintf("%s.zip", fixture.Name))
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return "", err
+	}
+	defer outputFile.Close()
+
+	zipWriter := zip.NewWriter(outputFile)
+
+	entries, err := fixtureFiles.ReadDir(fixturePath)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		writer, err := zipWriter.Create(entry.Name())
+		if err != nil {
+			return "", err
+		}
+
+		file, err := fixtureFiles.Open(path.Join(fixturePath, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(writer, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return outputPath, zipWriter.Close()
+}
+
+// Dataset builds the local file dataset that imports the fixture from the directory it was materialised in
+func (fixture *Fixture) Dataset(directory string) (datasets.DataSet, error) {
+	source, err := fixture.Materialise(directory)
+	if err != nil {
+		return datasets.DataSet{}, err
+	}
+
+	return manager.GetLocalFileDataset(fmt.Sprintf("fixture-%s", fixture.Name), string(fixture.Format), source, "", fixture.Supports)
+}
+
+// LoadFixture imports a fixture into the test environment
+func LoadFixture(t testing.TB, name string) {
+	t.Helper()
+
+	fixture, err := GetFixture(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := fixture.Dataset(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create fixture dataset: %s", err)
+	}
+
+	if err := manager.ImportDataset(&dataset, true); err != nil {
+		t.Fatalf("Failed to import fixture %s: %s", name, err)
+	}
+}
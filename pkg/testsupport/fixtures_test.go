@@ -0,0 +1,81 @@
+package testsupport_test
+
+import (
+	"archive/zip"
+	"testing"
+
+	"github.com/travigo/travigo/pkg/testsupport"
+)
+
+func TestMaterialiseZipsDirectoryFixtures(t *testing.T) {
+	fixture, err := testsupport.GetFixture("gtfs-schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath, err := fixture.Materialise(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to materialise fixture: %s", err)
+	}
+
+	archive, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("Materialised fixture isn't a zip: %s", err)
+	}
+	defer archive.Close()
+
+	files := map[string]bool{}
+	for _, file := range archive.File {
+		files[file.Name] = true
+	}
+
+	for _, expected := range []string{"agency.txt", "routes.txt", "stops.txt", "stop_times.txt", "trips.txt"} {
+		if !files[expected] {
+			t.Errorf("Expected %s in the materialised fixture", expected)
+		}
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		expected map[string][]string
+	}{
+		{
+			fixture: "gtfs-schedule",
+			expected: map[string][]string{
+				"operators": {"fixture-gtfs-schedule-operator-FIXA"},
+				"services":  {"fixture-gtfs-schedule-service-FIXR1"},
+				"journeys":  {"fixture-gtfs-schedule-journey-FIXT1", "fixture-gtfs-schedule-journey-FIXT2"},
+				"stops_raw": {"fixture-gtfs-schedule-stop-FIXS1", "fixture-gtfs-schedule-stop-FIXS2", "fixture-gtfs-schedule-stop-FIXS3"},
+			},
+		},
+		{
+			fixture: "gb-naptan",
+			expected: map[string][]string{
+				"stops_raw":   {"gb-atco-0100FIX00001", "gb-atco-0100FIX00002", "gb-atco-0100FIX00003"},
+				"stop_groups": {"gb-stopgroup-010GFIX00001"},
+				"car_parks":   {"gb-naptan-carpark-0100FIX00003"},
+			},
+		},
+		{
+			fixture: "gb-tflcarparks",
+			expected: map[string][]string{
+				"car_parks": {"gb-tfl-carpark-CarParks_FIX001", "gb-tfl-carpark-CarParks_FIX002"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			testsupport.Start(t)
+			testsupport.LoadFixture(t, test.fixture)
+
+			for collection, primaryIdentifiers := range test.expected {
+				for _, primaryIdentifier := range primaryIdentifiers {
+					testsupport.AssertExists(t, collection, primaryIdentifier)
+				}
+			}
+		})
+	}
+}
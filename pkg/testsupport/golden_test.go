@@ -0,0 +1,218 @@
+package testsupport_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travigo/travigo/pkg/dataimporter/datasink"
+	"github.com/travigo/travigo/pkg/dataimporter/manager"
+	"github.com/travigo/travigo/pkg/testsupport"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Regenerate with go test ./pkg/testsupport -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "Regenerate the golden files from the current importers")
+
+// Golden files are kept alongside the fixtures they come from
+const goldenDirectory = "golden"
+
+// Values that change on every run are replaced with this so the output only changes when the mapping does
+const importTimePlaceholder = "<import time>"
+
+// TestGolden runs each format importer against its fixture and compares every write it makes with the checked in
+// golden file. Importers look up existing records while mapping so they run against an empty test database
+func TestGolden(t *testing.T) {
+	for i := range testsupport.Fixtures {
+		fixture := &testsupport.Fixtures[i]
+
+		t.Run(fixture.Name, func(t *testing.T) {
+			testsupport.Start(t)
+
+			output, err := generateGolden(t, fixture)
+			if err != nil {
+				t.Fatalf("Failed to import fixture: %s", err)
+			}
+
+			goldenPath := filepath.Join(goldenDirectory, fmt.Sprintf("%s.ndjson", fixture.Name))
+
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, output, 0644); err != nil {
+					t.Fatalf("Failed to update golden file: %s", err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %s", err)
+			}
+
+			missing, unexpected := diffLines(expected, output)
+			for _, line := range missing {
+				t.Errorf("Missing write: %s", line)
+			}
+			for _, line := range unexpected {
+				t.Errorf("Unexpected write: %s", line)
+			}
+			if len(missing) > 0 || len(unexpected) > 0 {
+				t.Log("Rerun with -update if the changes are expected")
+			}
+		})
+	}
+}
+
+// generateGolden imports the fixture into a capturing sink and returns every write as canonical ndjson.
+// Lines are sorted as importers write concurrently so the order records arrive in isn't stable
+func generateGolden(t *testing.T, fixture *testsupport.Fixture) ([]byte, error) {
+	dataset, err := fixture.Dataset(t.TempDir())
+	if err != nil {
+		return nil, err
+	}
+
+	sink := datasink.NewCaptureSink()
+	dataset.Sink = sink
+
+	importStart := time.Now().Add(-time.Second)
+	if err := manager.ImportDataset(&dataset, true); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, write := range sink.Writes() {
+		line, err := canonicalise(write, importStart)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to encode %s write to %s: %s", write.Operation, write.Collection, err))
+		}
+
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+
+	var output bytes.Buffer
+	for _, line := range lines {
+		output.WriteString(line)
+		output.WriteString("\n")
+	}
+
+	return output.Bytes(), nil
+}
+
+// diffLines returns the lines only in expected & the lines only in output, counting repeated lines separately
+func diffLines(expected []byte, output []byte) ([]string, []string) {
+	expectedLines := countLines(expected)
+	outputLines := countLines(output)
+
+	var missing []string
+	var unexpected []string
+	for line, count := range expectedLines {
+		for i := outputLines[line]; i < count; i++ {
+			missing = append(missing, line)
+		}
+	}
+	for line, count := range outputLines {
+		for i := expectedLines[line]; i < count; i++ {
+			unexpected = append(unexpected, line)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+
+	return missing, unexpected
+}
+
+func countLines(contents []byte) map[string]int {
+	lines := map[string]int{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line != "" {
+			lines[line] += 1
+		}
+	}
+
+	return lines
+}
+
+// canonicalise encodes a write as a single line of JSON with the keys of every document sorted
+func canonicalise(write datasink.CapturedWrite, importStart time.Time) (string, error) {
+	encoded, err := bson.Marshal(bson.M{
+		"filter":   getDocument(write.Filter),
+		"document": getDocument(write.Document),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var decoded bson.D
+	if err := bson.Unmarshal(encoded, &decoded); err != nil {
+		return "", err
+	}
+
+	line := bson.D{
+		{Key: "collection", Value: write.Collection},
+		{Key: "operation", Value: write.Operation},
+	}
+	line = append(line, normalise(decoded, "", importStart).(bson.D)...)
+
+	output, err := bson.MarshalExtJSON(line, false, false)
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
+
+// getDocument treats already marshalled BSON as a document rather than binary data
+func getDocument(value interface{}) interface{} {
+	if encoded, ok := value.([]byte); ok {
+		return bson.Raw(encoded)
+	}
+
+	return value
+}
+
+func normalise(value interface{}, key string, importStart time.Time) interface{} {
+	switch typedValue := value.(type) {
+	case bson.D:
+		normalised := bson.D{}
+		for _, element := range typedValue {
+			// Query operators apply to the field they're under
+			elementKey := element.Key
+			if strings.HasPrefix(elementKey, "$") && key != "" {
+				elementKey = key
+			}
+
+			normalised = append(normalised, bson.E{Key: element.Key, Value: normalise(element.Value, elementKey, importStart)})
+		}
+		sort.SliceStable(normalised, func(i, j int) bool {
+			return normalised[i].Key < normalised[j].Key
+		})
+
+		return normalised
+	case bson.A:
+		normalised := bson.A{}
+		for _, element := range typedValue {
+			normalised = append(normalised, normalise(element, key, importStart))
+		}
+
+		return normalised
+	case primitive.DateTime:
+		if !typedValue.Time().Before(importStart) {
+			return importTimePlaceholder
+		}
+	case string:
+		// The datasource timestamp is the unix time of the import
+		if key == "timestamp" || strings.HasSuffix(key, ".timestamp") {
+			return importTimePlaceholder
+		}
+	}
+
+	return value
+}
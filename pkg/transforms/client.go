@@ -2,17 +2,99 @@ package transforms
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
+const transformsDirectory = "data/transforms/"
+
+// How often the transforms directory is checked for changes so rules can be updated without a redeploy
+const reloadInterval = 30 * time.Second
+
 var transforms []TransformDefinition
+var transformsMutex sync.RWMutex
 
 func SetupClient() {
-	err := filepath.Walk("data/transforms/",
+	loadedTransforms, err := loadTransforms()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load transforms directory")
+	}
+
+	setTransforms(loadedTransforms)
+
+	go watchTransforms()
+}
+
+func getTransforms() []TransformDefinition {
+	transformsMutex.RLock()
+	defer transformsMutex.RUnlock()
+
+	return transforms
+}
+
+func setTransforms(newTransforms []TransformDefinition) {
+	transformsMutex.Lock()
+	defer transformsMutex.Unlock()
+
+	transforms = newTransforms
+}
+
+func watchTransforms() {
+	lastFingerprint, _ := getDirectoryFingerprint()
+
+	for {
+		time.Sleep(reloadInterval)
+
+		fingerprint, err := getDirectoryFingerprint()
+		if err != nil || fingerprint == lastFingerprint {
+			continue
+		}
+		lastFingerprint = fingerprint
+
+		loadedTransforms, err := loadTransforms()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to reload transforms, keeping the existing ones")
+			continue
+		}
+
+		setTransforms(loadedTransforms)
+
+		log.Info().Int("transforms", len(loadedTransforms)).Msg("Reloaded transforms")
+	}
+}
+
+// getDirectoryFingerprint summarises the transform files so changes can be spotted without re-parsing them all
+func getDirectoryFingerprint() (string, error) {
+	var fingerprint string
+
+	err := filepath.Walk(transformsDirectory,
+		func(path string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !fileInfo.IsDir() {
+				fingerprint += fmt.Sprintf("%s:%d:%d;", path, fileInfo.ModTime().UnixNano(), fileInfo.Size())
+			}
+
+			return nil
+		})
+
+	return fingerprint, err
+}
+
+func loadTransforms() ([]TransformDefinition, error) {
+	var loadedTransforms []TransformDefinition
+
+	err := filepath.Walk(transformsDirectory,
 		func(path string, fileInfo os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -23,30 +105,43 @@ func SetupClient() {
 
 				extension := filepath.Ext(path)
 
-				if extension != ".yaml" {
+				if extension != ".yaml" && extension != ".json" {
 					return nil
 				}
 
-				transformYaml, err := os.ReadFile(path)
+				transformFile, err := os.ReadFile(path)
 				if err != nil {
 					return err
 				}
 
-				decoder := yaml.NewDecoder(bytes.NewReader(transformYaml))
+				var decode func(interface{}) error
+				if extension == ".json" {
+					decode = json.NewDecoder(bytes.NewReader(transformFile)).Decode
+				} else {
+					decode = yaml.NewDecoder(bytes.NewReader(transformFile)).Decode
+				}
 
 				for {
 					var transformDefinition TransformDefinition
-					if decoder.Decode(&transformDefinition) != nil {
+					err := decode(&transformDefinition)
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						log.Error().Err(err).Str("path", path).Msg("Failed to decode transforms file")
 						break
 					}
 
-					transforms = append(transforms, transformDefinition)
+					if err := transformDefinition.compile(); err != nil {
+						log.Error().Err(err).Str("path", path).Msg("Skipping invalid transform")
+						continue
+					}
+
+					loadedTransforms = append(loadedTransforms, transformDefinition)
 				}
 			}
 
 			return nil
 		})
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to load transforms directory")
-	}
+
+	return loadedTransforms, err
 }
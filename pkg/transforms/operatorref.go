@@ -0,0 +1,39 @@
+package transforms
+
+import "reflect"
+
+// OperatorRefTransformType is the type of transform that remaps operator references used by datasets & realtime feeds
+// which don't match the NOC registry, eg.
+//
+//	Type: OperatorRef
+//	Match:
+//	  OperatorRef: "gb-noc-ABCD"
+//	  DatasetID: "gb-dft-bods-gtfs-schedule"
+//	Data:
+//	  OperatorRef: "gb-noc-WXYZ"
+const OperatorRefTransformType = "OperatorRef"
+
+type operatorRefMatch struct {
+	OperatorRef string
+	DatasetID   string
+}
+
+// GetOperatorRef returns the operator reference to use in place of the one given, or the same one if there is no override
+func GetOperatorRef(operatorRef string, datasetID string) string {
+	matchValue := reflect.ValueOf(operatorRefMatch{
+		OperatorRef: operatorRef,
+		DatasetID:   datasetID,
+	})
+
+	for _, transform := range getTransforms() {
+		if transform.Type != OperatorRefTransformType || !transform.isMatch(matchValue) {
+			continue
+		}
+
+		if overrideRef, ok := transform.Data["OperatorRef"].(string); ok && overrideRef != "" {
+			return overrideRef
+		}
+	}
+
+	return operatorRef
+}
@@ -1,20 +1,95 @@
 package transforms
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 )
 
 type TransformDefinition struct {
-	Type  string                 `yaml:"Type"`
-	Group string                 `yaml:"Group"`
-	Match map[string]string      `yaml:"Match"`
-	Data  map[string]interface{} `yaml:"Data"`
+	Type  string `yaml:"Type"`
+	Group string `yaml:"Group"`
+
+	// Match & MatchRegex keys are field names, nested fields can be reached with a dotted path (eg. DataSource.DatasetID)
+	Match      map[string]string      `yaml:"Match"`
+	MatchRegex map[string]string      `yaml:"MatchRegex"`
+	Data       map[string]interface{} `yaml:"Data"`
+
+	matchRegex map[string]*regexp.Regexp
 }
 
-func (t *TransformDefinition) Transform(inputTypeOf reflect.Type, inputValue reflect.Value, depth int) {
-	isMatch := true
+func (t *TransformDefinition) compile() error {
+	t.matchRegex = map[string]*regexp.Regexp{}
+
+	for key, pattern := range t.MatchRegex {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid MatchRegex for %s on %s: %s", key, t.Type, err))
+		}
+
+		t.matchRegex[key] = regex
+	}
+
+	return nil
+}
+
+func (t *TransformDefinition) isMatch(inputValue reflect.Value) bool {
+	for key, value := range t.Match {
+		fieldValue, exists := getFieldString(inputValue, key)
+		if !exists || value != fieldValue {
+			return false
+		}
+	}
 
+	for key, regex := range t.matchRegex {
+		fieldValue, exists := getFieldString(inputValue, key)
+		if !exists || !regex.MatchString(fieldValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getFieldString follows a dotted field path through structs & pointers and returns the value it ends up at as a string
+func getFieldString(inputValue reflect.Value, path string) (string, bool) {
+	field := inputValue
+
+	for _, fieldName := range strings.Split(path, ".") {
+		for field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface {
+			if field.IsNil() {
+				return "", false
+			}
+			field = field.Elem()
+		}
+
+		if field.Kind() != reflect.Struct {
+			return "", false
+		}
+
+		field = field.FieldByName(fieldName)
+		if !field.IsValid() {
+			return "", false
+		}
+	}
+
+	for field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return "", false
+		}
+		field = field.Elem()
+	}
+
+	if field.Kind() == reflect.String {
+		return field.String(), true
+	}
+
+	return fmt.Sprint(field.Interface()), true
+}
+
+func (t *TransformDefinition) Transform(inputTypeOf reflect.Type, inputValue reflect.Value, depth int) {
 	if !inputValue.IsValid() {
 		return
 	}
@@ -26,23 +101,9 @@ func (t *TransformDefinition) Transform(inputTypeOf reflect.Type, inputValue ref
 	// Only check values and try and replace them if the types match the transform def
 	inputTypeName := strings.Replace(inputTypeOf.String(), "*", "", 1)
 
-	if inputTypeName == t.Type {
-		for key, value := range t.Match {
-			field := inputValue.FieldByName(key)
-			if field.IsValid() {
-				if value != field.String() {
-					isMatch = false
-				}
-			} else {
-				isMatch = false
-			}
-		}
-
-		// If we match then go over and update the values
-		if isMatch {
-			// pretty.Println(inputValue, t.Data)
-			handleSubDocument(inputValue, t.Data)
-		}
+	// If we match then go over and update the values
+	if inputTypeName == t.Type && t.isMatch(inputValue) {
+		handleSubDocument(inputValue, t.Data)
 	}
 
 	// Go through all the fields and try and run transform against anymore structs/slices
@@ -70,7 +131,9 @@ func handleSubDocument(inputValue reflect.Value, data map[string]interface{}) {
 		field := inputValue.FieldByName(key)
 		if field.IsValid() {
 			valueOf := reflect.ValueOf(value)
-			if valueOf.Kind() == reflect.Slice {
+			if valueOf.Kind() == reflect.Slice && field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Struct {
+				handleValueSlice(field, valueOf)
+			} else if valueOf.Kind() == reflect.Slice {
 				handleSubDocument2(field, valueOf, data)
 			} else if valueOf.Kind() == reflect.Map {
 				handleMap(field, value.(map[string]interface{}))
@@ -81,6 +144,21 @@ func handleSubDocument(inputValue reflect.Value, data map[string]interface{}) {
 	}
 }
 
+// handleValueSlice replaces a slice of plain values (eg. []string), the items are converted to the type of the field
+func handleValueSlice(field reflect.Value, valueOf reflect.Value) {
+	elemType := field.Type().Elem()
+	newSlice := reflect.MakeSlice(field.Type(), 0, valueOf.Len())
+
+	for i := 0; i < valueOf.Len(); i++ {
+		item := reflect.ValueOf(valueOf.Index(i).Interface())
+		if item.IsValid() && item.Type().ConvertibleTo(elemType) {
+			newSlice = reflect.Append(newSlice, item.Convert(elemType))
+		}
+	}
+
+	field.Set(newSlice)
+}
+
 func handleMap(field reflect.Value, data map[string]interface{}) {
 	for key, value := range data {
 		field.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
@@ -138,7 +216,7 @@ func transformValue(inputTypeOf reflect.Type, inputValueOf reflect.Value, depth
 		inputValue = inputValueOf.Elem()
 	}
 
-	for _, transformDef := range transforms {
+	for _, transformDef := range getTransforms() {
 		if transformDef.Group == group {
 			transformDef.Transform(inputTypeOf, inputValue, depth)
 		}
@@ -34,3 +34,37 @@ func TrimString(s string, length int) string {
 
 	return s[:length]
 }
+
+// StringSimilarity is the Levenshtein distance between the strings scaled to between 0 (nothing in common) & 1 (the same)
+func StringSimilarity(a string, b string) float64 {
+	aRunes := []rune(a)
+	bRunes := []rune(b)
+
+	longest := max(len(aRunes), len(bRunes))
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return 1 - float64(previous[len(bRunes)])/float64(longest)
+}